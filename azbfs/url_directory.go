@@ -48,6 +48,15 @@ func (d DirectoryURL) WithPipeline(p pipeline.Pipeline) DirectoryURL {
 	return NewDirectoryURL(d.URL(), p)
 }
 
+// GetParentDir returns the directory containing this one.
+func (d DirectoryURL) GetParentDir() (DirectoryURL, error) {
+	p, err := removeLastSectionOfPath(d.URL())
+	if err != nil {
+		return DirectoryURL{}, err
+	}
+	return NewDirectoryURL(p, d.directoryClient.Pipeline()), nil
+}
+
 // NewFileURL creates a new FileURL object by concatenating fileName to the end of
 // DirectoryURL's URL. The new FileURL uses the same request policy pipeline as the DirectoryURL.
 // To change the pipeline, create the FileURL and then call its WithPipeline method passing in the
@@ -108,6 +117,27 @@ func (d DirectoryURL) GetProperties(ctx context.Context) (*DirectoryGetPropertie
 	return (*DirectoryGetPropertiesResponse)(resp), err
 }
 
+// GetAccessControl returns the directory's owner, owning group and access control list.
+func (d DirectoryURL) GetAccessControl(ctx context.Context) (BlobFSAccessControl, error) {
+	resp, err := d.directoryClient.GetProperties(ctx, d.filesystem, d.pathParameter, PathGetPropertiesActionGetAccessControl, nil, nil,
+		nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		return BlobFSAccessControl{}, err
+	}
+	return BlobFSAccessControl{Owner: resp.XMsOwner(), Group: resp.XMsGroup(), ACL: resp.XMsACL()}, nil
+}
+
+// SetAccessControl sets the directory's owner, owning group and access control list.
+// For more information, see https://docs.microsoft.com/en-us/rest/api/storageservices/datalakestoragegen2/path/update.
+func (d DirectoryURL) SetAccessControl(ctx context.Context, accessControl BlobFSAccessControl) (*PathUpdateResponse, error) {
+	owner, group, acl := accessControl.headers()
+	overrideHttpVerb := "PATCH" // see the comments in FileURL.AppendData
+	return d.directoryClient.Update(ctx, PathUpdateActionSetAccessControl, d.filesystem, d.pathParameter, nil,
+		nil, nil, nil, nil, nil, nil,
+		nil, nil, nil, nil, nil, owner, group,
+		nil, acl, nil, nil, nil, nil, &overrideHttpVerb, nil, nil, nil, nil)
+}
+
 // FileSystemURL returns the fileSystemUrl from the directoryUrl
 // FileSystemURL is of the FS in which the current directory exists.
 func (d DirectoryURL) FileSystemURL() FileSystemURL {
@@ -8,6 +8,7 @@ import (
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"io"
 	"net/http"
+	"time"
 )
 
 // A FileURL represents a URL to an Azure Storage file.
@@ -26,6 +27,24 @@ type BlobFSHTTPHeaders struct {
 	CacheControl       string
 }
 
+// BlobFSAccessControl represents the owner, owning group and POSIX access control list of a file or directory.
+// Empty fields are left unchanged when setting access control.
+type BlobFSAccessControl struct {
+	Owner string
+	Group string
+	ACL   string // e.g. "user::rwx,group::r-x,other::---"
+}
+
+func (ac BlobFSAccessControl) headers() (owner, group, acl *string) {
+	optional := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+	return optional(ac.Owner), optional(ac.Group), optional(ac.ACL)
+}
+
 // NewFileURL creates a FileURL object using the specified URL and request policy pipeline.
 func NewFileURL(url url.URL, p pipeline.Pipeline) FileURL {
 	if p == nil {
@@ -73,6 +92,24 @@ func (f FileURL) Create(ctx context.Context, headers BlobFSHTTPHeaders) (*PathCr
 		nil)
 }
 
+// RenameFrom moves the source file to this URL, within the same account. The source keeps its properties and access
+// control. Pass "*" as ifNoneMatch to fail, rather than replace, when a file already exists here.
+// For more information, see https://docs.microsoft.com/en-us/rest/api/storageservices/datalakestoragegen2/path/create.
+func (f FileURL) RenameFrom(ctx context.Context, source FileURL, ifNoneMatch *string) (*PathCreateResponse, error) {
+	sourceURL := source.URL()
+	renameSource := sourceURL.EscapedPath() // i.e. "/filesystem/path"
+	if sourceURL.RawQuery != "" {
+		renameSource += "?" + sourceURL.RawQuery
+	}
+	return f.fileClient.Create(ctx, f.fileSystemName, f.path, PathResourceNone,
+		nil, PathRenameModeNone, nil, nil, nil, nil,
+		nil, nil, nil, nil, nil,
+		&renameSource, nil, nil, nil, nil, nil,
+		nil, ifNoneMatch, nil, nil, nil,
+		nil, nil, nil, nil, nil,
+		nil)
+}
+
 // Download downloads count bytes of data from the start offset. If count is CountToEnd (0), then data is read from specified offset to the end.
 // The response includes all of the file’s properties. However, passing true for rangeGetContentMD5 returns the range’s MD5 in the ContentMD5
 // response header/property if the range is <= 4MB; the HTTP request fails with 400 (Bad Request) if the requested range is greater than 4MB.
@@ -126,6 +163,16 @@ func (f FileURL) Delete(ctx context.Context) (*PathDeleteResponse, error) {
 		nil, nil, nil)
 }
 
+// DeleteIfUnmodifiedSince removes the file, unless it was modified after lastModified, in which case it fails with
+// 412 (Precondition Failed) and the file is kept.
+func (f FileURL) DeleteIfUnmodifiedSince(ctx context.Context, lastModified time.Time) (*PathDeleteResponse, error) {
+	recursive := false
+	ifUnmodifiedSince := lastModified.UTC().Format(http.TimeFormat)
+	return f.fileClient.Delete(ctx, f.fileSystemName, f.path, &recursive,
+		nil, nil, nil, nil, nil, &ifUnmodifiedSince,
+		nil, nil, nil)
+}
+
 // GetProperties returns the file's metadata and properties.
 // For more information, see https://docs.microsoft.com/rest/api/storageservices/get-file-properties.
 func (f FileURL) GetProperties(ctx context.Context) (*PathGetPropertiesResponse, error) {
@@ -138,6 +185,29 @@ func (f FileURL) GetProperties(ctx context.Context) (*PathGetPropertiesResponse,
 		nil, nil, nil, nil, nil)
 }
 
+// GetAccessControl returns the file's owner, owning group and access control list.
+// For more information, see https://docs.microsoft.com/en-us/rest/api/storageservices/datalakestoragegen2/path/getproperties.
+func (f FileURL) GetAccessControl(ctx context.Context) (BlobFSAccessControl, error) {
+	resp, err := f.fileClient.GetProperties(ctx, f.fileSystemName, f.path, PathGetPropertiesActionGetAccessControl, nil,
+		nil, nil, nil,
+		nil, nil, nil, nil, nil)
+	if err != nil {
+		return BlobFSAccessControl{}, err
+	}
+	return BlobFSAccessControl{Owner: resp.XMsOwner(), Group: resp.XMsGroup(), ACL: resp.XMsACL()}, nil
+}
+
+// SetAccessControl sets the file's owner, owning group and access control list.
+// For more information, see https://docs.microsoft.com/en-us/rest/api/storageservices/datalakestoragegen2/path/update.
+func (f FileURL) SetAccessControl(ctx context.Context, accessControl BlobFSAccessControl) (*PathUpdateResponse, error) {
+	owner, group, acl := accessControl.headers()
+	overrideHttpVerb := "PATCH" // see the comments in AppendData
+	return f.fileClient.Update(ctx, PathUpdateActionSetAccessControl, f.fileSystemName, f.path, nil,
+		nil, nil, nil, nil, nil, nil,
+		nil, nil, nil, nil, nil, owner, group,
+		nil, acl, nil, nil, nil, nil, &overrideHttpVerb, nil, nil, nil, nil)
+}
+
 // UploadRange writes bytes to a file.
 // offset indicates the offset at which to begin writing, in bytes.
 // custom headers are not valid on this operation
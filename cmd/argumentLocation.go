@@ -0,0 +1,161 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// copyFromTos are the from-tos that copy can carry out. Remove and set-properties share copy's arguments, so their
+// from-tos are here too
+var copyFromTos = []common.FromTo{
+	common.EFromTo.LocalBlob(),
+	common.EFromTo.LocalBlobFS(),
+	common.EFromTo.LocalFile(),
+	common.EFromTo.LocalManagedDisk(),
+	common.EFromTo.LocalLocal(),
+	common.EFromTo.BlobLocal(),
+	common.EFromTo.FileLocal(),
+	common.EFromTo.BlobFSLocal(),
+	common.EFromTo.BlobBlob(),
+	common.EFromTo.FileBlob(),
+	common.EFromTo.FileFile(),
+	common.EFromTo.BlobFile(),
+	common.EFromTo.BlobFSBlobFS(),
+	common.EFromTo.S3Blob(),
+	common.EFromTo.GCPBlob(),
+	common.EFromTo.PipeBlob(),
+	common.EFromTo.BlobPipe(),
+	common.EFromTo.BenchmarkBlob(),
+	common.EFromTo.BenchmarkFile(),
+	common.EFromTo.BenchmarkBlobFS(),
+	common.EFromTo.BlobTrash(),
+	common.EFromTo.FileTrash(),
+	common.EFromTo.BlobFSTrash(),
+	common.EFromTo.BlobNone(),
+}
+
+// describeLocation names a kind of location the way errors about the arguments refer to it
+func describeLocation(loc common.Location) string {
+	switch loc {
+	case common.ELocation.Local():
+		return "a local path"
+	case common.ELocation.Pipe():
+		return "a pipe"
+	case common.ELocation.Blob():
+		return "a Blob Storage URL"
+	case common.ELocation.File():
+		return "an Azure Files URL"
+	case common.ELocation.BlobFS():
+		return "an ADLS Gen2 (dfs) URL"
+	case common.ELocation.S3():
+		return "an S3 URL"
+	case common.ELocation.GCP():
+		return "a Google Cloud Storage URL"
+	case common.ELocation.ManagedDisk():
+		return "a managed disk's import URL"
+	case common.ELocation.Benchmark():
+		return "a benchmark source"
+	default:
+		return "an unrecognized location"
+	}
+}
+
+// classifyArgument is inferArgumentLocation, except that a URL which doesn't belong to any service we recognize is
+// Unknown rather than a local path. That's what an emulator, Azure Stack or an S3-compatible service looks like,
+// so it can only be checked against --from-to by the service itself.
+func classifyArgument(arg string) common.Location {
+	loc := inferArgumentLocation(arg)
+	if loc == common.ELocation.Local() && strings.HasPrefix(strings.ToLower(arg), "http") {
+		if u, err := url.Parse(arg); err == nil && u.Scheme != "" && u.Host != "" {
+			return common.ELocation.Unknown()
+		}
+	}
+	return loc
+}
+
+// locationsAgree says whether an argument that looks like actual can be used where --from-to says expected.
+// Blob and dfs endpoints reach the same account, a managed disk's import URL is a blob URL, and a pipe source can just
+// as well be read from a file
+func locationsAgree(actual, expected common.Location) bool {
+	switch {
+	case actual == expected, actual == common.ELocation.Unknown():
+		return true
+	case actual == common.ELocation.Blob() && expected == common.ELocation.BlobFS(),
+		actual == common.ELocation.BlobFS() && expected == common.ELocation.Blob(),
+		actual == common.ELocation.Blob() && expected == common.ELocation.ManagedDisk():
+		return true
+	case actual == common.ELocation.Local() && expected == common.ELocation.Pipe():
+		return true
+	}
+	return false
+}
+
+// validateUserFromTo checks a --from-to the user gave us before anything is done with it: copy has to support it,
+// and the source and destination have to be the kinds of location it names
+func validateUserFromTo(fromTo common.FromTo, src, dst string) error {
+	supported := false
+	for _, ft := range copyFromTos {
+		if ft == fromTo {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("--from-to %s isn't supported: AzCopy can't copy from %s to %s", fromTo,
+			describeLocation(fromTo.From()), describeLocation(fromTo.To()))
+	}
+
+	if err := checkArgumentLocation("source", src, fromTo.From(), fromTo); err != nil {
+		return err
+	}
+	if fromTo.To() == common.ELocation.Unknown() || fromTo.To() == common.ELocation.None() {
+		return nil // deletions and property changes have no destination
+	}
+	return checkArgumentLocation("destination", dst, fromTo.To(), fromTo)
+}
+
+func checkArgumentLocation(which, arg string, expected common.Location, fromTo common.FromTo) error {
+	if actual := classifyArgument(arg); !locationsAgree(actual, expected) {
+		return fmt.Errorf("the %s %q is %s, but --from-to %s says it's %s", which,
+			common.URLStringExtension(arg).RedactSecretQueryParamForLogging(), describeLocation(actual), fromTo,
+			describeLocation(expected))
+	}
+	return nil
+}
+
+// unsupportedPairError explains why no from-to could be inferred for the arguments
+func unsupportedPairError(src, dst string) error {
+	srcLocation, dstLocation := inferArgumentLocation(src), inferArgumentLocation(dst)
+	switch {
+	case srcLocation == common.ELocation.Unknown():
+		return fmt.Errorf("can't tell what kind of location the source %q is. Use --from-to to say",
+			common.URLStringExtension(src).RedactSecretQueryParamForLogging())
+	case dstLocation == common.ELocation.Unknown():
+		return fmt.Errorf("can't tell what kind of location the destination %q is. Use --from-to to say",
+			common.URLStringExtension(dst).RedactSecretQueryParamForLogging())
+	}
+	return fmt.Errorf("copying from %s to %s isn't supported", describeLocation(srcLocation), describeLocation(dstLocation))
+}
@@ -0,0 +1,358 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// commands whose Annotations include this key take a job ID as their argument, so that it can be completed
+const completeJobIDArgAnnotation = "azcopy_complete_job_id_arg"
+
+var jobIDArgAnnotations = map[string]string{completeJobIDArgAnnotation: "true"}
+
+// the hidden command that the completion scripts call to get the suggestions for the word being completed
+const completeCmdName = "__complete"
+
+// the completion scripts call the completeCmdName command with the words typed so far, the last being the one to complete,
+// and offer whatever it prints, one suggestion per line. If it prints nothing, the shell falls back to completing file names
+var completionScripts = map[string]string{
+	"bash": `# bash completion for azcopy
+_azcopy_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    [[ "${cur}" == "=" ]] && cur=""
+    local IFS=$'\n'
+    local suggestions
+    suggestions=$("${COMP_WORDS[0]}" ` + completeCmdName + ` "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+    COMPREPLY=( $(compgen -W "${suggestions}" -- "${cur}") )
+}
+complete -o default -F _azcopy_complete azcopy
+`,
+
+	"zsh": `#compdef azcopy
+# zsh completion for azcopy
+_azcopy() {
+    local -a suggestions
+    suggestions=("${(@f)$(${words[1]} ` + completeCmdName + ` "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    suggestions=(${suggestions:#})
+    if (( ${#suggestions} )); then
+        compadd -- ${suggestions}
+    else
+        _files
+    fi
+}
+compdef _azcopy azcopy
+`,
+
+	"fish": `# fish completion for azcopy
+function __azcopy_complete
+    set -l tokens (commandline -opc)
+    set -l program $tokens[1]
+    set -e tokens[1]
+    $program ` + completeCmdName + ` $tokens (commandline -ct) 2>/dev/null | string match -v ''
+end
+complete -c azcopy -a '(__azcopy_complete)'
+`,
+
+	"powershell": `# PowerShell completion for azcopy
+Register-ArgumentCompleter -Native -CommandName 'azcopy', 'azcopy.exe' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | Where-Object { $_.Extent.StartOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') {
+        # PowerShell drops empty arguments to native commands, so the empty word being completed is passed quoted
+        $words += '""'
+    }
+    & $commandAst.CommandElements[0].ToString() ` + completeCmdName + ` @words 2>$null | Where-Object { $_ -ne '' } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`,
+}
+
+var completionCmd = &cobra.Command{
+	Use:       "completion [bash|zsh|fish|powershell]",
+	Short:     completionCmdShortDescription,
+	Long:      completionCmdLongDescription,
+	Example:   completionCmdExample,
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("completion command requires the name of the shell")
+		}
+		if _, ok := completionScripts[strings.ToLower(args[0])]; !ok {
+			return fmt.Errorf("unsupported shell '%s'. The choices include: bash, zsh, fish, powershell", args[0])
+		}
+		return nil
+	},
+	// generating completions doesn't need the transfer engine, so don't start it
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	Run: func(cmd *cobra.Command, args []string) {
+		glcm.Exit(func(format common.OutputFormat) string {
+			return completionScripts[strings.ToLower(args[0])]
+		}, common.EExitCode.Success())
+	},
+}
+
+var completeCmd = &cobra.Command{
+	Use:    completeCmdName + " [words typed so far]",
+	Hidden: true,
+	// the words are those of another command line, so they mustn't be parsed as our own flags
+	DisableFlagParsing: true,
+	PersistentPreRunE:  func(cmd *cobra.Command, args []string) error { return nil },
+	Run: func(cmd *cobra.Command, args []string) {
+		suggestions := completeWords(rootCmd, args)
+		glcm.Exit(func(format common.OutputFormat) string {
+			return strings.Join(suggestions, "\n")
+		}, common.EExitCode.Success())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(completeCmd)
+}
+
+// completeWords returns the suggestions for the last of the given words, which are those typed after "azcopy"
+func completeWords(root *cobra.Command, words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	toComplete := words[len(words)-1]
+	typed := words[:len(words)-1]
+	if toComplete == `""` {
+		toComplete = "" // see the PowerShell script
+	}
+
+	// bash splits --flag=value into three words: the flag, the '=' and the value
+	if toComplete == "=" {
+		typed = append(typed, toComplete)
+		toComplete = ""
+	}
+	valueOfFlag := ""
+	if n := len(typed); n >= 2 && typed[n-1] == "=" {
+		valueOfFlag = typed[n-2]
+		typed = typed[:n-1]
+	}
+
+	cmd, argsAfterCmd, err := root.Find(typed)
+	if err != nil || cmd == nil {
+		return nil
+	}
+	positionalArgs := countPositionalArgs(cmd, argsAfterCmd)
+
+	// the value of a flag
+	switch {
+	case valueOfFlag != "":
+		return filterByPrefix(flagValueSuggestions(cmd, valueOfFlag), toComplete)
+	case strings.HasPrefix(toComplete, "--") && strings.Contains(toComplete, "="):
+		flagName := toComplete[:strings.Index(toComplete, "=")]
+		var suggestions []string
+		for _, v := range flagValueSuggestions(cmd, flagName) {
+			suggestions = append(suggestions, flagName+"="+v)
+		}
+		return filterByPrefix(suggestions, toComplete)
+	case len(typed) > 0 && strings.HasPrefix(typed[len(typed)-1], "--") && !strings.Contains(typed[len(typed)-1], "="):
+		if f := lookupFlag(cmd, typed[len(typed)-1]); f != nil && f.NoOptDefVal == "" {
+			return filterByPrefix(flagValueSuggestions(cmd, typed[len(typed)-1]), toComplete)
+		}
+	}
+
+	// the name of a flag
+	if strings.HasPrefix(toComplete, "-") {
+		var suggestions []string
+		addFlags := func(f *pflag.Flag) {
+			if !f.Hidden {
+				suggestions = append(suggestions, "--"+f.Name)
+			}
+		}
+		cmd.NonInheritedFlags().VisitAll(addFlags)
+		cmd.InheritedFlags().VisitAll(addFlags)
+		sort.Strings(suggestions)
+		return filterByPrefix(suggestions, toComplete)
+	}
+
+	// a sub-command, or the command's argument
+	var suggestions []string
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() {
+			suggestions = append(suggestions, sub.Name())
+		}
+	}
+	if len(suggestions) == 0 {
+		if _, ok := cmd.Annotations[completeJobIDArgAnnotation]; ok && positionalArgs == 0 {
+			suggestions = existingJobIDs()
+		} else if positionalArgs == 0 {
+			suggestions = cmd.ValidArgs
+		}
+	}
+	return filterByPrefix(suggestions, toComplete)
+}
+
+// countPositionalArgs counts the arguments that aren't flags, or the values of flags
+func countPositionalArgs(cmd *cobra.Command, args []string) int {
+	count := 0
+	for i := 0; i < len(args); i++ {
+		switch {
+		case strings.HasPrefix(args[i], "--") && !strings.Contains(args[i], "="):
+			if f := lookupFlag(cmd, args[i]); f != nil && f.NoOptDefVal == "" {
+				i++ // skip its value
+			}
+		case strings.HasPrefix(args[i], "-"):
+		default:
+			count++
+		}
+	}
+	return count
+}
+
+// lookupFlag finds the flag, given as it is typed (e.g. --from-to), that applies to the command
+func lookupFlag(cmd *cobra.Command, typedFlag string) *pflag.Flag {
+	name := strings.TrimPrefix(typedFlag, "--")
+	if f := cmd.NonInheritedFlags().Lookup(name); f != nil {
+		return f
+	}
+	return cmd.InheritedFlags().Lookup(name)
+}
+
+// flagValueSuggestions returns the values that the flag, given as it is typed, can be set to, if there are only a few
+func flagValueSuggestions(cmd *cobra.Command, typedFlag string) []string {
+	if lookupFlag(cmd, typedFlag) == nil {
+		return nil
+	}
+
+	switch strings.TrimPrefix(typedFlag, "--") {
+	case "from-to":
+		return enumSymbols(common.EFromTo, "Unknown")
+	case "overwrite":
+		return mapStrings(enumSymbols(common.EOverwriteOption), strings.ToLower)
+	case "delete-destination":
+		return mapStrings(enumSymbols(common.EDeleteDestination), strings.ToLower)
+	case "output-type":
+		return mapStrings(enumSymbols(common.EOutputFormat, "None"), strings.ToLower)
+	case "blob-listing-strategy":
+		return mapStrings(enumSymbols(common.EBlobListingStrategy), strings.ToLower)
+	case "blob-type":
+		return enumSymbols(common.EBlobType)
+	case "block-blob-tier":
+		return enumSymbols(common.EBlockBlobTier)
+	case "page-blob-tier":
+		return enumSymbols(common.EPageBlobTier)
+	case "check-md5":
+		return enumSymbols(common.EHashValidationOption)
+	case "check-free-space":
+		return enumSymbols(common.EFreeSpaceCheckOption)
+	case "flush-policy":
+		return enumSymbols(common.EFlushPolicy)
+	case "log-level":
+		return mapStrings(enumSymbols(common.ELogLevel), strings.ToUpper)
+	case "delete-snapshots":
+		return mapStrings(enumSymbols(common.EDeleteSnapshotsOption, "None"), strings.ToLower)
+	case "mode":
+		return []string{"upload", "download"}
+	case "compression-type":
+		return []string{"DISABLED", "LZ4"}
+	case "with-status":
+		switch cmd.Name() {
+		case "show":
+			return enumSymbols(common.ETransferStatus, "NotStarted")
+		case "history":
+			// only jobs that have finished are recorded in the history
+			return enumSymbols(common.EJobStatus, "InProgress", "Paused", "Cancelling")
+		default:
+			return enumSymbols(common.EJobStatus)
+		}
+	}
+	return nil
+}
+
+// enumSymbols returns the names of the values of one of common's enums (e.g. common.EFromTo), which are what its Parse
+// accepts, in alphabetical order. Those in except aren't included
+func enumSymbols(enumVar interface{}, except ...string) []string {
+	t := reflect.TypeOf(enumVar)
+	var symbols []string
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i) // the values are the methods that take no arguments, other than the receiver, and return the enum's type
+		if m.Type.NumIn() != 1 || m.Type.NumOut() != 1 || m.Type.Out(0) != t || containsString(except, m.Name) {
+			continue
+		}
+		symbols = append(symbols, m.Name)
+	}
+	return symbols
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func mapStrings(list []string, f func(string) string) []string {
+	for i := range list {
+		list[i] = f(list[i])
+	}
+	return list
+}
+
+// existingJobIDs returns the IDs of the jobs that have plan files, most recent first
+func existingJobIDs() []string {
+	files, err := ioutil.ReadDir(azcopyJobPlanFolder)
+	if err != nil {
+		return nil
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().After(files[j].ModTime()) })
+
+	var jobIDs []string
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if f.IsDir() || !strings.Contains(f.Name(), ".steV") {
+			continue
+		}
+		jobID := strings.Split(f.Name(), "--")[0]
+		if _, err := common.ParseJobID(jobID); err != nil || seen[jobID] {
+			continue
+		}
+		seen[jobID] = true
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs
+}
+
+func filterByPrefix(suggestions []string, prefix string) []string {
+	var filtered []string
+	for _, s := range suggestions {
+		if strings.HasPrefix(s, prefix) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
@@ -27,10 +27,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"net/url"
 	"os"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -45,6 +48,7 @@ import (
 )
 
 const pipingUploadParallelism = 5
+const pipingDownloadParallelism = 5
 const pipingDefaultBlockSize = 8 * 1024 * 1024
 
 // For networking throughput in Mbps, (and only for networking), we divide by 1000*1000 (not 1024 * 1024) because
@@ -58,12 +62,26 @@ const base10Mega = 1000 * 1000
 
 const pipeLocation = "~pipe~"
 
+const deleteSourceAfterTransferFlagName = "delete-source-after-transfer"
+
+// stdioArgument is what a source or destination argument of "-" stands for: stdin or stdout, as with other command line tools
+const stdioArgument = "-"
+
+// fromStdioArgument returns the pipe location for "-", and any other argument as it is
+func fromStdioArgument(arg string) string {
+	if arg == stdioArgument {
+		return pipeLocation
+	}
+	return arg
+}
+
 // represents the raw copy command input from the user
 type rawCopyCmdArgs struct {
 	// from arguments
 	src    string
 	dst    string
 	fromTo string
+	jobID  string
 	//blobUrlForRedirection string
 
 	// new include/exclude only apply to file names
@@ -72,19 +90,41 @@ type rawCopyCmdArgs struct {
 	exclude               string
 	includePath           string // NOTE: This gets handled like list-of-files! It may LOOK like a bug, but it is not.
 	excludePath           string
+	includeDirectory      string
+	excludeDirectory      string
 	includeFileAttributes string
 	excludeFileAttributes string
 	includeBefore         string
 	includeAfter          string
+	skipLargerThan        string
+	skipSmallerThan       string
+	transferTimeout       string
 	legacyInclude         string // used only for warnings
 	legacyExclude         string // used only for warnings
 	listOfVersionIDs      string
 
+	// delete each source file once it has been transferred, i.e. move rather than copy
+	deleteSourceAfterTransfer bool
+
 	// filters from flags
 	listOfFilesToCopy string
-	recursive         bool
-	followSymlinks    bool
-	autoDecompress    bool
+	// keep reading the list of files as it grows, adding to the running job, until it's sealed
+	followListOfFiles bool
+	// more destinations for an upload, separated by ';'
+	alsoTo         string
+	recursive      bool
+	followSymlinks bool
+	// fail the job, instead of skipping them, if followed symlinks have no target
+	failOnDanglingSymlinks bool
+	excludeHidden          bool
+	includeHidden          bool
+	ignoreFileName         string
+	perDirIgnoreFiles      bool
+	failedTransfersFile    string
+	retryFailedPasses      int
+	autoDecompress         bool
+	// fail the job if anything is skipped, other than by a filter, or any property of a source isn't preserved
+	strict bool
 	// forceWrite flag is used to define the User behavior
 	// to overwrite the existing blobs or not.
 	forceWrite      string
@@ -93,6 +133,13 @@ type rawCopyCmdArgs struct {
 	// options from flags
 	blockSizeMB              float64
 	metadata                 string
+	metadataRules            string
+	storeSourceLMT           bool
+	expandDstTemplate        bool
+	pathRewrite              string
+	duplicateDestination     string
+	toLowercase              bool
+	toUppercase              bool
 	contentType              string
 	contentEncoding          string
 	contentDisposition       string
@@ -100,9 +147,14 @@ type rawCopyCmdArgs struct {
 	cacheControl             string
 	noGuessMimeType          bool
 	preserveLastModifiedTime bool
+	preserveLastAccessTime   bool
 	putMd5                   bool
 	md5ValidationOption      string
+	flushPolicy              string
+	freeSpaceCheck           string
 	CheckLength              bool
+	estimate                 bool
+	priceSheet               string
 	deleteSnapshotsOption    string
 
 	blobTags string
@@ -117,11 +169,21 @@ type rawCopyCmdArgs struct {
 	// Opt-in flag to persist SMB ACLs to Azure Files.
 	preserveSMBPermissions bool
 	preserveOwner          bool // works in conjunction with preserveSmbPermissions
+	// Opt-in flag to persist POSIX permissions to ADLS Gen2.
+	preservePermissions bool
+	// file mapping local user and group IDs to Azure AD object IDs, for preserving owners in ADLS Gen2
+	posixIDMap string
+	// Opt-in flag to only apply the permissions and properties of the sources to destinations that already exist.
+	permissionsOnly bool
 	// Opt-in flag to persist additional SMB properties to Azure Files. Named ...info instead of ...properties
 	// because the latter was similar enough to preserveSMBPermissions to induce user error
 	preserveSMBInfo bool
 	// Opt-in flag to preserve the blob index tags during service to service transfer.
 	s2sPreserveBlobTags bool
+	// AAD tenant of the source for service to service copy, when it differs from the destination's.
+	s2sSourceTenantID string
+	// whether to check the permissions on the source and destination before enumerating, the default value is true.
+	preflightProbe bool
 	// Flag to enable Window's special privileges
 	backupMode bool
 	// whether user wants to preserve full properties during service to service copy, the default value is true.
@@ -162,6 +224,24 @@ func (raw *rawCopyCmdArgs) parsePatterns(pattern string) (cookedPatterns []strin
 	return
 }
 
+// dedupeIncludePaths drops repeated include paths, so that every path is only listed once.
+// Paths that differ only in surrounding slashes refer to the same place, and are treated as duplicates.
+func dedupeIncludePaths(paths []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(paths))
+	for _, path := range paths {
+		key := strings.Trim(path, common.AZCOPY_PATH_SEPARATOR_STRING)
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		unique = append(unique, path)
+	}
+
+	return unique
+}
+
 // blocSizeInBytes converts a FLOATING POINT number of MiB, to a number of bytes
 // A non-nil error is returned if the conversion is not possible to do accurately (e.g. it comes out of a fractional number of bytes)
 // The purpose of using floating point is to allow specialist users (e.g. those who want small block sizes to tune their read IOPS)
@@ -228,7 +308,8 @@ func (raw rawCopyCmdArgs) stripTrailingWildcardOnRemoteSource(location common.Lo
 
 func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 	cooked := cookedCopyCmdArgs{
-		jobID: azcopyCurrentJobID,
+		jobID:      azcopyCurrentJobID,
+		discovered: &discoveryCounter{},
 	}
 
 	err := cooked.logVerbosity.Parse(raw.logVerbosity)
@@ -243,6 +324,13 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 		azcopyScanningLogger.CloseLog()
 	})
 
+	// expand the job variables, e.g. {date}, before anything looks at the destination.
+	// Only on request, since braces are legal in blob names and local paths.
+	if raw.expandDstTemplate {
+		hostname, _ := os.Hostname()
+		raw.dst = gCopyUtil.expandDestinationTemplate(raw.dst, cooked.jobID, time.Now().UTC(), hostname)
+	}
+
 	fromTo, err := validateFromTo(raw.src, raw.dst, raw.fromTo) // TODO: src/dst
 	if err != nil {
 		return cooked, err
@@ -281,10 +369,34 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 	if err != nil {
 		return cooked, err
 	}
+	if err = validateShareSnapshots(fromTo, cooked.source, cooked.destination); err != nil {
+		return cooked, err
+	}
 
 	cooked.fromTo = fromTo
 	cooked.recursive = raw.recursive
 	cooked.followSymlinks = raw.followSymlinks
+	if raw.failOnDanglingSymlinks && !cooked.followSymlinks {
+		return cooked, fmt.Errorf("%s can only be used with follow-symlinks", common.FailOnDanglingSymlinksFlagName)
+	}
+	if cooked.followSymlinks {
+		cooked.danglingSymlinks = newDanglingSymlinkTracker(raw.failOnDanglingSymlinks)
+	}
+	// hidden files are only a notion of the local disk, so they are only left out of uploads
+	if raw.excludeHidden && !raw.includeHidden && fromTo.From() == common.ELocation.Local() {
+		cooked.hiddenFiles = newHiddenFileFilter(cooked.source.ValueLocal())
+	}
+	if fromTo.From() == common.ELocation.Local() {
+		if cooked.ignoreFiles, err = newIgnoreFileFilter(cooked.source.ValueLocal(), raw.ignoreFileName, raw.perDirIgnoreFiles); err != nil {
+			return cooked, fmt.Errorf("cannot read the ignore file %s: %w", raw.ignoreFileName, err)
+		}
+	}
+	cooked.failedTransfersFile = raw.failedTransfersFile
+	if raw.retryFailedPasses < 0 {
+		return cooked, fmt.Errorf("the number of retry passes for failed transfers cannot be negative")
+	}
+	cooked.retryFailedPasses = raw.retryFailedPasses
+	cooked.strict = raw.strict
 	cooked.forceIfReadOnly = raw.forceIfReadOnly
 	if err = validateForceIfReadOnly(cooked.forceIfReadOnly, cooked.fromTo); err != nil {
 		return cooked, err
@@ -295,6 +407,9 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 	if err != nil {
 		return cooked, err
 	}
+	if cooked.forceWrite == common.EOverwriteOption.IfVersioned() && cooked.fromTo.To() != common.ELocation.Blob() {
+		return cooked, fmt.Errorf("overwrite mode %s only applies to blob destinations, since only blobs keep previous versions", cooked.forceWrite)
+	}
 	allowAutoDecompress := fromTo == common.EFromTo.BlobLocal() || fromTo == common.EFromTo.FileLocal()
 	if raw.autoDecompress && !allowAutoDecompress {
 		return cooked, errors.New("automatic decompression is only supported for downloads from Blob and Azure Files") // as at Sept 2019, our ADLS Gen 2 Swagger does not include content-encoding for directory (path) listings so we can't support it there
@@ -333,6 +448,10 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 	if err != nil {
 		return cooked, err
 	}
+	if cooked.pageBlobTier != common.EPageBlobTier.None() &&
+		(cooked.blobType == common.EBlobType.BlockBlob() || cooked.blobType == common.EBlobType.AppendBlob()) {
+		return cooked, fmt.Errorf("page-blob-tier cannot be used with blob-type %s, only with page blobs", cooked.blobType)
+	}
 
 	// Everything uses the new implementation of list-of-files now.
 	// This handles both list-of-files and include-path as a list enumerator.
@@ -377,7 +496,19 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 			}
 		}
 
-		if f != nil {
+		if f != nil && raw.followListOfFiles {
+			checkBOM := false
+			err := followLines(f, followListPollInterval, func(v string) {
+				if !checkBOM {
+					v = strings.TrimPrefix(v, utf8BOM)
+					checkBOM = true
+				}
+				addToChannel(v, "list-of-files")
+			})
+			if err != nil {
+				glcm.Error(fmt.Sprintf("cannot read the list of files %s: %s", raw.listOfFilesToCopy, err))
+			}
+		} else if f != nil {
 			scanner := bufio.NewScanner(f)
 			checkBOM := false
 			headerLineNum := 0
@@ -416,7 +547,7 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 		}
 
 		// This occurs much earlier than the other include or exclude filters. It would be preferable to move them closer later on in the refactor.
-		includePathList := raw.parsePatterns(raw.includePath)
+		includePathList := dedupeIncludePaths(raw.parsePatterns(raw.includePath))
 
 		for _, v := range includePathList {
 			addToChannel(v, "include-path")
@@ -429,6 +560,28 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 		return cooked, errors.New("cannot combine list of files and include path")
 	}
 
+	if raw.followListOfFiles && raw.listOfFilesToCopy == "" {
+		return cooked, errors.New("follow-list-of-files needs a list-of-files to follow")
+	}
+	cooked.followListOfFiles = raw.followListOfFiles
+
+	if raw.alsoTo != "" {
+		if !cooked.fromTo.IsUpload() || cooked.isRedirection() {
+			return cooked, errors.New("also-to is only supported when uploading files")
+		}
+		for _, dst := range strings.Split(raw.alsoTo, ";") {
+			if inferArgumentLocation(dst) != cooked.fromTo.To() {
+				return cooked, fmt.Errorf("the also-to destination %s must be the same kind of location as the main destination, %s",
+					common.URLStringExtension(dst).RedactSecretQueryParamForLogging(), cooked.fromTo.To())
+			}
+			resource, err := SplitResourceString(dst, cooked.fromTo.To())
+			if err != nil {
+				return cooked, err
+			}
+			cooked.alsoTo = append(cooked.alsoTo, resource)
+		}
+	}
+
 	if raw.listOfFilesToCopy != "" || raw.includePath != "" {
 		cooked.listOfFilesChannel = listChan
 	}
@@ -453,6 +606,14 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 		cooked.includeAfter = &parsedIncludeAfter
 	}
 
+	if cooked.skipLargerThan, cooked.skipSmallerThan, err = parseSizeLimits(raw.skipLargerThan, raw.skipSmallerThan); err != nil {
+		return cooked, err
+	}
+
+	if cooked.transferTimeout, err = parseTransferTimeout(raw.transferTimeout); err != nil {
+		return cooked, err
+	}
+
 	versionsChan := make(chan string)
 	var filePtr *os.File
 	// Get file path from user which would contain list of all versionIDs
@@ -498,11 +659,21 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 	cooked.contentLanguage = raw.contentLanguage
 	cooked.contentDisposition = raw.contentDisposition
 	cooked.cacheControl = raw.cacheControl
+	if err = validateHTTPHeaderLengths(&cooked); err != nil {
+		return cooked, err
+	}
+	if len(cooked.metadata) > ste.MetadataMaxBytes {
+		return cooked, fmt.Errorf("the metadata given is %d bytes long, but the service accepts at most %d bytes of metadata", len(cooked.metadata), ste.MetadataMaxBytes)
+	}
 	cooked.noGuessMimeType = raw.noGuessMimeType
 	cooked.preserveLastModifiedTime = raw.preserveLastModifiedTime
+	cooked.preserveLastAccessTime = raw.preserveLastAccessTime
+	if cooked.preserveLastAccessTime && cooked.fromTo != common.EFromTo.BlobLocal() {
+		return cooked, fmt.Errorf("preserve-last-access-time is only supported when downloading from Blob storage")
+	}
 	cooked.includeDirectoryStubs = raw.includeDirectoryStubs
 
-	if cooked.fromTo.To() != common.ELocation.Blob() && raw.blobTags != "" {
+	if cooked.fromTo.To() != common.ELocation.Blob() && cooked.fromTo != common.EFromTo.BlobNone() && raw.blobTags != "" {
 		return cooked, errors.New("blob tags can only be set when transferring to blob storage")
 	}
 	blobTags := common.ToCommonBlobTagsMap(raw.blobTags)
@@ -525,6 +696,14 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 		}
 	}
 
+	if raw.s2sSourceTenantID != "" {
+		if cooked.fromTo != common.EFromTo.BlobBlob() {
+			return cooked, errors.New("s2s-source-tenant-id is only supported when copying from blob storage to blob storage")
+		}
+		cooked.s2sSourceTenantID = raw.s2sSourceTenantID
+	}
+	cooked.preflightProbe = raw.preflightProbe
+
 	// Make sure the given input is the one of the enums given by the blob SDK
 	err = cooked.deleteSnapshotsOption.Parse(raw.deleteSnapshotsOption)
 	if err != nil {
@@ -541,6 +720,14 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 		return cooked, err
 	}
 	globalBlobFSMd5ValidationOption = cooked.md5ValidationOption // workaround, to avoid having to pass this all the way through the chain of methods in enumeration, just for one weird and (presumably) temporary workaround
+	err = cooked.flushPolicy.Parse(raw.flushPolicy)
+	if err != nil {
+		return cooked, err
+	}
+	err = cooked.freeSpaceCheck.Parse(raw.freeSpaceCheck)
+	if err != nil {
+		return cooked, err
+	}
 
 	cooked.CheckLength = raw.CheckLength
 	// length of devnull will be 0, thus this will always fail unless downloading an empty file
@@ -556,13 +743,34 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 	if err = validatePreserveSMBPropertyOption(raw.preserveSMBPermissions, cooked.fromTo, &cooked.forceWrite, "preserve-smb-permissions"); err != nil {
 		return cooked, err
 	}
-	if err = validatePreserveOwner(raw.preserveOwner, cooked.fromTo); err != nil {
+	if err = validatePreservePermissions(raw.preservePermissions, raw.preserveSMBPermissions, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+	if err = validatePreserveOwner(raw.preserveOwner, cooked.fromTo); err != nil && !raw.preservePermissions {
 		return cooked, err
 	}
 	cooked.preserveSMBPermissions = common.NewPreservePermissionsOption(raw.preserveSMBPermissions, raw.preserveOwner, cooked.fromTo)
+	if raw.preservePermissions {
+		// POSIX permissions travel in the same job order field as SMB ones. The IDs of local owners only mean something
+		// to the account if the identities are shared, so their preservation follows --preserve-owner in uploads too.
+		if raw.preserveOwner {
+			cooked.preserveSMBPermissions = common.EPreservePermissionsOption.OwnershipAndACLs()
+		} else {
+			cooked.preserveSMBPermissions = common.EPreservePermissionsOption.ACLsOnly()
+		}
+	}
+
+	if raw.posixIDMap != "" {
+		if !raw.preservePermissions {
+			return cooked, errors.New("posix-id-map is only used with preserve-permissions")
+		}
+		if cooked.posixIDMap, err = common.ParsePOSIXIDMapFile(raw.posixIDMap); err != nil {
+			return cooked, fmt.Errorf("cannot read the POSIX ID map: %w", err)
+		}
+	}
 
 	cooked.preserveSMBInfo = raw.preserveSMBInfo
-	if err = validatePreserveSMBPropertyOption(cooked.preserveSMBInfo, cooked.fromTo, &cooked.forceWrite, "preserve-smb-info"); err != nil {
+	if err = validatePreserveSMBPropertyOption(cooked.preserveSMBInfo, cooked.fromTo, &cooked.forceWrite, common.PreserveSMBInfoFlagName); err != nil {
 		return cooked, err
 	}
 
@@ -570,6 +778,16 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 		return cooked, err
 	}
 
+	cooked.permissionsOnly = raw.permissionsOnly
+	if err = validatePermissionsOnly(cooked.permissionsOnly, cooked.fromTo, cooked.preserveSMBPermissions.IsTruthy()); err != nil {
+		return cooked, err
+	}
+
+	cooked.deleteSourceAfterTransfer = raw.deleteSourceAfterTransfer
+	if err = validateDeleteSourceAfterTransfer(cooked.deleteSourceAfterTransfer, cooked.fromTo, cooked.permissionsOnly, cooked.source); err != nil {
+		return cooked, err
+	}
+
 	cooked.backupMode = raw.backupMode
 	if err = validateBackupMode(cooked.backupMode, cooked.fromTo); err != nil {
 		return cooked, err
@@ -590,8 +808,8 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 			cooked.pageBlobTier != common.EPageBlobTier.None() {
 			return cooked, fmt.Errorf("blob-tier is not supported while uploading to ADLS Gen 2")
 		}
-		if cooked.preserveSMBPermissions.IsTruthy() {
-			return cooked, fmt.Errorf("preserve-smb-permissions is not supported while uploading to ADLS Gen 2")
+		if cooked.preserveSMBPermissions.IsTruthy() && !raw.preservePermissions {
+			return cooked, fmt.Errorf("preserve-smb-permissions is not supported while uploading to ADLS Gen 2, use preserve-permissions instead")
 		}
 		if cooked.s2sPreserveProperties {
 			return cooked, fmt.Errorf("s2s-preserve-properties is not supported while uploading")
@@ -621,6 +839,10 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 		if cooked.s2sSourceChangeValidation {
 			return cooked, fmt.Errorf("s2s-detect-source-changed is not supported while uploading to Blob Storage")
 		}
+	case common.EFromTo.LocalManagedDisk():
+		if err = cooked.validateManagedDiskUpload(); err != nil {
+			return cooked, err
+		}
 	case common.EFromTo.LocalFile():
 		if cooked.preserveLastModifiedTime {
 			return cooked, fmt.Errorf("preserve-last-modified-time is not supported while uploading")
@@ -672,12 +894,32 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 		if cooked.s2sSourceChangeValidation {
 			return cooked, fmt.Errorf("s2s-detect-source-changed is not supported while downloading")
 		}
+	case common.EFromTo.LocalLocal():
+		if cooked.blobType != common.EBlobType.Detect() ||
+			cooked.blockBlobTier != common.EBlockBlobTier.None() ||
+			cooked.pageBlobTier != common.EPageBlobTier.None() {
+			return cooked, fmt.Errorf("blob-type and blob-tier are not supported while copying between local paths")
+		}
+		if cooked.noGuessMimeType {
+			return cooked, fmt.Errorf("no-guess-mime-type is not supported while copying between local paths")
+		}
+		if len(cooked.contentType) > 0 || len(cooked.contentEncoding) > 0 || len(cooked.contentLanguage) > 0 || len(cooked.contentDisposition) > 0 || len(cooked.cacheControl) > 0 || len(cooked.metadata) > 0 {
+			return cooked, fmt.Errorf("content-type, content-encoding, content-language, content-disposition, cache-control, or metadata is not supported while copying between local paths")
+		}
+		if cooked.s2sPreserveProperties || cooked.s2sPreserveAccessTier || cooked.s2sSourceChangeValidation ||
+			cooked.s2sInvalidMetadataHandleOption != common.DefaultInvalidMetadataHandleOption {
+			return cooked, fmt.Errorf("the s2s flags are not supported while copying between local paths")
+		}
+		if err = validateLocalCopyPaths(cooked.source.Value, cooked.destination.Value); err != nil {
+			return cooked, err
+		}
 	case common.EFromTo.BlobFile(),
 		common.EFromTo.S3Blob(),
 		common.EFromTo.BlobBlob(),
 		common.EFromTo.FileBlob(),
 		common.EFromTo.FileFile(),
-		common.EFromTo.GCPBlob():
+		common.EFromTo.GCPBlob(),
+		common.EFromTo.BlobFSBlobFS():
 		if cooked.preserveLastModifiedTime {
 			return cooked, fmt.Errorf("preserve-last-modified-time is not supported while copying from service to service")
 		}
@@ -707,6 +949,12 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 	if err = validateMd5Option(cooked.md5ValidationOption, cooked.fromTo); err != nil {
 		return cooked, err
 	}
+	if err = validateFlushPolicy(cooked.flushPolicy, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+	if cooked.estimator, err = raw.cookEstimator(&cooked); err != nil {
+		return cooked, err
+	}
 
 	// Because of some of our defaults, these must live down here and can't be properly checked.
 	// TODO: Remove the above checks where they can't be done.
@@ -715,6 +963,59 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 	cooked.s2sPreserveAccessTier = raw.s2sPreserveAccessTier
 	cooked.s2sSourceChangeValidation = raw.s2sSourceChangeValidation
 
+	if raw.pathRewrite != "" {
+		if cooked.fromTo.To() == common.ELocation.Unknown() || cooked.fromTo.To() == common.ELocation.None() || cooked.fromTo.To() == common.ELocation.Pipe() {
+			return cooked, errors.New("path-rewrite can only be used when the destination is a location")
+		}
+		if cooked.pathRewriter, err = newPathRewriter(raw.pathRewrite); err != nil {
+			return cooked, err
+		}
+	}
+
+	var duplicateDestination common.DuplicateDestinationOption
+	if err = duplicateDestination.Parse(raw.duplicateDestination); err != nil {
+		return cooked, err
+	}
+	if duplicateDestination == common.EDuplicateDestinationOption.Auto() {
+		duplicateDestination = common.EDuplicateDestinationOption.None()
+		if len(cooked.pathRewriter) > 0 {
+			duplicateDestination = common.EDuplicateDestinationOption.Fail()
+		}
+	}
+	if duplicateDestination != common.EDuplicateDestinationOption.None() {
+		cooked.duplicateDestinations = newDuplicateDestinationDetector(duplicateDestination == common.EDuplicateDestinationOption.Rename())
+	}
+
+	if raw.toLowercase || raw.toUppercase {
+		if raw.toLowercase && raw.toUppercase {
+			return cooked, errors.New("to-lowercase and to-uppercase cannot be used together")
+		}
+		if !cooked.fromTo.To().IsRemote() && cooked.fromTo.To() != common.ELocation.Local() {
+			return cooked, errors.New("to-lowercase and to-uppercase can only be used when the destination is a location")
+		}
+		cooked.nameCaseConverter = newDestinationNameCaseConverter(raw.toUppercase)
+		if cooked.fromTo.From() == common.ELocation.Local() && len(cooked.pathRewriter) == 0 {
+			// local directories can be listed cheaply, so that renamed files can avoid the names of files that are yet to come
+			cooked.nameCaseConverter.listSourceDir = localSourceDirLister(cooked.source.ValueLocal())
+		}
+	}
+
+	if raw.metadataRules != "" {
+		if !cooked.fromTo.To().IsRemote() {
+			return cooked, errors.New("metadata-rules can only be used when transferring to Azure Storage")
+		}
+		if cooked.metadataRules, err = common.ParseMetadataRules(raw.metadataRules); err != nil {
+			return cooked, err
+		}
+		// The rules run while enumerating, so the source's metadata must be known by then.
+		cooked.s2sGetPropertiesInBackend = false
+	}
+
+	cooked.storeSourceLMT = raw.storeSourceLMT
+	if err = validateStoreSourceLMT(cooked.storeSourceLMT, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+
 	// If the user has provided some input with excludeBlobType flag, parse the input.
 	if len(raw.excludeBlobType) > 0 {
 		// Split the string using delimiter ';' and parse the individual blobType
@@ -738,6 +1039,8 @@ func (raw rawCopyCmdArgs) cook() (cookedCopyCmdArgs, error) {
 	cooked.includePatterns = raw.parsePatterns(raw.include)
 	cooked.excludePatterns = raw.parsePatterns(raw.exclude)
 	cooked.excludePathPatterns = raw.parsePatterns(raw.excludePath)
+	cooked.includeDirectories = raw.parsePatterns(raw.includeDirectory)
+	cooked.excludeDirectories = raw.parsePatterns(raw.excludeDirectory)
 
 	if (raw.includeFileAttributes != "" || raw.excludeFileAttributes != "") && fromTo.From() != common.ELocation.Local() {
 		return cooked, errors.New("cannot check file attributes on remote objects")
@@ -768,6 +1071,9 @@ func (raw *rawCopyCmdArgs) setMandatoryDefaults() {
 	raw.blockBlobTier = common.EBlockBlobTier.None().String()
 	raw.pageBlobTier = common.EPageBlobTier.None().String()
 	raw.md5ValidationOption = common.DefaultHashValidationOption.String()
+	raw.flushPolicy = common.EFlushPolicy.Never().String()
+	raw.freeSpaceCheck = common.EFreeSpaceCheckOption.Warn().String()
+	raw.duplicateDestination = common.EDuplicateDestinationOption.Auto().String()
 	raw.s2sInvalidMetadataHandleOption = common.DefaultInvalidMetadataHandleOption.String()
 	raw.forceWrite = common.EOverwriteOption.True().String()
 	raw.preserveOwner = common.PreserveOwnerDefault
@@ -786,9 +1092,17 @@ func validateForceIfReadOnly(toForce bool, fromTo common.FromTo) error {
 }
 
 func validatePreserveSMBPropertyOption(toPreserve bool, fromTo common.FromTo, overwrite *common.OverwriteOption, flagName string) error {
+	// blobs have a creation time, which is the only SMB info that can be preserved from them
+	blobCreationTimeOnly := fromTo == common.EFromTo.BlobLocal() && flagName == common.PreserveSMBInfoFlagName
+
+	if toPreserve && fromTo == common.EFromTo.LocalBlob() && flagName == common.PreserveSMBInfoFlagName {
+		return fmt.Errorf("%s is set, but a blob's creation time is set by the service and cannot be preserved when uploading", flagName)
+	}
+
 	if toPreserve && !(fromTo == common.EFromTo.LocalFile() ||
 		fromTo == common.EFromTo.FileLocal() ||
-		fromTo == common.EFromTo.FileFile()) {
+		fromTo == common.EFromTo.FileFile() ||
+		blobCreationTimeOnly) {
 		return fmt.Errorf("%s is set but the job is not between SMB-aware resources", flagName)
 	}
 
@@ -803,6 +1117,123 @@ func validatePreserveSMBPropertyOption(toPreserve bool, fromTo common.FromTo, ov
 	return nil
 }
 
+func validatePreservePermissions(preservePermissions, preserveSMBPermissions bool, fromTo common.FromTo) error {
+	if !preservePermissions {
+		return nil
+	}
+	if preserveSMBPermissions {
+		return errors.New("preserve-permissions and preserve-smb-permissions cannot be used together")
+	}
+	if fromTo == common.EFromTo.BlobFSBlobFS() {
+		return nil // read from the source account, so the OS doesn't matter
+	}
+	if fromTo != common.EFromTo.LocalBlobFS() {
+		return errors.New("preserve-permissions is only supported when uploading to, or copying between, ADLS Gen 2 accounts")
+	}
+	if runtime.GOOS != "linux" && runtime.GOOS != "windows" {
+		return errors.New("preserve-permissions reads POSIX ACLs on Linux, or translates Windows ACLs, so it's only supported on those")
+	}
+	return nil
+}
+
+func validatePermissionsOnly(permissionsOnly bool, fromTo common.FromTo, preservePermissions bool) error {
+	if !permissionsOnly {
+		return nil
+	}
+	if fromTo.IsDownload() || !fromTo.To().IsRemote() {
+		return errors.New("permissions-only is only supported when the destination is Azure Storage")
+	}
+	if fromTo.To() == common.ELocation.BlobFS() && !preservePermissions {
+		return errors.New("permissions-only requires preserve-permissions when the destination is ADLS Gen 2, since permissions are all there is to set")
+	}
+	return nil
+}
+
+// validateDeleteSourceAfterTransfer allows moves from the local file system, Blob Storage, Azure Files and ADLS Gen2,
+// which are the sources that AzCopy can delete from, and only when data is actually transferred
+func validateDeleteSourceAfterTransfer(deleteSource bool, fromTo common.FromTo, permissionsOnly bool, source common.ResourceString) error {
+	if !deleteSource {
+		return nil
+	}
+	switch fromTo {
+	case common.EFromTo.LocalBlob(), common.EFromTo.LocalFile(), common.EFromTo.LocalBlobFS(),
+		common.EFromTo.BlobLocal(), common.EFromTo.FileLocal(), common.EFromTo.BlobFSLocal(),
+		common.EFromTo.BlobBlob(), common.EFromTo.BlobFile(), common.EFromTo.FileBlob(), common.EFromTo.FileFile(),
+		common.EFromTo.BlobFSBlobFS():
+	default:
+		return fmt.Errorf("%s is not supported when copying %s", deleteSourceAfterTransferFlagName, fromTo)
+	}
+	if permissionsOnly {
+		return fmt.Errorf("%s can't be used with permissions-only, since nothing is transferred", deleteSourceAfterTransferFlagName)
+	}
+	if shareSnapshotOf(source, fromTo.From()) != "" {
+		return fmt.Errorf("%s can't be used when the source is a share snapshot, since snapshots are read-only", deleteSourceAfterTransferFlagName)
+	}
+	return nil
+}
+
+// parseSizeLimits parses --skip-larger-than and --skip-smaller-than, which copy and sync share
+func parseSizeLimits(rawLargerThan, rawSmallerThan string) (skipLargerThan, skipSmallerThan int64, err error) {
+	if skipLargerThan, err = parseSizeLimit(rawLargerThan, common.SkipLargerThanFlagName); err != nil {
+		return
+	}
+	if skipSmallerThan, err = parseSizeLimit(rawSmallerThan, common.SkipSmallerThanFlagName); err != nil {
+		return
+	}
+	if skipLargerThan > 0 && skipSmallerThan > skipLargerThan {
+		err = fmt.Errorf("%s cannot be more than %s, since that would skip everything", common.SkipSmallerThanFlagName, common.SkipLargerThanFlagName)
+	}
+	return
+}
+
+var sizeLimitRegex = regexp.MustCompile(`^(\d+)\s*([kmgt]i?b?|b)?$`)
+
+// parseSizeLimit parses sizes such as 1TiB, 500M or 4096 (bytes). All units are binary, so 1K and 1KiB both mean 1024 bytes.
+// An empty string means no limit, and returns 0.
+func parseSizeLimit(s string, name string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	matches := sizeLimitRegex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if matches == nil {
+		return 0, fmt.Errorf("%s must be a number of bytes, optionally followed by a unit of K, M, G or T (or KiB, MiB, GiB or TiB). E.g. 200M or 1TiB", name)
+	}
+	n, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is out of range: %s", name, err)
+	}
+
+	multiplier := int64(1)
+	if matches[2] != "" && matches[2] != "b" {
+		switch matches[2][0] {
+		case 'k':
+			multiplier = 1024
+		case 'm':
+			multiplier = 1024 * 1024
+		case 'g':
+			multiplier = 1024 * 1024 * 1024
+		case 't':
+			multiplier = 1024 * 1024 * 1024 * 1024
+		}
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("%s is out of range", name)
+	}
+	return n * multiplier, nil
+}
+
+// parseTransferTimeout parses durations such as 90m or 2h. An empty string means no limit, and returns 0.
+func parseTransferTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("transfer-timeout must be a positive duration, with a unit of h, m or s. E.g. 90m or 2h")
+	}
+	return d, nil
+}
+
 func validatePreserveOwner(preserve bool, fromTo common.FromTo) error {
 	if fromTo.IsDownload() {
 		return nil // it can be used in downloads
@@ -846,6 +1277,14 @@ func validatePutMd5(putMd5 bool, fromTo common.FromTo) error {
 	return nil
 }
 
+// validateStoreSourceLMT is shared by copy and sync, so that both accept the same destinations: those which can hold the metadata
+func validateStoreSourceLMT(storeSourceLMT bool, fromTo common.FromTo) error {
+	if storeSourceLMT && !(fromTo == common.EFromTo.LocalBlob() || fromTo == common.EFromTo.LocalFile()) {
+		return errors.New("store-source-lmt is only supported when uploading to Blob storage or Azure Files, as other destinations cannot hold the metadata")
+	}
+	return nil
+}
+
 func validateMd5Option(option common.HashValidationOption, fromTo common.FromTo) error {
 	hasMd5Validation := option != common.DefaultHashValidationOption
 	if hasMd5Validation && !fromTo.IsDownload() {
@@ -854,6 +1293,13 @@ func validateMd5Option(option common.HashValidationOption, fromTo common.FromTo)
 	return nil
 }
 
+func validateFlushPolicy(policy common.FlushPolicy, fromTo common.FromTo) error {
+	if policy != common.EFlushPolicy.Never() && !fromTo.IsDownload() && fromTo != common.EFromTo.LocalLocal() {
+		return fmt.Errorf("flush-policy is set but the job is not a download or a local copy")
+	}
+	return nil
+}
+
 // Valid tag key and value characters include:
 // 1. Lowercase and uppercase letters (a-z, A-Z)
 // 2. Digits (0-9)
@@ -914,10 +1360,17 @@ type cookedCopyCmdArgs struct {
 	includePatterns       []string
 	excludePatterns       []string
 	excludePathPatterns   []string
+	includeDirectories    []string
+	excludeDirectories    []string
 	includeFileAttributes []string
 	excludeFileAttributes []string
 	includeBefore         *time.Time
 	includeAfter          *time.Time
+	skipLargerThan        int64         // in bytes, 0 means no limit
+	skipSmallerThan       int64         // in bytes, 0 means no limit
+	transferTimeout       time.Duration // 0 means no limit
+	// delete each source file once it has been transferred, i.e. move rather than copy
+	deleteSourceAfterTransfer bool
 
 	// list of version ids
 	listOfVersionIDs chan string
@@ -926,10 +1379,39 @@ type cookedCopyCmdArgs struct {
 	recursive          bool
 	stripTopDir        bool
 	followSymlinks     bool
-	forceWrite         common.OverwriteOption // says whether we should try to overwrite
-	forceIfReadOnly    bool                   // says whether we should _force_ any overwrites (triggered by forceWrite) to work on Azure Files objects that are set to read-only
+	danglingSymlinks   *danglingSymlinkTracker // only set when following symlinks
+	hiddenFiles        *hiddenFileFilter       // only set when hidden files are being left out
+	ignoreFiles        *ignoreFileFilter       // only set when uploading from a directory that has ignore files
+	forceWrite         common.OverwriteOption  // says whether we should try to overwrite
+	forceIfReadOnly    bool                    // says whether we should _force_ any overwrites (triggered by forceWrite) to work on Azure Files objects that are set to read-only
 	autoDecompress     bool
 
+	// whether the list of files is followed as it's written, with its transfers sent on as they're found
+	followListOfFiles bool
+
+	// the destinations, besides the main one, that an upload is also sent to (--also-to)
+	alsoTo []common.ResourceString
+
+	// the number of the next job part to dispatch. It's kept here, rather than in the job part order, because a job with
+	// more than one destination has an order for each of them, and they all number their parts from the same sequence
+	nextPartNum common.PartNumber
+
+	// where to write the failed transfers at the end of the job, if anywhere
+	failedTransfersFile string
+
+	// how many more jobs to run for the transfers that failed, and which of those jobs this is (0 for the original job)
+	retryFailedPasses int
+	retryPass         int
+
+	// whether skips, other than those of filters, and properties that weren't preserved fail the job
+	strict bool
+
+	// tallies the size of a download against the free space on the destination, during enumeration
+	destinationSpace destinationSpaceTracker
+
+	// non-nil when the job is only enumerated, to estimate what it will cost, rather than run
+	estimator *transferEstimator
+
 	// options from flags
 	blockSize int64
 	// list of blobTypes to exclude while enumerating the transfer
@@ -941,6 +1423,11 @@ type cookedCopyCmdArgs struct {
 	blockBlobTier            common.BlockBlobTier
 	pageBlobTier             common.PageBlobTier
 	metadata                 string
+	metadataRules            common.MetadataRules
+	storeSourceLMT           bool
+	pathRewriter             pathRewriter                  // rewrites the destination's relative paths
+	nameCaseConverter        *destinationNameCaseConverter // nil unless the destination names' case is converted
+	duplicateDestinations    *duplicateDestinationDetector // nil unless destinations are checked for being claimed by two sources
 	contentType              string
 	contentEncoding          string
 	contentLanguage          string
@@ -948,9 +1435,12 @@ type cookedCopyCmdArgs struct {
 	cacheControl             string
 	noGuessMimeType          bool
 	preserveLastModifiedTime bool
+	preserveLastAccessTime   bool
 	deleteSnapshotsOption    common.DeleteSnapshotsOption
 	putMd5                   bool
 	md5ValidationOption      common.HashValidationOption
+	flushPolicy              common.FlushPolicy
+	freeSpaceCheck           common.FreeSpaceCheckOption
 	CheckLength              bool
 	logVerbosity             common.LogLevel
 	// commandString hold the user given command which is logged to the Job log file
@@ -975,9 +1465,14 @@ type cookedCopyCmdArgs struct {
 	// this flag is set by the enumerator
 	// it is useful to indicate whether we are simply waiting for the purpose of cancelling
 	isEnumerationComplete bool
+	// what the enumerator has found so far
+	discovered *discoveryCounter
 
 	// Whether the user wants to preserve the SMB ACLs assigned to their files when moving between resources that are SMB ACL aware.
 	preserveSMBPermissions common.PreservePermissionsOption
+	permissionsOnly        bool
+	// the principals of local owners, when preserving POSIX permissions in ADLS Gen2
+	posixIDMap common.POSIXIDMap
 	// Whether the user wants to preserve the SMB properties ...
 	preserveSMBInfo bool
 
@@ -1000,6 +1495,10 @@ type cookedCopyCmdArgs struct {
 	s2sSourceChangeValidation bool
 	// To specify whether user wants to preserve the blob index tags during service to service transfer.
 	s2sPreserveBlobTags bool
+	// the AAD tenant used to get the source's OAuth token in a service to service copy, empty means the login's tenant.
+	s2sSourceTenantID string
+	// whether to probe the source and destination for missing permissions before enumerating
+	preflightProbe bool
 	// specify how user wants to handle invalid metadata.
 	s2sInvalidMetadataHandleOption common.InvalidMetadataHandleOption
 
@@ -1050,19 +1549,26 @@ func (cca *cookedCopyCmdArgs) processRedirectionCopy() error {
 	if cca.fromTo == common.EFromTo.PipeBlob() {
 		return cca.processRedirectionUpload(cca.destination, cca.blockSize)
 	} else if cca.fromTo == common.EFromTo.BlobPipe() {
-		return cca.processRedirectionDownload(cca.source)
+		return cca.processRedirectionDownload(cca.source, cca.blockSize)
 	}
 
 	return fmt.Errorf("unsupported redirection type: %s", cca.fromTo)
 }
 
-func (cca *cookedCopyCmdArgs) processRedirectionDownload(blobResource common.ResourceString) error {
+func (cca *cookedCopyCmdArgs) processRedirectionDownload(blobResource common.ResourceString, blockSize int64) error {
 
 	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
 
-	// step 0: check the Stdout before uploading
-	_, err := os.Stdout.Stat()
-	if err != nil {
+	// step 0: check the Stdout before uploading, or open the named pipe that was given instead
+	var out io.Writer = os.Stdout
+	if cca.destination.Value != pipeLocation {
+		namedPipe, err := os.OpenFile(cca.destination.Value, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("fatal: cannot open the named pipe %s due to error: %s", cca.destination.Value, err.Error())
+		}
+		defer namedPipe.Close()
+		out = namedPipe
+	} else if _, err := os.Stdout.Stat(); err != nil {
 		return fmt.Errorf("fatal: cannot write to Stdout due to error: %s", err.Error())
 	}
 
@@ -1087,18 +1593,30 @@ func (cca *cookedCopyCmdArgs) processRedirectionDownload(blobResource common.Res
 		return fmt.Errorf("fatal: cannot parse source blob URL due to error: %s", err.Error())
 	}
 
-	// step 3: start download
+	// step 3: find out how much there is to download, and pin the version we'll download
 	blobURL := azblob.NewBlobURL(*u, p)
-	blobStream, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
 	if err != nil {
-		return fmt.Errorf("fatal: cannot download blob due to error: %s", err.Error())
+		return fmt.Errorf("fatal: cannot get properties of blob due to error: %s", err.Error())
 	}
+	sameVersion := azblob.BlobAccessConditions{ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfMatch: props.ETag()}}
 
-	blobBody := blobStream.Body(azblob.RetryReaderOptions{MaxRetryRequests: ste.MaxRetryPerDownloadBody})
-	defer blobBody.Close()
+	// if no block size is set, then use default value
+	if blockSize == 0 {
+		blockSize = pipingDefaultBlockSize
+	}
 
-	// step 4: pipe everything into Stdout
-	_, err = io.Copy(os.Stdout, blobBody)
+	// step 4: download ranges in parallel, and pipe them into Stdout in order
+	downloadRange := func(ctx context.Context, offset int64, count int64) ([]byte, error) {
+		blobStream, err := blobURL.Download(ctx, offset, count, sameVersion, false, azblob.ClientProvidedKeyOptions{})
+		if err != nil {
+			return nil, err
+		}
+		blobBody := blobStream.Body(azblob.RetryReaderOptions{MaxRetryRequests: ste.MaxRetryPerDownloadBody})
+		defer blobBody.Close()
+		return ioutil.ReadAll(blobBody)
+	}
+	err = downloadRangesInOrder(ctx, props.ContentLength(), blockSize, pipingDownloadParallelism, downloadRange, out)
 	if err != nil {
 		return fmt.Errorf("fatal: cannot download blob to Stdout due to error: %s", err.Error())
 	}
@@ -1133,9 +1651,20 @@ func (cca *cookedCopyCmdArgs) processRedirectionUpload(blobResource common.Resou
 		return fmt.Errorf("fatal: cannot parse destination blob URL due to error: %s", err.Error())
 	}
 
+	// read from stdin, or from the named pipe that was given instead
+	var in io.Reader = os.Stdin
+	if cca.source.Value != pipeLocation {
+		namedPipe, err := os.Open(cca.source.Value)
+		if err != nil {
+			return fmt.Errorf("fatal: cannot open the named pipe %s due to error: %s", cca.source.Value, err.Error())
+		}
+		defer namedPipe.Close()
+		in = namedPipe
+	}
+
 	// step 2: leverage high-level call in Blob SDK to upload stdin in parallel
 	blockBlobUrl := azblob.NewBlockBlobURL(*u, p)
-	_, err = azblob.UploadStreamToBlockBlob(ctx, os.Stdin, blockBlobUrl, azblob.UploadStreamToBlockBlobOptions{
+	_, err = azblob.UploadStreamToBlockBlob(ctx, in, blockBlobUrl, azblob.UploadStreamToBlockBlobOptions{
 		BufferSize: int(blockSize),
 		MaxBuffers: pipingUploadParallelism,
 	})
@@ -1201,14 +1730,18 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 			Metadata:                 cca.metadata,
 			NoGuessMimeType:          cca.noGuessMimeType,
 			PreserveLastModifiedTime: cca.preserveLastModifiedTime,
+			PreserveLastAccessTime:   cca.preserveLastAccessTime,
 			PutMd5:                   cca.putMd5,
 			MD5ValidationOption:      cca.md5ValidationOption,
+			FlushPolicy:              cca.flushPolicy,
+			FreeSpaceCheck:           cca.freeSpaceCheck,
 			DeleteSnapshotsOption:    cca.deleteSnapshotsOption,
 			// Setting tags when tags explicitly provided by the user through blob-tags flag
 			BlobTagsString: cca.blobTags.ToString(),
 		},
 		CommandString:  cca.commandString,
 		CredentialInfo: cca.credentialInfo,
+		POSIXIDMap:     cca.posixIDMap,
 	}
 
 	from := cca.fromTo.From()
@@ -1237,6 +1770,8 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 	case common.EFromTo.LocalBlob(),
 		common.EFromTo.LocalBlobFS(),
 		common.EFromTo.LocalFile(),
+		common.EFromTo.LocalManagedDisk(),
+		common.EFromTo.LocalLocal(),
 		common.EFromTo.BlobLocal(),
 		common.EFromTo.FileLocal(),
 		common.EFromTo.BlobFSLocal(),
@@ -1244,12 +1779,19 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 		common.EFromTo.FileBlob(),
 		common.EFromTo.FileFile(),
 		common.EFromTo.BlobFile(),
+		common.EFromTo.BlobFSBlobFS(),
 		common.EFromTo.S3Blob(),
 		common.EFromTo.GCPBlob(),
 		common.EFromTo.BenchmarkBlob(),
 		common.EFromTo.BenchmarkBlobFS(),
 		common.EFromTo.BenchmarkFile():
 
+		if cca.preflightProbe {
+			if err = cca.probePermissions(ctx); err != nil {
+				return err
+			}
+		}
+
 		var e *copyEnumerator
 		e, err = cca.initEnumerator(jobPartOrder, ctx)
 		if err != nil {
@@ -1265,6 +1807,14 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 
 		err = e.enumerate()
 
+	case common.EFromTo.BlobNone():
+		e, createErr := newSetPropertiesEnumerator(cca)
+		if createErr != nil {
+			return createErr
+		}
+
+		err = e.enumerate()
+
 	case common.EFromTo.BlobFSTrash():
 		// TODO merge with BlobTrash case
 		err = removeBfsResources(cca)
@@ -1294,10 +1844,7 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 func (cca *cookedCopyCmdArgs) waitUntilJobCompletion(blocking bool) {
 	// print initial message to indicate that the job is starting
 	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(),
-		fmt.Sprintf("%s%s%s.log",
-			azcopyLogPathFolder,
-			common.OS_PATH_SEPARATOR,
-			cca.jobID),
+		jobLogFilePath(cca.jobID),
 		cca.isCleanupJob,
 		cca.cleanupJobMessage))
 
@@ -1376,6 +1923,9 @@ func (cca *cookedCopyCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 		Rpc(common.ERpcCmd.GetJobLCMWrapper(), &cca.jobID, &glcm)
 	})
 	summary.IsCleanupJob = cca.isCleanupJob // only FE knows this, so we can only set it here
+	summary.DanglingSymlinksSkipped = cca.danglingSymlinks.Count()
+	summary.HiddenFilesSkipped = cca.hiddenFiles.Count()
+	summary.FilesDiscovered, summary.BytesDiscovered = cca.discovered.Counts()
 	cleanupStatusString := fmt.Sprintf("Cleanup %v/%v", summary.TransfersCompleted, summary.TotalTransfers)
 
 	jobDone := summary.JobStatus.IsJobDone()
@@ -1390,13 +1940,38 @@ func (cca *cookedCopyCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 			exitCode = common.EExitCode.Error()
 		}
 
+		// failed transfers get another chance in a new job, which then decides the outcome, unless the user cancelled this one
+		var retryPass *cookedCopyCmdArgs
+		if summary.TransfersFailed > 0 && summary.JobStatus != common.EJobStatus.Cancelled() {
+			retryPass = cca.retryPassForFailedTransfers()
+		}
+
+		if !cca.isCleanupJob {
+			recordJobHistory(newJobHistoryEntry(summary, cca.fromTo, cca.source, cca.destination, cca.jobStartTime))
+		}
+
+		failedTransfersMessage := ""
+		if retryPass != nil {
+			failedTransfersMessage = fmt.Sprintf("\nThe failed transfers will be retried in a new job (retry pass %v of %v)\n", retryPass.retryPass, cca.retryFailedPasses)
+		} else if cca.failedTransfersFile != "" && summary.TransfersFailed > 0 {
+			written, err := cca.writeFailedTransfersList(cca.failedTransfersFile)
+			failedTransfersMessage = formatFailedTransfersList(cca.failedTransfersFile, written, err)
+		}
+
+		strictModeMessage := ""
+		if cca.strict {
+			if violations := strictModeViolations(summary); violations != "" {
+				strictModeMessage = fmt.Sprintf("\nStrict mode: failing the job, because %s\n", violations)
+			}
+		}
+
 		builder := func(format common.OutputFormat) string {
 			if format == common.EOutputFormat.Json() {
 				jsonOutput, err := json.Marshal(summary)
 				common.PanicIfErr(err)
 				return string(jsonOutput)
 			} else {
-				screenStats, logStats := formatExtraStats(cca.fromTo, summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.NetworkErrorPercentage, summary.ServerBusyPercentage)
+				screenStats, logStats := formatExtraStats(cca.fromTo, summary)
 
 				output := fmt.Sprintf(
 					`
@@ -1408,8 +1983,8 @@ Number of Folder Property Transfers: %v
 Total Number of Transfers: %v
 Number of Transfers Completed: %v
 Number of Transfers Failed: %v
-Number of Transfers Skipped: %v
-TotalBytesTransferred: %v
+Number of Transfers Skipped: %v%s%s%s
+TotalBytesTransferred: %v%s
 Final Job Status: %v%s%s
 `,
 					summary.JobID.String(),
@@ -1420,10 +1995,16 @@ Final Job Status: %v%s%s
 					summary.TransfersCompleted,
 					summary.TransfersFailed,
 					summary.TransfersSkipped,
+					formatDanglingSymlinks(cca.followSymlinks, summary.DanglingSymlinksSkipped),
+					formatHiddenFiles(cca.hiddenFiles != nil, summary.HiddenFilesSkipped),
+					formatOverwrites(cca.forceWrite, summary),
 					summary.TotalBytesTransferred,
+					formatWireBytes(summary)+formatEmptyPagesSkipped(summary.EmptyPagesSkipped),
 					summary.JobStatus,
 					screenStats,
 					formatPerfAdvice(summary.PerformanceAdvice))
+				output += failedTransfersMessage
+				output += strictModeMessage
 
 				// abbreviated output for cleanup jobs
 				if cca.isCleanupJob {
@@ -1439,6 +2020,17 @@ Final Job Status: %v%s%s
 			}
 		}
 
+		if retryPass != nil {
+			// the retry pass takes over any other followup, so that it still runs after the last pass
+			cca.followupJobArgs = retryPass
+			exitCode = cca.getSuccessExitCode()
+		}
+
+		// a strict job fails here even if it's retried, since what was skipped isn't part of the retry
+		if strictModeMessage != "" {
+			exitCode = common.EExitCode.Error()
+		}
+
 		if cca.hasFollowup() {
 			lcm.Exit(builder, common.EExitCode.NoExit()) // leave the app running to process the followup
 			cca.launchFollowup(exitCode)
@@ -1472,11 +2064,8 @@ Final Job Status: %v%s%s
 			}
 
 			// if json is not needed, then we generate a message that goes nicely on the same line
-			// display a scanning keyword if the job is not completely ordered
-			var scanningString = " (scanning...)"
-			if summary.CompleteJobOrdered {
-				scanningString = ""
-			}
+			// display a scanning keyword, and what's been found so far, if the job is not completely ordered
+			scanningString := formatScanning(summary.CompleteJobOrdered, summary.FilesDiscovered, summary.BytesDiscovered)
 
 			throughput := computeThroughput()
 			throughputString := fmt.Sprintf("2-sec Throughput (Mb/s): %v", ste.ToFixed(throughput, 4))
@@ -1489,12 +2078,12 @@ Final Job Status: %v%s%s
 			isBenchmark := cca.fromTo.From() == common.ELocation.Benchmark()
 			perfString, diskString := getPerfDisplayText(summary.PerfStrings, summary.PerfConstraint, duration, isBenchmark)
 
-			return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Skipped, %v Total%s, %s%s%s",
+			return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Skipped, %v Total%s%s, %s%s%s",
 				summary.PercentComplete,
 				summary.TransfersCompleted,
 				summary.TransfersFailed,
 				summary.TotalTransfers-(summary.TransfersCompleted+summary.TransfersFailed+summary.TransfersSkipped),
-				summary.TransfersSkipped, summary.TotalTransfers, scanningString, perfString, throughputString, diskString)
+				summary.TransfersSkipped, summary.TotalTransfers, scanningString, formatEta(summary.EstimatedSecondsRemaining), perfString, throughputString, diskString)
 		}
 	})
 
@@ -1528,18 +2117,48 @@ func formatPerfAdvice(advice []common.PerformanceAdvice) string {
 	return b.String()
 }
 
+// validateHTTPHeaderLengths rejects HTTP header values that are too long to be kept in the job's plan, and warns about
+// ones that are unusually long, since that's more often a quoting mistake than a real header
+func validateHTTPHeaderLengths(cooked *cookedCopyCmdArgs) error {
+	for _, h := range []struct {
+		flag  string
+		value string
+	}{
+		{"content-type", cooked.contentType},
+		{"content-encoding", cooked.contentEncoding},
+		{"content-language", cooked.contentLanguage},
+		{"content-disposition", cooked.contentDisposition},
+		{"cache-control", cooked.cacheControl},
+	} {
+		if len(h.value) > math.MaxUint16 {
+			return fmt.Errorf("the %s value is %d bytes long, but can be at most %d bytes", h.flag, len(h.value), math.MaxUint16)
+		}
+		if len(h.value) > ste.CustomHeaderMaxBytes {
+			glcm.Info(fmt.Sprintf("*** Warning *** The %s value is %d bytes long, which is unusually long. It will be used as given, "+
+				"but please check that it was quoted as intended.", h.flag, len(h.value)))
+		}
+	}
+	return nil
+}
+
 // format extra stats to include in the log.  If benchmarking, also output them on screen (but not to screen in normal
 // usage because too cluttered)
-func formatExtraStats(fromTo common.FromTo, avgIOPS int, avgE2EMilliseconds int, networkErrorPercent float32, serverBusyPercent float32) (screenStats, logStats string) {
+func formatExtraStats(fromTo common.FromTo, summary common.ListJobSummaryResponse) (screenStats, logStats string) {
 	logStats = fmt.Sprintf(
 		`
 
 Diagnostic stats:
 IOPS: %v
-End-to-end ms per request: %v
+End-to-end ms per request: %v (median %v, 95th percentile %v, 99th percentile %v)
 Network Errors: %.2f%%
-Server Busy: %.2f%%`,
-		avgIOPS, avgE2EMilliseconds, networkErrorPercent, serverBusyPercent)
+Server Busy: %.2f%%
+Connections: %v new, %v re-used
+TLS Handshakes: %v (%v resumed an earlier session)`,
+		summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.MedianE2EMilliseconds, summary.P95E2EMilliseconds, summary.P99E2EMilliseconds,
+		summary.NetworkErrorPercentage, summary.ServerBusyPercentage,
+		summary.NewConnectionCount, summary.ReusedConnectionCount,
+		summary.TLSHandshakeCount, summary.ResumedTLSSessionCount)
+	logStats += formatTransferDurations(summary)
 
 	if fromTo.From() == common.ELocation.Benchmark() {
 		screenStats = logStats
@@ -1549,6 +2168,87 @@ Server Busy: %.2f%%`,
 	return
 }
 
+// formatTransferDurations shows the spread of how long the files took, and which were slowest, with their throughput
+func formatTransferDurations(summary common.ListJobSummaryResponse) string {
+	if len(summary.SlowestTransfers) == 0 {
+		return ""
+	}
+	output := fmt.Sprintf("\nFile transfer ms: min %v, median %v, 95th percentile %v, max %v\nSlowest file transfers:",
+		summary.MinTransferMilliseconds, summary.MedianTransferMilliseconds, summary.P95TransferMilliseconds, summary.MaxTransferMilliseconds)
+	for _, t := range summary.SlowestTransfers {
+		throughput := 0.0
+		if t.Milliseconds > 0 {
+			throughput = float64(t.Bytes) * 8 / base10Mega / (float64(t.Milliseconds) / 1000)
+		}
+		output += fmt.Sprintf("\n  %v ms, %v bytes, %.2f Mb/s: %s", t.Milliseconds, t.Bytes, throughput,
+			common.URLStringExtension(t.Src).RedactSecretQueryParamForLogging())
+	}
+	return output
+}
+
+// formatEmptyPagesSkipped shows how much of the page blob uploads, such as the unused parts of sparse VHDs, didn't need sending.
+// It's left out when there were none, as for every job that doesn't upload page blobs
+func formatEmptyPagesSkipped(skipped uint64) string {
+	if skipped == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nEmpty Pages Not Uploaded: %v (%v bytes)", skipped, skipped*azblob.PageBlobPageBytes)
+}
+
+// the summary line for dangling symlinks only makes sense when symlinks were followed
+func formatDanglingSymlinks(followSymlinks bool, skipped uint32) string {
+	if !followSymlinks {
+		return ""
+	}
+	return fmt.Sprintf("\nNumber of Dangling Symlinks Skipped: %v", skipped)
+}
+
+func formatHiddenFiles(excludingHidden bool, skipped uint32) string {
+	if !excludingHidden {
+		return ""
+	}
+	return fmt.Sprintf("\nNumber of Hidden Files Skipped: %v", skipped)
+}
+
+// formatOverwrites counts the destinations that were replaced. They're only known when the overwrite option isn't true,
+// since otherwise the destinations aren't checked for. For blob destinations, it also says how many were kept as versions
+func formatOverwrites(overwrite common.OverwriteOption, summary common.ListJobSummaryResponse) string {
+	if overwrite == common.EOverwriteOption.True() {
+		return ""
+	}
+	return fmt.Sprintf("\nNumber of Destinations Overwritten: %v (%v kept as previous blob versions)", summary.Overwrites, summary.VersionedOverwrites)
+}
+
+// strictModeViolations lists what a strict job doesn't tolerate: transfers skipped other than by a filter, dangling
+// symlinks (hidden files, like the size filters, are only left out when the user asks), and successful transfers that
+// left some property of their source behind. It's empty if there were none
+func strictModeViolations(summary common.ListJobSummaryResponse) string {
+	var violations []string
+	if skipped := summary.TransfersSkipped - summary.TransfersSkippedByFilter; skipped > 0 {
+		violations = append(violations, fmt.Sprintf("%v transfers were skipped", skipped))
+	}
+	if summary.DanglingSymlinksSkipped > 0 {
+		violations = append(violations, fmt.Sprintf("%v dangling symlinks were skipped", summary.DanglingSymlinksSkipped))
+	}
+	if summary.TransfersWithPropertiesNotPreserved > 0 {
+		violations = append(violations, fmt.Sprintf("%v transfers did not preserve all the properties of their source",
+			summary.TransfersWithPropertiesNotPreserved))
+	}
+	return strings.Join(violations, ", and ")
+}
+
+// formatWireBytes puts the job's network traffic next to its logical bytes transferred, so that the two can be reconciled
+// against a network bill. Retries add to the traffic, so they're counted alongside, and skipped files are savings that never reached the network
+func formatWireBytes(summary common.ListJobSummaryResponse) string {
+	return fmt.Sprintf("\nBytes Over the Network (including retries): %v\nBytes Resent by Retries: %v\nBytes Not Sent Because Skipped: %v%s",
+		summary.BytesOverWire, summary.BytesRetried, summary.BytesSkipped, formatRetries(summary))
+}
+
+// formatRetries counts the retries and throttling behind the job's traffic, for troubleshooting its performance
+func formatRetries(summary common.ListJobSummaryResponse) string {
+	return fmt.Sprintf("\nNumber of Retries: %v\nNumber of Server Busy (503) Responses: %v", summary.RetryCount, summary.ServerBusyCount)
+}
+
 // Is disk speed looking like a constraint on throughput?  Ignore the first little-while,
 // to give an (arbitrary) amount of time for things to reach steady-state.
 func getPerfDisplayText(perfDiagnosticStrings []string, constraint common.PerfConstraint, durationOfJob time.Duration, isBench bool) (perfString string, diskString string) {
@@ -1566,6 +2266,24 @@ func getPerfDisplayText(perfDiagnosticStrings []string, constraint common.PerfCo
 	return
 }
 
+// formatScanning shows, while the source is still being enumerated, how much of it has been found so far
+func formatScanning(completeJobOrdered bool, filesDiscovered uint32, bytesDiscovered uint64) string {
+	if completeJobOrdered {
+		return ""
+	} else if filesDiscovered == 0 {
+		return " (scanning...)"
+	}
+	return fmt.Sprintf(" (scanning... %v files, %s found so far)", filesDiscovered, byteSizeToString(int64(bytesDiscovered)))
+}
+
+// formatEta describes how long the job is likely to take. Nothing is shown until there is an estimate
+func formatEta(estimatedSecondsRemaining int64) string {
+	if estimatedSecondsRemaining < 0 {
+		return ""
+	}
+	return ", ETA " + (time.Duration(estimatedSecondsRemaining) * time.Second).String()
+}
+
 func shouldDisplayPerfStates() bool {
 	return glcm.GetEnvironmentVariable(common.EEnvironmentVariable.ShowPerfStates()) != ""
 }
@@ -1620,14 +2338,17 @@ func init() {
 					raw.src = args[0]
 					raw.dst = pipeLocation
 				}
-			} else if len(args) == 2 { // normal copy
-				raw.src = args[0]
-				raw.dst = args[1]
-
-				// under normal copy, we may ask the user questions such as whether to overwrite a file
-				glcm.EnableInputWatcher()
-				if cancelFromStdin {
-					glcm.EnableCancelFromStdIn()
+			} else if len(args) == 2 { // normal copy, or a redirection with "-" for stdin or stdout
+				raw.src = fromStdioArgument(args[0])
+				raw.dst = fromStdioArgument(args[1])
+
+				// under normal copy, we may ask the user questions such as whether to overwrite a file.
+				// But not when stdin is what's being uploaded
+				if raw.src != pipeLocation {
+					glcm.EnableInputWatcher()
+					if cancelFromStdin {
+						glcm.EnableCancelFromStdIn()
+					}
 				}
 			} else {
 				return errors.New("wrong number of arguments, please refer to the help page on usage of this command")
@@ -1635,6 +2356,16 @@ func init() {
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
+			if err := applyJobIDFlag(raw.jobID); err != nil {
+				glcm.Error("failed to parse user input due to error: " + err.Error())
+			}
+			if resumed, err := resumeIfJobExists(raw.src, raw.dst); resumed {
+				if err != nil {
+					glcm.Error("failed to resume the existing job due to error: " + err.Error())
+				}
+				glcm.SurrenderControl()
+			}
+
 			cooked, err := raw.cook()
 			if err != nil {
 				glcm.Error("failed to parse user input due to error: " + err.Error())
@@ -1653,9 +2384,28 @@ func init() {
 	}
 	rootCmd.AddCommand(cpCmd)
 
+	cpCmd.PersistentFlags().StringVar(&raw.jobID, "job-id", "", jobIDFlagDescription)
+
 	// filters change which files get transferred
 	cpCmd.PersistentFlags().BoolVar(&raw.followSymlinks, "follow-symlinks", false, "Follow symbolic links when uploading from local file system.")
+	cpCmd.PersistentFlags().BoolVar(&raw.failOnDanglingSymlinks, common.FailOnDanglingSymlinksFlagName, false, "False by default. When following symlinks, links whose targets do not exist are skipped and counted in the job summary. "+
+		"Set this to fail the job as soon as such a link is found. Only applies to copy, since sync doesn't follow symlinks.")
+	cpCmd.PersistentFlags().BoolVar(&raw.excludeHidden, "exclude-hidden", true, "True by default. When uploading, leave out hidden files and folders: those whose names start with a dot, "+
+		"and on Windows also those with the hidden or system attribute. The number of files left out is shown in the job summary.")
+	cpCmd.PersistentFlags().BoolVar(&raw.includeHidden, "include-hidden", false, "False by default. Upload hidden files and folders too. Takes precedence over exclude-hidden.")
+	cpCmd.PersistentFlags().StringVar(&raw.ignoreFileName, "ignore-file", defaultIgnoreFileName, "When uploading, leave out whatever the file with this name, at the root of the source, says to. "+
+		"It uses the same syntax as a .gitignore file, and applies together with the other filters. The ignore file itself is never uploaded. Set to an empty string to disable.")
+	cpCmd.PersistentFlags().BoolVar(&raw.perDirIgnoreFiles, "per-directory-ignore-files", false, "False by default. Also honor ignore files found in directories below the source root, for their own contents.")
 	cpCmd.PersistentFlags().StringVar(&raw.includeBefore, common.IncludeBeforeFlagName, "", "Include only those files modified before or on the given date/time. The value should be in ISO8601 format. If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy. E.g. '2020-08-19T15:04:00Z' for a UTC time, or '2020-08-19' for midnight (00:00) in the local timezone. As of AzCopy 10.7, this flag applies only to files, not folders, so folder properties won't be copied when using this flag with --preserve-smb-info or --preserve-smb-permissions.")
+	cpCmd.PersistentFlags().StringVar(&raw.skipLargerThan, common.SkipLargerThanFlagName, "", "Skip files larger than the given size, e.g. 1TiB or 200M, marking them as skipped in the job summary. "+
+		"Useful when migrating objects that exceed the size limits of the destination service.")
+	cpCmd.PersistentFlags().StringVar(&raw.skipSmallerThan, common.SkipSmallerThanFlagName, "", "Skip files smaller than the given size, e.g. 4K, marking them as skipped in the job summary.")
+	cpCmd.PersistentFlags().BoolVar(&raw.deleteSourceAfterTransfer, deleteSourceAfterTransferFlagName, false, "False by default. Move rather than copy: delete each source file "+
+		"once it has been transferred successfully, and verified if --check-md5 or --check-length asks for it. Sources that fail, or are skipped, are left in place, "+
+		"and so are folders. Supported when the source is local, Blob Storage, Azure Files or ADLS Gen2. Remote sources must be deletable with the credentials or SAS given. "+
+		"A blob that has snapshots is not deleted, and its transfer fails, since the snapshots would be lost with it.")
+	cpCmd.PersistentFlags().StringVar(&raw.transferTimeout, "transfer-timeout", "", "Fail any transfer that has been running for longer than this, e.g. 90m or 2h, instead of letting it retry indefinitely. "+
+		"The clock starts when the transfer's first chunk starts, not while it's waiting in the queue. Such transfers are counted as failed, and logged as timed out. By default there is no limit.")
 	cpCmd.PersistentFlags().StringVar(&raw.includeAfter, common.IncludeAfterFlagName, "", "Include only those files modified on or after the given date/time. The value should be in ISO8601 format. If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy. E.g. '2020-08-19T15:04:00Z' for a UTC time, or '2020-08-19' for midnight (00:00) in the local timezone. As of AzCopy 10.5, this flag applies only to files, not folders, so folder properties won't be copied when using this flag with --preserve-smb-info or --preserve-smb-permissions.")
 	cpCmd.PersistentFlags().StringVar(&raw.include, "include-pattern", "", "Include only these files when copying. "+
 		"This option supports wildcard characters (*). Separate files by using a ';'.")
@@ -1663,23 +2413,65 @@ func init() {
 		"This option does not support wildcard characters (*). Checks relative path prefix (For example: myFolder;myFolder/subDirName/file.pdf).")
 	cpCmd.PersistentFlags().StringVar(&raw.excludePath, "exclude-path", "", "Exclude these paths when copying. "+ // Currently, only exclude-path is supported alongside account traversal.
 		"This option does not support wildcard characters (*). Checks relative path prefix(For example: myFolder;myFolder/subDirName/file.pdf). When used in combination with account traversal, paths do not include the container name.")
+	cpCmd.PersistentFlags().StringVar(&raw.includeDirectory, "include-directory", "", "Include only files that are inside a directory with one of these names, at any depth. "+
+		"This option supports wildcard characters (*), which match within a single directory name. Separate names by using a ';' (For example: src;docs).")
+	cpCmd.PersistentFlags().StringVar(&raw.excludeDirectory, "exclude-directory", "", "Exclude directories with these names, and everything inside them, at any depth. "+
+		"This option supports wildcard characters (*), which match within a single directory name. Separate names by using a ';' (For example: node_modules;.git).")
+	cpCmd.PersistentFlags().StringVar(&raw.failedTransfersFile, failedTransfersFileFlagName, "", "If any transfers fail, write their source paths to this file at the end of the job, in the format of --list-of-files. "+
+		"The command to retry only those transfers is shown in the job summary, with the query of each URL (i.e. its SAS token) replaced by [SAS], "+
+		"which must be filled in again before running it.")
+	cpCmd.PersistentFlags().BoolVar(&raw.strict, "strict", false, "False by default. Fail the job, with a non-zero exit code, if any transfer is skipped (for example because the destination exists "+
+		"and --overwrite is false), any followed symlink has no target, or any property that was asked to be preserved, such as metadata, ACL entries "+
+		"or the last access time, could not be. What filters such as --exclude-pattern, --exclude-hidden, --skip-larger-than and --skip-smaller-than leave out does not count.")
+	cpCmd.PersistentFlags().IntVar(&raw.retryFailedPasses, "retry-failed-passes", 0, "After the job completes, retry the transfers that failed in up to this many new jobs, before declaring them failed. "+
+		"Useful on unreliable networks. Not supported for sources that contain wildcards. "+
+		"Only the copy command has retry passes: to retry a sync, run it again, and it will only transfer what's still missing or out of date.")
 	// This flag is implemented only for Storage Explorer.
 	cpCmd.PersistentFlags().StringVar(&raw.listOfFilesToCopy, "list-of-files", "", "Defines the location of text file which has the list of only files to be copied.")
+	cpCmd.PersistentFlags().BoolVar(&raw.followListOfFiles, "follow-list-of-files", false, "False by default. Keep reading the list-of-files as it's written, "+
+		"like 'tail -f', so that a producer can keep adding to a job while it runs. The list can be a file that's appended to, or a named pipe. "+
+		"Files are added to the running job in new job parts as they're listed, and the job is only sealed, so that it can finish, "+
+		"once the list has a line that's just '"+sealListLine+"'.")
+	cpCmd.PersistentFlags().StringVar(&raw.alsoTo, "also-to", "", "Upload to these destinations as well, separated by ';'. The source is only scanned once, "+
+		"and every file is sent to each destination, in the same job, at the same path as at the main destination. "+
+		"Each destination must be the same kind of location as the main one, and use the same kind of authentication. "+
+		"Each transfer succeeds or fails on its own, and the failed ones are listed with their destinations. "+
+		"To resume the job, give the same destinations, each with its SAS token, to the --also-to flag of 'jobs resume'.")
 	cpCmd.PersistentFlags().StringVar(&raw.exclude, "exclude-pattern", "", "Exclude these files when copying. This option supports wildcard characters (*)")
-	cpCmd.PersistentFlags().StringVar(&raw.forceWrite, "overwrite", "true", "Overwrite the conflicting files and blobs at the destination if this flag is set to true. (default 'true') Possible values include 'true', 'false', 'prompt', and 'ifSourceNewer'. For destinations that support folders, conflicting folder-level properties will be overwritten this flag is 'true' or if a positive response is provided to the prompt.")
+	cpCmd.PersistentFlags().StringVar(&raw.forceWrite, "overwrite", "true", "Overwrite the conflicting files and blobs at the destination if this flag is set to true. (default 'true') Possible values include 'true', 'false', 'prompt', 'ifSourceNewer', and 'ifVersioned'. "+
+		"With 'ifVersioned', blobs are only overwritten if blob versioning is enabled on the destination account, so that their previous content is kept as a version. "+
+		"Unless this flag is 'true', the job summary counts the destinations that were overwritten. For destinations that support folders, conflicting folder-level properties will be overwritten this flag is 'true' or if a positive response is provided to the prompt.")
 	cpCmd.PersistentFlags().BoolVar(&raw.autoDecompress, "decompress", false, "Automatically decompress files when downloading, if their content-encoding indicates that they are compressed. The supported content-encoding values are 'gzip' and 'deflate'. File extensions of '.gz'/'.gzip' or '.zz' aren't necessary, but will be removed if present.")
 	cpCmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", false, "Look into sub-directories recursively when uploading from local file system.")
-	cpCmd.PersistentFlags().StringVar(&raw.fromTo, "from-to", "", "Optionally specifies the source destination combination. For Example: LocalBlob, BlobLocal, LocalBlobFS. Piping: BlobPipe, PipeBlob")
+	cpCmd.PersistentFlags().StringVar(&raw.fromTo, "from-to", "", "Optionally specifies the source destination combination. For Example: LocalBlob, BlobLocal, LocalBlobFS. Piping: BlobPipe, PipeBlob. Managed disks: LocalManagedDisk. Between local paths: LocalLocal")
 	cpCmd.PersistentFlags().StringVar(&raw.excludeBlobType, "exclude-blob-type", "", "Optionally specifies the type of blob (BlockBlob/ PageBlob/ AppendBlob) to exclude when copying blobs from the container "+
 		"or the account. Use of this flag is not applicable for copying data from non azure-service to service. More than one blob should be separated by ';'. ")
 	// options change how the transfers are performed
 	cpCmd.PersistentFlags().Float64Var(&raw.blockSizeMB, "block-size-mb", 0, "Use this block size (specified in MiB) when uploading to Azure Storage, and downloading from Azure Storage. The default value is automatically calculated based on file size. Decimal fractions are allowed (For example: 0.25).")
 	cpCmd.PersistentFlags().StringVar(&raw.logVerbosity, "log-level", "INFO", "Define the log verbosity for the log file, available levels: INFO(all requests/responses), WARNING(slow responses), ERROR(only failed requests), and NONE(no output logs). (default 'INFO').")
 	cpCmd.PersistentFlags().StringVar(&raw.blobType, "blob-type", "Detect", "Defines the type of blob at the destination. This is used for uploading blobs and when copying between accounts (default 'Detect'). Valid values include 'Detect', 'BlockBlob', 'PageBlob', and 'AppendBlob'. "+
-		"When copying between accounts, a value of 'Detect' causes AzCopy to use the type of source blob to determine the type of the destination blob. When uploading a file, 'Detect' determines if the file is a VHD or a VHDX file based on the file extension. If the file is either a VHD or VHDX file, AzCopy treats the file as a page blob.")
+		"When copying between accounts, a value of 'Detect' causes AzCopy to use the type of source blob to determine the type of the destination blob. When uploading a file, 'Detect' determines if the file is a VHD or a VHDX file based on the extension of the destination, or failing that of the file. If the file is either a VHD or VHDX file, AzCopy treats the file as a page blob.")
 	cpCmd.PersistentFlags().StringVar(&raw.blockBlobTier, "block-blob-tier", "None", "upload block blob to Azure Storage using this blob tier.")
-	cpCmd.PersistentFlags().StringVar(&raw.pageBlobTier, "page-blob-tier", "None", "Upload page blob to Azure Storage using this blob tier. (default 'None').")
-	cpCmd.PersistentFlags().StringVar(&raw.metadata, "metadata", "", "Upload to Azure Storage with these key-value pairs as metadata.")
+	cpCmd.PersistentFlags().StringVar(&raw.pageBlobTier, "page-blob-tier", "None", "Upload page blob to Azure Storage using this blob tier. (default 'None'). "+
+		"Valid values are 'P4' through 'P80', and need a premium StorageV2 destination account.")
+	cpCmd.PersistentFlags().StringVar(&raw.metadata, "metadata", "", "Upload to Azure Storage with these key-value pairs as metadata. "+
+		"Values that aren't ASCII, from this flag or from the source, are stored as RFC 2047 encoded-words, since the service only accepts ASCII.")
+	cpCmd.PersistentFlags().StringVar(&raw.pathRewrite, "path-rewrite", "", "Rules, separated by ';', rewriting the relative path of every file at the destination. "+
+		"Available rules: s:regex:replacement: (sed-like, any character after s is the delimiter), strip-prefix:prefix and add-prefix:prefix. E.g. 's:^2023/::' moves the content of 2023/ up one level.")
+	cpCmd.PersistentFlags().StringVar(&raw.duplicateDestination, "duplicate-destination", common.EDuplicateDestinationOption.Auto().String(), "Specifies what to do when two different source files would be copied to the same destination, e.g. because of --path-rewrite. "+
+		"Available options: Fail (fail the job as soon as it's found), Rename (copy the second one to a name with a numbered suffix, e.g. report-2.pdf, and log a warning), None (don't check, so the transfers overwrite each other). "+
+		"Auto, the default, means Fail when --path-rewrite is used and None otherwise, since checking means remembering the destination of every file in the job.")
+	cpCmd.PersistentFlags().BoolVar(&raw.toLowercase, "to-lowercase", false, "Convert the relative paths of the files to lower case at the destination. "+
+		"Files whose names then collide get a numbered suffix, e.g. report-2.pdf, and a warning is logged.")
+	cpCmd.PersistentFlags().BoolVar(&raw.toUppercase, "to-uppercase", false, "Convert the relative paths of the files to upper case at the destination. "+
+		"Files whose names then collide get a numbered suffix, e.g. REPORT-2.PDF, and a warning is logged.")
+	cpCmd.PersistentFlags().StringVar(&raw.metadataRules, "metadata-rules", "", "Rules, separated by ';', transforming the metadata of every file transferred to Azure Storage. "+
+		"Available rules: add:key=value (only if the key is absent), set:key=value, remove:key and rename:oldkey=newkey. "+
+		"Values can use the placeholders {name}, {path}, {ext}, {size}, {lmt} and {meta:key} (the source's value of a key). When uploading, the rules apply on top of --metadata.")
+	cpCmd.PersistentFlags().BoolVar(&raw.storeSourceLMT, "store-source-lmt", false, "False by default. Only available when uploading to Blob storage or Azure Files. "+
+		"Records each file's last modified time in the '"+common.SourceLMTMetadataKey+"' metadata key. Later downloads and syncs use it instead of the time of the upload.")
+	cpCmd.PersistentFlags().BoolVar(&raw.expandDstTemplate, "expand-destination-template", false, "False by default. Expand {date}, {time}, {jobid} and {hostname} in the destination when the job is created. "+
+		"Dates and times are in UTC. Without this flag, braces in the destination are taken literally.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentType, "content-type", "", "Specifies the content type of the file. Implies no-guess-mime-type. Returned on download.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentEncoding, "content-encoding", "", "Set the content-encoding header. Returned on download.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentDisposition, "content-disposition", "", "Set the content-disposition header. Returned on download.")
@@ -1687,13 +2479,35 @@ func init() {
 	cpCmd.PersistentFlags().StringVar(&raw.cacheControl, "cache-control", "", "Set the cache-control header. Returned on download.")
 	cpCmd.PersistentFlags().BoolVar(&raw.noGuessMimeType, "no-guess-mime-type", false, "Prevents AzCopy from detecting the content-type based on the extension or content of the file.")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveLastModifiedTime, "preserve-last-modified-time", false, "Only available when destination is file system.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preserveLastAccessTime, "preserve-last-access-time", false, "Only available when downloading from Blob storage. "+
+		"Sets the access time of downloaded files to the blob's last access time, if last access time tracking is enabled on the source account.")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveSMBPermissions, "preserve-smb-permissions", false, "False by default. Preserves SMB ACLs between aware resources (Windows and Azure Files). For downloads, you will also need the --backup flag to restore permissions where the new Owner will not be the user running AzCopy. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern).")
-	cpCmd.PersistentFlags().BoolVar(&raw.preserveOwner, common.PreserveOwnerFlagName, common.PreserveOwnerDefault, "Only has an effect in downloads, and only when --preserve-smb-permissions is used. If true (the default), the file Owner and Group are preserved in downloads. If set to false, --preserve-smb-permissions will still preserve ACLs but Owner and Group will be based on the user running AzCopy")
-	cpCmd.PersistentFlags().BoolVar(&raw.preserveSMBInfo, "preserve-smb-info", false, "False by default. Preserves SMB property info (last write time, creation time, attribute bits) between SMB-aware resources (Windows and Azure Files). Only the attribute bits supported by Azure Files will be transferred; any others will be ignored. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern). The info transferred for folders is the same as that for files, except for Last Write Time which is never preserved for folders.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preservePermissions, "preserve-permissions", false, "False by default. Only available when uploading from Linux or Windows to ADLS Gen 2, or copying between ADLS Gen 2 accounts. "+
+		"Preserves the POSIX permissions and ACLs of files and folders, including the directories created implicitly above the files that are uploaded. "+
+		"Windows ACLs are translated to the closest POSIX ACLs, and the entries which can't be represented exactly, such as deny entries, are logged as warnings. "+
+		"Owners, owning groups and named ACL entries are only preserved for the local users and groups given in --posix-id-map, and not at all with --"+common.PreserveOwnerFlagName+"=false. "+
+		"Between ADLS Gen 2 accounts, they are copied as they are, since they're already Azure AD principals.")
+	cpCmd.PersistentFlags().StringVar(&raw.posixIDMap, "posix-id-map", "", "Used with --preserve-permissions. A file which maps local user and group IDs to the Azure AD object IDs of the same identities, "+
+		"one per line as user:<uid>=<object ID> or group:<gid>=<object ID>. On Windows the IDs are SIDs, e.g. user:S-1-5-21-...-1001=<object ID>. Owners, owning groups and named ACL entries whose IDs aren't in the map are not preserved. "+
+		"Give the same file to 'jobs resume'.")
+	cpCmd.PersistentFlags().BoolVar(&raw.permissionsOnly, "permissions-only", false, "False by default. Don't transfer any data, only apply the permissions and properties of the sources to destinations that already exist, "+
+		"e.g. to fix up a migration that was done without preserving permissions. Use it with the same flags as the original copy, plus the preserve flags of what is to be fixed up: "+
+		"--preserve-smb-permissions and --preserve-smb-info for Azure Files and --preserve-permissions for ADLS Gen 2. For blobs, the metadata and blob index tags are applied.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preserveOwner, common.PreserveOwnerFlagName, common.PreserveOwnerDefault, "Only has an effect in downloads, and only when --preserve-smb-permissions is used, or in uploads to ADLS Gen 2 with --preserve-permissions. If true (the default), the file Owner and Group are preserved in downloads. If set to false, --preserve-smb-permissions will still preserve ACLs but Owner and Group will be based on the user running AzCopy")
+	cpCmd.PersistentFlags().BoolVar(&raw.preserveSMBInfo, common.PreserveSMBInfoFlagName, false, "False by default. Preserves SMB property info (last write time, creation time, attribute bits) between SMB-aware resources (Windows and Azure Files). Only the attribute bits supported by Azure Files will be transferred; any others will be ignored. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern). The info transferred for folders is the same as that for files, except for Last Write Time which is never preserved for folders. "+
+		"When downloading from Blob storage to Windows, the blobs' creation time is preserved. It cannot be preserved when uploading to Blob storage, as the service sets it.")
 	cpCmd.PersistentFlags().BoolVar(&raw.forceIfReadOnly, "force-if-read-only", false, "When overwriting an existing file on Windows or Azure Files, force the overwrite to work even if the existing file has its read-only attribute set")
 	cpCmd.PersistentFlags().BoolVar(&raw.backupMode, common.BackupModeFlagName, false, "Activates Windows' SeBackupPrivilege for uploads, or SeRestorePrivilege for downloads, to allow AzCopy to see read all files, regardless of their file system permissions, and to restore all permissions. Requires that the account running AzCopy already has these permissions (e.g. has Administrator rights or is a member of the 'Backup Operators' group). All this flag does is activate privileges that the account already has")
 	cpCmd.PersistentFlags().BoolVar(&raw.putMd5, "put-md5", false, "Create an MD5 hash of each file, and save the hash as the Content-MD5 property of the destination blob or file. (By default the hash is NOT created.) Only available when uploading.")
 	cpCmd.PersistentFlags().StringVar(&raw.md5ValidationOption, "check-md5", common.DefaultHashValidationOption.String(), "Specifies how strictly MD5 hashes should be validated when downloading. Only available when downloading. Available options: NoCheck, LogOnly, FailIfDifferent, FailIfDifferentOrMissing. (default 'FailIfDifferent')")
+	cpCmd.PersistentFlags().StringVar(&raw.freeSpaceCheck, "check-free-space", common.EFreeSpaceCheckOption.Warn().String(), "Specifies what to do when downloading if the destination volume doesn't have enough free space. Available options: Warn (say so, but try anyway), Fail (fail the job as soon as it's found to be too big, and fail each file that won't fit, before writing any of it), None. Files that will be overwritten aren't counted as free space, which is why the default is Warn.")
+	cpCmd.PersistentFlags().BoolVar(&raw.estimate, "estimate", false, "False by default. Enumerate the source and, instead of moving any data, estimate how many write, read and list operations the job will make, "+
+		"how many bytes will be read from a remote source (which is billed as egress if it leaves the region), and, if a price sheet is given, what that will cost.")
+	cpCmd.PersistentFlags().StringVar(&raw.priceSheet, "price-sheet", "", "Used with --estimate. A JSON file of the prices to estimate the cost with, for example "+
+		`{"Currency": "USD", "WritePer10K": 0.05, "ReadPer10K": 0.004, "ListPer10K": 0.05, "EgressPerGB": 0.08}. Use the prices for the account's region, redundancy and tier. `+
+		"If the source and destination accounts are priced differently, give the source's price sheet and the destination's, separated by a comma. "+
+		"Reading and listing the source, and egress, are charged at the source's prices; writing the destination at the destination's.")
+	cpCmd.PersistentFlags().StringVar(&raw.flushPolicy, "flush-policy", "never", "Specifies when downloaded data is flushed (fsync'd) to durable storage. Only available when downloading, or copying between local paths. Available options: never (leave it to the OS, which is fastest), per-file (once each file is complete), per-chunk (after every chunk, and when each file is complete; slowest, but loses least on a power failure). Compressed files that are decompressed during download are only flushed per-file.")
 	cpCmd.PersistentFlags().StringVar(&raw.includeFileAttributes, "include-attributes", "", "(Windows only) Include files whose attributes match the attribute list. For example: A;S;R")
 	cpCmd.PersistentFlags().StringVar(&raw.excludeFileAttributes, "exclude-attributes", "", "(Windows only) Exclude files whose attributes match the attribute list. For example: A;S;R")
 	cpCmd.PersistentFlags().BoolVar(&raw.CheckLength, "check-length", true, "Check the length of a file on the destination after the transfer. If there is a mismatch between source and destination, the transfer is marked as failed.")
@@ -1707,6 +2521,10 @@ func init() {
 	cpCmd.PersistentFlags().StringVar(&raw.listOfVersionIDs, "list-of-versions", "", "Specifies a file where each version id is listed on a separate line. Ensure that the source must point to a single blob and all the version ids specified in the file using this flag must belong to the source blob only. AzCopy will download the specified versions in the destination folder provided.")
 	cpCmd.PersistentFlags().StringVar(&raw.blobTags, "blob-tags", "", "Set tags on blobs to categorize data in your storage account")
 	cpCmd.PersistentFlags().BoolVar(&raw.s2sPreserveBlobTags, "s2s-preserve-blob-tags", false, "Preserve index tags during service to service transfer from one blob storage to another")
+	cpCmd.PersistentFlags().StringVar(&raw.s2sSourceTenantID, "s2s-source-tenant-id", "", "The Azure Active Directory tenant of the source, when copying between blob accounts in different tenants with an interactive login. "+
+		"The source is then authorized with its own OAuth token rather than a SAS. The tenant is remembered, so that resuming the job gets a new token for it.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preflightProbe, preflightProbeFlagName, true, "Before enumerating, check the permissions of the source and destination SAS, and probe Blob Storage with a request or two, "+
+		"so that a copy that isn't authorized fails at once rather than after the enumeration. At a blob destination, the probe stages a block that is never committed, under the destination path.")
 	// s2sGetPropertiesInBackend is an optional flag for controlling whether S3 object's or Azure file's full properties are get during enumerating in frontend or
 	// right before transferring in ste(backend).
 	// The traditional behavior of all existing enumerator is to get full properties during enumerating(more specifically listing),
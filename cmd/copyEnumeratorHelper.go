@@ -6,37 +6,41 @@ import (
 	"github.com/Azure/azure-storage-azcopy/ste"
 	"math/rand"
 	"strings"
+	"sync/atomic"
 
 	"github.com/Azure/azure-storage-azcopy/common"
 )
 
 var enumerationParallelism = 1
 var enumerationParallelStatFiles = false
+var blobListingStrategy = common.EBlobListingStrategy.Auto()
 
 // addTransfer accepts a new transfer, if the threshold is reached, dispatch a job part order.
 func addTransfer(e *common.CopyJobPartOrderRequest, transfer common.CopyTransfer, cca *cookedCopyCmdArgs) error {
 	// Remove the source and destination roots from the path to save space in the plan files
 	transfer.Source = strings.TrimPrefix(transfer.Source, e.SourceRoot.Value)
 	transfer.Destination = strings.TrimPrefix(transfer.Destination, e.DestinationRoot.Value)
+	cca.discovered.add(transfer)
+
+	return queueTransfer(e, transfer, cca)
+}
+
+// queueTransfer adds a transfer, whose paths are already relative to the order's roots, to the order. If the order
+// already has a full part's worth of transfers, they're dispatched first
+func queueTransfer(e *common.CopyJobPartOrderRequest, transfer common.CopyTransfer, cca *cookedCopyCmdArgs) error {
+	// when estimating, nothing is dispatched
+	if cca.estimator != nil {
+		cca.estimator.add(transfer)
+		return nil
+	}
 
 	// dispatch the transfers once the number reaches NumOfFilesPerDispatchJobPart
 	// we do this so that in the case of large transfer, the transfer engine can get started
 	// while the frontend is still gathering more transfers
 	if len(e.Transfers) == NumOfFilesPerDispatchJobPart {
-		shuffleTransfers(e.Transfers)
-		resp := common.CopyJobPartOrderResponse{}
-
-		Rpc(common.ERpcCmd.CopyJobPartOrder(), (*common.CopyJobPartOrderRequest)(e), &resp)
-
-		if !resp.JobStarted {
-			return fmt.Errorf("copy job part order with JobId %s and part number %d failed because %s", e.JobID, e.PartNum, resp.ErrorMsg)
-		}
-		// if the current part order sent to engine is 0, then start fetching the Job Progress summary.
-		if e.PartNum == 0 {
-			cca.waitUntilJobCompletion(false)
+		if err := dispatchPart(e, cca); err != nil {
+			return err
 		}
-		e.Transfers = []common.CopyTransfer{}
-		e.PartNum++
 	}
 
 	// only append the transfer after we've checked and dispatched a part
@@ -46,6 +50,123 @@ func addTransfer(e *common.CopyJobPartOrderRequest, transfer common.CopyTransfer
 	return nil
 }
 
+// dispatchPart sends the transfers gathered so far as a part of the job that isn't its last, so that more can follow
+func dispatchPart(e *common.CopyJobPartOrderRequest, cca *cookedCopyCmdArgs) error {
+	if err := cca.checkDestinationSpace(e.Transfers); err != nil {
+		return err
+	}
+	shuffleTransfers(e.Transfers)
+	e.PartNum = cca.nextPartNum
+	resp := common.CopyJobPartOrderResponse{}
+
+	Rpc(common.ERpcCmd.CopyJobPartOrder(), (*common.CopyJobPartOrderRequest)(e), &resp)
+
+	if !resp.JobStarted {
+		return fmt.Errorf("copy job part order with JobId %s and part number %d failed because %s", e.JobID, e.PartNum, resp.ErrorMsg)
+	}
+	// if the current part order sent to engine is 0, then start fetching the Job Progress summary.
+	if e.PartNum == 0 {
+		cca.waitUntilJobCompletion(false)
+	}
+	e.Transfers = []common.CopyTransfer{}
+	cca.nextPartNum++
+	return nil
+}
+
+// dispatchPendingTransfers sends whatever transfers haven't been sent yet, if there are any, without waiting for a
+// full part. A job fed by --follow-list-of-files uses it so that files added to the list slowly don't sit unsent.
+// The job still only finishes once dispatchFinalPart seals it, even if that final part then has no transfers of its own
+func dispatchPendingTransfers(e *common.CopyJobPartOrderRequest, cca *cookedCopyCmdArgs) error {
+	if cca.estimator != nil || len(e.Transfers) == 0 {
+		return nil
+	}
+	return dispatchPart(e, cca)
+}
+
+// discoveryCounter counts the files, and their bytes, that the enumeration has found so far, including those that
+// haven't been sent to the transfer engine yet, so that the progress of a long enumeration can be seen as it goes
+type discoveryCounter struct {
+	atomicBytes uint64 // first, for 64-bit alignment on 32-bit systems
+	atomicFiles uint32
+}
+
+func (d *discoveryCounter) add(transfer common.CopyTransfer) {
+	if d == nil || transfer.EntityType != common.EEntityType.File() {
+		return
+	}
+	atomic.AddUint32(&d.atomicFiles, 1)
+	atomic.AddUint64(&d.atomicBytes, uint64(transfer.SourceSize))
+}
+
+// Counts returns how many files have been found, and how many bytes they come to
+func (d *discoveryCounter) Counts() (files uint32, bytes uint64) {
+	if d == nil {
+		return 0, 0
+	}
+	return atomic.LoadUint32(&d.atomicFiles), atomic.LoadUint64(&d.atomicBytes)
+}
+
+// destinationSpaceTracker keeps a running total of how much a download will write, to compare with the free space
+// on the destination volume. Each part is counted just before it's dispatched, and the free space is looked up once,
+// before the first part. That way a job that can't fit is caught before it starts if it fits in one part, and otherwise
+// while it is still being enumerated, rather than by each of its transfers failing once the volume is full
+type destinationSpaceTracker struct {
+	lookedUp       bool // whether we've looked up the free space yet
+	freeSpaceKnown bool
+	freeSpace      uint64
+	bytesNeeded    uint64
+	exceeded       bool
+}
+
+// add counts the transfer towards the space needed, and returns true the first time that's more than is free
+func (t *destinationSpaceTracker) add(transfer common.CopyTransfer, getFreeSpace func() (uint64, error)) bool {
+	if !t.lookedUp {
+		t.lookedUp = true
+		free, err := getFreeSpace()
+		t.freeSpace, t.freeSpaceKnown = free, err == nil
+	}
+	if !t.freeSpaceKnown || t.exceeded {
+		return false
+	}
+
+	t.bytesNeeded += uint64(transfer.SourceSize)
+	t.exceeded = t.bytesNeeded > t.freeSpace
+	return t.exceeded
+}
+
+// addPart counts a part's transfers, just before the part is dispatched, and acts on --check-free-space if that
+// makes the download bigger than the destination's free space
+func (t *destinationSpaceTracker) addPart(transfers []common.CopyTransfer, option common.FreeSpaceCheckOption, destination string) error {
+	if option == common.EFreeSpaceCheckOption.None() {
+		return nil
+	}
+
+	getFreeSpace := func() (uint64, error) { return common.GetFreeSpace(destination) }
+	exceeded := false
+	for _, transfer := range transfers {
+		exceeded = t.add(transfer, getFreeSpace) || exceeded
+	}
+	if !exceeded {
+		return nil
+	}
+
+	msg := fmt.Sprintf("the files to download come to more than the %s that is free on the destination volume",
+		byteSizeToString(int64(t.freeSpace)))
+	if option == common.EFreeSpaceCheckOption.Fail() {
+		return fmt.Errorf("%s. If existing files will be overwritten, making room for them, run again with --check-free-space=Warn", msg)
+	}
+	glcm.Info("*** Warning *** " + strings.ToUpper(msg[:1]) + msg[1:] + ", so some transfers may fail")
+	return nil
+}
+
+// checkDestinationSpace counts a part of a download towards the destination's free space, before the part is dispatched
+func (cca *cookedCopyCmdArgs) checkDestinationSpace(transfers []common.CopyTransfer) error {
+	if !(cca.fromTo.IsDownload() || cca.fromTo == common.EFromTo.LocalLocal()) || cca.destination.Value == common.Dev_Null {
+		return nil
+	}
+	return cca.destinationSpace.addPart(transfers, cca.freeSpaceCheck, cca.destination.ValueLocal())
+}
+
 // this function shuffles the transfers before they are dispatched
 // this is done to avoid hitting the same partition continuously in an append only pattern
 // TODO this should probably be removed after the high throughput block blob feature is implemented on the service side
@@ -56,14 +177,23 @@ func shuffleTransfers(transfers []common.CopyTransfer) {
 // we need to send a last part with isFinalPart set to true, along with whatever transfers that still haven't been sent
 // dispatchFinalPart sends a last part with isFinalPart set to true, along with whatever transfers that still haven't been sent.
 func dispatchFinalPart(e *common.CopyJobPartOrderRequest, cca *cookedCopyCmdArgs) error {
+	if cca.estimator != nil {
+		cca.reportEstimate()
+		return nil
+	}
+
+	if err := cca.checkDestinationSpace(e.Transfers); err != nil {
+		return err
+	}
 	shuffleTransfers(e.Transfers)
+	e.PartNum = cca.nextPartNum
 	e.IsFinalPart = true
 	var resp common.CopyJobPartOrderResponse
 	Rpc(common.ERpcCmd.CopyJobPartOrder(), (*common.CopyJobPartOrderRequest)(e), &resp)
 
 	if !resp.JobStarted {
 		// Output the log location and such
-		glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), fmt.Sprintf("%s%s%s.log", azcopyLogPathFolder, common.OS_PATH_SEPARATOR, cca.jobID), cca.isCleanupJob, cca.cleanupJobMessage))
+		glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), jobLogFilePath(cca.jobID), cca.isCleanupJob, cca.cleanupJobMessage))
 
 		if resp.ErrorMsg == common.ECopyJobPartOrderErrorType.NoTransfersScheduledErr() {
 			return NothingScheduledError
@@ -61,3 +61,24 @@ func (s *copyEnumeratorHelperTestSuite) TestAddTransferPathRootsTrimmed(c *chk.C
 	c.Assert(request.Transfers[0].Source, chk.Equals, "c.txt")
 	c.Assert(request.Transfers[0].Destination, chk.Equals, "c.txt")
 }
+
+func (s *copyEnumeratorHelperTestSuite) TestAddTransferCountsDiscoveredFiles(c *chk.C) {
+	// setup
+	request := common.CopyJobPartOrderRequest{
+		SourceRoot:      newLocalRes("a/"),
+		DestinationRoot: newLocalRes("z/"),
+	}
+	cca := &cookedCopyCmdArgs{discovered: &discoveryCounter{}}
+
+	// execute
+	c.Assert(addTransfer(&request, common.CopyTransfer{Source: "a/b.txt", Destination: "z/b.txt", EntityType: common.EEntityType.File(), SourceSize: 10}, cca), chk.IsNil)
+	c.Assert(addTransfer(&request, common.CopyTransfer{Source: "a/c.txt", Destination: "z/c.txt", EntityType: common.EEntityType.File(), SourceSize: 5}, cca), chk.IsNil)
+	c.Assert(addTransfer(&request, common.CopyTransfer{Source: "a/d", Destination: "z/d", EntityType: common.EEntityType.Folder()}, cca), chk.IsNil)
+
+	// assert: folders aren't counted
+	files, bytes := cca.discovered.Counts()
+	c.Assert(files, chk.Equals, uint32(2))
+	c.Assert(bytes, chk.Equals, uint64(15))
+	c.Assert(formatScanning(false, files, bytes), chk.Equals, " (scanning... 2 files, 15.00 B found so far)")
+	c.Assert(formatScanning(true, files, bytes), chk.Equals, "")
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/azure-storage-file-go/azfile"
 
+	"github.com/Azure/azure-storage-azcopy/azbfs"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/Azure/azure-storage-azcopy/ste"
 )
@@ -39,9 +40,20 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 
 	if srcCredInfo, isPublic, err = getCredentialInfoForLocation(ctx, cca.fromTo.From(), cca.source.Value, cca.source.SAS, true); err != nil {
 		return nil, err
-		// If S2S and source takes OAuthToken as its cred type (OR) source takes anonymous as its cred type, but it's not public and there's no SAS
+	} else if cca.s2sSourceTenantID != "" && srcCredInfo.CredentialType == common.ECredentialType.OAuthToken() {
+		// With --s2s-source-tenant-id, the source of a blob to blob copy is authorized with its own bearer token, from that tenant
+		uotm := GetUserOAuthTokenManagerInstance()
+		srcTokenInfo, err := uotm.SwitchTenant(ctx, &srcCredInfo.OAuthTokenInfo, cca.s2sSourceTenantID, false)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get a token for the source tenant: %v", err)
+		}
+		srcCredInfo.OAuthTokenInfo = *srcTokenInfo
+		jobPartOrder.S2SSourceCredentialInfo = srcCredInfo
+		jobPartOrder.S2SSourceTenantID = cca.s2sSourceTenantID
+		// If S2S and source takes OAuthToken or SharedKey as its cred type (OR) source takes anonymous as its cred type, but it's not public and there's no SAS
 	} else if cca.fromTo.From().IsRemote() && cca.fromTo.To().IsRemote() &&
 		(srcCredInfo.CredentialType == common.ECredentialType.OAuthToken() ||
+			srcCredInfo.CredentialType == common.ECredentialType.SharedKey() ||
 			(srcCredInfo.CredentialType == common.ECredentialType.Anonymous() && !isPublic && cca.source.SAS == "")) {
 		// TODO: Generate a SAS token if it's blob -> *
 		return nil, errors.New("a SAS token (or S3 access key) is required as a part of the source in S2S transfers, unless the source is a public resource")
@@ -49,6 +61,11 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 
 	jobPartOrder.PreserveSMBPermissions = cca.preserveSMBPermissions
 	jobPartOrder.PreserveSMBInfo = cca.preserveSMBInfo
+	jobPartOrder.PermissionsOnly = cca.permissionsOnly
+	jobPartOrder.SkipLargerThan = cca.skipLargerThan
+	jobPartOrder.SkipSmallerThan = cca.skipSmallerThan
+	jobPartOrder.TransferTimeout = cca.transferTimeout
+	jobPartOrder.DeleteSourceAfterTransfer = cca.deleteSourceAfterTransfer
 
 	// Infer on download so that we get LMT and MD5 on files download
 	// On S2S transfers the following rules apply:
@@ -64,7 +81,7 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 	jobPartOrder.S2SInvalidMetadataHandleOption = cca.s2sInvalidMetadataHandleOption
 	jobPartOrder.S2SPreserveBlobTags = cca.s2sPreserveBlobTags
 
-	traverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, &cca.followSymlinks, cca.listOfFilesChannel, cca.recursive, getRemoteProperties,
+	traverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, &cca.followSymlinks, cca.danglingSymlinks, cca.listOfFilesChannel, cca.recursive, getRemoteProperties,
 		cca.includeDirectoryStubs, func(common.EntityType) {}, cca.listOfVersionIDs, cca.s2sPreserveBlobTags, cca.logVerbosity.ToPipelineLogLevel())
 
 	if err != nil {
@@ -79,6 +96,9 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 
 	// Check if the destination is a directory so we can correctly decide where our files land
 	isDestDir := cca.isDestDirectory(cca.destination, &ctx)
+	if err = cca.checkPageBlobTierAllowed(ctx); err != nil {
+		return nil, err
+	}
 	if cca.listOfVersionIDs != nil && (!(cca.fromTo == common.EFromTo.BlobLocal() || cca.fromTo == common.EFromTo.BlobTrash()) || isSourceDir || !isDestDir) {
 		log.Fatalf("Either source is not a blob or destination is not a local folder")
 	}
@@ -217,6 +237,11 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 		ste.JobsAdmin.LogToJobLog(message, pipeline.LogInfo)
 	}
 
+	var extraDestinations *fanOut
+	if len(cca.alsoTo) > 0 {
+		extraDestinations = newFanOut(jobPartOrder, cca.alsoTo)
+	}
+
 	processor := func(object storedObject) error {
 		// Start by resolving the name and creating the container
 		if object.containerName != "" {
@@ -249,7 +274,47 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 		}
 
 		srcRelPath := cca.makeEscapedRelativePath(true, isDestDir, object)
-		dstRelPath := cca.makeEscapedRelativePath(false, isDestDir, object)
+		dstObject := object
+		if len(cca.pathRewriter) > 0 && !object.isSourceRootFolder() {
+			if object.isSingleSourceFile() {
+				dstObject.name = cca.pathRewriter.rewrite(object.name)
+			} else {
+				dstObject.relativePath = cca.pathRewriter.rewrite(object.relativePath)
+			}
+
+			if dstObject.name == "" || (!object.isSingleSourceFile() && dstObject.relativePath == "") {
+				WarnStdoutAndJobLog(fmt.Sprintf("skipping %s as the path rewrite rules leave it with an empty name", object.relativePath))
+				return nil
+			}
+		}
+		if cca.nameCaseConverter != nil && !object.isSourceRootFolder() {
+			isFolder := object.entityType == common.EEntityType.Folder()
+			var renamed bool
+			if object.isSingleSourceFile() {
+				dstObject.name, renamed = cca.nameCaseConverter.convert(dstObject.name, isFolder)
+			} else {
+				dstObject.relativePath, renamed = cca.nameCaseConverter.convert(dstObject.relativePath, isFolder)
+			}
+			if renamed {
+				WarnStdoutAndJobLog(fmt.Sprintf("%s collides with another file once its case is converted, it is copied to %s instead",
+					object.relativePath, common.IffString(object.isSingleSourceFile(), dstObject.name, dstObject.relativePath)))
+			}
+		}
+		dstRelPath := cca.makeEscapedRelativePath(false, isDestDir, dstObject)
+		if cca.duplicateDestinations != nil && object.entityType == common.EEntityType.File() && !object.isSingleSourceFile() {
+			destination, alreadyScheduled, err := cca.duplicateDestinations.claim(srcRelPath, dstRelPath)
+			if err != nil {
+				return err
+			}
+			if alreadyScheduled {
+				return nil
+			}
+			if destination != dstRelPath {
+				WarnStdoutAndJobLog(fmt.Sprintf("%s would be copied to the same destination as another file, so it is copied to %s instead",
+					object.relativePath, destination))
+			}
+			dstRelPath = destination
+		}
 
 		transfer, shouldSendToSte := object.ToNewCopyTransfer(
 			cca.autoDecompress && cca.fromTo.IsDownload(),
@@ -260,19 +325,73 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 		if !cca.s2sPreserveBlobTags {
 			transfer.BlobTags = cca.blobTags
 		}
+		if len(cca.metadataRules) > 0 && transfer.EntityType == common.EEntityType.File() {
+			transfer.Metadata = cca.metadataRules.Apply(cca.metadataRulesBase(object), common.MetadataTemplateVars{
+				Name:             object.name,
+				RelativePath:     object.relativePath,
+				Size:             object.size,
+				LastModifiedTime: object.lastModifiedTime,
+			})
+			transfer.MetadataOverride = true
+		}
+		if cca.storeSourceLMT && transfer.EntityType == common.EEntityType.File() {
+			if transfer.Metadata == nil {
+				transfer.Metadata = cca.metadataRulesBase(object)
+			}
+			transfer.Metadata = transfer.Metadata.WithSourceLastModifiedTime(object.lastModifiedTime)
+			transfer.MetadataOverride = true
+		}
 
-		if shouldSendToSte {
-			return addTransfer(&jobPartOrder, transfer, cca)
+		if !shouldSendToSte {
+			return nil
 		}
-		return nil
+		if extraDestinations != nil {
+			if err := extraDestinations.add(transfer, cca); err != nil {
+				return err
+			}
+		}
+		return addTransfer(&jobPartOrder, transfer, cca)
 	}
 	finalizer := func() error {
+		if extraDestinations != nil {
+			if err := extraDestinations.dispatchPending(cca); err != nil {
+				return err
+			}
+		}
 		return dispatchFinalPart(&jobPartOrder, cca)
 	}
+	if list, ok := traverser.(*listTraverser); ok && cca.followListOfFiles {
+		list.flushPending = func() error {
+			if extraDestinations != nil {
+				if err := extraDestinations.dispatchPending(cca); err != nil {
+					return err
+				}
+			}
+			return dispatchPendingTransfers(&jobPartOrder, cca)
+		}
+	}
 
 	return newCopyEnumerator(traverser, filters, processor, finalizer), nil
 }
 
+// metadataRulesBase returns the metadata the metadata rules start from: the source's metadata, or for uploads the --metadata flag.
+func (cca *cookedCopyCmdArgs) metadataRulesBase(object storedObject) common.Metadata {
+	if !cca.fromTo.IsUpload() {
+		return object.Metadata
+	}
+
+	metadata := common.Metadata{}
+	if cca.metadata != "" {
+		for _, keyAndValue := range strings.Split(cca.metadata, ";") { // key/value pairs are separated by ';'
+			kv := strings.SplitN(keyAndValue, "=", 2) // key/value are separated by '='
+			if len(kv) == 2 {
+				metadata[kv[0]] = kv[1]
+			}
+		}
+	}
+	return metadata
+}
+
 // This is condensed down into an individual function as we don't end up re-using the destination traverser at all.
 // This is just for the directory check.
 func (cca *cookedCopyCmdArgs) isDestDirectory(dst common.ResourceString, ctx *context.Context) bool {
@@ -287,7 +406,7 @@ func (cca *cookedCopyCmdArgs) isDestDirectory(dst common.ResourceString, ctx *co
 		return false
 	}
 
-	rt, err := initResourceTraverser(dst, cca.fromTo.To(), ctx, &dstCredInfo, nil, nil, false,
+	rt, err := initResourceTraverser(dst, cca.fromTo.To(), ctx, &dstCredInfo, nil, nil, nil, false,
 		false, false, func(common.EntityType) {}, cca.listOfVersionIDs, false, pipeline.LogNone)
 
 	if err != nil {
@@ -297,6 +416,42 @@ func (cca *cookedCopyCmdArgs) isDestDirectory(dst common.ResourceString, ctx *co
 	return rt.isDirectory(false)
 }
 
+// checkPageBlobTierAllowed turns down an explicit --page-blob-tier when the destination account can't take it, which
+// would otherwise only be noticed once the transfers start, and then each of them would quietly use the default tier.
+// If the account info can't be read, e.g. because the SAS doesn't allow it, the transfers are left to check for themselves
+func (cca *cookedCopyCmdArgs) checkPageBlobTierAllowed(ctx context.Context) error {
+	if cca.pageBlobTier == common.EPageBlobTier.None() || cca.fromTo.To() != common.ELocation.Blob() || cca.estimator != nil {
+		return nil
+	}
+
+	dstCredInfo, _, err := getCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value, cca.destination.SAS, false)
+	if err != nil {
+		return nil
+	}
+	dstPipeline, err := initPipeline(ctx, cca.fromTo.To(), dstCredInfo, pipeline.LogNone)
+	if err != nil {
+		return nil
+	}
+	accountRoot, err := GetAccountRoot(cca.destination, cca.fromTo.To())
+	if err != nil {
+		return nil
+	}
+	dstURL, err := url.Parse(accountRoot)
+	if err != nil {
+		return nil
+	}
+
+	info, err := azblob.NewServiceURL(*dstURL, dstPipeline).GetAccountInfo(ctx)
+	if err != nil {
+		return nil
+	}
+	sku, kind := string(info.SkuName()), string(info.AccountKind())
+	if !ste.BlobTierAllowedOn(sku, kind, cca.pageBlobTier.ToAccessTierType()) {
+		return fmt.Errorf("the destination is a %s %s account, which cannot take the page blob tier %s. Page blob tiers need a premium StorageV2 account", sku, kind, cca.pageBlobTier)
+	}
+	return nil
+}
+
 // Initialize the modular filters outside of copy to increase readability.
 func (cca *cookedCopyCmdArgs) initModularFilters() []objectFilter {
 	filters := make([]objectFilter, 0) // same as []objectFilter{} under the hood
@@ -328,6 +483,16 @@ func (cca *cookedCopyCmdArgs) initModularFilters() []objectFilter {
 		}
 	}
 
+	filters = append(filters, buildDirectoryNameFilters(cca.includeDirectories, cca.excludeDirectories)...)
+
+	if cca.hiddenFiles != nil {
+		filters = append(filters, cca.hiddenFiles)
+	}
+
+	if cca.ignoreFiles != nil {
+		filters = append(filters, cca.ignoreFiles)
+	}
+
 	if len(cca.excludeBlobType) != 0 {
 		excludeSet := map[azblob.BlobType]bool{}
 
@@ -362,6 +527,11 @@ func (cca *cookedCopyCmdArgs) createDstContainer(containerName string, dstWithSA
 	}
 	existingContainers[containerName] = true
 
+	if cca.estimator != nil {
+		cca.estimator.addContainer()
+		return nil
+	}
+
 	dstCredInfo := common.CredentialInfo{}
 
 	if dstCredInfo, _, err = getCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value, cca.destination.SAS, false); err != nil {
@@ -442,6 +612,35 @@ func (cca *cookedCopyCmdArgs) createDstContainer(containerName string, dstWithSA
 		} else {
 			return err
 		}
+	case common.ELocation.BlobFS():
+		accountRoot, err := GetAccountRoot(dstWithSAS, cca.fromTo.To())
+
+		if err != nil {
+			return err
+		}
+
+		dstURL, err := url.Parse(accountRoot)
+
+		if err != nil {
+			return err
+		}
+
+		fsURL := azbfs.NewServiceURL(*dstURL, dstPipeline).NewFileSystemURL(containerName)
+		_, err = fsURL.GetProperties(ctx)
+
+		if err == nil {
+			return err // File system already exists, return gracefully
+		}
+
+		_, err = fsURL.Create(ctx)
+
+		if stgErr, ok := err.(azbfs.StorageError); ok {
+			if stgErr.ServiceCode() != azbfs.ServiceCodeFileSystemAlreadyExists {
+				return err
+			}
+		} else {
+			return err
+		}
 	default:
 		panic(fmt.Sprintf("cannot create a destination container at location %s.", cca.fromTo.To()))
 	}
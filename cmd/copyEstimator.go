@@ -0,0 +1,379 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// the number of objects returned by each listing call
+const estimateListPageSize = 5000
+
+// priceSheet gives the prices used to estimate the cost of a job, as loaded from a JSON file given by --price-sheet.
+// Prices vary by region, redundancy and tier, so there are no defaults. The source account is charged for reading and
+// listing the source, and for egress, and the destination account for writing, and for looking up existing destinations
+type priceSheet struct {
+	Currency    string
+	WritePer10K float64 // per 10,000 write operations (e.g. Put Blob, Put Block, Put Block List, Create File, Put Range)
+	ReadPer10K  float64 // per 10,000 read operations (e.g. Get Blob, Get Blob Properties)
+	ListPer10K  float64 // per 10,000 list operations
+	EgressPerGB float64 // per GiB read from a remote source. Leave it zero if the data stays within the region
+}
+
+func loadPriceSheet(path string) (*priceSheet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	prices := &priceSheet{}
+	if err = json.Unmarshal(data, prices); err != nil {
+		return nil, fmt.Errorf("the price sheet %s is not valid JSON: %s", path, err)
+	}
+	return prices, nil
+}
+
+// loadPriceSheets loads the value of --price-sheet, which is either one price sheet for both the source and destination
+// accounts, or the source's and the destination's, separated by a comma
+func loadPriceSheets(value string) (source *priceSheet, destination *priceSheet, err error) {
+	paths := strings.Split(value, ",")
+	if len(paths) > 2 {
+		return nil, nil, errors.New("price-sheet takes one price sheet, or the source's and the destination's separated by a comma")
+	}
+	if source, err = loadPriceSheet(paths[0]); err != nil {
+		return nil, nil, err
+	}
+	if len(paths) == 1 {
+		return source, source, nil
+	}
+
+	if destination, err = loadPriceSheet(paths[1]); err != nil {
+		return nil, nil, err
+	}
+	if source.Currency != destination.Currency {
+		return nil, nil, fmt.Errorf("the source's price sheet is in %s but the destination's is in %s", source.Currency, destination.Currency)
+	}
+	return source, destination, nil
+}
+
+// TransferEstimate is what a job is predicted to cost, before any of its data has been moved
+type TransferEstimate struct {
+	FileTransfers   uint64 `json:",string"`
+	FolderTransfers uint64 `json:",string"`
+	TotalBytes      uint64 `json:",string"`
+
+	WriteOperations uint64 `json:",string"`
+	ReadOperations  uint64 `json:",string"`
+	ListOperations  uint64 `json:",string"`
+	EgressBytes     uint64 `json:",string"` // bytes read from a remote source
+
+	Currency      string   `json:",omitempty"`
+	EstimatedCost *float64 `json:",omitempty"` // only if a price sheet was given
+}
+
+// transferEstimator adds up the requests that each transfer will make, as the job is enumerated.
+// It follows how the transfer engine divides files into chunks, but leaves out retries and the requests
+// made while enumerating (other than listing), so it's an approximation
+type transferEstimator struct {
+	fromTo            common.FromTo
+	blockSize         int64 // as given by the user, or 0 to size blocks as the transfer engine does
+	blobType          common.BlobType
+	checkDestination  bool // whether each destination is looked up before it's written, e.g. with --overwrite=false
+	sourcePrices      *priceSheet
+	destinationPrices *priceSheet
+
+	// whether the source is listed a directory at a time, rather than all in one listing, and whether
+	// an extra call is made first, to choose between the two
+	listsPerDirectory bool
+	probesListing     bool
+
+	estimate                  TransferEstimate
+	destinationReadOperations uint64 // included in estimate.ReadOperations
+
+	// the number of entries (files and sub-directories) in each directory of the source, by relative path, if it's
+	// listed a directory at a time
+	listedDirectories map[string]uint64
+}
+
+// cookEstimator returns the estimator for --estimate, or nil if the job is to be run
+func (raw rawCopyCmdArgs) cookEstimator(cooked *cookedCopyCmdArgs) (*transferEstimator, error) {
+	if !raw.estimate {
+		if raw.priceSheet != "" {
+			return nil, errors.New("price-sheet is set but estimate is not")
+		}
+		return nil, nil
+	}
+
+	for _, l := range []common.Location{cooked.fromTo.From(), cooked.fromTo.To()} {
+		if l == common.ELocation.Pipe() || l == common.ELocation.Benchmark() || l == common.ELocation.Unknown() || l == common.ELocation.None() {
+			return nil, fmt.Errorf("estimate is not supported for %s", cooked.fromTo)
+		}
+	}
+
+	estimator := &transferEstimator{
+		fromTo:           cooked.fromTo,
+		blockSize:        cooked.blockSize,
+		blobType:         cooked.blobType,
+		checkDestination: cooked.forceWrite != common.EOverwriteOption.True(),
+	}
+	estimator.listsPerDirectory, estimator.probesListing = sourceListingOf(cooked.fromTo.From(), cooked.recursive)
+	if raw.priceSheet != "" {
+		var err error
+		if estimator.sourcePrices, estimator.destinationPrices, err = loadPriceSheets(raw.priceSheet); err != nil {
+			return nil, err
+		}
+	}
+	return estimator, nil
+}
+
+// sourceListingOf says whether the source will be listed a directory at a time, as the traversers do it, and whether
+// a call will be made first to choose how to list it. Azure Files has to be listed a directory at a time. Blob storage
+// is too, when it's listed hierarchically, or partitioned (which finds its partitions by listing hierarchically). When
+// the strategy is auto, the first page of a hierarchical listing is used to choose between those two.
+func sourceListingOf(source common.Location, recursive bool) (listsPerDirectory bool, probesListing bool) {
+	switch source {
+	case common.ELocation.File():
+		return true, false
+	case common.ELocation.Blob():
+		if blobListingStrategy == common.EBlobListingStrategy.Hierarchical() {
+			return true, false
+		}
+		concurrent := recursive && enumerationParallelism > 1
+		return concurrent, concurrent && blobListingStrategy == common.EBlobListingStrategy.Auto()
+	default:
+		return false, false
+	}
+}
+
+func (e *transferEstimator) addContainer() {
+	e.estimate.WriteOperations++
+}
+
+func (e *transferEstimator) add(transfer common.CopyTransfer) {
+	size := transfer.SourceSize
+	if size < 0 {
+		size = 0
+	}
+	if e.listsPerDirectory {
+		e.addListedEntry(transfer)
+	}
+
+	if transfer.EntityType == common.EEntityType.Folder() {
+		e.estimate.FolderTransfers++
+		// of the remote destinations, only blob storage has no real folders
+		if e.fromTo.To().IsRemote() && e.fromTo.To() != common.ELocation.Blob() {
+			e.estimate.WriteOperations++
+		}
+		return
+	}
+
+	e.estimate.FileTransfers++
+	e.estimate.TotalBytes += uint64(size)
+	blockSize := e.blockSizeFor(size)
+
+	// reading the source
+	if e.fromTo.From().IsRemote() {
+		readChunkSize := blockSize
+		if e.fromTo.To() == common.ELocation.File() {
+			readChunkSize = common.DefaultAzureFileChunkSize // service to service copies read as much as each range written
+		}
+		e.estimate.ReadOperations += chunkCount(size, readChunkSize)
+		e.estimate.EgressBytes += uint64(size)
+	}
+
+	// writing the destination
+	if e.checkDestination && e.fromTo.To().IsRemote() {
+		e.estimate.ReadOperations++
+		e.destinationReadOperations++
+	}
+	switch e.fromTo.To() {
+	case common.ELocation.Blob():
+		switch e.destinationBlobType(transfer) {
+		case common.EBlobType.PageBlob():
+			e.estimate.WriteOperations += 1 + chunkCount(size, common.DefaultPageBlobChunkSize)
+		case common.EBlobType.AppendBlob():
+			e.estimate.WriteOperations += 1 + chunkCount(size, common.MaxAppendBlobBlockSize)
+		default:
+			if chunks := chunkCount(size, blockSize); chunks <= 1 {
+				e.estimate.WriteOperations++ // a single Put Blob
+			} else {
+				e.estimate.WriteOperations += chunks + 1 // the blocks, then the block list
+			}
+		}
+	case common.ELocation.File():
+		e.estimate.WriteOperations += 1 + chunkCount(size, common.DefaultAzureFileChunkSize) // create, then the ranges
+	case common.ELocation.BlobFS():
+		e.estimate.WriteOperations += 2 + chunkCount(size, blockSize) // create, the appends, then the flush
+	case common.ELocation.ManagedDisk():
+		e.estimate.WriteOperations += chunkCount(size, common.DefaultPageBlobChunkSize) // the disk already exists, so just the pages
+	}
+}
+
+// addListedEntry counts the transfer's source in the listing of its directory, or, for a folder, makes sure the folder
+// itself is counted as being listed
+func (e *transferEstimator) addListedEntry(transfer common.CopyTransfer) {
+	if e.listedDirectories == nil {
+		e.listedDirectories = make(map[string]uint64)
+	}
+	relativePath := strings.Trim(transfer.Source, common.AZCOPY_PATH_SEPARATOR_STRING)
+	if transfer.EntityType == common.EEntityType.Folder() {
+		e.addListedDirectory(relativePath)
+		return
+	}
+	dir := parentDirectoryOf(relativePath)
+	e.addListedDirectory(dir)
+	e.listedDirectories[dir]++
+}
+
+// addListedDirectory makes sure the directory, and those above it, are counted as being listed
+func (e *transferEstimator) addListedDirectory(dir string) {
+	if _, seen := e.listedDirectories[dir]; seen {
+		return
+	}
+	e.listedDirectories[dir] = 0
+	if dir != "" {
+		parent := parentDirectoryOf(dir)
+		e.addListedDirectory(parent)
+		e.listedDirectories[parent]++ // the directory is an entry in its parent's listing
+	}
+}
+
+// parentDirectoryOf returns the directory that the relative path is in, which is "" for the root
+func parentDirectoryOf(relativePath string) string {
+	if i := strings.LastIndex(relativePath, common.AZCOPY_PATH_SEPARATOR_STRING); i >= 0 {
+		return relativePath[:i]
+	}
+	return ""
+}
+
+// blockSizeFor sizes blocks as the transfer engine does, keeping within the maximum number of blocks per blob
+func (e *transferEstimator) blockSizeFor(size int64) int64 {
+	if e.blockSize != 0 {
+		return e.blockSize
+	}
+	blockSize := int64(common.DefaultBlockBlobBlockSize)
+	for ; size/blockSize > common.MaxNumberOfBlocksPerBlob; blockSize = 2 * blockSize {
+		if blockSize > common.BlockSizeThreshold {
+			blockSize = size / common.MaxNumberOfBlocksPerBlob
+			break
+		}
+	}
+	return common.Iffint64(blockSize > common.MaxBlockBlobBlockSize, common.MaxBlockBlobBlockSize, blockSize)
+}
+
+func (e *transferEstimator) destinationBlobType(transfer common.CopyTransfer) common.BlobType {
+	if e.blobType != common.EBlobType.Detect() {
+		return e.blobType
+	}
+	switch transfer.BlobType {
+	case azblob.BlobPageBlob:
+		return common.EBlobType.PageBlob()
+	case azblob.BlobAppendBlob:
+		return common.EBlobType.AppendBlob()
+	default:
+		return common.EBlobType.BlockBlob()
+	}
+}
+
+// chunkCount is the number of requests needed to move size bytes, chunkSize at a time
+func chunkCount(size int64, chunkSize int64) uint64 {
+	if size <= 0 || chunkSize <= 0 {
+		return 0
+	}
+	return uint64((size + chunkSize - 1) / chunkSize)
+}
+
+// result completes the estimate once the whole job has been enumerated
+func (e *transferEstimator) result() TransferEstimate {
+	estimate := e.estimate
+	if e.fromTo.From().IsRemote() {
+		switch {
+		case !e.listsPerDirectory:
+			estimate.ListOperations = 1 + (estimate.FileTransfers+estimate.FolderTransfers)/estimateListPageSize
+		case len(e.listedDirectories) == 0:
+			estimate.ListOperations = 1 // the root is listed, even if nothing is found
+		default:
+			for _, entries := range e.listedDirectories {
+				estimate.ListOperations += 1 + entries/estimateListPageSize
+			}
+		}
+		if e.probesListing {
+			estimate.ListOperations++
+		}
+	}
+
+	if e.sourcePrices != nil {
+		sourceReads := estimate.ReadOperations - e.destinationReadOperations
+		cost := float64(sourceReads)/10000*e.sourcePrices.ReadPer10K +
+			float64(estimate.ListOperations)/10000*e.sourcePrices.ListPer10K +
+			float64(estimate.EgressBytes)/(1024*1024*1024)*e.sourcePrices.EgressPerGB +
+			float64(estimate.WriteOperations)/10000*e.destinationPrices.WritePer10K +
+			float64(e.destinationReadOperations)/10000*e.destinationPrices.ReadPer10K
+		estimate.EstimatedCost = &cost
+		estimate.Currency = e.sourcePrices.Currency
+	}
+	return estimate
+}
+
+// reportEstimate outputs the estimate, in place of running the job, and exits
+func (cca *cookedCopyCmdArgs) reportEstimate() {
+	estimate := cca.estimator.result()
+
+	glcm.Exit(func(format common.OutputFormat) string {
+		if format == common.EOutputFormat.Json() {
+			jsonOutput, err := json.Marshal(estimate)
+			common.PanicIfErr(err)
+			return string(jsonOutput)
+		}
+
+		costString := "(give a price sheet with --price-sheet to estimate the cost)"
+		if estimate.EstimatedCost != nil {
+			costString = fmt.Sprintf("%.2f %s", *estimate.EstimatedCost, estimate.Currency)
+		}
+		return fmt.Sprintf(
+			`
+Estimate (no data has been moved; retries are not included)
+Number of File Transfers: %v
+Number of Folder Property Transfers: %v
+Total Bytes: %v (%s)
+Write Operations: %v
+Read Operations: %v
+List Operations: %v
+Bytes Read from a Remote Source (egress, if it leaves the region): %v
+Estimated Cost: %s
+`,
+			estimate.FileTransfers,
+			estimate.FolderTransfers,
+			estimate.TotalBytes,
+			byteSizeToString(int64(estimate.TotalBytes)),
+			estimate.WriteOperations,
+			estimate.ReadOperations,
+			estimate.ListOperations,
+			estimate.EgressBytes,
+			costString)
+	}, common.EExitCode.Success())
+}
@@ -0,0 +1,70 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// fanOut sends each transfer that an upload finds to the extra destinations given with --also-to, as well as to the
+// main one, so that the source is only scanned once. A job part has a single destination root, so each extra destination
+// has job part orders of its own, but they're all parts of the same job. Every transfer has its own status, so a
+// destination that fails doesn't hold up the others, and the failed transfers say which destination they were for
+type fanOut struct {
+	orders []*common.CopyJobPartOrderRequest // one for each extra destination
+}
+
+func newFanOut(template common.CopyJobPartOrderRequest, destinations []common.ResourceString) *fanOut {
+	f := &fanOut{}
+	for _, dst := range destinations {
+		order := template
+		order.DestinationRoot = dst
+		order.Transfers = nil
+		f.orders = append(f.orders, &order)
+	}
+	return f
+}
+
+// add queues the transfer, with its paths as the processor made them for the main destination, for each extra destination
+func (f *fanOut) add(transfer common.CopyTransfer, cca *cookedCopyCmdArgs) error {
+	for _, e := range f.orders {
+		t := transfer
+		t.Source = strings.TrimPrefix(t.Source, e.SourceRoot.Value)
+		t.Destination = strings.TrimPrefix(t.Destination, e.DestinationRoot.Value)
+		if err := queueTransfer(e, t, cca); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatchPending sends on whatever transfers the extra destinations still have. It must be called before the main
+// order's final part is dispatched, since that seals the job
+func (f *fanOut) dispatchPending(cca *cookedCopyCmdArgs) error {
+	for _, e := range f.orders {
+		if err := dispatchPendingTransfers(e, cca); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/azbfs"
@@ -95,12 +96,26 @@ func (util copyHandlerUtil) redactSigQueryParam(rawQuery string) (bool, string)
 // If any argument passed is an http Url and contains the signature, then the signature is redacted
 func (util copyHandlerUtil) ConstructCommandStringFromArgs() string {
 	// Get the os Args and strip away the first argument since it will be the path of Azcopy executable
-	args := os.Args[1:]
+	return util.redactCommandArgs(os.Args[1:])
+}
+
+// redactCommandArgs joins the arguments of a command into a string, with the signatures of any SAS tokens redacted
+func (util copyHandlerUtil) redactCommandArgs(args []string) string {
 	if len(args) == 0 {
 		return ""
 	}
 	s := strings.Builder{}
-	for _, arg := range args {
+	for _, arg := range util.redactArgs(args) {
+		s.WriteString(arg)
+		s.WriteString(" ")
+	}
+	return s.String()
+}
+
+// redactArgs returns a copy of the arguments of a command, with the signatures of any SAS tokens redacted
+func (util copyHandlerUtil) redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
 		// If the argument starts with http, it is either the remote source or remote destination
 		// If there exists a signature in the argument string it needs to be redacted
 		if startsWith(arg, "http") {
@@ -113,13 +128,12 @@ func (util copyHandlerUtil) ConstructCommandStringFromArgs() string {
 			// Check for the signature query parameter
 			_, rawQuery := util.redactSigQueryParam(argUrl.RawQuery)
 			argUrl.RawQuery = rawQuery
-			s.WriteString(argUrl.String())
+			redacted[i] = argUrl.String()
 		} else {
-			s.WriteString(arg)
+			redacted[i] = arg
 		}
-		s.WriteString(" ")
 	}
-	return s.String()
+	return redacted
 }
 
 func (util copyHandlerUtil) urlIsBFSFileSystemOrDirectory(ctx context.Context, url *url.URL, p pipeline.Pipeline) bool {
@@ -200,6 +214,22 @@ func (util copyHandlerUtil) doesBlobRepresentAFolder(metadata azblob.Metadata) b
 	return metadata["hdi_isfolder"] == "true"
 }
 
+// expandDestinationTemplate replaces the job variables in a destination, so that scheduled runs can write to e.g. dated folders.
+// Supported tokens are {date} (2006-01-02), {time} (150405), {jobid} and {hostname}. Anything else is left as is.
+// The caller passes now in UTC, so that runs on machines in different time zones agree on the folder names.
+func (copyHandlerUtil) expandDestinationTemplate(destination string, jobID common.JobID, now time.Time, hostname string) string {
+	if !strings.Contains(destination, "{") {
+		return destination
+	}
+
+	return strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("150405"),
+		"{jobid}", jobID.String(),
+		"{hostname}", hostname,
+	).Replace(destination)
+}
+
 func startsWith(s string, t string) bool {
 	return len(s) >= len(t) && strings.EqualFold(s[0:len(t)], t)
 }
@@ -23,6 +23,9 @@ package cmd
 import (
 	chk "gopkg.in/check.v1"
 	"net/url"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
 )
 
 type copyUtilTestSuite struct{}
@@ -79,3 +82,15 @@ func (s *copyUtilTestSuite) TestIPIsContainerOrBlob(c *chk.C) {
 	c.Assert(isContainerURL, chk.Equals, true) // URL endpoints do not contain the account in the path, making the container the first entry.
 	// The behaviour isn't too different from here.
 }
+
+func (s *copyUtilTestSuite) TestExpandDestinationTemplate(c *chk.C) {
+	util := copyHandlerUtil{}
+	jobID := common.NewJobID()
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	expanded := util.expandDestinationTemplate("https://account.blob.core.windows.net/backups/{hostname}/{date}/{time}-{jobid}?sig=x", jobID, now, "myhost")
+	c.Assert(expanded, chk.Equals, "https://account.blob.core.windows.net/backups/myhost/2021-03-04/050607-"+jobID.String()+"?sig=x")
+
+	// unknown tokens are left untouched
+	c.Assert(util.expandDestinationTemplate("/data/{unknown}/{date}", jobID, now, "myhost"), chk.Equals, "/data/{unknown}/2021-03-04")
+}
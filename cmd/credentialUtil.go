@@ -26,6 +26,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -196,6 +197,11 @@ func getBlobCredentialType(ctx context.Context, blobResourceURL string, canBePub
 	if !oAuthTokenExists() { // no oauth token found, then directly return anonymous credential
 		isPublicResource := checkPublic()
 
+		// a public resource needs no key, which matters for S2S sources, where SharedKey can't be used
+		if !isPublicResource && sharedKeyExists(resourceURL) {
+			return common.ECredentialType.SharedKey(), false, nil
+		}
+
 		// No forms of auth are present.no SAS token or OAuth token is present and the resource is not public
 		if !isPublicResource {
 			return common.ECredentialType.Unknown(), isPublicResource,
@@ -240,9 +246,7 @@ func getBlobFSCredentialType(ctx context.Context, blobResourceURL string, standa
 		return common.ECredentialType.OAuthToken(), nil
 	}
 
-	name := glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AccountName())
-	key := glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AccountKey())
-	if name != "" && key != "" { // TODO: To remove, use for internal testing, SharedKey should not be supported from commandline
+	if sharedKeyExists(resourceURL) {
 		return common.ECredentialType.SharedKey(), nil
 	} else {
 		return common.ECredentialType.Unknown(),
@@ -276,9 +280,45 @@ func oAuthTokenExists() (oauthTokenExists bool) {
 	return
 }
 
+var announceSharedKeyOnce sync.Once
+
+// sharedKeyExists checks whether an account name and key were supplied through the environment, for the account that the resource is in.
+// The key is only good for the account named in ACCOUNT_NAME, so resources in any other account must be authorized some other way.
+// SharedKey is intended for emulator work and legacy automation, so we warn loudly whenever it is picked up.
+func sharedKeyExists(resourceURL *url.URL) bool {
+	if !common.SharedKeyEnvVarsExist() || !common.SharedKeyEnvVarsMatchAccount(storageAccountNameOf(resourceURL)) {
+		return false
+	}
+
+	announceSharedKeyOnce.Do(func() {
+		glcm.Info(fmt.Sprintf("*** WARNING *** %s and %s are set. AzCopy will authenticate with the storage account key, "+
+			"which grants full access to the account. Prefer SAS or Azure AD authentication where possible.",
+			common.EEnvironmentVariable.AccountName().Name, common.EEnvironmentVariable.AccountKey().Name))
+	})
+	return true
+}
+
+// storageAccountNameOf returns the account of a Blob, Files or ADLS Gen2 URL. In the IP endpoint style used by emulators
+// (e.g. http://127.0.0.1:10000/devstoreaccount1/container) the account is the first path segment, otherwise it's the first label of the host
+func storageAccountNameOf(resourceURL *url.URL) string {
+	if net.ParseIP(resourceURL.Hostname()) != nil || strings.EqualFold(resourceURL.Hostname(), "localhost") {
+		return strings.SplitN(strings.TrimPrefix(resourceURL.Path, "/"), "/", 2)[0]
+	}
+	return strings.Split(resourceURL.Hostname(), ".")[0]
+}
+
 // getAzureFileCredentialType is used to get Azure file's credential type
-func getAzureFileCredentialType() (common.CredentialType, error) {
-	// Azure file only support anonymous credential currently.
+func getAzureFileCredentialType(fileResourceURL string, standaloneSAS bool) (common.CredentialType, error) {
+	resourceURL, err := url.Parse(fileResourceURL)
+	if err != nil {
+		return common.ECredentialType.Unknown(), errors.New("provided file resource string is not in URL format")
+	}
+
+	// Azure file supports SAS, and SharedKey from environment variables when no SAS is present.
+	sas := azfile.NewFileURLParts(*resourceURL).SAS
+	if isSASExisted := sas.Signature() != ""; !isSASExisted && !standaloneSAS && sharedKeyExists(resourceURL) {
+		return common.ECredentialType.SharedKey(), nil
+	}
 	return common.ECredentialType.Anonymous(), nil
 }
 
@@ -409,7 +449,7 @@ func checkAuthSafeForTarget(ct common.CredentialType, resource, extraSuffixesAAD
 			return fmt.Errorf(
 				"s3 authentication to %s is not currently suported in AzCopy", host)
 		}
-	case common.ECredentialType.GoogleAppCredentials():
+	case common.ECredentialType.GoogleAppCredentials(), common.ECredentialType.GoogleHMAC():
 		if resourceType != common.ELocation.GCP() {
 			return fmt.Errorf("Google Application Credentials to %s is not valid", resourceType.String())
 		}
@@ -431,7 +471,7 @@ func checkAuthSafeForTarget(ct common.CredentialType, resource, extraSuffixesAAD
 }
 
 func logAuthType(ct common.CredentialType, location common.Location, isSource bool) {
-	if location == common.ELocation.Unknown() {
+	if location == common.ELocation.Unknown() || location == common.ELocation.None() {
 		return // nothing to log
 	} else if location.IsLocal() {
 		return // don't log local ones, no point
@@ -483,7 +523,7 @@ func doGetCredentialTypeForLocation(ctx context.Context, location common.Locatio
 				return common.ECredentialType.Unknown(), false, err
 			}
 		case common.ELocation.File():
-			if credType, err = getAzureFileCredentialType(); err != nil {
+			if credType, err = getAzureFileCredentialType(resource, resourceSAS != ""); err != nil {
 				return common.ECredentialType.Unknown(), false, err
 			}
 		case common.ELocation.BlobFS():
@@ -507,10 +547,16 @@ func doGetCredentialTypeForLocation(ctx context.Context, location common.Locatio
 			credType = common.ECredentialType.S3AccessKey()
 		case common.ELocation.GCP():
 			googleAppCredentials := glcm.GetEnvironmentVariable(common.EEnvironmentVariable.GoogleAppCredentials())
-			if googleAppCredentials == "" {
-				return common.ECredentialType.Unknown(), false, errors.New("GOOGLE_APPLICATION_CREDENTIALS environment variable must be set before using GCP transfer feature")
+			if googleAppCredentials != "" {
+				credType = common.ECredentialType.GoogleAppCredentials()
+			} else if common.UseGCPHMACKey() {
+				credType = common.ECredentialType.GoogleHMAC()
+			} else {
+				return common.ECredentialType.Unknown(), false, errors.New("GOOGLE_APPLICATION_CREDENTIALS environment variable (or AZCOPY_GCS_HMAC_ACCESS_ID and AZCOPY_GCS_HMAC_SECRET, for an HMAC key) must be set before using GCP transfer feature")
 			}
-			credType = common.ECredentialType.GoogleAppCredentials()
+		case common.ELocation.ManagedDisk():
+			// the disk's import URL is the only way in, and it carries its own SAS
+			return common.ECredentialType.Unknown(), false, errors.New("a managed disk can only be reached through the SAS URL granted for importing its data")
 		}
 	}
 
@@ -518,10 +564,27 @@ func doGetCredentialTypeForLocation(ctx context.Context, location common.Locatio
 		return common.ECredentialType.Unknown(), false, err
 	}
 
+	// secrets kept in Key Vault are fetched now, since the STE, which creates the credentials that use them, can't report a failure to fetch them
+	if err = resolveSecretsForCredentialType(ctx, credType); err != nil {
+		return common.ECredentialType.Unknown(), false, err
+	}
+
 	logAuthType(credType, location, isSource)
 	return
 }
 
+// resolveSecretsForCredentialType fetches, from Key Vault, any secret that the credential type needs and whose environment variable references one there
+func resolveSecretsForCredentialType(ctx context.Context, credType common.CredentialType) error {
+	switch credType {
+	case common.ECredentialType.SharedKey():
+		return common.ResolveSecretEnvironmentVariable(ctx, common.EEnvironmentVariable.AccountKey())
+	case common.ECredentialType.S3AccessKey():
+		return common.ResolveSecretEnvironmentVariable(ctx, common.EEnvironmentVariable.AWSSecretAccessKey())
+	default:
+		return nil
+	}
+}
+
 func getCredentialInfoForLocation(ctx context.Context, location common.Location, resource, resourceSAS string, isSource bool) (credInfo common.CredentialInfo, isPublic bool, err error) {
 
 	// get the type
@@ -556,9 +619,12 @@ func getCredentialType(ctx context.Context, raw rawFromToInfo) (credType common.
 		credType, _, err = getCredentialTypeForLocation(ctx, raw.fromTo.To(), raw.destination, raw.destinationSAS, false)
 	case raw.fromTo == common.EFromTo.BlobTrash() ||
 		raw.fromTo == common.EFromTo.BlobFSTrash() ||
-		raw.fromTo == common.EFromTo.FileTrash():
-		// For to Trash direction, use source as resource URL
+		raw.fromTo == common.EFromTo.FileTrash() ||
+		raw.fromTo == common.EFromTo.BlobNone():
+		// For to Trash direction, and in-place changes, use source as resource URL
 		credType, _, err = getCredentialTypeForLocation(ctx, raw.fromTo.From(), raw.source, raw.sourceSAS, true)
+	case raw.fromTo == common.EFromTo.LocalLocal():
+		credType = common.ECredentialType.Anonymous() // there's nothing to authenticate to
 	case raw.fromTo.From().IsRemote() && raw.fromTo.To().IsLocal():
 		// we authenticate to the source.
 		credType, _, err = getCredentialTypeForLocation(ctx, raw.fromTo.From(), raw.source, raw.sourceSAS, true)
@@ -646,8 +712,12 @@ func createBlobFSPipeline(ctx context.Context, credInfo common.CredentialInfo, l
 	), nil
 }
 
-// TODO note: ctx and credInfo are ignored at the moment because we only support SAS for Azure File
 func createFilePipeline(ctx context.Context, credInfo common.CredentialInfo, logLevel pipeline.LogLevel) (pipeline.Pipeline, error) {
+	credential := common.CreateFileCredential(ctx, credInfo, common.CredentialOpOptions{
+		//LogInfo:  glcm.Info, //Comment out for debugging
+		LogError: glcm.Info,
+	})
+
 	logOption := pipeline.LogOptions{}
 	if azcopyScanningLogger != nil {
 		logOption = pipeline.LogOptions{
@@ -657,7 +727,7 @@ func createFilePipeline(ctx context.Context, credInfo common.CredentialInfo, log
 	}
 
 	return ste.NewFilePipeline(
-		azfile.NewAnonymousCredential(),
+		credential,
 		azfile.PipelineOptions{
 			Telemetry: azfile.TelemetryOptions{
 				Value: glcm.AddUserAgentPrefix(common.UserAgent),
@@ -0,0 +1,137 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronExpression is a standard five-field cron expression: minute, hour, day of month, month and day of week, each of which
+// may be *, a number, a range (1-5), a list (1,3,5), or any of those with a step (*/15, 0-30/10)
+type cronExpression struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek uint64 // bit n is set if value n matches
+
+	// as in cron, if both the day of month and the day of week are restricted, a day matching either one matches
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+func parseCronExpression(s string) (cronExpression, error) {
+	if expanded, ok := cronMacros[strings.TrimSpace(s)]; ok {
+		s = expanded
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return cronExpression{}, fmt.Errorf("'%s' is not a cron expression. It needs five fields: minute, hour, day of month, month and day of week", s)
+	}
+
+	var e cronExpression
+	var err error
+	if e.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return cronExpression{}, fmt.Errorf("bad minute in '%s': %w", s, err)
+	}
+	if e.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return cronExpression{}, fmt.Errorf("bad hour in '%s': %w", s, err)
+	}
+	if e.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return cronExpression{}, fmt.Errorf("bad day of month in '%s': %w", s, err)
+	}
+	if e.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return cronExpression{}, fmt.Errorf("bad month in '%s': %w", s, err)
+	}
+	if e.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return cronExpression{}, fmt.Errorf("bad day of week in '%s': %w", s, err)
+	}
+	if e.daysOfWeek&(1<<7) != 0 {
+		e.daysOfWeek |= 1 // 7 is Sunday too
+	}
+	e.anyDayOfMonth = strings.HasPrefix(fields[2], "*")
+	e.anyDayOfWeek = strings.HasPrefix(fields[4], "*")
+	return e, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			rangePart = item[:i]
+			var err error
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("'%s' has a bad step", item)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("'%s' is not a number or range", item)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("'%s' is not a number or range", item)
+				}
+			} else if step > 1 {
+				high = max // as in cron, 5/15 means every 15 starting at 5
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("'%s' is outside %d-%d", item, min, max)
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// matches says whether the expression is due in the minute of the given time
+func (e cronExpression) matches(t time.Time) bool {
+	if e.minutes&(1<<uint(t.Minute())) == 0 || e.hours&(1<<uint(t.Hour())) == 0 || e.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	dayOfMonth := e.daysOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := e.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	switch {
+	case e.anyDayOfMonth && e.anyDayOfWeek:
+		return true
+	case e.anyDayOfMonth:
+		return dayOfWeek
+	case e.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// how many destination directories the converter remembers the names of
+const caseConvertedDirsRemembered = 10000
+
+// destinationNameCaseConverter converts the relative paths of the transfers to lower or upper case on the destination.
+// Files whose names only differ by case at the source would collide at the destination,
+// so the converter remembers which source path claimed every converted name in a directory, and resolves later collisions by adding a suffix
+// before the extension, e.g. Report.PDF and report.pdf become report.pdf and report-2.pdf. A suffix is never given if a source file
+// would convert to the same name, so that a renamed file doesn't take the name of a real one.
+// So that memory use is bounded, only the most recently used directories are remembered. Traversals deal with the files of a
+// directory together, so a directory is long finished with by the time it's forgotten
+type destinationNameCaseConverter struct {
+	toUpper bool
+
+	// listSourceDir, if set, lists the names in the source directory of a relative path, so that suffixes can avoid the names
+	// of files that are yet to be seen. Without it, only the names of the files seen so far are avoided
+	listSourceDir func(relativeDir string) ([]string, error)
+
+	mu     sync.Mutex
+	dirs   map[string]*list.Element // converted directory -> its element in recent
+	recent *list.List               // of *caseConvertedDir, most recently used at the front
+}
+
+// caseConvertedDir is what the converter remembers about one destination directory
+type caseConvertedDir struct {
+	path        string
+	claimed     map[string]string   // converted name -> source path which got it
+	sourceNames map[string]struct{} // converted names of the source files, seen or listed
+	listed      map[string]struct{} // source directories already listed into sourceNames
+}
+
+func newDestinationNameCaseConverter(toUpper bool) *destinationNameCaseConverter {
+	return &destinationNameCaseConverter{toUpper: toUpper, dirs: make(map[string]*list.Element), recent: list.New()}
+}
+
+func (c *destinationNameCaseConverter) convertCase(s string) string {
+	if c.toUpper {
+		return strings.ToUpper(s)
+	}
+	return strings.ToLower(s)
+}
+
+// dir returns what is remembered about the converted directory, forgetting the least recently used one if there are too many
+func (c *destinationNameCaseConverter) dir(convertedDir string) *caseConvertedDir {
+	if e, ok := c.dirs[convertedDir]; ok {
+		c.recent.MoveToFront(e)
+		return e.Value.(*caseConvertedDir)
+	}
+
+	d := &caseConvertedDir{path: convertedDir, claimed: make(map[string]string), sourceNames: make(map[string]struct{}), listed: make(map[string]struct{})}
+	c.dirs[convertedDir] = c.recent.PushFront(d)
+	if c.recent.Len() > caseConvertedDirsRemembered {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.dirs, oldest.Value.(*caseConvertedDir).path)
+	}
+	return d
+}
+
+// convert returns the converted path, and whether it had to be renamed because of a collision.
+// Folders are never renamed, as it is fine for several source folders to merge into one.
+func (c *destinationNameCaseConverter) convert(relativePath string, isFolder bool) (converted string, renamed bool) {
+	converted = c.convertCase(relativePath)
+	if isFolder {
+		return converted, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	convertedDir, name := path.Split(converted)
+	d := c.dir(convertedDir)
+	d.sourceNames[name] = struct{}{}
+
+	if owner, ok := d.claimed[name]; !ok || owner == relativePath {
+		d.claimed[name] = relativePath
+		return converted, false
+	}
+
+	// the name is taken, so a suffixed one is needed, which must not be that of any source file in the directory either
+	if sourceDir, _ := path.Split(relativePath); c.listSourceDir != nil {
+		if _, ok := d.listed[sourceDir]; !ok {
+			d.listed[sourceDir] = struct{}{}
+			if names, err := c.listSourceDir(sourceDir); err == nil {
+				for _, n := range names {
+					d.sourceNames[c.convertCase(n)] = struct{}{}
+				}
+			}
+		}
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		owner, claimed := d.claimed[candidate]
+		if claimed && owner == relativePath {
+			return convertedDir + candidate, true
+		}
+		if _, isSourceName := d.sourceNames[candidate]; !claimed && !isSourceName {
+			d.claimed[candidate] = relativePath
+			return convertedDir + candidate, true
+		}
+	}
+}
+
+// localSourceDirLister lists the names in the directories of a local source, for destinationNameCaseConverter.listSourceDir
+func localSourceDirLister(sourceRoot string) func(relativeDir string) ([]string, error) {
+	return func(relativeDir string) ([]string, error) {
+		f, err := os.Open(filepath.Join(sourceRoot, filepath.FromSlash(relativeDir)))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return f.Readdirnames(-1)
+	}
+}
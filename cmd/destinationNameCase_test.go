@@ -0,0 +1,107 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+
+	chk "gopkg.in/check.v1"
+)
+
+type destinationNameCaseSuite struct{}
+
+var _ = chk.Suite(&destinationNameCaseSuite{})
+
+func (s *destinationNameCaseSuite) TestLowercaseWithCollisions(c *chk.C) {
+	converter := newDestinationNameCaseConverter(false)
+
+	converted, renamed := converter.convert("Dir/Report.PDF", false)
+	c.Assert(converted, chk.Equals, "dir/report.pdf")
+	c.Assert(renamed, chk.Equals, false)
+
+	// same name except for the case: renamed
+	converted, renamed = converter.convert("dir/report.pdf", false)
+	c.Assert(converted, chk.Equals, "dir/report-2.pdf")
+	c.Assert(renamed, chk.Equals, true)
+
+	converted, _ = converter.convert("DIR/REPORT.pdf", false)
+	c.Assert(converted, chk.Equals, "dir/report-3.pdf")
+
+	// seeing the same source path again (e.g. on retry) gives the same answer
+	converted, renamed = converter.convert("Dir/Report.PDF", false)
+	c.Assert(converted, chk.Equals, "dir/report.pdf")
+	c.Assert(renamed, chk.Equals, false)
+
+	// folders are merged
+	converted, renamed = converter.convert("DIR", true)
+	c.Assert(converted, chk.Equals, "dir")
+	c.Assert(renamed, chk.Equals, false)
+}
+
+func (s *destinationNameCaseSuite) TestUppercase(c *chk.C) {
+	converter := newDestinationNameCaseConverter(true)
+
+	converted, renamed := converter.convert("dir/file.txt", false)
+	c.Assert(converted, chk.Equals, "DIR/FILE.TXT")
+	c.Assert(renamed, chk.Equals, false)
+}
+
+func (s *destinationNameCaseSuite) TestSuffixAvoidsSourceNames(c *chk.C) {
+	converter := newDestinationNameCaseConverter(false)
+
+	// a real report-2.pdf that was seen first keeps its name, and isn't given to a renamed file
+	converter.convert("report-2.pdf", false)
+	converter.convert("report.pdf", false)
+	converted, renamed := converter.convert("Report.pdf", false)
+	c.Assert(converted, chk.Equals, "report-3.pdf")
+	c.Assert(renamed, chk.Equals, true)
+
+	// nor is one that is yet to be seen, when the source directory can be listed
+	converter = newDestinationNameCaseConverter(false)
+	converter.listSourceDir = func(relativeDir string) ([]string, error) {
+		c.Assert(relativeDir, chk.Equals, "dir/")
+		return []string{"report.pdf", "Report.pdf", "Report-2.PDF"}, nil
+	}
+	converter.convert("dir/report.pdf", false)
+	converted, _ = converter.convert("dir/Report.pdf", false)
+	c.Assert(converted, chk.Equals, "dir/report-3.pdf")
+	converted, renamed = converter.convert("dir/Report-2.PDF", false)
+	c.Assert(converted, chk.Equals, "dir/report-2.pdf")
+	c.Assert(renamed, chk.Equals, false)
+
+	// a renamed file still gets the same answer when it's seen again
+	converted, _ = converter.convert("dir/Report.pdf", false)
+	c.Assert(converted, chk.Equals, "dir/report-3.pdf")
+}
+
+func (s *destinationNameCaseSuite) TestRemembersBoundedNumberOfDirectories(c *chk.C) {
+	converter := newDestinationNameCaseConverter(false)
+	for i := 0; i < caseConvertedDirsRemembered+10; i++ {
+		converter.convert(fmt.Sprintf("dir%d/file.txt", i), false)
+	}
+	c.Assert(converter.recent.Len(), chk.Equals, caseConvertedDirsRemembered)
+	c.Assert(converter.dirs, chk.HasLen, caseConvertedDirsRemembered)
+
+	// the most recent are the ones remembered
+	converted, renamed := converter.convert(fmt.Sprintf("DIR%d/FILE.txt", caseConvertedDirsRemembered+9), false)
+	c.Assert(converted, chk.Equals, fmt.Sprintf("dir%d/file-2.txt", caseConvertedDirsRemembered+9))
+	c.Assert(renamed, chk.Equals, true)
+}
@@ -0,0 +1,89 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// duplicateDestinationDetector remembers which source claimed each destination path of a job, so that two different sources
+// which the path rewrites map to the same destination don't race to overwrite each other. The second one either fails the job
+// or, like the name case converter does, is renamed by adding a suffix before its extension
+type duplicateDestinationDetector struct {
+	rename bool
+
+	mu      sync.Mutex
+	claimed map[string]string // destination relative path -> source relative path which got it
+	// the claims of the earlier passes of a job that retries failed transfers. A source gets its own destination back,
+	// but the destinations of the other sources stay taken, so that a renamed source isn't copied over the one it was renamed for
+	previous map[string]string
+}
+
+func newDuplicateDestinationDetector(rename bool) *duplicateDestinationDetector {
+	return &duplicateDestinationDetector{rename: rename, claimed: make(map[string]string)}
+}
+
+// claim returns the destination that the source should be copied to. alreadyScheduled is true if this very source was
+// already scheduled to that destination (e.g. by overlapping entries in a list of files), in which case it must be left out
+func (d *duplicateDestinationDetector) claim(source, destination string) (claimed string, alreadyScheduled bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ext := path.Ext(destination)
+	base := strings.TrimSuffix(destination, ext)
+	candidate := destination
+	for i := 2; ; i++ {
+		owner, ok := d.claimed[candidate]
+		if !ok {
+			if owner, ok = d.previous[candidate]; !ok || owner == source {
+				d.claimed[candidate] = source
+				return candidate, false, nil
+			}
+		}
+		if owner == source {
+			return candidate, true, nil
+		}
+		if !d.rename {
+			return "", false, fmt.Errorf("%s and %s would both be copied to %s. Change the path rewrite rules or filters so that they don't, "+
+				"or use --duplicate-destination=Rename", owner, source, destination)
+		}
+		candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+// forRetry returns a detector for a pass that retries the failed transfers of this job, which schedules its sources again
+func (d *duplicateDestinationDetector) forRetry() *duplicateDestinationDetector {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	retry := newDuplicateDestinationDetector(d.rename)
+	retry.previous = make(map[string]string, len(d.previous)+len(d.claimed))
+	for destination, source := range d.previous {
+		retry.previous[destination] = source
+	}
+	for destination, source := range d.claimed {
+		retry.previous[destination] = source
+	}
+	return retry
+}
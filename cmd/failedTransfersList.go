@@ -0,0 +1,224 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+const failedTransfersFileFlagName = "failed-transfers-file"
+
+// writeFailedTransfersList writes the source paths of the job's failed transfers to listFile, in the format of --list-of-files,
+// so that a new job can retry just those.
+func (cca *cookedCopyCmdArgs) writeFailedTransfersList(listFile string) (written int, err error) {
+	paths, err := cca.failedTransferPaths()
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.Create(listFile)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, relativePath := range paths {
+		if _, err = fmt.Fprintln(writer, relativePath); err != nil {
+			return written, err
+		}
+		written++
+	}
+
+	return written, writer.Flush()
+}
+
+// failedTransferPaths returns the paths, relative to the source, of the job's failed transfers.
+// Folder properties are left out, since listing a folder would copy all of it again.
+func (cca *cookedCopyCmdArgs) failedTransferPaths() ([]string, error) {
+	if strings.Contains(cca.source.Value, "*") {
+		return nil, fmt.Errorf("failed transfers can't be listed for a source that contains wildcards")
+	}
+
+	var response common.ListJobTransfersResponse
+	Rpc(common.ERpcCmd.ListJobTransfers(), common.ListJobTransfersRequest{JobID: cca.jobID, OfStatus: common.ETransferStatus.Failed()}, &response)
+	if response.ErrorMsg != "" {
+		return nil, fmt.Errorf("cannot list the failed transfers: %s", response.ErrorMsg)
+	}
+
+	paths := make([]string, 0, len(response.Details))
+	for _, transfer := range response.Details {
+		if transfer.IsFolderProperties || !isRetriableFailure(transfer.TransferStatus) {
+			continue
+		}
+
+		relativePath, err := relativePathUnderSource(transfer.Src, cca.source, cca.fromTo.From())
+		if err != nil {
+			return nil, err
+		}
+
+		// the source itself was a single file, which only needs the same command to be run again
+		if relativePath == "" {
+			continue
+		}
+
+		paths = append(paths, relativePath)
+	}
+
+	return paths, nil
+}
+
+// isRetriableFailure says whether a transfer failed in a way that another attempt might fix.
+// The STE lists every kind of failure, but e.g. a tier that the destination doesn't offer won't become available on retry.
+func isRetriableFailure(status common.TransferStatus) bool {
+	switch status {
+	case common.ETransferStatus.Failed(), common.ETransferStatus.BlobTierFailure(), common.ETransferStatus.TimedOut():
+		return true
+	default:
+		return false
+	}
+}
+
+// retryPassForFailedTransfers returns the arguments of a new job that copies only the failed transfers of this one,
+// or nil if there are no more retry passes left or the failed transfers can't be listed.
+func (cca *cookedCopyCmdArgs) retryPassForFailedTransfers() *cookedCopyCmdArgs {
+	if cca.retryPass >= cca.retryFailedPasses {
+		return nil
+	}
+
+	paths, err := cca.failedTransferPaths()
+	if err != nil {
+		glcm.Info("Failed transfers will not be retried: " + err.Error())
+		return nil
+	} else if len(paths) == 0 {
+		return nil
+	}
+
+	retry := *cca
+	retry.jobID = common.NewJobID()
+	retry.listOfFilesChannel = newIncludePathChannel(paths)
+	retry.isEnumerationComplete = false
+	retry.destinationSpace = destinationSpaceTracker{}
+	retry.discovered = &discoveryCounter{}
+	// the copy shares its filters with this job, so the ones that count what they left out must start again from zero.
+	// (The ignore file filter only caches the rules it has read, which apply to the retry just the same.)
+	if cca.hiddenFiles != nil {
+		retry.hiddenFiles = newHiddenFileFilter(cca.hiddenFiles.rootPath)
+	}
+	if cca.danglingSymlinks != nil {
+		retry.danglingSymlinks = newDanglingSymlinkTracker(cca.danglingSymlinks.failOnDangling)
+	}
+	// likewise, the sources to retry were all claimed by this job, and would otherwise be left out as already scheduled
+	if cca.duplicateDestinations != nil {
+		retry.duplicateDestinations = cca.duplicateDestinations.forRetry()
+	}
+	retry.retryPass++
+	return &retry
+}
+
+// relativePathUnderSource turns the full source of a transfer back into a path relative to the source the user gave,
+// which is what --list-of-files expects. Unlike in the job plan, remote paths are not URL-encoded in such lists.
+func relativePathUnderSource(transferSource string, source common.ResourceString, location common.Location) (string, error) {
+	if location == common.ELocation.Local() {
+		root := source.ValueLocal()
+		relativePath := strings.TrimPrefix(transferSource, root)
+		return strings.TrimLeft(relativePath, `/\`), nil
+	}
+
+	transferURL, err := url.Parse(transferSource)
+	if err != nil {
+		return "", err
+	}
+
+	sourceURL, err := url.Parse(source.Value)
+	if err != nil {
+		return "", err
+	}
+
+	relativePath := strings.TrimPrefix(transferURL.Path, strings.TrimSuffix(sourceURL.Path, "/"))
+	return strings.TrimPrefix(relativePath, "/"), nil
+}
+
+// buildRetryCommand rewrites the command line of this job to copy only what's in listFile.
+// Any list of files or include path given originally is replaced, and the queries of URLs (i.e. SAS tokens) are replaced by a placeholder.
+func buildRetryCommand(args []string, listFile string) string {
+	replacedFlags := []string{"list-of-files", "include-path", failedTransfersFileFlagName}
+
+	retryArgs := []string{"azcopy"}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		replaced := false
+		for _, flag := range replacedFlags {
+			if arg == "--"+flag {
+				replaced = true
+				i++ // the value is in the next argument
+				break
+			} else if strings.HasPrefix(arg, "--"+flag+"=") {
+				replaced = true
+				break
+			}
+		}
+		if replaced {
+			continue
+		}
+
+		// the query of a URL holds its SAS token, which must not end up on screen or in the log
+		if u, err := url.Parse(arg); err == nil && u.Scheme != "" && u.Host != "" && u.RawQuery != "" {
+			u.RawQuery = ""
+			arg = u.String() + "?[SAS]"
+		}
+		retryArgs = append(retryArgs, quoteArgIfNeeded(arg))
+	}
+
+	retryArgs = append(retryArgs, quoteArgIfNeeded("--list-of-files="+listFile))
+	return strings.Join(retryArgs, " ")
+}
+
+func quoteArgIfNeeded(arg string) string {
+	if strings.ContainsAny(arg, " \t&;|<>") {
+		return `"` + arg + `"`
+	}
+	return arg
+}
+
+func formatFailedTransfersList(listFile string, written int, err error) string {
+	if listFile == "" {
+		return ""
+	}
+
+	if err != nil {
+		return fmt.Sprintf("\nCould not write the failed transfers to %s: %s", listFile, err)
+	}
+
+	if written == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("\n%d failed transfers were written to %s. To retry only those (after filling in any [SAS] placeholders), run:\n%s",
+		written, listFile, buildRetryCommand(os.Args[1:], listFile))
+}
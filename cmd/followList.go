@@ -0,0 +1,60 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// sealListLine ends a list of files that's being followed. Until it's read, the job is left open for more parts.
+const sealListLine = "::seal::"
+
+// how long to wait, at the end of a list of files that's being followed, before looking for more of it
+var followListPollInterval = 500 * time.Millisecond
+
+// followLines hands each line of r to add as it's written, like 'tail -f', until it reads sealListLine.
+// At the end of r it waits for more, rather than stopping. That also covers a named pipe, which reads as ended
+// whenever no producer has it open, so producers can come and go. A line is only handed on once it's complete,
+// so one caught half written is picked up whole on the next look.
+func followLines(r io.Reader, pollInterval time.Duration, add func(line string)) error {
+	reader := bufio.NewReader(r)
+	partial := ""
+	for {
+		s, err := reader.ReadString('\n')
+		partial += s
+		if err == io.EOF {
+			time.Sleep(pollInterval)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		line := strings.TrimSuffix(strings.TrimSuffix(partial, "\n"), "\r")
+		partial = ""
+		if line == sealListLine {
+			return nil
+		}
+		add(line)
+	}
+}
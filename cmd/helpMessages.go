@@ -30,6 +30,10 @@ Copies source data to a destination location. The supported directions are:
   - AWS S3 (Access Key) -> Azure Block Blob (SAS or OAuth authentication)
   - Google Cloud Storage (Service Account Key) -> Azure Block Blob (SAS or OAuth authentication) [Preview]
 
+SharedKey authentication to Azure Blob, Azure Files and ADLS Gen 2 is also available, by setting the ACCOUNT_NAME and ACCOUNT_KEY
+environment variables. The key is used only for resources in the account named by ACCOUNT_NAME, and not for public resources.
+It is intended for storage emulators and legacy automation only, since the account key grants full access to the account.
+
 Please refer to the examples for more information.
 
 Advanced:
@@ -66,6 +70,28 @@ Upload a single file by using OAuth and piping (block blobs only):
 
   - cat "/path/to/file.txt" | azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]" --from-to PipeBlob
 
+Upload what another program writes to a named pipe (FIFO), or download a blob into one, which streams the data in the same way (block blobs only):
+
+  - azcopy cp "/path/to/fifo" "https://[account].blob.core.windows.net/[container]/[path/to/blob]?[SAS]"
+  - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]?[SAS]" "/path/to/fifo"
+
+Upload from stdin, or download to stdout, by giving "-" as the source or destination (block blobs only):
+
+  - tar -cz "/path/to/dir" | azcopy cp - "https://[account].blob.core.windows.net/[container]/[path/to/blob]?[SAS]"
+  - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]?[SAS]" - | tar -xz
+
+Upload a whole disk, straight from its block device, as a page blob (the size of the disk is found by AzCopy):
+
+  - azcopy cp "/dev/sdb" "https://[account].blob.core.windows.net/[container]/disk.vhd?[SAS]" --blob-type PageBlob
+
+Upload a fixed size VHD to a managed disk, through the import URL that Azure grants for it (the from-to is inferred from the URL):
+
+  - azcopy cp "/path/to/disk.vhd" "https://md-impexp-[id].blob.storage.azure.net/[id]/abcd?[SAS]" --from-to LocalManagedDisk
+
+Stage a directory onto a fast scratch disk before uploading it, with the same engine, and the same job resume, as transfers to and from Azure Storage (a URL that AzCopy doesn't recognize looks like a local path, so a copy between local paths is never inferred):
+
+  - azcopy cp "/path/to/dir" "/mnt/scratch" --recursive=true --from-to LocalLocal
+
 Upload an entire directory by using a SAS token:
   
   - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true
@@ -88,6 +114,15 @@ Upload files and directories to Azure Storage account and set the query-string e
 	- https://docs.microsoft.com/en-us/azure/storage/blobs/storage-blob-index-how-to?tabs=azure-portal
 	- While setting tags on the blobs, there are additional permissions('t' for tags) in SAS without which the service will give authorization error back.
 
+Upload a directory to a folder named after the current date and the machine, e.g. for scheduled backups.
+With --expand-destination-template, the destination can use {date}, {time}, {jobid} and {hostname}, which are expanded (in UTC) when the job is created:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/backups/{hostname}/{date}?[SAS]" --recursive=true --expand-destination-template
+
+Upload a directory and record each file's original path and extension in its metadata:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --metadata-rules="set:origin={path};set:kind={ext}"
+
 Download a single file by using OAuth authentication. If you have not yet logged into AzCopy, please run the azcopy login command before you run the following command.
 
   - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]" "/path/to/file.txt"
@@ -104,10 +139,18 @@ Download a single file by using OAuth and then piping the output to a file (bloc
   
   - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]" --from-to BlobPipe > "/path/to/file.txt"
 
+Stream a blob into another program, downloading it in 16 MiB ranges (the ranges arrive in order, so no temp file is needed):
+
+  - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/archive.tar.gz]?[SAS]" - --block-size-mb 16 | tar -xz
+
 Download an entire directory by using a SAS token:
   
   - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "/path/to/dir" --recursive=true
 
+Download a directory as it was in a share snapshot (a snapshot can be the source of a download or copy, but not a destination):
+
+  - azcopy cp "https://[account].file.core.windows.net/[share]/[path/to/directory]?sharesnapshot=[snapshot]&[SAS]" "/path/to/dir" --recursive=true
+
 A note about using a wildcard character (*) in URLs:
 
 There's only two supported ways to use a wildcard character in a URL. 
@@ -138,6 +181,10 @@ Copy a single blob to another blob by using a SAS token and an OAuth token. You
 
   - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/[path/to/blob]?[SAS]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/blob]"
 
+Copy a single blob to another blob with OAuth tokens only, when the source account is in a different tenant than the destination account (requires an interactive login):
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/[path/to/blob]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/blob]" --s2s-source-tenant-id "[SourceTenantID]"
+
 Copy one blob virtual directory to another by using a SAS token:
 
   - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true
@@ -199,6 +246,10 @@ Copy all buckets to Blob Storage from Google Cloud Storage (GCS) by using a serv
 Copy a subset of buckets by using a wildcard symbol (*) in the bucket name from Google Cloud Storage (GCS) by using a service account key and a SAS token for destination. First, set the environment variables GOOGLE_APPLICATION_CREDENTIALS and GOOGLE_CLOUD_PROJECT=<project-id> for GCS source
  
   - azcopy cp "https://storage.cloud.google.com/[bucket*name]/" "https://[destaccount].blob.core.windows.net/?[SAS]" --recursive=true
+
+Copy an entire bucket to Blob Storage from Google Cloud Storage (GCS) by using an HMAC key and a SAS token. First, set the environment variables AZCOPY_GCS_HMAC_ACCESS_ID and AZCOPY_GCS_HMAC_SECRET (and leave GOOGLE_APPLICATION_CREDENTIALS unset) for GCS source.
+ 
+  - azcopy cp "https://storage.cloud.google.com/[bucket]" "https://[destaccount].blob.core.windows.net/?[SAS]" --recursive=true
 `
 
 // ===================================== ENV COMMAND ===================================== //
@@ -227,6 +278,16 @@ If you provide only a job ID, and not a flag, then this command returns the prog
 The byte counts and percent complete that appears when you run this command reflect only files that are completed in the job. They don't reflect partially completed files.
 If you set the with-status flag, then only the list of transfers associated with the given status appear.`
 
+const summaryJobsCmdShortDescription = "Summarize the given job, or all jobs on this machine"
+
+const summaryJobsCmdLongDescription = `
+If you provide a job ID, then this command returns the progress summary of that job, as the show command does.
+If you set the all flag, then the summaries of every job that has a plan file on this machine are added up, reporting the number of jobs of each status,
+the total bytes moved, the numbers of transfers that completed, failed and were skipped, and the disk space used by the plan and log files.
+Jobs whose plan files can't be read are counted separately.`
+
+const summaryJobsCmdExample = "  azcopy jobs summary --all"
+
 const resumeJobsCmdShortDescription = "Resume the existing job with the given job ID."
 
 const resumeJobsCmdLongDescription = `
@@ -241,6 +302,27 @@ Note that you can customize the location where log and plan files are saved. See
 
 const removeJobsCmdExample = "  azcopy jobs rm e52247de-0323-b14d-4cc8-76e0be2e2d44"
 
+const cancelJobsCmdShortDescription = "Cancel the job with the given job ID, for good."
+
+const cancelJobsCmdLongDescription = `
+Cancel the job with the given job ID, wherever it is. If the job is running in another AzCopy process, sharing this plan folder,
+that process stops it within a few seconds, just as if Ctrl-C was pressed there. If the job isn't running, it's marked as cancelled
+in its plan. Unlike a job stopped with Ctrl-C, a job cancelled with this command can't be resumed. Jobs that have completed can't be cancelled.`
+
+const cancelJobsCmdExample = "  azcopy jobs cancel e52247de-0323-b14d-4cc8-76e0be2e2d44"
+
+const cancelTransferJobsCmdShortDescription = "Cancel one transfer of the job with the given job ID, leaving the rest of the job to carry on."
+
+const cancelTransferJobsCmdLongDescription = `
+Cancel one transfer of the job with the given job ID. The transfer is given by its source or destination, as listed by jobs show,
+or by its path relative to the job's source or destination, or by its position in the job (counting from 0, in the order jobs show lists
+the transfers, when it's not filtered by status). The job may be running in another AzCopy process on this machine, which notices the
+cancellation before it sends the transfer's next chunk. Transfers that have already finished can't be cancelled. If the job is resumed,
+the transfer is tried again, like any other cancelled transfer.`
+
+const cancelTransferJobsCmdExample = `  azcopy jobs cancel-transfer e52247de-0323-b14d-4cc8-76e0be2e2d44 --path=dir/file.txt
+  azcopy jobs cancel-transfer e52247de-0323-b14d-4cc8-76e0be2e2d44 --index=12`
+
 const cleanJobsCmdShortDescription = "Remove all log and plan files for all jobs"
 
 const cleanJobsCmdLongDescription = `
@@ -259,7 +341,8 @@ const listCmdExample = "azcopy list [containerURL]"
 const loginCmdShortDescription = "Log in to Azure Active Directory (AD) to access Azure Storage resources."
 
 const loginCmdLongDescription = `To be authorized to your Azure Storage account, you must assign the **Storage Blob Data Contributor** role to your user account in the context of either the Storage account, parent resource group or parent subscription.
-This command will cache encrypted login information for current user using the OS built-in mechanisms.
+This command will cache encrypted login information for current user using the OS built-in mechanisms
+(DPAPI on Windows, the Keychain on macOS and the kernel keyring on Linux); it is never written to disk in plain text.
 Please refer to the examples for more information.
 
 ` + environmentVariableNotice
@@ -275,6 +358,14 @@ Log in interactively with a specified tenant ID:
 
    - azcopy login --tenant-id "[TenantID]"
 
+Log in interactively and choose among the tenants your account belongs to (the choice is cached with your login):
+
+   - azcopy login --select-tenant
+
+Log in interactively and also choose the subscription you work in (the choice is cached with your login, and shown by --status):
+
+   - azcopy login --select-tenant --select-subscription
+
 Log in by using the system-assigned identity of a Virtual Machine (VM):
 
    - azcopy login --identity
@@ -303,12 +394,23 @@ Set the environment variable AZCOPY_SPA_CERT_PASSWORD to the certificate's passw
 
    Please treat /path/to/my/cert as a path to a PEM or PKCS12 file-- AzCopy does not reach into the system cert store to obtain your certificate.
    --certificate-path is mandatory when doing cert-based service principal auth.
+
+Log in as a service principal whose client secret is stored in Azure Key Vault:
+Set the environment variable AZCOPY_SPA_CLIENT_SECRET to the URI of the secret. It is fetched using the VM's managed identity
+(set AZCOPY_MSI_CLIENT_ID to choose a user-assigned identity)
+
+   - AZCOPY_SPA_CLIENT_SECRET=https://myvault.vault.azure.net/secrets/mysecret azcopy login --service-principal --application-id <your service principal's application ID>
+
+Show whether login information is cached for the current user, and when it expires:
+
+   - azcopy login --status
 `
 
 // ===================================== LOGOUT COMMAND ===================================== //
 const logoutCmdShortDescription = "Log out to terminate access to Azure Storage resources."
 
-const logoutCmdLongDescription = `This command will remove all of the cached login information for the current user.`
+const logoutCmdLongDescription = `This command will remove all of the cached login information for the current user.
+Use 'azcopy login --status' to check whether any login information is cached.`
 
 // ===================================== MAKE COMMAND ===================================== //
 const makeCmdShortDescription = "Create a container or file share."
@@ -381,6 +483,7 @@ The sync command differs from the copy command in several ways:
   1. By default, the recursive flag is true and sync copies all subdirectories. Sync only copies the top-level files inside a directory if the recursive flag is false.
   2. When syncing between virtual directories, add a trailing slash to the path (refer to examples) if there's a blob with the same name as one of the virtual directories.
   3. If the 'deleteDestination' flag is set to true or prompt, then sync will delete files and blobs at the destination that are not present at the source.
+     If it is set to move-to:<container/prefix>, they are moved there instead, within the destination's storage account.
 
 Advanced:
 
@@ -441,6 +544,10 @@ Sync an Azure File directory (same syntax as Blob):
 
    - azcopy sync "https://[account].file.core.windows.net/[share]/[path/to/dir]?[SAS]" "https://[account].file.core.windows.net/[share]/[path/to/dir]" --recursive=true
 
+Sync a virtual directory, moving the blobs that are no longer at the source to the 'archive' container rather than deleting them:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]?[SAS]" --delete-destination="move-to:archive/[path/to/virtual/dir]"
+
 Note: if include and exclude flags are used together, only files matching the include patterns are used, but those matching the exclude patterns are ignored.
 `
 
@@ -515,3 +622,93 @@ Run an upload that does not delete the transferred files. (These files can then
 
    - azcopy bench "https://[account].blob.core.windows.net/[container]?<SAS>" --file-count 100 --delete-test-data=false
 `
+
+// ===================================== SET-PROPERTIES COMMAND ===================================== //
+const setPropertiesCmdShortDescription = "Change the content headers, metadata or tags of existing blobs"
+
+const setPropertiesCmdLongDescription = `
+Change the content headers, metadata or tags of blobs that already exist, without copying their data.
+Only the properties that are specified are changed. Metadata and tags, when given, replace the existing ones.`
+
+const setPropertiesCmdExample = `
+Set the content type of a single blob:
+
+   - azcopy set-properties "https://[account].blob.core.windows.net/[container]/[path/to/blob]?[SAS]" --content-type="application/json"
+
+Set the cache control of every jpg blob in a virtual directory:
+
+   - azcopy set-properties "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --include-pattern="*.jpg" --cache-control="max-age=3600"
+
+Replace the metadata and tags of specific blobs listed (NOT URL-encoded) in a file:
+
+   - azcopy set-properties "https://[account].blob.core.windows.net/[container]/[path/to/parent/dir]" --list-of-files=/usr/bar/list.txt --metadata="team=ops" --blob-tags="env=prod"
+`
+
+// ===================================== HISTORY COMMAND ===================================== //
+const historyCmdShortDescription = "Lists the jobs that have finished on this machine, with their totals"
+
+const historyCmdLongDescription = `
+Lists the final summaries of copy, sync and remove jobs that have finished on this machine, and adds them up, for example to report how much data was migrated each month.
+A job's summary is recorded when it finishes, in a history file in the AzCopy folder. Unlike plan and log files, the history is not removed by the jobs clean or jobs remove commands.
+Use the filters to choose which jobs are listed and added up. Dates are given as YYYY-MM-DD, or in ISO 8601 format, and are compared with the start times of the jobs.`
+
+const historyCmdExample = `
+List every job that has finished on this machine:
+
+   - azcopy history
+
+Report the jobs that copied data to a given storage account in January 2021:
+
+   - azcopy history --from=2021-01-01 --to=2021-01-31 --destination-account=[account]
+
+List the jobs that failed:
+
+   - azcopy history --with-status=Failed
+`
+
+// ===================================== SCHEDULE COMMAND ===================================== //
+const scheduleCmdShortDescription = "Runs copy, sync and remove commands on a recurring schedule"
+
+const scheduleCmdLongDescription = `
+Stores commands, each with a cron expression, and runs them whenever they are due while 'azcopy schedule daemon' is running.
+Each run is a separate AzCopy process, with its own job, plan and log files. The jobs are recorded in the history with the name of their schedule, so 'azcopy history --schedule=[name]' lists the runs of a schedule.
+The schedules are stored in the AzCopy folder, in a file that only its owner can read. Since a stored command may contain a SAS token, prefer authorizing with a managed identity or service principal where possible.`
+
+const scheduleAddCmdShortDescription = "Adds a schedule that runs the command given after --"
+
+const scheduleAddCmdExample = `
+Upload a directory to a container at 2am every day:
+
+   - azcopy schedule add nightly-backup --cron="0 2 * * *" -- sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]"
+
+Run the schedules (e.g. as a service, or from a terminal that stays open):
+
+   - azcopy schedule daemon
+`
+
+const scheduleDaemonCmdShortDescription = "Runs the schedules whenever they are due, until it is stopped"
+
+// ===================================== COMPLETION COMMAND ===================================== //
+const completionCmdShortDescription = "Generates a shell completion script for AzCopy"
+
+const completionCmdLongDescription = `
+Generates a script that completes AzCopy's commands and flags when you press Tab, in bash, zsh, fish or PowerShell.
+Besides commands and flags, it suggests the IDs of existing jobs (from the job plan files) and the values of flags that take one of a fixed set of values.`
+
+const completionCmdExample = `
+Load completions into the current bash session (add this to ~/.bashrc to load them in every session):
+
+   - source <(azcopy completion bash)
+
+Load completions in zsh (add this to ~/.zshrc to load them in every session):
+
+   - source <(azcopy completion zsh)
+
+Load completions in fish:
+
+   - azcopy completion fish > ~/.config/fish/completions/azcopy.fish
+
+Load completions in PowerShell (add this to your PowerShell profile to load them in every session):
+
+   - azcopy completion powershell | Out-String | Invoke-Expression
+`
@@ -0,0 +1,307 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+	"github.com/spf13/cobra"
+)
+
+// the history is a file of JSON lines, one per finished job, in the app folder
+const jobHistoryFileName = "history.jsonl"
+
+// JobHistoryEntry is the final summary of a job, as recorded in the history
+type JobHistoryEntry struct {
+	JobID              common.JobID
+	StartTime          time.Time
+	EndTime            time.Time
+	FromTo             string
+	Source             string // without any SAS
+	Destination        string // without any SAS
+	DestinationAccount string // the storage account name, if the destination is remote
+	JobStatus          string
+	Schedule           string `json:",omitempty"` // the schedule that ran the job, if one did
+
+	TotalTransfers        uint32 `json:",string"`
+	TransfersCompleted    uint32 `json:",string"`
+	TransfersFailed       uint32 `json:",string"`
+	TransfersSkipped      uint32 `json:",string"`
+	TotalBytesTransferred uint64 `json:",string"`
+}
+
+func newJobHistoryEntry(summary common.ListJobSummaryResponse, fromTo common.FromTo, source, destination common.ResourceString, startTime time.Time) JobHistoryEntry {
+	return JobHistoryEntry{
+		JobID:                 summary.JobID,
+		StartTime:             startTime.UTC(),
+		EndTime:               time.Now().UTC(),
+		FromTo:                fromTo.String(),
+		Source:                source.Value,
+		Destination:           destination.Value,
+		DestinationAccount:    accountNameOf(destination),
+		JobStatus:             summary.JobStatus.String(),
+		TotalTransfers:        summary.TotalTransfers,
+		TransfersCompleted:    summary.TransfersCompleted,
+		TransfersFailed:       summary.TransfersFailed,
+		TransfersSkipped:      summary.TransfersSkipped,
+		TotalBytesTransferred: summary.TotalBytesTransferred,
+	}
+}
+
+// accountNameOf returns the storage account of a remote location, or nothing if it's local
+func accountNameOf(location common.ResourceString) string {
+	u, err := url.Parse(location.Value)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(strings.Split(u.Hostname(), ".")[0])
+}
+
+func jobHistoryPath() string {
+	return filepath.Join(azcopyAppPathFolder, jobHistoryFileName)
+}
+
+// recordJobHistory appends the final summary of a job to the history. Failing to do so doesn't fail the job
+func recordJobHistory(entry JobHistoryEntry) {
+	if azcopyAppPathFolder == "" {
+		return // there's no AzCopy folder to keep the history in, e.g. when testing
+	}
+	entry.Schedule = cmdLineScheduleName
+	if err := appendJobHistory(jobHistoryPath(), entry); err != nil && ste.JobsAdmin != nil {
+		ste.JobsAdmin.LogToJobLog(fmt.Sprintf("Failed to record the job in the job history: %s", err), pipeline.LogWarning)
+	}
+}
+
+func appendJobHistory(path string, entry JobHistoryEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// each entry is appended in a single write, so that entries from concurrent AzCopy processes don't interleave
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readJobHistory returns the entries in the history that match the filter, oldest first
+func readJobHistory(path string, filter jobHistoryFilter) ([]JobHistoryEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil // no job has finished yet
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []JobHistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := JobHistoryEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // e.g. a partial line, from a process that was killed while writing
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+type jobHistoryFilter struct {
+	from               time.Time // inclusive, if set
+	to                 time.Time // exclusive, if set
+	destinationAccount string
+	schedule           string
+	status             common.JobStatus
+}
+
+func (f jobHistoryFilter) matches(entry JobHistoryEntry) bool {
+	if !f.from.IsZero() && entry.StartTime.Before(f.from) {
+		return false
+	}
+	if !f.to.IsZero() && !entry.StartTime.Before(f.to) {
+		return false
+	}
+	if f.destinationAccount != "" && !strings.EqualFold(f.destinationAccount, entry.DestinationAccount) {
+		return false
+	}
+	if f.schedule != "" && f.schedule != entry.Schedule {
+		return false
+	}
+	if f.status != common.EJobStatus.All() && f.status.String() != entry.JobStatus {
+		return false
+	}
+	return true
+}
+
+// parseHistoryDate parses a date given as YYYY-MM-DD, or as an ISO 8601 time. The end of a range is exclusive, so for
+// that it returns the moment just after the given time, or the start of the next day, so that the time or day is included
+func parseHistoryDate(s string, endOfRange bool) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfRange {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is not a date in the format YYYY-MM-DD, or an ISO 8601 time", s)
+	}
+	if endOfRange {
+		t = t.Add(time.Nanosecond)
+	}
+	return t, nil
+}
+
+func init() {
+	type rawHistoryCmdArgs struct {
+		from               string
+		to                 string
+		destinationAccount string
+		schedule           string
+		withStatus         string
+	}
+
+	raw := rawHistoryCmdArgs{}
+
+	historyCmd := &cobra.Command{
+		Use:     "history",
+		Short:   historyCmdShortDescription,
+		Long:    historyCmdLongDescription,
+		Example: historyCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return errors.New("history command does not accept arguments")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			filter := jobHistoryFilter{destinationAccount: raw.destinationAccount, schedule: raw.schedule, status: common.EJobStatus.All()}
+			var err error
+			if raw.from != "" {
+				if filter.from, err = parseHistoryDate(raw.from, false); err != nil {
+					glcm.Error("Failed to parse --from: " + err.Error())
+				}
+			}
+			if raw.to != "" {
+				if filter.to, err = parseHistoryDate(raw.to, true); err != nil {
+					glcm.Error("Failed to parse --to: " + err.Error())
+				}
+			}
+			if err = filter.status.Parse(raw.withStatus); err != nil {
+				glcm.Error(fmt.Sprintf("Failed to parse --with-status due to error: %s.", err))
+			}
+
+			entries, err := readJobHistory(jobHistoryPath(), filter)
+			if err != nil {
+				glcm.Error(fmt.Sprintf("Failed to read the job history due to error: %s.", err))
+			}
+			PrintJobHistory(entries)
+		},
+	}
+
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.PersistentFlags().StringVar(&raw.from, "from", "", "Only include jobs that started on or after this date, or ISO 8601 time.")
+	historyCmd.PersistentFlags().StringVar(&raw.to, "to", "", "Only include jobs that started on or before this date, or ISO 8601 time.")
+	historyCmd.PersistentFlags().StringVar(&raw.destinationAccount, "destination-account", "", "Only include jobs whose destination is in the storage account with this name.")
+	historyCmd.PersistentFlags().StringVar(&raw.schedule, "schedule", "", "Only include jobs run by the schedule with this name. See 'azcopy schedule'.")
+	historyCmd.PersistentFlags().StringVar(&raw.withStatus, "with-status", "All",
+		"Only include jobs with this final status, available values: All, Cancelled, Failed, Completed, CompletedWithErrors, CompletedWithSkipped, CompletedWithErrorsAndSkipped")
+}
+
+// JobHistoryResponse is the output of the history command
+type JobHistoryResponse struct {
+	Jobs                  []JobHistoryEntry
+	TotalTransfers        uint64 `json:",string"`
+	TransfersCompleted    uint64 `json:",string"`
+	TransfersFailed       uint64 `json:",string"`
+	TotalBytesTransferred uint64 `json:",string"`
+}
+
+func newJobHistoryResponse(entries []JobHistoryEntry) JobHistoryResponse {
+	resp := JobHistoryResponse{Jobs: entries}
+	for _, e := range entries {
+		resp.TotalTransfers += uint64(e.TotalTransfers)
+		resp.TransfersCompleted += uint64(e.TransfersCompleted)
+		resp.TransfersFailed += uint64(e.TransfersFailed)
+		resp.TotalBytesTransferred += e.TotalBytesTransferred
+	}
+	return resp
+}
+
+// PrintJobHistory prints the jobs in the history, and their totals
+func PrintJobHistory(entries []JobHistoryEntry) {
+	resp := newJobHistoryResponse(entries)
+
+	glcm.Exit(func(format common.OutputFormat) string {
+		if format == common.EOutputFormat.Json() {
+			jsonOutput, err := json.Marshal(resp)
+			common.PanicIfErr(err)
+			return string(jsonOutput)
+		}
+
+		var sb strings.Builder
+		for _, e := range resp.Jobs {
+			if e.Schedule != "" {
+				sb.WriteString(fmt.Sprintf("Schedule: %s\n", e.Schedule))
+			}
+			sb.WriteString(fmt.Sprintf("JobId: %s\nStart Time: %s\nEnd Time: %s\nFrom-To: %s\nSource: %s\nDestination: %s\nStatus: %s\nTransfers Completed: %v of %v\nTransfers Failed: %v\nBytes Transferred: %v\n\n",
+				e.JobID.String(),
+				e.StartTime.Local().Format(time.RFC850),
+				e.EndTime.Local().Format(time.RFC850),
+				e.FromTo,
+				e.Source,
+				e.Destination,
+				e.JobStatus,
+				e.TransfersCompleted,
+				e.TotalTransfers,
+				e.TransfersFailed,
+				e.TotalBytesTransferred))
+		}
+		sb.WriteString(fmt.Sprintf("Number of Jobs: %v\nTotal Number of Transfers: %v\nNumber of Transfers Completed: %v\nNumber of Transfers Failed: %v\nTotal Bytes Transferred: %v (%s)\n",
+			len(resp.Jobs),
+			resp.TotalTransfers,
+			resp.TransfersCompleted,
+			resp.TransfersFailed,
+			resp.TotalBytesTransferred,
+			byteSizeToString(int64(resp.TotalBytesTransferred))))
+		return sb.String()
+	}, common.EExitCode.Success())
+}
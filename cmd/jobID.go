@@ -0,0 +1,100 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// jobIDFromCommand is the --job-id value that derives the job ID from the command line, so that running the same
+// command again, e.g. from a scheduler after a crash, gives the same job
+const jobIDFromCommand = "from-command"
+
+const jobIDFlagDescription = "Use this job ID instead of a new one. Either a GUID, or '" + jobIDFromCommand + "' to derive it from the command line and the current folder, " +
+	"leaving out SAS tokens so that a renewed token gives the same ID. If a job with the ID is already in the plan folder, it is resumed, as 'jobs resume' would, " +
+	"instead of a duplicate being started. Useful for schedulers that re-run commands after a crash."
+
+// applyJobIDFlag makes the job ID that --job-id asks for this run's job ID
+func applyJobIDFlag(raw string) error {
+	switch raw {
+	case "":
+		return nil
+	case jobIDFromCommand:
+		wd, _ := os.Getwd()
+		azcopyCurrentJobID = common.NewJobIDFromHash([]byte(wd + "\n" + commandIdentity(os.Args[1:])))
+		return nil
+	default:
+		jobID, err := common.ParseJobID(raw)
+		if err != nil || jobID.IsEmpty() {
+			return fmt.Errorf("invalid --job-id '%s'. It must be a GUID, or %s", raw, jobIDFromCommand)
+		}
+		azcopyCurrentJobID = jobID
+		return nil
+	}
+}
+
+// commandIdentity is the command line, less the SAS tokens of its URLs, since those are often renewed between runs
+func commandIdentity(args []string) string {
+	identity := make([]string, 0, len(args))
+	for _, arg := range args {
+		if u, err := url.Parse(arg); err == nil && strings.HasPrefix(u.Scheme, "http") {
+			parts := azblob.NewBlobURLParts(*u)
+			parts.SAS = azblob.SASQueryParameters{}
+			sasless := parts.URL()
+			arg = sasless.String()
+		}
+		identity = append(identity, arg)
+	}
+	return strings.Join(identity, " ")
+}
+
+// sasOf returns the SAS token of the resource, if it's a URL with one
+func sasOf(resource string) string {
+	u, err := url.Parse(resource)
+	if err != nil || !strings.HasPrefix(u.Scheme, "http") {
+		return ""
+	}
+	return azblob.NewBlobURLParts(*u).SAS.Encode()
+}
+
+// resumeIfJobExists resumes this run's job, rather than starting it again, if it's already in the plan folder.
+// It reports whether it did, in which case it's done with the run once it returns
+func resumeIfJobExists(src, dst string) (resumed bool, err error) {
+	planFiles, _ := filepath.Glob(filepath.Join(azcopyJobPlanFolder, azcopyCurrentJobID.String()+"--*"))
+	if len(planFiles) == 0 {
+		return false, nil
+	}
+
+	glcm.Info(fmt.Sprintf("Job %s is already in the plan folder, so it is resumed instead of being started again.", azcopyCurrentJobID))
+	return true, resumeCmdArgs{
+		jobID:          azcopyCurrentJobID.String(),
+		SourceSAS:      sasOf(src),
+		DestinationSAS: sasOf(dst),
+	}.process()
+}
@@ -0,0 +1,73 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/spf13/cobra"
+)
+
+// handleCancelJob cancels the job for good, whether it's running in this process, in another one, or not at all
+func handleCancelJob(jobID common.JobID) (string, error) {
+	var response common.CancelPauseResumeResponse
+	Rpc(common.ERpcCmd.CancelJobAnywhere(), jobID, &response)
+	if !response.CancelledPauseResumed {
+		return "", errors.New(response.ErrorMsg)
+	}
+	return response.ErrorMsg, nil
+}
+
+func init() {
+	var jobID common.JobID
+
+	jobsCancelCmd := &cobra.Command{
+		Use:         "cancel [jobID]",
+		Annotations: jobIDArgAnnotations,
+		Short:       cancelJobsCmdShortDescription,
+		Long:        cancelJobsCmdLongDescription,
+		Example:     cancelJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("cancel job command requires the JobID")
+			}
+			var err error
+			if jobID, err = common.ParseJobID(args[0]); err != nil {
+				return errors.New("invalid jobId given " + args[0])
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			msg, err := handleCancelJob(jobID)
+			if err != nil {
+				glcm.Error(fmt.Sprintf("Failed to cancel job %s due to error: %s.", jobID, err))
+				return
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				return msg
+			}, common.EExitCode.Success())
+		},
+	}
+
+	jobsCmd.AddCommand(jobsCancelCmd)
+}
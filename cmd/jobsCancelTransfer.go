@@ -0,0 +1,114 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/spf13/cobra"
+)
+
+type rawCancelTransferCmdArgs struct {
+	jobID string
+	path  string
+	index int64 // negative if not given
+}
+
+func (raw rawCancelTransferCmdArgs) cook() (common.CancelTransferRequest, error) {
+	jobID, err := common.ParseJobID(raw.jobID)
+	if err != nil {
+		return common.CancelTransferRequest{}, errors.New("invalid jobId given " + raw.jobID)
+	}
+
+	switch {
+	case raw.path != "" && raw.index >= 0:
+		return common.CancelTransferRequest{}, errors.New("give either the path or the index of the transfer to cancel, not both")
+	case raw.path != "":
+		return common.CancelTransferRequest{JobID: jobID, Path: raw.path}, nil
+	case raw.index >= 0:
+		return common.CancelTransferRequest{JobID: jobID, Index: uint32(raw.index)}, nil
+	default:
+		return common.CancelTransferRequest{}, errors.New("give the path or the index of the transfer to cancel")
+	}
+}
+
+// handleCancelTransfer cancels the transfer in its job's plan, from where the job, even if it's running in another process, sees it
+func handleCancelTransfer(request common.CancelTransferRequest) (string, error) {
+	var response common.CancelPauseResumeResponse
+	Rpc(common.ERpcCmd.CancelTransfer(), &request, &response)
+	if !response.CancelledPauseResumed {
+		return "", errors.New(response.ErrorMsg)
+	}
+	return response.ErrorMsg, nil
+}
+
+// cancelTransferFromStdin cancels a transfer of the current job, when the user sends in cancel-transfer followed by
+// its path (with --cancel-from-stdin)
+func cancelTransferFromStdin(path string) {
+	msg, err := handleCancelTransfer(common.CancelTransferRequest{JobID: azcopyCurrentJobID, Path: path})
+	if err != nil {
+		glcm.Info(fmt.Sprintf("Failed to cancel the transfer of %s: %s", path, err))
+		return
+	}
+	glcm.Info(msg)
+}
+
+func init() {
+	raw := rawCancelTransferCmdArgs{}
+
+	jobsCancelTransferCmd := &cobra.Command{
+		Use:         "cancel-transfer [jobID]",
+		Annotations: jobIDArgAnnotations,
+		Short:       cancelTransferJobsCmdShortDescription,
+		Long:        cancelTransferJobsCmdLongDescription,
+		Example:     cancelTransferJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("cancel-transfer command requires the JobID")
+			}
+			raw.jobID = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			request, err := raw.cook()
+			if err != nil {
+				glcm.Error("failed to parse user input due to error: " + err.Error())
+				return
+			}
+
+			msg, err := handleCancelTransfer(request)
+			if err != nil {
+				glcm.Error(fmt.Sprintf("Failed to cancel the transfer due to error: %s.", err))
+				return
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				return msg
+			}, common.EExitCode.Success())
+		},
+	}
+
+	jobsCmd.AddCommand(jobsCancelTransferCmd)
+
+	jobsCancelTransferCmd.PersistentFlags().StringVar(&raw.path, "path", "", "The source or destination of the transfer to cancel, as listed by jobs show, or its path relative to the job's source or destination.")
+	jobsCancelTransferCmd.PersistentFlags().Int64Var(&raw.index, "index", -1, "The position of the transfer to cancel in the job, counting from 0, in the order jobs show lists them.")
+}
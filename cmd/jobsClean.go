@@ -23,11 +23,13 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
 )
 
 func init() {
@@ -100,6 +102,10 @@ func handleCleanJobsCommand(givenStatus common.JobStatus) error {
 	for _, job := range resp.JobIDDetails {
 		// delete all jobs matching the givenStatus
 		if job.JobStatus == givenStatus {
+			if owner, running := ste.GetJobOwner(azcopyJobPlanFolder, job.JobId); running {
+				glcm.Info(fmt.Sprintf("Skipping job %s, which is in use by %s", job.JobId, owner))
+				continue
+			}
 			glcm.Info(fmt.Sprintf("Removing files for job %s", job.JobId))
 			err := handleRemoveSingleJob(job.JobId)
 			if err != nil {
@@ -112,9 +118,28 @@ func handleCleanJobsCommand(givenStatus common.JobStatus) error {
 }
 
 func blindDeleteAllJobFiles() (int, error) {
+	// the files of jobs that other azcopy processes are running are left alone
+	unlock, err := ste.LockPlanFolder(azcopyJobPlanFolder)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	runningJobs, err := runningJobIDs()
+	if err != nil {
+		return 0, err
+	}
+	isOfRunningJob := func(s string) bool {
+		for _, jobID := range runningJobs {
+			if strings.Contains(s, jobID.String()) {
+				return true
+			}
+		}
+		return false
+	}
+
 	// get rid of the job plan files
 	numPlanFilesRemoved, err := removeFilesWithPredicate(azcopyJobPlanFolder, func(s string) bool {
-		if strings.Contains(s, ".steV") {
+		if (strings.Contains(s, ".steV") || ste.IsJobSidecarFileName(s)) && !isOfRunningJob(s) {
 			return true
 		}
 		return false
@@ -125,7 +150,7 @@ func blindDeleteAllJobFiles() (int, error) {
 
 	// get rid of the logs
 	numLogFilesRemoved, err := removeFilesWithPredicate(azcopyLogPathFolder, func(s string) bool {
-		if strings.HasSuffix(s, ".log") {
+		if strings.HasSuffix(s, ".log") && !isOfRunningJob(s) {
 			return true
 		}
 		return false
@@ -133,3 +158,26 @@ func blindDeleteAllJobFiles() (int, error) {
 
 	return numPlanFilesRemoved + numLogFilesRemoved, err
 }
+
+// runningJobIDs returns the jobs in the plan folder that other azcopy processes are running
+func runningJobIDs() ([]common.JobID, error) {
+	files, err := ioutil.ReadDir(azcopyJobPlanFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []common.JobID
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ste.JobOwnerSuffix) {
+			continue
+		}
+		jobID, err := common.ParseJobID(strings.TrimSuffix(f.Name(), ste.JobOwnerSuffix))
+		if err != nil {
+			continue
+		}
+		if _, isRunning := ste.GetJobOwner(azcopyJobPlanFolder, jobID); isRunning {
+			running = append(running, jobID)
+		}
+	}
+	return running, nil
+}
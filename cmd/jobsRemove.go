@@ -29,6 +29,7 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
 	"github.com/spf13/cobra"
 )
 
@@ -41,11 +42,12 @@ func init() {
 
 	// remove a single job's log and plan file
 	jobsRemoveCmd := &cobra.Command{
-		Use:     "remove [jobID]",
-		Aliases: []string{"rm"},
-		Short:   removeJobsCmdShortDescription,
-		Long:    removeJobsCmdLongDescription,
-		Example: removeJobsCmdExample,
+		Use:         "remove [jobID]",
+		Annotations: jobIDArgAnnotations,
+		Aliases:     []string{"rm"},
+		Short:       removeJobsCmdShortDescription,
+		Long:        removeJobsCmdLongDescription,
+		Example:     removeJobsCmdExample,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return errors.New("remove job command requires the JobID")
@@ -74,9 +76,19 @@ func init() {
 }
 
 func handleRemoveSingleJob(jobID common.JobID) error {
+	// make sure no other azcopy process starts running the job while we remove it
+	unlock, err := ste.LockPlanFolder(azcopyJobPlanFolder)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if owner, running := ste.GetJobOwner(azcopyJobPlanFolder, jobID); running {
+		return fmt.Errorf("the job is in use by %s", owner)
+	}
+
 	// get rid of the job plan files
 	numPlanFileRemoved, err := removeFilesWithPredicate(azcopyJobPlanFolder, func(s string) bool {
-		if strings.Contains(s, jobID.String()) && strings.Contains(s, ".steV") {
+		if strings.Contains(s, jobID.String()) && (strings.Contains(s, ".steV") || ste.IsJobSidecarFileName(s)) {
 			return true
 		}
 		return false
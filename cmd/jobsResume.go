@@ -25,6 +25,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -49,6 +51,11 @@ type resumeJobController struct {
 
 	// used to calculate job summary
 	jobStartTime time.Time
+
+	// used to record the job in the history when it's done
+	fromTo      common.FromTo
+	source      common.ResourceString
+	destination common.ResourceString
 }
 
 // wraps call to lifecycle manager to wait for the job to complete
@@ -56,7 +63,7 @@ type resumeJobController struct {
 // if blocking is specified to false, then another goroutine spawns and wait out the job
 func (cca *resumeJobController) waitUntilJobCompletion(blocking bool) {
 	// print initial message to indicate that the job is starting
-	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), fmt.Sprintf("%s%s%s.log", azcopyLogPathFolder, common.OS_PATH_SEPARATOR, cca.jobID), false, ""))
+	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), jobLogFilePath(cca.jobID), false, ""))
 
 	// initialize the times necessary to track progress
 	cca.jobStartTime = time.Now()
@@ -99,6 +106,7 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 		if summary.TransfersFailed > 0 {
 			exitCode = common.EExitCode.Error()
 		}
+		recordJobHistory(newJobHistoryEntry(summary, cca.fromTo, cca.source, cca.destination, cca.jobStartTime))
 
 		lcm.Exit(func(format common.OutputFormat) string {
 			if format == common.EOutputFormat.Json() {
@@ -107,7 +115,7 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 				return string(jsonOutput)
 			} else {
 				return fmt.Sprintf(
-					"\n\nJob %s summary\nElapsed Time (Minutes): %v\nNumber of File Transfers: %v\nNumber of Folder Property Transfers: %v\nTotal Number Of Transfers: %v\nNumber of Transfers Completed: %v\nNumber of Transfers Failed: %v\nNumber of Transfers Skipped: %v\nTotalBytesTransferred: %v\nFinal Job Status: %v\n",
+					"\n\nJob %s summary\nElapsed Time (Minutes): %v\nNumber of File Transfers: %v\nNumber of Folder Property Transfers: %v\nTotal Number Of Transfers: %v\nNumber of Transfers Completed: %v\nNumber of Transfers Failed: %v\nNumber of Transfers Skipped: %v\nTotalBytesTransferred: %v%s\nFinal Job Status: %v\n",
 					summary.JobID.String(),
 					ste.ToFixed(duration.Minutes(), 4),
 					summary.FileTransfers,
@@ -117,6 +125,7 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 					summary.TransfersFailed,
 					summary.TransfersSkipped,
 					summary.TotalBytesTransferred,
+					formatWireBytes(summary),
 					summary.JobStatus)
 			}
 		}, exitCode)
@@ -157,12 +166,12 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 			// indicate whether constrained by disk or not
 			perfString, diskString := getPerfDisplayText(summary.PerfStrings, summary.PerfConstraint, duration, false)
 
-			return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Skipped, %v Total%s, %s%s%s",
+			return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Skipped, %v Total%s%s, %s%s%s",
 				summary.PercentComplete,
 				summary.TransfersCompleted,
 				summary.TransfersFailed,
 				summary.TotalTransfers-(summary.TransfersCompleted+summary.TransfersFailed+summary.TransfersSkipped),
-				summary.TransfersSkipped, summary.TotalTransfers, scanningString, perfString, throughputString, diskString)
+				summary.TransfersSkipped, summary.TotalTransfers, scanningString, formatEta(summary.EstimatedSecondsRemaining), perfString, throughputString, diskString)
 		}
 	})
 	return
@@ -173,10 +182,11 @@ func init() {
 
 	// resumeCmd represents the resume command
 	resumeCmd := &cobra.Command{
-		Use:        "resume [jobID]",
-		SuggestFor: []string{"resme", "esume", "resue"},
-		Short:      resumeJobsCmdShortDescription,
-		Long:       resumeJobsCmdLongDescription,
+		Use:         "resume [jobID]",
+		Annotations: jobIDArgAnnotations,
+		SuggestFor:  []string{"resme", "esume", "resue"},
+		Short:       resumeJobsCmdShortDescription,
+		Long:        resumeJobsCmdLongDescription,
 		Args: func(cmd *cobra.Command, args []string) error {
 			// the resume command requires necessarily to have an argument
 			// resume jobId -- resumes all the parts of an existing job for given jobId
@@ -205,6 +215,11 @@ func init() {
 	// oauth options
 	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.SourceSAS, "source-sas", "", "Source SAS token of the source for a given Job ID.")
 	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.DestinationSAS, "destination-sas", "", "destination SAS token of the destination for a given Job ID.")
+	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.alsoTo, "also-to", "", "The --also-to destinations of the upload, each with its SAS token, separated by ';'. "+
+		"The destination-sas is only used for the main destination.")
+	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.posixIDMap, "posix-id-map", "", "The --posix-id-map file given to the copy, for jobs which preserve POSIX owners in ADLS Gen2.")
+	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.sourceRootOverride, "source-root-override", "", "For uploads whose source has been remounted at a different path, e.g. under another drive letter after a reboot. "+
+		"The path where the source given to the copy, up to any wildcard, is now found. Files are read relative to it, by this and any later resume of the job.")
 }
 
 type resumeCmdArgs struct {
@@ -214,6 +229,48 @@ type resumeCmdArgs struct {
 
 	SourceSAS      string
 	DestinationSAS string
+	alsoTo         string
+
+	posixIDMap string
+
+	sourceRootOverride string
+}
+
+// cookSourceRootOverride checks that a job's source can be read from the given local path instead, and returns that
+// path as the job's source root would be. The path must exist, since the job's files are to be found under it
+func cookSourceRootOverride(raw string, fromTo common.FromTo) (string, error) {
+	if !fromTo.From().IsLocal() {
+		return "", fmt.Errorf("the source root can only be overridden when resuming jobs with a local source, not %s", fromTo)
+	}
+	abs, err := filepath.Abs(raw)
+	if err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(abs); err != nil {
+		return "", fmt.Errorf("cannot find the overriding source root: %w", err)
+	}
+	return cleanLocalPath(common.ToExtendedPath(abs)), nil
+}
+
+// cookAlsoToSAS takes the SAS of each --also-to destination of an upload, by the destination's root. SASs aren't kept
+// with a job, and each extra destination needs its own, since the main destination's SAS doesn't grant access to it
+func cookAlsoToSAS(raw string, fromTo common.FromTo) (map[string]string, error) {
+	if !fromTo.IsUpload() {
+		return nil, fmt.Errorf("also-to destinations are only found in uploads, not in %s jobs", fromTo)
+	}
+	alsoToSAS := make(map[string]string)
+	for _, dst := range strings.Split(raw, ";") {
+		if inferArgumentLocation(dst) != fromTo.To() {
+			return nil, fmt.Errorf("the also-to destination %s must be the same kind of location as the job's destination, %s",
+				common.URLStringExtension(dst).RedactSecretQueryParamForLogging(), fromTo.To())
+		}
+		resource, err := SplitResourceString(dst, fromTo.To())
+		if err != nil {
+			return nil, err
+		}
+		alsoToSAS[resource.Value] = resource.SAS
+	}
+	return alsoToSAS, nil
 }
 
 // processes the resume command,
@@ -296,16 +353,56 @@ func (rca resumeCmdArgs) process() error {
 		}
 	}
 
+	// A source that was authorized in its own tenant gets a fresh token for that tenant, unless a SAS is given for it now
+	s2sSourceCredentialInfo := common.CredentialInfo{}
+	if getJobFromToResponse.S2SSourceTenantID != "" && rca.SourceSAS == "" {
+		uotm := GetUserOAuthTokenManagerInstance()
+		tokenInfo, err := uotm.GetTokenInfo(ctx)
+		if err != nil {
+			return err
+		}
+		srcTokenInfo, err := uotm.SwitchTenant(ctx, tokenInfo, getJobFromToResponse.S2SSourceTenantID, false)
+		if err != nil {
+			return fmt.Errorf("cannot get a token for the source tenant: %v", err)
+		}
+		s2sSourceCredentialInfo = common.CredentialInfo{CredentialType: common.ECredentialType.OAuthToken(), OAuthTokenInfo: *srcTokenInfo}
+	}
+
+	sourceRootOverride := ""
+	if rca.sourceRootOverride != "" {
+		if sourceRootOverride, err = cookSourceRootOverride(rca.sourceRootOverride, getJobFromToResponse.FromTo); err != nil {
+			return err
+		}
+	}
+
+	var alsoToSAS map[string]string
+	if rca.alsoTo != "" {
+		if alsoToSAS, err = cookAlsoToSAS(rca.alsoTo, getJobFromToResponse.FromTo); err != nil {
+			return err
+		}
+	}
+
+	posixIDMap := common.POSIXIDMap{}
+	if rca.posixIDMap != "" {
+		if posixIDMap, err = common.ParsePOSIXIDMapFile(rca.posixIDMap); err != nil {
+			return fmt.Errorf("cannot read the POSIX ID map: %w", err)
+		}
+	}
+
 	// Send resume job request.
 	var resumeJobResponse common.CancelPauseResumeResponse
 	Rpc(common.ERpcCmd.ResumeJob(),
 		&common.ResumeJobRequest{
-			JobID:           jobID,
-			SourceSAS:       rca.SourceSAS,
-			DestinationSAS:  rca.DestinationSAS,
-			CredentialInfo:  credentialInfo,
-			IncludeTransfer: includeTransfer,
-			ExcludeTransfer: excludeTransfer,
+			JobID:                   jobID,
+			SourceSAS:               rca.SourceSAS,
+			DestinationSAS:          rca.DestinationSAS,
+			AlsoToSAS:               alsoToSAS,
+			CredentialInfo:          credentialInfo,
+			S2SSourceCredentialInfo: s2sSourceCredentialInfo,
+			POSIXIDMap:              posixIDMap,
+			SourceRootOverride:      sourceRootOverride,
+			IncludeTransfer:         includeTransfer,
+			ExcludeTransfer:         excludeTransfer,
 		},
 		&resumeJobResponse)
 
@@ -313,7 +410,12 @@ func (rca resumeCmdArgs) process() error {
 		glcm.Error(resumeJobResponse.ErrorMsg)
 	}
 
-	controller := resumeJobController{jobID: jobID}
+	controller := resumeJobController{
+		jobID:       jobID,
+		fromTo:      getJobFromToResponse.FromTo,
+		source:      common.ResourceString{Value: getJobFromToResponse.Source},
+		destination: common.ResourceString{Value: getJobFromToResponse.Destination},
+	}
 	controller.waitUntilJobCompletion(true)
 
 	return nil
@@ -28,6 +28,7 @@ import (
 	"encoding/json"
 
 	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
 	"github.com/spf13/cobra"
 )
 
@@ -41,9 +42,10 @@ func init() {
 
 	// shJob represents the ls command
 	shJob := &cobra.Command{
-		Use:   "show [jobID]",
-		Short: showJobsCmdShortDescription,
-		Long:  showJobsCmdLongDescription,
+		Use:         "show [jobID]",
+		Annotations: jobIDArgAnnotations,
+		Short:       showJobsCmdShortDescription,
+		Long:        showJobsCmdLongDescription,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return errors.New("show job command requires only the JobID")
@@ -141,6 +143,12 @@ func PrintJobProgressSummary(summary common.ListJobSummaryResponse) {
 
 	glcm.Exit(func(format common.OutputFormat) string {
 		if format == common.EOutputFormat.Json() {
+			// the summary only lists so many failed and skipped transfers, so list them all from the job's details file
+			if summary.TransferDetailsFile != "" {
+				if failed, skipped, err := ste.ReadJobTransferDetails(summary.TransferDetailsFile); err == nil {
+					summary.FailedTransfers, summary.SkippedTransfers = failed, skipped
+				}
+			}
 			jsonOutput, err := json.Marshal(summary) // see note below re % complete being approximate. We can't include "approx" in the JSON.
 			common.PanicIfErr(err)
 			return string(jsonOutput)
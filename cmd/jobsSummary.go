@@ -0,0 +1,203 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	type JobsSummaryReq struct {
+		JobID common.JobID
+		all   bool
+	}
+
+	commandLineInput := JobsSummaryReq{}
+
+	// summarize a single job, or every job on the machine
+	jobsSummaryCmd := &cobra.Command{
+		Use:         "summary [jobID]",
+		Annotations: jobIDArgAnnotations,
+		Short:       summaryJobsCmdShortDescription,
+		Long:        summaryJobsCmdLongDescription,
+		Example:     summaryJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if commandLineInput.all {
+				if len(args) != 0 {
+					return errors.New("summary command does not accept a JobID when --all is set")
+				}
+				return nil
+			}
+			if len(args) != 1 {
+				return errors.New("summary command requires either the JobID or --all")
+			}
+			// Parse the JobId
+			jobId, err := common.ParseJobID(args[0])
+			if err != nil {
+				return errors.New("invalid jobId given " + args[0])
+			}
+			commandLineInput.JobID = jobId
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if !commandLineInput.all {
+				err := HandleShowCommand(common.ListRequest{JobID: commandLineInput.JobID})
+				if err != nil {
+					glcm.Error(err.Error())
+				}
+				return
+			}
+
+			PrintAllJobsSummary(handleSummarizeAllJobs())
+		},
+	}
+
+	jobsCmd.AddCommand(jobsSummaryCmd)
+
+	jobsSummaryCmd.PersistentFlags().BoolVar(&commandLineInput.all, "all", false, "Add up the summaries of every job that has a plan file on this machine.")
+}
+
+// AllJobsSummary is the sum of the summaries of every job on the machine
+type AllJobsSummary struct {
+	TotalJobs               uint32         `json:",string"`
+	JobsByStatus            map[string]int // keyed by the name of the JobStatus
+	UnreadableJobs          []common.JobID // jobs that have plan files, but whose summaries couldn't be read
+	TotalTransfers          uint64         `json:",string"`
+	FileTransfers           uint64         `json:",string"`
+	FolderPropertyTransfers uint64         `json:",string"`
+	TransfersCompleted      uint64         `json:",string"`
+	TransfersFailed         uint64         `json:",string"`
+	TransfersSkipped        uint64         `json:",string"`
+
+	// does not include failed transfers or bytes sent in retries, as for a single job
+	TotalBytesTransferred uint64 `json:",string"`
+
+	// disk space used by AzCopy's own files
+	PlanFilesBytes int64 `json:",string"`
+	LogFilesBytes  int64 `json:",string"`
+}
+
+// add merges the summary of one job into the total
+func (s *AllJobsSummary) add(summary common.ListJobSummaryResponse) {
+	if s.JobsByStatus == nil {
+		s.JobsByStatus = make(map[string]int)
+	}
+	s.TotalJobs++
+	if summary.ErrorMsg != "" {
+		s.UnreadableJobs = append(s.UnreadableJobs, summary.JobID)
+		return
+	}
+
+	s.JobsByStatus[summary.JobStatus.String()]++
+	s.TotalTransfers += uint64(summary.TotalTransfers)
+	s.FileTransfers += uint64(summary.FileTransfers)
+	s.FolderPropertyTransfers += uint64(summary.FolderPropertyTransfers)
+	s.TransfersCompleted += uint64(summary.TransfersCompleted)
+	s.TransfersFailed += uint64(summary.TransfersFailed)
+	s.TransfersSkipped += uint64(summary.TransfersSkipped)
+	s.TotalBytesTransferred += summary.TotalBytesTransferred
+}
+
+// handleSummarizeAllJobs reads the summary of every job that has a plan file, and adds them up
+func handleSummarizeAllJobs() AllJobsSummary {
+	summary := AllJobsSummary{JobsByStatus: make(map[string]int)}
+
+	for _, id := range existingJobIDs() {
+		jobID, err := common.ParseJobID(id)
+		common.PanicIfErr(err) // existingJobIDs only returns valid IDs
+
+		jobSummary := common.ListJobSummaryResponse{}
+		Rpc(common.ERpcCmd.ListJobSummary(), &jobID, &jobSummary)
+		jobSummary.JobID = jobID // in case of an error, the response doesn't say which job it's for
+		summary.add(jobSummary)
+
+		// the job was resurrected to summarize it, so release it before going on to the next one
+		Rpc(common.ERpcCmd.ReleaseJob(), &jobID, nil)
+	}
+
+	summary.PlanFilesBytes = sizeOfFiles(azcopyJobPlanFolder, func(name string) bool { return strings.Contains(name, ".steV") })
+	summary.LogFilesBytes = sizeOfFiles(azcopyLogPathFolder, func(name string) bool { return strings.HasSuffix(name, ".log") })
+	return summary
+}
+
+// sizeOfFiles adds up the sizes of the files in the folder (but not its sub-folders) whose names are approved by the predicate
+func sizeOfFiles(folder string, predicate func(string) bool) int64 {
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		return 0
+	}
+
+	total := int64(0)
+	for _, f := range files {
+		if !f.IsDir() && predicate(f.Name()) {
+			total += f.Size()
+		}
+	}
+	return total
+}
+
+// PrintAllJobsSummary prints the sum of the summaries of all jobs
+func PrintAllJobsSummary(summary AllJobsSummary) {
+	glcm.Exit(func(format common.OutputFormat) string {
+		if format == common.EOutputFormat.Json() {
+			jsonOutput, err := json.Marshal(summary)
+			common.PanicIfErr(err)
+			return string(jsonOutput)
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("\nSummary of %v jobs\n", summary.TotalJobs))
+
+		statuses := make([]string, 0, len(summary.JobsByStatus))
+		for status := range summary.JobsByStatus {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			sb.WriteString(fmt.Sprintf("Jobs %s: %v\n", status, summary.JobsByStatus[status]))
+		}
+		if len(summary.UnreadableJobs) > 0 {
+			sb.WriteString(fmt.Sprintf("Jobs whose plan files couldn't be read: %v\n", len(summary.UnreadableJobs)))
+		}
+
+		sb.WriteString(fmt.Sprintf(
+			"Number of File Transfers: %v\nNumber of Folder Property Transfers: %v\nTotal Number Of Transfers: %v\nNumber of Transfers Completed: %v\nNumber of Transfers Failed: %v\nNumber of Transfers Skipped: %v\nTotal Bytes Transferred: %v\nDisk Space Used by Plan Files: %v\nDisk Space Used by Log Files: %v\n",
+			summary.FileTransfers,
+			summary.FolderPropertyTransfers,
+			summary.TotalTransfers,
+			summary.TransfersCompleted,
+			summary.TransfersFailed,
+			summary.TransfersSkipped,
+			summary.TotalBytesTransferred,
+			byteSizeToString(summary.PlanFilesBytes),
+			byteSizeToString(summary.LogFilesBytes),
+		))
+		return sb.String()
+	}, common.EExitCode.Success())
+}
@@ -120,7 +120,7 @@ func HandleListContainerCommand(unparsedSource string, location common.Location)
 		}
 	}
 
-	traverser, err := initResourceTraverser(source, location, &ctx, &credentialInfo, nil, nil, true, false,
+	traverser, err := initResourceTraverser(source, location, &ctx, &credentialInfo, nil, nil, nil, true, false,
 		false, func(common.EntityType) {}, nil, false, pipeline2.LogNone)
 
 	if err != nil {
@@ -0,0 +1,65 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// validateLocalCopyPaths makes sure that a copy between local paths can't read what it writes. If the destination is
+// inside the source, the copy would find its own output, and if the source is inside the destination (or they're the
+// same) files could be truncated as they are copied onto themselves.
+// Since a URL that isn't recognized is also taken for a local path, URLs are refused outright
+func validateLocalCopyPaths(source, destination string) error {
+	for _, arg := range []string{source, destination} {
+		if classifyArgument(arg) != common.ELocation.Local() {
+			return fmt.Errorf("%s isn't a local path, so it can't be copied to or from with --from-to LocalLocal",
+				common.URLStringExtension(arg).RedactSecretQueryParamForLogging())
+		}
+	}
+
+	src, err := filepath.Abs(common.ToShortPath(getPathBeforeFirstWildcard(source)))
+	if err != nil {
+		return err
+	}
+	dst, err := filepath.Abs(common.ToShortPath(destination))
+	if err != nil {
+		return err
+	}
+	if isLocalPathWithin(dst, src) || isLocalPathWithin(src, dst) {
+		return fmt.Errorf("the source %s and the destination %s overlap, so they can't be copied between", src, dst)
+	}
+	return nil
+}
+
+// isLocalPathWithin says whether child is parent, or is below it
+func isLocalPathWithin(child, parent string) bool {
+	rel, err := filepath.Rel(parent, child)
+	if err != nil {
+		return false // e.g. on different drives
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
@@ -23,7 +23,10 @@ package cmd
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/spf13/cobra"
@@ -47,6 +50,13 @@ func init() {
 			loginCmdArgs.clientSecret = glcm.GetEnvironmentVariable(common.EEnvironmentVariable.ClientSecret())
 			loginCmdArgs.persistToken = true
 
+			if loginCmdArgs.status {
+				if err := loginCmdArgs.printStatus(); err != nil {
+					glcm.Error("Failed to get the login status: " + err.Error())
+				}
+				return nil
+			}
+
 			if loginCmdArgs.certPass != "" || loginCmdArgs.clientSecret != "" {
 				glcm.Info(environmentVariableNotice)
 			}
@@ -80,6 +90,11 @@ func init() {
 	//login with SPN
 	lgCmd.PersistentFlags().StringVar(&loginCmdArgs.applicationID, "application-id", "", "Application ID of user-assigned identity. Required for service principal auth.")
 	lgCmd.PersistentFlags().StringVar(&loginCmdArgs.certPath, "certificate-path", "", "Path to certificate for SPN authentication. Required for certificate-based service principal auth.")
+	lgCmd.PersistentFlags().BoolVar(&loginCmdArgs.selectTenant, "select-tenant", false, "After an interactive login, choose which of your tenants to use from a list. The choice is cached with your login information.")
+	lgCmd.PersistentFlags().BoolVar(&loginCmdArgs.selectSubscription, "select-subscription", false, "After an interactive login, choose which of the tenant's subscriptions you work in from a list. The choice is cached with your login information, and shown by --status.")
+
+	// report on the cached login information instead of logging in.
+	lgCmd.PersistentFlags().BoolVar(&loginCmdArgs.status, "status", false, "Show whether login information is cached for the current user, and don't log in.")
 }
 
 type loginCmdArgs struct {
@@ -103,6 +118,10 @@ type loginCmdArgs struct {
 	certPass      string
 	clientSecret  string
 	persistToken  bool
+
+	status             bool // Only report on the cached login information.
+	selectTenant       bool // Pick the tenant interactively after a device code login.
+	selectSubscription bool // Pick the subscription interactively after a device code login (and after the tenant, if that is picked too).
 }
 
 type argValidity struct {
@@ -148,8 +167,181 @@ func (lca loginCmdArgs) validate() error {
 		if lca.identityClientID != "" || lca.identityObjectID != "" || lca.identityResourceID != "" {
 			return errors.New("identity client/object/resource IDs are exclusive to managed service identity auth and are not compatible with OAuth")
 		}
+
+		if lca.selectTenant && lca.tenantID != "" {
+			return errors.New("a tenant ID cannot be used with --select-tenant")
+		}
+	}
+
+	if lca.selectTenant && (lca.identity || lca.servicePrincipal) {
+		return errors.New("--select-tenant is only supported for interactive login")
+	}
+
+	if lca.selectSubscription && (lca.identity || lca.servicePrincipal) {
+		return errors.New("--select-subscription is only supported for interactive login")
+	}
+
+	return nil
+}
+
+// resolveKeyVaultSecrets replaces any secret given as a Key Vault secret URI with the secret's value.
+// The secret is fetched with the machine's managed identity, optionally selected via the AZCOPY_MSI_* environment variables.
+func (lca *loginCmdArgs) resolveKeyVaultSecrets() (err error) {
+	if !common.IsKeyVaultSecretURI(lca.clientSecret) && !common.IsKeyVaultSecretURI(lca.certPass) {
+		return nil
+	}
+
+	identityInfo, err := common.AmbientIdentityInfo()
+	if err != nil {
+		return err
+	}
+
+	if lca.clientSecret, err = common.ResolveSecret(context.TODO(), lca.clientSecret, identityInfo); err != nil {
+		return fmt.Errorf("cannot resolve the client secret from Key Vault: %v", err)
+	}
+	if lca.certPass, err = common.ResolveSecret(context.TODO(), lca.certPass, identityInfo); err != nil {
+		return fmt.Errorf("cannot resolve the certificate password from Key Vault: %v", err)
+	}
+	return nil
+}
+
+// pickTenant lists the tenants of the user who just logged in, and asks which one to use when there is more than one.
+// The token for the chosen tenant replaces the cached one, so later commands use that tenant without needing a flag.
+func (lca loginCmdArgs) pickTenant(uotm *common.UserOAuthTokenManager, tokenInfo *common.OAuthTokenInfo) (*common.OAuthTokenInfo, error) {
+	tenants, err := uotm.ListTenants(context.TODO(), tokenInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	var chosen common.TenantInfo
+	switch len(tenants) {
+	case 0:
+		return nil, errors.New("no tenant was found for the account")
+	case 1:
+		chosen = tenants[0]
+	default:
+		options := make([]common.ResponseOption, len(tenants))
+		for i, t := range tenants {
+			options[i] = common.ResponseOption{
+				ResponseType:             t.TenantID,
+				UserFriendlyResponseType: fmt.Sprintf("%s (%s)", t.DisplayName, common.IffString(t.DefaultDomain != "", t.DefaultDomain, t.TenantID)),
+				ResponseString:           strconv.Itoa(i + 1),
+			}
+		}
+
+		glcm.EnableInputWatcher()
+		answer := glcm.Prompt("Your account belongs to several tenants, which one should AzCopy use?",
+			common.PromptDetails{PromptType: common.EPromptType.SelectTenant(), ResponseOptions: options})
+		for _, t := range tenants {
+			if t.TenantID == answer.ResponseType {
+				chosen = t
+			}
+		}
+		if chosen.TenantID == "" {
+			return nil, errors.New("no tenant was selected")
+		}
+	}
+
+	newInfo, err := uotm.SwitchTenant(context.TODO(), tokenInfo, chosen.TenantID, lca.persistToken)
+	if err != nil {
+		return nil, err
+	}
+	glcm.Info(fmt.Sprintf("Using tenant %s (%s).", chosen.DisplayName, chosen.TenantID))
+	return newInfo, nil
+}
+
+// pickSubscription lists the subscriptions in the tenant of the login, and asks which one the user works in when there is more than one.
+// The choice is cached with the login.
+func (lca loginCmdArgs) pickSubscription(uotm *common.UserOAuthTokenManager, tokenInfo *common.OAuthTokenInfo) error {
+	all, err := uotm.ListSubscriptions(context.TODO(), tokenInfo)
+	if err != nil {
+		return err
+	}
+	subscriptions := make([]common.SubscriptionInfo, 0, len(all))
+	for _, sub := range all {
+		if tokenInfo.Tenant == "" || strings.EqualFold(sub.TenantID, tokenInfo.Tenant) {
+			subscriptions = append(subscriptions, sub)
+		}
+	}
+
+	var chosen common.SubscriptionInfo
+	switch len(subscriptions) {
+	case 0:
+		return errors.New("no subscription was found in the tenant")
+	case 1:
+		chosen = subscriptions[0]
+	default:
+		options := make([]common.ResponseOption, len(subscriptions))
+		for i, sub := range subscriptions {
+			options[i] = common.ResponseOption{
+				ResponseType:             sub.SubscriptionID,
+				UserFriendlyResponseType: fmt.Sprintf("%s (%s)", sub.DisplayName, sub.SubscriptionID),
+				ResponseString:           strconv.Itoa(i + 1),
+			}
+		}
+
+		glcm.EnableInputWatcher()
+		answer := glcm.Prompt("You can access several subscriptions, which one do you work in?",
+			common.PromptDetails{PromptType: common.EPromptType.SelectSubscription(), ResponseOptions: options})
+		for _, sub := range subscriptions {
+			if sub.SubscriptionID == answer.ResponseType {
+				chosen = sub
+			}
+		}
+		if chosen.SubscriptionID == "" {
+			return errors.New("no subscription was selected")
+		}
+	}
+
+	if _, err := uotm.SelectSubscription(tokenInfo, chosen.SubscriptionID, lca.persistToken); err != nil {
+		return err
+	}
+	glcm.Info(fmt.Sprintf("Using subscription %s (%s).", chosen.DisplayName, chosen.SubscriptionID))
+	return nil
+}
+
+// printStatus reports on the cached login information without refreshing it.
+func (lca loginCmdArgs) printStatus() error {
+	uotm := GetUserOAuthTokenManagerInstance()
+	tokenInfo, err := uotm.LoadCachedTokenInfo()
+	if err != nil {
+		return err
+	}
+	if tokenInfo == nil {
+		glcm.Info("You are not logged in. Run 'azcopy login' to cache your login information.")
+		return nil
+	}
+
+	authType := "user (device code)"
+	switch {
+	case tokenInfo.Identity:
+		authType = "managed service identity"
+	case tokenInfo.ServicePrincipalName:
+		authType = "service principal"
+	}
+
+	lines := []string{
+		"You are logged in.",
+		"Auth type: " + authType,
+	}
+	if tokenInfo.Tenant != "" {
+		lines = append(lines, "Tenant ID: "+tokenInfo.Tenant)
+	}
+	if tokenInfo.SubscriptionID != "" {
+		lines = append(lines, "Subscription ID: "+tokenInfo.SubscriptionID)
+	}
+	if tokenInfo.ActiveDirectoryEndpoint != "" {
+		lines = append(lines, "AAD endpoint: "+tokenInfo.ActiveDirectoryEndpoint)
+	}
+	if tokenInfo.ApplicationID != "" && tokenInfo.ServicePrincipalName {
+		lines = append(lines, "Application ID: "+tokenInfo.ApplicationID)
+	}
+	lines = append(lines, "Access token expires: "+tokenInfo.Expires().Local().Format(time.RFC1123))
+	if tokenInfo.IsExpired() && tokenInfo.RefreshToken == "" && !tokenInfo.Identity && !tokenInfo.ServicePrincipalName {
+		lines = append(lines, "The cached token has expired, please log in again.")
 	}
 
+	glcm.Info(strings.Join(lines, "\n"))
 	return nil
 }
 
@@ -159,6 +351,10 @@ func (lca loginCmdArgs) process() error {
 		return err
 	}
 
+	if err := lca.resolveKeyVaultSecrets(); err != nil {
+		return err
+	}
+
 	uotm := GetUserOAuthTokenManagerInstance()
 	// Persist the token to cache, if login fulfilled successfully.
 
@@ -189,9 +385,20 @@ func (lca loginCmdArgs) process() error {
 		// For MSI login, info success message to user.
 		glcm.Info("Login with identity succeeded.")
 	default:
-		if _, err := uotm.UserLogin(lca.tenantID, lca.aadEndpoint, lca.persistToken); err != nil {
+		tokenInfo, err := uotm.UserLogin(lca.tenantID, lca.aadEndpoint, lca.persistToken)
+		if err != nil {
 			return err
 		}
+		if lca.selectTenant {
+			if tokenInfo, err = lca.pickTenant(uotm, tokenInfo); err != nil {
+				return err
+			}
+		}
+		if lca.selectSubscription {
+			if err := lca.pickSubscription(uotm, tokenInfo); err != nil {
+				return err
+			}
+		}
 		// User fulfills login in browser, and there would be message in browser indicating whether login fulfilled successfully.
 		glcm.Info("Login succeeded.")
 	}
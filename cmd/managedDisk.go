@@ -0,0 +1,110 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+const (
+	// the import URLs that Azure grants for managed disks are in accounts whose names start with this
+	managedDiskImportAccountPrefix = "md-impexp-"
+
+	// a VHD ends with a footer, which starts with the cookie, and has the disk type at diskTypeOffset
+	vhdFooterSize           = 512
+	vhdFooterCookie         = "conectix"
+	vhdFooterDiskTypeOffset = 60
+	vhdDiskTypeFixed        = 2
+
+	// the size of a managed disk, which is the size of its VHD without the footer, is a whole number of MiB
+	managedDiskSizeAlignment = 1024 * 1024
+)
+
+// isManagedDiskURL says whether the URL is a managed disk's import URL, so that an upload to it is inferred
+// to be LocalManagedDisk rather than LocalBlob
+func isManagedDiskURL(arg string) bool {
+	u, err := url.Parse(arg)
+	return err == nil && strings.HasPrefix(strings.ToLower(u.Host), managedDiskImportAccountPrefix)
+}
+
+// validateManagedDiskUpload checks what can't be done to a managed disk. Its import URL only takes the disk's data,
+// written over the page blob that was made with the disk, so there's no tier, header, metadata or tag to set, and
+// the destination always exists already. The data has to be a fixed size VHD
+func (cca *cookedCopyCmdArgs) validateManagedDiskUpload() error {
+	switch {
+	case cca.blobType != common.EBlobType.Detect() && cca.blobType != common.EBlobType.PageBlob():
+		return fmt.Errorf("a managed disk is a page blob, so blob-type can't be %s", cca.blobType)
+	case cca.blockBlobTier != common.EBlockBlobTier.None() || cca.pageBlobTier != common.EPageBlobTier.None():
+		return errors.New("blob-tier is not supported when uploading to a managed disk, since its disk type decides its tier")
+	case cca.putMd5:
+		return errors.New("put-md5 is not supported when uploading to a managed disk")
+	case len(cca.contentType) > 0 || len(cca.contentEncoding) > 0 || len(cca.contentLanguage) > 0 || len(cca.contentDisposition) > 0 ||
+		len(cca.cacheControl) > 0 || len(cca.metadata) > 0 || len(cca.blobTags) > 0:
+		return errors.New("content-type, content-encoding, content-language, content-disposition, cache-control, metadata and blob-tags can't be set on a managed disk")
+	case cca.preserveLastModifiedTime:
+		return errors.New("preserve-last-modified-time is not supported while uploading to a managed disk")
+	case cca.forceWrite != common.EOverwriteOption.True():
+		return errors.New("a managed disk always exists before its data is uploaded, so overwrite must be true to upload to it")
+	}
+	return validateDiskImage(cca.source.ValueLocal())
+}
+
+// validateDiskImage checks that the file is what Azure accepts as a managed disk's data: a fixed size VHD, whose footer
+// comes after a whole number of MiB. Anything else would only be found to be unusable once the upload is done
+func validateDiskImage(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, but a managed disk can only be uploaded from a single VHD file", path)
+	}
+	size := info.Size()
+	if size < vhdFooterSize || (size-vhdFooterSize)%managedDiskSizeAlignment != 0 {
+		return fmt.Errorf("%s is %d bytes, but a managed disk's VHD must be a whole number of MiB, plus its %d byte footer",
+			path, size, vhdFooterSize)
+	}
+
+	footer := make([]byte, vhdFooterSize)
+	if _, err = f.ReadAt(footer, size-vhdFooterSize); err != nil {
+		return err
+	}
+	if string(footer[:len(vhdFooterCookie)]) != vhdFooterCookie {
+		return fmt.Errorf("%s doesn't end with a VHD footer. Managed disks can only be uploaded from fixed size VHD files, so convert other images (such as VHDX) first", path)
+	}
+	if diskType := binary.BigEndian.Uint32(footer[vhdFooterDiskTypeOffset:]); diskType != vhdDiskTypeFixed {
+		return fmt.Errorf("%s is a dynamic or differencing VHD, but managed disks can only be uploaded from fixed size VHDs", path)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+const pathRewriteStripPrefix = "strip-prefix:"
+const pathRewriteAddPrefix = "add-prefix:"
+
+type pathRewriteRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// pathRewriter rewrites the relative paths of the transfers on the destination side, between enumeration and plan creation.
+// The rules are separated by ';' and applied in order, and can be:
+// 1. s/regex/replacement/, sed-like, any character following the s is used as delimiter (e.g. s:^2023/::). \ escapes the delimiter.
+// 2. strip-prefix:prefix, which removes the prefix from the paths which start with it.
+// 3. add-prefix:prefix, which prepends the prefix to every path.
+// Paths are always seen with '/' as separator, whatever the OS.
+type pathRewriter []pathRewriteRule
+
+func newPathRewriter(rules string) (pathRewriter, error) {
+	var r pathRewriter
+
+	for rest := strings.TrimSpace(rules); rest != ""; rest = strings.TrimSpace(rest) {
+		var rule pathRewriteRule
+		var err error
+
+		switch {
+		case strings.HasPrefix(rest, pathRewriteStripPrefix), strings.HasPrefix(rest, pathRewriteAddPrefix):
+			var arg string
+			arg, rest = splitPathRewriteRule(rest)
+			if strings.HasPrefix(arg, pathRewriteStripPrefix) {
+				rule.pattern = regexp.MustCompile("^" + regexp.QuoteMeta(strings.TrimPrefix(arg, pathRewriteStripPrefix)))
+			} else {
+				rule.pattern = regexp.MustCompile("^")
+				rule.replacement = strings.Replace(strings.TrimPrefix(arg, pathRewriteAddPrefix), "$", "$$", -1)
+			}
+		case len(rest) > 1 && rest[0] == 's':
+			if rule, rest, err = parseSedPathRewriteRule(rest); err != nil {
+				return nil, err
+			}
+		default:
+			arg, _ := splitPathRewriteRule(rest)
+			return nil, fmt.Errorf("invalid path rewrite rule %q, expected s/regex/replacement/, strip-prefix:prefix or add-prefix:prefix", arg)
+		}
+
+		r = append(r, rule)
+	}
+
+	return r, nil
+}
+
+// splitPathRewriteRule splits the first rule off the list of rules.
+func splitPathRewriteRule(rules string) (rule string, rest string) {
+	if i := strings.Index(rules, ";"); i >= 0 {
+		return rules[:i], rules[i+1:]
+	}
+	return rules, ""
+}
+
+func parseSedPathRewriteRule(rules string) (rule pathRewriteRule, rest string, err error) {
+	delimiter := rules[1]
+	parts := make([]string, 0, 2)
+	var current strings.Builder
+
+	i := 2
+	for ; i < len(rules) && len(parts) < 2; i++ {
+		switch {
+		case rules[i] == '\\' && i+1 < len(rules) && rules[i+1] == delimiter:
+			current.WriteByte(delimiter)
+			i++
+		case rules[i] == delimiter:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(rules[i])
+		}
+	}
+
+	if len(parts) != 2 {
+		return rule, "", fmt.Errorf("invalid path rewrite rule %q, expected s%cregex%creplacement%c", rules, delimiter, delimiter, delimiter)
+	}
+
+	rest = strings.TrimSpace(rules[i:])
+	if rest != "" && rest[0] != ';' {
+		return rule, "", fmt.Errorf("invalid path rewrite rule %q, expected ';' after the rule", rules)
+	}
+	rest = strings.TrimPrefix(rest, ";")
+
+	if rule.pattern, err = regexp.Compile(parts[0]); err != nil {
+		return rule, "", fmt.Errorf("invalid regular expression in path rewrite rule: %v", err)
+	}
+	rule.replacement = parts[1]
+
+	return rule, rest, nil
+}
+
+// rewrite applies the rules to a relative path, the result never starts with a separator.
+func (r pathRewriter) rewrite(relativePath string) string {
+	p := strings.Replace(relativePath, common.OS_PATH_SEPARATOR, common.AZCOPY_PATH_SEPARATOR_STRING, -1)
+	for _, rule := range r {
+		p = rule.pattern.ReplaceAllString(p, rule.replacement)
+	}
+
+	return strings.TrimLeft(p, common.AZCOPY_PATH_SEPARATOR_STRING)
+}
@@ -0,0 +1,65 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+)
+
+type pathRewriterSuite struct{}
+
+var _ = chk.Suite(&pathRewriterSuite{})
+
+func (s *pathRewriterSuite) TestPathRewriter(c *chk.C) {
+	cases := []struct {
+		rules    string
+		path     string
+		expected string
+	}{
+		{"s:^2023/::", "2023/01/a.txt", "01/a.txt"},
+		{"s:^2023/::", "2022/01/a.txt", "2022/01/a.txt"},
+		{`s/^(\d{4})\/(\d{2})\//year=$1\/month=$2\//`, "2023/01/a.txt", "year=2023/month=01/a.txt"},
+		{"strip-prefix:logs/", "logs/app/a.log", "app/a.log"},
+		{"strip-prefix:logs/", "other/logs/a.log", "other/logs/a.log"},
+		{"add-prefix:archive/", "a/b.txt", "archive/a/b.txt"},
+		{"strip-prefix:old/; add-prefix:new/", "old/a.txt", "new/a.txt"},
+		{"s|\\.jpeg$|.jpg|;add-prefix:img/", "x/y.jpeg", "img/x/y.jpg"},
+		{"s:^dir::", "dir/a.txt", "a.txt"}, // no leading separator is left
+	}
+
+	for _, x := range cases {
+		r, err := newPathRewriter(x.rules)
+		c.Assert(err, chk.IsNil, chk.Commentf("%s", x.rules))
+		c.Assert(r.rewrite(x.path), chk.Equals, x.expected, chk.Commentf("%s on %s", x.rules, x.path))
+	}
+}
+
+func (s *pathRewriterSuite) TestPathRewriterInvalidRules(c *chk.C) {
+	for _, rules := range []string{
+		"unknown:x",
+		"s:^a:b",     // unterminated
+		"s:(:b:",     // invalid regex
+		"s:a:b:junk", // trailing characters
+	} {
+		_, err := newPathRewriter(rules)
+		c.Assert(err, chk.NotNil, chk.Commentf("%s", rules))
+	}
+}
@@ -56,7 +56,7 @@ func determineLocationLevel(location string, locationType common.Location, sourc
 	case common.ELocation.Benchmark():
 		return ELocationLevel.Object(), nil // we always benchmark to a subfolder, not the container root
 
-	case common.ELocation.Blob(),
+	case common.ELocation.Blob(), common.ELocation.ManagedDisk(),
 		common.ELocation.File(),
 		common.ELocation.BlobFS(),
 		common.ELocation.S3(),
@@ -106,7 +106,7 @@ func GetResourceRoot(resource string, location common.Location) (resourceBase st
 		return cleanLocalPath(getPathBeforeFirstWildcard(resource)), nil
 
 	//noinspection GoNilness
-	case common.ELocation.Blob():
+	case common.ELocation.Blob(), common.ELocation.ManagedDisk():
 		bURLParts := azblob.NewBlobURLParts(*resourceURL)
 
 		if bURLParts.ContainerName == "" || strings.Contains(bURLParts.ContainerName, "*") {
@@ -221,7 +221,8 @@ func splitAuthTokenFromResource(resource string, location common.Location) (reso
 	case common.ELocation.GCP():
 		return resource, "", nil
 	case common.ELocation.Benchmark(), // cover for benchmark as we generate data for that
-		common.ELocation.Unknown(): // cover for unknown as we treat that as garbage
+		common.ELocation.Unknown(), // cover for unknown as we treat that as garbage
+		common.ELocation.None():    // nothing is written anywhere for in-place changes
 		// Local and S3 don't feature URL-embedded tokens
 		return resource, "", nil
 
@@ -231,7 +232,7 @@ func splitAuthTokenFromResource(resource string, location common.Location) (reso
 	//       We've already seen a similar thing happen with Blob SAS tokens and the introduction of User Delegation Keys.
 	//       It's not a breaking change to the way SAS tokens work, but a pretty major addition.
 	// TODO: Find a clever way to reduce code duplication in here. Especially the URL parsing.
-	case common.ELocation.Blob():
+	case common.ELocation.Blob(), common.ELocation.ManagedDisk():
 		var baseURL *url.URL // Do not shadow err for clean return statement
 		baseURL, err = url.Parse(resource)
 
@@ -330,7 +331,7 @@ func GetAccountRoot(resource common.ResourceString, location common.Location) (s
 	switch location {
 	case common.ELocation.Local():
 		panic("attempted to get account root on local location")
-	case common.ELocation.Blob(),
+	case common.ELocation.Blob(), common.ELocation.ManagedDisk(),
 		common.ELocation.File(),
 		common.ELocation.BlobFS():
 		baseURL, err := resource.FullURL()
@@ -355,7 +356,7 @@ func GetContainerName(path string, location common.Location) (string, error) {
 	switch location {
 	case common.ELocation.Local():
 		panic("attempted to get container name on local location")
-	case common.ELocation.Blob(),
+	case common.ELocation.Blob(), common.ELocation.ManagedDisk(),
 		common.ELocation.File(),
 		common.ELocation.BlobFS():
 		baseURL, err := url.Parse(path)
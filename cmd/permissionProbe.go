@@ -0,0 +1,224 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+const preflightProbeFlagName = "preflight-probe"
+
+// the name of the blob, under the destination path, that an uncommitted block is staged to at blob destinations. Uncommitted blocks are never listed as blobs,
+// and the service discards them after a week.
+const preflightProbeBlobPrefix = ".azcopy-permission-probe-"
+
+// probePermissions checks, before anything is enumerated, that the source can be read and the destination written,
+// so that a job over millions of objects fails at once rather than after the enumeration.
+// The permissions of a SAS are checked without contacting the service. Blob Storage is also probed with a request or two,
+// which catches expired SAS, missing role assignments and firewalls as well.
+// Only authorization failures are reported: anything else is left for the enumeration and the transfers to report as before.
+func (cca *cookedCopyCmdArgs) probePermissions(ctx context.Context) error {
+	from, to := cca.fromTo.From(), cca.fromTo.To()
+
+	if from.IsRemote() {
+		needed := []string{"r"}
+		if cca.deleteSourceAfterTransfer {
+			needed = append(needed, "d")
+		}
+		if err := checkSASPermissions("source", cca.source.SAS, needed...); err != nil {
+			return err
+		}
+	}
+	if to.IsRemote() {
+		// creating new blobs and files only takes c, but overwriting them takes w
+		if err := checkSASPermissions("destination", cca.destination.SAS, "wc"); err != nil {
+			return err
+		}
+	}
+
+	// an S2S source is authorized by its SAS, or is public, unless it has a token from a tenant of its own
+	if from == common.ELocation.Blob() && (to.IsLocal() || cca.s2sSourceTenantID == "") {
+		credInfo := common.CredentialInfo{CredentialType: common.ECredentialType.Anonymous()}
+		if to.IsLocal() {
+			credInfo = cca.credentialInfo
+		}
+		if err := cca.probeBlob(ctx, "source", cca.source, credInfo, probeBlobSource); err != nil {
+			return err
+		}
+	}
+	if to == common.ELocation.Blob() {
+		if err := cca.probeBlob(ctx, "destination", cca.destination, cca.credentialInfo, cca.probeBlobDestination); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSASPermissions fails if the SAS lacks one of the needed permissions.
+// Each needed entry lists alternatives, any one of which will do. A SAS that names no permissions, such as one that
+// refers to a stored access policy, is not checked.
+func checkSASPermissions(side string, sas string, needed ...string) error {
+	query, err := url.ParseQuery(sas)
+	if err != nil {
+		return nil // the SAS is validated elsewhere
+	}
+	permissions := query.Get("sp")
+	if permissions == "" {
+		return nil
+	}
+
+	if missing := missingSASPermissions(permissions, needed...); len(missing) > 0 {
+		return fmt.Errorf("the %s SAS has permissions '%s', but this copy needs '%s' as well. "+
+			"Generate a SAS with those permissions, or to skip this check, use --%s=false",
+			side, permissions, strings.Join(missing, "' and '"), preflightProbeFlagName)
+	}
+	return nil
+}
+
+// missingSASPermissions returns the needed entries that none of the permissions satisfy
+func missingSASPermissions(permissions string, needed ...string) []string {
+	missing := make([]string, 0)
+	for _, alternatives := range needed {
+		if !strings.ContainsAny(permissions, alternatives) {
+			missing = append(missing, strings.Join(strings.Split(alternatives, ""), "' or '"))
+		}
+	}
+	return missing
+}
+
+func (cca *cookedCopyCmdArgs) probeBlob(ctx context.Context, side string, resource common.ResourceString,
+	credInfo common.CredentialInfo, probe func(context.Context, pipeline.Pipeline, url.URL) error) error {
+	resourceURL, err := resource.FullURL()
+	if err != nil {
+		return nil // the URL is reported by the enumeration
+	}
+	p, err := createBlobPipeline(ctx, credInfo, pipeline.LogNone)
+	if err != nil {
+		return err
+	}
+
+	if err = probe(ctx, p, *resourceURL); isAuthorizationFailure(err) {
+		hint := "Check that the SAS hasn't expired"
+		if credInfo.CredentialType == common.ECredentialType.OAuthToken() {
+			hint = "Check that your login has a data role on the storage account, such as Storage Blob Data Contributor"
+		} else if resource.SAS == "" {
+			hint = "Append a SAS to the URL, or log in with azcopy login"
+		}
+		return fmt.Errorf("the %s isn't authorized for this copy (%s). %s, or to skip this check, use --%s=false",
+			side, err.(azblob.StorageError).ServiceCode(), hint, preflightProbeFlagName)
+	}
+	return nil
+}
+
+// isNarrowerThanContainer says whether the URL's SAS only grants access to a blob, or to a directory, and so can't
+// be used to list the container or to write at its root
+func isNarrowerThanContainer(u url.URL) bool {
+	switch u.Query().Get("sr") {
+	case "b", "bs", "bv", "d":
+		return true
+	default:
+		return false
+	}
+}
+
+// probeBlobSource reads the source blob's properties. When the source is a container or a virtual directory,
+// it lists one blob and reads that blob's properties instead, which shows that both listing and reading are allowed.
+// A SAS scoped to a blob or a directory only gets the first of those.
+func probeBlobSource(ctx context.Context, p pipeline.Pipeline, u url.URL) error {
+	parts := azblob.NewBlobURLParts(u)
+	if parts.ContainerName == "" || strings.Contains(parts.ContainerName, "*") {
+		return nil // a listing of the account fails as soon as the enumeration starts
+	}
+
+	prefix := parts.BlobName
+	if i := strings.Index(prefix, "*"); i >= 0 {
+		prefix = prefix[:i]
+	} else if prefix != "" {
+		_, err := azblob.NewBlobURL(u, p).GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+		if stgErr, ok := err.(azblob.StorageError); !ok || stgErr.ServiceCode() != azblob.ServiceCodeBlobNotFound {
+			return err
+		}
+		prefix = strings.TrimSuffix(prefix, common.AZCOPY_PATH_SEPARATOR_STRING) + common.AZCOPY_PATH_SEPARATOR_STRING
+	}
+	if isNarrowerThanContainer(u) {
+		return nil
+	}
+
+	parts.BlobName = ""
+	parts.Snapshot = ""
+	parts.VersionID = ""
+	containerURL := azblob.NewContainerURL(parts.URL(), p)
+	resp, err := containerURL.ListBlobsFlatSegment(ctx, azblob.Marker{}, azblob.ListBlobsSegmentOptions{Prefix: prefix, MaxResults: 1})
+	if err != nil || len(resp.Segment.BlobItems) == 0 {
+		return err
+	}
+	_, err = containerURL.NewBlobURL(resp.Segment.BlobItems[0].Name).GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	return err
+}
+
+// probeBlobDestination stages a block under the destination path, without ever committing it. A SAS scoped to a single
+// blob isn't probed, since the only place it could stage a block is the destination blob itself, whose own blocks
+// must not be mixed with the probe's
+func (cca *cookedCopyCmdArgs) probeBlobDestination(ctx context.Context, p pipeline.Pipeline, u url.URL) error {
+	parts := azblob.NewBlobURLParts(u)
+	if parts.ContainerName == "" {
+		return nil // the containers are created during the enumeration, and fail there
+	}
+	if sr := u.Query().Get("sr"); sr == "b" || sr == "bs" || sr == "bv" {
+		return nil
+	}
+
+	parts.BlobName = preflightProbeBlobName(parts.BlobName, cca.jobID)
+	blockID := base64.StdEncoding.EncodeToString([]byte(common.NewUUID().String()))
+	_, err := azblob.NewBlockBlobURL(parts.URL(), p).StageBlock(ctx, blockID, strings.NewReader("0"),
+		azblob.LeaseAccessConditions{}, nil, azblob.ClientProvidedKeyOptions{})
+	return err
+}
+
+// preflightProbeBlobName names the probe blob of a job under the destination path
+func preflightProbeBlobName(destination string, jobID common.JobID) string {
+	dir := strings.TrimSuffix(destination, common.AZCOPY_PATH_SEPARATOR_STRING)
+	if dir != "" {
+		dir += common.AZCOPY_PATH_SEPARATOR_STRING
+	}
+	return dir + preflightProbeBlobPrefix + jobID.String()
+}
+
+// isAuthorizationFailure tells the failures of the probes that are down to the credentials apart from the rest,
+// such as a container that the copy has yet to create
+func isAuthorizationFailure(err error) bool {
+	stgErr, ok := err.(azblob.StorageError)
+	if !ok || stgErr.Response() == nil {
+		return false
+	}
+	return stgErr.Response().StatusCode == http.StatusForbidden || stgErr.Response().StatusCode == http.StatusUnauthorized
+}
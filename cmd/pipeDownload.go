@@ -0,0 +1,77 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"io"
+)
+
+// rangeDownloader fetches count bytes of the source, starting at offset
+type rangeDownloader func(ctx context.Context, offset int64, count int64) ([]byte, error)
+
+// downloadRangesInOrder streams size bytes to out, fetching them in ranges of blockSize, up to parallelism at a time.
+// The ranges are written in order, so out can be a pipe, and only the ranges in flight are held in memory.
+func downloadRangesInOrder(ctx context.Context, size int64, blockSize int64, parallelism int, download rangeDownloader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // stops the scheduling below if we return early
+
+	type rangeResult struct {
+		data []byte
+		err  error
+	}
+
+	// the ranges that have been started, oldest first. Its capacity is what limits how many are in flight
+	inFlight := make(chan chan rangeResult, parallelism)
+
+	go func() {
+		defer close(inFlight)
+		for offset := int64(0); offset < size; offset += blockSize {
+			count := blockSize
+			if size-offset < count {
+				count = size - offset
+			}
+
+			result := make(chan rangeResult, 1)
+			select {
+			case inFlight <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(offset, count int64) {
+				data, err := download(ctx, offset, count)
+				result <- rangeResult{data: data, err: err}
+			}(offset, count)
+		}
+	}()
+
+	for result := range inFlight {
+		r := <-result
+		if r.err != nil {
+			return r.err
+		}
+		if _, err := out.Write(r.data); err != nil {
+			return err
+		}
+	}
+	return ctx.Err() // the ranges stop early if we're cancelled, and that mustn't look like the end of the source
+}
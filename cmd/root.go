@@ -45,7 +45,13 @@ var azcopyMaxFileAndSocketHandles int
 var outputFormatRaw string
 var cancelFromStdin bool
 var azcopyOutputFormat common.OutputFormat
+var blobListingStrategyRaw string
 var cmdLineCapMegaBitsPerSecond float64
+var cmdLineBoostNearlyComplete bool
+var cmdLinePlanFileLocation string
+var cmdLineLogLocation string
+var cmdLineLogNameTemplate string
+var cmdLineScheduleName string
 var azcopyAwaitContinue bool
 var azcopyAwaitAllowOpenFiles bool
 var azcopyScanningLogger common.ILoggerResetable
@@ -78,6 +84,10 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
+		if err = blobListingStrategy.Parse(blobListingStrategyRaw); err != nil {
+			return fmt.Errorf("invalid value '%s' for --blob-listing-strategy. The choices include: auto, flat, hierarchical", blobListingStrategyRaw)
+		}
+
 		// warn Windows users re quoting (since our docs all use single quotes, but CMD needs double)
 		// Single ones just come through as part of the args, in CMD.
 		// Ideally, for usability, we'd ideally have this info come back in the result of url.Parse. But that's hard to
@@ -98,14 +108,42 @@ var rootCmd = &cobra.Command{
 		preferToAutoTuneGRs := cmd == benchCmd // TODO: do we have a better way to do this than making benchCmd global?
 		providePerformanceAdvice := cmd == benchCmd
 
+		// likewise the log files, which can also be named to suit whatever collects them
+		if cmdLineLogLocation != "" {
+			if err := os.MkdirAll(cmdLineLogLocation, os.ModeDir|os.ModePerm); err != nil {
+				return fmt.Errorf("couldn't create the folder for --log-location: %w", err)
+			}
+			azcopyLogPathFolder = cmdLineLogLocation
+		}
+		logNameTemplate := common.DefaultLogFileNameTemplate
+		if cmdLineLogNameTemplate != "" {
+			logNameTemplate = common.LogFileNameTemplate(cmdLineLogNameTemplate)
+		} else if raw := glcm.GetEnvironmentVariable(common.EEnvironmentVariable.LogNameTemplate()); raw != "" {
+			logNameTemplate = common.LogFileNameTemplate(raw)
+		}
+		if err := logNameTemplate.Validate(); err != nil {
+			return err
+		}
+		common.SetLogFileNaming(logNameTemplate, cmd.Name())
+
+		// the plan files of this run go where the command line says, ahead of where the environment says
+		if cmdLinePlanFileLocation != "" {
+			if err := os.MkdirAll(cmdLinePlanFileLocation, os.ModeDir|os.ModePerm); err != nil {
+				return fmt.Errorf("couldn't create the folder for --plan-file-location: %w", err)
+			}
+			azcopyJobPlanFolder = cmdLinePlanFileLocation
+		}
+
 		// startup of the STE happens here, so that the startup can access the values of command line parameters that are defined for "root" command
 		concurrencySettings := ste.NewConcurrencySettings(azcopyMaxFileAndSocketHandles, preferToAutoTuneGRs)
+		concurrencySettings.BoostNearlyComplete = cmdLineBoostNearlyComplete
 		err = ste.MainSTE(concurrencySettings, float64(cmdLineCapMegaBitsPerSecond), azcopyJobPlanFolder, azcopyLogPathFolder, providePerformanceAdvice)
 		if err != nil {
 			return err
 		}
 		enumerationParallelism = concurrencySettings.EnumerationPoolSize.Value
 		enumerationParallelStatFiles = concurrencySettings.ParallelStatFiles.Value
+		glcm.OnCancelTransferFromStdIn(cancelTransferFromStdin)
 
 		// Log a clear ISO 8601-formatted start time, so it can be read and use in the --include-after parameter
 		// Subtract a few seconds, to ensure that this date DEFINITELY falls before the LMT of any file changed while this
@@ -128,6 +166,11 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// jobLogFilePath is where the main log file of the given job is, according to this run's log location and naming
+func jobLogFilePath(jobID common.JobID) string {
+	return fmt.Sprintf("%s%s%s", azcopyLogPathFolder, common.OS_PATH_SEPARATOR, common.JobLogFileName(jobID, ""))
+}
+
 // hold a pointer to the global lifecycle controller so that commands could output messages and exit properly
 var glcm = common.GetLifecycleMgr()
 var glcmSwapOnce = &sync.Once{}
@@ -161,20 +204,38 @@ func init() {
 	rootCmd.SetUsageTemplate(strings.Replace((&cobra.Command{}).UsageTemplate(), "Global Flags", "Flags Applying to All Commands", -1))
 
 	rootCmd.PersistentFlags().Float64Var(&cmdLineCapMegaBitsPerSecond, "cap-mbps", 0, "Caps the transfer rate, in megabits per second. Moment-by-moment throughput might vary slightly from the cap. If this option is set to zero, or it is omitted, the throughput isn't capped.")
+	rootCmd.PersistentFlags().BoolVar(&cmdLineBoostNearlyComplete, "boost-nearly-complete", false, "False by default. Send the remaining chunks of files that are more than 90% transferred ahead of other chunks. "+
+		"This finishes files sooner, and keeps fewer partly-transferred files open at once, but may slow down the rest of the job slightly.")
+	rootCmd.PersistentFlags().StringVar(&cmdLinePlanFileLocation, "plan-file-location", "", "Overrides where job plan files are stored, ahead of the "+common.EEnvironmentVariable.JobPlanLocation().Name+" environment variable. "+
+		"Jobs with very many files have large plan files, so this lets them live on a large scratch volume. Give the same location to the jobs commands (e.g. 'jobs resume') so that they can find the job. "+
+		"A job part whose plan file clearly won't fit in the space left there fails before the plan file is written.")
+	rootCmd.PersistentFlags().StringVar(&cmdLineLogLocation, "log-location", "", "Overrides where log files are stored, ahead of the "+common.EEnvironmentVariable.LogLocation().Name+" environment variable. "+
+		"Useful for putting the logs on a volume that's collected centrally.")
+	rootCmd.PersistentFlags().StringVar(&cmdLineLogNameTemplate, "log-name-template", "", "Overrides how log files are named, ahead of the "+common.EEnvironmentVariable.LogNameTemplate().Name+" environment variable. "+
+		"May contain {jobid}, which is required, {date} (the UTC date the command started, as yyyymmdd) and {verb} (the command, e.g. copy or sync). "+
+		"For example, {date}-{verb}-{jobid}. The default is {jobid}.")
 	rootCmd.PersistentFlags().StringVar(&outputFormatRaw, "output-type", "text", "Format of the command's output. The choices include: text, json. The default value is 'text'.")
 
+	rootCmd.PersistentFlags().StringVar(&blobListingStrategyRaw, "blob-listing-strategy", "auto", "Applies only when Azure Blobs is the source. Specifies how containers are enumerated. The choices include: "+
+		"auto (picks one of the others from the first page of the listing), flat (fewest listing calls, best for very deep virtual directory trees), "+
+		"hierarchical (lists virtual directories concurrently). The default value is 'auto'.")
+
 	rootCmd.PersistentFlags().StringVar(&cmdLineExtraSuffixesAAD, trustedSuffixesNameAAD, "", "Specifies additional domain suffixes where Azure Active Directory login tokens may be sent.  The default is '"+
 		trustedSuffixesAAD+"'. Any listed here are added to the default. For security, you should only put Microsoft Azure domains here. Separate multiple entries with semi-colons.")
 
 	// Note: this is due to Windows not supporting signals properly
-	rootCmd.PersistentFlags().BoolVar(&cancelFromStdin, "cancel-from-stdin", false, "Used by partner teams to send in `cancel` through stdin to stop a job.")
+	rootCmd.PersistentFlags().BoolVar(&cancelFromStdin, "cancel-from-stdin", false, "Used by partner teams to send in `cancel` through stdin to stop a job, or `cancel-transfer` followed by a transfer's source or destination to stop just that transfer.")
 
 	// special E2E testing flags
 	rootCmd.PersistentFlags().BoolVar(&azcopyAwaitContinue, "await-continue", false, "Used when debugging, to tell AzCopy to await `continue` on stdin before starting any work. Assists with debugging AzCopy via attach-to-process")
 	rootCmd.PersistentFlags().BoolVar(&azcopyAwaitAllowOpenFiles, "await-open", false, "Used when debugging, to tell AzCopy to await `open` on stdin, after scanning but before opening the first file. Assists with testing cases around file modifications between scanning and usage")
 
+	// set by 'schedule daemon' on the commands it runs, so that their jobs are recorded in the history as runs of the schedule
+	rootCmd.PersistentFlags().StringVar(&cmdLineScheduleName, scheduleNameFlag, "", "The schedule that this job is a run of.")
+
 	// reserved for partner teams
 	rootCmd.PersistentFlags().MarkHidden("cancel-from-stdin")
+	rootCmd.PersistentFlags().MarkHidden(scheduleNameFlag)
 
 	// debug-only
 	rootCmd.PersistentFlags().MarkHidden("await-continue")
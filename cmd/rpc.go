@@ -61,12 +61,21 @@ func inprocSend(rpcCmd common.RpcCmd, requestData interface{}, responseData inte
 	case common.ERpcCmd.CancelJob():
 		*(responseData.(*common.CancelPauseResumeResponse)) = ste.CancelPauseJobOrder(requestData.(common.JobID), common.EJobStatus.Cancelling())
 
+	case common.ERpcCmd.CancelJobAnywhere():
+		*(responseData.(*common.CancelPauseResumeResponse)) = ste.CancelJobAnywhere(requestData.(common.JobID))
+
+	case common.ERpcCmd.CancelTransfer():
+		*(responseData.(*common.CancelPauseResumeResponse)) = ste.CancelTransfer(*requestData.(*common.CancelTransferRequest))
+
 	case common.ERpcCmd.ResumeJob():
 		*(responseData.(*common.CancelPauseResumeResponse)) = ste.ResumeJobOrder(*requestData.(*common.ResumeJobRequest))
 
 	case common.ERpcCmd.GetJobFromTo():
 		*(responseData.(*common.GetJobFromToResponse)) = ste.GetJobFromTo(*requestData.(*common.GetJobFromToRequest))
 
+	case common.ERpcCmd.ReleaseJob():
+		ste.ReleaseJob(*requestData.(*common.JobID))
+
 	default:
 		panic(fmt.Errorf("Unrecognized RpcCmd: %q", rpcCmd.String()))
 	}
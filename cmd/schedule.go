@@ -0,0 +1,321 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// the schedules are kept in a JSON file in the app folder
+const scheduleFileName = "schedules.json"
+
+// the hidden flag that 'schedule daemon' adds to the commands it runs
+const scheduleNameFlag = "schedule-name"
+
+// the commands that a schedule may run
+var schedulableVerbs = []string{"copy", "sync", "remove"}
+
+// JobSchedule is a command that the schedule daemon runs whenever its cron expression is due
+type JobSchedule struct {
+	Name  string
+	Cron  string
+	Args  []string // the command, e.g. copy, and its arguments and flags
+	Added time.Time
+}
+
+// redactSchedules returns copies of the schedules to show, with the signatures of any SAS tokens in their arguments redacted
+func redactSchedules(schedules []JobSchedule) []JobSchedule {
+	redacted := make([]JobSchedule, len(schedules))
+	for i, s := range schedules {
+		redacted[i] = s
+		redacted[i].Args = copyHandlerUtil{}.redactArgs(s.Args)
+	}
+	return redacted
+}
+
+func schedulesPath() string {
+	return filepath.Join(azcopyAppPathFolder, scheduleFileName)
+}
+
+func loadSchedules(path string) ([]JobSchedule, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var schedules []JobSchedule
+	if err = json.Unmarshal(b, &schedules); err != nil {
+		return nil, fmt.Errorf("couldn't read the schedules in %s: %w", path, err)
+	}
+	return schedules, nil
+}
+
+// updateSchedules changes the schedules under a lock, so that concurrent adds and removes don't lose each other's changes.
+// The file is only readable by its owner, since the commands may hold SAS tokens
+func updateSchedules(path string, update func([]JobSchedule) ([]JobSchedule, error)) error {
+	unlock, err := common.LockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	schedules, err := loadSchedules(path)
+	if err != nil {
+		return err
+	}
+	if schedules, err = update(schedules); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(path+".tmp", b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func newJobSchedule(name, cron string, args []string) (JobSchedule, error) {
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return JobSchedule{}, errors.New("the schedule name must not be empty, or contain spaces")
+	}
+	if _, err := parseCronExpression(cron); err != nil {
+		return JobSchedule{}, err
+	}
+	if len(args) == 0 {
+		return JobSchedule{}, errors.New("the schedule needs a command to run, after --")
+	}
+	verbOK := false
+	for _, v := range schedulableVerbs {
+		verbOK = verbOK || args[0] == v
+	}
+	if !verbOK {
+		return JobSchedule{}, fmt.Errorf("a schedule can't run '%s'. The choices include: %s", args[0], strings.Join(schedulableVerbs, ", "))
+	}
+	return JobSchedule{Name: name, Cron: cron, Args: args, Added: time.Now().UTC()}, nil
+}
+
+func addSchedule(path string, schedule JobSchedule) error {
+	return updateSchedules(path, func(schedules []JobSchedule) ([]JobSchedule, error) {
+		for _, s := range schedules {
+			if s.Name == schedule.Name {
+				return nil, fmt.Errorf("there is already a schedule named %s", schedule.Name)
+			}
+		}
+		return append(schedules, schedule), nil
+	})
+}
+
+func removeSchedule(path string, name string) error {
+	return updateSchedules(path, func(schedules []JobSchedule) ([]JobSchedule, error) {
+		for i, s := range schedules {
+			if s.Name == name {
+				return append(schedules[:i], schedules[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("there is no schedule named %s", name)
+	})
+}
+
+// schedulesDue returns the schedules whose cron expressions are due in the minute of the given time
+func schedulesDue(schedules []JobSchedule, t time.Time) []JobSchedule {
+	var due []JobSchedule
+	for _, s := range schedules {
+		if cron, err := parseCronExpression(s.Cron); err == nil && cron.matches(t) {
+			due = append(due, s)
+		}
+	}
+	return due
+}
+
+// scheduleDaemon runs each schedule's command, as a separate AzCopy process, whenever the schedule is due.
+// A schedule whose previous run hasn't finished is skipped, rather than being run twice at once
+type scheduleDaemon struct {
+	path       string
+	executable string
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func (d *scheduleDaemon) run() {
+	glcm.Info(fmt.Sprintf("Running the schedules in %s. Press Ctrl-C to stop.", d.path))
+	for {
+		now := time.Now()
+		minute := now.Truncate(time.Minute).Add(time.Minute)
+		time.Sleep(minute.Sub(now))
+
+		// read every time, so that schedules added or removed meanwhile are picked up
+		schedules, err := loadSchedules(d.path)
+		if err != nil {
+			glcm.Info(err.Error())
+			continue
+		}
+		for _, s := range schedulesDue(schedules, minute) {
+			d.start(s)
+		}
+	}
+}
+
+func (d *scheduleDaemon) start(s JobSchedule) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running[s.Name] {
+		glcm.Info(fmt.Sprintf("%s Skipping schedule %s, since its previous run hasn't finished", time.Now().Format(time.RFC3339), s.Name))
+		return
+	}
+
+	// the schedule's name goes right after the command, so that it's ahead of any -- in the arguments
+	args := append([]string{s.Args[0], "--" + scheduleNameFlag + "=" + s.Name}, s.Args[1:]...)
+	cmd := exec.Command(d.executable, args...)
+	if err := cmd.Start(); err != nil {
+		glcm.Info(fmt.Sprintf("%s Couldn't start schedule %s: %s", time.Now().Format(time.RFC3339), s.Name, err))
+		return
+	}
+	d.running[s.Name] = true
+	glcm.Info(fmt.Sprintf("%s Started schedule %s, as process %d", time.Now().Format(time.RFC3339), s.Name, cmd.Process.Pid))
+
+	go func() {
+		err := cmd.Wait()
+		d.mu.Lock()
+		delete(d.running, s.Name)
+		d.mu.Unlock()
+
+		result := "succeeded"
+		if err != nil {
+			result = "failed: " + err.Error()
+		}
+		glcm.Info(fmt.Sprintf("%s Schedule %s %s. See 'azcopy history --schedule=%s' for its jobs", time.Now().Format(time.RFC3339), s.Name, result, s.Name))
+	}()
+}
+
+func init() {
+	scheduleCmd := &cobra.Command{
+		Use:   "schedule",
+		Short: scheduleCmdShortDescription,
+		Long:  scheduleCmdLongDescription,
+		Args: func(cmd *cobra.Command, args []string) error {
+			return errors.New("please specify a subcommand: add, list, remove or daemon")
+		},
+	}
+	rootCmd.AddCommand(scheduleCmd)
+
+	var cron string
+	scheduleAddCmd := &cobra.Command{
+		Use:     "add [name] -- [command] [arguments]",
+		Short:   scheduleAddCmdShortDescription,
+		Example: scheduleAddCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() != 1 {
+				return errors.New("the schedule add command requires a name, then --, then the command to run")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			schedule, err := newJobSchedule(args[0], cron, args[1:])
+			if err != nil {
+				glcm.Error("Failed to add the schedule: " + err.Error())
+			}
+			if err = addSchedule(schedulesPath(), schedule); err != nil {
+				glcm.Error("Failed to add the schedule: " + err.Error())
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				return fmt.Sprintf("Added schedule %s. It runs while 'azcopy schedule daemon' is running.", schedule.Name)
+			}, common.EExitCode.Success())
+		},
+	}
+	scheduleAddCmd.PersistentFlags().StringVar(&cron, "cron", "", "When to run the command, as a five-field cron expression (minute, hour, day of month, month, day of week) in local time, "+
+		"e.g. \"0 2 * * *\" for 2am every day. @hourly, @daily, @weekly and @monthly may be used too.")
+	scheduleCmd.AddCommand(scheduleAddCmd)
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Lists the schedules",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			schedules, err := loadSchedules(schedulesPath())
+			if err != nil {
+				glcm.Error("Failed to list the schedules: " + err.Error())
+			}
+			sort.Slice(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+			schedules = redactSchedules(schedules)
+			glcm.Exit(func(format common.OutputFormat) string {
+				if format == common.EOutputFormat.Json() {
+					jsonOutput, err := json.Marshal(schedules)
+					common.PanicIfErr(err)
+					return string(jsonOutput)
+				}
+				var sb strings.Builder
+				for _, s := range schedules {
+					sb.WriteString(fmt.Sprintf("Name: %s\nCron: %s\nCommand: %s\n\n", s.Name, s.Cron, strings.Join(s.Args, " ")))
+				}
+				sb.WriteString(fmt.Sprintf("Number of Schedules: %d\n", len(schedules)))
+				return sb.String()
+			}, common.EExitCode.Success())
+		},
+	})
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:     "remove [name]",
+		Aliases: []string{"rm"},
+		Short:   "Removes a schedule. Runs that have already started carry on",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := removeSchedule(schedulesPath(), args[0]); err != nil {
+				glcm.Error("Failed to remove the schedule: " + err.Error())
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				return fmt.Sprintf("Removed schedule %s.", args[0])
+			}, common.EExitCode.Success())
+		},
+	})
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "daemon",
+		Short: scheduleDaemonCmdShortDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			executable, err := os.Executable()
+			if err != nil {
+				glcm.Error("Failed to find the AzCopy executable: " + err.Error())
+			}
+			(&scheduleDaemon{path: schedulesPath(), executable: executable, running: make(map[string]bool)}).run()
+		},
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	raw := rawCopyCmdArgs{}
+	// setPropertiesCmd represents the set-properties command
+	var setPropertiesCmd = &cobra.Command{
+		Use:        "set-properties [resourceURL]",
+		Aliases:    []string{"set-props", "sp"},
+		SuggestFor: []string{"set-metadata", "set-tags"},
+		Short:      setPropertiesCmdShortDescription,
+		Long:       setPropertiesCmdLongDescription,
+		Example:    setPropertiesCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("set-properties command only takes 1 arguments. Passed %d arguments", len(args))
+			}
+
+			// the resource whose properties are set is given as the source, there is no destination
+			raw.src = args[0]
+
+			srcLocationType := inferArgumentLocation(raw.src)
+			if srcLocationType != common.ELocation.Blob() {
+				return fmt.Errorf("invalid source type %s to set properties on. azcopy only supports setting properties of blobs", srcLocationType.String())
+			}
+			raw.fromTo = common.EFromTo.BlobNone().String()
+
+			raw.setMandatoryDefaults()
+
+			if raw.contentType == "" && raw.contentEncoding == "" && raw.contentLanguage == "" &&
+				raw.contentDisposition == "" && raw.cacheControl == "" && raw.metadata == "" && raw.blobTags == "" {
+				return fmt.Errorf("nothing to set. Please specify at least one of the content headers, metadata or blob-tags")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			glcm.EnableInputWatcher()
+			if cancelFromStdin {
+				glcm.EnableCancelFromStdIn()
+			}
+
+			cooked, err := raw.cook()
+			if err != nil {
+				glcm.Error("failed to parse user input due to error: " + err.Error())
+			}
+			cooked.commandString = copyHandlerUtil{}.ConstructCommandStringFromArgs()
+			err = cooked.process()
+			if err != nil {
+				glcm.Error("failed to perform set-properties command due to error: " + err.Error())
+			}
+
+			glcm.SurrenderControl()
+		},
+	}
+	rootCmd.AddCommand(setPropertiesCmd)
+
+	setPropertiesCmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", false, "Look into sub-directories recursively when setting properties of the blobs in a virtual directory.")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.logVerbosity, "log-level", "INFO", "Define the log verbosity for the log file. Available levels include: INFO(all requests/responses), WARNING(slow responses), ERROR(only failed requests), and NONE(no output logs). (default 'INFO')")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.include, "include-pattern", "", "Include only blobs where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.includePath, "include-path", "", "Include only these paths when setting properties. "+
+		"This option does not support wildcard characters (*). Checks relative path prefix. For example: myFolder;myFolder/subDirName/file.pdf")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.exclude, "exclude-pattern", "", "Exclude blobs where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.excludePath, "exclude-path", "", "Exclude these paths when setting properties. "+
+		"This option does not support wildcard characters (*). Checks relative path prefix. For example: myFolder;myFolder/subDirName/file.pdf")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.includeDirectory, "include-directory", "", "Include only blobs that are inside a virtual directory with one of these names, at any depth. "+
+		"This option supports wildcard characters (*), which match within a single directory name. For example: src;docs")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.excludeDirectory, "exclude-directory", "", "Exclude virtual directories with these names, and everything inside them, at any depth. "+
+		"This option supports wildcard characters (*), which match within a single directory name. For example: node_modules;.git")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.listOfFilesToCopy, "list-of-files", "", "Defines the location of a file which contains the list of blobs and virtual directories whose properties are set. The relative paths should be delimited by line breaks, and the paths should NOT be URL-encoded.")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.contentType, "content-type", "", "Set the content type of the blobs. Left unchanged if not given.")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.contentEncoding, "content-encoding", "", "Set the content encoding of the blobs. Left unchanged if not given.")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.contentDisposition, "content-disposition", "", "Set the content disposition of the blobs. Left unchanged if not given.")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.contentLanguage, "content-language", "", "Set the content language of the blobs. Left unchanged if not given.")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.cacheControl, "cache-control", "", "Set the cache control of the blobs. Left unchanged if not given.")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.metadata, "metadata", "", "Replace the metadata of the blobs with these key-value pairs. Left unchanged if not given.")
+	setPropertiesCmd.PersistentFlags().StringVar(&raw.blobTags, "blob-tags", "", "Replace the tags of the blobs with these key-value pairs. Left unchanged if not given.")
+}
@@ -0,0 +1,122 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+var NothingToSetPropertiesError = errors.New("nothing found to set properties on")
+
+// provide an enumerator that lists the given blobs and schedules transfers that change their properties in place
+func newSetPropertiesEnumerator(cca *cookedCopyCmdArgs) (enumerator *copyEnumerator, err error) {
+	var sourceTraverser resourceTraverser
+
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+
+	// Include-path is handled by ListOfFilesChannel.
+	sourceTraverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &cca.credentialInfo, nil, nil, cca.listOfFilesChannel, cca.recursive, false,
+		false, func(common.EntityType) {}, nil, false, cca.logVerbosity.ToPipelineLogLevel())
+
+	// report failure to create traverser
+	if err != nil {
+		return nil, err
+	}
+
+	includeFilters := buildIncludeFilters(cca.includePatterns)
+	excludeFilters := buildExcludeFilters(cca.excludePatterns, false)
+	excludePathFilters := buildExcludeFilters(cca.excludePathPatterns, true)
+
+	// set up the filters in the right order
+	filters := append(includeFilters, excludeFilters...)
+	filters = append(filters, excludePathFilters...)
+	filters = append(filters, buildDirectoryNameFilters(cca.includeDirectories, cca.excludeDirectories)...)
+
+	fpo, message := newFolderPropertyOption(cca.fromTo, cca.recursive, cca.stripTopDir, filters, false, false)
+	glcm.Info(message)
+	if ste.JobsAdmin != nil {
+		ste.JobsAdmin.LogToJobLog(message, pipeline.LogInfo)
+	}
+
+	transferScheduler := newSetPropertiesTransferProcessor(cca, NumOfFilesPerDispatchJobPart, fpo)
+
+	finalize := func() error {
+		jobInitiated, err := transferScheduler.dispatchFinalPart()
+		if err != nil {
+			if err == NothingScheduledError {
+				// No log file needed. Logging begins as a part of awaiting job completion.
+				return NothingToSetPropertiesError
+			}
+
+			return err
+		}
+
+		if !jobInitiated {
+			glcm.Error("Nothing to set properties on. Please verify that recursive flag is set properly if targeting a directory.")
+		}
+
+		return nil
+	}
+
+	return newCopyEnumerator(sourceTraverser, filters, transferScheduler.scheduleCopyTransfer, finalize), nil
+}
+
+// extract the right info from cooked arguments and instantiate a generic copy transfer processor from it
+func newSetPropertiesTransferProcessor(cca *cookedCopyCmdArgs, numOfTransfersPerPart int, fpo common.FolderPropertyOption) *copyTransferProcessor {
+	copyJobTemplate := &common.CopyJobPartOrderRequest{
+		JobID:          cca.jobID,
+		CommandString:  cca.commandString,
+		FromTo:         cca.fromTo,
+		Fpo:            fpo,
+		SourceRoot:     cca.source.CloneWithConsolidatedSeparators(),
+		CredentialInfo: cca.credentialInfo,
+
+		// flags
+		LogLevel: cca.logVerbosity,
+		BlobAttributes: common.BlobTransferAttributes{
+			ContentType:        cca.contentType,
+			ContentEncoding:    cca.contentEncoding,
+			ContentLanguage:    cca.contentLanguage,
+			ContentDisposition: cca.contentDisposition,
+			CacheControl:       cca.cacheControl,
+			Metadata:           cca.metadata,
+			BlobTagsString:     cca.blobTags.ToString(),
+			// the headers must be taken as given, nothing is inferred for blobs that already exist
+			NoGuessMimeType: true,
+		},
+	}
+
+	reportFirstPart := func(jobStarted bool) {
+		if jobStarted {
+			cca.waitUntilJobCompletion(false)
+		}
+	}
+	reportFinalPart := func() { cca.isEnumerationComplete = true }
+
+	return newCopyTransferProcessor(copyJobTemplate, numOfTransfersPerPart, cca.source, cca.destination,
+		reportFirstPart, reportFinalPart, false)
+}
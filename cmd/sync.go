@@ -39,6 +39,7 @@ import (
 type rawSyncCmdArgs struct {
 	src       string
 	dst       string
+	jobID     string
 	recursive bool
 
 	// options from flags
@@ -47,6 +48,9 @@ type rawSyncCmdArgs struct {
 	include               string
 	exclude               string
 	excludePath           string
+	includePath           string
+	includeDirectory      string
+	excludeDirectory      string
 	includeFileAttributes string
 	excludeFileAttributes string
 	legacyInclude         string // for warning messages only
@@ -56,13 +60,24 @@ type rawSyncCmdArgs struct {
 	preserveOwner          bool
 	preserveSMBInfo        bool
 	followSymlinks         bool
+	excludeHidden          bool
+	includeHidden          bool
+	ignoreFileName         string
+	perDirIgnoreFiles      bool
 	backupMode             bool
 	putMd5                 bool
+	storeSourceLMT         bool
 	md5ValidationOption    string
+	flushPolicy            string
+	skipLargerThan         string
+	skipSmallerThan        string
+	transferTimeout        string
+	freeSpaceCheck         string
 	// this flag indicates the user agreement with respect to deleting the extra files at the destination
 	// which do not exists at source. With this flag turned on/off, users will not be asked for permission.
 	// otherwise the user is prompted to make a decision
-	deleteDestination string
+	deleteDestination    string
+	snapshotBeforeDelete bool
 
 	s2sPreserveAccessTier bool
 	// Opt-in flag to preserve the blob index tags during service to service transfer.
@@ -136,6 +151,10 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 		return cooked, fmt.Errorf("source '%s' / destination '%s' combination '%s' not supported for sync command ", raw.src, raw.dst, cooked.fromTo)
 	}
 
+	if err = validateShareSnapshots(cooked.fromTo, cooked.source, cooked.destination); err != nil {
+		return cooked, err
+	}
+
 	// Do this check separately so we don't end up with a bunch of code duplication when new src/dstn are added
 	if cooked.fromTo.From() == common.ELocation.Local() {
 		cooked.source = common.ResourceString{Value: common.ToExtendedPath(cleanLocalPath(raw.src))}
@@ -143,6 +162,27 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 		cooked.destination = common.ResourceString{Value: common.ToExtendedPath(cleanLocalPath(raw.dst))}
 	}
 
+	// hidden files are left out on both sides, so that hidden files at a local destination are not deleted either
+	if raw.excludeHidden && !raw.includeHidden {
+		if cooked.fromTo.From() == common.ELocation.Local() {
+			cooked.hiddenFiles = newHiddenFileFilter(cooked.source.ValueLocal())
+		} else if cooked.fromTo.To() == common.ELocation.Local() {
+			cooked.hiddenFiles = newHiddenFileFilter(cooked.destination.ValueLocal())
+		}
+	}
+
+	// the ignore rules apply to both sides too, so that ignored files at the destination are not deleted.
+	// When syncing down, it's the ignore file at the local destination that's honored.
+	if cooked.fromTo.From() == common.ELocation.Local() {
+		if cooked.ignoreFiles, err = newIgnoreFileFilter(cooked.source.ValueLocal(), raw.ignoreFileName, raw.perDirIgnoreFiles); err != nil {
+			return cooked, fmt.Errorf("cannot read the ignore file %s: %w", raw.ignoreFileName, err)
+		}
+	} else if cooked.fromTo.To() == common.ELocation.Local() {
+		if cooked.ignoreFiles, err = newIgnoreFileFilter(cooked.destination.ValueLocal(), raw.ignoreFileName, raw.perDirIgnoreFiles); err != nil {
+			return cooked, fmt.Errorf("cannot read the ignore file %s: %w", raw.ignoreFileName, err)
+		}
+	}
+
 	// we do not support service level sync yet
 	if cooked.fromTo.From().IsRemote() {
 		err = raw.validateURLIsNotServiceLevel(cooked.source.Value, cooked.fromTo.From())
@@ -183,10 +223,21 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	}
 
 	// determine whether we should prompt the user to delete extra files
-	err = cooked.deleteDestination.Parse(raw.deleteDestination)
-	if err != nil {
+	if location, ok := parseDeleteDestinationMoveTo(raw.deleteDestination); ok {
+		// the extra objects are still removed from the destination, just not lost
+		cooked.deleteDestination = common.EDeleteDestination.True()
+		if cooked.deletionArchive, err = newDeletionArchive(location, cooked.destination, cooked.fromTo.To()); err != nil {
+			return cooked, err
+		}
+	} else if err = cooked.deleteDestination.Parse(raw.deleteDestination); err != nil {
 		return cooked, err
 	}
+	if raw.snapshotBeforeDelete {
+		if cooked.fromTo.To() != common.ELocation.Blob() && cooked.fromTo.To() != common.ELocation.File() {
+			return cooked, fmt.Errorf("--%s only applies when the destination is a blob container or a file share", snapshotBeforeDeleteFlag)
+		}
+		cooked.snapshotBeforeDelete = true
+	}
 
 	// warn on legacy filters
 	if raw.legacyInclude != "" || raw.legacyExclude != "" {
@@ -197,6 +248,9 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	cooked.includePatterns = raw.parsePatterns(raw.include)
 	cooked.excludePatterns = raw.parsePatterns(raw.exclude)
 	cooked.excludePaths = raw.parsePatterns(raw.excludePath)
+	cooked.includePaths = dedupeIncludePaths(raw.parsePatterns(raw.includePath))
+	cooked.includeDirectories = raw.parsePatterns(raw.includeDirectory)
+	cooked.excludeDirectories = raw.parsePatterns(raw.excludeDirectory)
 
 	// parse the attribute filter patterns
 	cooked.includeFileAttributes = raw.parsePatterns(raw.includeFileAttributes)
@@ -212,7 +266,24 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	cooked.preserveSMBPermissions = common.NewPreservePermissionsOption(raw.preserveSMBPermissions, raw.preserveOwner, cooked.fromTo)
 
 	cooked.preserveSMBInfo = raw.preserveSMBInfo
-	if err = validatePreserveSMBPropertyOption(cooked.preserveSMBInfo, cooked.fromTo, nil, "preserve-smb-info"); err != nil {
+	if err = validatePreserveSMBPropertyOption(cooked.preserveSMBInfo, cooked.fromTo, nil, common.PreserveSMBInfoFlagName); err != nil {
+		return cooked, err
+	}
+
+	cooked.storeSourceLMT = raw.storeSourceLMT
+	if err = validateStoreSourceLMT(cooked.storeSourceLMT, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+
+	if cooked.skipLargerThan, cooked.skipSmallerThan, err = parseSizeLimits(raw.skipLargerThan, raw.skipSmallerThan); err != nil {
+		return cooked, err
+	}
+
+	if cooked.transferTimeout, err = parseTransferTimeout(raw.transferTimeout); err != nil {
+		return cooked, err
+	}
+
+	if err = cooked.freeSpaceCheck.Parse(raw.freeSpaceCheck); err != nil {
 		return cooked, err
 	}
 
@@ -229,6 +300,14 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 		return cooked, err
 	}
 
+	err = cooked.flushPolicy.Parse(raw.flushPolicy)
+	if err != nil {
+		return cooked, err
+	}
+	if err = validateFlushPolicy(cooked.flushPolicy, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+
 	if cooked.fromTo.IsS2S() {
 		cooked.preserveAccessTier = raw.s2sPreserveAccessTier
 	}
@@ -254,6 +333,8 @@ type cookedSyncCmdArgs struct {
 	atomicSourceFilesScanned uint64
 	// defines the number of files listed at the destination and compared.
 	atomicDestinationFilesScanned uint64
+	// what the files that were already up to date at the destination come to. They are never transfers, so the STE can't count them
+	atomicBytesUpToDate uint64
 	// defines the scanning status of the sync operation.
 	// 0 means scanning is in progress and 1 means scanning is complete.
 	atomicScanningStatus uint32
@@ -275,14 +356,25 @@ type cookedSyncCmdArgs struct {
 	includePatterns       []string
 	excludePatterns       []string
 	excludePaths          []string
+	includePaths          []string // listed one by one on both sides, rather than filtered
+	includeDirectories    []string
+	excludeDirectories    []string
 	includeFileAttributes []string
 	excludeFileAttributes []string
+	hiddenFiles           *hiddenFileFilter // only set when hidden files are being left out
+	ignoreFiles           *ignoreFileFilter // only set when syncing from a directory that has ignore files
 
 	// options
 	preserveSMBPermissions common.PreservePermissionsOption
 	preserveSMBInfo        bool
 	putMd5                 bool
+	storeSourceLMT         bool
 	md5ValidationOption    common.HashValidationOption
+	flushPolicy            common.FlushPolicy
+	skipLargerThan         int64         // in bytes, 0 means no limit
+	skipSmallerThan        int64         // in bytes, 0 means no limit
+	transferTimeout        time.Duration // 0 means no limit
+	freeSpaceCheck         common.FreeSpaceCheckOption
 	blockSize              int64
 	logVerbosity           common.LogLevel
 	forceIfReadOnly        bool
@@ -312,7 +404,9 @@ type cookedSyncCmdArgs struct {
 	// this flag indicates the user agreement with respect to deleting the extra files at the destination
 	// which do not exists at source. With this flag turned on/off, users will not be asked for permission.
 	// otherwise the user is prompted to make a decision
-	deleteDestination common.DeleteDestination
+	deleteDestination    common.DeleteDestination
+	snapshotBeforeDelete bool
+	deletionArchive      *deletionArchive // where the extra files are moved instead, for --delete-destination=move-to:<container/prefix>
 
 	preserveAccessTier bool
 	// To specify whether user wants to preserve the blob index tags during service to service transfer.
@@ -323,6 +417,11 @@ func (cca *cookedSyncCmdArgs) incrementDeletionCount() {
 	atomic.AddUint32(&cca.atomicDeletionCount, 1)
 }
 
+// countUpToDate is told about each source object that didn't need transferring, because the destination was already up to date
+func (cca *cookedSyncCmdArgs) countUpToDate(sourceObject storedObject) {
+	atomic.AddUint64(&cca.atomicBytesUpToDate, uint64(sourceObject.size))
+}
+
 func (cca *cookedSyncCmdArgs) getDeletionCount() uint32 {
 	return atomic.LoadUint32(&cca.atomicDeletionCount)
 }
@@ -352,7 +451,7 @@ func (cca *cookedSyncCmdArgs) scanningComplete() bool {
 // if blocking is specified to false, then another goroutine spawns and wait out the job
 func (cca *cookedSyncCmdArgs) waitUntilJobCompletion(blocking bool) {
 	// print initial message to indicate that the job is starting
-	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), fmt.Sprintf("%s%s%s.log", azcopyLogPathFolder, common.OS_PATH_SEPARATOR, cca.jobID), false, ""))
+	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), jobLogFilePath(cca.jobID), false, ""))
 
 	// initialize the times necessary to track progress
 	cca.jobStartTime = time.Now()
@@ -446,6 +545,8 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 		Rpc(common.ERpcCmd.GetJobLCMWrapper(), &cca.jobID, &lcm)
 		jobDone = summary.JobStatus.IsJobDone()
 		totalKnownCount = summary.TotalTransfers
+		summary.HiddenFilesSkipped = cca.hiddenFiles.Count()
+		summary.BytesSkipped += atomic.LoadUint64(&cca.atomicBytesUpToDate)
 
 		// compute the average throughput for the last time interval
 		bytesInMb := float64(float64(summary.BytesOverWire-cca.intervalBytesTransferred) * 8 / float64(base10Mega))
@@ -469,12 +570,13 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 		if summary.TransfersFailed > 0 {
 			exitCode = common.EExitCode.Error()
 		}
+		recordJobHistory(newJobHistoryEntry(summary, cca.fromTo, cca.source, cca.destination, cca.jobStartTime))
 
 		lcm.Exit(func(format common.OutputFormat) string {
 			if format == common.EOutputFormat.Json() {
 				return cca.getJsonOfSyncJobSummary(summary)
 			}
-			screenStats, logStats := formatExtraStats(cca.fromTo, summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.NetworkErrorPercentage, summary.ServerBusyPercentage)
+			screenStats, logStats := formatExtraStats(cca.fromTo, summary)
 
 			output := fmt.Sprintf(
 				`
@@ -487,8 +589,8 @@ Number of Copy Transfers for Folder Properties: %v
 Total Number Of Copy Transfers: %v
 Number of Copy Transfers Completed: %v
 Number of Copy Transfers Failed: %v
-Number of Deletions at Destination: %v
-Total Number of Bytes Transferred: %v
+Number of Deletions at Destination: %v%s
+Total Number of Bytes Transferred: %v%s
 Total Number of Bytes Enumerated: %v
 Final Job Status: %v%s%s
 `,
@@ -502,7 +604,9 @@ Final Job Status: %v%s%s
 				summary.TransfersCompleted,
 				summary.TransfersFailed,
 				cca.atomicDeletionCount,
+				formatHiddenFiles(cca.hiddenFiles != nil, summary.HiddenFilesSkipped),
 				summary.TotalBytesTransferred,
+				formatWireBytes(summary),
 				summary.TotalBytesEnumerated,
 				summary.JobStatus,
 				screenStats,
@@ -525,12 +629,12 @@ Final Job Status: %v%s%s
 		// indicate whether constrained by disk or not
 		perfString, diskString := getPerfDisplayText(summary.PerfStrings, summary.PerfConstraint, duration, false)
 
-		return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Total%s, 2-sec Throughput (Mb/s): %v%s",
+		return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Total%s, %s2-sec Throughput (Mb/s): %v%s",
 			summary.PercentComplete,
 			summary.TransfersCompleted,
 			summary.TransfersFailed,
 			summary.TotalTransfers-summary.TransfersCompleted-summary.TransfersFailed,
-			summary.TotalTransfers, perfString, ste.ToFixed(throughput, 4), diskString)
+			summary.TotalTransfers, formatEta(summary.EstimatedSecondsRemaining), perfString, ste.ToFixed(throughput, 4), diskString)
 	})
 
 	return
@@ -615,6 +719,16 @@ func init() {
 				glcm.EnableCancelFromStdIn()
 			}
 
+			if err := applyJobIDFlag(raw.jobID); err != nil {
+				glcm.Error("error parsing the input given by the user. Failed with error " + err.Error())
+			}
+			if resumed, err := resumeIfJobExists(raw.src, raw.dst); resumed {
+				if err != nil {
+					glcm.Error("Cannot resume the existing job due to error: " + err.Error())
+				}
+				glcm.SurrenderControl()
+			}
+
 			cooked, err := raw.cook()
 			if err != nil {
 				glcm.Error("error parsing the input given by the user. Failed with error " + err.Error())
@@ -630,6 +744,7 @@ func init() {
 	}
 
 	rootCmd.AddCommand(syncCmd)
+	syncCmd.PersistentFlags().StringVar(&raw.jobID, "job-id", "", jobIDFlagDescription)
 	syncCmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", true, "True by default, look into sub-directories recursively when syncing between directories. (default true).")
 
 	// TODO: enable for copy with IfSourceNewer
@@ -647,13 +762,42 @@ func init() {
 	syncCmd.PersistentFlags().StringVar(&raw.exclude, "exclude-pattern", "", "Exclude files where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
 	syncCmd.PersistentFlags().StringVar(&raw.excludePath, "exclude-path", "", "Exclude these paths when comparing the source against the destination. "+
 		"This option does not support wildcard characters (*). Checks relative path prefix(For example: myFolder;myFolder/subDirName/file.pdf).")
+	syncCmd.PersistentFlags().StringVar(&raw.includePath, "include-path", "", "Include only these paths when comparing the source against the destination. "+
+		"This option does not support wildcard characters (*). Each path is listed on its own, instead of listing everything and filtering (For example: myFolder;myFolder/subDirName/file.pdf). "+
+		"Unless --delete-destination is false, a path that can't be scanned on the source fails the sync.")
+	syncCmd.PersistentFlags().StringVar(&raw.includeDirectory, "include-directory", "", "Include only files that are inside a directory with one of these names, at any depth, when comparing the source against the destination. "+
+		"This option supports wildcard characters (*), which match within a single directory name. For example: src;docs")
+	syncCmd.PersistentFlags().StringVar(&raw.excludeDirectory, "exclude-directory", "", "Exclude directories with these names, and everything inside them, at any depth, when comparing the source against the destination. "+
+		"This option supports wildcard characters (*), which match within a single directory name. For example: node_modules;.git")
+	syncCmd.PersistentFlags().BoolVar(&raw.excludeHidden, "exclude-hidden", true, "True by default. When syncing to or from the local file system, leave out hidden files and folders on both sides: those whose names start with a dot, "+
+		"and on Windows also those with the hidden or system attribute.")
+	syncCmd.PersistentFlags().BoolVar(&raw.includeHidden, "include-hidden", false, "False by default. Sync hidden files and folders too. Takes precedence over exclude-hidden.")
+	syncCmd.PersistentFlags().StringVar(&raw.ignoreFileName, "ignore-file", defaultIgnoreFileName, "Leave out whatever the file with this name, at the root of the local side of the sync, says to, on both sides. "+
+		"So when syncing down, it's the ignore file at the destination that applies. "+
+		"It uses the same syntax as a .gitignore file, and applies together with the other filters. The ignore file itself is never synced. Set to an empty string to disable.")
+	syncCmd.PersistentFlags().BoolVar(&raw.perDirIgnoreFiles, "per-directory-ignore-files", false, "False by default. Also honor ignore files found in directories below the root of the local side, for their own contents.")
 	syncCmd.PersistentFlags().StringVar(&raw.includeFileAttributes, "include-attributes", "", "(Windows only) Include only files whose attributes match the attribute list. For example: A;S;R")
 	syncCmd.PersistentFlags().StringVar(&raw.excludeFileAttributes, "exclude-attributes", "", "(Windows only) Exclude files whose attributes match the attribute list. For example: A;S;R")
 	syncCmd.PersistentFlags().StringVar(&raw.logVerbosity, "log-level", "INFO", "Define the log verbosity for the log file, available levels: INFO(all requests and responses), WARNING(slow responses), ERROR(only failed requests), and NONE(no output logs). (default INFO).")
 	syncCmd.PersistentFlags().StringVar(&raw.deleteDestination, "delete-destination", "false", "Defines whether to delete extra files from the destination that are not present at the source. Could be set to true, false, or prompt. "+
-		"If set to prompt, the user will be asked a question before scheduling files and blobs for deletion. (default 'false').")
+		"If set to prompt, the user will be asked a question before scheduling files and blobs for deletion. (default 'false'). "+
+		"Set it to move-to:<container/prefix> to move the extra files to that prefix, in another container or share of the destination's account, "+
+		"rather than delete them. Each one is copied server-side, and deleted only once the copy succeeded.")
+	syncCmd.PersistentFlags().BoolVar(&raw.snapshotBeforeDelete, snapshotBeforeDeleteFlag, false, "False by default. Make sure the deletions of --delete-destination can be undone before the first one happens. "+
+		"For a file share destination, a snapshot of the share is taken, and its URL shown. Containers can't be snapshotted, and deleting a blob deletes its snapshots, "+
+		"so for a blob destination, blob versioning must be enabled on the storage account instead. If it isn't, nothing is deleted.")
+	syncCmd.PersistentFlags().StringVar(&raw.skipLargerThan, common.SkipLargerThanFlagName, "", "Skip files larger than the given size, e.g. 1TiB or 200M, marking them as skipped in the job summary. "+
+		"Skipped files still count as present in the source, so their destination copies are not deleted by --delete-destination.")
+	syncCmd.PersistentFlags().StringVar(&raw.skipSmallerThan, common.SkipSmallerThanFlagName, "", "Skip files smaller than the given size, e.g. 4K, marking them as skipped in the job summary.")
+	syncCmd.PersistentFlags().StringVar(&raw.transferTimeout, "transfer-timeout", "", "Fail any transfer that has been running for longer than this, e.g. 90m or 2h, instead of letting it retry indefinitely. "+
+		"The clock starts when the transfer's first chunk starts, not while it's waiting in the queue. Such transfers are counted as failed, and logged as timed out. By default there is no limit.")
+	syncCmd.PersistentFlags().StringVar(&raw.freeSpaceCheck, "check-free-space", common.EFreeSpaceCheckOption.Warn().String(), "Specifies what to do when downloading if the destination volume doesn't have enough free space for the files that need syncing. "+
+		"Available options: Warn (say so, but try anyway), Fail (fail the job as soon as it's found to be too big, and fail each file that won't fit, before writing any of it), None. Files that will be overwritten aren't counted as free space, which is why the default is Warn.")
 	syncCmd.PersistentFlags().BoolVar(&raw.putMd5, "put-md5", false, "Create an MD5 hash of each file, and save the hash as the Content-MD5 property of the destination blob or file. (By default the hash is NOT created.) Only available when uploading.")
+	syncCmd.PersistentFlags().BoolVar(&raw.storeSourceLMT, "store-source-lmt", false, "False by default. Only available when uploading to Blob storage or Azure Files. "+
+		"Records each file's last modified time in the '"+common.SourceLMTMetadataKey+"' metadata key, so that later syncs compare against the original time rather than the time of the upload.")
 	syncCmd.PersistentFlags().StringVar(&raw.md5ValidationOption, "check-md5", common.DefaultHashValidationOption.String(), "Specifies how strictly MD5 hashes should be validated when downloading. This option is only available when downloading. Available values include: NoCheck, LogOnly, FailIfDifferent, FailIfDifferentOrMissing. (default 'FailIfDifferent').")
+	syncCmd.PersistentFlags().StringVar(&raw.flushPolicy, "flush-policy", "never", "Specifies when downloaded data is flushed (fsync'd) to durable storage. This option is only available when downloading. Available values include: never (leave it to the OS, which is fastest), per-file (once each file is complete), per-chunk (after every chunk, and when each file is complete; slowest, but loses least on a power failure).")
 	syncCmd.PersistentFlags().BoolVar(&raw.s2sPreserveAccessTier, "s2s-preserve-access-tier", true, "Preserve access tier during service to service copy. "+
 		"Please refer to [Azure Blob storage: hot, cool, and archive access tiers](https://docs.microsoft.com/azure/storage/blobs/storage-blob-storage-tiers) to ensure destination storage account supports setting access tier. "+
 		"In the cases that setting access tier is not supported, please use s2sPreserveAccessTier=false to bypass copying access tier. (default true). ")
@@ -32,6 +32,9 @@ type syncDestinationComparator struct {
 
 	// storing the source objects
 	sourceIndex *objectIndexer
+
+	// optionally told about each source object that didn't need transferring, because the destination was up to date
+	onUpToDate func(storedObject)
 }
 
 func newSyncDestinationComparator(i *objectIndexer, copyScheduler, cleaner objectProcessor) *syncDestinationComparator {
@@ -55,6 +58,8 @@ func (f *syncDestinationComparator) processIfNecessary(destinationObject storedO
 			if err != nil {
 				return err
 			}
+		} else if f.onUpToDate != nil {
+			f.onUpToDate(sourceObjectInMap)
 		}
 	} else {
 		// purposefully ignore the error from destinationCleaner
@@ -74,6 +79,9 @@ type syncSourceComparator struct {
 
 	// storing the destination objects
 	destinationIndex *objectIndexer
+
+	// optionally told about each source object that didn't need transferring, because the destination was up to date
+	onUpToDate func(storedObject)
 }
 
 func newSyncSourceComparator(i *objectIndexer, copyScheduler objectProcessor) *syncSourceComparator {
@@ -96,6 +104,9 @@ func (f *syncSourceComparator) processIfNecessary(sourceObject storedObject) err
 			return f.copyTransferScheduler(sourceObject)
 		}
 		// skip if source is more recent
+		if f.onUpToDate != nil {
+			f.onUpToDate(sourceObject)
+		}
 		return nil
 	}
 
@@ -0,0 +1,174 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// deleteDestinationMoveToPrefix introduces the archive location in --delete-destination=move-to:<container/prefix>
+const deleteDestinationMoveToPrefix = "move-to:"
+
+// how often to check on a server-side copy into the archive that the service hasn't finished right away
+const archiveCopyPollInterval = time.Second
+
+// parseDeleteDestinationMoveTo returns the archive location of a --delete-destination=move-to:<container/prefix> value,
+// and whether the value has that form at all
+func parseDeleteDestinationMoveTo(value string) (location string, ok bool) {
+	if len(value) < len(deleteDestinationMoveToPrefix) || !strings.EqualFold(value[:len(deleteDestinationMoveToPrefix)], deleteDestinationMoveToPrefix) {
+		return "", false
+	}
+	return value[len(deleteDestinationMoveToPrefix):], true
+}
+
+// deletionArchive is where sync moves the extra objects that it would otherwise delete from a remote destination:
+// a prefix in another (or the same) container or share of the destination's account.
+// Each object is moved with a server-side copy, and deleted only once the copy has succeeded
+type deletionArchive struct {
+	container string
+	prefix    string
+}
+
+func newDeletionArchive(location string, destination common.ResourceString, to common.Location) (*deletionArchive, error) {
+	location = strings.Trim(location, "/")
+	container, prefix := location, ""
+	if i := strings.Index(location, "/"); i >= 0 {
+		container, prefix = location[:i], strings.Trim(location[i+1:], "/")
+	}
+	if container == "" {
+		return nil, fmt.Errorf("--delete-destination=%s needs a container or share to move the extra objects to, e.g. %sarchive/deleted",
+			deleteDestinationMoveToPrefix+location, deleteDestinationMoveToPrefix)
+	}
+
+	destURL, err := url.Parse(destination.Value)
+	if err != nil {
+		return nil, err
+	}
+	var destContainer, destPath string
+	switch to {
+	case common.ELocation.Blob():
+		parts := azblob.NewBlobURLParts(*destURL)
+		destContainer, destPath = parts.ContainerName, parts.BlobName
+	case common.ELocation.File():
+		parts := azfile.NewFileURLParts(*destURL)
+		destContainer, destPath = parts.ShareName, parts.DirectoryOrFilePath
+	default:
+		return nil, fmt.Errorf("extra objects can only be moved when the destination is a blob container or a file share, not %s", to)
+	}
+
+	// an archive inside the destination would be seen as extra objects by the next sync, and moved into itself
+	if container == destContainer && pathsOverlap(prefix, strings.Trim(destPath, "/")) {
+		return nil, fmt.Errorf("the location %s that extra objects are moved to overlaps the sync destination; "+
+			"use another container, or a prefix outside of the destination", location)
+	}
+
+	return &deletionArchive{container: container, prefix: prefix}, nil
+}
+
+// pathsOverlap tells whether one of two slash-separated paths is the other, or lies under it. The empty path is the root
+func pathsOverlap(a, b string) bool {
+	return a == "" || b == "" || a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// pathFor is where the object at the given path, relative to the root of the sync, is moved to
+func (a *deletionArchive) pathFor(relativePath string) string {
+	return path.Join(a.container, a.prefix, relativePath)
+}
+
+func (a *deletionArchive) archiveBlob(ctx context.Context, blobURL azblob.BlobURL, relativePath string, p pipeline.Pipeline) error {
+	parts := azblob.NewBlobURLParts(blobURL.URL())
+	parts.ContainerName = a.container
+	parts.BlobName = path.Join(a.prefix, relativePath)
+	parts.Snapshot = ""
+	archiveURL := azblob.NewBlobURL(parts.URL(), p)
+
+	resp, err := archiveURL.StartCopyFromURL(ctx, blobURL.URL(), nil, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't copy it to %s: %w", a.pathFor(relativePath), err)
+	}
+	return a.awaitCopy(ctx, relativePath, string(resp.CopyStatus()), func() (string, error) {
+		props, err := archiveURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+		if err != nil {
+			return "", err
+		}
+		return string(props.CopyStatus()), nil
+	})
+}
+
+func (a *deletionArchive) archiveFile(ctx context.Context, fileURL azfile.FileURL, relativePath string, p pipeline.Pipeline) error {
+	parts := azfile.NewFileURLParts(fileURL.URL())
+	parts.ShareName = a.container
+	parts.DirectoryOrFilePath = path.Join(a.prefix, relativePath)
+	parts.ShareSnapshot = ""
+	archiveURL := azfile.NewFileURL(parts.URL(), p)
+
+	// unlike blob names, file paths need their directories to exist
+	tracker := common.NewFolderCreationTracker(common.EFolderPropertiesOption.NoFolders())
+	if err := (ste.AzureFileParentDirCreator{}).CreateParentDirToRoot(ctx, archiveURL, p, tracker); err != nil {
+		return fmt.Errorf("couldn't create the directory for %s: %w", a.pathFor(relativePath), err)
+	}
+
+	resp, err := archiveURL.StartCopy(ctx, fileURL.URL(), nil)
+	if err != nil {
+		return fmt.Errorf("couldn't copy it to %s: %w", a.pathFor(relativePath), err)
+	}
+	return a.awaitCopy(ctx, relativePath, string(resp.CopyStatus()), func() (string, error) {
+		props, err := archiveURL.GetProperties(ctx)
+		if err != nil {
+			return "", err
+		}
+		return string(props.CopyStatus()), nil
+	})
+}
+
+// awaitCopy polls a server-side copy into the archive until it's no longer pending, and fails unless it succeeded.
+// Blob and file copy statuses share the same values
+func (a *deletionArchive) awaitCopy(ctx context.Context, relativePath string, status string, getStatus func() (string, error)) error {
+	for status == string(azblob.CopyStatusPending) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(archiveCopyPollInterval):
+		}
+
+		var err error
+		if status, err = getStatus(); err != nil {
+			return fmt.Errorf("couldn't check the copy to %s: %w", a.pathFor(relativePath), err)
+		}
+	}
+
+	if status != string(azblob.CopyStatusSuccess) {
+		return fmt.Errorf("the copy to %s ended with status %s, so the object was kept", a.pathFor(relativePath), status)
+	}
+	return nil
+}
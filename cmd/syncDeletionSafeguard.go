@@ -0,0 +1,106 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+const snapshotBeforeDeleteFlag = "snapshot-before-delete"
+
+// deletionSafeguard makes sure, before sync deletes anything from a remote destination, that the deletions can be undone.
+// For a file share, it takes a snapshot of the share. Containers can't be snapshotted, and deleting a blob deletes its
+// snapshots, so for blobs it relies on versioning instead, and refuses to delete anything if the destination doesn't keep versions
+type deletionSafeguard struct {
+	rootURL  url.URL
+	p        pipeline.Pipeline
+	location common.Location
+
+	once sync.Once
+	err  error
+}
+
+func newDeletionSafeguard(rootURL url.URL, p pipeline.Pipeline, location common.Location) *deletionSafeguard {
+	return &deletionSafeguard{rootURL: rootURL, p: p, location: location}
+}
+
+// prepare is called before each deletion, with the URL of the object that's about to be deleted. Only the first call
+// does anything, and if that fails, so do all the others, so that nothing is deleted
+func (s *deletionSafeguard) prepare(ctx context.Context, objectURL url.URL) error {
+	s.once.Do(func() {
+		switch s.location {
+		case common.ELocation.File():
+			s.err = s.snapshotShare(ctx)
+		case common.ELocation.Blob():
+			s.err = s.checkVersioning(ctx, objectURL)
+		default:
+			s.err = fmt.Errorf("deletions from %s can't be safeguarded", s.location)
+		}
+		if s.err != nil {
+			s.err = fmt.Errorf("nothing will be deleted, since --%s was given: %w", snapshotBeforeDeleteFlag, s.err)
+		}
+	})
+	return s.err
+}
+
+func (s *deletionSafeguard) snapshotShare(ctx context.Context) error {
+	parts := azfile.NewFileURLParts(s.rootURL)
+	parts.DirectoryOrFilePath = ""
+	resp, err := azfile.NewShareURL(parts.URL(), s.p).CreateSnapshot(ctx, azfile.Metadata{})
+	if err != nil {
+		return fmt.Errorf("couldn't snapshot the destination share: %w", err)
+	}
+
+	parts.ShareSnapshot = resp.Snapshot()
+	parts.SAS = azfile.SASQueryParameters{}
+	snapshotURL := parts.URL()
+	msg := fmt.Sprintf("Took snapshot %s of the destination share before deleting anything. To undo the deletions, copy the files back from %s",
+		resp.Snapshot(), snapshotURL.String())
+	glcm.Info(msg)
+	if azcopyScanningLogger != nil {
+		azcopyScanningLogger.Log(pipeline.LogInfo, msg)
+	}
+	return nil
+}
+
+func (s *deletionSafeguard) checkVersioning(ctx context.Context, objectURL url.URL) error {
+	props, err := azblob.NewBlobURL(objectURL, s.p).GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return fmt.Errorf("couldn't check whether the destination keeps blob versions: %w", err)
+	}
+	if props.Response().Header.Get("x-ms-version-id") == "" {
+		return errors.New("blob versioning isn't enabled on the destination's storage account, and containers can't be snapshotted, " +
+			"so the deletions couldn't be undone. Enable versioning, or sync without it")
+	}
+
+	glcm.Info("Blob versioning is enabled on the destination, so blobs that sync deletes can be restored from their previous versions.")
+	return nil
+}
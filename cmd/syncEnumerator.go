@@ -58,7 +58,7 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	// TODO: enable symlink support in a future release after evaluating the implications
 	// GetProperties is enabled by default as sync supports both upload and download.
 	// This property only supports Files and S3 at the moment, but provided that Files sync is coming soon, enable to avoid stepping on Files sync work
-	sourceTraverser, err := initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, nil, nil, cca.recursive, true, false, func(entityType common.EntityType) {
+	sourceTraverser, err := initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, nil, nil, newIncludePathChannel(cca.includePaths), cca.recursive, true, false, func(entityType common.EntityType) {
 		if entityType == common.EEntityType.File() {
 			atomic.AddUint64(&cca.atomicSourceFilesScanned, 1)
 		}
@@ -68,6 +68,12 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		return nil, err
 	}
 
+	// an include path that can't be scanned on the source must not be mistaken for one that's been emptied,
+	// or its contents would be deleted from the destination
+	if lt, ok := sourceTraverser.(*listTraverser); ok && cca.deleteDestination != common.EDeleteDestination.False() {
+		lt.failOnChildError = true
+	}
+
 	// Because we can't trust cca.credinfo, given that it's for the overall job, not the individual traversers, we get cred info again here.
 	dstCredInfo, _, err := getCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value, cca.destination.SAS, false)
 
@@ -78,7 +84,8 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	// TODO: enable symlink support in a future release after evaluating the implications
 	// GetProperties is enabled by default as sync supports both upload and download.
 	// This property only supports Files and S3 at the moment, but provided that Files sync is coming soon, enable to avoid stepping on Files sync work
-	destinationTraverser, err := initResourceTraverser(cca.destination, cca.fromTo.To(), &ctx, &dstCredInfo, nil, nil, cca.recursive, true, false, func(entityType common.EntityType) {
+	// the destination is restricted to the same paths, so that nothing outside them is considered for deletion
+	destinationTraverser, err := initResourceTraverser(cca.destination, cca.fromTo.To(), &ctx, &dstCredInfo, nil, nil, newIncludePathChannel(cca.includePaths), cca.recursive, true, false, func(entityType common.EntityType) {
 		if entityType == common.EEntityType.File() {
 			atomic.AddUint64(&cca.atomicDestinationFilesScanned, 1)
 		}
@@ -104,6 +111,15 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 
 	filters = append(filters, buildExcludeFilters(cca.excludePatterns, false)...)
 	filters = append(filters, buildExcludeFilters(cca.excludePaths, true)...)
+	filters = append(filters, buildDirectoryNameFilters(cca.includeDirectories, cca.excludeDirectories)...)
+	hiddenFilesIndex := -1
+	if cca.hiddenFiles != nil {
+		hiddenFilesIndex = len(filters)
+		filters = append(filters, cca.hiddenFiles)
+	}
+	if cca.ignoreFiles != nil {
+		filters = append(filters, cca.ignoreFiles)
+	}
 	if cca.fromTo.From() == common.ELocation.Local() {
 		excludeAttrFilters := buildAttrFilters(cca.excludeFileAttributes, cca.source.ValueLocal(), false)
 		filters = append(filters, excludeAttrFilters...)
@@ -115,6 +131,13 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		}
 	}
 
+	// the destination is filtered the same way, but the hidden files left out of the sync are only those at the source
+	destinationFilters := filters
+	if hiddenFilesIndex >= 0 {
+		destinationFilters = append([]objectFilter(nil), filters...)
+		destinationFilters[hiddenFilesIndex] = cca.hiddenFiles.uncounted()
+	}
+
 	// decide our folder transfer strategy
 	fpo, folderMessage := newFolderPropertyOption(cca.fromTo, cca.recursive, true, filters, cca.preserveSMBInfo, cca.preserveSMBPermissions.IsTruthy()) // sync always acts like stripTopDir=true
 	glcm.Info(folderMessage)
@@ -123,6 +146,16 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	}
 
 	transferScheduler := newSyncTransferProcessor(cca, NumOfFilesPerDispatchJobPart, fpo)
+	copyScheduler := transferScheduler.scheduleCopyTransfer
+	if cca.storeSourceLMT {
+		transferScheduler.metadataOverride = true
+		copyScheduler = func(object storedObject) error {
+			if object.entityType == common.EEntityType.File() {
+				object.Metadata = object.Metadata.WithSourceLastModifiedTime(object.lastModifiedTime)
+			}
+			return transferScheduler.scheduleCopyTransfer(object)
+		}
+	}
 
 	// set up the comparator so that the source/destination can be compared
 	indexer := newObjectIndexer()
@@ -144,10 +177,12 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		// when uploading, we can delete remote objects immediately, because as we traverse the remote location
 		// we ALREADY have available a complete map of everything that exists locally
 		// so as soon as we see a remote destination object we can know whether it exists in the local source
-		comparator = newSyncDestinationComparator(indexer, transferScheduler.scheduleCopyTransfer, destCleanerFunc).processIfNecessary
+		destinationComparator := newSyncDestinationComparator(indexer, copyScheduler, destCleanerFunc)
+		destinationComparator.onUpToDate = cca.countUpToDate
+		comparator = destinationComparator.processIfNecessary
 		finalize = func() error {
-			// schedule every local file that doesn't exist at the destination
-			err = indexer.traverse(transferScheduler.scheduleCopyTransfer, filters)
+			// schedule every local file that doesn't exist at the destination (they were counted as they were indexed)
+			err = indexer.traverse(copyScheduler, destinationFilters)
 			if err != nil {
 				return err
 			}
@@ -163,11 +198,13 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 			return nil
 		}
 
-		return newSyncEnumerator(sourceTraverser, destinationTraverser, indexer, filters, comparator, finalize), nil
+		return newSyncEnumerator(sourceTraverser, destinationTraverser, indexer, filters, destinationFilters, comparator, finalize), nil
 	default:
 		// in all other cases (download and S2S), the destination is scanned/indexed first
 		// then the source is scanned and filtered based on what the destination contains
-		comparator = newSyncSourceComparator(indexer, transferScheduler.scheduleCopyTransfer).processIfNecessary
+		sourceComparator := newSyncSourceComparator(indexer, copyScheduler)
+		sourceComparator.onUpToDate = cca.countUpToDate
+		comparator = sourceComparator.processIfNecessary
 
 		finalize = func() error {
 			// remove the extra files at the destination that were not present at the source
@@ -203,11 +240,17 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 			return nil
 		}
 
-		return newSyncEnumerator(destinationTraverser, sourceTraverser, indexer, filters, comparator, finalize), nil
+		return newSyncEnumerator(destinationTraverser, sourceTraverser, indexer, destinationFilters, filters, comparator, finalize), nil
 	}
 }
 
 func quitIfInSync(transferJobInitiated, anyDestinationFileDeleted bool, cca *cookedSyncCmdArgs) {
+	if !transferJobInitiated {
+		// the job finishes here, without any transfers, rather than when the progress reporting sees it's done
+		summary := common.ListJobSummaryResponse{JobID: cca.jobID, JobStatus: common.EJobStatus.Completed()}
+		recordJobHistory(newJobHistoryEntry(summary, cca.fromTo, cca.source, cca.destination, cca.jobStartTime))
+	}
+
 	if !transferJobInitiated && !anyDestinationFileDeleted {
 		cca.reportScanningProgress(glcm, 0)
 		glcm.Exit(func(format common.OutputFormat) string {
@@ -221,3 +264,19 @@ func quitIfInSync(transferJobInitiated, anyDestinationFileDeleted bool, cca *coo
 		}, common.EExitCode.Success())
 	}
 }
+
+// newIncludePathChannel feeds the include paths to a list traverser, which lists each of them on its own.
+// A fresh channel is needed for every traverser, since reading it drains it. It returns nil when there is nothing to include.
+func newIncludePathChannel(includePaths []string) chan string {
+	if len(includePaths) == 0 {
+		return nil
+	}
+
+	includePathChannel := make(chan string, len(includePaths))
+	for _, path := range includePaths {
+		includePathChannel <- path
+	}
+	close(includePathChannel)
+
+	return includePathChannel
+}
@@ -51,9 +51,14 @@ func newSyncTransferProcessor(cca *cookedSyncCmdArgs, numOfTransfersPerPart int,
 			PreserveLastModifiedTime: true, // must be true for sync so that future syncs have this information available
 			PutMd5:                   cca.putMd5,
 			MD5ValidationOption:      cca.md5ValidationOption,
+			FlushPolicy:              cca.flushPolicy,
+			FreeSpaceCheck:           cca.freeSpaceCheck,
 			BlockSizeInBytes:         cca.blockSize},
 		ForceWrite:                     common.EOverwriteOption.True(), // once we decide to transfer for a sync operation, we overwrite the destination regardless
 		ForceIfReadOnly:                cca.forceIfReadOnly,
+		SkipLargerThan:                 cca.skipLargerThan,
+		SkipSmallerThan:                cca.skipSmallerThan,
+		TransferTimeout:                cca.transferTimeout,
 		LogLevel:                       cca.logVerbosity,
 		PreserveSMBPermissions:         cca.preserveSMBPermissions,
 		PreserveSMBInfo:                cca.preserveSMBInfo,
@@ -68,8 +73,13 @@ func newSyncTransferProcessor(cca *cookedSyncCmdArgs, numOfTransfersPerPart int,
 
 	// note that the source and destination, along with the template are given to the generic processor's constructor
 	// this means that given an object with a relative path, this processor already knows how to schedule the right kind of transfers
-	return newCopyTransferProcessor(copyJobTemplate, numOfTransfersPerPart, cca.source, cca.destination,
+	processor := newCopyTransferProcessor(copyJobTemplate, numOfTransfersPerPart, cca.source, cca.destination,
 		reportFirstPart, reportFinalPart, cca.preserveAccessTier)
+	if cca.fromTo.IsDownload() {
+		processor.destinationSpace = &destinationSpaceTracker{}
+		processor.freeSpaceCheck = cca.freeSpaceCheck
+	}
+	return processor
 }
 
 // base for delete processors targeting different resources
@@ -211,7 +221,12 @@ func newSyncDeleteProcessor(cca *cookedSyncCmdArgs) (*interactiveDeleteProcessor
 		return nil, err
 	}
 
-	return newInteractiveDeleteProcessor(newRemoteResourceDeleter(rawURL, p, ctx, cca.fromTo.To()).delete,
+	deleter := newRemoteResourceDeleter(rawURL, p, ctx, cca.fromTo.To())
+	if cca.snapshotBeforeDelete {
+		deleter.safeguard = newDeletionSafeguard(*rawURL, p, cca.fromTo.To())
+	}
+	deleter.archive = cca.deletionArchive
+	return newInteractiveDeleteProcessor(deleter.delete,
 		cca.deleteDestination, cca.fromTo.To().String(), cca.destination, cca.incrementDeletionCount), nil
 }
 
@@ -220,6 +235,8 @@ type remoteResourceDeleter struct {
 	p              pipeline.Pipeline
 	ctx            context.Context
 	targetLocation common.Location
+	safeguard      *deletionSafeguard // nil unless the deletions must be made undoable first
+	archive        *deletionArchive   // nil unless extra objects are moved there rather than deleted
 }
 
 func newRemoteResourceDeleter(rawRootURL *url.URL, p pipeline.Pipeline, ctx context.Context, targetLocation common.Location) *remoteResourceDeleter {
@@ -234,18 +251,42 @@ func newRemoteResourceDeleter(rawRootURL *url.URL, p pipeline.Pipeline, ctx cont
 func (b *remoteResourceDeleter) delete(object storedObject) error {
 	if object.entityType == common.EEntityType.File() {
 		// TODO: use b.targetLocation.String() in the next line, instead of "object", if we can make it come out as string
-		glcm.Info("Deleting extra object: " + object.relativePath)
+		if b.archive != nil {
+			glcm.Info(fmt.Sprintf("Moving extra object %s to %s", object.relativePath, b.archive.pathFor(object.relativePath)))
+		} else {
+			glcm.Info("Deleting extra object: " + object.relativePath)
+		}
 		switch b.targetLocation {
 		case common.ELocation.Blob():
 			blobURLParts := azblob.NewBlobURLParts(*b.rootURL)
 			blobURLParts.BlobName = path.Join(blobURLParts.BlobName, object.relativePath)
 			blobURL := azblob.NewBlobURL(blobURLParts.URL(), b.p)
+			if b.safeguard != nil {
+				if err := b.safeguard.prepare(b.ctx, blobURL.URL()); err != nil {
+					return err
+				}
+			}
+			if b.archive != nil {
+				if err := b.archive.archiveBlob(b.ctx, blobURL, object.relativePath, b.p); err != nil {
+					return err
+				}
+			}
 			_, err := blobURL.Delete(b.ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
 			return err
 		case common.ELocation.File():
 			fileURLParts := azfile.NewFileURLParts(*b.rootURL)
 			fileURLParts.DirectoryOrFilePath = path.Join(fileURLParts.DirectoryOrFilePath, object.relativePath)
 			fileURL := azfile.NewFileURL(fileURLParts.URL(), b.p)
+			if b.safeguard != nil {
+				if err := b.safeguard.prepare(b.ctx, fileURL.URL()); err != nil {
+					return err
+				}
+			}
+			if b.archive != nil {
+				if err := b.archive.archiveFile(b.ctx, fileURL, object.relativePath, b.p); err != nil {
+					return err
+				}
+			}
 			_, err := fileURL.Delete(b.ctx)
 			return err
 		default:
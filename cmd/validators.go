@@ -21,11 +21,15 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
+	"os"
 	"regexp"
 	"strings"
 
+	"github.com/Azure/azure-storage-file-go/azfile"
+
 	"github.com/Azure/azure-storage-azcopy/common"
 )
 
@@ -35,7 +39,7 @@ func validateFromTo(src, dst string, userSpecifiedFromTo string) (common.FromTo,
 
 		// If user didn't explicitly specify FromTo, use what was inferred (if possible)
 		if inferredFromTo == common.EFromTo.Unknown() {
-			return common.EFromTo.Unknown(), fmt.Errorf("the inferred source/destination combination could not be identified, or is currently not supported")
+			return common.EFromTo.Unknown(), unsupportedPairError(src, dst)
 		}
 		return inferredFromTo, nil
 	}
@@ -47,12 +51,15 @@ func validateFromTo(src, dst string, userSpecifiedFromTo string) (common.FromTo,
 		return common.EFromTo.Unknown(), fmt.Errorf("invalid --from-to value specified: %q. "+fromToHelpText, userSpecifiedFromTo)
 
 	}
+	if err = validateUserFromTo(userFromTo, src, dst); err != nil {
+		return common.EFromTo.Unknown(), err
+	}
 
 	return userFromTo, nil
 }
 
 const fromToHelpText = "Valid values are two-word phases of the form BlobLocal, LocalBlob etc.  Use the word 'Blob' for Blob Storage, " +
-	"'Local' for the local file system, 'File' for Azure Files, and 'BlobFS' for ADLS Gen2. " +
+	"'Local' for the local file system, 'File' for Azure Files, 'BlobFS' for ADLS Gen2, and 'ManagedDisk' for a managed disk's import URL. " +
 	"If you need a combination that is not supported yet, please log an issue on the AzCopy GitHub issues list."
 
 func inferFromTo(src, dst string) common.FromTo {
@@ -75,6 +82,9 @@ func inferFromTo(src, dst string) common.FromTo {
 
 	switch {
 	case srcLocation == common.ELocation.Local() && dstLocation == common.ELocation.Blob():
+		if isManagedDiskURL(dst) {
+			return common.EFromTo.LocalManagedDisk()
+		}
 		return common.EFromTo.LocalBlob()
 	case srcLocation == common.ELocation.Blob() && dstLocation == common.ELocation.Local():
 		return common.EFromTo.BlobLocal()
@@ -90,6 +100,8 @@ func inferFromTo(src, dst string) common.FromTo {
 		return common.EFromTo.LocalBlobFS()
 	case srcLocation == common.ELocation.BlobFS() && dstLocation == common.ELocation.Local():
 		return common.EFromTo.BlobFSLocal()
+	case srcLocation == common.ELocation.BlobFS() && dstLocation == common.ELocation.BlobFS():
+		return common.EFromTo.BlobFSBlobFS()
 	case srcLocation == common.ELocation.Blob() && dstLocation == common.ELocation.Blob():
 		return common.EFromTo.BlobBlob()
 	case srcLocation == common.ELocation.File() && dstLocation == common.ELocation.Blob():
@@ -120,6 +132,13 @@ func inferFromTo(src, dst string) common.FromTo {
 	return common.EFromTo.Unknown()
 }
 
+// isNamedPipe says whether the local path is a named pipe (FIFO). Those are streamed, like stdin and stdout, since
+// their length isn't known until the other end closes them
+func isNamedPipe(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeNamedPipe != 0
+}
+
 var IPv4Regex = regexp.MustCompile(`\d+\.\d+\.\d+\.\d+`) // simple regex
 
 func inferArgumentLocation(arg string) common.Location {
@@ -157,5 +176,45 @@ func inferArgumentLocation(arg string) common.Location {
 		}
 	}
 
+	if isNamedPipe(arg) {
+		return common.ELocation.Pipe()
+	}
+
 	return common.ELocation.Local()
 }
+
+// shareSnapshotOf returns the share snapshot that an Azure Files resource names with its sharesnapshot parameter, if any
+func shareSnapshotOf(resource common.ResourceString, loc common.Location) string {
+	if loc != common.ELocation.File() || resource.ExtraQuery == "" {
+		return ""
+	}
+	query, err := url.ParseQuery(resource.ExtraQuery)
+	if err != nil {
+		return ""
+	}
+	return query.Get("sharesnapshot")
+}
+
+// validateShareSnapshots allows a share snapshot only as something to read from, since snapshots are read-only,
+// and only when the URL names a share, since a snapshot belongs to a single share
+func validateShareSnapshots(fromTo common.FromTo, source, destination common.ResourceString) error {
+	if shareSnapshotOf(destination, fromTo.To()) != "" {
+		return errors.New("a share snapshot is read-only, so it can't be the destination. Remove the sharesnapshot parameter from the destination URL")
+	}
+
+	snapshot := shareSnapshotOf(source, fromTo.From())
+	if snapshot == "" {
+		return nil
+	}
+	if fromTo.To() == common.ELocation.Unknown() || fromTo.To() == common.ELocation.None() {
+		return fmt.Errorf("the files in share snapshot %s can't be removed or changed, since snapshots are read-only", snapshot)
+	}
+	u, err := url.Parse(source.Value)
+	if err != nil {
+		return err
+	}
+	if shareName := azfile.NewFileURLParts(*u).ShareName; shareName == "" || strings.Contains(shareName, "*") {
+		return fmt.Errorf("share snapshot %s belongs to a single share, so the source URL must name that share", snapshot)
+	}
+	return nil
+}
@@ -22,6 +22,8 @@
 
 package cmd
 
+import "os"
+
 type attrFilter struct{}
 
 func (f *attrFilter) doesSupportThisOS() (msg string, supported bool) {
@@ -47,3 +49,14 @@ func buildAttrFilters(attributes []string, fullPath string, resultIfMatch bool)
 	}
 	return filters
 }
+
+// there are no hidden or system attributes on Unix systems, only dot-prefixed names
+const hiddenAttributesSupported = false
+
+func hasHiddenOrSystemAttribute(fullPath string) bool {
+	return false
+}
+
+func hiddenOrSystemAttributeOf(fileInfo os.FileInfo) (hidden bool, known bool) {
+	return false, false
+}
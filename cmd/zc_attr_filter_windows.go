@@ -22,6 +22,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"syscall"
 
@@ -113,3 +114,28 @@ func buildAttrFilters(attributes []string, fullPath string, isIncludeFilter bool
 	}
 	return filters
 }
+
+const hiddenAttributesSupported = true
+
+// hasHiddenOrSystemAttribute reports whether the file or folder at fullPath is marked as hidden or as a system file.
+// Anything whose attributes can't be read is treated as visible.
+func hasHiddenOrSystemAttribute(fullPath string) bool {
+	lpFileName, _ := syscall.UTF16PtrFromString(fullPath)
+	attributes, err := syscall.GetFileAttributes(lpFileName)
+	if err != nil {
+		return false
+	}
+
+	return attributes&(syscall.FILE_ATTRIBUTE_HIDDEN|syscall.FILE_ATTRIBUTE_SYSTEM) != 0
+}
+
+// hiddenOrSystemAttributeOf reads the attributes that were fetched along with the file info, when there are any,
+// which saves hasHiddenOrSystemAttribute from making another syscall
+func hiddenOrSystemAttributeOf(fileInfo os.FileInfo) (hidden bool, known bool) {
+	data, ok := fileInfo.Sys().(*syscall.Win32FileAttributeData)
+	if !ok || data == nil {
+		return false, false
+	}
+
+	return data.FileAttributes&(syscall.FILE_ATTRIBUTE_HIDDEN|syscall.FILE_ATTRIBUTE_SYSTEM) != 0, true
+}
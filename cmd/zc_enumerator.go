@@ -82,6 +82,11 @@ type storedObject struct {
 	Metadata      common.Metadata
 	blobVersionID string
 	blobTags      common.BlobTags
+
+	// set by the local traverser when the file info it read says whether the object has the hidden or system attribute,
+	// so that filters don't have to look the attributes up again
+	hiddenAttributeKnown bool
+	hasHiddenAttribute   bool
 }
 
 const (
@@ -89,7 +94,16 @@ const (
 )
 
 func (s *storedObject) isMoreRecentThan(storedObject2 storedObject) bool {
-	return s.lastModifiedTime.After(storedObject2.lastModifiedTime)
+	return s.sourceLastModifiedTime().After(storedObject2.sourceLastModifiedTime())
+}
+
+// sourceLastModifiedTime prefers the last modified time recorded in the metadata at upload time over the service's,
+// so that objects which have been round-tripped don't look like they've changed
+func (s *storedObject) sourceLastModifiedTime() time.Time {
+	if lmt, ok := s.Metadata.SourceLastModifiedTime(); ok {
+		return lmt
+	}
+	return s.lastModifiedTime
 }
 
 func (s *storedObject) isSingleSourceFile() bool {
@@ -293,10 +307,11 @@ type enumerationCounterFunc func(entityType common.EntityType)
 // source, location, recursive, and incrementEnumerationCounter are always required.
 // ctx, pipeline are only required for remote resources.
 // followSymlinks is only required for local resources (defaults to false)
+// danglingSymlinks is only used for local resources, to count (or fail on) followed symlinks whose targets don't exist. It may be nil.
 // errorOnDirWOutRecursive is used by copy.
 
 func initResourceTraverser(resource common.ResourceString, location common.Location, ctx *context.Context,
-	credential *common.CredentialInfo, followSymlinks *bool, listOfFilesChannel chan string, recursive, getProperties,
+	credential *common.CredentialInfo, followSymlinks *bool, danglingSymlinks *danglingSymlinkTracker, listOfFilesChannel chan string, recursive, getProperties,
 	includeDirectoryStubs bool, incrementEnumerationCounter enumerationCounterFunc, listOfVersionIds chan string,
 	s2sPreserveBlobTags bool, logLevel pipeline.LogLevel) (resourceTraverser, error) {
 	var output resourceTraverser
@@ -335,7 +350,7 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 			}
 		}
 
-		output = newListTraverser(resource, location, credential, ctx, recursive, toFollow, getProperties, listOfFilesChannel, includeDirectoryStubs, incrementEnumerationCounter, s2sPreserveBlobTags, logLevel)
+		output = newListTraverser(resource, location, credential, ctx, recursive, toFollow, danglingSymlinks, getProperties, listOfFilesChannel, includeDirectoryStubs, incrementEnumerationCounter, s2sPreserveBlobTags, logLevel)
 		return output, nil
 	}
 
@@ -362,10 +377,10 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 			}()
 
 			baseResource := resource.CloneWithValue(cleanLocalPath(basePath))
-			output = newListTraverser(baseResource, location, nil, nil, recursive, toFollow, getProperties,
+			output = newListTraverser(baseResource, location, nil, nil, recursive, toFollow, danglingSymlinks, getProperties,
 				globChan, includeDirectoryStubs, incrementEnumerationCounter, s2sPreserveBlobTags, logLevel)
 		} else {
-			output = newLocalTraverser(resource.ValueLocal(), recursive, toFollow, incrementEnumerationCounter)
+			output = newLocalTraverser(resource.ValueLocal(), recursive, toFollow, danglingSymlinks, incrementEnumerationCounter)
 		}
 	case common.ELocation.Benchmark():
 		ben, err := newBenchmarkTraverser(resource.Value, incrementEnumerationCounter)
@@ -374,7 +389,7 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 		}
 		output = ben
 
-	case common.ELocation.Blob():
+	case common.ELocation.Blob(), common.ELocation.ManagedDisk():
 		resourceURL, err := resource.FullURL()
 		if err != nil {
 			return nil, err
@@ -572,6 +587,25 @@ type preFilterProvider interface {
 	getEnumerationPreFilter() string
 }
 
+// directoryPruner is implemented by filters that can reject a whole directory tree up front,
+// so that traversers which list directory by directory never need to look inside it
+type directoryPruner interface {
+	// relativeDirPath uses the azcopy path separator
+	prunesDirectory(relativeDirPath string) bool
+}
+
+func shouldPruneDirectory(filters []objectFilter, relativeDirPath string) bool {
+	if relativeDirPath == "" {
+		return false // never prune the root
+	}
+	for _, filter := range filters {
+		if pruner, ok := filter.(directoryPruner); ok && pruner.prunesDirectory(relativeDirPath) {
+			return true
+		}
+	}
+	return false
+}
+
 // -------------------------------------- Generic Enumerators -------------------------------------- \\
 // the following enumerators must be instantiated with configurations
 // they define the work flow in the most generic terms
@@ -585,8 +619,10 @@ type syncEnumerator struct {
 	// the results from the primary traverser would be stored here
 	objectIndexer *objectIndexer
 
-	// general filters apply to both the primary and secondary traverser
-	filters []objectFilter
+	// general filters apply to both the primary and secondary traverser. They are given separately for each,
+	// so that filters which count what they leave out only count it on the source side
+	primaryFilters   []objectFilter
+	secondaryFilters []objectFilter
 
 	// the processor that apply only to the secondary traverser
 	// it processes objects as scanning happens
@@ -598,12 +634,13 @@ type syncEnumerator struct {
 }
 
 func newSyncEnumerator(primaryTraverser, secondaryTraverser resourceTraverser, indexer *objectIndexer,
-	filters []objectFilter, comparator objectProcessor, finalize func() error) *syncEnumerator {
+	primaryFilters, secondaryFilters []objectFilter, comparator objectProcessor, finalize func() error) *syncEnumerator {
 	return &syncEnumerator{
 		primaryTraverser:   primaryTraverser,
 		secondaryTraverser: secondaryTraverser,
 		objectIndexer:      indexer,
-		filters:            filters,
+		primaryFilters:     primaryFilters,
+		secondaryFilters:   secondaryFilters,
 		objectComparator:   comparator,
 		finalize:           finalize,
 	}
@@ -611,7 +648,7 @@ func newSyncEnumerator(primaryTraverser, secondaryTraverser resourceTraverser, i
 
 func (e *syncEnumerator) enumerate() (err error) {
 	// enumerate the primary resource and build lookup map
-	err = e.primaryTraverser.traverse(noPreProccessor, e.objectIndexer.store, e.filters)
+	err = e.primaryTraverser.traverse(noPreProccessor, e.objectIndexer.store, e.primaryFilters)
 	if err != nil {
 		return
 	}
@@ -620,7 +657,7 @@ func (e *syncEnumerator) enumerate() (err error) {
 	// they will be passed to the object comparator
 	// which can process given objects based on what's already indexed
 	// note: transferring can start while scanning is ongoing
-	err = e.secondaryTraverser.traverse(noPreProccessor, e.objectComparator, e.filters)
+	err = e.secondaryTraverser.traverse(noPreProccessor, e.objectComparator, e.secondaryFilters)
 	if err != nil {
 		return
 	}
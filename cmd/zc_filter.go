@@ -24,6 +24,8 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -193,6 +195,189 @@ func buildIncludeFilters(patterns []string) []objectFilter {
 	return []objectFilter{&includeFilter{patterns: validPatterns}}
 }
 
+// directoryNameFilter matches directory names at any depth of the relative path,
+// e.g. node_modules matches both node_modules/a.js and src/node_modules/b/c.js, but not node_modules.txt.
+// Patterns are matched against one path segment at a time, so they may contain wildcards but never separators.
+// For a file, only the directories above it are considered. For a folder, its own name is considered too.
+type directoryNameFilter struct {
+	patterns  []string
+	isInclude bool
+}
+
+func (f *directoryNameFilter) doesSupportThisOS() (msg string, supported bool) {
+	return "", true
+}
+
+func (f *directoryNameFilter) appliesOnlyToFiles() bool {
+	return false // it must also stop the folders themselves from being transferred
+}
+
+func (f *directoryNameFilter) doesPass(storedObject storedObject) bool {
+	matched := f.matchesAnyDirectory(storedObject)
+
+	if f.isInclude {
+		return matched
+	}
+
+	return !matched
+}
+
+// prunesDirectory says whether nothing in the directory, nor the directory itself, can pass.
+// Only exclusions can prune: an include filter may still match a directory further down.
+func (f *directoryNameFilter) prunesDirectory(relativeDirPath string) bool {
+	if f.isInclude {
+		return false
+	}
+
+	return f.matchesAnyDirectory(storedObject{relativePath: relativeDirPath, entityType: common.EEntityType.Folder()})
+}
+
+func (f *directoryNameFilter) matchesAnyDirectory(storedObject storedObject) bool {
+	if storedObject.relativePath == "" {
+		return false
+	}
+
+	segments := strings.Split(storedObject.relativePath, common.DeterminePathSeparator(storedObject.relativePath))
+	if storedObject.entityType == common.EEntityType.File() {
+		segments = segments[:len(segments)-1]
+	}
+
+	for _, segment := range segments {
+		for _, pattern := range f.patterns {
+			// an invalid pattern simply doesn't match anything
+			if matched, err := path.Match(pattern, segment); err == nil && matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hiddenFileFilter excludes hidden files and folders, along with everything inside hidden folders.
+// A name that starts with a dot is hidden on every OS. On Windows, anything with the hidden or system attribute is too,
+// but attributes can only be looked up when the objects being filtered are on the local disk, under rootPath.
+type hiddenFileFilter struct {
+	rootPath string
+
+	// the attributes of each folder are looked up once, and shared by everything inside it
+	hiddenDirsLock sync.Mutex
+	hiddenDirs     map[string]bool
+
+	skippedFiles uint32 // accessed atomically
+	isUncounted  bool   // the filter is for the destination of a sync, so what it leaves out isn't counted
+}
+
+func newHiddenFileFilter(rootPath string) *hiddenFileFilter {
+	if strings.Contains(rootPath, "*") {
+		rootPath = getPathBeforeFirstWildcard(rootPath)
+	}
+
+	return &hiddenFileFilter{rootPath: rootPath, hiddenDirs: make(map[string]bool)}
+}
+
+// uncounted returns a filter that leaves out the same objects, without counting them.
+// Sync filters its destination too, and hidden files there aren't files that the sync left out
+func (f *hiddenFileFilter) uncounted() *hiddenFileFilter {
+	return &hiddenFileFilter{rootPath: f.rootPath, hiddenDirs: make(map[string]bool), isUncounted: true}
+}
+
+func (f *hiddenFileFilter) doesSupportThisOS() (msg string, supported bool) {
+	return "", true
+}
+
+func (f *hiddenFileFilter) appliesOnlyToFiles() bool {
+	return false // hidden folders, such as .git, are left out entirely
+}
+
+func (f *hiddenFileFilter) doesPass(storedObject storedObject) bool {
+	// the root was named explicitly by the user, so it's never considered hidden
+	if storedObject.relativePath == "" || !f.isHidden(storedObject) {
+		return true
+	}
+
+	if storedObject.entityType == common.EEntityType.File() && !f.isUncounted {
+		atomic.AddUint32(&f.skippedFiles, 1)
+	}
+	return false
+}
+
+// Count returns the number of files that have been left out for being hidden. It is safe to call on a nil filter.
+func (f *hiddenFileFilter) Count() uint32 {
+	if f == nil {
+		return 0
+	}
+	return atomic.LoadUint32(&f.skippedFiles)
+}
+
+func (f *hiddenFileFilter) isHidden(storedObject storedObject) bool {
+	relativePath := storedObject.relativePath
+	separator := common.DeterminePathSeparator(relativePath)
+	segments := strings.Split(relativePath, separator)
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+
+	if f.rootPath == "" || !hiddenAttributesSupported {
+		return false
+	}
+
+	for i := 1; i < len(segments); i++ {
+		if f.isHiddenDir(strings.Join(segments[:i], separator)) {
+			return true
+		}
+	}
+
+	// the local traverser has usually read the attributes already
+	if storedObject.hiddenAttributeKnown {
+		if storedObject.entityType == common.EEntityType.Folder() {
+			f.rememberHiddenDir(relativePath, storedObject.hasHiddenAttribute)
+		}
+		return storedObject.hasHiddenAttribute
+	}
+
+	return hasHiddenOrSystemAttribute(common.GenerateFullPath(f.rootPath, relativePath))
+}
+
+// rememberHiddenDir saves isHiddenDir a syscall for folders whose attributes the traverser has already read
+func (f *hiddenFileFilter) rememberHiddenDir(relativeDirPath string, hidden bool) {
+	f.hiddenDirsLock.Lock()
+	defer f.hiddenDirsLock.Unlock()
+
+	f.hiddenDirs[relativeDirPath] = hidden
+}
+
+func (f *hiddenFileFilter) isHiddenDir(relativeDirPath string) bool {
+	f.hiddenDirsLock.Lock()
+	defer f.hiddenDirsLock.Unlock()
+
+	hidden, ok := f.hiddenDirs[relativeDirPath]
+	if !ok {
+		hidden = hasHiddenOrSystemAttribute(common.GenerateFullPath(f.rootPath, relativeDirPath))
+		f.hiddenDirs[relativeDirPath] = hidden
+	}
+
+	return hidden
+}
+
+// buildDirectoryNameFilters returns the filters for --include-directory and --exclude-directory.
+// As with include patterns, the included names are ORed together, so they share a single filter.
+func buildDirectoryNameFilters(includeDirectories, excludeDirectories []string) []objectFilter {
+	filters := make([]objectFilter, 0)
+
+	if len(includeDirectories) != 0 {
+		filters = append(filters, &directoryNameFilter{patterns: includeDirectories, isInclude: true})
+	}
+
+	if len(excludeDirectories) != 0 {
+		filters = append(filters, &directoryNameFilter{patterns: excludeDirectories})
+	}
+
+	return filters
+}
+
 type filterSet []objectFilter
 
 // GetEnumerationPreFilter returns a prefix that is common to all the include filters, or "" if no such prefix can
@@ -0,0 +1,255 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+const defaultIgnoreFileName = ".azcopyignore"
+
+// ignoreRule is one line of an ignore file, which follows the gitignore syntax:
+//   - blank lines and lines starting with # are skipped, and \# or \! escape a leading # or !
+//   - a leading ! re-includes what an earlier rule excluded
+//   - a trailing / only matches directories
+//   - a pattern that contains any other / is relative to the directory holding the ignore file,
+//     otherwise it matches a name at any depth below it
+//   - * and ? match within a name, and ** matches any number of directories
+type ignoreRule struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+func parseIgnoreRules(reader io.Reader) ([]ignoreRule, error) {
+	rules := make([]ignoreRule, 0)
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		rule.segments = strings.Split(line, "/")
+		if !anchored {
+			rule.segments = append([]string{"**"}, rule.segments...)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, scanner.Err()
+}
+
+func (r ignoreRule) matches(pathSegments []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	return matchIgnoreSegments(r.segments, pathSegments)
+}
+
+func matchIgnoreSegments(pattern []string, pathSegments []string) bool {
+	if len(pattern) == 0 {
+		return len(pathSegments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(pathSegments); i++ {
+			if matchIgnoreSegments(pattern[1:], pathSegments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(pathSegments) == 0 {
+		return false
+	}
+
+	// an invalid pattern simply doesn't match anything
+	if matched, err := path.Match(pattern[0], pathSegments[0]); err != nil || !matched {
+		return false
+	}
+
+	return matchIgnoreSegments(pattern[1:], pathSegments[1:])
+}
+
+// ignoreFileFilter excludes whatever the ignore file at the root of a local source says to.
+// When perDirectory is set, an ignore file in any directory below the root applies to that directory's contents as well,
+// with the rules of deeper files taking precedence, just like nested .gitignore files.
+// As in git, nothing inside an excluded directory can be re-included.
+type ignoreFileFilter struct {
+	rootPath     string
+	fileName     string
+	perDirectory bool
+
+	// rules found so far, keyed by the relative path of the directory holding them. Directories without a file map to nil.
+	rulesLock sync.Mutex
+	rules     map[string][]ignoreRule
+}
+
+// newIgnoreFileFilter returns nil if there are no ignore files to honor.
+func newIgnoreFileFilter(rootPath, fileName string, perDirectory bool) (*ignoreFileFilter, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+
+	if strings.Contains(rootPath, "*") {
+		rootPath = getPathBeforeFirstWildcard(rootPath)
+	}
+
+	// a single file has nothing below it to ignore
+	if info, err := os.Stat(rootPath); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+
+	f := &ignoreFileFilter{rootPath: rootPath, fileName: fileName, perDirectory: perDirectory, rules: make(map[string][]ignoreRule)}
+
+	rootRules, err := f.loadRules("")
+	if err != nil {
+		return nil, err
+	}
+
+	if rootRules == nil && !perDirectory {
+		return nil, nil
+	}
+
+	return f, nil
+}
+
+func (f *ignoreFileFilter) doesSupportThisOS() (msg string, supported bool) {
+	return "", true
+}
+
+func (f *ignoreFileFilter) appliesOnlyToFiles() bool {
+	return false // ignored directories are left out along with their contents
+}
+
+func (f *ignoreFileFilter) doesPass(storedObject storedObject) bool {
+	// the root was named explicitly by the user, so it's never ignored
+	if storedObject.relativePath == "" {
+		return true
+	}
+
+	segments := strings.Split(storedObject.relativePath, common.DeterminePathSeparator(storedObject.relativePath))
+
+	// the ignore files themselves are never transferred, whatever the other filters (e.g. --include-hidden) say
+	if f.isIgnoreFile(segments, storedObject.entityType) {
+		return false
+	}
+
+	for i := 1; i < len(segments); i++ {
+		if f.isIgnored(segments[:i], true) {
+			return false
+		}
+	}
+
+	return !f.isIgnored(segments, storedObject.entityType == common.EEntityType.Folder())
+}
+
+func (f *ignoreFileFilter) isIgnoreFile(segments []string, entityType common.EntityType) bool {
+	if entityType != common.EEntityType.File() || segments[len(segments)-1] != f.fileName {
+		return false
+	}
+
+	return f.perDirectory || len(segments) == 1
+}
+
+func (f *ignoreFileFilter) isIgnored(segments []string, isDir bool) bool {
+	ignored := false
+
+	// the ignore files that apply are those in the root, and (optionally) in each directory above the path
+	depths := 1
+	if f.perDirectory {
+		depths = len(segments)
+	}
+
+	for depth := 0; depth < depths; depth++ {
+		rules, err := f.loadRules(strings.Join(segments[:depth], common.AZCOPY_PATH_SEPARATOR_STRING))
+		if err != nil {
+			glcm.Info(fmt.Sprintf("Ignoring the %s file in %s due to error: %s", f.fileName, strings.Join(segments[:depth], "/"), err))
+			continue
+		}
+
+		for _, rule := range rules {
+			if rule.matches(segments[depth:], isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	return ignored
+}
+
+func (f *ignoreFileFilter) loadRules(relativeDirPath string) ([]ignoreRule, error) {
+	f.rulesLock.Lock()
+	defer f.rulesLock.Unlock()
+
+	if rules, ok := f.rules[relativeDirPath]; ok {
+		return rules, nil
+	}
+
+	var rules []ignoreRule
+	file, err := os.Open(common.GenerateFullPath(common.GenerateFullPath(f.rootPath, relativeDirPath), f.fileName))
+	if err == nil {
+		defer file.Close()
+		rules, err = parseIgnoreRules(file)
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+
+	if err != nil {
+		rules = nil
+	}
+
+	// remember missing and unreadable files too, so that each directory is only looked at (and complained about) once
+	f.rules[relativeDirPath] = rules
+	return rules, err
+}
@@ -15,7 +15,7 @@ func initPipeline(ctx context.Context, location common.Location, credential comm
 		common.ELocation.Benchmark():
 		// Gracefully return
 		return nil, nil
-	case common.ELocation.Blob():
+	case common.ELocation.Blob(), common.ELocation.ManagedDisk():
 		p, err = createBlobPipeline(ctx, credential, logLevel)
 	case common.ELocation.File():
 		p, err = createFilePipeline(ctx, credential, logLevel)
@@ -43,6 +43,13 @@ type copyTransferProcessor struct {
 
 	preserveAccessTier     bool
 	folderPropertiesOption common.FolderPropertyOption
+
+	// set when the objects' metadata has been computed per file (e.g. by --store-source-lmt) and replaces the job's metadata
+	metadataOverride bool
+
+	// set for downloads, to check each part against the destination's free space before it's dispatched
+	destinationSpace *destinationSpaceTracker
+	freeSpaceCheck   common.FreeSpaceCheckOption
 }
 
 func newCopyTransferProcessor(copyJobTemplate *common.CopyJobPartOrderRequest, numOfTransfersPerPart int,
@@ -78,8 +85,12 @@ func (s *copyTransferProcessor) scheduleCopyTransfer(storedObject storedObject)
 	if !shouldSendToSte {
 		return nil // skip this one
 	}
+	copyTransfer.MetadataOverride = s.metadataOverride && copyTransfer.EntityType == common.EEntityType.File()
 
 	if len(s.copyJobTemplate.Transfers) == s.numOfTransfersPerPart {
+		if err = s.checkDestinationSpace(); err != nil {
+			return err
+		}
 		resp := s.sendPartToSte()
 
 		// TODO: If we ever do launch errors outside of the final "no transfers" error, make them output nicer things here.
@@ -103,6 +114,10 @@ var NothingScheduledError = errors.New("no transfers were scheduled because no f
 var FinalPartCreatedMessage = "Final job part has been created"
 
 func (s *copyTransferProcessor) dispatchFinalPart() (copyJobInitiated bool, err error) {
+	if err = s.checkDestinationSpace(); err != nil {
+		return false, err
+	}
+
 	var resp common.CopyJobPartOrderResponse
 	s.copyJobTemplate.IsFinalPart = true
 	resp = s.sendPartToSte()
@@ -126,6 +141,14 @@ func (s *copyTransferProcessor) dispatchFinalPart() (copyJobInitiated bool, err
 	return true, nil
 }
 
+// checkDestinationSpace counts the part that's about to be dispatched towards the destination's free space
+func (s *copyTransferProcessor) checkDestinationSpace() error {
+	if s.destinationSpace == nil {
+		return nil
+	}
+	return s.destinationSpace.addPart(s.copyJobTemplate.Transfers, s.freeSpaceCheck, s.destination.ValueLocal())
+}
+
 // only test the response on the final dispatch to help diagnose root cause of test failures from 0 transfers
 func (s *copyTransferProcessor) sendPartToSte() common.CopyJobPartOrderResponse {
 	var resp common.CopyJobPartOrderResponse
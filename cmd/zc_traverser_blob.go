@@ -26,6 +26,7 @@ import (
 	"github.com/Azure/azure-storage-azcopy/common/parallel"
 	"net/url"
 	"strings"
+	"unicode"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -45,6 +46,9 @@ type blobTraverser struct {
 	// cx should have the option to disable this optimization in the name of saving costs
 	parallelListing bool
 
+	// the listing strategy asked for by the user. Auto is resolved when the listing starts, see resolveListingMethod
+	listingStrategy common.BlobListingStrategy
+
 	// whether to include blobs that have metadata 'hdi_isfolder = true'
 	includeDirectoryStubs bool
 
@@ -199,11 +203,115 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 	// as a performance optimization, get an extra prefix to do pre-filtering. It's typically the start portion of a blob name.
 	extraSearchPrefix := filterSet(filters).GetEnumerationPreFilter(t.recursive)
 
-	if t.parallelListing {
+	method, err := t.resolveListingMethod(containerURL, searchPrefix+extraSearchPrefix)
+	if err != nil {
+		return err
+	}
+
+	if azcopyScanningLogger != nil {
+		azcopyScanningLogger.Log(pipeline.LogDebug, fmt.Sprintf("Listing container %s with the %s listing API.", blobUrlParts.ContainerName, method))
+	}
+
+	switch method {
+	case blobListingHierarchical:
 		return t.parallelList(containerURL, blobUrlParts.ContainerName, searchPrefix, extraSearchPrefix, preprocessor, processor, filters)
+	case blobListingPartitioned:
+		return t.partitionedList(containerURL, blobUrlParts.ContainerName, searchPrefix, extraSearchPrefix, preprocessor, processor, filters)
+	default:
+		return t.serialList(containerURL, blobUrlParts.ContainerName, searchPrefix, extraSearchPrefix, preprocessor, processor, filters)
+	}
+}
+
+// blobListingMethod is how a container actually gets listed, once the listing strategy has been resolved
+type blobListingMethod int
+
+const (
+	blobListingSerial       blobListingMethod = iota // one flat pager
+	blobListingHierarchical                          // one hierarchical pager per virtual directory, run in parallel
+	blobListingPartitioned                           // several flat pagers, run in parallel, one per partition of the name space
+)
+
+func (m blobListingMethod) String() string {
+	switch m {
+	case blobListingHierarchical:
+		return "hierarchical"
+	case blobListingPartitioned:
+		return "partitioned flat"
+	default:
+		return "flat"
+	}
+}
+
+// if at least this fraction of the entries on the first page of a hierarchical listing are virtual directories,
+// the directories are probably small, and listing them one call at a time would cost more than it parallelizes
+const denseDirectoryHitRate = 0.5
+
+// resolveListingMethod turns the listing strategy into a listing method.
+// For auto, the first page of a hierarchical listing at the root is looked at, and the method is based on how many of
+// its entries the delimiter hit (i.e. how many are virtual directories rather than blobs). That costs one extra call.
+func (t *blobTraverser) resolveListingMethod(containerURL azblob.ContainerURL, prefix string) (blobListingMethod, error) {
+	// a flat listing can still be spread over several pagers, unless the user has opted out of the
+	// (few) hierarchical listing calls needed to find the split points
+	flat := blobListingSerial
+	if t.recursive && t.parallelListing && enumerationParallelism > 1 {
+		flat = blobListingPartitioned
+	}
+
+	switch t.listingStrategy {
+	case common.EBlobListingStrategy.Flat():
+		return flat, nil
+	case common.EBlobListingStrategy.Hierarchical():
+		return blobListingHierarchical, nil
+	}
+
+	if !t.parallelListing {
+		return blobListingSerial, nil
+	}
+
+	// a single level is always listed hierarchically, since that's the only way to list just that level
+	if !t.recursive {
+		return blobListingHierarchical, nil
+	}
+
+	// there's nothing to be gained from listing directories separately, if they can't be listed concurrently
+	if enumerationParallelism <= 1 {
+		return blobListingSerial, nil
+	}
+
+	lResp, err := containerURL.ListBlobsHierarchySegment(t.ctx, azblob.Marker{}, "/", azblob.ListBlobsSegmentOptions{Prefix: prefix})
+	if err != nil {
+		return blobListingSerial, fmt.Errorf("cannot list files due to reason %s", err)
+	}
+
+	return chooseAutoBlobListing(len(lResp.Segment.BlobPrefixes), len(lResp.Segment.BlobItems), flat), nil
+}
+
+// chooseAutoBlobListing picks the listing method for auto, from what's on the first page of a hierarchical listing.
+// With no virtual directories, or a lot of them compared to blobs, the flat method is used.
+// Otherwise the tree has fewer, bigger directories, and listing each with its own pager is worth it.
+func chooseAutoBlobListing(dirCount, blobCount int, flat blobListingMethod) blobListingMethod {
+	if dirCount == 0 {
+		return flat
+	}
+
+	if float64(dirCount)/float64(dirCount+blobCount) >= denseDirectoryHitRate {
+		return flat
 	}
 
-	return t.serialList(containerURL, blobUrlParts.ContainerName, searchPrefix, extraSearchPrefix, preprocessor, processor, filters)
+	return blobListingHierarchical
+}
+
+// prunesVirtualDir says whether the filters reject everything under a virtual directory, so there's no point listing it
+func (t *blobTraverser) prunesVirtualDir(virtualDirName string, searchPrefix string, filters []objectFilter) bool {
+	relativePath := strings.TrimSuffix(strings.TrimPrefix(virtualDirName, searchPrefix), common.AZCOPY_PATH_SEPARATOR_STRING)
+	if !shouldPruneDirectory(filters, relativePath) {
+		return false
+	}
+
+	if azcopyScanningLogger != nil {
+		azcopyScanningLogger.Log(pipeline.LogDebug, fmt.Sprintf("Not listing %s, since the filters exclude everything in it.", virtualDirName))
+	}
+	return true
 }
 
 func (t *blobTraverser) parallelList(containerURL azblob.ContainerURL, containerName string, searchPrefix string,
@@ -223,6 +331,9 @@ func (t *blobTraverser) parallelList(containerURL azblob.ContainerURL, container
 			// queue up the sub virtual directories if recursive is true
 			if t.recursive {
 				for _, virtualDir := range lResp.Segment.BlobPrefixes {
+					if t.prunesVirtualDir(virtualDir.Name, searchPrefix, filters) {
+						continue
+					}
 					enqueueDir(virtualDir.Name)
 				}
 			}
@@ -299,6 +410,212 @@ func (t *blobTraverser) parallelList(containerURL azblob.ContainerURL, container
 	return nil
 }
 
+// blobListingPartition is a unit of work for partitionedList.
+// A partition that needs splitting is listed one level deep to discover its virtual directories,
+// while any other partition is listed flat, in its entirety.
+type blobListingPartition struct {
+	prefix     string
+	needsSplit bool
+}
+
+// the size of the first page of a partition that needs splitting. If it's full of blobs, with no virtual directory,
+// split points are learned from the blob names on it, see learnSplitDelimiter
+var blobSplitProbePageSize int32 = 1000
+
+// learnSplitDelimiter picks a character to use as the delimiter of a hierarchical listing, from a sample of the names
+// (after the prefix) that it will split. That lets flat name spaces, e.g. of dates or IDs, be partitioned as if they
+// had virtual directories. Only punctuation is considered. It must be in at least half the names, and split them into
+// groups of at least two names on average, since a partition per blob would cost more calls than it saves.
+// Of those, the coarsest split wins, since the sample is sorted and so sees fewer groups than the whole listing will.
+// An empty string means nothing suitable was found.
+func learnSplitDelimiter(names []string, prefix string) string {
+	hits := make(map[rune]int)
+	groups := make(map[rune]map[string]struct{})
+	for _, name := range names {
+		suffix := strings.TrimPrefix(name, prefix)
+		seen := make(map[rune]bool)
+		for i, r := range suffix {
+			if r == '/' || unicode.IsLetter(r) || unicode.IsDigit(r) || seen[r] {
+				continue
+			}
+			seen[r] = true // the listing groups names only at the first occurrence of the delimiter
+
+			hits[r]++
+			if groups[r] == nil {
+				groups[r] = make(map[string]struct{})
+			}
+			groups[r][suffix[:i]] = struct{}{}
+		}
+	}
+
+	best, bestCount := rune(0), 0
+	for r, g := range groups {
+		if hits[r]*2 < len(names) || len(g)*2 > hits[r] {
+			continue
+		}
+		if best == 0 || len(g) < bestCount || (len(g) == bestCount && r < best) {
+			best, bestCount = r, len(g)
+		}
+	}
+
+	if best == 0 {
+		return ""
+	}
+	return string(best)
+}
+
+func (t *blobTraverser) partitionedList(containerURL azblob.ContainerURL, containerName string, searchPrefix string,
+	extraSearchPrefix string, preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	details := azblob.BlobListingDetails{Metadata: true, Tags: t.s2sPreserveSourceTags}
+
+	enqueueBlob := func(blobInfo azblob.BlobItemInternal, enqueueOutput func(parallel.DirectoryEntry, error)) {
+		// if the blob represents a hdi folder, then skip it
+		if t.doesBlobRepresentAFolder(blobInfo.Metadata) {
+			return
+		}
+
+		storedObject := t.createStoredObjectForBlob(preprocessor, blobInfo, strings.TrimPrefix(blobInfo.Name, searchPrefix), containerName)
+
+		if t.s2sPreserveSourceTags && blobInfo.BlobTags != nil {
+			blobTagsMap := common.BlobTags{}
+			for _, blobTag := range blobInfo.BlobTags.BlobTagSet {
+				blobTagsMap[url.QueryEscape(blobTag.Key)] = url.QueryEscape(blobTag.Value)
+			}
+			storedObject.blobTags = blobTagsMap
+		}
+
+		enqueueOutput(storedObject, nil)
+	}
+
+	// list the partition hierarchically with the given delimiter. Blobs without it are output right away, and every
+	// group of blobs with it becomes a partition of its own. Between them, they cover the whole partition.
+	splitOnDelimiter := func(prefix, delimiter string, enqueueDir func(parallel.Directory), enqueueOutput func(parallel.DirectoryEntry, error)) error {
+		groups := make([]string, 0)
+		for marker := (azblob.Marker{}); marker.NotDone(); {
+			lResp, err := containerURL.ListBlobsHierarchySegment(t.ctx, marker, delimiter, azblob.ListBlobsSegmentOptions{Prefix: prefix, Details: details})
+			if err != nil {
+				return fmt.Errorf("cannot list files due to reason %s", err)
+			}
+
+			for _, group := range lResp.Segment.BlobPrefixes {
+				groups = append(groups, group.Name)
+			}
+
+			for _, blobInfo := range lResp.Segment.BlobItems {
+				enqueueBlob(blobInfo, enqueueOutput)
+			}
+
+			marker = lResp.NextMarker
+		}
+
+		// as with virtual directories, a single group has to be split further before it can be listed in parallel
+		needsSplit := len(groups) == 1
+		for _, group := range groups {
+			if azcopyScanningLogger != nil {
+				azcopyScanningLogger.Log(pipeline.LogDebug, fmt.Sprintf("Partitioning listing at %s, a split point learned from the blob names.", group))
+			}
+			enqueueDir(blobListingPartition{prefix: group, needsSplit: needsSplit})
+		}
+		return nil
+	}
+
+	// This func must be thread safe/goroutine safe
+	enumerateOnePartition := func(dir parallel.Directory, enqueueDir func(parallel.Directory), enqueueOutput func(parallel.DirectoryEntry, error)) error {
+		partition := dir.(blobListingPartition)
+
+		if !partition.needsSplit {
+			for marker := (azblob.Marker{}); marker.NotDone(); {
+				listBlob, err := containerURL.ListBlobsFlatSegment(t.ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: partition.prefix, Details: details})
+				if err != nil {
+					return fmt.Errorf("cannot list blobs. Failed with error %s", err.Error())
+				}
+
+				for _, blobInfo := range listBlob.Segment.BlobItems {
+					enqueueBlob(blobInfo, enqueueOutput)
+				}
+
+				marker = listBlob.NextMarker
+			}
+			return nil
+		}
+
+		// the blobs directly under the prefix are output right away, and every virtual directory becomes a partition of its own
+		subPrefixes := make([]string, 0)
+		for marker, firstPage := (azblob.Marker{}), true; marker.NotDone(); firstPage = false {
+			options := azblob.ListBlobsSegmentOptions{Prefix: partition.prefix, Details: details}
+			if firstPage {
+				options.MaxResults = blobSplitProbePageSize
+			}
+			lResp, err := containerURL.ListBlobsHierarchySegment(t.ctx, marker, "/", options)
+			if err != nil {
+				return fmt.Errorf("cannot list files due to reason %s", err)
+			}
+
+			// lots of blobs, but no virtual directory to split on, so try to learn where else to split from their names
+			if firstPage && len(lResp.Segment.BlobPrefixes) == 0 && lResp.NextMarker.NotDone() {
+				names := make([]string, len(lResp.Segment.BlobItems))
+				for i, blobInfo := range lResp.Segment.BlobItems {
+					names[i] = blobInfo.Name
+				}
+
+				if delimiter := learnSplitDelimiter(names, partition.prefix); delimiter != "" {
+					return splitOnDelimiter(partition.prefix, delimiter, enqueueDir, enqueueOutput)
+				}
+			}
+
+			for _, virtualDir := range lResp.Segment.BlobPrefixes {
+				if t.prunesVirtualDir(virtualDir.Name, searchPrefix, filters) {
+					continue
+				}
+				subPrefixes = append(subPrefixes, virtualDir.Name)
+			}
+
+			for _, blobInfo := range lResp.Segment.BlobItems {
+				enqueueBlob(blobInfo, enqueueOutput)
+			}
+
+			marker = lResp.NextMarker
+		}
+
+		// a single virtual directory can't be listed in parallel with anything, so keep splitting until it fans out
+		needsSplit := len(subPrefixes) == 1
+		for _, subPrefix := range subPrefixes {
+			if azcopyScanningLogger != nil {
+				azcopyScanningLogger.Log(pipeline.LogDebug, fmt.Sprintf("Partitioning listing at %s.", subPrefix))
+			}
+			enqueueDir(blobListingPartition{prefix: subPrefix, needsSplit: needsSplit})
+		}
+
+		return nil
+	}
+
+	// initiate partitioned scanning, learning the split points from the root path
+	workerContext, cancelWorkers := context.WithCancel(t.ctx)
+	cCrawled := parallel.Crawl(workerContext, blobListingPartition{prefix: searchPrefix + extraSearchPrefix, needsSplit: true}, enumerateOnePartition, enumerationParallelism)
+
+	for x := range cCrawled {
+		item, workerError := x.Item()
+		if workerError != nil {
+			cancelWorkers()
+			return workerError
+		}
+
+		if t.incrementEnumerationCounter != nil {
+			t.incrementEnumerationCounter(common.EEntityType.File())
+		}
+
+		object := item.(storedObject)
+		processErr := processIfPassedFilters(filters, object, processor)
+		_, processErr = getProcessingError(processErr)
+		if processErr != nil {
+			cancelWorkers()
+			return processErr
+		}
+	}
+
+	return nil
+}
+
 func (t *blobTraverser) createStoredObjectForBlob(preprocessor objectMorpher, blobInfo azblob.BlobItemInternal, relativePath string, containerName string) storedObject {
 	adapter := blobPropertiesAdapter{blobInfo.Properties}
 	return newStoredObject(
@@ -378,7 +695,8 @@ func (t *blobTraverser) serialList(containerURL azblob.ContainerURL, containerNa
 
 func newBlobTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, recursive, includeDirectoryStubs bool, incrementEnumerationCounter enumerationCounterFunc, s2sPreserveSourceTags bool) (t *blobTraverser) {
 	t = &blobTraverser{rawURL: rawURL, p: p, ctx: ctx, recursive: recursive, includeDirectoryStubs: includeDirectoryStubs,
-		incrementEnumerationCounter: incrementEnumerationCounter, parallelListing: true, s2sPreserveSourceTags: s2sPreserveSourceTags}
+		incrementEnumerationCounter: incrementEnumerationCounter, parallelListing: true, listingStrategy: blobListingStrategy,
+		s2sPreserveSourceTags: s2sPreserveSourceTags}
 
 	if strings.ToLower(glcm.GetEnvironmentVariable(common.EEnvironmentVariable.DisableHierarchicalScanning())) == "true" {
 		// TODO log to frontend log that parallel listing was disabled, once the frontend log PR is merged
@@ -8,6 +8,8 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/minio/minio-go"
+
 	"github.com/Azure/azure-storage-azcopy/common"
 )
 
@@ -19,6 +21,7 @@ type gcpTraverser struct {
 
 	gcpURLParts common.GCPURLParts
 	gcpClient   *gcpUtils.Client
+	hmacClient  *minio.Client // set instead of gcpClient, when reading with an HMAC key through the XML API
 
 	incrementEnumerationCounter enumerationCounterFunc
 }
@@ -29,6 +32,10 @@ func (t *gcpTraverser) isDirectory(isSource bool) bool {
 	if !isSource {
 		return isDirDirect
 	}
+	if t.hmacClient != nil {
+		_, err := t.hmacClient.StatObject(t.gcpURLParts.BucketName, t.gcpURLParts.ObjectKey, minio.StatObjectOptions{})
+		return err != nil
+	}
 	bkt := t.gcpClient.Bucket(t.gcpURLParts.BucketName)
 	obj := bkt.Object(t.gcpURLParts.ObjectKey)
 	//Directories do not have attributes and hence throw error
@@ -40,6 +47,10 @@ func (t *gcpTraverser) isDirectory(isSource bool) bool {
 }
 
 func (t *gcpTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	if t.hmacClient != nil {
+		return t.traverseXMLAPI(preprocessor, processor, filters)
+	}
+
 	//Syntactically ensure whether single object or not
 	if t.gcpURLParts.IsObjectSyntactically() && !t.gcpURLParts.IsDirectorySyntactically() && !t.gcpURLParts.IsBucketSyntactically() {
 		objectPath := strings.Split(t.gcpURLParts.ObjectKey, "/")
@@ -146,7 +157,90 @@ func newGCPTraverser(rawURL *url.URL, ctx context.Context, recursive, getPropert
 		t.gcpURLParts = gcpURLParts
 	}
 
-	t.gcpClient, err = common.CreateGCPClient(t.ctx)
+	if common.UseGCPHMACKey() {
+		t.hmacClient, err = common.CreateGCPHMACClient()
+	} else {
+		t.gcpClient, err = common.CreateGCPClient(t.ctx)
+	}
 
 	return t, err
 }
+
+// traverseXMLAPI does what traverse does, but through GCS's XML API, since that's the only one an HMAC key can be used with
+func (t *gcpTraverser) traverseXMLAPI(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	bucket := t.gcpURLParts.BucketName
+
+	//Syntactically ensure whether single object or not
+	if t.gcpURLParts.IsObjectSyntactically() && !t.gcpURLParts.IsDirectorySyntactically() && !t.gcpURLParts.IsBucketSyntactically() {
+		objectPath := strings.Split(t.gcpURLParts.ObjectKey, "/")
+		objectName := objectPath[len(objectPath)-1]
+
+		oi, err := t.hmacClient.StatObject(bucket, t.gcpURLParts.ObjectKey, minio.StatObjectOptions{})
+		if err == nil {
+			gie := common.GCPObjectInfoExtension{ObjectInfo: common.NewGCPObjectAttrsFromXMLAPI(bucket, oi)}
+			storedObject := newStoredObject(
+				preprocessor,
+				objectName,
+				"",
+				common.EEntityType.File(),
+				oi.LastModified,
+				oi.Size,
+				&gie,
+				noBlobProps,
+				gie.NewCommonMetadata(),
+				bucket)
+			return processIfPassedFilters(filters, storedObject, processor)
+		}
+	}
+
+	//Append trailing slash if missing
+	if !strings.HasSuffix(t.gcpURLParts.ObjectKey, "/") && t.gcpURLParts.ObjectKey != "" {
+		t.gcpURLParts.ObjectKey += "/"
+	}
+	searchPrefix := t.gcpURLParts.ObjectKey
+
+	//If code reaches here then the URL points to a bucket or a virtual directory
+	for objectInfo := range t.hmacClient.ListObjects(bucket, searchPrefix, t.recursive, t.ctx.Done()) {
+		if objectInfo.Err != nil {
+			return fmt.Errorf("cannot list objects, %v", objectInfo.Err)
+		}
+		//Virtual directories alone have "/" as suffix
+		if strings.HasSuffix(objectInfo.Key, "/") || objectInfo.Key == "" {
+			continue
+		}
+		objectPath := strings.Split(objectInfo.Key, "/")
+		objectName := objectPath[len(objectPath)-1]
+
+		relativePath := strings.TrimPrefix(objectInfo.Key, searchPrefix)
+
+		oie := common.GCPObjectInfoExtension{ObjectInfo: gcpUtils.ObjectAttrs{}}
+
+		if t.getProperties {
+			oi, err := t.hmacClient.StatObject(bucket, objectInfo.Key, minio.StatObjectOptions{})
+			if err != nil {
+				return err
+			}
+			oie = common.GCPObjectInfoExtension{ObjectInfo: common.NewGCPObjectAttrsFromXMLAPI(bucket, oi)}
+		}
+
+		storedObject := newStoredObject(
+			preprocessor,
+			objectName,
+			relativePath,
+			common.EEntityType.File(),
+			objectInfo.LastModified,
+			objectInfo.Size,
+			&oie,
+			noBlobProps,
+			oie.NewCommonMetadata(),
+			bucket)
+
+		err := processIfPassedFilters(filters,
+			storedObject,
+			processor)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
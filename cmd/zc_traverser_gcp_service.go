@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/minio/minio-go"
 	"google.golang.org/api/iterator"
 	"net/url"
 	"strings"
@@ -16,8 +17,9 @@ type gcpServiceTraverser struct {
 	cachedBuckets []string
 	getProperties bool
 
-	gcpURL    common.GCPURLParts
-	gcpClient *gcpUtils.Client
+	gcpURL     common.GCPURLParts
+	gcpClient  *gcpUtils.Client
+	hmacClient *minio.Client // set instead of gcpClient, when reading with an HMAC key through the XML API
 
 	incrementEnumerationCounter enumerationCounterFunc
 }
@@ -30,7 +32,26 @@ func (t *gcpServiceTraverser) isDirectory(isSource bool) bool {
 
 func (t *gcpServiceTraverser) listContainers() ([]string, error) {
 
-	if len(t.cachedBuckets) == 0 {
+	if len(t.cachedBuckets) == 0 && t.hmacClient != nil {
+		// the XML API lists the buckets of the project that the HMAC key belongs to
+		buckets, err := t.hmacClient.ListBuckets()
+		if err != nil {
+			return nil, err
+		}
+		bucketList := make([]string, 0, len(buckets))
+		for _, b := range buckets {
+			if t.bucketPattern != "" {
+				if ok, err := containerNameMatchesPattern(b.Name, t.bucketPattern); err != nil {
+					return nil, err
+				} else if !ok {
+					continue
+				}
+			}
+			bucketList = append(bucketList, b.Name)
+		}
+		t.cachedBuckets = bucketList
+		return bucketList, nil
+	} else if len(t.cachedBuckets) == 0 {
 		bucketList := make([]string, 0)
 		if projectID == "" {
 			return nil, fmt.Errorf("ProjectID cannot be empty. Ensure that environment variable GOOGLE_CLOUD_PROJECT is not empty")
@@ -109,6 +130,10 @@ func newGCPServiceTraverser(rawURL *url.URL, ctx context.Context, getProperties
 	}
 
 	t.gcpURL = gcpURLParts
+	if common.UseGCPHMACKey() {
+		t.hmacClient, err = common.CreateGCPHMACClient()
+		return t, err
+	}
 	t.gcpClient, err = common.CreateGCPClient(t.ctx)
 	return t, nil
 }
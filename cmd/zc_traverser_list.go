@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"net/url"
+	"time"
 
 	"github.com/Azure/azure-storage-azcopy/common"
 )
@@ -35,8 +36,19 @@ type listTraverser struct {
 	listReader              chan string
 	recursive               bool
 	childTraverserGenerator childTraverserGenerator
+
+	// if set, a child path that can't be scanned fails the whole traversal, rather than being skipped.
+	// Sync sets it on its source, since whatever couldn't be seen there would otherwise look deleted.
+	failOnChildError bool
+
+	// if set, it's called every listFlushInterval while the traverser waits for the next path on the list, to send on
+	// what's been found so far. It's for lists that are still being written, so that their files don't wait on the rest
+	flushPending func() error
 }
 
+// how often a list traverser that's waiting on its list flushes what it has found
+var listFlushInterval = 2 * time.Second
+
 type childTraverserGenerator func(childPath string) (resourceTraverser, error)
 
 // There is no impact to a list traverser returning false because a list traverser points directly to relative paths.
@@ -47,15 +59,31 @@ func (l *listTraverser) isDirectory(bool) bool {
 // To kill the traverser, close() the channel under it.
 // Behavior demonstrated: https://play.golang.org/p/OYdvLmNWgwO
 func (l *listTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	var flush <-chan time.Time
+	if l.flushPending != nil {
+		ticker := time.NewTicker(listFlushInterval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+
 	// read a channel until it closes to get a list of objects
-	childPath, ok := <-l.listReader
-	for ; ok; childPath, ok = <-l.listReader {
+	for {
+		childPath, ok, err := l.next(flush)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
 
 		// fetch an appropriate traverser, and go through the child path, which could be
 		//   1. a single entity
 		//   2. a directory entity that needs to be scanned
 		childTraverser, err := l.childTraverserGenerator(childPath)
 		if err != nil {
+			if l.failOnChildError {
+				return fmt.Errorf("cannot scan %s due to error %s", childPath, err)
+			}
 			glcm.Info(fmt.Sprintf("Skipping %s due to error %s", childPath, err))
 			continue
 		}
@@ -81,6 +109,10 @@ func (l *listTraverser) traverse(preprocessor objectMorpher, processor objectPro
 
 		err = childTraverser.traverse(preProcessorForThisChild, processor, filters)
 		if err != nil {
+			// a dangling symlink only gets this far if the user asked for it to stop the enumeration
+			if _, isDanglingSymlink := err.(danglingSymlinkFailure); l.failOnChildError || isDanglingSymlink {
+				return err
+			}
 			glcm.Info(fmt.Sprintf("Skipping %s as it cannot be scanned due to error: %s", childPath, err))
 		}
 	}
@@ -88,9 +120,23 @@ func (l *listTraverser) traverse(preprocessor objectMorpher, processor objectPro
 	return nil
 }
 
+// next waits for the next path on the list, returning false once the list is closed.
+// Every time flush ticks while it's waiting, it flushes whatever has been found so far.
+func (l *listTraverser) next(flush <-chan time.Time) (childPath string, ok bool, err error) {
+	for {
+		select {
+		case childPath, ok = <-l.listReader:
+			return childPath, ok, nil
+		case <-flush:
+			if err = l.flushPending(); err != nil {
+				return "", false, err
+			}
+		}
+	}
+}
 
 func newListTraverser(parent common.ResourceString, parentType common.Location, credential *common.CredentialInfo,
-	ctx *context.Context, recursive, followSymlinks, getProperties bool, listChan chan string, includeDirectoryStubs bool,
+	ctx *context.Context, recursive, followSymlinks bool, danglingSymlinks *danglingSymlinkTracker, getProperties bool, listChan chan string, includeDirectoryStubs bool,
 	incrementEnumerationCounter enumerationCounterFunc, s2sPreserveBlobTags bool, logLevel pipeline.LogLevel) resourceTraverser {
 	var traverserGenerator childTraverserGenerator
 
@@ -107,7 +153,7 @@ func newListTraverser(parent common.ResourceString, parentType common.Location,
 		}
 
 		// Construct a traverser that goes through the child
-		traverser, err := initResourceTraverser(source, parentType, ctx, credential, &followSymlinks, nil,
+		traverser, err := initResourceTraverser(source, parentType, ctx, credential, &followSymlinks, danglingSymlinks, nil,
 			recursive, getProperties, includeDirectoryStubs, incrementEnumerationCounter, nil, s2sPreserveBlobTags, logLevel)
 		if err != nil {
 			return nil, err
@@ -25,11 +25,13 @@ import (
 	"fmt"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/Azure/azure-storage-azcopy/common/parallel"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 )
 
 type localTraverser struct {
@@ -37,6 +39,9 @@ type localTraverser struct {
 	recursive      bool
 	followSymlinks bool
 
+	// counts the followed symlinks whose targets don't exist (may be nil)
+	danglingSymlinks *danglingSymlinkTracker
+
 	// a generic function to notify that a new stored object has been enumerated
 	incrementEnumerationCounter enumerationCounterFunc
 }
@@ -69,6 +74,22 @@ func (t *localTraverser) getInfoIfSingleFile() (os.FileInfo, bool, error) {
 	return fileInfo, true, nil
 }
 
+// isBlockDevice says whether the file is a block device, such as /dev/sdb, which can be uploaded as a disk image
+func isBlockDevice(fileInfo os.FileInfo) bool {
+	return fileInfo.Mode()&os.ModeDevice != 0 && fileInfo.Mode()&os.ModeCharDevice == 0
+}
+
+// blockDeviceSize finds the size of a block device. Stat reports it as 0, but seeking to the end gives the real size
+func blockDeviceSize(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return f.Seek(0, io.SeekEnd)
+}
+
 func UnfurlSymlinks(symlinkPath string) (result string, err error) {
 	unfurlingPlan := []string{symlinkPath}
 
@@ -139,6 +160,58 @@ func (r *realSeenPathsRecorder) HasSeen(path string) bool {
 	return ok
 }
 
+// danglingSymlinkError is given to the walk func, for a followed symlink whose target doesn't exist
+type danglingSymlinkError struct {
+	linkPath string
+	err      error
+}
+
+func (e danglingSymlinkError) Error() string {
+	return fmt.Sprintf("the target of symlink %s does not exist: %s", e.linkPath, e.err)
+}
+
+// danglingSymlinkTracker counts the followed symlinks whose targets don't exist. They are skipped, rather than
+// reported as failures, unless failOnDangling is set, in which case finding any of them fails the enumeration.
+type danglingSymlinkTracker struct {
+	count          uint32 // accessed atomically
+	failOnDangling bool
+}
+
+func newDanglingSymlinkTracker(failOnDangling bool) *danglingSymlinkTracker {
+	return &danglingSymlinkTracker{failOnDangling: failOnDangling}
+}
+
+// danglingSymlinkFailure is what stops the enumeration when a dangling symlink is found and failOnDangling is set.
+// It has a type of its own so that meta traversers, like listTraverser, can tell it apart from errors they may skip over.
+type danglingSymlinkFailure struct {
+	linkPath string
+}
+
+func (e danglingSymlinkFailure) Error() string {
+	return fmt.Sprintf("the target of symlink %s does not exist, and --%s is set", e.linkPath, common.FailOnDanglingSymlinksFlagName)
+}
+
+// Record notes that the symlink at linkPath is dangling. It returns the error which stops the enumeration
+// straight away if dangling symlinks aren't to be skipped.
+func (d *danglingSymlinkTracker) Record(linkPath string) error {
+	if d != nil {
+		atomic.AddUint32(&d.count, 1)
+		if d.failOnDangling {
+			return danglingSymlinkFailure{linkPath: linkPath}
+		}
+	}
+	WarnStdoutAndJobLog(fmt.Sprintf("Skipping symlink at %s because its target does not exist", linkPath))
+	return nil
+}
+
+// Count returns how many dangling symlinks have been found
+func (d *danglingSymlinkTracker) Count() uint32 {
+	if d == nil {
+		return 0
+	}
+	return atomic.LoadUint32(&d.count)
+}
+
 type symlinkTargetFileInfo struct {
 	os.FileInfo
 	name string
@@ -178,13 +251,16 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 		seenPaths = &realSeenPathsRecorder{make(map[string]struct{})} // have to use the RAM if we are dealing with symlinks, to prevent cycles
 	}
 
+	// parallel.Walk stops at the first error from the walk func, but doesn't return it, so it's kept here
+	var walkErr error
+
 	for len(walkQueue) > 0 {
 		queueItem := walkQueue[0]
 		walkQueue = walkQueue[1:]
 
 		// walk contents of this queueItem in parallel
 		// (for simplicity of coding, we don't parallelize across multiple queueItems)
-		parallel.Walk(queueItem.fullPath, enumerationParallelism, enumerationParallelStatFiles, func(filePath string, fileInfo os.FileInfo, fileError error) error {
+		parallel.Walk(queueItem.fullPath, enumerationParallelism, enumerationParallelStatFiles, keepFirstWalkError(&walkErr, func(filePath string, fileInfo os.FileInfo, fileError error) error {
 			if fileError != nil {
 				WarnStdoutAndJobLog(fmt.Sprintf("Accessing '%s' failed with error: %s", filePath, fileError))
 				return nil
@@ -201,6 +277,9 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 				result, err := UnfurlSymlinks(filePath)
 
 				if err != nil {
+					if os.IsNotExist(err) {
+						return walkFunc(common.GenerateFullPath(fullPath, computedRelativePath), fileInfo, danglingSymlinkError{filePath, err})
+					}
 					WarnStdoutAndJobLog(fmt.Sprintf("Failed to resolve symlink %s: %s", filePath, err))
 					return nil
 				}
@@ -219,6 +298,9 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 
 				rStat, err := os.Stat(result)
 				if err != nil {
+					if os.IsNotExist(err) {
+						return walkFunc(common.GenerateFullPath(fullPath, computedRelativePath), fileInfo, danglingSymlinkError{filePath, err})
+					}
 					WarnStdoutAndJobLog(fmt.Sprintf("Failed to get properties of symlink target at %s: %s", result, err))
 					return nil
 				}
@@ -226,6 +308,9 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 				if rStat.IsDir() {
 					if !seenPaths.HasSeen(result) {
 						err := walkFunc(common.GenerateFullPath(fullPath, computedRelativePath), symlinkTargetFileInfo{rStat, fileInfo.Name()}, fileError)
+						if err == filepath.SkipDir {
+							return nil // the walk func doesn't want the target's contents, so don't queue it up
+						}
 						// Since this doesn't directly manipulate the error, and only checks for a specific error, it's OK to use in a generic function.
 						skipped, err := getProcessingError(err)
 
@@ -274,6 +359,9 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 
 				if !seenPaths.HasSeen(result) {
 					err := walkFunc(common.GenerateFullPath(fullPath, computedRelativePath), fileInfo, fileError)
+					if err == filepath.SkipDir {
+						return err // parallel.Walk will leave out the directory's contents
+					}
 					// Since this doesn't directly manipulate the error, and only checks for a specific error, it's OK to use in a generic function.
 					skipped, err := getProcessingError(err)
 
@@ -294,11 +382,25 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 					return nil
 				}
 			}
-		})
+		}))
+		if walkErr != nil {
+			return walkErr
+		}
 	}
 	return
 }
 
+// keepFirstWalkError records the first error returned by walkFunc. parallel.Walk calls its walk func from one goroutine.
+func keepFirstWalkError(firstErr *error, walkFunc filepath.WalkFunc) filepath.WalkFunc {
+	return func(filePath string, fileInfo os.FileInfo, fileError error) error {
+		err := walkFunc(filePath, fileInfo, fileError)
+		if err != nil && err != filepath.SkipDir && *firstErr == nil {
+			*firstErr = err
+		}
+		return err
+	}
+}
+
 func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
 	singleFileInfo, isSingleFile, err := t.getInfoIfSingleFile()
 
@@ -312,6 +414,13 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 			t.incrementEnumerationCounter(common.EEntityType.File())
 		}
 
+		size := singleFileInfo.Size()
+		if isBlockDevice(singleFileInfo) {
+			if size, err = blockDeviceSize(t.fullPath); err != nil {
+				return fmt.Errorf("cannot get the size of the block device %s: %w", t.fullPath, err)
+			}
+		}
+
 		err := processIfPassedFilters(filters,
 			newStoredObject(
 				preprocessor,
@@ -319,7 +428,7 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 				"",
 				common.EEntityType.File(),
 				singleFileInfo.ModTime(),
-				singleFileInfo.Size(),
+				size,
 				noContentProps, // Local MD5s are computed in the STE, and other props don't apply to local files
 				noBlobProps,
 				noMetdata,
@@ -332,6 +441,9 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 	} else {
 		if t.recursive {
 			processFile := func(filePath string, fileInfo os.FileInfo, fileError error) error {
+				if dangling, ok := fileError.(danglingSymlinkError); ok {
+					return t.danglingSymlinks.Record(dangling.linkPath)
+				}
 				if fileError != nil {
 					WarnStdoutAndJobLog(fmt.Sprintf("Accessing %s failed with error: %s", filePath, fileError))
 					return nil
@@ -350,13 +462,18 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 					return nil
 				}
 
+				// don't even read directories that the filters would reject everything in
+				if fileInfo.IsDir() && shouldPruneDirectory(filters, strings.ReplaceAll(relPath, common.DeterminePathSeparator(t.fullPath), common.AZCOPY_PATH_SEPARATOR_STRING)) {
+					return filepath.SkipDir
+				}
+
 				if t.incrementEnumerationCounter != nil {
 					t.incrementEnumerationCounter(entityType)
 				}
 
 				// This is an exception to the rule. We don't strip the error here, because WalkWithSymlinks catches it.
 				return processIfPassedFilters(filters,
-					newStoredObject(
+					withHiddenAttribute(newStoredObject(
 						preprocessor,
 						fileInfo.Name(),
 						strings.ReplaceAll(relPath, common.DeterminePathSeparator(t.fullPath), common.AZCOPY_PATH_SEPARATOR_STRING), // Consolidate relative paths to the azcopy path separator for sync
@@ -367,7 +484,7 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 						noBlobProps,
 						noMetdata,
 						"", // Local has no such thing as containers
-					),
+					), fileInfo),
 					processor)
 			}
 
@@ -396,7 +513,12 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 						// Evaluate the symlink
 						result, err := UnfurlSymlinks(symlinkPath)
 
-						if err != nil {
+						if os.IsNotExist(err) {
+							if err := t.danglingSymlinks.Record(symlinkPath); err != nil {
+								return err
+							}
+							continue
+						} else if err != nil {
 							return err
 						}
 
@@ -410,7 +532,12 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 						// Replace the current FileInfo with
 						singleFile, err = common.OSStat(result)
 
-						if err != nil {
+						if os.IsNotExist(err) {
+							if err := t.danglingSymlinks.Record(symlinkPath); err != nil {
+								return err
+							}
+							continue
+						} else if err != nil {
 							return err
 						}
 					}
@@ -426,7 +553,7 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 				}
 
 				err := processIfPassedFilters(filters,
-					newStoredObject(
+					withHiddenAttribute(newStoredObject(
 						preprocessor,
 						singleFile.Name(),
 						strings.ReplaceAll(relativePath, common.DeterminePathSeparator(t.fullPath), common.AZCOPY_PATH_SEPARATOR_STRING), // Consolidate relative paths to the azcopy path separator for sync
@@ -437,24 +564,32 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 						noBlobProps,
 						noMetdata,
 						"", // Local has no such thing as containers
-					),
+					), singleFile),
 					processor)
 				_, err = getProcessingError(err)
 				if err != nil {
 					return err
 				}
 			}
+			return nil
 		}
 	}
 
 	return
 }
 
-func newLocalTraverser(fullPath string, recursive bool, followSymlinks bool, incrementEnumerationCounter enumerationCounterFunc) *localTraverser {
+// withHiddenAttribute records whether the file info says the object is hidden, where the OS fetches the attributes along with it
+func withHiddenAttribute(object storedObject, fileInfo os.FileInfo) storedObject {
+	object.hasHiddenAttribute, object.hiddenAttributeKnown = hiddenOrSystemAttributeOf(fileInfo)
+	return object
+}
+
+func newLocalTraverser(fullPath string, recursive bool, followSymlinks bool, danglingSymlinks *danglingSymlinkTracker, incrementEnumerationCounter enumerationCounterFunc) *localTraverser {
 	traverser := localTraverser{
 		fullPath:                    cleanLocalPath(fullPath),
 		recursive:                   recursive,
 		followSymlinks:              followSymlinks,
+		danglingSymlinks:            danglingSymlinks,
 		incrementEnumerationCounter: incrementEnumerationCounter}
 	return &traverser
 }
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	chk "gopkg.in/check.v1"
 )
@@ -50,3 +52,25 @@ func (s *localTraverserTestSuite) TestCleanLocalPathForWindows(c *chk.C) {
 		c.Assert(cleanLocalPath(orig), chk.Equals, expected)
 	}
 }
+
+func (s *localTraverserTestSuite) TestBlockDeviceSizeFromSeeking(c *chk.C) {
+	dir, err := ioutil.TempDir("", "blockdevice")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	// seeking works the same on a regular file, which stands in for a device here
+	path := filepath.Join(dir, "disk.img")
+	c.Assert(ioutil.WriteFile(path, make([]byte, 1536), 0644), chk.IsNil)
+	size, err := blockDeviceSize(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(size, chk.Equals, int64(1536))
+
+	info, err := os.Stat(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(isBlockDevice(info), chk.Equals, false)
+
+	// a character device, like /dev/null, isn't a disk
+	if info, err := os.Stat(os.DevNull); err == nil {
+		c.Assert(isBlockDevice(info), chk.Equals, false)
+	}
+}
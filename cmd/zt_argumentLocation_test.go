@@ -0,0 +1,86 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"strings"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type argumentLocationTestSuite struct{}
+
+var _ = chk.Suite(&argumentLocationTestSuite{})
+
+const (
+	testBlobArg  = "https://acct.blob.core.windows.net/container/blob"
+	testFileArg  = "https://acct.file.core.windows.net/share/file"
+	testDfsArg   = "https://acct.dfs.core.windows.net/fs/file"
+	testLocalArg = "/data/file"
+)
+
+func (s *argumentLocationTestSuite) TestUnrecognizedURLsAreNotLocalPaths(c *chk.C) {
+	c.Assert(classifyArgument(testLocalArg), chk.Equals, common.ELocation.Local())
+	c.Assert(classifyArgument("https://storage.contoso.com/container/blob"), chk.Equals, common.ELocation.Unknown())
+	c.Assert(classifyArgument(testFileArg), chk.Equals, common.ELocation.File())
+	c.Assert(classifyArgument(pipeLocation), chk.Equals, common.ELocation.Pipe())
+}
+
+func (s *argumentLocationTestSuite) TestUserFromToMustMatchArguments(c *chk.C) {
+	fromTo, err := validateFromTo(testLocalArg, testBlobArg, "LocalBlob")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo, chk.Equals, common.EFromTo.LocalBlob())
+
+	// the same account can be reached through either endpoint, and a custom domain can't be checked at all
+	_, err = validateFromTo(testLocalArg, testBlobArg, "LocalBlobFS")
+	c.Assert(err, chk.IsNil)
+	_, err = validateFromTo(testLocalArg, "https://storage.contoso.com/share", "LocalFile")
+	c.Assert(err, chk.IsNil)
+
+	_, err = validateFromTo(testLocalArg, testBlobArg, "LocalFile")
+	c.Assert(err, chk.NotNil)
+	c.Assert(strings.Contains(err.Error(), "destination"), chk.Equals, true)
+	c.Assert(strings.Contains(err.Error(), "is a Blob Storage URL"), chk.Equals, true)
+
+	_, err = validateFromTo(testFileArg, testLocalArg, "BlobLocal")
+	c.Assert(err, chk.NotNil)
+	c.Assert(strings.Contains(err.Error(), "source"), chk.Equals, true)
+
+	// removals have no destination to check
+	_, err = validateFromTo(testDfsArg, "", "BlobFSTrash")
+	c.Assert(err, chk.IsNil)
+}
+
+func (s *argumentLocationTestSuite) TestUnsupportedPairsAreRejectedUpFront(c *chk.C) {
+	_, err := validateFromTo(testFileArg, pipeLocation, "FilePipe")
+	c.Assert(err, chk.NotNil)
+	c.Assert(strings.Contains(err.Error(), "isn't supported"), chk.Equals, true)
+
+	_, err = validateFromTo(testFileArg, testDfsArg, "")
+	c.Assert(err, chk.NotNil)
+	c.Assert(err.Error(), chk.Equals, "copying from an Azure Files URL to an ADLS Gen2 (dfs) URL isn't supported")
+
+	_, err = validateFromTo("https://127.0.0.1:10000/devstoreaccount1/container", testLocalArg, "")
+	c.Assert(err, chk.NotNil)
+	c.Assert(strings.Contains(err.Error(), "Use --from-to"), chk.Equals, true)
+}
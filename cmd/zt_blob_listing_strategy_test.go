@@ -0,0 +1,119 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type blobListingStrategySuite struct{}
+
+var _ = chk.Suite(&blobListingStrategySuite{})
+
+func (s *blobListingStrategySuite) TestExplicitStrategyWins(c *chk.C) {
+	defer func(old int) { enumerationParallelism = old }(enumerationParallelism)
+	enumerationParallelism = 16
+
+	// none of these need to look at the container, so it can be left empty
+	t := &blobTraverser{recursive: true, parallelListing: false, listingStrategy: common.EBlobListingStrategy.Hierarchical()}
+	method, err := t.resolveListingMethod(azblob.ContainerURL{}, "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(method, chk.Equals, blobListingHierarchical)
+
+	// flat listings are partitioned only if the hierarchical calls needed to find the split points are allowed
+	t = &blobTraverser{recursive: true, parallelListing: true, listingStrategy: common.EBlobListingStrategy.Flat()}
+	method, err = t.resolveListingMethod(azblob.ContainerURL{}, "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(method, chk.Equals, blobListingPartitioned)
+
+	t.parallelListing = false
+	method, err = t.resolveListingMethod(azblob.ContainerURL{}, "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(method, chk.Equals, blobListingSerial)
+}
+
+func (s *blobListingStrategySuite) TestAutoStrategyWithoutProbing(c *chk.C) {
+	defer func(old int) { enumerationParallelism = old }(enumerationParallelism)
+	auto := common.EBlobListingStrategy.Auto()
+
+	// the cost-saving opt-out is respected
+	enumerationParallelism = 16
+	t := &blobTraverser{recursive: true, parallelListing: false, listingStrategy: auto}
+	method, err := t.resolveListingMethod(azblob.ContainerURL{}, "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(method, chk.Equals, blobListingSerial)
+
+	// a single level is always listed hierarchically
+	t = &blobTraverser{recursive: false, parallelListing: true, listingStrategy: auto}
+	method, err = t.resolveListingMethod(azblob.ContainerURL{}, "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(method, chk.Equals, blobListingHierarchical)
+
+	// without parallelism, there's nothing to choose between
+	enumerationParallelism = 1
+	t = &blobTraverser{recursive: true, parallelListing: true, listingStrategy: auto}
+	method, err = t.resolveListingMethod(azblob.ContainerURL{}, "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(method, chk.Equals, blobListingSerial)
+}
+
+func (s *blobListingStrategySuite) TestAutoStrategyFromFirstPage(c *chk.C) {
+	// no virtual directories to list separately
+	c.Assert(chooseAutoBlobListing(0, 5000, blobListingPartitioned), chk.Equals, blobListingPartitioned)
+
+	// mostly virtual directories, which are probably small
+	c.Assert(chooseAutoBlobListing(30, 2, blobListingPartitioned), chk.Equals, blobListingPartitioned)
+	c.Assert(chooseAutoBlobListing(10, 10, blobListingSerial), chk.Equals, blobListingSerial)
+
+	// a few big virtual directories
+	c.Assert(chooseAutoBlobListing(5, 4995, blobListingPartitioned), chk.Equals, blobListingHierarchical)
+}
+
+func (s *blobListingStrategySuite) TestParseStrategy(c *chk.C) {
+	var strategy common.BlobListingStrategy
+	c.Assert(strategy.Parse("flat"), chk.IsNil)
+	c.Assert(strategy, chk.Equals, common.EBlobListingStrategy.Flat())
+	c.Assert(strategy.Parse("Hierarchical"), chk.IsNil)
+	c.Assert(strategy, chk.Equals, common.EBlobListingStrategy.Hierarchical())
+	c.Assert(strategy.Parse("bogus"), chk.NotNil)
+}
+
+func (s *blobListingStrategySuite) TestLearnSplitDelimiter(c *chk.C) {
+	// dates split on their dashes, rather than on their (much finer) dots
+	names := []string{"logs/2020-01-01.log", "logs/2020-01-02.log", "logs/2020-02-01.log", "logs/2021-01-01.log"}
+	c.Assert(learnSplitDelimiter(names, "logs/"), chk.Equals, "-")
+
+	// the coarsest split wins
+	names = []string{"a_x.1", "a_x.2", "a_y.1", "a_y.2"}
+	c.Assert(learnSplitDelimiter(names, ""), chk.Equals, "_")
+
+	// ties go to the lowest character, so that the choice is stable
+	names = []string{"a-b_1", "a-b_2"}
+	c.Assert(learnSplitDelimiter(names, ""), chk.Equals, "-")
+
+	// nothing to split on, or nothing worth splitting on
+	c.Assert(learnSplitDelimiter([]string{"abc", "def"}, ""), chk.Equals, "")
+	c.Assert(learnSplitDelimiter([]string{"abc-1", "def-2"}, ""), chk.Equals, "")
+	c.Assert(learnSplitDelimiter([]string{"a-1", "a-2", "b", "c", "d"}, ""), chk.Equals, "")
+	c.Assert(learnSplitDelimiter([]string{"dir/a", "dir/b"}, ""), chk.Equals, "")
+}
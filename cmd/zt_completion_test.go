@@ -0,0 +1,116 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/spf13/cobra"
+	chk "gopkg.in/check.v1"
+)
+
+type completionSuite struct{}
+
+var _ = chk.Suite(&completionSuite{})
+
+func newCompletionTestTree() *cobra.Command {
+	noop := func(cmd *cobra.Command, args []string) {}
+	root := &cobra.Command{Use: "azcopy"}
+	root.PersistentFlags().String("output-type", "text", "")
+
+	cp := &cobra.Command{Use: "copy", Run: noop}
+	cp.Flags().String("from-to", "", "")
+	cp.Flags().Bool("recursive", false, "")
+	cp.Flags().String("hidden-flag", "", "")
+	cp.Flags().MarkHidden("hidden-flag")
+
+	jobs := &cobra.Command{Use: "jobs"}
+	jobs.AddCommand(&cobra.Command{Use: "show [jobID]", Annotations: jobIDArgAnnotations, Run: noop})
+	jobs.AddCommand(&cobra.Command{Use: "list", Run: noop})
+
+	root.AddCommand(cp, jobs)
+	return root
+}
+
+func (s *completionSuite) TestCompletesCommandsAndFlags(c *chk.C) {
+	root := newCompletionTestTree()
+
+	c.Assert(completeWords(root, []string{"j"}), chk.DeepEquals, []string{"jobs"})
+	c.Assert(completeWords(root, []string{"jobs", ""}), chk.DeepEquals, []string{"list", "show"})
+	c.Assert(completeWords(root, []string{"copy", "--"}), chk.DeepEquals, []string{"--from-to", "--output-type", "--recursive"})
+	c.Assert(completeWords(root, []string{"copy", "--r"}), chk.DeepEquals, []string{"--recursive"})
+}
+
+func (s *completionSuite) TestCompletesFlagValues(c *chk.C) {
+	root := newCompletionTestTree()
+
+	// as separate words, as one word (zsh, fish, PowerShell), and split around the '=' (bash)
+	c.Assert(completeWords(root, []string{"copy", "--from-to", "BlobF"}), chk.DeepEquals, []string{"BlobFSBlobFS", "BlobFSLocal", "BlobFSTrash", "BlobFile"})
+	c.Assert(completeWords(root, []string{"copy", "--output-type=j"}), chk.DeepEquals, []string{"--output-type=json"})
+	c.Assert(completeWords(root, []string{"copy", "--output-type", "=", "t"}), chk.DeepEquals, []string{"text"})
+	c.Assert(completeWords(root, []string{"copy", "--output-type", "="}), chk.DeepEquals, []string{"json", "text"})
+
+	// bool flags don't take a separate value, so what follows them isn't completed as one
+	c.Assert(completeWords(root, []string{"copy", "--recursive", ""}), chk.HasLen, 0)
+}
+
+func (s *completionSuite) TestFlagValuesComeFromEnums(c *chk.C) {
+	root := newCompletionTestTree()
+	fromTos := completeWords(root, []string{"copy", "--from-to", ""})
+	c.Assert(containsString(fromTos, "BlobNone"), chk.Equals, true)
+	c.Assert(containsString(fromTos, "Unknown"), chk.Equals, false)
+	for _, value := range fromTos {
+		var fromTo common.FromTo
+		c.Assert(fromTo.Parse(value), chk.IsNil)
+	}
+
+	list := &cobra.Command{Use: "list"}
+	list.Flags().String("with-status", "All", "")
+	jobStatuses := flagValueSuggestions(list, "--with-status")
+	c.Assert(containsString(jobStatuses, "CompletedWithSkipped"), chk.Equals, true)
+	for _, value := range jobStatuses {
+		var status common.JobStatus
+		c.Assert(status.Parse(value), chk.IsNil)
+	}
+}
+
+func (s *completionSuite) TestCompletesJobIDsFromPlanFiles(c *chk.C) {
+	planFolder, err := ioutil.TempDir("", "completion")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(planFolder)
+
+	oldPlanFolder := azcopyJobPlanFolder
+	azcopyJobPlanFolder = planFolder
+	defer func() { azcopyJobPlanFolder = oldPlanFolder }()
+
+	jobID := common.NewJobID()
+	for _, name := range []string{jobID.String() + "--00000.steV17", jobID.String() + "--00001.steV17", "not-a-plan-file.txt"} {
+		c.Assert(ioutil.WriteFile(filepath.Join(planFolder, name), nil, 0644), chk.IsNil)
+	}
+
+	root := newCompletionTestTree()
+	c.Assert(completeWords(root, []string{"jobs", "show", ""}), chk.DeepEquals, []string{jobID.String()})
+	c.Assert(completeWords(root, []string{"jobs", "show", jobID.String(), ""}), chk.HasLen, 0)
+	c.Assert(completeWords(root, []string{"jobs", "list", ""}), chk.HasLen, 0)
+}
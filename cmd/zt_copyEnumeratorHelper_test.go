@@ -0,0 +1,57 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyEnumeratorHelperSuite struct{}
+
+var _ = chk.Suite(&copyEnumeratorHelperSuite{})
+
+func (s *copyEnumeratorHelperSuite) TestDestinationSpaceTrackerReportsOnceWhenExceeded(c *chk.C) {
+	lookups := 0
+	getFreeSpace := func() (uint64, error) {
+		lookups++
+		return 100, nil
+	}
+
+	t := destinationSpaceTracker{}
+	c.Assert(t.add(common.CopyTransfer{SourceSize: 60}, getFreeSpace), chk.Equals, false)
+	c.Assert(t.add(common.CopyTransfer{SourceSize: 40}, getFreeSpace), chk.Equals, false) // exactly fills the volume
+	c.Assert(t.add(common.CopyTransfer{SourceSize: 1}, getFreeSpace), chk.Equals, true)
+	c.Assert(t.add(common.CopyTransfer{SourceSize: 1}, getFreeSpace), chk.Equals, false) // only reported the first time
+	c.Assert(lookups, chk.Equals, 1)
+}
+
+func (s *copyEnumeratorHelperSuite) TestDestinationSpaceTrackerIgnoresUnknownFreeSpace(c *chk.C) {
+	getFreeSpace := func() (uint64, error) {
+		return 0, errors.New("can't tell")
+	}
+
+	t := destinationSpaceTracker{}
+	c.Assert(t.add(common.CopyTransfer{SourceSize: 1}, getFreeSpace), chk.Equals, false)
+	c.Assert(t.add(common.CopyTransfer{SourceSize: 1}, getFreeSpace), chk.Equals, false)
+}
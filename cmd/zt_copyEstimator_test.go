@@ -0,0 +1,141 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyEstimatorSuite struct{}
+
+var _ = chk.Suite(&copyEstimatorSuite{})
+
+func (s *copyEstimatorSuite) TestEstimateUpload(c *chk.C) {
+	e := &transferEstimator{fromTo: common.EFromTo.LocalBlob(), checkDestination: true}
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.File(), SourceSize: 20 * 1024 * 1024}) // 3 blocks of 8 MiB, and the block list
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.File(), SourceSize: 1024})             // a single Put Blob
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.File(), SourceSize: 0})                // a single Put Blob
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.Folder()})                             // nothing, in blob storage
+
+	estimate := e.result()
+	c.Assert(estimate.FileTransfers, chk.Equals, uint64(3))
+	c.Assert(estimate.FolderTransfers, chk.Equals, uint64(1))
+	c.Assert(estimate.TotalBytes, chk.Equals, uint64(20*1024*1024+1024))
+	c.Assert(estimate.WriteOperations, chk.Equals, uint64(6))
+	c.Assert(estimate.ReadOperations, chk.Equals, uint64(3)) // each destination is checked before it's overwritten
+	c.Assert(estimate.ListOperations, chk.Equals, uint64(0))
+	c.Assert(estimate.EgressBytes, chk.Equals, uint64(0))
+	c.Assert(estimate.EstimatedCost, chk.IsNil)
+}
+
+func (s *copyEstimatorSuite) TestEstimateServiceToServiceWithBlockSize(c *chk.C) {
+	e := &transferEstimator{fromTo: common.EFromTo.BlobBlob(), blockSize: 4 * 1024 * 1024}
+	e.addContainer()
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.File(), SourceSize: 10 * 1024 * 1024})
+
+	estimate := e.result()
+	c.Assert(estimate.WriteOperations, chk.Equals, uint64(1+3+1)) // the container, 3 blocks, and the block list
+	c.Assert(estimate.ReadOperations, chk.Equals, uint64(3))
+	c.Assert(estimate.ListOperations, chk.Equals, uint64(1))
+	c.Assert(estimate.EgressBytes, chk.Equals, uint64(10*1024*1024))
+}
+
+func (s *copyEstimatorSuite) TestEstimateDownloadCost(c *chk.C) {
+	prices := &priceSheet{Currency: "USD", WritePer10K: 1, ReadPer10K: 2, ListPer10K: 10000, EgressPerGB: 1}
+	e := &transferEstimator{fromTo: common.EFromTo.BlobLocal(), sourcePrices: prices, destinationPrices: prices}
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.File(), SourceSize: 1024 * 1024 * 1024})
+
+	estimate := e.result()
+	c.Assert(estimate.WriteOperations, chk.Equals, uint64(0))
+	c.Assert(estimate.ReadOperations, chk.Equals, uint64(128))
+	c.Assert(estimate.ListOperations, chk.Equals, uint64(1))
+	c.Assert(estimate.Currency, chk.Equals, "USD")
+	c.Assert(estimate.EstimatedCost, chk.NotNil)
+
+	// 128 reads, 1 list, and 1 GiB of egress
+	c.Assert(math.Abs(*estimate.EstimatedCost-(128.0/10000*2+1+1)) < 1e-9, chk.Equals, true)
+}
+
+func (s *copyEstimatorSuite) TestBlockSizeKeepsWithinMaxBlocks(c *chk.C) {
+	e := &transferEstimator{fromTo: common.EFromTo.LocalBlob()}
+	c.Assert(e.blockSizeFor(1024), chk.Equals, int64(common.DefaultBlockBlobBlockSize))
+
+	size := int64(common.DefaultBlockBlobBlockSize) * common.MaxNumberOfBlocksPerBlob * 3
+	c.Assert(chunkCount(size, e.blockSizeFor(size)) <= common.MaxNumberOfBlocksPerBlob, chk.Equals, true)
+}
+
+func (s *copyEstimatorSuite) TestEstimateCostWithSeparatePriceSheets(c *chk.C) {
+	source := &priceSheet{Currency: "USD", ReadPer10K: 10000, ListPer10K: 10000}
+	destination := &priceSheet{Currency: "USD", WritePer10K: 20000, ReadPer10K: 30000}
+	e := &transferEstimator{fromTo: common.EFromTo.BlobBlob(), checkDestination: true, sourcePrices: source, destinationPrices: destination}
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.File(), SourceSize: 1024})
+
+	estimate := e.result()
+	c.Assert(estimate.ReadOperations, chk.Equals, uint64(2)) // one from the source, and one looking up the destination
+	c.Assert(estimate.WriteOperations, chk.Equals, uint64(1))
+	c.Assert(estimate.ListOperations, chk.Equals, uint64(1))
+
+	// the source read and list at the source's prices, the write and the destination lookup at the destination's
+	c.Assert(math.Abs(*estimate.EstimatedCost-(1+1+2+3)) < 1e-9, chk.Equals, true)
+}
+
+func (s *copyEstimatorSuite) TestEstimateListsPerDirectory(c *chk.C) {
+	e := &transferEstimator{fromTo: common.EFromTo.FileLocal(), listsPerDirectory: true}
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.Folder(), Source: ""})
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.File(), Source: "/top.txt"})
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.File(), Source: "/a/b/deep.txt"}) // a is listed too, although it has no files
+	e.add(common.CopyTransfer{EntityType: common.EEntityType.Folder(), Source: "/empty"})
+	for i := 0; i < estimateListPageSize; i++ {
+		e.add(common.CopyTransfer{EntityType: common.EEntityType.File(), Source: fmt.Sprintf("/big/%d", i)})
+	}
+
+	// the root, a, a/b, empty, and two pages of big
+	c.Assert(e.result().ListOperations, chk.Equals, uint64(6))
+
+	e.probesListing = true
+	c.Assert(e.result().ListOperations, chk.Equals, uint64(7))
+}
+
+func (s *copyEstimatorSuite) TestSourceListing(c *chk.C) {
+	oldStrategy, oldParallelism := blobListingStrategy, enumerationParallelism
+	defer func() { blobListingStrategy, enumerationParallelism = oldStrategy, oldParallelism }()
+
+	enumerationParallelism = 16
+	perDirectory, probes := sourceListingOf(common.ELocation.Blob(), true)
+	c.Assert(perDirectory, chk.Equals, true) // partitioned or hierarchical, whichever auto picks
+	c.Assert(probes, chk.Equals, true)
+
+	blobListingStrategy = common.EBlobListingStrategy.Flat()
+	perDirectory, probes = sourceListingOf(common.ELocation.Blob(), true)
+	c.Assert(perDirectory, chk.Equals, true) // partitioned
+	c.Assert(probes, chk.Equals, false)
+
+	enumerationParallelism = 1
+	perDirectory, _ = sourceListingOf(common.ELocation.Blob(), true)
+	c.Assert(perDirectory, chk.Equals, false) // serial
+
+	perDirectory, _ = sourceListingOf(common.ELocation.File(), false)
+	c.Assert(perDirectory, chk.Equals, true)
+}
@@ -0,0 +1,63 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type copyFanOutTestSuite struct{}
+
+var _ = chk.Suite(&copyFanOutTestSuite{})
+
+func (s *copyFanOutTestSuite) TestEachExtraDestinationGetsItsOwnOrder(c *chk.C) {
+	template := common.CopyJobPartOrderRequest{
+		JobID:           common.NewJobID(),
+		SourceRoot:      newLocalRes("/data"),
+		DestinationRoot: newRemoteRes("https://east.blob.core.windows.net/c?sig=main"),
+		Transfers:       []common.CopyTransfer{{Source: "/already.txt"}},
+	}
+	west := newRemoteRes("https://west.blob.core.windows.net/c?sig=west")
+	north := newRemoteRes("https://north.blob.core.windows.net/c?sig=north")
+	f := newFanOut(template, []common.ResourceString{west, north})
+	cca := &cookedCopyCmdArgs{discovered: &discoveryCounter{}}
+
+	transfer := common.CopyTransfer{Source: "/data/a.txt", Destination: "/a.txt", EntityType: common.EEntityType.File(), SourceSize: 3}
+	c.Assert(f.add(transfer, cca), chk.IsNil)
+
+	c.Assert(f.orders, chk.HasLen, 2)
+	for i, dst := range []common.ResourceString{west, north} {
+		order := f.orders[i]
+		c.Assert(order.JobID, chk.Equals, template.JobID)
+		c.Assert(order.DestinationRoot, chk.DeepEquals, dst)
+		c.Assert(order.Transfers, chk.HasLen, 1) // none of the main order's transfers
+		c.Assert(order.Transfers[0].Source, chk.Equals, "/a.txt")
+		c.Assert(order.Transfers[0].Destination, chk.Equals, "/a.txt")
+	}
+
+	// the main order is untouched, and the file is only counted as found once it's added to that
+	c.Assert(template.DestinationRoot.Value, chk.Equals, "https://east.blob.core.windows.net/c")
+	c.Assert(template.Transfers, chk.HasLen, 1)
+	files, _ := cca.discovered.Counts()
+	c.Assert(files, chk.Equals, uint32(0))
+}
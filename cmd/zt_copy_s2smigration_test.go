@@ -80,6 +80,8 @@ func getDefaultRawCopyInput(src, dst string) rawCopyCmdArgs {
 		blockBlobTier:                  defaultBlockBlobTierForCopy,
 		pageBlobTier:                   defaultPageBlobTierForCopy,
 		md5ValidationOption:            common.DefaultHashValidationOption.String(),
+		flushPolicy:                    common.EFlushPolicy.Never().String(),
+		freeSpaceCheck:                 common.EFreeSpaceCheckOption.Warn().String(),
 		s2sGetPropertiesInBackend:      defaultS2SGetPropertiesInBackend,
 		s2sPreserveAccessTier:          defaultS2SPreserveAccessTier,
 		s2sPreserveProperties:          defaultS2SPreserveProperties,
@@ -24,6 +24,7 @@ import (
 	"context"
 	"github.com/Azure/azure-storage-azcopy/common"
 	chk "gopkg.in/check.v1"
+	"net/url"
 	"strings"
 )
 
@@ -56,6 +57,7 @@ func (s *credentialUtilSuite) TestCheckAuthSafeForTarget(c *chk.C) {
 		{common.ECredentialType.S3AccessKey(), common.ELocation.S3(), "http://s3.cn-north-1.amazonaws.com.cn", "", true},
 		{common.ECredentialType.S3AccessKey(), common.ELocation.S3(), "http://s3.amazonaws.com", "", true},
 		{common.ECredentialType.GoogleAppCredentials(), common.ELocation.GCP(), "http://storage.cloud.google.com", "", true},
+		{common.ECredentialType.GoogleHMAC(), common.ELocation.GCP(), "http://storage.cloud.google.com", "", true},
 
 		// These should fail (they are not storage)
 		{common.ECredentialType.OAuthToken(), common.ELocation.Blob(), "http://somethingelseinazure.windows.net", "", false},
@@ -69,6 +71,7 @@ func (s *credentialUtilSuite) TestCheckAuthSafeForTarget(c *chk.C) {
 		// Test that we don't want to send an S3 access key to a blob resource type.
 		{common.ECredentialType.S3AccessKey(), common.ELocation.Blob(), "http://abc.example.com", "", false},
 		{common.ECredentialType.GoogleAppCredentials(), common.ELocation.Blob(), "http://abc.example.com", "", false},
+		{common.ECredentialType.GoogleHMAC(), common.ELocation.Blob(), "http://abc.example.com", "", false},
 
 		// But the same Azure one should pass if the user opts in to them (we don't support any similar override for S3)
 		{common.ECredentialType.OAuthToken(), common.ELocation.Blob(), "http://abc.example.com", "*.foo.com;*.example.com", true},
@@ -94,3 +97,19 @@ func (s *credentialUtilSuite) TestCheckAuthSafeForTargetIsCalledWhenGettingAuthT
 	c.Assert(strings.Contains(err.Error(), "If this URL is in fact an Azure service, you can enable Azure authentication to notblob.example.com."),
 		chk.Equals, true)
 }
+
+func (s *credentialUtilSuite) TestStorageAccountNameOf(c *chk.C) {
+	tests := map[string]string{
+		"https://myaccount.blob.core.windows.net/container/blob": "myaccount",
+		"https://myaccount.file.core.windows.net/share":          "myaccount",
+		"https://myaccount.dfs.core.chinacloudapi.cn/filesystem": "myaccount",
+		"http://127.0.0.1:10000/devstoreaccount1/container/blob": "devstoreaccount1",
+		"http://localhost:10000/devstoreaccount1":                "devstoreaccount1",
+	}
+
+	for raw, expected := range tests {
+		u, err := url.Parse(raw)
+		c.Assert(err, chk.IsNil)
+		c.Assert(storageAccountNameOf(u), chk.Equals, expected, chk.Commentf("for %s", raw))
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type deleteSourceAfterTransferTestSuite struct{}
+
+var _ = chk.Suite(&deleteSourceAfterTransferTestSuite{})
+
+func (s *deleteSourceAfterTransferTestSuite) TestMovesNeedADeletableSource(c *chk.C) {
+	source := common.ResourceString{Value: "https://acct.blob.core.windows.net/container"}
+	for _, fromTo := range []common.FromTo{common.EFromTo.LocalBlob(), common.EFromTo.BlobLocal(), common.EFromTo.FileFile(), common.EFromTo.BlobFSBlobFS()} {
+		c.Assert(validateDeleteSourceAfterTransfer(true, fromTo, false, source), chk.IsNil)
+	}
+	for _, fromTo := range []common.FromTo{common.EFromTo.S3Blob(), common.EFromTo.GCPBlob(), common.EFromTo.BlobPipe(), common.EFromTo.BenchmarkBlob()} {
+		c.Assert(validateDeleteSourceAfterTransfer(true, fromTo, false, source), chk.NotNil)
+	}
+
+	// nothing is checked unless a move was asked for
+	c.Assert(validateDeleteSourceAfterTransfer(false, common.EFromTo.S3Blob(), false, source), chk.IsNil)
+}
+
+func (s *deleteSourceAfterTransferTestSuite) TestMovesMustTransferSomething(c *chk.C) {
+	source := common.ResourceString{Value: "/data"}
+	c.Assert(validateDeleteSourceAfterTransfer(true, common.EFromTo.LocalBlob(), true, source), chk.NotNil)
+
+	snapshot := common.ResourceString{Value: "https://acct.file.core.windows.net/share", ExtraQuery: "sharesnapshot=2021-01-01T00:00:00.0000000Z"}
+	c.Assert(validateDeleteSourceAfterTransfer(true, common.EFromTo.FileLocal(), false, snapshot), chk.NotNil)
+}
@@ -0,0 +1,94 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+)
+
+type duplicateDestinationSuite struct{}
+
+var _ = chk.Suite(&duplicateDestinationSuite{})
+
+func (s *duplicateDestinationSuite) TestFailOnSecondSource(c *chk.C) {
+	detector := newDuplicateDestinationDetector(false)
+
+	destination, alreadyScheduled, err := detector.claim("a/report.pdf", "out/report.pdf")
+	c.Assert(err, chk.IsNil)
+	c.Assert(destination, chk.Equals, "out/report.pdf")
+	c.Assert(alreadyScheduled, chk.Equals, false)
+
+	// the same source again is left out rather than failing the job
+	_, alreadyScheduled, err = detector.claim("a/report.pdf", "out/report.pdf")
+	c.Assert(err, chk.IsNil)
+	c.Assert(alreadyScheduled, chk.Equals, true)
+
+	_, _, err = detector.claim("b/report.pdf", "out/report.pdf")
+	c.Assert(err, chk.NotNil)
+	c.Assert(err, chk.ErrorMatches, "a/report.pdf and b/report.pdf would both be copied to out/report.pdf.*")
+}
+
+func (s *duplicateDestinationSuite) TestRenameSecondSource(c *chk.C) {
+	detector := newDuplicateDestinationDetector(true)
+
+	destination, _, err := detector.claim("a/report.pdf", "out/report.pdf")
+	c.Assert(err, chk.IsNil)
+	c.Assert(destination, chk.Equals, "out/report.pdf")
+
+	destination, alreadyScheduled, err := detector.claim("b/report.pdf", "out/report.pdf")
+	c.Assert(err, chk.IsNil)
+	c.Assert(destination, chk.Equals, "out/report-2.pdf")
+	c.Assert(alreadyScheduled, chk.Equals, false)
+
+	destination, _, _ = detector.claim("c/report.pdf", "out/report.pdf")
+	c.Assert(destination, chk.Equals, "out/report-3.pdf")
+
+	// seeing a renamed source again gives the name it got the first time
+	destination, alreadyScheduled, _ = detector.claim("b/report.pdf", "out/report.pdf")
+	c.Assert(destination, chk.Equals, "out/report-2.pdf")
+	c.Assert(alreadyScheduled, chk.Equals, true)
+}
+
+func (s *duplicateDestinationSuite) TestRetryClaimsSameDestinationsAgain(c *chk.C) {
+	detector := newDuplicateDestinationDetector(true)
+	_, _, _ = detector.claim("a/report.pdf", "out/report.pdf")
+	_, _, _ = detector.claim("b/report.pdf", "out/report.pdf")
+
+	// a retry of b, which failed, schedules it again to the name it got, rather than over a's copy
+	retry := detector.forRetry()
+	destination, alreadyScheduled, err := retry.claim("b/report.pdf", "out/report.pdf")
+	c.Assert(err, chk.IsNil)
+	c.Assert(destination, chk.Equals, "out/report-2.pdf")
+	c.Assert(alreadyScheduled, chk.Equals, false)
+
+	// and a second retry pass still knows both
+	destination, alreadyScheduled, _ = retry.forRetry().claim("a/report.pdf", "out/report.pdf")
+	c.Assert(destination, chk.Equals, "out/report.pdf")
+	c.Assert(alreadyScheduled, chk.Equals, false)
+}
+
+func (s *duplicateDestinationSuite) TestRetryStillFailsOnSecondSource(c *chk.C) {
+	detector := newDuplicateDestinationDetector(false)
+	_, _, _ = detector.claim("a/report.pdf", "out/report.pdf")
+
+	_, _, err := detector.forRetry().claim("b/report.pdf", "out/report.pdf")
+	c.Assert(err, chk.NotNil)
+}
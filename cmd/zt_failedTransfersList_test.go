@@ -0,0 +1,119 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type failedTransfersListSuite struct{}
+
+var _ = chk.Suite(&failedTransfersListSuite{})
+
+func (s *failedTransfersListSuite) TestRelativePathUnderSource(c *chk.C) {
+	source := common.ResourceString{Value: "https://acct.blob.core.windows.net/container/dir"}
+	relativePath, err := relativePathUnderSource("https://acct.blob.core.windows.net/container/dir/sub/a%20b.txt?versionid=1", source, common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(relativePath, chk.Equals, "sub/a b.txt")
+
+	source = common.ResourceString{Value: "/data/dir"}
+	relativePath, err = relativePathUnderSource("/data/dir/sub/file.txt", source, common.ELocation.Local())
+	c.Assert(err, chk.IsNil)
+	c.Assert(relativePath, chk.Equals, "sub/file.txt")
+}
+
+func (s *failedTransfersListSuite) TestBuildRetryCommand(c *chk.C) {
+	args := []string{"copy", "/data/dir", "https://acct.blob.core.windows.net/container?sv=2019&sig=secret", "--recursive",
+		"--include-path", "a;b", "--failed-transfers-file=failed.txt"}
+
+	command := buildRetryCommand(args, "failed.txt")
+	c.Assert(command, chk.Equals, "azcopy copy /data/dir https://acct.blob.core.windows.net/container?[SAS] --recursive --list-of-files=failed.txt")
+}
+
+func (s *failedTransfersListSuite) TestRetriableFailures(c *chk.C) {
+	for _, status := range []common.TransferStatus{common.ETransferStatus.Failed(), common.ETransferStatus.BlobTierFailure(), common.ETransferStatus.TimedOut()} {
+		c.Assert(isRetriableFailure(status), chk.Equals, true, chk.Commentf(status.String()))
+	}
+
+	for _, status := range []common.TransferStatus{common.ETransferStatus.TierAvailabilityCheckFailure(), common.ETransferStatus.Cancelled(),
+		common.ETransferStatus.SkippedEntityAlreadyExists(), common.ETransferStatus.SkippedSizeLimit(), common.ETransferStatus.Success()} {
+		c.Assert(isRetriableFailure(status), chk.Equals, false, chk.Commentf(status.String()))
+	}
+}
+
+func (s *failedTransfersListSuite) TestNoRetryPassBeyondLimit(c *chk.C) {
+	cca := cookedCopyCmdArgs{retryFailedPasses: 0}
+	c.Assert(cca.retryPassForFailedTransfers(), chk.IsNil)
+
+	cca = cookedCopyCmdArgs{retryFailedPasses: 2, retryPass: 2}
+	c.Assert(cca.retryPassForFailedTransfers(), chk.IsNil)
+}
+
+func (s *failedTransfersListSuite) TestRetryPassResetsCounters(c *chk.C) {
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+	mockedRPC.listJobTransfersResponse = common.ListJobTransfersResponse{Details: []common.TransferDetail{
+		{Src: "/data/dir/a.txt", TransferStatus: common.ETransferStatus.Failed()},
+	}}
+
+	cca := cookedCopyCmdArgs{
+		source:            common.ResourceString{Value: "/data/dir"},
+		fromTo:            common.EFromTo.LocalBlob(),
+		retryFailedPasses: 1,
+		hiddenFiles:       newHiddenFileFilter("/data/dir"),
+		danglingSymlinks:  newDanglingSymlinkTracker(true),
+	}
+	cca.hiddenFiles.doesPass(storedObject{relativePath: ".hidden", entityType: common.EEntityType.File()})
+	_ = cca.danglingSymlinks.Record("/data/dir/link")
+
+	retry := cca.retryPassForFailedTransfers()
+	c.Assert(retry, chk.NotNil)
+	c.Assert(retry.hiddenFiles.Count(), chk.Equals, uint32(0))
+	c.Assert(retry.danglingSymlinks.Count(), chk.Equals, uint32(0))
+	c.Assert(retry.danglingSymlinks.failOnDangling, chk.Equals, true)
+	c.Assert(cca.hiddenFiles.Count(), chk.Equals, uint32(1))
+}
+
+func (s *failedTransfersListSuite) TestRetryPassReschedulesFailedFile(c *chk.C) {
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+	mockedRPC.listJobTransfersResponse = common.ListJobTransfersResponse{Details: []common.TransferDetail{
+		{Src: "/data/dir/a.txt", TransferStatus: common.ETransferStatus.Failed()},
+	}}
+
+	cca := cookedCopyCmdArgs{
+		source:                common.ResourceString{Value: "/data/dir"},
+		fromTo:                common.EFromTo.LocalBlob(),
+		retryFailedPasses:     1,
+		duplicateDestinations: newDuplicateDestinationDetector(false),
+	}
+	_, _, _ = cca.duplicateDestinations.claim("/a.txt", "/dir/a.txt")
+
+	retry := cca.retryPassForFailedTransfers()
+	c.Assert(retry, chk.NotNil)
+	destination, alreadyScheduled, err := retry.duplicateDestinations.claim("/a.txt", "/dir/a.txt")
+	c.Assert(err, chk.IsNil)
+	c.Assert(destination, chk.Equals, "/dir/a.txt")
+	c.Assert(alreadyScheduled, chk.Equals, false)
+}
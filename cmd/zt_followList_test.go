@@ -0,0 +1,109 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type followListSuite struct{}
+
+var _ = chk.Suite(&followListSuite{})
+
+// growingList is a list of files that's still being written, so reading to its end doesn't mean it's finished
+type growingList struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (l *growingList) Read(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.buf.Read(p)
+}
+
+func (l *growingList) write(s string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.buf.WriteString(s)
+}
+
+func (s *followListSuite) TestFollowLinesUntilSealed(c *chk.C) {
+	list := &growingList{}
+	list.write("a\nb")
+
+	lines := make(chan string, 10)
+	done := make(chan error)
+	go func() {
+		done <- followLines(list, time.Millisecond, func(line string) { lines <- line })
+	}()
+
+	// a complete line is read straight away, but one that's half written waits for the rest of it
+	c.Assert(<-lines, chk.Equals, "a")
+	select {
+	case line := <-lines:
+		c.Fatalf("read %q before it was complete", line)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// nothing after the seal is read
+	list.write("c\r\nd\n" + sealListLine + "\ne\n")
+	c.Assert(<-done, chk.IsNil)
+	close(lines)
+	var read []string
+	for line := range lines {
+		read = append(read, line)
+	}
+	c.Assert(read, chk.DeepEquals, []string{"bc", "d"})
+}
+
+func (s *followListSuite) TestListTraverserFlushesWhileWaiting(c *chk.C) {
+	defer func(interval time.Duration) { listFlushInterval = interval }(listFlushInterval)
+	listFlushInterval = time.Millisecond
+
+	var flushes int32
+	list := make(chan string)
+	lt := &listTraverser{
+		listReader:              list,
+		recursive:               true,
+		childTraverserGenerator: func(string) (resourceTraverser, error) { return &erroringTraverser{}, nil },
+		flushPending:            func() error { atomic.AddInt32(&flushes, 1); return nil },
+	}
+
+	processor := dummyProcessor{}
+	done := make(chan error)
+	go func() { done <- lt.traverse(noPreProccessor, processor.process, nil) }()
+
+	// what's been found is flushed while the list is quiet, without it being closed
+	list <- "first"
+	for atomic.LoadInt32(&flushes) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	list <- "second"
+	close(list)
+	c.Assert(<-done, chk.IsNil)
+	c.Assert(len(processor.record), chk.Equals, 2)
+}
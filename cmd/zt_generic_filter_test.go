@@ -23,6 +23,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
 	chk "gopkg.in/check.v1"
 	"strings"
 	"time"
@@ -78,6 +79,113 @@ func (s *genericFilterSuite) TestExcludeFilter(c *chk.C) {
 	}
 }
 
+func (s *genericFilterSuite) TestExcludeDirectoryFilter(c *chk.C) {
+	// set up the filters
+	raw := rawSyncCmdArgs{}
+	excludeFilterList := buildDirectoryNameFilters(nil, raw.parsePatterns("node_modules;.git;tmp*"))
+
+	// test the positive cases
+	filesToPass := []string{"node_modules.txt", "src/node_modules", "src/main.go", "git/config", "src/temp/a.txt"}
+	for _, file := range filesToPass {
+		dummyProcessor := &dummyProcessor{}
+		err := processIfPassedFilters(excludeFilterList, storedObject{relativePath: file, entityType: common.EEntityType.File()}, dummyProcessor.process)
+		c.Assert(err, chk.IsNil)
+		c.Assert(len(dummyProcessor.record), chk.Equals, 1)
+	}
+
+	// test the negative cases, at any depth
+	filesToNotPass := []string{"node_modules/a.js", "src/node_modules/b/c.js", ".git/config", "a/tmp123/b.txt"}
+	for _, file := range filesToNotPass {
+		dummyProcessor := &dummyProcessor{}
+		err := processIfPassedFilters(excludeFilterList, storedObject{relativePath: file, entityType: common.EEntityType.File()}, dummyProcessor.process)
+		c.Assert(err, chk.Equals, ignoredError)
+		c.Assert(len(dummyProcessor.record), chk.Equals, 0)
+	}
+
+	// the excluded folder itself is not transferred either
+	dummyProcessor := &dummyProcessor{}
+	err := processIfPassedFilters(excludeFilterList, storedObject{relativePath: "src/node_modules", entityType: common.EEntityType.Folder()}, dummyProcessor.process)
+	c.Assert(err, chk.Equals, ignoredError)
+}
+
+func (s *genericFilterSuite) TestDirectoryFilterPruning(c *chk.C) {
+	raw := rawSyncCmdArgs{}
+	excludeFilterList := buildDirectoryNameFilters(nil, raw.parsePatterns("node_modules;tmp*"))
+
+	// excluded directories, and anything inside them, need not be listed at all
+	for _, dir := range []string{"node_modules", "src/node_modules", "a/tmp123", "a/tmp123/b"} {
+		c.Assert(shouldPruneDirectory(excludeFilterList, dir), chk.Equals, true)
+	}
+	for _, dir := range []string{"", "src", "node_modules.d", "a/temp"} {
+		c.Assert(shouldPruneDirectory(excludeFilterList, dir), chk.Equals, false)
+	}
+
+	// an include filter can't prune, since a matching directory may be further down
+	includeFilterList := buildDirectoryNameFilters(raw.parsePatterns("src"), nil)
+	c.Assert(shouldPruneDirectory(includeFilterList, "lib"), chk.Equals, false)
+}
+
+func (s *genericFilterSuite) TestIncludeDirectoryFilter(c *chk.C) {
+	// set up the filters
+	raw := rawSyncCmdArgs{}
+	includeFilterList := buildDirectoryNameFilters(raw.parsePatterns("src;docs"), nil)
+	c.Assert(len(includeFilterList), chk.Equals, 1)
+
+	// test the positive cases
+	filesToPass := []string{"src/main.go", "a/b/docs/readme.md", "src/lib/util.go"}
+	for _, file := range filesToPass {
+		passed := includeFilterList[0].doesPass(storedObject{relativePath: file, entityType: common.EEntityType.File()})
+		c.Assert(passed, chk.Equals, true)
+	}
+
+	// test the negative cases
+	filesToNotPass := []string{"src", "main.go", "source/main.go", "a/b/docs.md"}
+	for _, file := range filesToNotPass {
+		passed := includeFilterList[0].doesPass(storedObject{relativePath: file, entityType: common.EEntityType.File()})
+		c.Assert(passed, chk.Equals, false)
+	}
+}
+
+func (s *genericFilterSuite) TestHiddenFileFilter(c *chk.C) {
+	// without a root path, only the names are looked at
+	hiddenFilter := newHiddenFileFilter("")
+
+	// test the positive cases
+	filesToPass := []string{"visible.txt", "dir/visible.txt", "dir.with.dots/a.b", ""}
+	for _, file := range filesToPass {
+		passed := hiddenFilter.doesPass(storedObject{relativePath: file, entityType: common.EEntityType.File()})
+		c.Assert(passed, chk.Equals, true)
+	}
+
+	// test the negative cases, including everything inside hidden folders
+	filesToNotPass := []string{".bashrc", "dir/.hidden", ".git/config", "a/.cache/b/c.bin"}
+	for _, file := range filesToNotPass {
+		passed := hiddenFilter.doesPass(storedObject{relativePath: file, entityType: common.EEntityType.File()})
+		c.Assert(passed, chk.Equals, false)
+	}
+
+	// hidden folders are left out too, but only files are counted
+	c.Assert(hiddenFilter.doesPass(storedObject{relativePath: ".git", entityType: common.EEntityType.Folder()}), chk.Equals, false)
+	c.Assert(hiddenFilter.Count(), chk.Equals, uint32(len(filesToNotPass)))
+
+	var nilFilter *hiddenFileFilter
+	c.Assert(nilFilter.Count(), chk.Equals, uint32(0))
+}
+
+func (s *genericFilterSuite) TestUncountedHiddenFileFilter(c *chk.C) {
+	// sync filters its destination with the same rules, but only the source side is counted
+	hiddenFilter := newHiddenFileFilter("")
+	destinationFilter := hiddenFilter.uncounted()
+
+	c.Assert(destinationFilter.doesPass(storedObject{relativePath: "visible.txt", entityType: common.EEntityType.File()}), chk.Equals, true)
+	c.Assert(destinationFilter.doesPass(storedObject{relativePath: ".bashrc", entityType: common.EEntityType.File()}), chk.Equals, false)
+	c.Assert(destinationFilter.doesPass(storedObject{relativePath: ".git/config", entityType: common.EEntityType.File()}), chk.Equals, false)
+	c.Assert(destinationFilter.Count(), chk.Equals, uint32(0))
+
+	c.Assert(hiddenFilter.doesPass(storedObject{relativePath: ".bashrc", entityType: common.EEntityType.File()}), chk.Equals, false)
+	c.Assert(hiddenFilter.Count(), chk.Equals, uint32(1))
+}
+
 func (s *genericFilterSuite) TestDateParsingForIncludeAfter(c *chk.C) {
 	examples := []struct {
 		input                 string // ISO 8601
@@ -23,10 +23,13 @@ package cmd
 import (
 	gcpUtils "cloud.google.com/go/storage"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -348,6 +351,34 @@ func (s *genericTraverserSuite) TestWalkWithSymlinksBreakLoop(c *chk.C) {
 	c.Assert(fileCount, chk.Equals, 3)
 }
 
+// Test that returning filepath.SkipDir for a folder leaves out its contents, without stopping the walk
+func (s *genericTraverserSuite) TestWalkWithSymlinksSkipDir(c *chk.C) {
+	fileNames := []string{"top.txt", "kept/a.txt", "skipped/b.txt", "skipped/deeper/c.txt"}
+	tmpDir := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(tmpDir)
+
+	scenarioHelper{}.generateLocalFilesFromList(c, tmpDir, fileNames)
+
+	seen := make([]string, 0)
+	c.Assert(WalkWithSymlinks(tmpDir, func(path string, fi os.FileInfo, err error) error {
+		c.Assert(err, chk.IsNil)
+
+		if fi.IsDir() {
+			if fi.Name() == "skipped" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		seen = append(seen, fi.Name())
+		return nil
+	},
+		false), chk.IsNil)
+
+	sort.Strings(seen)
+	c.Assert(seen, chk.DeepEquals, []string{"a.txt", "top.txt"})
+}
+
 // Test ability to dedupe within the same directory
 func (s *genericTraverserSuite) TestWalkWithSymlinksDedupe(c *chk.C) {
 	fileNames := []string{"stonks.txt", "jaws but its a baby shark.mp3", "my crow soft.txt"}
@@ -443,6 +474,38 @@ func (s *genericTraverserSuite) TestWalkWithSymlinksToParentAndChild(c *chk.C) {
 	c.Assert(fileCount, chk.Equals, 6)
 }
 
+// Test that symlinks whose targets don't exist are counted and skipped, or fail the traversal if asked
+func (s *genericTraverserSuite) TestLocalTraverserDanglingSymlinks(c *chk.C) {
+	fileNames := []string{"file1.txt", "file2.txt"}
+	tmpDir := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(tmpDir)
+
+	scenarioHelper{}.generateLocalFilesFromList(c, tmpDir, fileNames)
+	trySymlink(filepath.Join(tmpDir, "nothing here"), filepath.Join(tmpDir, "dangling"), c)
+
+	for _, recursive := range []bool{true, false} {
+		tracker := newDanglingSymlinkTracker(false)
+		fileCount := 0
+		traverser := newLocalTraverser(tmpDir, recursive, true, tracker, func(common.EntityType) {})
+		err := traverser.traverse(noPreProccessor, func(object storedObject) error {
+			if object.entityType == common.EEntityType.File() {
+				fileCount++
+			}
+			return nil
+		}, nil)
+		c.Assert(err, chk.IsNil)
+		c.Assert(fileCount, chk.Equals, len(fileNames))
+		c.Assert(tracker.Count(), chk.Equals, uint32(1))
+
+		// the traversal stops at the dangling symlink, with its error
+		tracker = newDanglingSymlinkTracker(true)
+		traverser = newLocalTraverser(tmpDir, recursive, true, tracker, func(common.EntityType) {})
+		err = traverser.traverse(noPreProccessor, func(storedObject) error { return nil }, nil)
+		c.Assert(err, chk.NotNil)
+		c.Assert(err.Error(), chk.Matches, ".*symlink .*dangling.*")
+	}
+}
+
 // validate traversing a single Blob, a single Azure File, and a single local file
 // compare that the traversers get consistent results
 func (s *genericTraverserSuite) TestTraverserWithSingleObject(c *chk.C) {
@@ -486,7 +549,7 @@ func (s *genericTraverserSuite) TestTraverserWithSingleObject(c *chk.C) {
 		scenarioHelper{}.generateLocalFilesFromList(c, dstDirName, blobList)
 
 		// construct a local traverser
-		localTraverser := newLocalTraverser(filepath.Join(dstDirName, dstFileName), false, false, func(common.EntityType) {})
+		localTraverser := newLocalTraverser(filepath.Join(dstDirName, dstFileName), false, false, nil, func(common.EntityType) {})
 
 		// invoke the local traversal with a dummy processor
 		localDummyProcessor := dummyProcessor{}
@@ -645,7 +708,7 @@ func (s *genericTraverserSuite) TestTraverserContainerAndLocalDirectory(c *chk.C
 	// test two scenarios, either recursive or not
 	for _, isRecursiveOn := range []bool{true, false} {
 		// construct a local traverser
-		localTraverser := newLocalTraverser(dstDirName, isRecursiveOn, false, func(common.EntityType) {})
+		localTraverser := newLocalTraverser(dstDirName, isRecursiveOn, false, nil, func(common.EntityType) {})
 
 		// invoke the local traversal with an indexer
 		// so that the results are indexed for easy validation
@@ -805,7 +868,7 @@ func (s *genericTraverserSuite) TestTraverserWithVirtualAndLocalDirectory(c *chk
 	// test two scenarios, either recursive or not
 	for _, isRecursiveOn := range []bool{true, false} {
 		// construct a local traverser
-		localTraverser := newLocalTraverser(filepath.Join(dstDirName, virDirName), isRecursiveOn, false, func(common.EntityType) {})
+		localTraverser := newLocalTraverser(filepath.Join(dstDirName, virDirName), isRecursiveOn, false, nil, func(common.EntityType) {})
 
 		// invoke the local traversal with an indexer
 		// so that the results are indexed for easy validation
@@ -957,3 +1020,144 @@ func (s *genericTraverserSuite) TestSerialAndParallelBlobTraverser(c *chk.C) {
 		}
 	}
 }
+
+// validate that every listing method finds the same blobs as the serial one
+func (s *genericTraverserSuite) TestBlobTraverserListingStrategies(c *chk.C) {
+	bsu := getBSU()
+	containerURL, containerName := createNewContainer(c, bsu)
+	defer deleteContainer(c, containerURL)
+
+	virDirName := "virdir"
+	scenarioHelper{}.generateCommonRemoteScenarioForBlob(c, containerURL, virDirName+"/")
+
+	defer func(old int) { enumerationParallelism = old }(enumerationParallelism)
+	enumerationParallelism = 4
+
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	rawVirDirURLWithSAS := scenarioHelper{}.getRawBlobURLWithSAS(c, containerName, virDirName)
+
+	serialBlobTraverser := newBlobTraverser(&rawVirDirURLWithSAS, p, ctx, true, false, func(common.EntityType) {}, false)
+	serialBlobTraverser.parallelListing = false
+	serialDummyProcessor := dummyProcessor{}
+	c.Assert(serialBlobTraverser.traverse(noPreProccessor, serialDummyProcessor.process, nil), chk.IsNil)
+
+	lookupMap := make(map[string]storedObject)
+	for _, entry := range serialDummyProcessor.record {
+		lookupMap[entry.relativePath] = entry
+	}
+
+	for _, strategy := range []common.BlobListingStrategy{common.EBlobListingStrategy.Auto(), common.EBlobListingStrategy.Flat(), common.EBlobListingStrategy.Hierarchical()} {
+		blobTraverser := newBlobTraverser(&rawVirDirURLWithSAS, p, ctx, true, false, func(common.EntityType) {}, false)
+		blobTraverser.listingStrategy = strategy
+
+		dummyProcessor := dummyProcessor{}
+		c.Assert(blobTraverser.traverse(noPreProccessor, dummyProcessor.process, nil), chk.IsNil)
+		c.Assert(len(dummyProcessor.record), chk.Equals, len(serialDummyProcessor.record))
+
+		for _, storedObject := range dummyProcessor.record {
+			correspondingFile, present := lookupMap[storedObject.relativePath]
+			c.Assert(present, chk.Equals, true)
+			c.Assert(storedObject.lastModifiedTime, chk.DeepEquals, correspondingFile.lastModifiedTime)
+		}
+	}
+}
+
+// validate that a partitioned listing of a virtual directory without sub-directories, which has to be split on
+// points learned from the blob names, finds the same blobs as the serial one
+func (s *genericTraverserSuite) TestPartitionedBlobTraverserLearnsSplitPoints(c *chk.C) {
+	bsu := getBSU()
+	containerURL, containerName := createNewContainer(c, bsu)
+	defer deleteContainer(c, containerURL)
+
+	virDirName := "virdir"
+	blobList := make([]string, 0)
+	for _, year := range []string{"2019", "2020", "2021"} {
+		for day := 1; day <= 10; day++ {
+			blobList = append(blobList, fmt.Sprintf("%s/%s-01-%02d.log", virDirName, year, day))
+		}
+	}
+	blobList = append(blobList, virDirName+"/readme")
+	scenarioHelper{}.generateBlobsFromList(c, containerURL, blobList, blockBlobDefaultData)
+
+	defer func(old int, oldSize int32) {
+		enumerationParallelism = old
+		blobSplitProbePageSize = oldSize
+	}(enumerationParallelism, blobSplitProbePageSize)
+	enumerationParallelism = 4
+	blobSplitProbePageSize = 5 // so that the first page doesn't hold everything
+
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	rawVirDirURLWithSAS := scenarioHelper{}.getRawBlobURLWithSAS(c, containerName, virDirName)
+
+	partitionedBlobTraverser := newBlobTraverser(&rawVirDirURLWithSAS, p, ctx, true, false, func(common.EntityType) {}, false)
+	partitionedBlobTraverser.listingStrategy = common.EBlobListingStrategy.Flat()
+	partitionedDummyProcessor := dummyProcessor{}
+	c.Assert(partitionedBlobTraverser.traverse(noPreProccessor, partitionedDummyProcessor.process, nil), chk.IsNil)
+
+	c.Assert(len(partitionedDummyProcessor.record), chk.Equals, len(blobList))
+	found := make(map[string]bool)
+	for _, entry := range partitionedDummyProcessor.record {
+		c.Assert(found[entry.relativePath], chk.Equals, false) // no blob is listed twice
+		found[entry.relativePath] = true
+	}
+	for _, blobName := range blobList {
+		c.Assert(found[strings.TrimPrefix(blobName, virDirName+"/")], chk.Equals, true)
+	}
+}
+
+// erroringTraverser is a child traverser that fails with the given error, after finding one file
+type erroringTraverser struct {
+	err error
+}
+
+func (t *erroringTraverser) isDirectory(bool) bool {
+	return true
+}
+
+func (t *erroringTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	if err := processor(newStoredObject(preprocessor, "file", "file", common.EEntityType.File(), time.Now(), 1, noContentProps, noBlobProps, noMetdata, "")); err != nil {
+		return err
+	}
+	return t.err
+}
+
+func (s *genericTraverserSuite) TestListTraverserChildErrors(c *chk.C) {
+	newList := func(paths ...string) chan string {
+		ch := make(chan string, len(paths))
+		for _, path := range paths {
+			ch <- path
+		}
+		close(ch)
+		return ch
+	}
+	generator := func(childErr error) childTraverserGenerator {
+		return func(childPath string) (resourceTraverser, error) {
+			if childPath == "missing" {
+				return nil, errors.New("no such path")
+			}
+			if childPath == "bad" {
+				return &erroringTraverser{err: childErr}, nil
+			}
+			return &erroringTraverser{}, nil
+		}
+	}
+
+	// by default, paths that can't be scanned are skipped
+	processor := dummyProcessor{}
+	lt := &listTraverser{listReader: newList("missing", "bad", "good"), recursive: true, childTraverserGenerator: generator(errors.New("listing failed"))}
+	c.Assert(lt.traverse(noPreProccessor, processor.process, nil), chk.IsNil)
+	c.Assert(len(processor.record), chk.Equals, 2)
+
+	// but not when asked to fail on them
+	lt = &listTraverser{listReader: newList("missing", "good"), recursive: true, childTraverserGenerator: generator(nil), failOnChildError: true}
+	c.Assert(lt.traverse(noPreProccessor, processor.process, nil), chk.ErrorMatches, ".*missing.*no such path.*")
+
+	lt = &listTraverser{listReader: newList("good", "bad"), recursive: true, childTraverserGenerator: generator(errors.New("listing failed")), failOnChildError: true}
+	c.Assert(lt.traverse(noPreProccessor, processor.process, nil), chk.ErrorMatches, "listing failed")
+
+	// and a dangling symlink that should stop the enumeration always does
+	lt = &listTraverser{listReader: newList("bad", "good"), recursive: true, childTraverserGenerator: generator(danglingSymlinkFailure{linkPath: "link"})}
+	c.Assert(lt.traverse(noPreProccessor, processor.process, nil), chk.ErrorMatches, ".*symlink link does not exist.*")
+}
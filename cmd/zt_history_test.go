@@ -0,0 +1,102 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type historySuite struct{}
+
+var _ = chk.Suite(&historySuite{})
+
+func (s *historySuite) TestJobHistoryRoundTripAndFilter(c *chk.C) {
+	folder, err := ioutil.TempDir("", "history")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(folder)
+	path := filepath.Join(folder, jobHistoryFileName)
+
+	// no history yet
+	entries, err := readJobHistory(path, jobHistoryFilter{status: common.EJobStatus.All()})
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 0)
+
+	january := time.Date(2021, 1, 15, 10, 0, 0, 0, time.UTC)
+	february := time.Date(2021, 2, 15, 10, 0, 0, 0, time.UTC)
+	toAccount := common.ResourceString{Value: "https://myaccount.blob.core.windows.net/container/dir", SAS: "sig=secret"}
+	toOtherAccount := common.ResourceString{Value: "https://other.blob.core.windows.net/container"}
+	local := common.ResourceString{Value: "/data/dir"}
+
+	for _, e := range []JobHistoryEntry{
+		newJobHistoryEntry(common.ListJobSummaryResponse{JobID: common.NewJobID(), JobStatus: common.EJobStatus.Completed(), TotalBytesTransferred: 100}, common.EFromTo.LocalBlob(), local, toAccount, january),
+		newJobHistoryEntry(common.ListJobSummaryResponse{JobID: common.NewJobID(), JobStatus: common.EJobStatus.Failed(), TotalBytesTransferred: 10}, common.EFromTo.LocalBlob(), local, toAccount, february),
+		newJobHistoryEntry(common.ListJobSummaryResponse{JobID: common.NewJobID(), JobStatus: common.EJobStatus.Completed(), TotalBytesTransferred: 1}, common.EFromTo.LocalBlob(), local, toOtherAccount, january),
+	} {
+		c.Assert(appendJobHistory(path, e), chk.IsNil)
+	}
+
+	// a partial line, as if a process was killed while writing, is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	c.Assert(err, chk.IsNil)
+	f.WriteString(`{"JobID":`)
+	f.Close()
+
+	entries, err = readJobHistory(path, jobHistoryFilter{status: common.EJobStatus.All()})
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 3)
+	c.Assert(entries[0].DestinationAccount, chk.Equals, "myaccount")
+	c.Assert(entries[0].Destination, chk.Equals, toAccount.Value) // no SAS
+
+	from, _ := parseHistoryDate("2021-01-01", false)
+	to, _ := parseHistoryDate("2021-01-31", true)
+	entries, err = readJobHistory(path, jobHistoryFilter{from: from, to: to, destinationAccount: "MyAccount", status: common.EJobStatus.All()})
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 1)
+	c.Assert(entries[0].TotalBytesTransferred, chk.Equals, uint64(100))
+
+	entries, err = readJobHistory(path, jobHistoryFilter{status: common.EJobStatus.Failed()})
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 1)
+	c.Assert(entries[0].TotalBytesTransferred, chk.Equals, uint64(10))
+}
+
+func (s *historySuite) TestParseHistoryDate(c *chk.C) {
+	end, err := parseHistoryDate("2021-01-31", true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(end, chk.DeepEquals, time.Date(2021, 2, 1, 0, 0, 0, 0, time.Local)) // includes the whole of the last day
+
+	t, err := parseHistoryDate("2021-01-31T12:00:00Z", true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(t.After(time.Date(2021, 1, 31, 12, 0, 0, 0, time.UTC)), chk.Equals, true) // the end of the range is exclusive, so this includes 12:00 itself
+
+	filter := jobHistoryFilter{to: t, status: common.EJobStatus.All()}
+	c.Assert(filter.matches(JobHistoryEntry{StartTime: time.Date(2021, 1, 31, 12, 0, 0, 0, time.UTC)}), chk.Equals, true)
+	c.Assert(filter.matches(JobHistoryEntry{StartTime: time.Date(2021, 1, 31, 12, 0, 1, 0, time.UTC)}), chk.Equals, false)
+
+	_, err = parseHistoryDate("31/01/2021", false)
+	c.Assert(err, chk.NotNil)
+}
@@ -0,0 +1,105 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type ignoreFileFilterSuite struct{}
+
+var _ = chk.Suite(&ignoreFileFilterSuite{})
+
+func (s *ignoreFileFilterSuite) TestParseIgnoreRules(c *chk.C) {
+	rules, err := parseIgnoreRules(strings.NewReader("# comment\n\n*.log\n!keep.log\nbuild/\n/top.txt\ndocs/**/*.tmp\n\\#hash\n"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(rules), chk.Equals, 6)
+
+	c.Assert(rules[0].segments, chk.DeepEquals, []string{"**", "*.log"})
+	c.Assert(rules[1].negate, chk.Equals, true)
+	c.Assert(rules[2].dirOnly, chk.Equals, true)
+	c.Assert(rules[3].segments, chk.DeepEquals, []string{"top.txt"})
+	c.Assert(rules[4].segments, chk.DeepEquals, []string{"docs", "**", "*.tmp"})
+	c.Assert(rules[5].segments, chk.DeepEquals, []string{"**", "#hash"})
+}
+
+func (s *ignoreFileFilterSuite) TestIgnoreFileFilter(c *chk.C) {
+	rootDir, err := ioutil.TempDir("", "ignoreFileFilter")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(rootDir)
+
+	err = ioutil.WriteFile(filepath.Join(rootDir, defaultIgnoreFileName), []byte("*.log\n!keep.log\nbuild/\n/top.txt\n"), 0644)
+	c.Assert(err, chk.IsNil)
+	err = os.MkdirAll(filepath.Join(rootDir, "sub"), 0755)
+	c.Assert(err, chk.IsNil)
+	err = ioutil.WriteFile(filepath.Join(rootDir, "sub", defaultIgnoreFileName), []byte("*.txt\n"), 0644)
+	c.Assert(err, chk.IsNil)
+
+	// only the root file applies by default
+	filter, err := newIgnoreFileFilter(rootDir, defaultIgnoreFileName, false)
+	c.Assert(err, chk.IsNil)
+	c.Assert(filter, chk.NotNil)
+
+	filesToPass := []string{"a.txt", "dir/top.txt", "keep.log", "dir/keep.log", "builds/a.bin", "sub/a.txt"}
+	for _, file := range filesToPass {
+		c.Assert(filter.doesPass(storedObject{relativePath: file, entityType: common.EEntityType.File()}), chk.Equals, true, chk.Commentf(file))
+	}
+
+	filesToNotPass := []string{"a.log", "dir/b.log", "top.txt", "build/out.bin", "dir/build/out.bin"}
+	for _, file := range filesToNotPass {
+		c.Assert(filter.doesPass(storedObject{relativePath: file, entityType: common.EEntityType.File()}), chk.Equals, false, chk.Commentf(file))
+	}
+
+	// a file named build is not a directory, so it is not ignored
+	c.Assert(filter.doesPass(storedObject{relativePath: "build", entityType: common.EEntityType.File()}), chk.Equals, true)
+	c.Assert(filter.doesPass(storedObject{relativePath: "build", entityType: common.EEntityType.Folder()}), chk.Equals, false)
+
+	// the ignore file itself is never transferred, but ignore files further down are just files unless they're honored
+	c.Assert(filter.doesPass(storedObject{relativePath: defaultIgnoreFileName, entityType: common.EEntityType.File()}), chk.Equals, false)
+	c.Assert(filter.doesPass(storedObject{relativePath: "sub/" + defaultIgnoreFileName, entityType: common.EEntityType.File()}), chk.Equals, true)
+
+	// nested files apply to their own directory when asked for
+	filter, err = newIgnoreFileFilter(rootDir, defaultIgnoreFileName, true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(filter.doesPass(storedObject{relativePath: "sub/a.txt", entityType: common.EEntityType.File()}), chk.Equals, false)
+	c.Assert(filter.doesPass(storedObject{relativePath: "a.txt", entityType: common.EEntityType.File()}), chk.Equals, true)
+	c.Assert(filter.doesPass(storedObject{relativePath: "sub/" + defaultIgnoreFileName, entityType: common.EEntityType.File()}), chk.Equals, false)
+}
+
+func (s *ignoreFileFilterSuite) TestNoIgnoreFile(c *chk.C) {
+	rootDir, err := ioutil.TempDir("", "ignoreFileFilter")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(rootDir)
+
+	filter, err := newIgnoreFileFilter(rootDir, defaultIgnoreFileName, false)
+	c.Assert(err, chk.IsNil)
+	c.Assert(filter, chk.IsNil)
+
+	filter, err = newIgnoreFileFilter(rootDir, "", true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(filter, chk.IsNil)
+}
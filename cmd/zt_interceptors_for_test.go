@@ -31,6 +31,16 @@ import (
 type interceptor struct {
 	transfers   []common.CopyTransfer
 	lastRequest interface{}
+
+	// what ListJobTransfers answers with
+	listJobTransfersResponse common.ListJobTransfersResponse
+
+	// the jobs that ReleaseJob was called for
+	releasedJobs []common.JobID
+
+	// the transfers that CancelTransfer was called for, and what it answers with
+	cancelledTransfers     []common.CancelTransferRequest
+	cancelTransferResponse common.CancelPauseResumeResponse
 }
 
 func (i *interceptor) intercept(cmd common.RpcCmd, request interface{}, response interface{}) {
@@ -49,7 +59,13 @@ func (i *interceptor) intercept(cmd common.RpcCmd, request interface{}, response
 		}
 	case common.ERpcCmd.ListJobs():
 	case common.ERpcCmd.ListJobSummary():
+	case common.ERpcCmd.ReleaseJob():
+		i.releasedJobs = append(i.releasedJobs, *request.(*common.JobID))
 	case common.ERpcCmd.ListJobTransfers():
+		*(response.(*common.ListJobTransfersResponse)) = i.listJobTransfersResponse
+	case common.ERpcCmd.CancelTransfer():
+		i.cancelledTransfers = append(i.cancelledTransfers, *request.(*common.CancelTransferRequest))
+		*(response.(*common.CancelPauseResumeResponse)) = i.cancelTransferResponse
 	case common.ERpcCmd.PauseJob():
 	case common.ERpcCmd.CancelJob():
 	case common.ERpcCmd.ResumeJob():
@@ -122,9 +138,10 @@ func (*mockedLifecycleManager) GetEnvironmentVariable(env common.EnvironmentVari
 	}
 	return value
 }
-func (*mockedLifecycleManager) SetOutputFormat(common.OutputFormat) {}
-func (*mockedLifecycleManager) EnableInputWatcher()                 {}
-func (*mockedLifecycleManager) EnableCancelFromStdIn()              {}
+func (*mockedLifecycleManager) SetOutputFormat(common.OutputFormat)    {}
+func (*mockedLifecycleManager) EnableInputWatcher()                    {}
+func (*mockedLifecycleManager) EnableCancelFromStdIn()                 {}
+func (*mockedLifecycleManager) OnCancelTransferFromStdIn(func(string)) {}
 func (*mockedLifecycleManager) AddUserAgentPrefix(userAgent string) string {
 	return userAgent
 }
@@ -0,0 +1,57 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobIDSuite struct{}
+
+var _ = chk.Suite(&jobIDSuite{})
+
+func (s *jobIDSuite) TestCommandIdentityLeavesOutSAS(c *chk.C) {
+	a := commandIdentity([]string{"copy", "/data", "https://account.blob.core.windows.net/container?sv=2019-12-12&se=2021-01-01T00%3A00%3A00Z&sp=rw&sig=abc", "--recursive"})
+	b := commandIdentity([]string{"copy", "/data", "https://account.blob.core.windows.net/container?sv=2019-12-12&se=2021-02-01T00%3A00%3A00Z&sp=rw&sig=xyz", "--recursive"})
+	c.Assert(a, chk.Equals, b)
+	c.Assert(a, chk.Equals, "copy /data https://account.blob.core.windows.net/container --recursive")
+
+	// but other query parameters, like a snapshot, make it a different command
+	snapshot := commandIdentity([]string{"copy", "/data", "https://account.blob.core.windows.net/container/blob?snapshot=2021-01-01T00%3A00%3A00.0000000Z&sig=abc", "--recursive"})
+	c.Assert(snapshot, chk.Matches, ".*snapshot=.*")
+	c.Assert(snapshot, chk.Not(chk.Matches), ".*sig=.*")
+}
+
+func (s *jobIDSuite) TestApplyJobIDFlag(c *chk.C) {
+	defer func(jobID common.JobID) { azcopyCurrentJobID = jobID }(azcopyCurrentJobID)
+
+	jobID := common.NewJobID()
+	c.Assert(applyJobIDFlag(jobID.String()), chk.IsNil)
+	c.Assert(azcopyCurrentJobID, chk.Equals, jobID)
+
+	c.Assert(applyJobIDFlag(jobIDFromCommand), chk.IsNil)
+	fromCommand := azcopyCurrentJobID
+	c.Assert(applyJobIDFlag(jobIDFromCommand), chk.IsNil)
+	c.Assert(azcopyCurrentJobID, chk.Equals, fromCommand)
+
+	c.Assert(applyJobIDFlag("not-a-guid"), chk.NotNil)
+}
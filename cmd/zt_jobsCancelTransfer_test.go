@@ -0,0 +1,66 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobsCancelTransferSuite struct{}
+
+var _ = chk.Suite(&jobsCancelTransferSuite{})
+
+func (s *jobsCancelTransferSuite) TestCookCancelTransfer(c *chk.C) {
+	jobID := common.NewJobID()
+
+	request, err := rawCancelTransferCmdArgs{jobID: jobID.String(), path: "dir/file.txt", index: -1}.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(request, chk.DeepEquals, common.CancelTransferRequest{JobID: jobID, Path: "dir/file.txt"})
+
+	request, err = rawCancelTransferCmdArgs{jobID: jobID.String(), index: 0}.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(request, chk.DeepEquals, common.CancelTransferRequest{JobID: jobID, Index: 0})
+
+	_, err = rawCancelTransferCmdArgs{jobID: jobID.String(), path: "dir/file.txt", index: 3}.cook()
+	c.Assert(err, chk.NotNil)
+	_, err = rawCancelTransferCmdArgs{jobID: jobID.String(), index: -1}.cook()
+	c.Assert(err, chk.NotNil)
+	_, err = rawCancelTransferCmdArgs{jobID: "not a job", path: "file.txt", index: -1}.cook()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *jobsCancelTransferSuite) TestCancelTransferReportsWhetherItWasCancelled(c *chk.C) {
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	request := common.CancelTransferRequest{JobID: common.NewJobID(), Index: 7}
+	mockedRPC.cancelTransferResponse = common.CancelPauseResumeResponse{CancelledPauseResumed: true, ErrorMsg: "canceled"}
+	msg, err := handleCancelTransfer(request)
+	c.Assert(err, chk.IsNil)
+	c.Assert(msg, chk.Equals, "canceled")
+	c.Assert(mockedRPC.cancelledTransfers, chk.DeepEquals, []common.CancelTransferRequest{request})
+
+	mockedRPC.cancelTransferResponse = common.CancelPauseResumeResponse{ErrorMsg: "already finished"}
+	_, err = handleCancelTransfer(request)
+	c.Assert(err, chk.ErrorMatches, "already finished")
+}
@@ -0,0 +1,72 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type jobsResumeTestSuite struct{}
+
+var _ = chk.Suite(&jobsResumeTestSuite{})
+
+func (s *jobsResumeTestSuite) TestSourceRootOverride(c *chk.C) {
+	dir, err := ioutil.TempDir("", "remounted")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	root, err := cookSourceRootOverride(dir+string(os.PathSeparator), common.EFromTo.LocalBlob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(root, chk.Equals, cleanLocalPath(common.ToExtendedPath(dir)))
+
+	// the source must be found at the new root
+	_, err = cookSourceRootOverride(filepath.Join(dir, "missing"), common.EFromTo.LocalBlob())
+	c.Assert(err, chk.NotNil)
+
+	// only local sources are remounted
+	_, err = cookSourceRootOverride(dir, common.EFromTo.BlobBlob())
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *jobsResumeTestSuite) TestAlsoToSAS(c *chk.C) {
+	alsoToSAS, err := cookAlsoToSAS("https://acct2.blob.core.windows.net/c2?sv=2&sig=b;https://acct3.blob.core.windows.net/c3/dir?sv=3&sig=c",
+		common.EFromTo.LocalBlob())
+	c.Assert(err, chk.IsNil)
+
+	// each destination keeps its own SAS, found by the root its job parts have
+	c.Assert(alsoToSAS, chk.HasLen, 2)
+	c.Assert(alsoToSAS["https://acct2.blob.core.windows.net/c2"], chk.Equals, "sv=2&sig=b")
+	c.Assert(alsoToSAS["https://acct3.blob.core.windows.net/c3/dir"], chk.Equals, "sv=3&sig=c")
+
+	// the destinations must be of the kind the job uploads to
+	_, err = cookAlsoToSAS("https://acct2.file.core.windows.net/share?sv=2&sig=b", common.EFromTo.LocalBlob())
+	c.Assert(err, chk.NotNil)
+
+	// and only uploads have them
+	_, err = cookAlsoToSAS("https://acct2.blob.core.windows.net/c2?sv=2&sig=b", common.EFromTo.BlobBlob())
+	c.Assert(err, chk.NotNil)
+}
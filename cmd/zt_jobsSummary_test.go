@@ -0,0 +1,83 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobsSummarySuite struct{}
+
+var _ = chk.Suite(&jobsSummarySuite{})
+
+func (s *jobsSummarySuite) TestAllJobsSummaryAddsUpJobs(c *chk.C) {
+	summary := AllJobsSummary{}
+	summary.add(common.ListJobSummaryResponse{JobStatus: common.EJobStatus.Completed(), TotalTransfers: 10, FileTransfers: 8, FolderPropertyTransfers: 2,
+		TransfersCompleted: 10, TotalBytesTransferred: 1000})
+	summary.add(common.ListJobSummaryResponse{JobStatus: common.EJobStatus.CompletedWithErrors(), TotalTransfers: 5, FileTransfers: 5,
+		TransfersCompleted: 2, TransfersFailed: 2, TransfersSkipped: 1, TotalBytesTransferred: 200})
+	summary.add(common.ListJobSummaryResponse{JobStatus: common.EJobStatus.Completed(), TotalTransfers: 1, FileTransfers: 1, TransfersCompleted: 1})
+
+	unreadable := common.NewJobID()
+	summary.add(common.ListJobSummaryResponse{JobID: unreadable, ErrorMsg: "no job with JobId exists", TotalTransfers: 100})
+
+	c.Assert(summary.TotalJobs, chk.Equals, uint32(4))
+	c.Assert(summary.JobsByStatus, chk.DeepEquals, map[string]int{"Completed": 2, "CompletedWithErrors": 1})
+	c.Assert(summary.UnreadableJobs, chk.DeepEquals, []common.JobID{unreadable})
+	c.Assert(summary.TotalTransfers, chk.Equals, uint64(16)) // the unreadable job isn't counted
+	c.Assert(summary.FileTransfers, chk.Equals, uint64(14))
+	c.Assert(summary.FolderPropertyTransfers, chk.Equals, uint64(2))
+	c.Assert(summary.TransfersCompleted, chk.Equals, uint64(13))
+	c.Assert(summary.TransfersFailed, chk.Equals, uint64(2))
+	c.Assert(summary.TransfersSkipped, chk.Equals, uint64(1))
+	c.Assert(summary.TotalBytesTransferred, chk.Equals, uint64(1200))
+}
+
+func (s *jobsSummarySuite) TestSummarizeAllJobsReleasesEachJob(c *chk.C) {
+	planFolder, err := ioutil.TempDir("", "jobsSummary")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(planFolder)
+
+	oldPlanFolder := azcopyJobPlanFolder
+	azcopyJobPlanFolder = planFolder
+	defer func() { azcopyJobPlanFolder = oldPlanFolder }()
+
+	jobIDs := []common.JobID{common.NewJobID(), common.NewJobID()}
+	for _, jobID := range jobIDs {
+		c.Assert(ioutil.WriteFile(filepath.Join(planFolder, jobID.String()+"--00000.steV17"), nil, 0644), chk.IsNil)
+	}
+
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	summary := handleSummarizeAllJobs()
+	c.Assert(summary.TotalJobs, chk.Equals, uint32(2))
+	c.Assert(mockedRPC.releasedJobs, chk.HasLen, 2) // rather than keeping every job in memory
+	for _, jobID := range jobIDs {
+		c.Assert(mockedRPC.releasedJobs[0] == jobID || mockedRPC.releasedJobs[1] == jobID, chk.Equals, true)
+	}
+}
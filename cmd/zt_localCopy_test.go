@@ -0,0 +1,65 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type localCopyTestSuite struct{}
+
+var _ = chk.Suite(&localCopyTestSuite{})
+
+func (s *localCopyTestSuite) TestLocalCopyIsNotInferred(c *chk.C) {
+	// a URL on a custom domain looks just like a local path, so a copy between local paths must be asked for
+	c.Assert(inferFromTo(filepath.FromSlash("/data/in"), filepath.FromSlash("/scratch/out")), chk.Equals, common.EFromTo.Unknown())
+	c.Assert(inferFromTo(filepath.FromSlash("/data/in"), "https://storage.contoso.com/c?sig=xyz"), chk.Equals, common.EFromTo.Unknown())
+}
+
+func (s *localCopyTestSuite) TestLocalCopyRefusesURLs(c *chk.C) {
+	err := validateLocalCopyPaths(filepath.FromSlash("/data/in"), "https://storage.contoso.com/c?sig=xyz")
+	c.Assert(err, chk.NotNil)
+	c.Assert(err, chk.ErrorMatches, ".*isn't a local path.*")
+
+	c.Assert(validateLocalCopyPaths("http://10.1.2.3/c", filepath.FromSlash("/scratch/out")), chk.NotNil)
+}
+
+func (s *localCopyTestSuite) TestLocalCopyPathsMustNotOverlap(c *chk.C) {
+	path := func(p string) string { return filepath.FromSlash(p) }
+
+	// separate trees, including siblings whose names share a prefix
+	c.Assert(validateLocalCopyPaths(path("/data/in"), path("/scratch/out")), chk.IsNil)
+	c.Assert(validateLocalCopyPaths(path("/data/dir"), path("/data/dir2")), chk.IsNil)
+	c.Assert(validateLocalCopyPaths(path("/data/a.txt"), path("/data/b.txt")), chk.IsNil)
+
+	// the same path, a destination inside the source, and a source inside the destination
+	c.Assert(validateLocalCopyPaths(path("/data/in"), path("/data/in")), chk.NotNil)
+	c.Assert(validateLocalCopyPaths(path("/data/in"), path("/data/in/copy")), chk.NotNil)
+	c.Assert(validateLocalCopyPaths(path("/data/in/a.txt"), path("/data/in")), chk.NotNil)
+
+	// only the part of the source before any wildcard counts
+	c.Assert(validateLocalCopyPaths(path("/data/in/*.txt"), path("/data/in/copy")), chk.NotNil)
+	c.Assert(validateLocalCopyPaths(path("/data/in/*.txt"), path("/data/out")), chk.IsNil)
+}
@@ -0,0 +1,119 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type managedDiskTestSuite struct{}
+
+var _ = chk.Suite(&managedDiskTestSuite{})
+
+const testDiskImportURL = "https://md-impexp-abcd.blob.storage.azure.net/xyz/abcd?sv=2018-03-28&sr=b&sp=w&sig=xyz"
+
+// writeTestVHD writes a VHD of the given size, whose footer has the cookie and disk type given
+func writeTestVHD(c *chk.C, size int64, cookie string, diskType uint32) string {
+	dir, err := ioutil.TempDir("", "disk")
+	c.Assert(err, chk.IsNil)
+	path := filepath.Join(dir, "disk.vhd")
+
+	data := make([]byte, size)
+	footer := data[size-vhdFooterSize:]
+	copy(footer, cookie)
+	binary.BigEndian.PutUint32(footer[vhdFooterDiskTypeOffset:], diskType)
+	c.Assert(ioutil.WriteFile(path, data, 0600), chk.IsNil)
+	return path
+}
+
+func (s *managedDiskTestSuite) TestFixedVHDIsAccepted(c *chk.C) {
+	path := writeTestVHD(c, managedDiskSizeAlignment+vhdFooterSize, vhdFooterCookie, vhdDiskTypeFixed)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	c.Assert(validateDiskImage(path), chk.IsNil)
+}
+
+func (s *managedDiskTestSuite) TestOtherImagesAreRejected(c *chk.C) {
+	for _, image := range []struct {
+		size     int64
+		cookie   string
+		diskType uint32
+	}{
+		{managedDiskSizeAlignment + vhdFooterSize, vhdFooterCookie, 3}, // dynamic
+		{managedDiskSizeAlignment + vhdFooterSize, "vhdxfile", vhdDiskTypeFixed},
+		{managedDiskSizeAlignment + 2*vhdFooterSize, vhdFooterCookie, vhdDiskTypeFixed}, // not a whole number of MiB
+	} {
+		path := writeTestVHD(c, image.size, image.cookie, image.diskType)
+		c.Assert(validateDiskImage(path), chk.NotNil)
+		os.RemoveAll(filepath.Dir(path))
+	}
+
+	dir, err := ioutil.TempDir("", "disk")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	c.Assert(validateDiskImage(dir), chk.NotNil)
+}
+
+func (s *managedDiskTestSuite) TestDiskImportURLIsInferred(c *chk.C) {
+	c.Assert(inferFromTo("/path/to/disk.vhd", testDiskImportURL), chk.Equals, common.EFromTo.LocalManagedDisk())
+	c.Assert(inferFromTo("/path/to/disk.vhd", "https://acct.blob.core.windows.net/container/disk.vhd"), chk.Equals, common.EFromTo.LocalBlob())
+
+	// and it's still a blob URL, as far as checking the from-to goes
+	c.Assert(validateUserFromTo(common.EFromTo.LocalManagedDisk(), "/path/to/disk.vhd", testDiskImportURL), chk.IsNil)
+}
+
+func (s *managedDiskTestSuite) TestManagedDiskOnlyTakesData(c *chk.C) {
+	path := writeTestVHD(c, managedDiskSizeAlignment+vhdFooterSize, vhdFooterCookie, vhdDiskTypeFixed)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	valid := func() cookedCopyCmdArgs {
+		return cookedCopyCmdArgs{
+			source:     common.ResourceString{Value: path},
+			fromTo:     common.EFromTo.LocalManagedDisk(),
+			forceWrite: common.EOverwriteOption.True(),
+		}
+	}
+	cca := valid()
+	c.Assert(cca.validateManagedDiskUpload(), chk.IsNil)
+
+	cca = valid()
+	cca.blobType = common.EBlobType.BlockBlob()
+	c.Assert(cca.validateManagedDiskUpload(), chk.NotNil)
+
+	cca = valid()
+	cca.pageBlobTier = common.EPageBlobTier.P10()
+	c.Assert(cca.validateManagedDiskUpload(), chk.NotNil)
+
+	cca = valid()
+	cca.metadata = "a=b"
+	c.Assert(cca.validateManagedDiskUpload(), chk.NotNil)
+
+	cca = valid()
+	cca.forceWrite = common.EOverwriteOption.False()
+	c.Assert(cca.validateManagedDiskUpload(), chk.NotNil)
+}
@@ -0,0 +1,68 @@
+// +build linux darwin
+
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type namedPipeTestSuite struct{}
+
+var _ = chk.Suite(&namedPipeTestSuite{})
+
+func (s *namedPipeTestSuite) TestNamedPipesAreInferredAsPipes(c *chk.C) {
+	dir, err := ioutil.TempDir("", "namedpipe")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	fifo := filepath.Join(dir, "fifo")
+	c.Assert(syscall.Mkfifo(fifo, 0600), chk.IsNil)
+	file := filepath.Join(dir, "file")
+	c.Assert(ioutil.WriteFile(file, nil, 0600), chk.IsNil)
+
+	const blob = "https://acct.blob.core.windows.net/container/blob"
+	c.Assert(inferArgumentLocation(fifo), chk.Equals, common.ELocation.Pipe())
+	c.Assert(inferFromTo(fifo, blob), chk.Equals, common.EFromTo.PipeBlob())
+	c.Assert(inferFromTo(blob, fifo), chk.Equals, common.EFromTo.BlobPipe())
+
+	// regular files, and paths that don't exist yet, are still local
+	c.Assert(inferArgumentLocation(file), chk.Equals, common.ELocation.Local())
+	c.Assert(inferArgumentLocation(filepath.Join(dir, "new")), chk.Equals, common.ELocation.Local())
+}
+
+func (s *namedPipeTestSuite) TestDashIsStdinOrStdout(c *chk.C) {
+	const blob = "https://acct.blob.core.windows.net/container/blob"
+	c.Assert(inferFromTo(fromStdioArgument("-"), fromStdioArgument(blob)), chk.Equals, common.EFromTo.PipeBlob())
+	c.Assert(inferFromTo(fromStdioArgument(blob), fromStdioArgument("-")), chk.Equals, common.EFromTo.BlobPipe())
+
+	// a file whose name only starts with a dash is still a file
+	c.Assert(fromStdioArgument("-file"), chk.Equals, "-file")
+	c.Assert(fromStdioArgument("./-"), chk.Equals, "./-")
+}
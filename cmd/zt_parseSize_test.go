@@ -21,6 +21,8 @@
 package cmd
 
 import (
+	"time"
+
 	chk "gopkg.in/check.v1"
 )
 
@@ -56,3 +58,48 @@ func (s *parseSizeSuite) TestParseSize(c *chk.C) {
 	c.Assert(err.Error(), chk.Equals, expectedError)
 
 }
+
+func (s *parseSizeSuite) TestParseSizeLimit(c *chk.C) {
+	b, err := parseSizeLimit("", "x")
+	c.Assert(err, chk.IsNil)
+	c.Assert(b, chk.Equals, int64(0))
+
+	b, _ = parseSizeLimit("4096", "x")
+	c.Assert(b, chk.Equals, int64(4096))
+
+	b, _ = parseSizeLimit("1TiB", "x")
+	c.Assert(b, chk.Equals, int64(1024*1024*1024*1024))
+
+	b, _ = parseSizeLimit("200M", "x")
+	c.Assert(b, chk.Equals, int64(200*1024*1024))
+
+	b, _ = parseSizeLimit("3 gb", "x")
+	c.Assert(b, chk.Equals, int64(3*1024*1024*1024))
+
+	_, err = parseSizeLimit("1PiB", "x")
+	c.Assert(err, chk.NotNil)
+
+	_, err = parseSizeLimit("-1K", "x")
+	c.Assert(err, chk.NotNil)
+
+	_, err = parseSizeLimit("99999999999T", "x")
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *parseSizeSuite) TestParseTransferTimeout(c *chk.C) {
+	d, err := parseTransferTimeout("")
+	c.Assert(err, chk.IsNil)
+	c.Assert(d, chk.Equals, time.Duration(0))
+
+	d, _ = parseTransferTimeout("90m")
+	c.Assert(d, chk.Equals, 90*time.Minute)
+
+	d, _ = parseTransferTimeout("1h30m")
+	c.Assert(d, chk.Equals, 90*time.Minute)
+
+	_, err = parseTransferTimeout("90")
+	c.Assert(err, chk.NotNil)
+
+	_, err = parseTransferTimeout("-1h")
+	c.Assert(err, chk.NotNil)
+}
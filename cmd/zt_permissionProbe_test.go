@@ -0,0 +1,95 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type permissionProbeTestSuite struct{}
+
+var _ = chk.Suite(&permissionProbeTestSuite{})
+
+func (s *permissionProbeTestSuite) TestMissingSASPermissions(c *chk.C) {
+	c.Assert(missingSASPermissions("rl", "r"), chk.HasLen, 0)
+	c.Assert(missingSASPermissions("racwdl", "r", "d"), chk.HasLen, 0)
+	c.Assert(missingSASPermissions("c", "wc"), chk.HasLen, 0)
+
+	c.Assert(missingSASPermissions("l", "r"), chk.DeepEquals, []string{"r"})
+	c.Assert(missingSASPermissions("rl", "r", "d"), chk.DeepEquals, []string{"d"})
+	c.Assert(missingSASPermissions("rl", "wc"), chk.DeepEquals, []string{"w' or 'c"})
+}
+
+func (s *permissionProbeTestSuite) TestCheckSASPermissions(c *chk.C) {
+	c.Assert(checkSASPermissions("source", "sv=2019-12-12&sp=rl&sig=abc", "r"), chk.IsNil)
+	c.Assert(checkSASPermissions("destination", "sv=2019-12-12&sp=rl&sig=abc", "wc"), chk.NotNil)
+
+	// there's nothing to check without a SAS, or with one that refers to a stored access policy
+	c.Assert(checkSASPermissions("source", "", "r"), chk.IsNil)
+	c.Assert(checkSASPermissions("destination", "sv=2019-12-12&si=policy&sig=abc", "wc"), chk.IsNil)
+}
+
+func (s *permissionProbeTestSuite) TestOnlyAuthorizationFailuresAreReported(c *chk.C) {
+	c.Assert(isAuthorizationFailure(nil), chk.Equals, false)
+	c.Assert(isAuthorizationFailure(errors.New("no such host")), chk.Equals, false)
+}
+
+func (s *permissionProbeTestSuite) TestScopedSASIsNarrowerThanContainer(c *chk.C) {
+	parse := func(raw string) url.URL {
+		u, err := url.Parse(raw)
+		c.Assert(err, chk.IsNil)
+		return *u
+	}
+
+	c.Assert(isNarrowerThanContainer(parse("https://acct.blob.core.windows.net/c/a.txt?sr=b&sp=r&sig=abc")), chk.Equals, true)
+	c.Assert(isNarrowerThanContainer(parse("https://acct.blob.core.windows.net/c/dir?sr=d&sdd=1&sp=rl&sig=abc")), chk.Equals, true)
+	c.Assert(isNarrowerThanContainer(parse("https://acct.blob.core.windows.net/c?sr=c&sp=rl&sig=abc")), chk.Equals, false)
+	// an account SAS, and no SAS at all, e.g. with a login
+	c.Assert(isNarrowerThanContainer(parse("https://acct.blob.core.windows.net/c?ss=b&srt=sco&sp=rl&sig=abc")), chk.Equals, false)
+	c.Assert(isNarrowerThanContainer(parse("https://acct.blob.core.windows.net/c")), chk.Equals, false)
+}
+
+func (s *permissionProbeTestSuite) TestScopedSASSkipsProbesOutsideItsScope(c *chk.C) {
+	parse := func(raw string) url.URL {
+		u, err := url.Parse(raw)
+		c.Assert(err, chk.IsNil)
+		return *u
+	}
+
+	// neither makes a request, so there's no pipeline to make one with: a directory SAS can't list the container,
+	// and a blob SAS could only stage the probe's block on the destination blob itself
+	c.Assert(probeBlobSource(context.Background(), nil, parse("https://acct.blob.core.windows.net/c/dir/*?sr=d&sdd=1&sp=rl&sig=abc")), chk.IsNil)
+	cca := cookedCopyCmdArgs{jobID: common.NewJobID()}
+	c.Assert(cca.probeBlobDestination(context.Background(), nil, parse("https://acct.blob.core.windows.net/c/a.txt?sr=b&sp=cw&sig=abc")), chk.IsNil)
+}
+
+func (s *permissionProbeTestSuite) TestProbeBlobIsUnderDestination(c *chk.C) {
+	jobID := common.NewJobID()
+	c.Assert(preflightProbeBlobName("", jobID), chk.Equals, preflightProbeBlobPrefix+jobID.String())
+	c.Assert(preflightProbeBlobName("dir/sub/", jobID), chk.Equals, "dir/sub/"+preflightProbeBlobPrefix+jobID.String())
+	c.Assert(preflightProbeBlobName("dir/sub", jobID), chk.Equals, "dir/sub/"+preflightProbeBlobPrefix+jobID.String())
+}
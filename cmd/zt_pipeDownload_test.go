@@ -0,0 +1,89 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type pipeDownloadTestSuite struct{}
+
+var _ = chk.Suite(&pipeDownloadTestSuite{})
+
+func (s *pipeDownloadTestSuite) TestRangesAreWrittenInOrder(c *chk.C) {
+	source := make([]byte, 1000)
+	rand.Read(source)
+
+	var inFlight, maxInFlight int32
+	download := func(ctx context.Context, offset int64, count int64) ([]byte, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond) // so that ranges finish out of order
+		return source[offset : offset+count], nil
+	}
+
+	var out bytes.Buffer
+	err := downloadRangesInOrder(context.Background(), int64(len(source)), 64, 3, download, &out)
+	c.Assert(err, chk.IsNil)
+	c.Assert(out.Bytes(), chk.DeepEquals, source)
+
+	// the one being written, plus those queued behind it
+	c.Assert(maxInFlight <= 4, chk.Equals, true)
+}
+
+func (s *pipeDownloadTestSuite) TestEmptySourceWritesNothing(c *chk.C) {
+	download := func(ctx context.Context, offset int64, count int64) ([]byte, error) {
+		c.Fatal("there's nothing to download")
+		return nil, nil
+	}
+
+	var out bytes.Buffer
+	c.Assert(downloadRangesInOrder(context.Background(), 0, 64, 3, download, &out), chk.IsNil)
+	c.Assert(out.Len(), chk.Equals, 0)
+}
+
+func (s *pipeDownloadTestSuite) TestFailedRangeStopsTheOutput(c *chk.C) {
+	failure := errors.New("range failed")
+	download := func(ctx context.Context, offset int64, count int64) ([]byte, error) {
+		if offset == 128 {
+			return nil, failure
+		}
+		return make([]byte, count), nil
+	}
+
+	var out bytes.Buffer
+	err := downloadRangesInOrder(context.Background(), 1000, 64, 3, download, &out)
+	c.Assert(err, chk.Equals, failure)
+	c.Assert(out.Len(), chk.Equals, 128) // only the ranges before the failure
+}
@@ -832,6 +832,9 @@ func getDefaultSyncRawInput(src, dst string) rawSyncCmdArgs {
 		logVerbosity:        defaultLogVerbosityForSync,
 		deleteDestination:   deleteDestination.String(),
 		md5ValidationOption: common.DefaultHashValidationOption.String(),
+		flushPolicy:         common.EFlushPolicy.Never().String(),
+		freeSpaceCheck:      common.EFreeSpaceCheckOption.Warn().String(),
+		excludeHidden:       true,
 	}
 }
 
@@ -844,9 +847,12 @@ func getDefaultCopyRawInput(src string, dst string) rawCopyCmdArgs {
 		blockBlobTier:                  common.EBlockBlobTier.None().String(),
 		pageBlobTier:                   common.EPageBlobTier.None().String(),
 		md5ValidationOption:            common.DefaultHashValidationOption.String(),
+		flushPolicy:                    common.EFlushPolicy.Never().String(),
+		freeSpaceCheck:                 common.EFreeSpaceCheckOption.Warn().String(),
 		s2sInvalidMetadataHandleOption: defaultS2SInvalideMetadataHandleOption.String(),
 		forceWrite:                     common.EOverwriteOption.True().String(),
 		preserveOwner:                  common.PreserveOwnerDefault,
+		excludeHidden:                  true,
 	}
 }
 
@@ -868,9 +874,21 @@ func getDefaultRemoveRawInput(src string) rawCopyCmdArgs {
 		blockBlobTier:                  common.EBlockBlobTier.None().String(),
 		pageBlobTier:                   common.EPageBlobTier.None().String(),
 		md5ValidationOption:            common.DefaultHashValidationOption.String(),
+		flushPolicy:                    common.EFlushPolicy.Never().String(),
+		freeSpaceCheck:                 common.EFreeSpaceCheckOption.Warn().String(),
 		s2sInvalidMetadataHandleOption: defaultS2SInvalideMetadataHandleOption.String(),
 		forceWrite:                     common.EOverwriteOption.True().String(),
 		preserveOwner:                  common.PreserveOwnerDefault,
 		includeDirectoryStubs:          true,
 	}
 }
+
+func getDefaultSetPropertiesRawInput(src string) rawCopyCmdArgs {
+	raw := rawCopyCmdArgs{
+		src:          src,
+		fromTo:       common.EFromTo.BlobNone().String(),
+		logVerbosity: defaultLogVerbosityForSync,
+	}
+	raw.setMandatoryDefaults()
+	return raw
+}
@@ -0,0 +1,116 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type scheduleSuite struct{}
+
+var _ = chk.Suite(&scheduleSuite{})
+
+func (s *scheduleSuite) TestCronExpression(c *chk.C) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2021, 3, day, hour, minute, 0, 0, time.Local) // 1 March 2021 was a Monday
+	}
+
+	e, err := parseCronExpression("0 2 * * *")
+	c.Assert(err, chk.IsNil)
+	c.Assert(e.matches(at(1, 2, 0)), chk.Equals, true)
+	c.Assert(e.matches(at(1, 2, 1)), chk.Equals, false)
+	c.Assert(e.matches(at(1, 3, 0)), chk.Equals, false)
+
+	e, err = parseCronExpression("*/15 9-17 * * 1-5")
+	c.Assert(err, chk.IsNil)
+	c.Assert(e.matches(at(1, 9, 45)), chk.Equals, true)
+	c.Assert(e.matches(at(1, 9, 50)), chk.Equals, false)
+	c.Assert(e.matches(at(6, 9, 45)), chk.Equals, false) // Saturday
+
+	// both days restricted: either one matches, as in cron
+	e, err = parseCronExpression("0 0 15 * 0")
+	c.Assert(err, chk.IsNil)
+	c.Assert(e.matches(at(15, 0, 0)), chk.Equals, true)
+	c.Assert(e.matches(at(7, 0, 0)), chk.Equals, true) // Sunday
+	c.Assert(e.matches(at(8, 0, 0)), chk.Equals, false)
+
+	e, err = parseCronExpression("@weekly")
+	c.Assert(err, chk.IsNil)
+	c.Assert(e.matches(at(7, 0, 0)), chk.Equals, true)
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err = parseCronExpression(bad)
+		c.Assert(err, chk.NotNil, chk.Commentf(bad))
+	}
+}
+
+func (s *scheduleSuite) TestAddAndRemoveSchedules(c *chk.C) {
+	folder, err := ioutil.TempDir("", "schedules")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(folder)
+	path := filepath.Join(folder, scheduleFileName)
+
+	nightly, err := newJobSchedule("nightly", "0 2 * * *", []string{"sync", "/data", "https://account.blob.core.windows.net/container"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(addSchedule(path, nightly), chk.IsNil)
+	c.Assert(addSchedule(path, nightly), chk.NotNil) // names are unique
+
+	hourly, err := newJobSchedule("hourly", "@hourly", []string{"copy", "/logs", "https://account.blob.core.windows.net/logs"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(addSchedule(path, hourly), chk.IsNil)
+
+	schedules, err := loadSchedules(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(schedules, chk.HasLen, 2)
+
+	due := schedulesDue(schedules, time.Date(2021, 3, 1, 2, 0, 0, 0, time.Local))
+	c.Assert(due, chk.HasLen, 2)
+	due = schedulesDue(schedules, time.Date(2021, 3, 1, 3, 0, 0, 0, time.Local))
+	c.Assert(due, chk.HasLen, 1)
+	c.Assert(due[0].Name, chk.Equals, "hourly")
+
+	c.Assert(removeSchedule(path, "nightly"), chk.IsNil)
+	c.Assert(removeSchedule(path, "nightly"), chk.NotNil)
+	schedules, err = loadSchedules(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(schedules, chk.HasLen, 1)
+
+	_, err = newJobSchedule("bad", "0 2 * * *", []string{"login"})
+	c.Assert(err, chk.NotNil)
+	_, err = newJobSchedule("bad", "0 2 * *", []string{"copy"})
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *scheduleSuite) TestSchedulesAreListedRedacted(c *chk.C) {
+	schedule, err := newJobSchedule("nightly", "0 2 * * *", []string{"copy", "/data", "https://account.blob.core.windows.net/container?sv=2019-12-12&sig=secret"})
+	c.Assert(err, chk.IsNil)
+
+	// the JSON output marshals the redacted copies too, so the signature isn't in either output
+	redacted := redactSchedules([]JobSchedule{schedule})
+	c.Assert(redacted[0].Args[2], chk.Not(chk.Matches), ".*secret.*")
+	c.Assert(redacted[0].Args[:2], chk.DeepEquals, []string{"copy", "/data"})
+	c.Assert(schedule.Args[2], chk.Matches, ".*sig=secret") // the stored schedule still has it, to run with
+}
@@ -0,0 +1,55 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type shareSnapshotSuite struct{}
+
+var _ = chk.Suite(&shareSnapshotSuite{})
+
+func (s *shareSnapshotSuite) TestShareSnapshotOnlyAsSource(c *chk.C) {
+	snapshotURL := "https://account.file.core.windows.net/share/dir?sharesnapshot=2021-03-01T00:00:00.0000000Z&sv=2019-12-12&sig=abc"
+	snapshot, err := SplitResourceString(snapshotURL, common.ELocation.File())
+	c.Assert(err, chk.IsNil)
+	c.Assert(shareSnapshotOf(snapshot, common.ELocation.File()), chk.Equals, "2021-03-01T00:00:00.0000000Z")
+	local := common.ResourceString{Value: "/data"}
+	share, err := SplitResourceString("https://account.file.core.windows.net/share2?sv=2019-12-12&sig=abc", common.ELocation.File())
+	c.Assert(err, chk.IsNil)
+	c.Assert(shareSnapshotOf(share, common.ELocation.File()), chk.Equals, "")
+
+	// downloads and copies may read from it
+	c.Assert(validateShareSnapshots(common.EFromTo.FileLocal(), snapshot, local), chk.IsNil)
+	c.Assert(validateShareSnapshots(common.EFromTo.FileFile(), snapshot, share), chk.IsNil)
+
+	// but nothing may write to it
+	c.Assert(validateShareSnapshots(common.EFromTo.FileFile(), share, snapshot), chk.ErrorMatches, ".*can't be the destination.*")
+	c.Assert(validateShareSnapshots(common.EFromTo.LocalFile(), local, snapshot), chk.ErrorMatches, ".*can't be the destination.*")
+	c.Assert(validateShareSnapshots(common.EFromTo.FileTrash(), snapshot, common.ResourceString{}), chk.ErrorMatches, ".*can't be removed or changed.*")
+
+	// and it must name its share
+	account, err := SplitResourceString("https://account.file.core.windows.net/?sharesnapshot=2021-03-01T00:00:00.0000000Z&sv=2019-12-12&sig=abc", common.ELocation.File())
+	c.Assert(err, chk.IsNil)
+	c.Assert(validateShareSnapshots(common.EFromTo.FileLocal(), account, local), chk.ErrorMatches, ".*must name that share.*")
+}
@@ -0,0 +1,54 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type strictModeTestSuite struct{}
+
+var _ = chk.Suite(&strictModeTestSuite{})
+
+func (s *strictModeTestSuite) TestFilteredSkipsAreTolerated(c *chk.C) {
+	summary := common.ListJobSummaryResponse{
+		TransfersCompleted:       10,
+		TransfersSkipped:         4,
+		TransfersSkippedByFilter: 4,
+		HiddenFilesSkipped:       2,
+	}
+	c.Assert(strictModeViolations(summary), chk.Equals, "")
+}
+
+func (s *strictModeTestSuite) TestSkipsAndLostPropertiesAreViolations(c *chk.C) {
+	summary := common.ListJobSummaryResponse{
+		TransfersSkipped:         5,
+		TransfersSkippedByFilter: 2,
+	}
+	c.Assert(strictModeViolations(summary), chk.Equals, "3 transfers were skipped")
+
+	summary.DanglingSymlinksSkipped = 1
+	summary.TransfersWithPropertiesNotPreserved = 2
+	c.Assert(strictModeViolations(summary), chk.Equals,
+		"3 transfers were skipped, and 1 dangling symlinks were skipped, and 2 transfers did not preserve all the properties of their source")
+}
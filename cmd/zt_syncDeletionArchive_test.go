@@ -0,0 +1,73 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type syncDeletionArchiveSuite struct{}
+
+var _ = chk.Suite(&syncDeletionArchiveSuite{})
+
+func (s *syncDeletionArchiveSuite) TestParseDeleteDestinationMoveTo(c *chk.C) {
+	location, ok := parseDeleteDestinationMoveTo("Move-To:archive/deleted")
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(location, chk.Equals, "archive/deleted")
+
+	for _, value := range []string{"true", "false", "prompt", "move", ""} {
+		_, ok = parseDeleteDestinationMoveTo(value)
+		c.Assert(ok, chk.Equals, false)
+	}
+}
+
+func (s *syncDeletionArchiveSuite) TestDeletionArchiveLocation(c *chk.C) {
+	destination := common.ResourceString{Value: "https://account.blob.core.windows.net/container/dir"}
+
+	archive, err := newDeletionArchive("/archive/deleted/", destination, common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(archive.pathFor("sub/file.txt"), chk.Equals, "archive/deleted/sub/file.txt")
+
+	// the same container is fine, as long as the archive is outside of the destination
+	archive, err = newDeletionArchive("container/dir-deleted", destination, common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(archive.pathFor("file.txt"), chk.Equals, "container/dir-deleted/file.txt")
+
+	for _, location := range []string{"container", "container/dir", "container/dir/deleted", ""} {
+		_, err = newDeletionArchive(location, destination, common.ELocation.Blob())
+		c.Assert(err, chk.NotNil)
+	}
+
+	_, err = newDeletionArchive("archive", common.ResourceString{Value: "/tmp/dir"}, common.ELocation.Local())
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *syncDeletionArchiveSuite) TestDeletionArchiveFileShare(c *chk.C) {
+	destination := common.ResourceString{Value: "https://account.file.core.windows.net/share"}
+
+	_, err := newDeletionArchive("share/deleted", destination, common.ELocation.File())
+	c.Assert(err, chk.NotNil)
+
+	archive, err := newDeletionArchive("archive", destination, common.ELocation.File())
+	c.Assert(err, chk.IsNil)
+	c.Assert(archive.pathFor("dir/file.txt"), chk.Equals, "archive/dir/file.txt")
+}
@@ -254,6 +254,43 @@ func (s *cmdIntegrationSuite) TestSyncDownloadWithIncludePatternFlag(c *chk.C) {
 	})
 }
 
+// include-path lists only the given paths, on both the source and the destination
+func (s *cmdIntegrationSuite) TestSyncDownloadWithIncludePathFlag(c *chk.C) {
+	bsu := getBSU()
+
+	// set up the container with numerous blobs
+	containerURL, containerName := createNewContainer(c, bsu)
+	blobList := scenarioHelper{}.generateCommonRemoteScenarioForBlob(c, containerURL, "")
+	defer deleteContainer(c, containerURL)
+	c.Assert(containerURL, chk.NotNil)
+	c.Assert(len(blobList), chk.Not(chk.Equals), 0)
+
+	// add special blobs that we wish to include
+	blobsToInclude := []string{"includeSub/amazing.jpeg", "includeSub/nested/great.pdf", "exactName"}
+	scenarioHelper{}.generateBlobsFromList(c, containerURL, blobsToInclude, blockBlobDefaultData)
+	includeString := "includeSub;exactName;includeSub/"
+
+	// set up the destination with an empty folder
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+
+	// set up interceptor
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	// construct the raw input to simulate user input
+	rawContainerURLWithSAS := scenarioHelper{}.getRawContainerURLWithSAS(c, containerName)
+	raw := getDefaultSyncRawInput(rawContainerURLWithSAS.String(), dstDirName)
+	raw.includePath = includeString
+
+	// the duplicated path must not schedule the same transfers twice
+	runSyncAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+		validateDownloadTransfersAreScheduled(c, "", "", blobsToInclude, mockedRPC)
+	})
+}
+
 // exclude flag limits the scope of source/destination comparison
 func (s *cmdIntegrationSuite) TestSyncDownloadWithExcludePatternFlag(c *chk.C) {
 	bsu := getBSU()
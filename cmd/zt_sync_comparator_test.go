@@ -21,6 +21,7 @@
 package cmd
 
 import (
+	"github.com/Azure/azure-storage-azcopy/common"
 	chk "gopkg.in/check.v1"
 	"time"
 )
@@ -137,3 +138,24 @@ func (s *syncComparatorSuite) TestSyncDestinationComparator(c *chk.C) {
 	c.Assert(dummyCopyScheduler.record[0].md5, chk.DeepEquals, srcMD5)
 	c.Assert(len(dummyCleaner.record), chk.Equals, 0)
 }
+
+func (s *syncComparatorSuite) TestSyncDestinationComparatorPrefersStoredSourceLMT(c *chk.C) {
+	dummyCopyScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	localLMT := time.Now().Add(-time.Hour)
+
+	// set up the indexer as well as the destination comparator
+	indexer := newObjectIndexer()
+	destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process)
+
+	// the local file hasn't changed since it was uploaded, but the blob's own LMT is the time of the upload
+	err := indexer.store(storedObject{name: "test", relativePath: "/usr/test", lastModifiedTime: localLMT})
+	c.Assert(err, chk.IsNil)
+	compareErr := destinationComparator.processIfNecessary(storedObject{name: "test", relativePath: "/usr/test", lastModifiedTime: time.Now().Add(-2 * time.Hour),
+		Metadata: common.Metadata{}.WithSourceLastModifiedTime(localLMT)})
+	c.Assert(compareErr, chk.Equals, nil)
+
+	// verify that nothing is transferred, even though the blob's own LMT is older
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 0)
+	c.Assert(len(dummyCleaner.record), chk.Equals, 0)
+}
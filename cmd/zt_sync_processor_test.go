@@ -134,3 +134,16 @@ func (s *syncProcessorSuite) TestFileDeleter(c *chk.C) {
 	_, err = fileURL.GetProperties(context.Background())
 	c.Assert(err, chk.NotNil)
 }
+
+func (s *syncProcessorSuite) TestSnapshotBeforeDeleteNeedsRemoteDestination(c *chk.C) {
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+
+	raw := getDefaultSyncRawInput("https://account.blob.core.windows.net/container", dstDirName)
+	raw.snapshotBeforeDelete = true
+
+	// there is nothing to snapshot when the extra files are local, so the flag is rejected
+	_, err := raw.cook()
+	c.Assert(err, chk.NotNil)
+	c.Assert(err.Error(), chk.Matches, ".*"+snapshotBeforeDeleteFlag+".*")
+}
@@ -0,0 +1,180 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// how often the changes made to a BufferedMMF are written back to its file
+const bufferedMMFFlushInterval = 2 * time.Second
+
+// the size of the blocks that a BufferedMMF compares, and writes when they have changed
+const bufferedMMFBlockSize = 4096
+
+// BufferedMMF is an IMappedFile that holds the file's contents in ordinary memory, rather than memory mapping it.
+// Since its users change that memory directly, it can't know what has changed. So it keeps a copy of what is in the file,
+// and periodically writes back the blocks that differ from that. If the process stops abruptly, the last few seconds
+// of changes may be lost, which (for plan files) just means some transfers are redone when the job is resumed.
+type BufferedMMF struct {
+	file   *os.File
+	offset int64
+
+	slice []byte
+
+	// what is in the file, so we can tell which blocks have changed. Nil if the file isn't writable
+	saved []byte
+
+	// holds a block while it's being written, so that what's saved is exactly what was written
+	scratch []byte
+
+	// serializes flushes, which happen both in the background and when unmapping
+	flushLock sync.Mutex
+
+	stopFlushing   chan struct{}
+	flusherStopped chan struct{}
+
+	// as for MMF
+	isMapped bool
+	lock     sync.RWMutex
+}
+
+// NewBufferedMMF reads the given range of the file into memory. Like NewMMF, the caller can close the file once this returns
+func NewBufferedMMF(file *os.File, writable bool, offset int64, length int64) (*BufferedMMF, error) {
+	flag := os.O_RDONLY
+	if writable {
+		flag = os.O_RDWR
+	}
+	f, err := os.OpenFile(file.Name(), flag, 0) // our own handle, since the caller's may be closed
+	if err != nil {
+		return nil, err
+	}
+
+	slice := make([]byte, length)
+	if _, err = f.ReadAt(slice, offset); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	m := &BufferedMMF{file: f, offset: offset, slice: slice, isMapped: true}
+	if writable {
+		m.saved = append([]byte(nil), slice...)
+		m.scratch = make([]byte, bufferedMMFBlockSize)
+		m.stopFlushing = make(chan struct{})
+		m.flusherStopped = make(chan struct{})
+		go m.flushPeriodically()
+	}
+	return m, nil
+}
+
+func (m *BufferedMMF) flushPeriodically() {
+	defer close(m.flusherStopped)
+	ticker := time.NewTicker(bufferedMMFFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = m.Flush() // if this fails, the blocks are still unsaved, so we'll try them again next time
+		case <-m.stopFlushing:
+			return
+		}
+	}
+}
+
+// Flush writes the blocks that have changed since they were last written to the file
+func (m *BufferedMMF) Flush() error {
+	m.flushLock.Lock()
+	defer m.flushLock.Unlock()
+
+	for start := 0; start < len(m.saved); start += bufferedMMFBlockSize {
+		end := start + bufferedMMFBlockSize
+		if end > len(m.saved) {
+			end = len(m.saved)
+		}
+
+		// the slice may be changing as we go, so take a copy, and compare and write that
+		block := m.scratch[:end-start]
+		loadBlock(block, m.slice[start:end])
+		if bytes.Equal(block, m.saved[start:end]) {
+			continue
+		}
+		if _, err := m.file.WriteAt(block, m.offset+int64(start)); err != nil {
+			return err
+		}
+		copy(m.saved[start:end], block)
+	}
+	return nil
+}
+
+// loadBlock copies src to dst a word at a time with atomic loads, since the slice's users update it with atomic stores
+// (without taking any lock) while it's being flushed. Blocks start on word boundaries, so only the few bytes at the end
+// of a file whose length isn't a whole number of words are copied normally
+func loadBlock(dst, src []byte) {
+	words := len(src) &^ 3
+	for i := 0; i < words; i += 4 {
+		*(*uint32)(unsafe.Pointer(&dst[i])) = atomic.LoadUint32((*uint32)(unsafe.Pointer(&src[i])))
+	}
+	copy(dst[words:], src[words:])
+}
+
+// Unmap writes any outstanding changes, and closes the file
+func (m *BufferedMMF) Unmap() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var err error
+	if m.saved != nil {
+		close(m.stopFlushing)
+		<-m.flusherStopped
+		err = m.Flush()
+	}
+	closeErr := m.file.Close()
+	m.slice = nil
+	m.saved = nil
+	m.isMapped = false
+	PanicIfErr(err)
+	PanicIfErr(closeErr)
+}
+
+func (m *BufferedMMF) UseMMF() bool {
+	m.lock.RLock()
+	if !m.isMapped {
+		m.lock.RUnlock()
+		return false
+	}
+	return true
+}
+
+// RUnlock unlocks the held lock
+func (m *BufferedMMF) UnuseMMF() {
+	m.lock.RUnlock()
+}
+
+// Slice() returns the in-memory contents of the file
+func (m *BufferedMMF) Slice() []byte {
+	return m.slice
+}
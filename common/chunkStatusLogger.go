@@ -268,7 +268,7 @@ func NewChunkStatusLogger(jobID JobID, cpuMon CPUMonitor, logFileFolder string,
 		cpuMonitor:     cpuMon,
 	}
 	if enableOutput {
-		chunkLogPath := path.Join(logFileFolder, jobID.String()+"-chunks.log") // its a CSV, but using log extension for consistency with other files in the directory
+		chunkLogPath := path.Join(logFileFolder, JobLogFileName(jobID, "-chunks")) // its a CSV, but using log extension for consistency with other files in the directory
 		go logger.main(chunkLogPath)
 	}
 	return logger
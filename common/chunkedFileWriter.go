@@ -60,7 +60,14 @@ type chunkedFileWriter struct {
 	// refer to: https://golang.org/pkg/sync/atomic/#pkg-note-BUG
 	// all time received count for this instance
 	totalChunkReceiveMilliseconds int64
+	totalChunkSaveMicroseconds    int64
 	totalReceivedChunkCount       int32
+	totalSavedChunkCount          int32
+
+	// how far into the file we have written, and a signal each time that moves forward. Only maintained when maxChunksAhead is set
+	atomicNextOffsetToSave int64
+	chunkSaved             chan struct{}
+	atomicWriterFailed     int32
 
 	// the file we are writing to (type as interface to somewhat abstract away io.File - e.g. for unit testing)
 	file io.WriteCloser
@@ -93,6 +100,19 @@ type chunkedFileWriter struct {
 	md5ValidationOption HashValidationOption
 
 	sourceMd5Exists bool
+
+	// if not zero, chunks are only scheduled when they are no more than reorderWindow chunks ahead of what has been
+	// written, and this is the most that window may grow to. That bounds the reorder buffer of each file, so that writes
+	// are strictly sequential without much waiting
+	maxChunksAhead uint32
+
+	// when written data is committed to durable storage. Only PerChunk is acted on here; the file does the rest when it's closed
+	flushPolicy FlushPolicy
+}
+
+// implemented by files (and writers that wrap them) that can commit what has been written to durable storage
+type fileSyncer interface {
+	Sync() error
 }
 
 type fileChunk struct {
@@ -100,7 +120,7 @@ type fileChunk struct {
 	data []byte
 }
 
-func NewChunkedFileWriter(ctx context.Context, slicePool ByteSlicePooler, cacheLimiter CacheLimiter, chunkLogger ChunkStatusLogger, file io.WriteCloser, numChunks uint32, maxBodyRetries int, md5ValidationOption HashValidationOption, sourceMd5Exists bool) ChunkedFileWriter {
+func NewChunkedFileWriter(ctx context.Context, slicePool ByteSlicePooler, cacheLimiter CacheLimiter, chunkLogger ChunkStatusLogger, file io.WriteCloser, numChunks uint32, maxBodyRetries int, md5ValidationOption HashValidationOption, sourceMd5Exists bool, maxChunksAhead uint32, flushPolicy FlushPolicy) ChunkedFileWriter {
 	// Set max size for buffered channel. The upper limit here is believed to be generous, given worker routine drains it constantly.
 	// Use num chunks in file if lower than the upper limit, to prevent allocating RAM for lots of large channel buffers when dealing with
 	// very large numbers of very small files.
@@ -117,6 +137,9 @@ func NewChunkedFileWriter(ctx context.Context, slicePool ByteSlicePooler, cacheL
 		maxRetryPerDownloadBody: maxBodyRetries,
 		md5ValidationOption:     md5ValidationOption,
 		sourceMd5Exists:         sourceMd5Exists,
+		maxChunksAhead:          maxChunksAhead,
+		flushPolicy:             flushPolicy,
+		chunkSaved:              make(chan struct{}, 1),
 	}
 	go w.workerRoutine(ctx)
 	return w
@@ -126,6 +149,13 @@ var ChunkWriterAlreadyFailed = errors.New("chunk Writer already failed")
 
 const maxDesirableActiveChunks = 20 // TODO: can we find a sensible way to remove the hard-coded count threshold here?
 
+// bounds of the reorder window, before and after it's sized from what has been observed
+const (
+	initialChunksAhead         = 16
+	minChunksAhead             = 2
+	chunksObservedToSizeWindow = 4
+)
+
 // Waits until we have enough RAM, within our pre-determined allocation, to accommodate the chunk.
 // After any necessary wait, it updates the count of scheduled-but-unsaved bytes
 // Note: we considered tracking only received-but-unsaved-bytes (i.e. increment the count at time of making the
@@ -134,6 +164,10 @@ const maxDesirableActiveChunks = 20 // TODO: can we find a sensible way to remov
 // Is here, as method of this struct, for symmetry with the point where we remove it's count
 // from the cache limiter, which is also in this struct.
 func (w *chunkedFileWriter) WaitToScheduleChunk(ctx context.Context, id ChunkID, chunkSize int64) error {
+	if err := w.waitUntilWithinReorderWindow(ctx, id, chunkSize); err != nil {
+		return err
+	}
+
 	w.chunkLogger.LogChunkStatus(id, EWaitReason.RAMToSchedule())
 	err := w.cacheLimiter.WaitUntilAdd(ctx, chunkSize, w.shouldUseRelaxedRamThreshold)
 	if err == nil {
@@ -142,6 +176,57 @@ func (w *chunkedFileWriter) WaitToScheduleChunk(ctx context.Context, id ChunkID,
 	return err
 }
 
+// reorderWindow returns how many chunks may be scheduled ahead of what has been written.
+// Each chunk arrives over its own connection, so to keep the file being written without gaps, enough chunks must be
+// in flight that, at the observed time to receive one, they arrive as fast as they're saved. Until a few chunks have
+// been received and saved, a fixed window is used. Either way, it's no more than maxChunksAhead.
+func (w *chunkedFileWriter) reorderWindow() int64 {
+	maxWindow := int64(w.maxChunksAhead)
+	received := int64(atomic.LoadInt32(&w.totalReceivedChunkCount))
+	saved := int64(atomic.LoadInt32(&w.totalSavedChunkCount))
+	if received < chunksObservedToSizeWindow || saved < chunksObservedToSizeWindow {
+		if maxWindow < initialChunksAhead {
+			return maxWindow
+		}
+		return initialChunksAhead
+	}
+
+	avgReceiveMicroseconds := atomic.LoadInt64(&w.totalChunkReceiveMilliseconds) * 1000 / received
+	avgSaveMicroseconds := atomic.LoadInt64(&w.totalChunkSaveMicroseconds) / saved
+	if avgSaveMicroseconds < 1 {
+		avgSaveMicroseconds = 1
+	}
+	window := (avgReceiveMicroseconds+avgSaveMicroseconds-1)/avgSaveMicroseconds + 1 // one spare, for the chunk being saved
+	if window < minChunksAhead {
+		window = minChunksAhead
+	}
+	if window > maxWindow {
+		window = maxWindow
+	}
+	return window
+}
+
+// waitUntilWithinReorderWindow blocks until the chunk is within the reorder window, beyond what has already been written
+func (w *chunkedFileWriter) waitUntilWithinReorderWindow(ctx context.Context, id ChunkID, chunkSize int64) error {
+	if w.maxChunksAhead == 0 {
+		return nil
+	}
+	w.chunkLogger.LogChunkStatus(id, EWaitReason.PriorChunk())
+	for id.OffsetInFile() >= atomic.LoadInt64(&w.atomicNextOffsetToSave)+w.reorderWindow()*chunkSize {
+		if atomic.LoadInt32(&w.atomicWriterFailed) == 1 {
+			return ChunkWriterAlreadyFailed // nothing more will be written, so we'd wait forever
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.chunkSaved:
+		case <-time.After(time.Second):
+			// just to check for failure of the writer
+		}
+	}
+	return nil
+}
+
 // Threadsafe method to enqueue a new chunk for processing
 func (w *chunkedFileWriter) EnqueueChunk(ctx context.Context, id ChunkID, chunkSize int64, chunkContents io.Reader, retryable bool) error {
 
@@ -236,6 +321,7 @@ func (w *chunkedFileWriter) workerRoutine(ctx context.Context) {
 				return
 			}
 		case <-ctx.Done(): // If cancelled out in the middle of enqueuing chunks OR processing chunks, they will both cleanly cancel out and we'll get back to here.
+			atomic.StoreInt32(&w.atomicWriterFailed, 1)
 			w.failureError <- ctx.Err()
 			return
 		}
@@ -248,6 +334,7 @@ func (w *chunkedFileWriter) workerRoutine(ctx context.Context) {
 		w.setStatusForContiguousAvailableChunks(unsavedChunksByFileOffset, nextOffsetToSave, ctx) // update states of those that have all their prior ones already here
 		err := w.sequentiallyProcessAvailableChunks(unsavedChunksByFileOffset, &nextOffsetToSave, md5Hasher, ctx)
 		if err != nil {
+			atomic.StoreInt32(&w.atomicWriterFailed, 1)
 			w.failureError <- err
 			close(w.failureError) // must close because many goroutines may be calling the public methods, and all need to be able to tell there's been an error, even tho only one will get the actual error
 			return                // no point in processing any more after a failure
@@ -278,6 +365,15 @@ func (w *chunkedFileWriter) sequentiallyProcessAvailableChunks(unsavedChunksByFi
 		if err != nil {
 			return err
 		}
+
+		// let any chunk waiting for the reorder window know that it has moved
+		if w.maxChunksAhead > 0 {
+			atomic.StoreInt64(&w.atomicNextOffsetToSave, *nextOffsetToSave)
+			select {
+			case w.chunkSaved <- struct{}{}:
+			default:
+			}
+		}
 	}
 }
 
@@ -313,6 +409,11 @@ func (w *chunkedFileWriter) saveOneChunk(chunk fileChunk, md5Hasher hash.Hash) e
 	const maxWriteSize = 1024 * 1024
 
 	w.chunkLogger.LogChunkStatus(chunk.id, EWaitReason.DiskIO())
+	saveStart := time.Now()
+	defer func() {
+		atomic.AddInt32(&w.totalSavedChunkCount, 1)
+		atomic.AddInt64(&w.totalChunkSaveMicroseconds, time.Since(saveStart).Nanoseconds()/1000)
+	}()
 
 	// in some cases, e.g. Storage Spaces in Azure VMs, chopping up the writes helps perf. TODO: look into the reasons why it helps
 	for i := 0; i < len(chunk.data); i += maxWriteSize {
@@ -329,6 +430,13 @@ func (w *chunkedFileWriter) saveOneChunk(chunk fileChunk, md5Hasher hash.Hash) e
 		}
 	}
 
+	if w.flushPolicy == EFlushPolicy.PerChunk() {
+		// writers that can't be synced, such as the one that decompresses, are left to sync when the file is closed
+		if syncer, ok := w.file.(fileSyncer); ok {
+			return syncer.Sync()
+		}
+	}
+
 	return nil
 }
 
@@ -32,6 +32,7 @@ import (
 
 	"github.com/Azure/azure-storage-azcopy/azbfs"
 	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/minio/minio-go"
 	"github.com/minio/minio-go/pkg/credentials"
@@ -89,11 +90,40 @@ func (o CredentialOpOptions) cancel() {
 	}
 }
 
+// SharedKeyEnvVarsExist reports whether both ACCOUNT_NAME and ACCOUNT_KEY are set in the environment.
+func SharedKeyEnvVarsExist() bool {
+	name, key := getSharedKeyFromEnvVars()
+	return name != "" && key != ""
+}
+
+// SharedKeyEnvVarsMatchAccount reports whether ACCOUNT_NAME names the given account.
+// Credentials created for SharedKey always sign with ACCOUNT_NAME, so SharedKey must only be chosen for resources in that account.
+func SharedKeyEnvVarsMatchAccount(accountName string) bool {
+	name, _ := getSharedKeyFromEnvVars()
+	return accountName != "" && strings.EqualFold(name, accountName)
+}
+
+// getSharedKeyFromEnvVars reads the account name and key used for SharedKey authentication.
+// Account keys are deliberately never accepted on the command line.
+func getSharedKeyFromEnvVars() (name, key string) {
+	name = lcm.GetEnvironmentVariable(EEnvironmentVariable.AccountName())
+	key = lcm.GetEnvironmentVariable(EEnvironmentVariable.AccountKey())
+	return
+}
+
+// resolveSharedKeyFromEnvVars is like getSharedKeyFromEnvVars, but fetches the key from Key Vault if ACCOUNT_KEY references a secret there
+func resolveSharedKeyFromEnvVars() (name, key string, err error) {
+	name = lcm.GetEnvironmentVariable(EEnvironmentVariable.AccountName())
+	key, err = GetSecretEnvironmentVariable(EEnvironmentVariable.AccountKey())
+	return
+}
+
 // CreateBlobCredential creates Blob credential according to credential info.
 func CreateBlobCredential(ctx context.Context, credInfo CredentialInfo, options CredentialOpOptions) azblob.Credential {
 	credential := azblob.NewAnonymousCredential()
 
-	if credInfo.CredentialType == ECredentialType.OAuthToken() {
+	switch credInfo.CredentialType {
+	case ECredentialType.OAuthToken():
 		if credInfo.OAuthTokenInfo.IsEmpty() {
 			options.panicError(errors.New("invalid state, cannot get valid OAuth token information"))
 		}
@@ -104,11 +134,48 @@ func CreateBlobCredential(ctx context.Context, credInfo CredentialInfo, options
 			func(credential azblob.TokenCredential) time.Duration {
 				return refreshBlobToken(ctx, credInfo.OAuthTokenInfo, credential, options)
 			})
+
+	case ECredentialType.SharedKey():
+		name, key, err := resolveSharedKeyFromEnvVars()
+		if err != nil {
+			options.panicError(err)
+		}
+		if name == "" || key == "" {
+			options.panicError(errors.New("ACCOUNT_NAME and ACCOUNT_KEY environment variables must be set before creating the blob SharedKey credential"))
+		}
+
+		sharedKey, err := azblob.NewSharedKeyCredential(name, key)
+		if err != nil {
+			options.panicError(fmt.Errorf("cannot create the blob SharedKey credential: %v", err))
+		}
+		return sharedKey
 	}
 
 	return credential
 }
 
+// CreateFileCredential creates Azure Files credential according to credential info.
+// Azure Files supports SAS (anonymous) and SharedKey only.
+func CreateFileCredential(ctx context.Context, credInfo CredentialInfo, options CredentialOpOptions) azfile.Credential {
+	if credInfo.CredentialType == ECredentialType.SharedKey() {
+		name, key, err := resolveSharedKeyFromEnvVars()
+		if err != nil {
+			options.panicError(err)
+		}
+		if name == "" || key == "" {
+			options.panicError(errors.New("ACCOUNT_NAME and ACCOUNT_KEY environment variables must be set before creating the file SharedKey credential"))
+		}
+
+		sharedKey, err := azfile.NewSharedKeyCredential(name, key)
+		if err != nil {
+			options.panicError(fmt.Errorf("cannot create the file SharedKey credential: %v", err))
+		}
+		return sharedKey
+	}
+
+	return azfile.NewAnonymousCredential()
+}
+
 // refreshPolicyHalfOfExpiryWithin is used for calculating next refresh time,
 // it checkes how long it will be before the token get expired, and use half of the value as
 // duration to wait.
@@ -175,8 +242,10 @@ func CreateBlobFSCredential(ctx context.Context, credInfo CredentialInfo, option
 
 	case ECredentialType.SharedKey():
 		// Get the Account Name and Key variables from environment
-		name := lcm.GetEnvironmentVariable(EEnvironmentVariable.AccountName())
-		key := lcm.GetEnvironmentVariable(EEnvironmentVariable.AccountKey())
+		name, key, err := resolveSharedKeyFromEnvVars()
+		if err != nil {
+			options.panicError(err)
+		}
 		// If the ACCOUNT_NAME and ACCOUNT_KEY are not set in environment variables
 		if name == "" || key == "" {
 			options.panicError(errors.New("ACCOUNT_NAME and ACCOUNT_KEY environment variables must be set before creating the blobfs SharedKey credential"))
@@ -200,7 +269,10 @@ func CreateS3Credential(ctx context.Context, credInfo CredentialInfo, options Cr
 	switch credInfo.CredentialType {
 	case ECredentialType.S3AccessKey():
 		accessKeyID := glcm.GetEnvironmentVariable(EEnvironmentVariable.AWSAccessKeyID())
-		secretAccessKey := glcm.GetEnvironmentVariable(EEnvironmentVariable.AWSSecretAccessKey())
+		secretAccessKey, err := GetSecretEnvironmentVariable(EEnvironmentVariable.AWSSecretAccessKey())
+		if err != nil {
+			return nil, err
+		}
 		sessionToken := glcm.GetEnvironmentVariable(EEnvironmentVariable.AwsSessionToken())
 
 		if accessKeyID == "" || secretAccessKey == "" {
@@ -294,8 +366,33 @@ func CreateGCPClient(ctx context.Context) (*gcpUtils.Client, error) {
 	return client, err
 }
 
+// gcpXMLAPIEndpoint serves GCS's S3 compatible XML API, which is the only one of its APIs that HMAC keys can be used with
+const gcpXMLAPIEndpoint = "storage.googleapis.com"
+
+// UseGCPHMACKey says whether GCS is to be read with the HMAC key in the environment. Application credentials take
+// precedence, when both are given
+func UseGCPHMACKey() bool {
+	glcm := GetLifecycleMgr()
+	return glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleAppCredentials()) == "" &&
+		glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleHMACAccessID()) != "" &&
+		glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleHMACSecret()) != ""
+}
+
+// CreateGCPHMACClient creates a client for GCS's XML API, authorized with the HMAC key in the environment.
+// It's the client we use for S3, which knows how to sign requests for GCS
+func CreateGCPHMACClient() (*minio.Client, error) {
+	glcm := GetLifecycleMgr()
+	accessID := glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleHMACAccessID())
+	secret := glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleHMACSecret())
+	if accessID == "" || secret == "" {
+		return nil, errors.New("AZCOPY_GCS_HMAC_ACCESS_ID and AZCOPY_GCS_HMAC_SECRET environment variables must be set before using a GCS HMAC key")
+	}
+	return minio.NewWithCredentials(gcpXMLAPIEndpoint, credentials.NewStaticV4(accessID, secret, ""), true, "auto")
+}
+
 type GCPClientFactory struct {
 	gcpClients map[CredentialInfo]*gcpUtils.Client
+	hmacClient *minio.Client
 	lock       sync.RWMutex
 }
 
@@ -326,3 +423,24 @@ func (f *GCPClientFactory) GetGCPClient(ctx context.Context, credInfo Credential
 		return gcpClient, nil
 	}
 }
+
+// GetGCPHMACClient gets the client for GCS's XML API, creating it the first time
+func (f *GCPClientFactory) GetGCPHMACClient() (*minio.Client, error) {
+	f.lock.RLock()
+	hmacClient := f.hmacClient
+	f.lock.RUnlock()
+
+	if hmacClient != nil {
+		return hmacClient, nil
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.hmacClient == nil {
+		newHMACClient, err := CreateGCPHMACClient()
+		if err != nil {
+			return nil, err
+		}
+		f.hmacClient = newHMACClient
+	}
+	return f.hmacClient, nil
+}
@@ -0,0 +1,273 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"io"
+	"math/bits"
+	"os"
+	"sync"
+	"unsafe"
+)
+
+// DirectIOAlignment is the alignment, of file offsets, lengths and memory buffers, that is used for unbuffered IO.
+// It's a multiple of the sector size of all common disks, whether they have 512 byte or 4 KiB sectors
+const DirectIOAlignment = 4096
+
+// largest size of the staging buffer used by directIOWriter. Writes are passed to the OS in blocks of this size
+const directIOWriteBufferSize = 4 * 1024 * 1024
+
+// NewAlignedBuffer returns a slice of the given size whose first byte is aligned to DirectIOAlignment,
+// as required for the memory that is used in unbuffered IO
+func NewAlignedBuffer(size int) []byte {
+	raw := make([]byte, size+DirectIOAlignment)
+	shift := 0
+	if rem := int(uintptr(unsafe.Pointer(&raw[0])) & (DirectIOAlignment - 1)); rem != 0 {
+		shift = DirectIOAlignment - rem
+	}
+	return raw[shift : shift+size : shift+size]
+}
+
+func alignDown(n int64) int64 {
+	return n &^ (DirectIOAlignment - 1)
+}
+
+func alignUp(n int64) int64 {
+	return alignDown(n + DirectIOAlignment - 1)
+}
+
+// aligned buffers for directIOReaderAt, shared by all files since chunks tend to be of similar sizes
+var directIOReadBuffers = sync.Pool{}
+
+// directIOReaderAt reads from a file that was opened for unbuffered IO. Since the OS will only do reads whose
+// offset and length are aligned, it reads the aligned range that covers what was asked for, into an aligned buffer,
+// and copies the requested part out of that
+type directIOReaderAt struct {
+	file CloseableReaderAt
+}
+
+// NewDirectIOReaderAt wraps a file that was opened with OpenFileForDirectIO, so that it can be read at any offset and length
+func NewDirectIOReaderAt(file *os.File) CloseableReaderAt {
+	return &directIOReaderAt{file: file}
+}
+
+func (r *directIOReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	start := alignDown(off)
+	end := alignUp(off + int64(len(p)))
+	bufPtr := getDirectIOReadBuffer(int(end - start))
+	defer directIOReadBuffers.Put(bufPtr)
+	buf := *bufPtr
+
+	// Reads end early at the end of the file, and a read of an unaligned length is fine there, since it's only
+	// the start of each read (and the memory) that the OS checks at the end of a file
+	n, err := r.file.ReadAt(buf, start)
+	skip := int(off - start)
+	if n <= skip {
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	copied := copy(p, buf[skip:n])
+	if copied < len(p) {
+		if err == nil {
+			err = io.EOF
+		}
+		return copied, err
+	}
+	return copied, nil
+}
+
+func getDirectIOReadBuffer(size int) *[]byte {
+	if b, ok := directIOReadBuffers.Get().(*[]byte); ok && cap(*b) >= size {
+		*b = (*b)[:size]
+		return b
+	}
+	b := NewAlignedBuffer(size)
+	return &b
+}
+
+func (r *directIOReaderAt) Close() error {
+	return r.file.Close()
+}
+
+// staging buffers for directIOWriter, pooled by size. Their sizes are powers of two, from DirectIOAlignment to directIOWriteBufferSize,
+// and the pool for size DirectIOAlignment<<i is directIOWriteBuffers[i]
+var directIOWriteBuffers [directIOWriteBufferClasses]sync.Pool
+
+const directIOWriteBufferClasses = 11 // i.e. 4 KiB << 10 == 4 MiB
+
+// directIOWriteBufferSizeFor returns the size of staging buffer to use for a file of the given size, which is never more than the file needs.
+// A size of 0 or less means the final size is not known
+func directIOWriteBufferSizeFor(fileSize int64) int {
+	if fileSize <= 0 || fileSize >= directIOWriteBufferSize {
+		return directIOWriteBufferSize
+	}
+	return int(alignUp(fileSize))
+}
+
+// getDirectIOWriteBuffer takes a buffer of at least the given size from the pool of the smallest class that's big enough
+func getDirectIOWriteBuffer(size int) *[]byte {
+	class := bits.Len(uint((size - 1) / DirectIOAlignment))
+	if b, ok := directIOWriteBuffers[class].Get().(*[]byte); ok {
+		*b = (*b)[:size]
+		return b
+	}
+	b := NewAlignedBuffer(DirectIOAlignment << class)[:size]
+	return &b
+}
+
+func putDirectIOWriteBuffer(b *[]byte) {
+	class := bits.Len(uint((cap(*b) - 1) / DirectIOAlignment))
+	directIOWriteBuffers[class].Put(b)
+}
+
+// directIOWriter writes, sequentially, to a file that was opened for unbuffered IO.
+// Since the OS will only do aligned writes, it collects what's written into an aligned staging buffer,
+// and writes that out whenever it's full. The last, partial, block is padded to the alignment when it's written
+// on Close, and the file is then truncated back to the length that was actually written to it.
+type directIOWriter struct {
+	file         DirectIOFile
+	bufferPtr    *[]byte
+	buffer       []byte
+	used         int
+	bytesWritten int64
+	cacheLimiter CacheLimiter // the staging buffer counts towards the RAM in use, like the chunks themselves
+}
+
+// DirectIOFile is the part of os.File that is needed to write a file with NewDirectIOWriter
+type DirectIOFile interface {
+	io.WriteCloser
+	Truncate(size int64) error
+}
+
+// NewDirectIOWriter wraps a file that was created for unbuffered IO, so that it can be written with writes of any length.
+// The file must be positioned at its start. fileSize, if known, keeps the staging buffer no larger than the file needs,
+// and the buffer is charged to cacheLimiter (if there is one) until the writer is closed
+func NewDirectIOWriter(ctx context.Context, file DirectIOFile, fileSize int64, cacheLimiter CacheLimiter) (io.WriteCloser, error) {
+	return newDirectIOWriter(ctx, file, fileSize, cacheLimiter)
+}
+
+func newDirectIOWriter(ctx context.Context, file DirectIOFile, fileSize int64, cacheLimiter CacheLimiter) (*directIOWriter, error) {
+	size := directIOWriteBufferSizeFor(fileSize)
+	if cacheLimiter != nil {
+		// like the chunks that are already in RAM, the buffer may use the extra room above the strict limit,
+		// since the file can't make progress without it
+		if err := cacheLimiter.WaitUntilAdd(ctx, int64(size), func() bool { return true }); err != nil {
+			return nil, err
+		}
+	}
+
+	bufferPtr := getDirectIOWriteBuffer(size)
+	return &directIOWriter{file: file, bufferPtr: bufferPtr, buffer: *bufferPtr, cacheLimiter: cacheLimiter}, nil
+}
+
+func (w *directIOWriter) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		n := copy(w.buffer[w.used:], p)
+		w.used += n
+		w.bytesWritten += int64(n)
+		total += n
+		p = p[n:]
+		if w.used == len(w.buffer) {
+			if err := w.flush(len(w.buffer)); err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+func (w *directIOWriter) flush(length int) error {
+	_, err := w.file.Write(w.buffer[:length])
+	w.used = 0
+	return err
+}
+
+// Sync commits what has been written to the file so far, if the file supports that, including what's still in the staging buffer.
+// The whole aligned blocks are written out. The partial block after them is written padded, and if the file can seek,
+// the file's position is moved back to the start of that block, so that it's written again, in full, once the rest of it arrives.
+// (Otherwise the partial block is only written when the writer is closed, so the file should also be synced then)
+func (w *directIOWriter) Sync() error {
+	syncer, ok := w.file.(fileSyncer)
+	if !ok {
+		return nil
+	}
+
+	if aligned := int(alignDown(int64(w.used))); aligned > 0 {
+		remainder := w.used - aligned
+		if _, err := w.file.Write(w.buffer[:aligned]); err != nil {
+			return err
+		}
+		copy(w.buffer, w.buffer[aligned:w.used])
+		w.used = remainder
+	}
+
+	if seeker, ok := w.file.(io.Seeker); ok && w.used > 0 {
+		padded := int(alignUp(int64(w.used)))
+		for i := w.used; i < padded; i++ {
+			w.buffer[i] = 0
+		}
+		if _, err := w.file.Write(w.buffer[:padded]); err != nil {
+			return err
+		}
+		if _, err := seeker.Seek(-int64(padded), io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+
+	return syncer.Sync()
+}
+
+func (w *directIOWriter) Close() error {
+	var err error
+	if w.used > 0 {
+		padded := int(alignUp(int64(w.used)))
+		for i := w.used; i < padded; i++ {
+			w.buffer[i] = 0
+		}
+		err = w.flush(padded)
+	}
+	if err == nil {
+		err = w.file.Truncate(w.bytesWritten) // remove the padding, and anything beyond our data that was pre-allocated
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.releaseBuffer()
+	return err
+}
+
+func (w *directIOWriter) releaseBuffer() {
+	if w.bufferPtr == nil {
+		return
+	}
+	putDirectIOWriteBuffer(w.bufferPtr)
+	if w.cacheLimiter != nil {
+		w.cacheLimiter.Remove(int64(len(w.buffer)))
+	}
+	w.bufferPtr, w.buffer = nil, nil
+}
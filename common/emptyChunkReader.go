@@ -57,6 +57,10 @@ func (cr *emptyChunkReader) HasPrefetchedEntirelyZeros() bool {
 	return false // we don't have any zeros (or anything else for that matter)
 }
 
+func (cr *emptyChunkReader) PrefetchedZeroRuns(alignment, minLength int64) []ZeroRun {
+	return nil
+}
+
 func (cr *emptyChunkReader) Length() int64 {
 	return 0
 }
@@ -38,17 +38,33 @@ type EnvironmentVariable struct {
 var VisibleEnvironmentVariables = []EnvironmentVariable{
 	EEnvironmentVariable.LogLocation(),
 	EEnvironmentVariable.JobPlanLocation(),
+	EEnvironmentVariable.LogNameTemplate(),
 	EEnvironmentVariable.ConcurrencyValue(),
 	EEnvironmentVariable.TransferInitiationPoolSize(),
+	EEnvironmentVariable.SmallFilePoolSize(),
+	EEnvironmentVariable.SmallFileThreshold(),
 	EEnvironmentVariable.EnumerationPoolSize(),
+	EEnvironmentVariable.SchedulingFairness(),
 	EEnvironmentVariable.DisableHierarchicalScanning(),
 	EEnvironmentVariable.ParallelStatFiles(),
 	EEnvironmentVariable.BufferGB(),
 	EEnvironmentVariable.AWSAccessKeyID(),
 	EEnvironmentVariable.AWSSecretAccessKey(),
 	EEnvironmentVariable.GoogleAppCredentials(),
+	EEnvironmentVariable.GoogleHMACAccessID(),
+	EEnvironmentVariable.GoogleHMACSecret(),
 	EEnvironmentVariable.ShowPerfStates(),
 	EEnvironmentVariable.PacePageBlobs(),
+	EEnvironmentVariable.HedgeChunkRequests(),
+	EEnvironmentVariable.ReadFromSecondary(),
+	EEnvironmentVariable.MinChunkThroughput(),
+	EEnvironmentVariable.MinChunkThroughputPeriod(),
+	EEnvironmentVariable.SequentialWrites(),
+	EEnvironmentVariable.DirectIO(),
+	EEnvironmentVariable.ReadSourceOnce(),
+	EEnvironmentVariable.WarmConnections(),
+	EEnvironmentVariable.PlanFileMapping(),
+	EEnvironmentVariable.MaxMappedPlanFiles(),
 	EEnvironmentVariable.AutoTuneToCpu(),
 	EEnvironmentVariable.CacheProxyLookup(),
 	EEnvironmentVariable.DefaultServiceApiVersion(),
@@ -65,6 +81,8 @@ var VisibleEnvironmentVariables = []EnvironmentVariable{
 	EEnvironmentVariable.ManagedIdentityClientID(),
 	EEnvironmentVariable.ManagedIdentityObjectID(),
 	EEnvironmentVariable.ManagedIdentityResourceString(),
+	EEnvironmentVariable.AccountName(),
+	EEnvironmentVariable.AccountKey(),
 }
 
 var EEnvironmentVariable = EnvironmentVariable{}
@@ -108,7 +126,7 @@ func (EnvironmentVariable) ApplicationID() EnvironmentVariable {
 func (EnvironmentVariable) ClientSecret() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AZCOPY_SPA_CLIENT_SECRET",
-		Description: "The Azure Active Directory client secret used for Service Principal authentication",
+		Description: "The Azure Active Directory client secret used for Service Principal authentication. May instead be the URI of an Azure Key Vault secret (e.g. https://myvault.vault.azure.net/secrets/mysecret), which is fetched using the machine's managed identity.",
 		Hidden:      true,
 	}
 }
@@ -123,7 +141,7 @@ func (EnvironmentVariable) CertificatePath() EnvironmentVariable {
 func (EnvironmentVariable) CertificatePassword() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AZCOPY_SPA_CERT_PASSWORD",
-		Description: "The password used to decrypt the certificate used for Service Principal authentication.",
+		Description: "The password used to decrypt the certificate used for Service Principal authentication. May instead be the URI of an Azure Key Vault secret, which is fetched using the machine's managed identity.",
 		Hidden:      true,
 	}
 }
@@ -172,6 +190,28 @@ func (EnvironmentVariable) TransferInitiationPoolSize() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) SmallFilePoolSize() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_CONCURRENT_SMALL_FILES",
+		Description: "Overrides the number of small files (see AZCOPY_SMALL_FILE_THRESHOLD) that are uploaded concurrently. They have their own pool, separate from the one that sends the chunks of larger files.",
+	}
+}
+
+func (EnvironmentVariable) SmallFileThreshold() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_SMALL_FILE_THRESHOLD",
+		Description: "Uploaded files smaller than this many bytes are sent by the small-file pool (see AZCOPY_CONCURRENT_SMALL_FILES), and block blobs among them with a single request. Default is 1048576 (1 MiB). Set to 0 to send all files with the main pool.",
+	}
+}
+
+func (EnvironmentVariable) SchedulingFairness() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_SCHEDULING_FAIRNESS",
+		Description:  "How the transfers and chunks of different job parts share the workers. If 'FIFO', they are worked through in the order they were scheduled. If 'RoundRobin', the parts with work waiting take turns, so that a big part near the start of a job doesn't hold up the files in later parts. Default is FIFO.",
+		DefaultValue: "FIFO",
+	}
+}
+
 const azCopyConcurrentScan = "AZCOPY_CONCURRENT_SCAN"
 
 func (EnvironmentVariable) EnumerationPoolSize() EnvironmentVariable {
@@ -215,14 +255,21 @@ func (EnvironmentVariable) CacheProxyLookup() EnvironmentVariable {
 func (EnvironmentVariable) LogLocation() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AZCOPY_LOG_LOCATION",
-		Description: "Overrides where the log files are stored, to avoid filling up a disk.",
+		Description: "Overrides where the log files are stored, to avoid filling up a disk. The --log-location flag takes precedence.",
+	}
+}
+
+func (EnvironmentVariable) LogNameTemplate() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_LOG_NAME_TEMPLATE",
+		Description: "Overrides how log files are named. May contain {jobid} (required), {date} and {verb}, e.g. {date}-{verb}-{jobid}. The --log-name-template flag takes precedence.",
 	}
 }
 
 func (EnvironmentVariable) JobPlanLocation() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AZCOPY_JOB_PLAN_LOCATION",
-		Description: "Overrides where the job plan files (used for progress tracking and resuming) are stored, to avoid filling up a disk.",
+		Description: "Overrides where the job plan files (used for progress tracking and resuming) are stored, to avoid filling up a disk. The --plan-file-location flag takes precedence.",
 	}
 }
 
@@ -234,13 +281,17 @@ func (EnvironmentVariable) BufferGB() EnvironmentVariable {
 }
 
 func (EnvironmentVariable) AccountName() EnvironmentVariable {
-	return EnvironmentVariable{Name: "ACCOUNT_NAME"}
+	return EnvironmentVariable{
+		Name:        "ACCOUNT_NAME",
+		Description: "The storage account name used for SharedKey authentication, together with ACCOUNT_KEY. Intended for emulators and legacy automation that cannot use SAS or Azure AD.",
+	}
 }
 
 func (EnvironmentVariable) AccountKey() EnvironmentVariable {
 	return EnvironmentVariable{
-		Name:   "ACCOUNT_KEY",
-		Hidden: true,
+		Name:        "ACCOUNT_KEY",
+		Description: "The storage account key used for SharedKey authentication. Grants full access to the account, so prefer SAS or Azure AD wherever possible. May instead be the URI of an Azure Key Vault secret, which is fetched using the machine's managed identity.",
+		Hidden:      true,
 	}
 }
 
@@ -259,6 +310,83 @@ func (EnvironmentVariable) PacePageBlobs() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) HedgeChunkRequests() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_HEDGE_CHUNK_REQUESTS",
+		Description: "Applies to service to service copies and downloads. If 'true', a chunk that is slower than 95% of recent ones of the same size is requested again (for downloads, the part of it not yet received), and whichever copy completes first is used. Reduces the delays caused by slow server nodes, at the cost of some extra requests. Default is false.",
+	}
+}
+
+func (EnvironmentVariable) ReadFromSecondary() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_READ_FROM_SECONDARY",
+		Description: "Applies to downloads from read-access geo-redundant (RA-GRS) accounts. If 'true', reads that keep failing against the primary endpoint are retried against the account's -secondary endpoint, and the log records which transfers were served by it. Data there may lag behind the primary. Default is false.",
+	}
+}
+
+func (EnvironmentVariable) MinChunkThroughput() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_MIN_CHUNK_THROUGHPUT",
+		Description: "Minimum throughput of a chunk, in KiB per second (e.g. 100). A chunk that stays below it for 30 seconds (see AZCOPY_MIN_CHUNK_THROUGHPUT_PERIOD) is cancelled and retried on a new connection, rather than waiting for the request to time out. Default is no minimum.",
+	}
+}
+
+func (EnvironmentVariable) MinChunkThroughputPeriod() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_MIN_CHUNK_THROUGHPUT_PERIOD",
+		DefaultValue: "30",
+		Description:  "How long, in seconds, a chunk's throughput must stay below AZCOPY_MIN_CHUNK_THROUGHPUT before the chunk is abandoned. The period starts when the chunk's first byte moves. Default is 30.",
+	}
+}
+
+func (EnvironmentVariable) SequentialWrites() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_SEQUENTIAL_WRITES",
+		Description:  "Applies to downloads. If 'true', files are written strictly in order and are not pre-sized, which suits network file systems such as SMB and NFS. If 'auto', that is done only when the destination is on a network file system. Default is auto.",
+		DefaultValue: "auto",
+	}
+}
+
+func (EnvironmentVariable) DirectIO() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_DIRECT_IO",
+		Description:  "Applies to local files on Linux and Windows. If 'true', they are read and written with unbuffered IO (O_DIRECT or FILE_FLAG_NO_BUFFERING), so that moving very large datasets doesn't fill the OS page cache. Default is false.",
+		DefaultValue: "false",
+	}
+}
+
+func (EnvironmentVariable) ReadSourceOnce() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_READ_SOURCE_ONCE",
+		Description:  "Applies to uploads from local files. If 'true', each chunk is read from the source only once: it's kept in RAM until it has been sent, so a retry resends it from memory instead of reading the file again, and what's hashed is exactly what's sent. That suits slow sources, such as USB drives and network mounts, at the cost of holding chunks in RAM a little longer. If 'auto', that is done when the source is on a network file system. Default is auto.",
+		DefaultValue: "auto",
+	}
+}
+
+func (EnvironmentVariable) WarmConnections() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_WARM_CONNECTIONS",
+		Description:  "The number of connections to open to each Azure Storage endpoint before the first file of a job is started, so that short jobs don't spend their first seconds on DNS lookups and TLS handshakes. It is limited by the number of idle connections kept per endpoint. The endpoints' names are always resolved in advance. Default is 0.",
+		DefaultValue: "0",
+	}
+}
+
+func (EnvironmentVariable) PlanFileMapping() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_PLAN_FILE_MAPPING",
+		Description:  "How job plan files are held in memory. If 'mmap', they are memory mapped. If 'buffered', they are read into memory and changes are written back every few seconds, for platforms and file systems (such as some container overlays) where memory mapping is unsupported or unreliable. If 'auto', they are memory mapped unless that fails. Default is auto.",
+		DefaultValue: "auto",
+	}
+}
+
+func (EnvironmentVariable) MaxMappedPlanFiles() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_MAX_MAPPED_PLAN_FILES",
+		Description:  "The most job plan files (one per job part) to keep in memory at once. Beyond that, the plans of parts that have no transfers running, and haven't been read for a few seconds, are unmapped, and mapped again if they're needed. Plans with transfers running always stay mapped, so this can be exceeded. Lower it to save memory on constrained hosts running jobs with thousands of parts. 0 means no limit. Default is 256.",
+		DefaultValue: "256",
+	}
+}
+
 func (EnvironmentVariable) ShowPerfStates() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AZCOPY_SHOW_PERF_STATES",
@@ -276,7 +404,7 @@ func (EnvironmentVariable) AWSAccessKeyID() EnvironmentVariable {
 func (EnvironmentVariable) AWSSecretAccessKey() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AWS_SECRET_ACCESS_KEY",
-		Description: "The AWS secret access key for S3 source used in service to service copy.",
+		Description: "The AWS secret access key for S3 source used in service to service copy. May instead be the URI of an Azure Key Vault secret, which is fetched using the machine's managed identity.",
 		Hidden:      true,
 	}
 }
@@ -293,6 +421,21 @@ func (EnvironmentVariable) GoogleAppCredentials() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) GoogleHMACAccessID() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_GCS_HMAC_ACCESS_ID",
+		Description: "The access ID of a Google Cloud Storage HMAC key, to read GCS sources with instead of application credentials. Only used when GOOGLE_APPLICATION_CREDENTIALS is not set.",
+	}
+}
+
+func (EnvironmentVariable) GoogleHMACSecret() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_GCS_HMAC_SECRET",
+		Description: "The secret of the Google Cloud Storage HMAC key given by AZCOPY_GCS_HMAC_ACCESS_ID.",
+		Hidden:      true,
+	}
+}
+
 func (EnvironmentVariable) GoogleCloudProject() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "GOOGLE_CLOUD_PROJECT",
@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"math"
+	"mime"
 	"reflect"
 	"regexp"
 	"strings"
@@ -74,6 +75,11 @@ func NewJobID() JobID {
 	return JobID(NewUUID())
 }
 
+// NewJobIDFromHash returns the job ID that the given bytes, e.g. a command line, always map to
+func NewJobIDFromHash(b []byte) JobID {
+	return JobID(NewUUIDFromHash(b))
+}
+
 //var EmptyJobId JobID = JobID{}
 func (j JobID) IsEmpty() bool {
 	return j == JobID{}
@@ -216,6 +222,10 @@ func (OverwriteOption) False() OverwriteOption         { return OverwriteOption(
 func (OverwriteOption) Prompt() OverwriteOption        { return OverwriteOption(2) }
 func (OverwriteOption) IfSourceNewer() OverwriteOption { return OverwriteOption(3) }
 
+// IfVersioned only overwrites blobs whose storage account has blob versioning enabled, so that what's overwritten
+// is kept as a previous version, and skips the others
+func (OverwriteOption) IfVersioned() OverwriteOption { return OverwriteOption(4) }
+
 func (o *OverwriteOption) Parse(s string) error {
 	val, err := enum.Parse(reflect.TypeOf(o), s, true)
 	if err == nil {
@@ -250,6 +260,56 @@ func (of OutputFormat) String() string {
 	return enum.StringInt(of, reflect.TypeOf(of))
 }
 
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var EBlobListingStrategy = BlobListingStrategy(0)
+
+// BlobListingStrategy controls which listing API is used to enumerate blob containers.
+// Flat listing returns every blob under a prefix in as few calls as possible, while hierarchical listing
+// returns one virtual directory at a time, which lets directories be listed concurrently.
+type BlobListingStrategy uint8
+
+func (BlobListingStrategy) Auto() BlobListingStrategy         { return BlobListingStrategy(0) }
+func (BlobListingStrategy) Flat() BlobListingStrategy         { return BlobListingStrategy(1) }
+func (BlobListingStrategy) Hierarchical() BlobListingStrategy { return BlobListingStrategy(2) }
+
+func (s *BlobListingStrategy) Parse(str string) error {
+	val, err := enum.Parse(reflect.TypeOf(s), str, true)
+	if err == nil {
+		*s = val.(BlobListingStrategy)
+	}
+	return err
+}
+
+func (s BlobListingStrategy) String() string {
+	return enum.StringInt(s, reflect.TypeOf(s))
+}
+
+var ESchedulingFairness = SchedulingFairness(0)
+
+// SchedulingFairness controls the order in which the transfers and chunks of different job parts are handed to the workers.
+// FIFO works through them in the order they were scheduled, so the parts of a job finish in order. RoundRobin takes
+// turns between the parts (of all jobs) that have work waiting, so a big part that was scheduled early can't hold up the
+// parts after it.
+type SchedulingFairness uint8
+
+func (SchedulingFairness) FIFO() SchedulingFairness       { return SchedulingFairness(0) }
+func (SchedulingFairness) RoundRobin() SchedulingFairness { return SchedulingFairness(1) }
+
+func (s *SchedulingFairness) Parse(str string) error {
+	val, err := enum.Parse(reflect.TypeOf(s), str, true)
+	if err == nil {
+		*s = val.(SchedulingFairness)
+	}
+	return err
+}
+
+func (s SchedulingFairness) String() string {
+	return enum.StringInt(s, reflect.TypeOf(s))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 var EExitCode = ExitCode(0)
 
 type ExitCode uint32
@@ -416,6 +476,11 @@ func (Location) BlobFS() Location    { return Location(5) }
 func (Location) S3() Location        { return Location(6) }
 func (Location) Benchmark() Location { return Location(7) }
 func (Location) GCP() Location       { return Location(8) }
+func (Location) None() Location      { return Location(9) } // the "destination" of jobs that change existing objects in place
+
+// ManagedDisk is a managed disk, reached through the SAS URL that Azure grants for importing its data. It's a page blob,
+// but one that already exists with the disk's size, and that doesn't allow most blob operations (such as Set Tier)
+func (Location) ManagedDisk() Location { return Location(10) }
 
 func (l Location) String() string {
 	return enum.StringInt(l, reflect.TypeOf(l))
@@ -442,9 +507,9 @@ func fromToValue(from Location, to Location) FromTo {
 
 func (l Location) IsRemote() bool {
 	switch l {
-	case ELocation.BlobFS(), ELocation.Blob(), ELocation.File(), ELocation.S3(), ELocation.GCP():
+	case ELocation.BlobFS(), ELocation.Blob(), ELocation.File(), ELocation.S3(), ELocation.GCP(), ELocation.ManagedDisk():
 		return true
-	case ELocation.Local(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown():
+	case ELocation.Local(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown(), ELocation.None():
 		return false
 	default:
 		panic("unexpected location, please specify if it is remote")
@@ -452,7 +517,7 @@ func (l Location) IsRemote() bool {
 }
 
 func (l Location) IsLocal() bool {
-	if l == ELocation.Unknown() {
+	if l == ELocation.Unknown() || l == ELocation.None() {
 		return false
 	} else {
 		return !l.IsRemote()
@@ -465,7 +530,7 @@ func (l Location) IsFolderAware() bool {
 	switch l {
 	case ELocation.BlobFS(), ELocation.File(), ELocation.Local():
 		return true
-	case ELocation.Blob(), ELocation.S3(), ELocation.GCP(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown():
+	case ELocation.Blob(), ELocation.S3(), ELocation.GCP(), ELocation.ManagedDisk(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown(), ELocation.None():
 		return false
 	default:
 		panic("unexpected location, please specify if it is folder-aware")
@@ -492,6 +557,7 @@ func (FromTo) FilePipe() FromTo  { return FromTo(fromToValue(ELocation.File(), E
 func (FromTo) PipeFile() FromTo  { return FromTo(fromToValue(ELocation.Pipe(), ELocation.File())) }
 func (FromTo) BlobTrash() FromTo { return FromTo(fromToValue(ELocation.Blob(), ELocation.Unknown())) }
 func (FromTo) FileTrash() FromTo { return FromTo(fromToValue(ELocation.File(), ELocation.Unknown())) }
+func (FromTo) BlobNone() FromTo  { return FromTo(fromToValue(ELocation.Blob(), ELocation.None())) }
 func (FromTo) BlobFSTrash() FromTo {
 	return FromTo(fromToValue(ELocation.BlobFS(), ELocation.Unknown()))
 }
@@ -503,6 +569,9 @@ func (FromTo) BlobFile() FromTo    { return FromTo(fromToValue(ELocation.Blob(),
 func (FromTo) FileFile() FromTo    { return FromTo(fromToValue(ELocation.File(), ELocation.File())) }
 func (FromTo) S3Blob() FromTo      { return FromTo(fromToValue(ELocation.S3(), ELocation.Blob())) }
 func (FromTo) GCPBlob() FromTo     { return FromTo(fromToValue(ELocation.GCP(), ELocation.Blob())) }
+func (FromTo) BlobFSBlobFS() FromTo {
+	return FromTo(fromToValue(ELocation.BlobFS(), ELocation.BlobFS()))
+}
 
 // todo: to we really want these?  Starts to look like a bit of a combinatorial explosion
 func (FromTo) BenchmarkBlob() FromTo {
@@ -514,6 +583,10 @@ func (FromTo) BenchmarkFile() FromTo {
 func (FromTo) BenchmarkBlobFS() FromTo {
 	return FromTo(fromToValue(ELocation.Benchmark(), ELocation.BlobFS()))
 }
+func (FromTo) LocalManagedDisk() FromTo {
+	return FromTo(fromToValue(ELocation.Local(), ELocation.ManagedDisk()))
+}
+func (FromTo) LocalLocal() FromTo { return FromTo(fromToValue(ELocation.Local(), ELocation.Local())) }
 
 func (ft FromTo) String() string {
 	return enum.StringInt(ft, reflect.TypeOf(ft))
@@ -641,6 +714,32 @@ func (TransferStatus) TierAvailabilityCheckFailure() TransferStatus { return Tra
 
 func (TransferStatus) Cancelled() TransferStatus { return TransferStatus(-6) }
 
+// Transfer skipped because the file is outside the size bounds given by --skip-larger-than or --skip-smaller-than
+func (TransferStatus) SkippedSizeLimit() TransferStatus { return TransferStatus(-7) }
+
+// Transfer failed because it took longer than the per-transfer time limit given by --transfer-timeout
+func (TransferStatus) TimedOut() TransferStatus { return TransferStatus(-8) }
+
+// IsFailed says whether the transfer failed, for whatever reason. Skipped and cancelled transfers haven't failed.
+func (ts TransferStatus) IsFailed() bool {
+	switch ts {
+	case ETransferStatus.Failed(), ETransferStatus.BlobTierFailure(), ETransferStatus.TierAvailabilityCheckFailure(), ETransferStatus.TimedOut():
+		return true
+	default:
+		return false
+	}
+}
+
+// IsSkipped says whether the transfer was deliberately not done
+func (ts TransferStatus) IsSkipped() bool {
+	switch ts {
+	case ETransferStatus.SkippedEntityAlreadyExists(), ETransferStatus.SkippedBlobHasSnapshots(), ETransferStatus.SkippedSizeLimit():
+		return true
+	default:
+		return false
+	}
+}
+
 func (ts TransferStatus) ShouldTransfer() bool {
 	return ts == ETransferStatus.NotStarted() || ts == ETransferStatus.Started()
 }
@@ -734,6 +833,9 @@ func (PageBlobTier) P4() PageBlobTier   { return PageBlobTier(4) }
 func (PageBlobTier) P40() PageBlobTier  { return PageBlobTier(40) }
 func (PageBlobTier) P50() PageBlobTier  { return PageBlobTier(50) }
 func (PageBlobTier) P6() PageBlobTier   { return PageBlobTier(6) }
+func (PageBlobTier) P60() PageBlobTier  { return PageBlobTier(60) }
+func (PageBlobTier) P70() PageBlobTier  { return PageBlobTier(70) }
+func (PageBlobTier) P80() PageBlobTier  { return PageBlobTier(80) }
 
 func (pbt PageBlobTier) String() string {
 	return enum.StringInt(pbt, reflect.TypeOf(pbt))
@@ -777,6 +879,7 @@ func (CredentialType) Anonymous() CredentialType            { return CredentialT
 func (CredentialType) SharedKey() CredentialType            { return CredentialType(3) } // For Azure, SharedKey
 func (CredentialType) S3AccessKey() CredentialType          { return CredentialType(4) } // For S3, AccessKeyID and SecretAccessKey
 func (CredentialType) GoogleAppCredentials() CredentialType { return CredentialType(5) }
+func (CredentialType) GoogleHMAC() CredentialType           { return CredentialType(6) } // For GCS, the access ID and secret of an HMAC key
 
 func (ct CredentialType) String() string {
 	return enum.StringInt(ct, reflect.TypeOf(ct))
@@ -840,6 +943,104 @@ func (hvo *HashValidationOption) UnmarshalJSON(b []byte) error {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+var EFlushPolicy = FlushPolicy(0)
+
+// FlushPolicy says when downloaded data is committed to durable storage (with fsync, or FlushFileBuffers on Windows)
+type FlushPolicy uint8
+
+// Never leaves it to the OS to write cached data out in its own time. This is the fastest option
+func (FlushPolicy) Never() FlushPolicy { return FlushPolicy(0) }
+
+// PerFile commits each file once all of it has been written
+func (FlushPolicy) PerFile() FlushPolicy { return FlushPolicy(1) }
+
+// PerChunk commits what has been written after every chunk, as well as at the end of each file
+func (FlushPolicy) PerChunk() FlushPolicy { return FlushPolicy(2) }
+
+func (fp FlushPolicy) String() string {
+	return enum.StringInt(fp, reflect.TypeOf(fp))
+}
+
+// Parse accepts the names as they're given on the command line (e.g. per-file) as well as the names of the values
+func (fp *FlushPolicy) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(fp), strings.ReplaceAll(s, "-", ""), true, true)
+	if err == nil {
+		*fp = val.(FlushPolicy)
+	}
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var EFreeSpaceCheckOption = FreeSpaceCheckOption(0)
+
+// FreeSpaceCheckOption says what to do, when downloading, if there's not enough free space on the destination volume
+type FreeSpaceCheckOption uint8
+
+// Warn means warn about it, but still try the transfers. Since files that will be overwritten aren't counted as
+// free space, the job may well fit anyway
+func (FreeSpaceCheckOption) Warn() FreeSpaceCheckOption { return FreeSpaceCheckOption(0) }
+
+// Fail means fail the job, as soon as enumeration finds that it's too big, and fail each transfer that doesn't fit
+func (FreeSpaceCheckOption) Fail() FreeSpaceCheckOption { return FreeSpaceCheckOption(1) }
+
+// None means don't check. E.g. for file systems that compress or deduplicate data, where files take less space than their size
+func (FreeSpaceCheckOption) None() FreeSpaceCheckOption { return FreeSpaceCheckOption(2) }
+
+func (o FreeSpaceCheckOption) String() string {
+	return enum.StringInt(o, reflect.TypeOf(o))
+}
+
+func (o *FreeSpaceCheckOption) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(o), s, true, true)
+	if err == nil {
+		*o = val.(FreeSpaceCheckOption)
+	}
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var EDuplicateDestinationOption = DuplicateDestinationOption(0)
+
+// DuplicateDestinationOption says what to do when two different source files would be transferred to the same destination
+type DuplicateDestinationOption uint8
+
+// Auto means Fail if path rewrite rules are in use, since they're what usually maps two sources to one destination,
+// and None otherwise, so that huge jobs don't pay for remembering every destination
+func (DuplicateDestinationOption) Auto() DuplicateDestinationOption {
+	return DuplicateDestinationOption(0)
+}
+
+// Fail means fail the job, during enumeration, as soon as a second source is found for a destination
+func (DuplicateDestinationOption) Fail() DuplicateDestinationOption {
+	return DuplicateDestinationOption(1)
+}
+
+// Rename means send the second source to a destination with a numbered suffix before its extension, e.g. report-2.pdf
+func (DuplicateDestinationOption) Rename() DuplicateDestinationOption {
+	return DuplicateDestinationOption(2)
+}
+
+// None means don't check, and let the transfers overwrite each other
+func (DuplicateDestinationOption) None() DuplicateDestinationOption {
+	return DuplicateDestinationOption(3)
+}
+
+func (o DuplicateDestinationOption) String() string {
+	return enum.StringInt(o, reflect.TypeOf(o))
+}
+
+func (o *DuplicateDestinationOption) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(o), s, true, true)
+	if err == nil {
+		*o = val.(DuplicateDestinationOption)
+	}
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 var EInvalidMetadataHandleOption = InvalidMetadataHandleOption(0)
 
 var DefaultInvalidMetadataHandleOption = EInvalidMetadataHandleOption.ExcludeIfInvalid()
@@ -914,6 +1115,8 @@ type CopyTransfer struct {
 	CacheControl       string
 	ContentMD5         []byte
 	Metadata           Metadata
+	// MetadataOverride says Metadata was computed for this file (e.g. by --metadata-rules) and replaces the job's metadata, even when empty
+	MetadataOverride bool
 
 	// Properties for S2S blob copy
 	BlobType      azblob.BlobType
@@ -928,14 +1131,36 @@ type CopyTransfer struct {
 // Metadata used in AzCopy.
 type Metadata map[string]string
 
-// ToAzBlobMetadata converts metadata to azblob's metadata.
+// ToAzBlobMetadata converts metadata to azblob's metadata, ready to be sent to the service.
 func (m Metadata) ToAzBlobMetadata() azblob.Metadata {
-	return azblob.Metadata(m)
+	return azblob.Metadata(m.encodedForService())
 }
 
-// ToAzFileMetadata converts metadata to azfile's metadata.
+// ToAzFileMetadata converts metadata to azfile's metadata, ready to be sent to the service.
 func (m Metadata) ToAzFileMetadata() azfile.Metadata {
-	return azfile.Metadata(m)
+	return azfile.Metadata(m.encodedForService())
+}
+
+// encodedForService returns the metadata with any value that isn't printable ASCII, which the service refuses since
+// metadata travels in headers, encoded as an RFC 2047 encoded-word (e.g. "=?utf-8?q?caf=C3=A9?="). Those decode with any
+// MIME library. The values that are already ASCII, including ones encoded by an earlier copy, are left as they are.
+func (m Metadata) encodedForService() Metadata {
+	var encoded Metadata
+	for k, v := range m {
+		if e := mime.QEncoding.Encode("utf-8", v); e != v {
+			if encoded == nil {
+				encoded = make(Metadata, len(m))
+				for k2, v2 := range m {
+					encoded[k2] = v2
+				}
+			}
+			encoded[k] = e
+		}
+	}
+	if encoded == nil {
+		return m
+	}
+	return encoded
 }
 
 // FromAzBlobMetadataToCommonMetadata converts azblob's metadata to common metadata.
@@ -1113,6 +1338,36 @@ func (m Metadata) ConcatenatedKeys() string {
 	return buf.String()
 }
 
+// SourceLMTMetadataKey is the metadata key under which uploads can record the last modified time of their local source,
+// so that later downloads and syncs see the original time rather than the time of the upload.
+const SourceLMTMetadataKey = "azcopy_source_lmt"
+
+// SourceLastModifiedTime returns the source last modified time recorded in the metadata, if there is a valid one.
+func (m Metadata) SourceLastModifiedTime() (time.Time, bool) {
+	key, ok := m.findKey(SourceLMTMetadataKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	lmt, err := time.Parse(time.RFC3339Nano, m[key])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return lmt, true
+}
+
+// WithSourceLastModifiedTime returns a copy of the metadata recording the given time as the source last modified time.
+func (m Metadata) WithSourceLastModifiedTime(lmt time.Time) Metadata {
+	result := make(Metadata, len(m)+1)
+	for k, v := range m {
+		result[k] = v
+	}
+	if key, ok := result.findKey(SourceLMTMetadataKey); ok {
+		delete(result, key)
+	}
+	result[SourceLMTMetadataKey] = lmt.UTC().Format(time.RFC3339Nano)
+	return result
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 // Common resource's HTTP headers stands for properties used in AzCopy.
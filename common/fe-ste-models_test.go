@@ -90,6 +90,23 @@ func (s *feSteModelsTestSuite) TestIsJobDone(c *chk.C) {
 	c.Assert(status.IsJobDone(), chk.Equals, true)
 }
 
+func (s *feSteModelsTestSuite) TestTransferStatusIsFailedOrSkipped(c *chk.C) {
+	status := common.ETransferStatus
+
+	for _, ts := range []common.TransferStatus{status.Failed(), status.BlobTierFailure(), status.TierAvailabilityCheckFailure(), status.TimedOut()} {
+		c.Assert(ts.IsFailed(), chk.Equals, true)
+		c.Assert(ts.IsSkipped(), chk.Equals, false)
+	}
+	for _, ts := range []common.TransferStatus{status.SkippedEntityAlreadyExists(), status.SkippedBlobHasSnapshots(), status.SkippedSizeLimit()} {
+		c.Assert(ts.IsFailed(), chk.Equals, false)
+		c.Assert(ts.IsSkipped(), chk.Equals, true)
+	}
+	for _, ts := range []common.TransferStatus{status.NotStarted(), status.Started(), status.Success(), status.Cancelled()} {
+		c.Assert(ts.IsFailed(), chk.Equals, false)
+		c.Assert(ts.IsSkipped(), chk.Equals, false)
+	}
+}
+
 func getInvalidMetadataSample() common.Metadata {
 	m := make(map[string]string)
 
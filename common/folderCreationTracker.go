@@ -75,6 +75,7 @@ func (f *simpleFolderTracker) ShouldSetProperties(folder string, overwrite Overw
 		return true
 	case EOverwriteOption.Prompt(),
 		EOverwriteOption.IfSourceNewer(), // TODO discuss if this case should be treated differently than false
+		EOverwriteOption.IfVersioned(),   // folders don't have versions, so there is nothing to keep their properties in
 		EOverwriteOption.False():
 
 		f.mu.Lock()
@@ -2,6 +2,8 @@ package common
 
 import (
 	gcpUtils "cloud.google.com/go/storage"
+	"encoding/base64"
+	"github.com/minio/minio-go"
 	"strings"
 )
 
@@ -49,3 +51,48 @@ func (gie *GCPObjectInfoExtension) NewCommonMetadata() Metadata {
 	}
 	return md
 }
+
+// NewGCPObjectAttrsFromXMLAPI converts the properties of an object, as read through GCS's XML API (which is what an
+// HMAC key gives access to), into the attributes that the JSON API would have returned.
+// User-defined metadata keeps its x-goog-meta- prefix, so that NewCommonMetadata finds it
+func NewGCPObjectAttrsFromXMLAPI(bucket string, oi minio.ObjectInfo) gcpUtils.ObjectAttrs {
+	attrs := gcpUtils.ObjectAttrs{
+		Bucket:             bucket,
+		Name:               oi.Key,
+		Size:               oi.Size,
+		Updated:            oi.LastModified,
+		StorageClass:       oi.StorageClass,
+		ContentType:        oi.ContentType,
+		CacheControl:       oi.Metadata.Get("Cache-Control"),
+		ContentDisposition: oi.Metadata.Get("Content-Disposition"),
+		ContentEncoding:    oi.Metadata.Get("Content-Encoding"),
+		ContentLanguage:    oi.Metadata.Get("Content-Language"),
+	}
+	for k, v := range oi.Metadata {
+		if len(v) == 0 {
+			continue
+		}
+		// GCS answers with x-goog-meta- headers, but takes x-amz-meta- ones too, so objects written through its
+		// interoperability support may be returned with either
+		for _, prefix := range []string{gcpMetadataPrefix, s3MetadataPrefix} {
+			if len(k) > len(prefix) && strings.EqualFold(k[:len(prefix)], prefix) {
+				if attrs.Metadata == nil {
+					attrs.Metadata = make(map[string]string)
+				}
+				attrs.Metadata[gcpMetadataPrefix+k[len(prefix):]] = v[0]
+			}
+		}
+	}
+	// x-goog-hash lists the object's checksums, e.g. "crc32c=n03x6A==,md5=Ojk9c3dhfxgoKVVHYwFbHQ==".
+	// Composite objects have no MD5
+	for _, hashes := range oi.Metadata["X-Goog-Hash"] {
+		for _, h := range strings.Split(hashes, ",") {
+			if h = strings.TrimSpace(h); strings.HasPrefix(h, "md5=") {
+				if md5, err := base64.StdEncoding.DecodeString(h[len("md5="):]); err == nil {
+					attrs.MD5 = md5
+				}
+			}
+		}
+	}
+	return attrs
+}
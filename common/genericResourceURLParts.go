@@ -27,7 +27,7 @@ func NewGenericResourceURLParts(resourceURL url.URL, location Location) GenericR
 	g := GenericResourceURLParts{location: location}
 
 	switch location {
-	case ELocation.Blob():
+	case ELocation.Blob(), ELocation.ManagedDisk():
 		g.blobURLParts = azblob.NewBlobURLParts(resourceURL)
 	case ELocation.File():
 		g.fileURLParts = azfile.NewFileURLParts(resourceURL)
@@ -50,7 +50,7 @@ func NewGenericResourceURLParts(resourceURL url.URL, location Location) GenericR
 
 func (g GenericResourceURLParts) GetContainerName() string {
 	switch g.location {
-	case ELocation.Blob():
+	case ELocation.Blob(), ELocation.ManagedDisk():
 		return g.blobURLParts.ContainerName
 	case ELocation.File():
 		return g.fileURLParts.ShareName
@@ -67,7 +67,7 @@ func (g GenericResourceURLParts) GetContainerName() string {
 
 func (g GenericResourceURLParts) GetObjectName() string {
 	switch g.location {
-	case ELocation.Blob():
+	case ELocation.Blob(), ELocation.ManagedDisk():
 		return g.blobURLParts.BlobName
 	case ELocation.File():
 		return g.fileURLParts.DirectoryOrFilePath
@@ -84,7 +84,7 @@ func (g GenericResourceURLParts) GetObjectName() string {
 
 func (g *GenericResourceURLParts) SetObjectName(objectName string) {
 	switch g.location {
-	case ELocation.Blob():
+	case ELocation.Blob(), ELocation.ManagedDisk():
 		g.blobURLParts.BlobName = objectName
 	case ELocation.File():
 		g.fileURLParts.DirectoryOrFilePath = objectName
@@ -107,7 +107,7 @@ func (g GenericResourceURLParts) String() string {
 		return g.s3URLParts.String()
 	case ELocation.GCP():
 		return g.gcpURLParts.String()
-	case ELocation.Blob():
+	case ELocation.Blob(), ELocation.ManagedDisk():
 		URLOut = g.blobURLParts.URL()
 	case ELocation.File():
 		URLOut = g.fileURLParts.URL()
@@ -123,7 +123,7 @@ func (g GenericResourceURLParts) String() string {
 
 func (g GenericResourceURLParts) URL() url.URL {
 	switch g.location {
-	case ELocation.Blob():
+	case ELocation.Blob(), ELocation.ManagedDisk():
 		return g.blobURLParts.URL()
 	case ELocation.File():
 		return g.fileURLParts.URL()
@@ -0,0 +1,202 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// KeyVaultResource is the AAD resource used to acquire tokens for Azure Key Vault in the public cloud
+const KeyVaultResource = "https://vault.azure.net"
+const keyVaultAPIVersion = "7.1"
+
+// the DNS suffixes of Key Vault in the public and national clouds. The AAD resource for each is the suffix itself, e.g. https://vault.azure.cn
+var keyVaultHostSuffixes = []string{
+	".vault.azure.net",
+	".vault.azure.cn",
+	".vault.usgovcloudapi.net",
+	".vault.microsoftazure.de",
+}
+
+// keyVaultResourceFor returns the AAD resource for the cloud that the vault is in, or nothing if the host isn't a vault
+func keyVaultResourceFor(host string) string {
+	host = strings.ToLower(host)
+	for _, suffix := range keyVaultHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return "https://" + strings.TrimPrefix(suffix, ".")
+		}
+	}
+	return ""
+}
+
+// IsKeyVaultSecretURI returns true if the given value looks like a reference to a Key Vault secret,
+// e.g. https://myvault.vault.azure.net/secrets/mysecret or https://myvault.vault.azure.net/secrets/mysecret/<version>
+func IsKeyVaultSecretURI(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil || !strings.EqualFold(u.Scheme, "https") {
+		return false
+	}
+
+	if keyVaultResourceFor(u.Host) == "" {
+		return false
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	return (len(segments) == 2 || len(segments) == 3) && strings.EqualFold(segments[0], "secrets") && segments[1] != ""
+}
+
+// ResolveSecret returns value unchanged, unless it is a Key Vault secret URI,
+// in which case the secret is fetched using the ambient (managed identity) AAD credential.
+// This allows secrets to be referenced, instead of being placed directly in environment variables.
+func ResolveSecret(ctx context.Context, value string, identityInfo IdentityInfo) (string, error) {
+	if !IsKeyVaultSecretURI(value) {
+		return value, nil
+	}
+
+	return GetKeyVaultSecret(ctx, value, identityInfo)
+}
+
+// GetKeyVaultSecret fetches the value of a secret from Azure Key Vault.
+func GetKeyVaultSecret(ctx context.Context, secretURI string, identityInfo IdentityInfo) (string, error) {
+	if !IsKeyVaultSecretURI(secretURI) {
+		return "", fmt.Errorf("%s is not a valid Key Vault secret URI", secretURI)
+	}
+
+	u, err := url.Parse(secretURI)
+	if err != nil {
+		return "", err
+	}
+
+	credInfo := &OAuthTokenInfo{Identity: true, IdentityInfo: identityInfo}
+	token, err := credInfo.getNewTokenFromMSIForResource(ctx, keyVaultResourceFor(u.Host))
+	if err != nil {
+		return "", fmt.Errorf("failed to get a token for Key Vault, %v", err)
+	}
+	params := u.Query()
+	params.Set("api-version", keyVaultAPIVersion)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request, %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req = req.WithContext(ctx)
+
+	resp, err := msiTokenHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret from Key Vault, %v", err)
+	}
+	defer func() {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if !(HTTPResponseExtension{Response: resp}).IsSuccessStatusCode(http.StatusOK) {
+		return "", fmt.Errorf("failed to get secret from Key Vault, status code: %v", resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var secret struct {
+		Value string `json:"value"`
+	}
+	b = ByteSliceExtension{ByteSlice: b}.RemoveBOM()
+	if err := json.Unmarshal(b, &secret); err != nil {
+		return "", fmt.Errorf("failed to unmarshal Key Vault response, %v", err)
+	}
+	if secret.Value == "" {
+		return "", errors.New("the Key Vault secret is empty")
+	}
+
+	return secret.Value, nil
+}
+
+// AmbientIdentityInfo returns the managed identity used to fetch secrets from Key Vault.
+// It's the machine's system-assigned identity, unless the AZCOPY_MSI_* environment variables choose a user-assigned one
+func AmbientIdentityInfo() (IdentityInfo, error) {
+	identityInfo := IdentityInfo{
+		ClientID: lcm.GetEnvironmentVariable(EEnvironmentVariable.ManagedIdentityClientID()),
+		ObjectID: lcm.GetEnvironmentVariable(EEnvironmentVariable.ManagedIdentityObjectID()),
+		MSIResID: lcm.GetEnvironmentVariable(EEnvironmentVariable.ManagedIdentityResourceString()),
+	}
+	return identityInfo, identityInfo.Validate()
+}
+
+var resolvedSecretEnvVars = struct {
+	sync.Mutex
+	values map[string]string
+}{values: map[string]string{}}
+
+// ResolveSecretEnvironmentVariable fetches the secret, if an environment variable that holds a secret holds a Key Vault secret URI instead.
+// The secret is fetched with the ambient identity, once per run. The front end calls this as the job starts, so that a failure can be
+// reported there, since the credentials that need the secret are created by the STE, for every job part, where it can't
+func ResolveSecretEnvironmentVariable(ctx context.Context, env EnvironmentVariable) error {
+	value := lcm.GetEnvironmentVariable(env)
+	if !IsKeyVaultSecretURI(value) {
+		return nil
+	}
+
+	resolvedSecretEnvVars.Lock()
+	defer resolvedSecretEnvVars.Unlock()
+	if _, ok := resolvedSecretEnvVars.values[value]; ok {
+		return nil
+	}
+
+	identityInfo, err := AmbientIdentityInfo()
+	if err != nil {
+		return err
+	}
+	secret, err := GetKeyVaultSecret(ctx, value, identityInfo)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s from Key Vault: %v", env.Name, err)
+	}
+	resolvedSecretEnvVars.values[value] = secret
+	return nil
+}
+
+// GetSecretEnvironmentVariable reads an environment variable that holds a secret, such as an account key.
+// If it holds a Key Vault secret URI instead, it returns the secret that ResolveSecretEnvironmentVariable fetched
+func GetSecretEnvironmentVariable(env EnvironmentVariable) (string, error) {
+	value := lcm.GetEnvironmentVariable(env)
+	if !IsKeyVaultSecretURI(value) {
+		return value, nil
+	}
+
+	resolvedSecretEnvVars.Lock()
+	defer resolvedSecretEnvVars.Unlock()
+	if secret, ok := resolvedSecretEnvVars.values[value]; ok {
+		return secret, nil
+	}
+	return "", fmt.Errorf("%s references a Key Vault secret, which wasn't fetched when the job started", env.Name)
+}
@@ -59,6 +59,7 @@ type LifecycleMgr interface {
 	SetOutputFormat(OutputFormat)                                // change the output format of the entire application
 	EnableInputWatcher()                                         // depending on the command, we may allow user to give input through Stdin
 	EnableCancelFromStdIn()                                      // allow user to send in `cancel` to stop the job
+	OnCancelTransferFromStdIn(func(transfer string))             // with cancel from stdin, `cancel-transfer <transfer>` calls this to cancel one transfer of the job
 	AddUserAgentPrefix(string) string                            // append the global user agent prefix, if applicable
 	E2EAwaitContinue()                                           // used by E2E tests
 	E2EAwaitAllowOpenFiles()                                     // used by E2E tests
@@ -83,6 +84,7 @@ type lifecycleMgr struct {
 	inputQueue            chan userInput // msgs from the user
 	allowWatchInput       bool           // accept user inputs and place then in the inputQueue
 	allowCancelFromStdIn  bool           // allow user to send in 'cancel' from the stdin to stop the current job
+	cancelTransfer        func(string)   // called when the user sends in 'cancel-transfer' from the stdin, if cancelling from the stdin is allowed
 	e2eAllowAwaitContinue bool           // allow the user to send 'continue' from stdin to start the current job
 	e2eAllowAwaitOpen     bool           // allow the user to send 'open' from stdin to allow the opening of the first file
 	closeFunc             func()         // used to close logs before exiting
@@ -115,6 +117,8 @@ func (lcm *lifecycleMgr) watchInputs() {
 
 		if lcm.allowCancelFromStdIn && strings.EqualFold(msg, "cancel") {
 			lcm.cancelChannel <- os.Interrupt
+		} else if lcm.allowCancelFromStdIn && lcm.cancelTransfer != nil && hasPrefixFold(msg, cancelTransferInput) {
+			lcm.cancelTransfer(strings.TrimSpace(msg[len(cancelTransferInput):]))
 		} else if lcm.e2eAllowAwaitContinue && strings.EqualFold(msg, "continue") {
 			close(lcm.e2eContinueChannel)
 		} else if lcm.e2eAllowAwaitOpen && strings.EqualFold(msg, "open") {
@@ -149,6 +153,16 @@ func (lcm *lifecycleMgr) EnableCancelFromStdIn() {
 	lcm.allowCancelFromStdIn = true
 }
 
+const cancelTransferInput = "cancel-transfer "
+
+func (lcm *lifecycleMgr) OnCancelTransferFromStdIn(cancelTransfer func(transfer string)) {
+	lcm.cancelTransfer = cancelTransfer
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
 func (lcm *lifecycleMgr) ClearEnvironmentVariable(variable EnvironmentVariable) {
 	_ = os.Setenv(variable.Name, "")
 }
@@ -0,0 +1,89 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogFileNameTemplate says how the log files of a job are named, e.g. "{date}-{verb}-{jobid}".
+// {jobid} is required, since the jobs commands find a job's logs by looking for its ID in their names
+type LogFileNameTemplate string
+
+const DefaultLogFileNameTemplate = LogFileNameTemplate("{jobid}")
+
+var logFileNameTokenRegex = regexp.MustCompile(`{[^{}]*}`)
+
+// Validate makes sure the template has a job ID, knows all of its tokens, and names a file rather than a path
+func (t LogFileNameTemplate) Validate() error {
+	if !strings.Contains(string(t), "{jobid}") {
+		return errors.New("the log file name template must contain {jobid}")
+	}
+	if strings.ContainsAny(string(t), `/\`) {
+		return errors.New("the log file name template must not contain a path separator. Use --log-location to choose the folder")
+	}
+	for _, token := range logFileNameTokenRegex.FindAllString(string(t), -1) {
+		if token != "{jobid}" && token != "{date}" && token != "{verb}" {
+			return fmt.Errorf("unknown token %s in the log file name template. The choices include: {jobid}, {date}, {verb}", token)
+		}
+	}
+	return nil
+}
+
+// FileName fills in the template for the given job. The suffix tells apart the job's different logs (e.g. "-scanning"), and
+// comes before the .log extension
+func (t LogFileNameTemplate) FileName(jobID JobID, verb string, date time.Time, suffix string) string {
+	name := strings.NewReplacer(
+		"{jobid}", jobID.String(),
+		"{date}", date.UTC().Format("20060102"),
+		"{verb}", verb,
+	).Replace(string(t))
+	return name + suffix + ".log"
+}
+
+var logFileNaming = struct {
+	sync.RWMutex
+	template LogFileNameTemplate
+	verb     string
+	date     time.Time
+}{template: DefaultLogFileNameTemplate, date: time.Now()}
+
+// SetLogFileNaming sets how this run names its log files. The date is fixed when it's called, so that all the logs of a job
+// started just before midnight have the same name
+func SetLogFileNaming(template LogFileNameTemplate, verb string) {
+	logFileNaming.Lock()
+	defer logFileNaming.Unlock()
+	logFileNaming.template = template
+	logFileNaming.verb = verb
+	logFileNaming.date = time.Now()
+}
+
+// JobLogFileName returns the name of the given log of a job, according to this run's log file naming
+func JobLogFileName(jobID JobID, suffix string) string {
+	logFileNaming.RLock()
+	defer logFileNaming.RUnlock()
+	return logFileNaming.template.FileName(jobID, logFileNaming.verb, logFileNaming.date, suffix)
+}
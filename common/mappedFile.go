@@ -0,0 +1,39 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+// IMappedFile is the contents of a file as a byte slice, where changes made to the slice are saved to the file.
+// MMF does that by memory mapping the file. BufferedMMF instead reads the file into memory, and writes changes back
+// to it periodically, for platforms and file systems where memory mapping is unsupported or unreliable.
+type IMappedFile interface {
+	// Slice returns the contents of the file
+	Slice() []byte
+
+	// UseMMF takes shared access, and says whether the file is still mapped. If it returns true, UnuseMMF must be called when done
+	UseMMF() bool
+	UnuseMMF()
+
+	// Unmap saves any outstanding changes, and releases the file. The slice must not be used after that
+	Unmap()
+}
+
+var _ IMappedFile = &MMF{}
+var _ IMappedFile = &BufferedMMF{}
@@ -0,0 +1,224 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JeffreyRichter/enum/enum"
+)
+
+var EMetadataRuleAction = MetadataRuleAction(0)
+
+type MetadataRuleAction uint8
+
+// Add sets a key only if it isn't present yet.
+func (MetadataRuleAction) Add() MetadataRuleAction { return MetadataRuleAction(0) }
+
+// Set sets a key, overwriting its current value.
+func (MetadataRuleAction) Set() MetadataRuleAction { return MetadataRuleAction(1) }
+
+// Remove deletes a key.
+func (MetadataRuleAction) Remove() MetadataRuleAction { return MetadataRuleAction(2) }
+
+// Rename moves the value of a key to another key.
+func (MetadataRuleAction) Rename() MetadataRuleAction { return MetadataRuleAction(3) }
+
+func (a MetadataRuleAction) String() string {
+	return enum.StringInt(a, reflect.TypeOf(a))
+}
+
+func (a *MetadataRuleAction) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(a), s, true, true)
+	if err == nil {
+		*a = val.(MetadataRuleAction)
+	}
+	return err
+}
+
+// MetadataRule is a single step of a metadata transformation.
+// Value is the target key for Rename, and a template for Add and Set.
+type MetadataRule struct {
+	Action MetadataRuleAction
+	Key    string
+	Value  string
+}
+
+// MetadataRules are applied in order to the metadata of every transferred file.
+type MetadataRules []MetadataRule
+
+// MetadataTemplateVars are the per transfer values available to templates.
+type MetadataTemplateVars struct {
+	Name             string
+	RelativePath     string
+	Size             int64
+	LastModifiedTime time.Time
+}
+
+// matches {name}, {path}, {ext}, {size}, {lmt} and {meta:key}
+var metadataTemplatePlaceholder = regexp.MustCompile(`\{([a-z]+)(?::([^{}]*))?\}`)
+
+// ParseMetadataRules parses rules separated by ';', each written as action:arguments, e.g.
+// "rename:Owner=owner;remove:temp;add:source=contoso;set:origin={path}".
+func ParseMetadataRules(s string) (MetadataRules, error) {
+	var rules MetadataRules
+	for _, raw := range strings.Split(s, ";") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		parts := strings.SplitN(raw, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid metadata rule %q, expected action:arguments", raw)
+		}
+
+		var rule MetadataRule
+		if err := rule.Action.Parse(parts[0]); err != nil {
+			return nil, fmt.Errorf("invalid metadata rule %q, unknown action %q", raw, parts[0])
+		}
+
+		if rule.Action == EMetadataRuleAction.Remove() {
+			rule.Key = strings.TrimSpace(parts[1])
+		} else {
+			kv := strings.SplitN(parts[1], "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid metadata rule %q, expected %s:key=value", raw, strings.ToLower(rule.Action.String()))
+			}
+			rule.Key, rule.Value = strings.TrimSpace(kv[0]), kv[1]
+		}
+
+		if rule.Key == "" {
+			return nil, fmt.Errorf("invalid metadata rule %q, the key is empty", raw)
+		}
+
+		switch rule.Action {
+		case EMetadataRuleAction.Add(), EMetadataRuleAction.Set():
+			if !isValidMetadataKey(rule.Key) {
+				return nil, fmt.Errorf("invalid metadata rule %q, %q is not a valid metadata key", raw, rule.Key)
+			}
+			for _, m := range metadataTemplatePlaceholder.FindAllStringSubmatch(rule.Value, -1) {
+				if !isKnownMetadataPlaceholder(m[1], m[2]) {
+					return nil, fmt.Errorf("invalid metadata rule %q, unknown placeholder %q", raw, m[0])
+				}
+			}
+		case EMetadataRuleAction.Rename():
+			rule.Value = strings.TrimSpace(rule.Value)
+			if !isValidMetadataKey(rule.Value) {
+				return nil, fmt.Errorf("invalid metadata rule %q, %q is not a valid metadata key", raw, rule.Value)
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func isKnownMetadataPlaceholder(name, arg string) bool {
+	switch name {
+	case "name", "path", "ext", "size", "lmt":
+		return arg == ""
+	case "meta":
+		return arg != ""
+	}
+	return false
+}
+
+// Apply returns a transformed copy of the metadata, the input is left untouched.
+// Keys are matched case-insensitively, as the service treats them.
+func (r MetadataRules) Apply(m Metadata, vars MetadataTemplateVars) Metadata {
+	result := Metadata{}
+	for k, v := range m {
+		result[k] = v
+	}
+
+	for _, rule := range r {
+		existingKey, exists := result.findKey(rule.Key)
+
+		switch rule.Action {
+		case EMetadataRuleAction.Add():
+			if !exists {
+				result[rule.Key] = rule.expand(m, vars)
+			}
+		case EMetadataRuleAction.Set():
+			if exists {
+				delete(result, existingKey)
+			}
+			result[rule.Key] = rule.expand(m, vars)
+		case EMetadataRuleAction.Remove():
+			if exists {
+				delete(result, existingKey)
+			}
+		case EMetadataRuleAction.Rename():
+			if exists {
+				value := result[existingKey]
+				delete(result, existingKey)
+				if target, ok := result.findKey(rule.Value); ok {
+					delete(result, target)
+				}
+				result[rule.Value] = value
+			}
+		}
+	}
+
+	return result
+}
+
+func (m Metadata) findKey(key string) (string, bool) {
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// expand fills in the placeholders of the rule's value; {meta:key} reads the metadata as it was before any rule ran.
+func (rule MetadataRule) expand(original Metadata, vars MetadataTemplateVars) string {
+	return metadataTemplatePlaceholder.ReplaceAllStringFunc(rule.Value, func(placeholder string) string {
+		m := metadataTemplatePlaceholder.FindStringSubmatch(placeholder)
+		switch m[1] {
+		case "name":
+			return vars.Name
+		case "path":
+			return vars.RelativePath
+		case "ext":
+			return strings.TrimPrefix(path.Ext(vars.Name), ".")
+		case "size":
+			return strconv.FormatInt(vars.Size, 10)
+		case "lmt":
+			return vars.LastModifiedTime.UTC().Format(time.RFC3339)
+		case "meta":
+			if k, ok := original.findKey(m[2]); ok {
+				return original[k]
+			}
+			return ""
+		}
+		return placeholder
+	})
+}
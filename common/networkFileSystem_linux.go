@@ -0,0 +1,43 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"syscall"
+)
+
+// file system types, as reported by statfs, of network file systems.
+// Statfs_t.Type is signed, and only 32 bits wide on some platforms, so it's compared as a uint32 to match the larger magic numbers
+var networkFileSystemMagicNumbers = map[uint32]bool{
+	0x6969:     true, // NFS
+	0x517B:     true, // SMB
+	0xFF534D42: true, // CIFS
+	0xFE534D42: true, // SMB2
+}
+
+// IsNetworkFileSystem says whether the given path, or its closest existing ancestor, is on a network mount (e.g. NFS or SMB)
+func IsNetworkFileSystem(path string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(closestExistingAncestor(path), &stat); err != nil {
+		return false
+	}
+	return networkFileSystemMagicNumbers[uint32(stat.Type)]
+}
@@ -0,0 +1,195 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+// ManagementResource is the resource used to get a token for Azure Resource Manager (ARM) in the public cloud, which lists the tenants of a user.
+const ManagementResource = "https://management.core.windows.net/"
+const ManagementEndpoint = "https://management.azure.com"
+const listTenantsAPIVersion = "2020-01-01"
+const listSubscriptionsAPIVersion = "2020-01-01"
+
+// the ARM endpoint and resource of each national cloud, keyed by the host of the cloud's AAD endpoint
+var managementEnvironments = map[string]struct{ endpoint, resource string }{
+	"login.microsoftonline.com": {ManagementEndpoint, ManagementResource},
+	"login.chinacloudapi.cn":    {"https://management.chinacloudapi.cn", "https://management.core.chinacloudapi.cn/"},
+	"login.microsoftonline.us":  {"https://management.usgovcloudapi.net", "https://management.core.usgovcloudapi.net/"},
+	"login.microsoftonline.de":  {"https://management.microsoftazure.de", "https://management.core.cloudapi.de/"},
+}
+
+// managementEnvironmentFor returns the ARM endpoint and resource of the cloud that the given AAD endpoint belongs to
+func managementEnvironmentFor(activeDirectoryEndpoint string) (endpoint, resource string, err error) {
+	if activeDirectoryEndpoint == "" {
+		activeDirectoryEndpoint = DefaultActiveDirectoryEndpoint
+	}
+	u, err := url.Parse(activeDirectoryEndpoint)
+	if err != nil {
+		return "", "", err
+	}
+	env, ok := managementEnvironments[strings.ToLower(u.Hostname())]
+	if !ok {
+		return "", "", fmt.Errorf("the Azure Resource Manager endpoint for AAD endpoint %s is not known", activeDirectoryEndpoint)
+	}
+	return env.endpoint, env.resource, nil
+}
+
+// TenantInfo describes an Azure Active Directory tenant the logged in user belongs to.
+type TenantInfo struct {
+	TenantID      string `json:"tenantId"`
+	DisplayName   string `json:"displayName"`
+	DefaultDomain string `json:"defaultDomain"`
+}
+
+// SubscriptionInfo describes an Azure subscription the logged in user can access.
+type SubscriptionInfo struct {
+	SubscriptionID string `json:"subscriptionId"`
+	DisplayName    string `json:"displayName"`
+	TenantID       string `json:"tenantId"`
+	State          string `json:"state"`
+}
+
+// ListTenants lists the tenants the user of a device code login belongs to.
+// The refresh token is exchanged for an Azure Resource Manager token to query them, the storage token is left untouched.
+func (uotm *UserOAuthTokenManager) ListTenants(ctx context.Context, tokenInfo *OAuthTokenInfo) ([]TenantInfo, error) {
+	var result struct {
+		Value []TenantInfo `json:"value"`
+	}
+	if err := uotm.getFromManagement(ctx, tokenInfo, "/tenants?api-version="+listTenantsAPIVersion, &result); err != nil {
+		return nil, fmt.Errorf("failed to list tenants, %v", err)
+	}
+	return result.Value, nil
+}
+
+// ListSubscriptions lists the subscriptions that the user of a device code login can access, in the tenant of the login.
+func (uotm *UserOAuthTokenManager) ListSubscriptions(ctx context.Context, tokenInfo *OAuthTokenInfo) ([]SubscriptionInfo, error) {
+	var result struct {
+		Value []SubscriptionInfo `json:"value"`
+	}
+	if err := uotm.getFromManagement(ctx, tokenInfo, "/subscriptions?api-version="+listSubscriptionsAPIVersion, &result); err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions, %v", err)
+	}
+	return result.Value, nil
+}
+
+// getFromManagement issues a GET to Azure Resource Manager, in the cloud of the login, and unmarshals the JSON response into out
+func (uotm *UserOAuthTokenManager) getFromManagement(ctx context.Context, tokenInfo *OAuthTokenInfo, pathAndQuery string, out interface{}) error {
+	if tokenInfo.Identity || tokenInfo.ServicePrincipalName || tokenInfo.RefreshToken == "" {
+		return errors.New("this is only supported for an interactive user login")
+	}
+
+	endpoint, resource, err := managementEnvironmentFor(tokenInfo.ActiveDirectoryEndpoint)
+	if err != nil {
+		return err
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(tokenInfo.ActiveDirectoryEndpoint, tokenInfo.Tenant)
+	if err != nil {
+		return err
+	}
+	spt, err := adal.NewServicePrincipalTokenFromManualToken(*oauthConfig, ApplicationID, Resource, tokenInfo.Token)
+	if err != nil {
+		return err
+	}
+	spt.SetSender(uotm.oauthClient)
+	if err := spt.RefreshExchangeWithContext(ctx, resource); err != nil {
+		return fmt.Errorf("cannot get a token for Azure Resource Manager, %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint+pathAndQuery, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+spt.OAuthToken())
+
+	resp, err := uotm.oauthClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code %d: %s", resp.StatusCode, string(b))
+	}
+
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("cannot parse the response, %v", err)
+	}
+	return nil
+}
+
+// SwitchTenant gets a storage token for another tenant of the logged in user, using the refresh token of the current login.
+// The tenant is kept with the token, so that later refreshes (and commands using the cached token) stay in that tenant.
+func (uotm *UserOAuthTokenManager) SwitchTenant(ctx context.Context, tokenInfo *OAuthTokenInfo, tenantID string, persist bool) (*OAuthTokenInfo, error) {
+	if tokenInfo.Identity || tokenInfo.ServicePrincipalName || tokenInfo.RefreshToken == "" {
+		return nil, errors.New("the tenant can only be switched for an interactive user login")
+	}
+
+	newInfo := *tokenInfo
+	newInfo.Tenant = tenantID
+
+	token, err := newInfo.RefreshTokenWithUserCredential(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a token for tenant %q, %v", tenantID, err)
+	}
+	newInfo.Token = *token
+
+	// Only replace the login when persisting, a transient token for another tenant must not leak into other requests.
+	if persist {
+		uotm.stashedInfo = &newInfo
+		if err := uotm.credCache.SaveToken(newInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	return &newInfo, nil
+}
+
+// SelectSubscription records the chosen subscription with the login, so that later commands can tell which subscription the user works in.
+func (uotm *UserOAuthTokenManager) SelectSubscription(tokenInfo *OAuthTokenInfo, subscriptionID string, persist bool) (*OAuthTokenInfo, error) {
+	newInfo := *tokenInfo
+	newInfo.SubscriptionID = subscriptionID
+
+	if persist {
+		uotm.stashedInfo = &newInfo
+		if err := uotm.credCache.SaveToken(newInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	return &newInfo, nil
+}
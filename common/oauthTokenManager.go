@@ -525,6 +525,17 @@ func (uotm *UserOAuthTokenManager) HasCachedToken() (bool, error) {
 	return uotm.credCache.HasCachedToken()
 }
 
+// LoadCachedTokenInfo returns the cached token info as stored, without refreshing it.
+// It's used to report on the login state, and returns nil if no token is cached.
+func (uotm *UserOAuthTokenManager) LoadCachedTokenInfo() (*OAuthTokenInfo, error) {
+	hasToken, err := uotm.credCache.HasCachedToken()
+	if err != nil || !hasToken {
+		return nil, err
+	}
+
+	return uotm.credCache.LoadToken()
+}
+
 // RemoveCachedToken delete all the cached token.
 func (uotm *UserOAuthTokenManager) RemoveCachedToken() error {
 	return uotm.credCache.RemoveCachedToken()
@@ -598,6 +609,7 @@ const TokenRefreshSourceTokenStore = "tokenstore"
 type OAuthTokenInfo struct {
 	adal.Token
 	Tenant                  string `json:"_tenant"`
+	SubscriptionID          string `json:"_subscription_id,omitempty"` // chosen during an interactive login, for information only
 	ActiveDirectoryEndpoint string `json:"_ad_endpoint"`
 	TokenRefreshSource      string `json:"_token_refresh_source"`
 	ApplicationID           string `json:"_application_id"`
@@ -696,13 +708,18 @@ func (credInfo *OAuthTokenInfo) GetNewTokenFromTokenStore(ctx context.Context) (
 // GetNewTokenFromMSI gets token from Azure Instance Metadata Service identity endpoint.
 // For details, please refer to https://docs.microsoft.com/en-us/azure/active-directory/managed-identities-azure-resources/overview
 func (credInfo *OAuthTokenInfo) GetNewTokenFromMSI(ctx context.Context) (*adal.Token, error) {
+	return credInfo.getNewTokenFromMSIForResource(ctx, Resource)
+}
+
+// getNewTokenFromMSIForResource gets a token for an arbitrary AAD resource (e.g. Key Vault) from the identity endpoint.
+func (credInfo *OAuthTokenInfo) getNewTokenFromMSIForResource(ctx context.Context, resource string) (*adal.Token, error) {
 	// Prepare request to get token from Azure Instance Metadata Service identity endpoint.
 	req, err := http.NewRequest("GET", MSIEndpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request, %v", err)
 	}
 	params := req.URL.Query()
-	params.Set("resource", Resource)
+	params.Set("resource", resource)
 	params.Set("api-version", IMDSAPIVersion)
 	if credInfo.IdentityInfo.ClientID != "" {
 		params.Set("client_id", credInfo.IdentityInfo.ClientID)
@@ -59,9 +59,11 @@ var EPromptType = PromptType("")
 
 type PromptType string
 
-func (PromptType) Cancel() PromptType            { return PromptType("Cancel") }
-func (PromptType) Overwrite() PromptType         { return PromptType("Overwrite") }
-func (PromptType) DeleteDestination() PromptType { return PromptType("DeleteDestination") }
+func (PromptType) Cancel() PromptType             { return PromptType("Cancel") }
+func (PromptType) Overwrite() PromptType          { return PromptType("Overwrite") }
+func (PromptType) DeleteDestination() PromptType  { return PromptType("DeleteDestination") }
+func (PromptType) SelectTenant() PromptType       { return PromptType("SelectTenant") }
+func (PromptType) SelectSubscription() PromptType { return PromptType("SelectSubscription") }
 
 // -------------------------------------- JSON templates -------------------------------------- //
 // used to help formatting of JSON outputs
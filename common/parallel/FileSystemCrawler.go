@@ -25,6 +25,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
 type FileSystemEntry struct {
@@ -48,21 +49,59 @@ type DirReader interface {
 // The items in the CrawResult output channel are FileSystemEntry s.
 // For a wrapper that makes this look more like filepath.Walk, see parallel.Walk.
 func CrawlLocalDirectory(ctx context.Context, root string, parallelism int, reader DirReader) <-chan CrawlResult {
+	return crawlLocalDirectory(ctx, root, parallelism, reader, nil)
+}
+
+func crawlLocalDirectory(ctx context.Context, root string, parallelism int, reader DirReader, skipped *skippedDirs) <-chan CrawlResult {
 	return Crawl(ctx,
 		root,
 		func(dir Directory, enqueueDir func(Directory), enqueueOutput func(DirectoryEntry, error)) error {
-			return enumerateOneFileSystemDirectory(dir, enqueueDir, enqueueOutput, reader)
+			return enumerateOneFileSystemDirectory(dir, enqueueDir, enqueueOutput, reader, skipped)
 		},
 		parallelism,
 	)
 }
 
+// skippedDirs records the directories for which the walk func returned filepath.SkipDir. The crawler's workers check it
+// too, so that such directories are usually not even read. It's safe for concurrent use.
+type skippedDirs struct {
+	lock sync.RWMutex
+	dirs map[string]struct{}
+}
+
+func (s *skippedDirs) add(dir string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.dirs[dir] = struct{}{}
+}
+
+// covers says whether the path is, or is inside, a skipped directory
+func (s *skippedDirs) covers(path string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.dirs) == 0 {
+		return false
+	}
+	for {
+		if _, ok := s.dirs[path]; ok {
+			return true
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		path = parent
+	}
+}
+
 // Walk is similar to filepath.Walk.
 // But note the following difference is how WalkFunc is used:
 // 1. If fileError passed to walkFunc is not nil, then here the filePath passed to that function will usually be ""
 //    (whereas with filepath.Walk it will usually (always?) have a value).
-// 2. If the return value of walkFunc function is not nil, enumeration will always stop, not matter what the type of the error.
-//    (Unlike filepath.WalkFunc, where returning filePath.SkipDir is handled as a special case).
+// 2. If the return value of walkFunc function is not nil, enumeration will stop, whatever the type of the error,
+//    except that filepath.SkipDir, returned for a directory, skips that directory's contents as it does with filepath.Walk.
+//    Since the directory may already be being read by then, anything found inside it is dropped rather than walked.
 func Walk(root string, parallelism int, parallelStat bool, walkFn filepath.WalkFunc) {
 	signalRootError := func(e error) {
 		_ = walkFn(root, nil, e)
@@ -87,22 +126,32 @@ func Walk(root string, parallelism int, parallelStat bool, walkFn filepath.WalkF
 		return
 	}
 	err = walkFn(root, rs, nil)
-	if err != nil {
+	_ = r.Close()
+	if err == filepath.SkipDir {
+		return
+	} else if err != nil {
 		signalRootError(err)
 		return
 	}
-	_ = r.Close()
 
 	// walk the stuff inside the root
 	reader, remainingParallelism := NewDirReader(parallelism, parallelStat)
 	defer reader.Close()
 	ctx, cancel := context.WithCancel(context.Background())
-	ch := CrawlLocalDirectory(ctx, root, remainingParallelism, reader)
+	skipped := &skippedDirs{dirs: make(map[string]struct{})}
+	ch := crawlLocalDirectory(ctx, root, remainingParallelism, reader, skipped)
 	for crawlResult := range ch {
 		entry, err := crawlResult.Item()
 		if err == nil {
 			fsEntry := entry.(FileSystemEntry)
+			if skipped.covers(filepath.Dir(fsEntry.fullPath)) {
+				continue
+			}
 			err = walkFn(fsEntry.fullPath, fsEntry.info, nil)
+			if err == filepath.SkipDir && fsEntry.info.IsDir() {
+				skipped.add(fsEntry.fullPath)
+				err = nil
+			}
 		} else {
 			err = walkFn("", nil, err) // cannot supply path here, because crawlResult probably doesn't have one, due to the error
 		}
@@ -114,9 +163,13 @@ func Walk(root string, parallelism int, parallelStat bool, walkFn filepath.WalkF
 }
 
 // enumerateOneFileSystemDirectory is an implementation of EnumerateOneDirFunc specifically for the local file system
-func enumerateOneFileSystemDirectory(dir Directory, enqueueDir func(Directory), enqueueOutput func(DirectoryEntry, error), r DirReader) error {
+func enumerateOneFileSystemDirectory(dir Directory, enqueueDir func(Directory), enqueueOutput func(DirectoryEntry, error), r DirReader, skipped *skippedDirs) error {
 	dirString := dir.(string)
 
+	if skipped != nil && skipped.covers(dirString) {
+		return nil // the walk func has already said it doesn't want anything from in here
+	}
+
 	d, err := os.Open(dirString) // for directories, we don't need a special open with FILE_FLAG_BACKUP_SEMANTICS, because directory opening uses FindFirst which doesn't need that flag. https://blog.differentpla.net/blog/2007/05/25/findfirstfile-and-se_backup_name
 	if err != nil {
 		return err
@@ -0,0 +1,131 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// POSIXIDMap maps local POSIX user and group IDs, or Windows SIDs, to the Azure AD object IDs which ADLS Gen2 expects
+// as principals. Owners, owning groups and named ACL entries whose IDs aren't in the map are not preserved.
+type POSIXIDMap struct {
+	Users     map[uint32]string
+	Groups    map[uint32]string
+	UserSIDs  map[string]string
+	GroupSIDs map[string]string
+}
+
+// User returns the object ID of the given local user, if it's mapped
+func (m POSIXIDMap) User(uid uint32) (string, bool) {
+	objectID, ok := m.Users[uid]
+	return objectID, ok
+}
+
+// Group returns the object ID of the given local group, if it's mapped
+func (m POSIXIDMap) Group(gid uint32) (string, bool) {
+	objectID, ok := m.Groups[gid]
+	return objectID, ok
+}
+
+// UserSID returns the object ID of the given Windows user SID, if it's mapped
+func (m POSIXIDMap) UserSID(sid string) (string, bool) {
+	objectID, ok := m.UserSIDs[strings.ToUpper(sid)]
+	return objectID, ok
+}
+
+// GroupSID returns the object ID of the given Windows group SID, if it's mapped
+func (m POSIXIDMap) GroupSID(sid string) (string, bool) {
+	objectID, ok := m.GroupSIDs[strings.ToUpper(sid)]
+	return objectID, ok
+}
+
+// IsSID tells whether a principal is a Windows SID, e.g. S-1-5-21-..., rather than a numeric POSIX ID
+func IsSID(id string) bool {
+	return len(id) > 2 && strings.EqualFold(id[:2], "S-")
+}
+
+// ParsePOSIXIDMapFile reads a map with one entry per line, either user:<id>=<object ID> or group:<id>=<object ID>,
+// where the ID is a uid or gid, or a SID for sources on Windows. Empty lines and lines starting with # are ignored.
+func ParsePOSIXIDMapFile(path string) (POSIXIDMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return POSIXIDMap{}, err
+	}
+	defer f.Close()
+
+	result := POSIXIDMap{Users: map[uint32]string{}, Groups: map[uint32]string{}, UserSIDs: map[string]string{}, GroupSIDs: map[string]string{}}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err = result.addEntry(line); err != nil {
+			return POSIXIDMap{}, fmt.Errorf("%s, line %d: %w", path, lineNum, err)
+		}
+	}
+	return result, scanner.Err()
+}
+
+func (m POSIXIDMap) addEntry(line string) error {
+	kindAndID, objectID := line, ""
+	if i := strings.Index(line, "="); i >= 0 {
+		kindAndID, objectID = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	}
+	if objectID == "" {
+		return fmt.Errorf("expected user:<id>=<object ID> or group:<id>=<object ID>, got %q", line)
+	}
+
+	parts := strings.SplitN(kindAndID, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected user:<id> or group:<id>, got %q", kindAndID)
+	}
+	if IsSID(parts[1]) {
+		sid := strings.ToUpper(parts[1])
+		switch parts[0] {
+		case "user":
+			m.UserSIDs[sid] = objectID
+		case "group":
+			m.GroupSIDs[sid] = objectID
+		default:
+			return fmt.Errorf("unknown kind %q, expected user or group", parts[0])
+		}
+		return nil
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid ID %q", parts[1])
+	}
+
+	switch parts[0] {
+	case "user":
+		m.Users[uint32(id)] = objectID
+	case "group":
+		m.Groups[uint32(id)] = objectID
+	default:
+		return fmt.Errorf("unknown kind %q, expected user or group", parts[0])
+	}
+	return nil
+}
@@ -24,8 +24,11 @@ func (RpcCmd) ListSyncJobSummary() RpcCmd { return RpcCmd("ListSyncJobSummary")
 func (RpcCmd) ListJobTransfers() RpcCmd   { return RpcCmd("ListJobTransfers") }
 func (RpcCmd) CancelJob() RpcCmd          { return RpcCmd("Cancel") }
 func (RpcCmd) PauseJob() RpcCmd           { return RpcCmd("PauseJob") }
+func (RpcCmd) CancelTransfer() RpcCmd     { return RpcCmd("CancelTransfer") }
 func (RpcCmd) ResumeJob() RpcCmd          { return RpcCmd("ResumeJob") }
 func (RpcCmd) GetJobFromTo() RpcCmd       { return RpcCmd("GetJobFromTo") }
+func (RpcCmd) ReleaseJob() RpcCmd         { return RpcCmd("ReleaseJob") }
+func (RpcCmd) CancelJobAnywhere() RpcCmd  { return RpcCmd("CancelJobAnywhere") }
 
 func (c RpcCmd) String() string {
 	return enum.String(c, reflect.TypeOf(c))
@@ -130,9 +133,21 @@ type CopyJobPartOrderRequest struct {
 	BlobAttributes BlobTransferAttributes
 	CommandString  string // commandString hold the user given command which is logged to the Job log file
 	CredentialInfo CredentialInfo
+	// S2SSourceCredentialInfo is set when the source of a service to service copy is authorized with OAuth,
+	// which could be in a different tenant than the destination.
+	S2SSourceCredentialInfo CredentialInfo
+	// S2SSourceTenantID is the tenant that S2SSourceCredentialInfo's token is for. It's kept in the plan, so that resume can get a new token
+	S2SSourceTenantID string
+	// POSIXIDMap maps local owners to the principals of ADLS Gen2. Like the credentials, it's only kept in memory
+	POSIXIDMap POSIXIDMap
 
 	PreserveSMBPermissions         PreservePermissionsOption
 	PreserveSMBInfo                bool
+	PermissionsOnly                bool          // only apply permissions and properties to destinations which already exist, without sending any data
+	SkipLargerThan                 int64         // files larger than this many bytes are skipped (0 means no limit)
+	SkipSmallerThan                int64         // files smaller than this many bytes are skipped (0 means no limit)
+	TransferTimeout                time.Duration // transfers still running after this long are failed (0 means no limit)
+	DeleteSourceAfterTransfer      bool          // each file's source is deleted once it has been transferred successfully, so the job is a move
 	S2SGetPropertiesInBackend      bool
 	S2SSourceChangeValidation      bool
 	DestLengthValidation           bool
@@ -192,8 +207,11 @@ type BlobTransferAttributes struct {
 	Metadata                 string                // User-defined Name-value pairs associated with the blob
 	NoGuessMimeType          bool                  // represents user decision to interpret the content-encoding from source file
 	PreserveLastModifiedTime bool                  // when downloading, tell engine to set file's timestamp to timestamp of blob
+	PreserveLastAccessTime   bool                  // when downloading, tell engine to set file's access time to the blob's last access time
 	PutMd5                   bool                  // when uploading, should we create and PUT Content-MD5 hashes
 	MD5ValidationOption      HashValidationOption  // when downloading, how strictly should we validate MD5 hashes?
+	FlushPolicy              FlushPolicy           // when downloading, when to commit written data to durable storage
+	FreeSpaceCheck           FreeSpaceCheckOption  // when downloading, what to do if the destination doesn't have enough free space
 	BlockSizeInBytes         int64                 // when uploading/downloading/copying, specify the size of each chunk
 	DeleteSnapshotsOption    DeleteSnapshotsOption // when deleting, specify what to do with the snapshots
 	BlobTagsString           string                // when user explicitly provides blob tags
@@ -239,9 +257,17 @@ type ListJobSummaryResponse struct {
 	TransfersCompleted uint32 `json:",string"`
 	TransfersFailed    uint32 `json:",string"`
 	TransfersSkipped   uint32 `json:",string"`
+	// the part of TransfersSkipped that the user asked for, with --skip-larger-than or --skip-smaller-than
+	TransfersSkippedByFilter uint32 `json:",string"`
 
 	// includes bytes sent in retries (i.e. has double counting, if there are retries) and in failed transfers
 	BytesOverWire uint64 `json:",string"`
+	// the part of BytesOverWire that was in request bodies of tries that failed, and so was sent again if the request was retried.
+	// Like BytesOverWire, it's only known to the process running the job
+	BytesRetried uint64 `json:",string"`
+	// what the skipped transfers come to, i.e. bytes that never had to cross the wire. For sync, this includes the files that were
+	// already up to date at the destination, so never became transfers
+	BytesSkipped uint64 `json:",string"`
 
 	// does not include failed transfers or bytes sent in retries (i.e. no double counting). Includes successful transfers and transfers in progress
 	TotalBytesTransferred uint64 `json:",string"`
@@ -253,6 +279,10 @@ type ListJobSummaryResponse struct {
 
 	PercentComplete float32 `json:",string"`
 
+	// how many more seconds the job is likely to take, predicted from its throughput and per-file overhead over the last minute or so.
+	// It is -1 while there is too little recent progress to go on, and covers only the transfers that have been enumerated so far
+	EstimatedSecondsRemaining int64 `json:",string"`
+
 	// Stats measured from the network pipeline
 	// Values are all-time values, for the duration of the job.
 	// Will be zero if read outside the process running the job (e.g. with 'jobs show' command)
@@ -260,14 +290,55 @@ type ListJobSummaryResponse struct {
 	AverageE2EMilliseconds int     `json:",string"`
 	ServerBusyPercentage   float32 `json:",string"`
 	NetworkErrorPercentage float32 `json:",string"`
+	// unlike the values above, these two count from the start of the job, even while the concurrency is being tuned
+	RetryCount      uint64 `json:",string"` // tries that failed in a way that is retried, i.e. network errors, timeouts, throttling and server errors
+	ServerBusyCount uint64 `json:",string"` // the 503s among them
+	// how requests got their connections, also counted from the start of the job. Few re-used connections or resumed TLS sessions
+	// suggest that something between us and the service, such as a proxy or firewall, is closing connections
+	NewConnectionCount     uint64 `json:",string"`
+	ReusedConnectionCount  uint64 `json:",string"`
+	TLSHandshakeCount      uint64 `json:",string"`
+	ResumedTLSSessionCount uint64 `json:",string"`
+	// destinations that were replaced, and how many of those were blobs that kept their previous content as a version.
+	// Only destinations that were checked for before their transfer are counted, i.e. none when the overwrite option is true
+	Overwrites          uint32 `json:",string"`
+	VersionedOverwrites uint32 `json:",string"`
+	// successful transfers that left some property of their source behind, such as ACL entries that couldn't be mapped
+	// or metadata that was invalid at the destination
+	TransfersWithPropertiesNotPreserved uint32 `json:",string"`
+	// pages of page blob uploads, such as the unused parts of a sparse VHD, that weren't sent because they were empty
+	EmptyPagesSkipped uint64 `json:",string"`
+	// percentiles of the end-to-end time of recent requests
+	MedianE2EMilliseconds int `json:",string"`
+	P95E2EMilliseconds    int `json:",string"`
+	P99E2EMilliseconds    int `json:",string"`
+	// how long the job's successful file transfers took, each from its last start to when it was done, and the slowest of them,
+	// slowest first. These are only worked out once the job is done
+	MinTransferMilliseconds    int64 `json:",string"`
+	MedianTransferMilliseconds int64 `json:",string"`
+	P95TransferMilliseconds    int64 `json:",string"`
+	MaxTransferMilliseconds    int64 `json:",string"`
+	SlowestTransfers           []TransferDuration
 
 	FailedTransfers  []TransferDetail
 	SkippedTransfers []TransferDetail
-	PerfConstraint   PerfConstraint
-	PerfStrings      []string `json:"-"`
+	// FailedTransfers and SkippedTransfers only list so many transfers. When there are more, this file has the details
+	// of all of them, one TransferDetail in JSON per line, as of when the job's summary was last saved
+	TransferDetailsFile string `json:",omitempty"`
+	PerfConstraint      PerfConstraint
+	PerfStrings         []string `json:"-"`
 
 	PerformanceAdvice []PerformanceAdvice
 	IsCleanupJob      bool
+
+	// followed symlinks whose targets don't exist. These are skipped by the front end, during enumeration, so they are not transfers
+	DanglingSymlinksSkipped uint32 `json:",string"`
+	// hidden files left out of an upload. Like dangling symlinks, these are never transfers
+	HiddenFilesSkipped uint32 `json:",string"`
+	// the files found by the enumeration so far, and how many bytes they come to, including those not yet sent to the
+	// transfer engine. Like the counts above, only the front end knows these, so they're zero in 'jobs show'
+	FilesDiscovered uint32 `json:",string"`
+	BytesDiscovered uint64 `json:",string"`
 }
 
 // wraps the standard ListJobSummaryResponse with sync-specific stats
@@ -289,6 +360,13 @@ type ResumeJobRequest struct {
 	IncludeTransfer map[string]int
 	ExcludeTransfer map[string]int
 	CredentialInfo  CredentialInfo
+	// the source's own credential, for service to service copies whose source was authorized with a token from another tenant
+	S2SSourceCredentialInfo CredentialInfo
+	POSIXIDMap              POSIXIDMap
+	// where the source root of an upload is now, if its volume has been remounted at a different path. Empty otherwise
+	SourceRootOverride string
+	// the SAS of each --also-to destination of an upload, by its root. DestinationSAS is for the main destination
+	AlsoToSAS map[string]string
 }
 
 // represents the Details and details of a single transfer
@@ -300,6 +378,23 @@ type TransferDetail struct {
 	ErrorCode          int32 `json:",string"`
 }
 
+// TransferDuration is how long one successful file transfer took, and how much it moved in that time
+type TransferDuration struct {
+	Src          string
+	Dst          string
+	Bytes        int64 `json:",string"`
+	Milliseconds int64 `json:",string"`
+}
+
+// CancelTransferRequest identifies the one transfer of a job that is to be cancelled
+type CancelTransferRequest struct {
+	JobID JobID
+	// the transfer's source or destination, as listed by jobs show, or relative to the job's source or destination
+	Path string
+	// the transfer's position in the job, counting from 0 across all its parts, which identifies it when Path is empty
+	Index uint32
+}
+
 type CancelPauseResumeResponse struct {
 	ErrorMsg              string
 	CancelledPauseResumed bool
@@ -323,4 +418,6 @@ type GetJobFromToResponse struct {
 	FromTo      FromTo
 	Source      string
 	Destination string
+	// the tenant that the source was authorized in, when it was given with --s2s-source-tenant-id
+	S2SSourceTenantID string
 }
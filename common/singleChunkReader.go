@@ -39,6 +39,8 @@ import (
 // transmitted chunk in RAM until acknowledged by the service.  We just re-read if the service says we need to retry.
 // Although there's a time (performance) cost in the re-read, that's fine in a retry situation because the retry
 // indicates we were going too fast for the service anyway.
+// Where re-reading is costly (slow sources), the reader can instead keep the data until it's closed, so that every
+// byte is read from the source exactly once.
 type SingleChunkReader interface {
 
 	// ReadSeeker is used to read the contents of the chunk, and because the sending pipeline seeks at various times
@@ -66,11 +68,23 @@ type SingleChunkReader interface {
 	// we'll just treat it as a non-zero chunk. That's simpler (to code, to review and to test) than having this code force a prefetch.
 	HasPrefetchedEntirelyZeros() bool
 
+	// PrefetchedZeroRuns finds the runs of zeros in a chunk that isn't entirely zeros, so that destinations with "sparse file"
+	// semantics can skip those parts of it. Runs are made of whole blocks of alignment bytes (counted from the start of the
+	// chunk, with the last block possibly shorter), and only those at least minLength long are returned.
+	// Like HasPrefetchedEntirelyZeros, it finds none if the chunk hasn't been prefetched.
+	PrefetchedZeroRuns(alignment, minLength int64) []ZeroRun
+
 	// WriteBufferTo writes the entire contents of the prefetched buffer to h
 	// Panics if the internal buffer has not been prefetched (or if its been discarded after a complete Read)
 	WriteBufferTo(h hash.Hash)
 }
 
+// ZeroRun is a range of a chunk that holds nothing but zeros. Offset is from the start of the chunk
+type ZeroRun struct {
+	Offset int64
+	Length int64
+}
+
 // Simple aggregation of existing io interfaces
 type CloseableReaderAt interface {
 	io.ReaderAt
@@ -146,6 +160,10 @@ type singleChunkReader struct {
 	// buffer used by prefetch
 	buffer []byte
 
+	// if true, the buffer isn't thrown away when it has all been read, but only on Close, so that a retry never
+	// re-reads the source
+	keepBufferUntilClose bool
+
 	// muMaster locks everything for single-threaded use...
 	muMaster *sync.Mutex
 
@@ -157,7 +175,7 @@ type singleChunkReader struct {
 	isClosed bool
 }
 
-func NewSingleChunkReader(ctx context.Context, sourceFactory ChunkReaderSourceFactory, chunkId ChunkID, length int64, chunkLogger ChunkStatusLogger, generalLogger ILogger, slicePool ByteSlicePooler, cacheLimiter CacheLimiter) SingleChunkReader {
+func NewSingleChunkReader(ctx context.Context, sourceFactory ChunkReaderSourceFactory, chunkId ChunkID, length int64, chunkLogger ChunkStatusLogger, generalLogger ILogger, slicePool ByteSlicePooler, cacheLimiter CacheLimiter, keepBufferUntilClose bool) SingleChunkReader {
 	if length <= 0 {
 		return &emptyChunkReader{}
 	}
@@ -172,6 +190,8 @@ func NewSingleChunkReader(ctx context.Context, sourceFactory ChunkReaderSourceFa
 		sourceFactory: sourceFactory,
 		chunkId:       chunkId,
 		length:        length,
+
+		keepBufferUntilClose: keepBufferUntilClose,
 	}
 }
 
@@ -207,6 +227,46 @@ func (cr *singleChunkReader) HasPrefetchedEntirelyZeros() bool {
 	//       and (c) we would want to check whether it really did offer meaningful real-world performance gain, before introducing use of unsafe.
 }
 
+func (cr *singleChunkReader) PrefetchedZeroRuns(alignment, minLength int64) []ZeroRun {
+	cr.use()
+	defer cr.unuse()
+
+	if cr.buffer == nil {
+		return nil // not prefetched, as for HasPrefetchedEntirelyZeros
+	}
+
+	var runs []ZeroRun
+	runStart := int64(-1)
+	endRun := func(end int64) {
+		if runStart >= 0 && end-runStart >= minLength {
+			runs = append(runs, ZeroRun{Offset: runStart, Length: end - runStart})
+		}
+		runStart = -1
+	}
+	for blockStart := int64(0); blockStart < cr.length; blockStart += alignment {
+		blockEnd := blockStart + alignment
+		if blockEnd > cr.length {
+			blockEnd = cr.length
+		}
+		if !isEntirelyZeros(cr.buffer[blockStart:blockEnd]) {
+			endRun(blockStart)
+		} else if runStart < 0 {
+			runStart = blockStart
+		}
+	}
+	endRun(cr.length)
+	return runs
+}
+
+func isEntirelyZeros(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func (cr *singleChunkReader) BlockingPrefetch(fileReader io.ReaderAt, isRetry bool) error {
 	cr.use()
 	defer cr.unuse()
@@ -321,7 +381,8 @@ func (cr *singleChunkReader) Read(p []byte) (n int, err error) {
 	// This is a normal read, so free the prefetch buffer when hit EOF (i.e. end of this chunk).
 	// We do so on the assumption that if we've read to the end we don't need the prefetched data any longer.
 	// (If later, there's a retry that forces seek back to start and re-read, we'll automatically trigger a re-fetch at that time)
-	return cr.doRead(p, true)
+	// Unless we were asked to keep it, in which case Close frees it.
+	return cr.doRead(p, !cr.keepBufferUntilClose)
 }
 
 func (cr *singleChunkReader) doRead(p []byte, freeBufferOnEof bool) (n int, err error) {
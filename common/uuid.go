@@ -2,6 +2,7 @@ package common
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"unsafe"
@@ -40,6 +41,18 @@ func NewUUID() (u UUID) {
 	return
 }
 
+// NewUUIDFromHash returns a UUID derived from a SHA-256 hash of the given bytes, so the same bytes always give the same UUID.
+func NewUUIDFromHash(b []byte) (u UUID) {
+	sum := sha256.Sum256(b)
+	uuid := (*[16]byte)(unsafe.Pointer(&u))[:]
+	copy(uuid, sum[:16])
+	uuid[8] = (uuid[8] | reservedRFC4122) & 0x7F // u.setVariant(ReservedRFC4122)
+
+	var version byte = 8                       // custom, which RFC 9562 allows for UUIDs made from hashes other than SHA-1
+	uuid[6] = (uuid[6] & 0xF) | (version << 4) // u.setVersion(8)
+	return
+}
+
 // String returns an unparsed version of the generated UUID sequence.
 func (u UUID) String() string {
 	return fmt.Sprintf(guidFormat, u.D1, u.D2, u.D3, u.D4[0], u.D4[1], u.D4[2], u.D4[3], u.D4[4], u.D4[5], u.D4[6], u.D4[7])
@@ -42,3 +42,13 @@ func (s *uuidTestSuite) TestGUIDGenerationAndParsing(c *chk.C) {
 		c.Assert(parsed, chk.DeepEquals, uuid)
 	}
 }
+
+func (s *uuidTestSuite) TestGUIDFromHash(c *chk.C) {
+	uuid := NewUUIDFromHash([]byte("copy /data https://account.blob.core.windows.net/container"))
+	c.Assert(NewUUIDFromHash([]byte("copy /data https://account.blob.core.windows.net/container")), chk.DeepEquals, uuid)
+	c.Assert(NewUUIDFromHash([]byte("copy /data2 https://account.blob.core.windows.net/container")), chk.Not(chk.DeepEquals), uuid)
+
+	parsed, err := ParseUUID(uuid.String())
+	c.Assert(err, chk.IsNil)
+	c.Assert(parsed, chk.DeepEquals, uuid)
+}
@@ -22,6 +22,7 @@ package common
 
 import (
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
@@ -31,6 +32,10 @@ const IncludeAfterFlagName = "include-after"
 const BackupModeFlagName = "backup" // original name, backup mode, matches the name used for the same thing in Robocopy
 const PreserveOwnerFlagName = "preserve-owner"
 const PreserveOwnerDefault = true
+const PreserveSMBInfoFlagName = "preserve-smb-info"
+const FailOnDanglingSymlinksFlagName = "fail-on-dangling-symlinks"
+const SkipLargerThanFlagName = "skip-larger-than"
+const SkipSmallerThanFlagName = "skip-smaller-than"
 
 // The regex doesn't require a / on the ending, it just requires something similar to the following
 // C:
@@ -90,3 +95,39 @@ func CreateDirectoryIfNotExist(directory string, tracker FolderCreationTracker)
 		return nil
 	}
 }
+
+// closestExistingAncestor returns path itself if it exists, or else its nearest parent directory that does.
+// Useful for looking up the volume of a destination that hasn't been created yet
+func closestExistingAncestor(path string) string {
+	for {
+		if _, err := OSStat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// SyncOnCloseFile is a file whose contents are committed to durable storage (with fsync, or FlushFileBuffers on Windows)
+// when it's closed, so that it has been fully saved once Close returns without error
+type SyncOnCloseFile struct {
+	*os.File
+
+	// ShouldSync, if set, is asked at close time whether the file is still wanted.
+	// One that's about to be deleted, e.g. because its transfer failed, is just closed
+	ShouldSync func() bool
+}
+
+func (f SyncOnCloseFile) Close() error {
+	var err error
+	if f.ShouldSync == nil || f.ShouldSync() {
+		err = f.File.Sync()
+	}
+	if closeErr := f.File.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
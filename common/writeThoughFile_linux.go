@@ -25,7 +25,7 @@ import (
 	"syscall"
 )
 
-func CreateFileOfSizeWithWriteThroughOption(destinationPath string, fileSize int64, writeThrough bool, t FolderCreationTracker, forceIfReadOnly bool) (*os.File, error) {
+func CreateFileOfSizeWithWriteThroughOption(destinationPath string, fileSize int64, writeThrough bool, directIO bool, t FolderCreationTracker, forceIfReadOnly bool) (*os.File, error) {
 	// forceIfReadOnly is not used on this OS
 
 	err := CreateParentDirectoryIfNotExist(destinationPath, t)
@@ -38,7 +38,14 @@ func CreateFileOfSizeWithWriteThroughOption(destinationPath string, fileSize int
 		// TODO: conduct further testing of this code path, on Linux
 		flags = flags | os.O_SYNC // technically, O_DSYNC may be very slightly faster, but its not exposed in the os package
 	}
+	if directIO {
+		flags = flags | syscall.O_DIRECT
+	}
 	f, err := os.OpenFile(destinationPath, flags, DEFAULT_FILE_PERM)
+	if err == syscall.EINVAL && directIO {
+		// the file system (e.g. tmpfs) doesn't support O_DIRECT, so just use normal IO. The direct IO writer still works on such files
+		f, err = os.OpenFile(destinationPath, flags&^syscall.O_DIRECT, DEFAULT_FILE_PERM)
+	}
 	if err != nil {
 		return nil, err
 	}
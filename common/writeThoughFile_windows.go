@@ -67,12 +67,20 @@ func GetFileInformation(path string) (windows.ByHandleFileInformation, error) {
 	return info, err
 }
 
-func CreateFileOfSizeWithWriteThroughOption(destinationPath string, fileSize int64, writeThrough bool, tracker FolderCreationTracker, forceIfReadOnly bool) (*os.File, error) {
+func CreateFileOfSizeWithWriteThroughOption(destinationPath string, fileSize int64, writeThrough bool, directIO bool, tracker FolderCreationTracker, forceIfReadOnly bool) (*os.File, error) {
 	const FILE_ATTRIBUTE_READONLY = windows.FILE_ATTRIBUTE_READONLY
 	const FILE_ATTRIBUTE_HIDDEN = windows.FILE_ATTRIBUTE_HIDDEN
 
+	var extraAttributes uint32
+	if writeThrough {
+		extraAttributes |= FILE_ATTRIBUTE_WRITE_THROUGH
+	}
+	if directIO {
+		extraAttributes |= windows.FILE_FLAG_NO_BUFFERING
+	}
+
 	doOpen := func() (windows.Handle, error) {
-		return OpenWithWriteThroughSetting(destinationPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, DEFAULT_FILE_PERM, writeThrough)
+		return openWithAttributes(destinationPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, extraAttributes)
 	}
 
 	getFlagMatches := func(flags uint32) (matches uint32, allFlags uint32, retry bool) {
@@ -171,6 +179,16 @@ const FILE_ATTRIBUTE_WRITE_THROUGH = 0x80000000
 // Furthermore, all of the os, syscall, and windows packages line up. So, putting in os.O_RDWR or whatever of that nature into mode works fine.
 // Param "perm" is unused both here and in the original Windows version of this routine.
 func OpenWithWriteThroughSetting(path string, mode int, perm uint32, writeThrough bool) (fd windows.Handle, err error) {
+	var extraAttributes uint32
+	if writeThrough {
+		extraAttributes = FILE_ATTRIBUTE_WRITE_THROUGH
+	}
+	return openWithAttributes(path, mode, extraAttributes)
+}
+
+// openWithAttributes does the work of OpenWithWriteThroughSetting, adding the given attributes and flags
+// (such as FILE_FLAG_NO_BUFFERING) to the ones that are always used
+func openWithAttributes(path string, mode int, extraAttributes uint32) (fd windows.Handle, err error) {
 	if len(path) == 0 {
 		return windows.InvalidHandle, windows.ERROR_FILE_NOT_FOUND
 	}
@@ -215,10 +233,7 @@ func OpenWithWriteThroughSetting(path string, mode int, perm uint32, writeThroug
 	}
 
 	var attr uint32
-	attr = windows.FILE_ATTRIBUTE_NORMAL | windows.FILE_FLAG_BACKUP_SEMANTICS
-	if writeThrough {
-		attr |= FILE_ATTRIBUTE_WRITE_THROUGH
-	}
+	attr = windows.FILE_ATTRIBUTE_NORMAL | windows.FILE_FLAG_BACKUP_SEMANTICS | extraAttributes
 	h, e := windows.CreateFile(pathp, access, sharemode, sa, createmode, attr, 0)
 	return h, e
 }
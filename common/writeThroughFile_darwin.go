@@ -26,8 +26,8 @@ import (
 	"os"
 )
 
-func CreateFileOfSizeWithWriteThroughOption(destinationPath string, fileSize int64, writeThrough bool, t FolderCreationTracker, forceIfReadOnly bool) (*os.File, error) {
-	// forceIfReadOnly is not used on this OS
+func CreateFileOfSizeWithWriteThroughOption(destinationPath string, fileSize int64, writeThrough bool, directIO bool, t FolderCreationTracker, forceIfReadOnly bool) (*os.File, error) {
+	// forceIfReadOnly and directIO are not used on this OS
 
 	err := CreateParentDirectoryIfNotExist(destinationPath, t)
 	if err != nil {
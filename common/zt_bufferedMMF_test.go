@@ -0,0 +1,106 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+)
+
+type bufferedMMFSuite struct{}
+
+var _ = chk.Suite(&bufferedMMFSuite{})
+
+func (s *bufferedMMFSuite) TestChangesAreWrittenBackToFile(c *chk.C) {
+	dir, err := ioutil.TempDir("", "bufferedMMF")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "plan")
+	original := bytes.Repeat([]byte{1}, 3*bufferedMMFBlockSize+100)
+	c.Assert(ioutil.WriteFile(path, original, 0644), chk.IsNil)
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	c.Assert(err, chk.IsNil)
+	m, err := NewBufferedMMF(file, true, 0, int64(len(original)))
+	c.Assert(err, chk.IsNil)
+	c.Assert(file.Close(), chk.IsNil) // as with a memory map, the mapping outlives the caller's handle
+	c.Assert(bytes.Equal(m.Slice(), original), chk.Equals, true)
+
+	// changes are saved by Flush
+	m.Slice()[bufferedMMFBlockSize+10] = 2
+	c.Assert(m.Flush(), chk.IsNil)
+	onDisk, err := ioutil.ReadFile(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(bytes.Equal(onDisk, m.Slice()), chk.Equals, true)
+
+	// and by Unmap, including in the last, partial, block
+	expected := append([]byte(nil), m.Slice()...)
+	expected[len(expected)-1] = 3
+	m.Slice()[len(expected)-1] = 3
+	c.Assert(m.UseMMF(), chk.Equals, true)
+	m.UnuseMMF()
+	m.Unmap()
+	c.Assert(m.UseMMF(), chk.Equals, false)
+
+	onDisk, err = ioutil.ReadFile(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(bytes.Equal(onDisk, expected), chk.Equals, true)
+}
+
+func (s *bufferedMMFSuite) TestFlushWhileSliceIsUpdated(c *chk.C) {
+	dir, err := ioutil.TempDir("", "bufferedMMF")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "plan")
+	c.Assert(ioutil.WriteFile(path, make([]byte, 2*bufferedMMFBlockSize+2), 0644), chk.IsNil)
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	c.Assert(err, chk.IsNil)
+	m, err := NewBufferedMMF(file, true, 0, 2*bufferedMMFBlockSize+2)
+	c.Assert(err, chk.IsNil)
+	c.Assert(file.Close(), chk.IsNil)
+
+	// the plan's users update it with atomic stores, concurrently with flushing (which the race detector checks)
+	counter := (*uint32)(unsafe.Pointer(&m.Slice()[bufferedMMFBlockSize]))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			atomic.AddUint32(counter, 1)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		c.Assert(m.Flush(), chk.IsNil)
+	}
+	<-done
+	m.Slice()[len(m.Slice())-1] = 7 // in the bytes after the last whole word
+	m.Unmap()
+
+	onDisk, err := ioutil.ReadFile(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(*(*uint32)(unsafe.Pointer(&onDisk[bufferedMMFBlockSize])), chk.Equals, uint32(1000))
+	c.Assert(onDisk[len(onDisk)-1], chk.Equals, byte(7))
+}
@@ -0,0 +1,129 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type chunkedFileWriterSuite struct{}
+
+var _ = chk.Suite(&chunkedFileWriterSuite{})
+
+func (s *chunkedFileWriterSuite) TestReorderWindowBoundsChunksAhead(c *chk.C) {
+	ctx := context.Background()
+	const chunkSize = 4
+	file := &closeableBuffer{Buffer: &bytes.Buffer{}}
+	logger := NewChunkStatusLogger(NewJobID(), NewNullCpuMonitor(), "", false)
+	w := NewChunkedFileWriter(ctx, NewMultiSizeSlicePool(1024), NewCacheLimiter(1024), logger, file, 3, 0, EHashValidationOption.NoCheck(), false, 2, EFlushPolicy.Never())
+
+	ids := []ChunkID{NewChunkID("f", 0, chunkSize), NewChunkID("f", chunkSize, chunkSize), NewChunkID("f", 2*chunkSize, chunkSize)}
+	contents := []string{"aaaa", "bbbb", "cccc"}
+
+	// the first two chunks are within the window
+	c.Assert(w.WaitToScheduleChunk(ctx, ids[0], chunkSize), chk.IsNil)
+	c.Assert(w.WaitToScheduleChunk(ctx, ids[1], chunkSize), chk.IsNil)
+
+	// the third must wait until the first has been written
+	scheduled := make(chan error, 1)
+	go func() { scheduled <- w.WaitToScheduleChunk(ctx, ids[2], chunkSize) }()
+	select {
+	case <-scheduled:
+		c.Fatal("chunk was scheduled beyond the reorder window")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	c.Assert(w.EnqueueChunk(ctx, ids[0], chunkSize, bytes.NewReader([]byte(contents[0])), false), chk.IsNil)
+	select {
+	case err := <-scheduled:
+		c.Assert(err, chk.IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("chunk was not scheduled once the window moved")
+	}
+
+	// the rest may arrive out of order, and are still written in order
+	c.Assert(w.EnqueueChunk(ctx, ids[2], chunkSize, bytes.NewReader([]byte(contents[2])), false), chk.IsNil)
+	c.Assert(w.EnqueueChunk(ctx, ids[1], chunkSize, bytes.NewReader([]byte(contents[1])), false), chk.IsNil)
+	_, err := w.Flush(ctx)
+	c.Assert(err, chk.IsNil)
+	c.Assert(file.String(), chk.Equals, "aaaabbbbcccc")
+}
+
+// records what had been written each time it was synced
+type syncRecordingBuffer struct {
+	closeableBuffer
+	contentAtEachSync []string
+}
+
+func (b *syncRecordingBuffer) Sync() error {
+	b.contentAtEachSync = append(b.contentAtEachSync, b.String())
+	return nil
+}
+
+func (s *chunkedFileWriterSuite) TestPerChunkFlushPolicySyncsAfterEachChunk(c *chk.C) {
+	ctx := context.Background()
+	const chunkSize = 4
+	logger := NewChunkStatusLogger(NewJobID(), NewNullCpuMonitor(), "", false)
+
+	for _, policy := range []FlushPolicy{EFlushPolicy.Never(), EFlushPolicy.PerFile(), EFlushPolicy.PerChunk()} {
+		file := &syncRecordingBuffer{closeableBuffer: closeableBuffer{Buffer: &bytes.Buffer{}}}
+		w := NewChunkedFileWriter(ctx, NewMultiSizeSlicePool(1024), NewCacheLimiter(1024), logger, file, 2, 0, EHashValidationOption.NoCheck(), false, 0, policy)
+
+		for i, content := range []string{"aaaa", "bbbb"} {
+			id := NewChunkID("f", int64(i*chunkSize), chunkSize)
+			c.Assert(w.WaitToScheduleChunk(ctx, id, chunkSize), chk.IsNil)
+			c.Assert(w.EnqueueChunk(ctx, id, chunkSize, bytes.NewReader([]byte(content)), false), chk.IsNil)
+		}
+		_, err := w.Flush(ctx)
+		c.Assert(err, chk.IsNil)
+
+		// syncing the whole file, for PerFile, is left to the file itself when it's closed
+		if policy == EFlushPolicy.PerChunk() {
+			c.Assert(file.contentAtEachSync, chk.DeepEquals, []string{"aaaa", "aaaabbbb"})
+		} else {
+			c.Assert(file.contentAtEachSync, chk.HasLen, 0)
+		}
+	}
+}
+
+func (s *chunkedFileWriterSuite) TestReorderWindowFollowsThroughput(c *chk.C) {
+	w := &chunkedFileWriter{maxChunksAhead: 64}
+	c.Assert(w.reorderWindow(), chk.Equals, int64(initialChunksAhead)) // nothing observed yet
+
+	observe := func(avgReceiveMilliseconds, avgSaveMicroseconds int64) int64 {
+		w.totalReceivedChunkCount, w.totalSavedChunkCount = 10, 10
+		w.totalChunkReceiveMilliseconds, w.totalChunkSaveMicroseconds = 10*avgReceiveMilliseconds, 10*avgSaveMicroseconds
+		return w.reorderWindow()
+	}
+	// a chunk takes five times as long to arrive as to save, so five must be in flight, plus the one being saved
+	c.Assert(observe(100, 20000), chk.Equals, int64(6))
+	// a fast disk is limited by the RAM allowed for the window
+	c.Assert(observe(100, 1000), chk.Equals, int64(64))
+	// a slow disk needs few chunks ahead
+	c.Assert(observe(100, 200000), chk.Equals, int64(minChunksAhead))
+
+	// the limit applies before anything is observed, too
+	c.Assert((&chunkedFileWriter{maxChunksAhead: 3}).reorderWindow(), chk.Equals, int64(3))
+}
@@ -0,0 +1,174 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"unsafe"
+
+	chk "gopkg.in/check.v1"
+)
+
+type directIOSuite struct{}
+
+var _ = chk.Suite(&directIOSuite{})
+
+// records what's written to it, and checks that each write is aligned, as it would have to be for a real direct IO file
+type alignedWriteRecorder struct {
+	c *chk.C
+	bytes.Buffer
+	truncatedTo int64
+	closed      bool
+}
+
+func (r *alignedWriteRecorder) Write(p []byte) (int, error) {
+	r.c.Assert(len(p)%DirectIOAlignment, chk.Equals, 0)
+	r.c.Assert(uintptr(unsafe.Pointer(&p[0]))%DirectIOAlignment, chk.Equals, uintptr(0))
+	return r.Buffer.Write(p)
+}
+
+func (r *alignedWriteRecorder) Truncate(size int64) error {
+	r.truncatedTo = size
+	return nil
+}
+
+func (r *alignedWriteRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+type nopCloserReaderAt struct {
+	io.ReaderAt
+}
+
+func (nopCloserReaderAt) Close() error {
+	return nil
+}
+
+func (s *directIOSuite) TestAlignedBuffer(c *chk.C) {
+	for _, size := range []int{1, DirectIOAlignment, 3*DirectIOAlignment + 7} {
+		b := NewAlignedBuffer(size)
+		c.Assert(len(b), chk.Equals, size)
+		c.Assert(uintptr(unsafe.Pointer(&b[0]))%DirectIOAlignment, chk.Equals, uintptr(0))
+	}
+}
+
+func (s *directIOSuite) TestDirectIOWriterPadsAndTruncates(c *chk.C) {
+	data := bytes.Repeat([]byte("0123456789"), (directIOWriteBufferSize+DirectIOAlignment)/10+3)
+	file := &alignedWriteRecorder{c: c}
+	w, err := newDirectIOWriter(context.Background(), file, 0, nil)
+	c.Assert(err, chk.IsNil)
+
+	// write in odd-sized pieces, which need to be gathered into aligned ones
+	for remaining := data; len(remaining) > 0; {
+		n := 1000
+		if n > len(remaining) {
+			n = len(remaining)
+		}
+		written, err := w.Write(remaining[:n])
+		c.Assert(err, chk.IsNil)
+		c.Assert(written, chk.Equals, n)
+		remaining = remaining[n:]
+	}
+	c.Assert(w.Close(), chk.IsNil)
+
+	c.Assert(file.closed, chk.Equals, true)
+	c.Assert(file.truncatedTo, chk.Equals, int64(len(data)))
+	c.Assert(int64(file.Len()), chk.Equals, alignUp(int64(len(data))))
+	c.Assert(bytes.Equal(file.Bytes()[:len(data)], data), chk.Equals, true)
+}
+
+func (s *directIOSuite) TestDirectIOWriterBufferSizeAndLimit(c *chk.C) {
+	c.Assert(directIOWriteBufferSizeFor(0), chk.Equals, directIOWriteBufferSize)
+	c.Assert(directIOWriteBufferSizeFor(1), chk.Equals, DirectIOAlignment)
+	c.Assert(directIOWriteBufferSizeFor(3*DirectIOAlignment+1), chk.Equals, 4*DirectIOAlignment)
+	c.Assert(directIOWriteBufferSizeFor(100*directIOWriteBufferSize), chk.Equals, directIOWriteBufferSize)
+
+	// a small file only takes a small buffer, which counts towards the RAM in use until the writer is closed
+	limiter := NewCacheLimiter(4 * DirectIOAlignment) // of which 3 blocks are within the strict limit
+	data := []byte("a small file")
+	file := &alignedWriteRecorder{c: c}
+	w, err := newDirectIOWriter(context.Background(), file, int64(len(data)), limiter)
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(w.buffer), chk.Equals, DirectIOAlignment)
+	c.Assert(limiter.TryAdd(3*DirectIOAlignment, false), chk.Equals, false)
+
+	_, err = w.Write(data)
+	c.Assert(err, chk.IsNil)
+	c.Assert(w.Close(), chk.IsNil)
+	c.Assert(file.truncatedTo, chk.Equals, int64(len(data)))
+	c.Assert(limiter.TryAdd(3*DirectIOAlignment, false), chk.Equals, true)
+}
+
+func (s *directIOSuite) TestDirectIOWriterSyncWritesStagedData(c *chk.C) {
+	file, err := ioutil.TempFile("", "directIOWriter")
+	c.Assert(err, chk.IsNil)
+	defer os.Remove(file.Name())
+
+	w, err := newDirectIOWriter(context.Background(), file, 0, nil)
+	c.Assert(err, chk.IsNil)
+
+	// what was staged is on disk once synced, even the partial block at the end
+	first := bytes.Repeat([]byte("x"), DirectIOAlignment+100)
+	_, err = w.Write(first)
+	c.Assert(err, chk.IsNil)
+	c.Assert(w.Sync(), chk.IsNil)
+	onDisk, err := ioutil.ReadFile(file.Name())
+	c.Assert(err, chk.IsNil)
+	c.Assert(bytes.Equal(onDisk[:len(first)], first), chk.Equals, true)
+
+	// and the partial block is completed by what's written next
+	second := bytes.Repeat([]byte("y"), 200)
+	_, err = w.Write(second)
+	c.Assert(err, chk.IsNil)
+	c.Assert(w.Close(), chk.IsNil)
+	onDisk, err = ioutil.ReadFile(file.Name())
+	c.Assert(err, chk.IsNil)
+	c.Assert(bytes.Equal(onDisk, append(first, second...)), chk.Equals, true)
+}
+
+func (s *directIOSuite) TestDirectIOReaderAtUnalignedReads(c *chk.C) {
+	data := bytes.Repeat([]byte("abcdefg"), 3*DirectIOAlignment/7+5)
+	r := &directIOReaderAt{file: nopCloserReaderAt{bytes.NewReader(data)}}
+
+	// within the file
+	p := make([]byte, DirectIOAlignment+10)
+	n, err := r.ReadAt(p, 100)
+	c.Assert(err, chk.IsNil)
+	c.Assert(n, chk.Equals, len(p))
+	c.Assert(bytes.Equal(p, data[100:100+len(p)]), chk.Equals, true)
+
+	// running past the end of the file
+	off := int64(len(data) - 20)
+	n, err = r.ReadAt(p, off)
+	c.Assert(err, chk.Equals, io.EOF)
+	c.Assert(n, chk.Equals, 20)
+	c.Assert(bytes.Equal(p[:n], data[off:]), chk.Equals, true)
+
+	// entirely beyond the end
+	n, err = r.ReadAt(p, int64(len(data)+1))
+	c.Assert(err, chk.Equals, io.EOF)
+	c.Assert(n, chk.Equals, 0)
+}
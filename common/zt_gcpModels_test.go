@@ -0,0 +1,70 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go"
+	chk "gopkg.in/check.v1"
+)
+
+type gcpModelsTestSuite struct{}
+
+var _ = chk.Suite(&gcpModelsTestSuite{})
+
+func (s *gcpModelsTestSuite) TestObjectAttrsFromXMLAPI(c *chk.C) {
+	updated := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	oi := minio.ObjectInfo{
+		Key:          "dir/object",
+		Size:         42,
+		LastModified: updated,
+		ContentType:  "text/plain",
+		Metadata: http.Header{
+			"Content-Encoding": {"gzip"},
+			"Cache-Control":    {"no-cache"},
+			"X-Goog-Meta-Foo":  {"bar"},
+			"X-Amz-Meta-Baz":   {"qux"},
+			"X-Goog-Hash":      {"crc32c=n03x6A==,md5=Ojk9c3dhfxgoKVVHYwFbHQ=="},
+		},
+	}
+
+	attrs := NewGCPObjectAttrsFromXMLAPI("bucket", oi)
+	c.Assert(attrs.Bucket, chk.Equals, "bucket")
+	c.Assert(attrs.Name, chk.Equals, "dir/object")
+	c.Assert(attrs.Size, chk.Equals, int64(42))
+	c.Assert(attrs.Updated, chk.Equals, updated)
+	c.Assert(attrs.ContentType, chk.Equals, "text/plain")
+	c.Assert(attrs.ContentEncoding, chk.Equals, "gzip")
+	c.Assert(attrs.CacheControl, chk.Equals, "no-cache")
+	c.Assert(attrs.MD5, chk.DeepEquals, []byte{0x3a, 0x39, 0x3d, 0x73, 0x77, 0x61, 0x7f, 0x18, 0x28, 0x29, 0x55, 0x47, 0x63, 0x01, 0x5b, 0x1d})
+
+	gie := GCPObjectInfoExtension{ObjectInfo: attrs}
+	c.Assert(gie.NewCommonMetadata(), chk.DeepEquals, Metadata{"Foo": "bar", "Baz": "qux"})
+}
+
+func (s *gcpModelsTestSuite) TestObjectAttrsFromXMLAPIWithoutMD5(c *chk.C) {
+	// composite objects only have a CRC32C
+	attrs := NewGCPObjectAttrsFromXMLAPI("bucket", minio.ObjectInfo{Key: "composite", Metadata: http.Header{"X-Goog-Hash": {"crc32c=n03x6A=="}}})
+	c.Assert(attrs.MD5, chk.IsNil)
+	c.Assert(attrs.Metadata, chk.IsNil)
+}
@@ -0,0 +1,95 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"os"
+
+	chk "gopkg.in/check.v1"
+)
+
+type keyVaultSuite struct{}
+
+var _ = chk.Suite(&keyVaultSuite{})
+
+func (s *keyVaultSuite) TestIsKeyVaultSecretURI(c *chk.C) {
+	cases := []struct {
+		value    string
+		expected bool
+	}{
+		{"https://myvault.vault.azure.net/secrets/mysecret", true},
+		{"https://myvault.vault.azure.net/secrets/mysecret/", true},
+		{"https://myvault.vault.azure.net/secrets/mysecret/0123456789abcdef", true},
+		{"https://MyVault.Vault.Azure.Net/Secrets/mysecret", true},
+		{"https://myvault.vault.azure.cn/secrets/mysecret", true},
+		{"https://myvault.vault.usgovcloudapi.net/secrets/mysecret", true},
+
+		{"plainsecretvalue", false},
+		{"", false},
+		{"http://myvault.vault.azure.net/secrets/mysecret", false},   // not https
+		{"https://myvault.vault.azure.net/keys/mykey", false},        // keys, not secrets
+		{"https://myvault.vault.azure.net/secrets/", false},          // no secret name
+		{"https://myaccount.blob.core.windows.net/secrets/x", false}, // not a vault
+	}
+
+	for _, x := range cases {
+		c.Assert(IsKeyVaultSecretURI(x.value), chk.Equals, x.expected, chk.Commentf("%s", x.value))
+	}
+}
+
+func (s *keyVaultSuite) TestKeyVaultResourceIsPerCloud(c *chk.C) {
+	c.Assert(keyVaultResourceFor("myvault.vault.azure.net"), chk.Equals, KeyVaultResource)
+	c.Assert(keyVaultResourceFor("MyVault.Vault.Azure.CN"), chk.Equals, "https://vault.azure.cn")
+	c.Assert(keyVaultResourceFor("myvault.vault.usgovcloudapi.net"), chk.Equals, "https://vault.usgovcloudapi.net")
+	c.Assert(keyVaultResourceFor("myaccount.blob.core.windows.net"), chk.Equals, "")
+}
+
+func (s *keyVaultSuite) TestSecretEnvironmentVariableIsOnlyFetchedByTheFrontEnd(c *chk.C) {
+	env := EEnvironmentVariable.AccountKey()
+	secretURI := "https://myvault.vault.azure.net/secrets/accountkey"
+	old, wasSet := os.LookupEnv(env.Name)
+	c.Assert(os.Setenv(env.Name, secretURI), chk.IsNil)
+	defer func() {
+		if wasSet {
+			os.Setenv(env.Name, old)
+		} else {
+			os.Unsetenv(env.Name)
+		}
+	}()
+
+	// a secret that wasn't fetched as the job started is an error, rather than being fetched (and failing) wherever credentials are created
+	_, err := GetSecretEnvironmentVariable(env)
+	c.Assert(err, chk.NotNil)
+
+	resolvedSecretEnvVars.Lock()
+	resolvedSecretEnvVars.values[secretURI] = "key"
+	resolvedSecretEnvVars.Unlock()
+	defer func() {
+		resolvedSecretEnvVars.Lock()
+		delete(resolvedSecretEnvVars.values, secretURI)
+		resolvedSecretEnvVars.Unlock()
+	}()
+	c.Assert(ResolveSecretEnvironmentVariable(context.Background(), env), chk.IsNil) // already fetched, so not fetched again
+	key, err := GetSecretEnvironmentVariable(env)
+	c.Assert(err, chk.IsNil)
+	c.Assert(key, chk.Equals, "key")
+}
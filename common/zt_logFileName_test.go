@@ -0,0 +1,50 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type logFileNameSuite struct{}
+
+var _ = chk.Suite(&logFileNameSuite{})
+
+func (s *logFileNameSuite) TestLogFileNameTemplateValidate(c *chk.C) {
+	c.Assert(DefaultLogFileNameTemplate.Validate(), chk.IsNil)
+	c.Assert(LogFileNameTemplate("{date}-{verb}-{jobid}").Validate(), chk.IsNil)
+
+	c.Assert(LogFileNameTemplate("{date}-{verb}").Validate(), chk.ErrorMatches, ".*must contain {jobid}")
+	c.Assert(LogFileNameTemplate("logs/{jobid}").Validate(), chk.ErrorMatches, ".*path separator.*")
+	c.Assert(LogFileNameTemplate("{host}-{jobid}").Validate(), chk.ErrorMatches, "unknown token {host}.*")
+}
+
+func (s *logFileNameSuite) TestLogFileNameTemplateFileName(c *chk.C) {
+	jobID, err := ParseJobID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	c.Assert(err, chk.IsNil)
+	date := time.Date(2021, 3, 9, 23, 30, 0, 0, time.UTC)
+
+	c.Assert(DefaultLogFileNameTemplate.FileName(jobID, "copy", date, ""), chk.Equals, "6ba7b810-9dad-11d1-80b4-00c04fd430c8.log")
+	c.Assert(LogFileNameTemplate("{date}-{verb}-{jobid}").FileName(jobID, "sync", date, "-scanning"), chk.Equals,
+		"20210309-sync-6ba7b810-9dad-11d1-80b4-00c04fd430c8-scanning.log")
+}
@@ -0,0 +1,52 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"mime"
+
+	chk "gopkg.in/check.v1"
+)
+
+type metadataEncodingSuite struct{}
+
+var _ = chk.Suite(&metadataEncodingSuite{})
+
+func (s *metadataEncodingSuite) TestNonASCIIValuesAreEncodedForTheService(c *chk.C) {
+	m := Metadata{"author": "José Müller", "title": "北京", "plain": "just ascii", "multiline": "a\nb"}
+
+	sent := m.ToAzBlobMetadata()
+	c.Assert(sent["plain"], chk.Equals, "just ascii")
+	decoder := mime.WordDecoder{}
+	for k, v := range m {
+		for _, b := range []byte(sent[k]) {
+			c.Assert(b >= ' ' && b <= '~', chk.Equals, true, chk.Commentf("%q has %q", k, sent[k]))
+		}
+		decoded, err := decoder.DecodeHeader(sent[k])
+		c.Assert(err, chk.IsNil)
+		c.Assert(decoded, chk.Equals, v)
+	}
+	c.Assert(map[string]string(m.ToAzFileMetadata()), chk.DeepEquals, map[string]string(sent))
+
+	// the original is left alone, and an already encoded value isn't encoded again, e.g. in a copy between accounts
+	c.Assert(m["author"], chk.Equals, "José Müller")
+	c.Assert(Metadata(sent).ToAzBlobMetadata()["author"], chk.Equals, sent["author"])
+}
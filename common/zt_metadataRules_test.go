@@ -0,0 +1,79 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type metadataRulesSuite struct{}
+
+var _ = chk.Suite(&metadataRulesSuite{})
+
+func (s *metadataRulesSuite) TestParseMetadataRules(c *chk.C) {
+	rules, err := ParseMetadataRules("rename:Owner=owner; remove:temp;add:source=contoso;set:origin={path}")
+	c.Assert(err, chk.IsNil)
+	c.Assert(rules, chk.DeepEquals, MetadataRules{
+		{Action: EMetadataRuleAction.Rename(), Key: "Owner", Value: "owner"},
+		{Action: EMetadataRuleAction.Remove(), Key: "temp"},
+		{Action: EMetadataRuleAction.Add(), Key: "source", Value: "contoso"},
+		{Action: EMetadataRuleAction.Set(), Key: "origin", Value: "{path}"},
+	})
+
+	for _, invalid := range []string{
+		"rename",              // no arguments
+		"move:a=b",            // unknown action
+		"add:=value",          // empty key
+		"set:1key=value",      // invalid key
+		"rename:a=b-c",        // invalid target key
+		"set:a={unknown}",     // unknown placeholder
+		"set:a={meta:}",       // meta without a key
+		"add:justakeynovalue", // missing value
+	} {
+		_, err := ParseMetadataRules(invalid)
+		c.Assert(err, chk.NotNil, chk.Commentf("%s", invalid))
+	}
+}
+
+func (s *metadataRulesSuite) TestApplyMetadataRules(c *chk.C) {
+	rules, err := ParseMetadataRules("rename:OWNER=owner;remove:temp;add:source=contoso;add:author=nobody;set:origin={meta:Author}/{path}.{ext}")
+	c.Assert(err, chk.IsNil)
+
+	original := Metadata{"Owner": "alice", "Temp": "1", "author": "bob"}
+	result := rules.Apply(original, MetadataTemplateVars{
+		Name:             "file.txt",
+		RelativePath:     "dir/file",
+		Size:             10,
+		LastModifiedTime: time.Now(),
+	})
+
+	c.Assert(result, chk.DeepEquals, Metadata{
+		"owner":  "alice",
+		"source": "contoso",
+		"author": "bob",
+		"origin": "bob/dir/file.txt",
+	})
+
+	// the input is not modified
+	c.Assert(original, chk.DeepEquals, Metadata{"Owner": "alice", "Temp": "1", "author": "bob"})
+}
@@ -0,0 +1,44 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	chk "gopkg.in/check.v1"
+)
+
+type oauthTenantsSuite struct{}
+
+var _ = chk.Suite(&oauthTenantsSuite{})
+
+func (s *oauthTenantsSuite) TestManagementEnvironmentFollowsTheCloud(c *chk.C) {
+	endpoint, resource, err := managementEnvironmentFor("")
+	c.Assert(err, chk.IsNil)
+	c.Assert(endpoint, chk.Equals, ManagementEndpoint)
+	c.Assert(resource, chk.Equals, ManagementResource)
+
+	endpoint, resource, err = managementEnvironmentFor("https://login.chinacloudapi.cn/")
+	c.Assert(err, chk.IsNil)
+	c.Assert(endpoint, chk.Equals, "https://management.chinacloudapi.cn")
+	c.Assert(resource, chk.Equals, "https://management.core.chinacloudapi.cn/")
+
+	_, _, err = managementEnvironmentFor("https://login.example.com")
+	c.Assert(err, chk.NotNil)
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+)
+
+type posixIDMapSuite struct{}
+
+var _ = chk.Suite(&posixIDMapSuite{})
+
+func (s *posixIDMapSuite) TestParsePOSIXIDMapFile(c *chk.C) {
+	dir, err := ioutil.TempDir("", "posixidmap")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ids")
+	c.Assert(ioutil.WriteFile(path, []byte("# local to AAD\nuser:1000 = 11111111-1111-1111-1111-111111111111\n\ngroup:100=22222222-2222-2222-2222-222222222222\n"), 0644), chk.IsNil)
+
+	idMap, err := ParsePOSIXIDMapFile(path)
+	c.Assert(err, chk.IsNil)
+	objectID, ok := idMap.User(1000)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(objectID, chk.Equals, "11111111-1111-1111-1111-111111111111")
+	_, ok = idMap.User(100) // users and groups are kept apart
+	c.Assert(ok, chk.Equals, false)
+	objectID, ok = idMap.Group(100)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(objectID, chk.Equals, "22222222-2222-2222-2222-222222222222")
+
+	c.Assert(ioutil.WriteFile(path, []byte("user:S-1-5-21-1-2-3-1001=33333333-3333-3333-3333-333333333333\ngroup:s-1-5-32-544=44444444-4444-4444-4444-444444444444\n"), 0644), chk.IsNil)
+	idMap, err = ParsePOSIXIDMapFile(path)
+	c.Assert(err, chk.IsNil)
+	objectID, ok = idMap.UserSID("s-1-5-21-1-2-3-1001") // SIDs are case insensitive
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(objectID, chk.Equals, "33333333-3333-3333-3333-333333333333")
+	objectID, ok = idMap.GroupSID("S-1-5-32-544")
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(objectID, chk.Equals, "44444444-4444-4444-4444-444444444444")
+	_, ok = idMap.UserSID("S-1-5-32-544")
+	c.Assert(ok, chk.Equals, false)
+
+	c.Assert(ioutil.WriteFile(path, []byte("owner:1000=x\n"), 0644), chk.IsNil)
+	_, err = ParsePOSIXIDMapFile(path)
+	c.Assert(err, chk.NotNil)
+}
@@ -0,0 +1,116 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+)
+
+type singleChunkReaderSuite struct{}
+
+var _ = chk.Suite(&singleChunkReaderSuite{})
+
+type countingReaderAt struct {
+	*bytes.Reader
+	reads *int
+}
+
+func (r countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	*r.reads++
+	return r.Reader.ReadAt(p, off)
+}
+
+func (countingReaderAt) Close() error { return nil }
+
+type nullTestLogger struct{}
+
+func (nullTestLogger) ShouldLog(level pipeline.LogLevel) bool  { return false }
+func (nullTestLogger) Log(level pipeline.LogLevel, msg string) {}
+func (nullTestLogger) Panic(err error)                         { panic(err) }
+
+// reads the chunk to the end, then again as a retry would
+func readChunkTwice(c *chk.C, keepBufferUntilClose bool) (reads int) {
+	data := []byte("some chunk data")
+	source := countingReaderAt{Reader: bytes.NewReader(data), reads: &reads}
+	factory := func() (CloseableReaderAt, error) { return source, nil }
+	logger := NewChunkStatusLogger(NewJobID(), NewNullCpuMonitor(), "", false)
+	cacheLimiter := NewCacheLimiter(1024)
+
+	cr := NewSingleChunkReader(context.Background(), factory, NewChunkID("f", 0, int64(len(data))), int64(len(data)),
+		logger, nullTestLogger{}, NewMultiSizeSlicePool(1024), cacheLimiter, keepBufferUntilClose)
+	c.Assert(cr.BlockingPrefetch(source, false), chk.IsNil)
+
+	for i := 0; i < 2; i++ {
+		_, err := cr.Seek(0, io.SeekStart)
+		c.Assert(err, chk.IsNil)
+		read, err := ioutil.ReadAll(cr)
+		c.Assert(err, chk.IsNil)
+		c.Assert(read, chk.DeepEquals, data)
+	}
+
+	c.Assert(cr.Close(), chk.IsNil)
+	c.Assert(cacheLimiter.(*cacheLimiter).value, chk.Equals, int64(0)) // the buffer is always given back in the end
+	return reads
+}
+
+func (s *singleChunkReaderSuite) TestRetryReReadsSourceByDefault(c *chk.C) {
+	c.Assert(readChunkTwice(c, false), chk.Equals, 2)
+}
+
+func (s *singleChunkReaderSuite) TestRetryUsesKeptBuffer(c *chk.C) {
+	c.Assert(readChunkTwice(c, true), chk.Equals, 1)
+}
+
+func prefetchedChunk(c *chk.C, data []byte) SingleChunkReader {
+	source := countingReaderAt{Reader: bytes.NewReader(data), reads: new(int)}
+	factory := func() (CloseableReaderAt, error) { return source, nil }
+	cr := NewSingleChunkReader(context.Background(), factory, NewChunkID("f", 0, int64(len(data))), int64(len(data)),
+		NewChunkStatusLogger(NewJobID(), NewNullCpuMonitor(), "", false), nullTestLogger{}, NewMultiSizeSlicePool(1024), NewCacheLimiter(1024), false)
+	c.Assert(cr.BlockingPrefetch(source, false), chk.IsNil)
+	return cr
+}
+
+func (s *singleChunkReaderSuite) TestZeroRunsAreWholeBlocks(c *chk.C) {
+	data := make([]byte, 100)
+	data[5] = 1  // in the first block
+	data[65] = 1 // in the seventh
+	cr := prefetchedChunk(c, data)
+	defer cr.Close()
+
+	c.Assert(cr.PrefetchedZeroRuns(10, 1), chk.DeepEquals, []ZeroRun{{Offset: 10, Length: 50}, {Offset: 70, Length: 30}})
+	c.Assert(cr.PrefetchedZeroRuns(10, 40), chk.DeepEquals, []ZeroRun{{Offset: 10, Length: 50}})
+
+	// the last block is shorter, when the chunk isn't a whole number of them
+	c.Assert(cr.PrefetchedZeroRuns(30, 1), chk.DeepEquals, []ZeroRun{{Offset: 30, Length: 30}, {Offset: 90, Length: 10}})
+}
+
+func (s *singleChunkReaderSuite) TestNoZeroRunsInData(c *chk.C) {
+	cr := prefetchedChunk(c, []byte("no zeros in here"))
+	defer cr.Close()
+
+	c.Assert(cr.PrefetchedZeroRuns(4, 1), chk.HasLen, 0)
+}
@@ -3,9 +3,11 @@ package ste
 import (
 	"errors"
 	"reflect"
+	"sync"
 	"unsafe"
 
 	"sync/atomic"
+	"time"
 
 	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -14,24 +16,99 @@ import (
 // dataSchemaVersion defines the data schema version of JobPart order files supported by
 // current version of azcopy
 // To be Incremented every time when we release azcopy with changed dataSchema
-const DataSchemaVersion common.Version = 16
+const DataSchemaVersion common.Version = 17
 
 const (
 	CustomHeaderMaxBytes = 256
-	MetadataMaxBytes     = 1000 // If > 65536, then jobPartPlanBlobData's MetadataLength field's type must change
+	MetadataMaxBytes     = 8192 // the service's limit. If > 65536, then jobPartPlanBlobData's MetadataLength field's type must change
 	BlobTagsMaxByte      = 4000
 )
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-type JobPartPlanMMF common.MMF
+// JobPartPlanMMF is a job part plan file, held in memory by either a memory map or a buffer.
+// The file is only mapped once its plan is first needed. After that, while none of the part's transfers are running,
+// it can be evicted (unmapped) to make room for other plans, and is mapped again the next time it's needed
+type JobPartPlanMMF struct {
+	// first, for 64-bit alignment on 32-bit systems
+	atomicLastUsed int64 // when the plan was last asked for, in Unix nanoseconds
+	atomicPinned   int32 // 1 while the part's transfers are running, since they hold pointers into the plan
+
+	filename JobPartPlanFileName
+	tracker  *mappedPlanTracker
+
+	// the mapped plan's header, or nil while the file isn't mapped. Every transfer reads the plan, many times over,
+	// so this is read without taking the lock, which only guards mapping and unmapping
+	atomicPlan unsafe.Pointer
+
+	lock    sync.Mutex
+	mapping common.IMappedFile // nil while the file isn't mapped
+}
 
 func (mmf *JobPartPlanMMF) Plan() *JobPartPlanHeader {
-	// getJobPartPlanPointer returns the memory map JobPartPlanHeader pointer
-	// casting the mmf slice's address  to JobPartPlanHeader Pointer
-	return (*JobPartPlanHeader)(unsafe.Pointer((*reflect.SliceHeader)(unsafe.Pointer(mmf)).Data))
+	// recording the use before reading the plan is what stops evictIfCold unmapping it from under us
+	atomic.StoreInt64(&mmf.atomicLastUsed, time.Now().UnixNano())
+	if plan := atomic.LoadPointer(&mmf.atomicPlan); plan != nil {
+		return (*JobPartPlanHeader)(plan)
+	}
+
+	mmf.lock.Lock()
+	overLimit := false
+	if mmf.mapping == nil {
+		mmf.mapping = mmf.filename.mapFile()
+		overLimit = mmf.tracker.add(mmf)
+
+		// getJobPartPlanPointer returns the memory map JobPartPlanHeader pointer
+		// casting the mmf slice's address  to JobPartPlanHeader Pointer
+		slice := mmf.mapping.Slice()
+		atomic.StorePointer(&mmf.atomicPlan, unsafe.Pointer((*reflect.SliceHeader)(unsafe.Pointer(&slice)).Data))
+	}
+	plan := (*JobPartPlanHeader)(atomic.LoadPointer(&mmf.atomicPlan))
+	mmf.lock.Unlock()
+
+	// evict others only once we've let go of this plan, so that no two plans are ever locked at once
+	if overLimit {
+		mmf.tracker.evictColdest(time.Now())
+	}
+	return plan
+}
+
+func (mmf *JobPartPlanMMF) Unmap() {
+	mmf.lock.Lock()
+	defer mmf.lock.Unlock()
+	if mmf.mapping != nil {
+		atomic.StorePointer(&mmf.atomicPlan, nil)
+		mmf.mapping.Unmap()
+		mmf.mapping = nil
+		mmf.tracker.remove(mmf)
+	}
+}
+
+// pin keeps the plan mapped while the part's transfers run, and unpin lets it be evicted again once they're done
+func (mmf *JobPartPlanMMF) pin()   { atomic.StoreInt32(&mmf.atomicPinned, 1) }
+func (mmf *JobPartPlanMMF) unpin() { atomic.StoreInt32(&mmf.atomicPinned, 0) }
+
+// evictIfCold unmaps the plan if it has gone cold: if it's mapped, isn't pinned, and hasn't been asked for in planColdAfter
+func (mmf *JobPartPlanMMF) evictIfCold(now time.Time) bool {
+	mmf.lock.Lock()
+	defer mmf.lock.Unlock()
+	lastUsed := atomic.LoadInt64(&mmf.atomicLastUsed)
+	if mmf.mapping == nil || atomic.LoadInt32(&mmf.atomicPinned) != 0 || now.Sub(time.Unix(0, lastUsed)) < planColdAfter {
+		return false
+	}
+
+	// Withdraw the plan, then check again that no one has used it. Plan records a use before it reads the plan,
+	// so anyone who got the plan before it was withdrawn shows up here, and it's put back rather than unmapped
+	plan := atomic.SwapPointer(&mmf.atomicPlan, nil)
+	if atomic.LoadInt64(&mmf.atomicLastUsed) != lastUsed {
+		atomic.StorePointer(&mmf.atomicPlan, plan)
+		return false
+	}
+	mmf.mapping.Unmap()
+	mmf.mapping = nil
+	mmf.tracker.remove(mmf)
+	return true
 }
-func (mmf *JobPartPlanMMF) Unmap() { (*common.MMF)(mmf).Unmap() }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
@@ -66,6 +143,15 @@ type JobPartPlanHeader struct {
 
 	PreserveSMBPermissions common.PreservePermissionsOption
 	PreserveSMBInfo        bool
+	// PermissionsOnly represents whether only the permissions and properties of existing destinations are to be set, without sending data
+	PermissionsOnly bool
+	// SkipLargerThan and SkipSmallerThan are the bounds, in bytes, outside which files are skipped rather than transferred. 0 means no bound
+	SkipLargerThan  int64
+	SkipSmallerThan int64
+	// TransferTimeout is how long any single transfer may run before it is failed as timed out. 0 means no limit
+	TransferTimeout time.Duration
+	// DeleteSourceAfterTransfer says each file's source is deleted once the file has been transferred successfully, i.e. it's moved
+	DeleteSourceAfterTransfer bool
 	// S2SGetPropertiesInBackend represents whether to enable get S3 objects' or Azure files' properties during s2s copy in backend.
 	S2SGetPropertiesInBackend bool
 	// S2SSourceChangeValidation represents whether user wants to check if source has changed after enumerating.
@@ -74,6 +160,10 @@ type JobPartPlanHeader struct {
 	DestLengthValidation bool
 	// S2SInvalidMetadataHandleOption represents how user wants to handle invalid metadata.
 	S2SInvalidMetadataHandleOption common.InvalidMetadataHandleOption
+	// S2SSourceTenantID is the AAD tenant whose OAuth token authorizes the source of a service to service copy, if that's not the login's tenant.
+	// Only the tenant is kept (never the token), so that resume can get a fresh token for it
+	S2SSourceTenantIDLength uint16
+	S2SSourceTenantID       [256]byte
 
 	// Any fields below this comment are NOT constants; they may change over as the job part is processed.
 	// Care must be taken to read/write to these fields in a thread-safe way!
@@ -97,6 +187,17 @@ func (jpph *JobPartPlanHeader) SetJobStatus(newJobStatus common.JobStatus) {
 	jpph.atomicJobStatus.AtomicStore(newJobStatus)
 }
 
+// setSourceRoot replaces the source root, e.g. because a local source has been remounted at a different path.
+// Each transfer's source is relative to the root, so it moves too. Not thread-safe, so call it before scheduling
+func (jpph *JobPartPlanHeader) setSourceRoot(root string) error {
+	if len(root) > len(jpph.SourceRoot) {
+		return errors.New("the source root is too long: " + root)
+	}
+	copy(jpph.SourceRoot[:], root)
+	jpph.SourceRootLength = uint16(len(root))
+	return nil
+}
+
 // Transfer api gives memory map JobPartPlanTransfer header for given index
 func (jpph *JobPartPlanHeader) Transfer(transferIndex uint32) *JobPartPlanTransfer {
 	// get memory map JobPartPlan Header Pointer
@@ -129,36 +230,78 @@ func (jpph *JobPartPlanHeader) TransferSrcDstStrings(transferIndex uint32) (sour
 
 	jppt := jpph.Transfer(transferIndex)
 	isFolder = jppt.EntityType == common.EEntityType.Folder()
+	srcRelative, dstRelative := jpph.TransferSrcDstRelatives(transferIndex)
+
+	return common.GenerateFullPathWithQuery(srcRoot, srcRelative, srcExtraQuery),
+		common.GenerateFullPathWithQuery(dstRoot, dstRelative, dstExtraQuery),
+		isFolder
+}
+
+// TransferSrcDstRelatives returns the transfer's source and destination, relative to the roots of the job part
+func (jpph *JobPartPlanHeader) TransferSrcDstRelatives(transferIndex uint32) (srcRelative, dstRelative string) {
+	jppt := jpph.Transfer(transferIndex)
 
 	srcSlice := []byte{}
 	sh := (*reflect.SliceHeader)(unsafe.Pointer(&srcSlice))
 	sh.Data = uintptr(unsafe.Pointer(jpph)) + uintptr(jppt.SrcOffset) // Address of Job Part Plan + this transfer's src string offset
 	sh.Len = int(jppt.SrcLength)
 	sh.Cap = sh.Len
-	srcRelative := string(srcSlice)
+	srcRelative = string(srcSlice)
 
 	dstSlice := []byte{}
 	sh = (*reflect.SliceHeader)(unsafe.Pointer(&dstSlice))
 	sh.Data = uintptr(unsafe.Pointer(jpph)) + uintptr(jppt.SrcOffset) + uintptr(jppt.SrcLength) // Address of Job Part Plan + this transfer's src string offset + length of this transfer's src string
 	sh.Len = int(jppt.DstLength)
 	sh.Cap = sh.Len
-	dstRelative := string(dstSlice)
+	dstRelative = string(dstSlice)
 
-	return common.GenerateFullPathWithQuery(srcRoot, srcRelative, srcExtraQuery),
-		common.GenerateFullPathWithQuery(dstRoot, dstRelative, dstExtraQuery),
-		isFolder
+	return srcRelative, dstRelative
 }
 
 func (jpph *JobPartPlanHeader) getString(offset int64, length int16) string {
+	return jpph.getLongString(offset, int(length))
+}
+
+// getLongString is getString, for strings whose lengths don't fit in an int16
+func (jpph *JobPartPlanHeader) getLongString(offset int64, length int) string {
 	tempSlice := []byte{}
 	sh := (*reflect.SliceHeader)(unsafe.Pointer(&tempSlice))
 	sh.Data = uintptr(unsafe.Pointer(jpph)) + uintptr(offset) // Address of Job Part Plan + this string's offset
-	sh.Len = int(length)
+	sh.Len = length
 	sh.Cap = sh.Len
 
 	return string(tempSlice)
 }
 
+// DstHTTPHeaders returns the HTTP headers that were given for the destination, including any that were too long for their fields
+func (jpph *JobPartPlanHeader) DstHTTPHeaders() common.ResourceHTTPHeaders {
+	dstData := &jpph.DstBlobData
+	overflowOffset := dstData.HeaderOverflowOffset
+	header := func(field []byte, length uint16) string {
+		if int(length) <= len(field) {
+			return string(field[:length])
+		}
+		s := jpph.getLongString(overflowOffset, int(length))
+		overflowOffset += int64(length)
+		return s
+	}
+
+	// in the order the overflowing ones are stored
+	contentType := header(dstData.ContentType[:], dstData.ContentTypeLength)
+	contentEncoding := header(dstData.ContentEncoding[:], dstData.ContentEncodingLength)
+	contentLanguage := header(dstData.ContentLanguage[:], dstData.ContentLanguageLength)
+	contentDisposition := header(dstData.ContentDisposition[:], dstData.ContentDispositionLength)
+	cacheControl := header(dstData.CacheControl[:], dstData.CacheControlLength)
+
+	return common.ResourceHTTPHeaders{
+		ContentType:        contentType,
+		ContentEncoding:    contentEncoding,
+		ContentDisposition: contentDisposition,
+		ContentLanguage:    contentLanguage,
+		CacheControl:       cacheControl,
+	}
+}
+
 // TransferSrcPropertiesAndMetadata returns the SrcHTTPHeaders, properties and metadata for a transfer at given transferIndex in JobPartOrder
 // TODO: Refactor return type to an object
 func (jpph *JobPartPlanHeader) TransferSrcPropertiesAndMetadata(transferIndex uint32) (h common.ResourceHTTPHeaders, metadata common.Metadata, blobType azblob.BlobType, blobTier azblob.AccessTierType,
@@ -282,6 +425,11 @@ type JobPartPlanDstBlob struct {
 
 	// Specifies the maximum size of block which determines the number of chunks and chunk size of a transfer
 	BlockSize int64
+
+	// Headers that are too long for their fields above are stored with the plan's strings instead, after those of the
+	// transfers, and their fields are left empty. This is the offset of the first of them. They are stored in the order
+	// content type, content encoding, content language, content disposition, cache control
+	HeaderOverflowOffset int64
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -295,6 +443,15 @@ type JobPartPlanDstLocal struct {
 
 	// says how MD5 verification failures should be actioned
 	MD5VerificationOption common.HashValidationOption
+
+	// Specifies whether the access time of destination file has to be set to the last access time of source file
+	PreserveLastAccessTime bool
+
+	// says when written data should be committed to durable storage
+	FlushPolicy common.FlushPolicy
+
+	// says what to do when there's not enough free space for a file
+	FreeSpaceCheck common.FreeSpaceCheckOption
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -318,8 +475,10 @@ type JobPartPlanTransfer struct {
 	ModifiedTime int64
 	// SourceSize represents the actual size of the source on disk
 	SourceSize int64
-	// CompletionTime represents the time at which transfer was completed
+	// CompletionTime represents the time at which transfer was completed, and StartTime the time at which it last
+	// started, both in Unix nanoseconds. Unlike the fields around them, they are set as the transfer runs, atomically
 	CompletionTime uint64
+	StartTime      uint64
 
 	// For S2S copy, per Transfer source's properties
 	// TODO: ensure the length is enough
@@ -334,6 +493,8 @@ type JobPartPlanTransfer struct {
 	SrcBlobTierLength           int16
 	SrcBlobVersionIDLength      int16
 	SrcBlobTagsLength           int16
+	// SrcMetadataOverride says the transfer's metadata replaces the job's metadata, even when it is empty
+	SrcMetadataOverride bool
 
 	// Any fields below this comment are NOT constants; they may change over as the transfer is processed.
 	// Care must be taken to read/write to these fields in a thread-safe way!
@@ -346,6 +507,14 @@ type JobPartPlanTransfer struct {
 	// atomicErrorCode has a default value (0) which means either there was no error or transfer failed because some non storageError.
 	// atomicErrorCode should not be directly accessed anywhere except by transferStatus and setTransferStatus
 	atomicErrorCode int32
+
+	// atomicCommitPending is 1 from when all of a block blob's blocks have been staged until they are committed, so that a
+	// job which is resumed after failing or crashing in between can just commit them, rather than send them all again
+	atomicCommitPending int32
+
+	// atomicSourceDeletion records, for a job that moves its sources, how far the deletion of this transfer's source got.
+	// It's only begun once the transfer has succeeded, so a source is never deleted before its copy is known to be good
+	atomicSourceDeletion int32
 }
 
 // TransferStatus returns the transfer's status
@@ -370,11 +539,55 @@ func (jppt *JobPartPlanTransfer) SetTransferStatus(status common.TransferStatus,
 	}
 }
 
+// CancelUnlessFinished marks the transfer Cancelled, unless it has already finished, and returns the status it had.
+// Unlike SetTransferStatus, it won't overwrite a success, or a skip.
+func (jppt *JobPartPlanTransfer) CancelUnlessFinished() (status common.TransferStatus, cancelled bool) {
+	result := common.AtomicMorphInt32((*int32)(&jppt.atomicTransferStatus),
+		func(startVal int32) (val int32, morphResult interface{}) {
+			status := common.TransferStatus(startVal)
+			if status != common.ETransferStatus.NotStarted() && status != common.ETransferStatus.Started() {
+				return startVal, status
+			}
+			return int32(common.ETransferStatus.Cancelled()), status
+		}).(common.TransferStatus)
+	return result, result == common.ETransferStatus.NotStarted() || result == common.ETransferStatus.Started()
+}
+
 // ErrorCode returns the transfer's errorCode.
 func (jppt *JobPartPlanTransfer) ErrorCode() int32 {
 	return atomic.LoadInt32(&jppt.atomicErrorCode)
 }
 
+// SetStartTime records when the transfer started. A transfer that's resumed starts again, so only its last run is timed
+func (jppt *JobPartPlanTransfer) SetStartTime(t time.Time) {
+	atomic.StoreUint64(&jppt.StartTime, uint64(t.UnixNano()))
+	atomic.StoreUint64(&jppt.CompletionTime, 0)
+}
+
+// SetCompletionTime records when the transfer was done
+func (jppt *JobPartPlanTransfer) SetCompletionTime(t time.Time) {
+	atomic.StoreUint64(&jppt.CompletionTime, uint64(t.UnixNano()))
+}
+
+// Duration returns how long the transfer took, from its last start to when it was done, if it has done both
+func (jppt *JobPartPlanTransfer) Duration() (time.Duration, bool) {
+	start, end := atomic.LoadUint64(&jppt.StartTime), atomic.LoadUint64(&jppt.CompletionTime)
+	if start == 0 || end < start {
+		return 0, false
+	}
+	return time.Duration(end - start), true
+}
+
+// CommitPending says whether the transfer's blocks have all been staged but not yet committed
+func (jppt *JobPartPlanTransfer) CommitPending() bool {
+	return atomic.LoadInt32(&jppt.atomicCommitPending) != 0
+}
+
+// SetCommitPending records whether the transfer's blocks have all been staged but not yet committed
+func (jppt *JobPartPlanTransfer) SetCommitPending(pending bool) {
+	atomic.StoreInt32(&jppt.atomicCommitPending, common.Iffint32(pending, 1, 0))
+}
+
 // SetErrorCode sets the error code of the error if transfer failed.
 // overWrite flags if set to true overWrites the atomicErrorCode.
 // If overWrite flag is set to false, then errorCode won't be overwritten.
@@ -1,14 +1,13 @@
 package ste
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
-	"reflect"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -25,7 +24,15 @@ const jobPartPlanFileNameFormat = "%v--%05d.steV%d"
 
 // TODO: This needs testing
 func (jpfn JobPartPlanFileName) Parse() (jobID common.JobID, partNumber common.PartNumber, err error) {
-	var dataSchemaVersion common.Version
+	jobID, partNumber, dataSchemaVersion, err := jpfn.parseNameAndVersion()
+	if err == nil && dataSchemaVersion != DataSchemaVersion {
+		err = fmt.Errorf("job part Plan file's data schema version ('%d') doesn't match whatthis app requires ('%d')", dataSchemaVersion, DataSchemaVersion)
+	}
+	return
+}
+
+// parseNameAndVersion parses the plan file's name, without requiring that its data schema version is the current one
+func (jpfn JobPartPlanFileName) parseNameAndVersion() (jobID common.JobID, partNumber common.PartNumber, dataSchemaVersion common.Version, err error) {
 	//n, err := fmt.Sscanf(string(jpfn), jobPartPlanFileNameFormat, &jobID, &partNumber, &dataSchemaVersion)
 	//if err != nil || n != 3 {
 	//	panic(err)
@@ -44,9 +51,6 @@ func (jpfn JobPartPlanFileName) Parse() (jobID common.JobID, partNumber common.P
 	if err != nil || n != 2 {
 		panic(err)
 	}
-	if dataSchemaVersion != DataSchemaVersion {
-		err = fmt.Errorf("job part Plan file's data schema version ('%d') doesn't match whatthis app requires ('%d')", dataSchemaVersion, DataSchemaVersion)
-	}
 	return
 }
 
@@ -54,7 +58,12 @@ func (jpfn JobPartPlanFileName) Delete() error {
 	return os.Remove(string(jpfn))
 }
 
+// Map returns the plan held in the file. The file is mapped on demand, the first time the plan is asked for
 func (jpfn JobPartPlanFileName) Map() *JobPartPlanMMF {
+	return &JobPartPlanMMF{filename: jpfn, tracker: mappedPlans}
+}
+
+func (jpfn JobPartPlanFileName) mapFile() common.IMappedFile {
 	// opening the file with given filename
 	file, err := os.OpenFile(jpfn.GetJobPartPlanPath(), os.O_RDWR, common.DEFAULT_FILE_PERM)
 	common.PanicIfErr(err)
@@ -63,9 +72,36 @@ func (jpfn JobPartPlanFileName) Map() *JobPartPlanMMF {
 
 	fileInfo, err := file.Stat()
 	common.PanicIfErr(err)
-	mmf, err := common.NewMMF(file, true, 0, fileInfo.Size())
+	mapping, err := mapPlanFile(file, fileInfo.Size())
 	common.PanicIfErr(err)
-	return (*JobPartPlanMMF)(mmf)
+	return mapping
+}
+
+// how plan files are held in memory, read once from the environment
+var planFileMappingOnce sync.Once
+var planFileMapping string
+
+// mapPlanFile memory maps the plan file, or buffers it in memory if memory mapping has been turned off or isn't supported
+func mapPlanFile(file *os.File, length int64) (common.IMappedFile, error) {
+	planFileMappingOnce.Do(func() {
+		planFileMapping = strings.ToLower(common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.PlanFileMapping()))
+	})
+
+	if planFileMapping != "buffered" {
+		mmf, err := common.NewMMF(file, true, 0, length)
+		if err == nil {
+			return mmf, nil
+		}
+		if planFileMapping == "mmap" {
+			return nil, err // not mmf, which would be a non-nil interface holding a nil pointer
+		}
+		// in auto mode, we fall back to buffering
+	}
+	buffered, err := common.NewBufferedMMF(file, true, 0, length)
+	if err != nil {
+		return nil, err
+	}
+	return buffered, nil
 }
 
 // createJobPartPlanFile creates the memory map JobPartPlanHeader using the given JobPartOrder and JobPartPlanBlobData
@@ -83,19 +119,23 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	if len(order.DestinationRoot.ExtraQuery) > len(JobPartPlanHeader{}.DestExtraQuery) {
 		panic(fmt.Errorf("destination extra query strings too large: %q", order.DestinationRoot.ExtraQuery))
 	}
-	if len(order.BlobAttributes.ContentType) > len(JobPartPlanDstBlob{}.ContentType) {
+	if len(order.S2SSourceTenantID) > len(JobPartPlanHeader{}.S2SSourceTenantID) {
+		panic(fmt.Errorf("source tenant ID is too large: %q", order.S2SSourceTenantID))
+	}
+	// the HTTP headers can be longer than their fields, since they overflow into the plan's strings, but their lengths are uint16s
+	if len(order.BlobAttributes.ContentType) > math.MaxUint16 {
 		panic(fmt.Errorf("content type string is too large: %q", order.BlobAttributes.ContentType))
 	}
-	if len(order.BlobAttributes.ContentEncoding) > len(JobPartPlanDstBlob{}.ContentEncoding) {
+	if len(order.BlobAttributes.ContentEncoding) > math.MaxUint16 {
 		panic(fmt.Errorf("content encoding string is too large: %q", order.BlobAttributes.ContentEncoding))
 	}
-	if len(order.BlobAttributes.ContentLanguage) > len(JobPartPlanDstBlob{}.ContentLanguage) {
+	if len(order.BlobAttributes.ContentLanguage) > math.MaxUint16 {
 		panic(fmt.Errorf("content language string is too large: %q", order.BlobAttributes.ContentLanguage))
 	}
-	if len(order.BlobAttributes.ContentDisposition) > len(JobPartPlanDstBlob{}.ContentDisposition) {
+	if len(order.BlobAttributes.ContentDisposition) > math.MaxUint16 {
 		panic(fmt.Errorf("content disposition string is too large: %q", order.BlobAttributes.ContentDisposition))
 	}
-	if len(order.BlobAttributes.CacheControl) > len(JobPartPlanDstBlob{}.CacheControl) {
+	if len(order.BlobAttributes.CacheControl) > math.MaxUint16 {
 		panic(fmt.Errorf("cache control string is too large: %q", order.BlobAttributes.CacheControl))
 	}
 	if len(order.BlobAttributes.Metadata) > len(JobPartPlanDstBlob{}.Metadata) {
@@ -105,17 +145,6 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		panic(fmt.Errorf("blob tags string is too large: %q", order.BlobAttributes.BlobTagsString))
 	}
 
-	// This nested function writes a structure value to an io.Writer & returns the number of bytes written
-	writeValue := func(writer io.Writer, v interface{}) int64 {
-		rv := reflect.ValueOf(v)
-		structSize := reflect.TypeOf(v).Elem().Size()
-		slice := reflect.SliceHeader{Data: rv.Pointer(), Len: int(structSize), Cap: int(structSize)}
-		byteSlice := *(*[]byte)(unsafe.Pointer(&slice))
-		err := binary.Write(writer, binary.LittleEndian, byteSlice)
-		common.PanicIfErr(err)
-		return int64(structSize)
-	}
-
 	eof := int64(0)
 	/*
 	*       Following Steps are executed:
@@ -193,13 +222,22 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		DstLocalData: JobPartPlanDstLocal{
 			PreserveLastModifiedTime: order.BlobAttributes.PreserveLastModifiedTime,
 			MD5VerificationOption:    order.BlobAttributes.MD5ValidationOption, // here because it relates to downloads (file destination)
+			PreserveLastAccessTime:   order.BlobAttributes.PreserveLastAccessTime,
+			FlushPolicy:              order.BlobAttributes.FlushPolicy,
+			FreeSpaceCheck:           order.BlobAttributes.FreeSpaceCheck,
 		},
-		PreserveSMBPermissions: order.PreserveSMBPermissions,
-		PreserveSMBInfo:        order.PreserveSMBInfo,
+		PreserveSMBPermissions:    order.PreserveSMBPermissions,
+		PreserveSMBInfo:           order.PreserveSMBInfo,
+		PermissionsOnly:           order.PermissionsOnly,
+		SkipLargerThan:            order.SkipLargerThan,
+		SkipSmallerThan:           order.SkipSmallerThan,
+		TransferTimeout:           order.TransferTimeout,
+		DeleteSourceAfterTransfer: order.DeleteSourceAfterTransfer,
 		// For S2S copy, per JobPartPlan info
 		S2SGetPropertiesInBackend:      order.S2SGetPropertiesInBackend,
 		S2SSourceChangeValidation:      order.S2SSourceChangeValidation,
 		S2SInvalidMetadataHandleOption: order.S2SInvalidMetadataHandleOption,
+		S2SSourceTenantIDLength:        uint16(len(order.S2SSourceTenantID)),
 		DestLengthValidation:           order.DestLengthValidation,
 		atomicJobStatus:                common.EJobStatus.InProgress(), // We default to InProgress
 		DeleteSnapshotsOption:          order.BlobAttributes.DeleteSnapshotsOption,
@@ -211,15 +249,29 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	copy(jpph.SourceExtraQuery[:], order.SourceRoot.ExtraQuery)
 	copy(jpph.DestinationRoot[:], order.DestinationRoot.Value)
 	copy(jpph.DestExtraQuery[:], order.DestinationRoot.ExtraQuery)
-	copy(jpph.DstBlobData.ContentType[:], order.BlobAttributes.ContentType)
-	copy(jpph.DstBlobData.ContentEncoding[:], order.BlobAttributes.ContentEncoding)
-	copy(jpph.DstBlobData.ContentLanguage[:], order.BlobAttributes.ContentLanguage)
-	copy(jpph.DstBlobData.ContentDisposition[:], order.BlobAttributes.ContentDisposition)
-	copy(jpph.DstBlobData.CacheControl[:], order.BlobAttributes.CacheControl)
+	copy(jpph.S2SSourceTenantID[:], order.S2SSourceTenantID)
+	// HTTP headers that don't fit their fields are written after the transfers' strings, in the order DstHTTPHeaders reads them
+	overflowHeaders := make([]string, 0)
+	for _, h := range []struct {
+		field []byte
+		value string
+	}{
+		{jpph.DstBlobData.ContentType[:], order.BlobAttributes.ContentType},
+		{jpph.DstBlobData.ContentEncoding[:], order.BlobAttributes.ContentEncoding},
+		{jpph.DstBlobData.ContentLanguage[:], order.BlobAttributes.ContentLanguage},
+		{jpph.DstBlobData.ContentDisposition[:], order.BlobAttributes.ContentDisposition},
+		{jpph.DstBlobData.CacheControl[:], order.BlobAttributes.CacheControl},
+	} {
+		if len(h.value) > len(h.field) {
+			overflowHeaders = append(overflowHeaders, h.value)
+		} else {
+			copy(h.field, h.value)
+		}
+	}
 	copy(jpph.DstBlobData.Metadata[:], order.BlobAttributes.Metadata)
 	copy(jpph.DstBlobData.BlobTags[:], order.BlobAttributes.BlobTagsString)
 
-	eof += writeValue(file, &jpph)
+	eof += writePlanStruct(file, &jpph)
 
 	// write the command string in the JobPart Plan file
 	bytesWritten, err := file.WriteString(order.CommandString)
@@ -284,11 +336,12 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 			SrcBlobTierLength:           int16(len(order.Transfers[t].BlobTier)),
 			SrcBlobVersionIDLength:      int16(len(order.Transfers[t].BlobVersionID)),
 			SrcBlobTagsLength:           int16(srcBlobTagsLength),
+			SrcMetadataOverride:         order.Transfers[t].MetadataOverride,
 
 			atomicTransferStatus: common.ETransferStatus.Started(), // Default
 			//ChunkNum:                getNumChunks(uint64(order.Transfers[t].SourceSize), uint64(data.BlockSize)),
 		}
-		eof += writeValue(file, &jppt) // Write the transfer entry
+		eof += writePlanStruct(file, &jppt) // Write the transfer entry
 
 		// The NEXT transfer's src/dst string come after THIS transfer's src/dst strings
 		srcDstStringsOffset[t] = currentSrcStringOffset
@@ -378,5 +431,24 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 			eof += int64(bytesWritten)
 		}
 	}
+
+	if len(overflowHeaders) > 0 {
+		jpph.DstBlobData.HeaderOverflowOffset = eof
+		for _, h := range overflowHeaders {
+			bytesWritten, err = file.WriteString(h)
+			common.PanicIfErr(err)
+			eof += int64(bytesWritten)
+		}
+
+		// the header was written before we knew where they'd go, so write it again now that it says
+		_, err = file.Seek(0, io.SeekStart)
+		common.PanicIfErr(err)
+		writePlanStruct(file, &jpph)
+		_, err = file.Seek(eof, io.SeekStart)
+		common.PanicIfErr(err)
+	}
+
+	// Finally, describe the layout that the plan was written with, so that other builds can read it
+	writePlanLayout(file, eof)
 	// the file is closed to due to defer above
 }
@@ -0,0 +1,382 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"unsafe"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Plan files are serialized field by field, in this process's byte order and at the offsets of its struct layout, so that
+// they can be memory mapped and used in place. So that they can still be read by builds in which that layout differs (on
+// a different CPU architecture, or in a later version of AzCopy that has added fields), each plan file ends with a
+// description of the layout it was written with. A plan file whose layout doesn't match ours is deserialized, field by
+// field, matching fields by name, and serialized again in our layout, before it's mapped. Plan files from before layout
+// descriptions were added are converted the same way, using the layouts in legacyPlanLayouts.
+
+// OldestReadableDataSchemaVersion is the oldest version of plan file that can be converted to the current one.
+// Every version from it up to the one before layout descriptions must have an entry in legacyPlanLayouts. It must be
+// raised whenever the meaning of a field changes, since conversion only deals with fields being added, removed, resized or moved.
+const OldestReadableDataSchemaVersion common.Version = 16
+
+// the plan layout trailer, at the very end of the file, is the offset and length of the layout description, then this
+const planLayoutMagic = "AZPL"
+const planLayoutTrailerSize = 8 + 4 + len(planLayoutMagic)
+
+// planFieldLayout describes where one field of a plan struct is. Fields of nested structs are flattened, with dotted names
+type planFieldLayout struct {
+	Name     string
+	Offset   uint64
+	Size     uint64
+	Kind     reflect.Kind // for arrays, the kind of the elements
+	IsArray  bool
+	ElemSize uint64 `json:",omitempty"`
+}
+
+// planFileLayout describes the layout of the structs in a plan file
+type planFileLayout struct {
+	BigEndian    bool
+	HeaderSize   uint64
+	TransferSize uint64
+	Header       []planFieldLayout
+	Transfer     []planFieldLayout
+}
+
+var nativeByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// the layout that this build uses
+var nativePlanLayout = planFileLayout{
+	BigEndian:    nativeByteOrder == binary.BigEndian,
+	HeaderSize:   uint64(unsafe.Sizeof(JobPartPlanHeader{})),
+	TransferSize: uint64(unsafe.Sizeof(JobPartPlanTransfer{})),
+	Header:       describePlanStruct(reflect.TypeOf(JobPartPlanHeader{}), "", 0, nil),
+	Transfer:     describePlanStruct(reflect.TypeOf(JobPartPlanTransfer{}), "", 0, nil),
+}
+
+func describePlanStruct(t reflect.Type, prefix string, base uintptr, fields []planFieldLayout) []planFieldLayout {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		field := planFieldLayout{Name: prefix + f.Name, Offset: uint64(base + f.Offset), Size: uint64(f.Type.Size()), Kind: f.Type.Kind()}
+		switch f.Type.Kind() {
+		case reflect.Struct:
+			fields = describePlanStruct(f.Type, field.Name+".", base+f.Offset, fields)
+			continue
+		case reflect.Array:
+			field.IsArray = true
+			field.Kind = f.Type.Elem().Kind()
+			field.ElemSize = uint64(f.Type.Elem().Size())
+		}
+		if !isPlanScalarKind(field.Kind) {
+			panic(fmt.Sprintf("plan field %s is of kind %s, which can't be stored in a plan file", field.Name, field.Kind))
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func isPlanScalarKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isSignedKind(k reflect.Kind) bool {
+	return k >= reflect.Int8 && k <= reflect.Int64
+}
+
+func (l *planFileLayout) byteOrder() binary.ByteOrder {
+	if l.BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// writePlanLayout writes the description of our layout, and the trailer that locates it, at the given offset
+func writePlanLayout(writer io.Writer, offset int64) int64 {
+	description, err := json.Marshal(nativePlanLayout)
+	common.PanicIfErr(err)
+
+	trailer := make([]byte, planLayoutTrailerSize)
+	binary.LittleEndian.PutUint64(trailer, uint64(offset))
+	binary.LittleEndian.PutUint32(trailer[8:], uint32(len(description)))
+	copy(trailer[12:], planLayoutMagic)
+
+	_, err = writer.Write(append(description, trailer...))
+	common.PanicIfErr(err)
+	return int64(len(description) + len(trailer))
+}
+
+// readPlanLayout returns the layout that the plan file in data was written with, and where its description starts
+func readPlanLayout(data []byte) (layout planFileLayout, descriptionOffset uint64, err error) {
+	if len(data) < planLayoutTrailerSize || string(data[len(data)-len(planLayoutMagic):]) != planLayoutMagic {
+		return layout, 0, errors.New("plan file doesn't describe its layout")
+	}
+	trailer := data[len(data)-planLayoutTrailerSize:]
+	descriptionOffset = binary.LittleEndian.Uint64(trailer)
+	descriptionLength := uint64(binary.LittleEndian.Uint32(trailer[8:]))
+	if descriptionOffset+descriptionLength > uint64(len(data)-planLayoutTrailerSize) {
+		return layout, 0, errors.New("plan file's layout description is out of range")
+	}
+
+	err = json.Unmarshal(data[descriptionOffset:descriptionOffset+descriptionLength], &layout)
+	return layout, descriptionOffset, err
+}
+
+// convertPlanStruct copies each field of src, laid out as described by from, into the field of the same name in dst,
+// which has our native layout, as described by to. Fields that src doesn't have are left as they are in dst
+func convertPlanStruct(src []byte, from []planFieldLayout, fromOrder binary.ByteOrder, dst []byte, to []planFieldLayout) error {
+	fromByName := make(map[string]planFieldLayout, len(from))
+	for _, f := range from {
+		if !isPlanScalarKind(f.Kind) || f.Offset+f.Size > uint64(len(src)) || (f.IsArray && f.ElemSize == 0) {
+			return fmt.Errorf("plan field %s has an invalid layout", f.Name)
+		}
+		fromByName[f.Name] = f
+	}
+
+	for _, t := range to {
+		f, ok := fromByName[t.Name]
+		if !ok || f.IsArray != t.IsArray {
+			continue
+		}
+		if !t.IsArray {
+			convertPlanScalar(src[f.Offset:f.Offset+f.Size], f.Kind, fromOrder, dst[t.Offset:t.Offset+t.Size])
+			continue
+		}
+
+		count := f.Size / f.ElemSize
+		if n := t.Size / t.ElemSize; n < count {
+			count = n
+		}
+		for i := uint64(0); i < count; i++ {
+			s := f.Offset + i*f.ElemSize
+			d := t.Offset + i*t.ElemSize
+			convertPlanScalar(src[s:s+f.ElemSize], f.Kind, fromOrder, dst[d:d+t.ElemSize])
+		}
+	}
+	return nil
+}
+
+// convertPlanScalar copies a bool or integer, converting its size and byte order
+func convertPlanScalar(src []byte, srcKind reflect.Kind, srcOrder binary.ByteOrder, dst []byte) {
+	var v uint64
+	switch len(src) {
+	case 1:
+		v = uint64(src[0])
+	case 2:
+		v = uint64(srcOrder.Uint16(src))
+	case 4:
+		v = uint64(srcOrder.Uint32(src))
+	case 8:
+		v = srcOrder.Uint64(src)
+	}
+	if bits := uint(len(src) * 8); isSignedKind(srcKind) && bits < 64 {
+		v = uint64(int64(v<<(64-bits)) >> (64 - bits)) // sign extend
+	}
+
+	putPlanUint(dst, v, nativeByteOrder)
+}
+
+// structBytes returns the memory of the struct that v points to
+func structBytes(v interface{}) []byte {
+	rv := reflect.ValueOf(v)
+	size := int(rv.Elem().Type().Size())
+	return (*[math.MaxInt32]byte)(unsafe.Pointer(rv.Pointer()))[:size:size]
+}
+
+// ConvertToCurrentLayout makes sure that the plan file is in the current version and layout, converting it if necessary.
+// Since the version is part of the file name, it returns the name that the plan file has once converted
+func (jpfn JobPartPlanFileName) ConvertToCurrentLayout() (JobPartPlanFileName, error) {
+	jobID, partNum, version, err := jpfn.parseNameAndVersion()
+	if err != nil {
+		return jpfn, err
+	}
+	if version < OldestReadableDataSchemaVersion || version > DataSchemaVersion {
+		return jpfn, fmt.Errorf("job part plan file's data schema version ('%d') can't be read by this app, which reads versions %d to %d", version, OldestReadableDataSchemaVersion, DataSchemaVersion)
+	}
+
+	data, err := ioutil.ReadFile(jpfn.GetJobPartPlanPath())
+	if err != nil {
+		return jpfn, err
+	}
+	layout, descriptionOffset, err := readPlanLayout(data)
+	if legacyLayout, isLegacy := legacyPlanLayouts[version]; isLegacy {
+		layout, descriptionOffset, err = legacyLayout, uint64(len(data)), nil
+	}
+	if err != nil {
+		return jpfn, err
+	}
+	if version == DataSchemaVersion && reflect.DeepEqual(layout, nativePlanLayout) {
+		return jpfn, nil // nothing to do
+	}
+
+	converted, err := convertPlanFile(data[:descriptionOffset], layout)
+	if err != nil {
+		return jpfn, fmt.Errorf("couldn't convert job part plan file %s: %w", jpfn, err)
+	}
+
+	// write the converted file alongside, and only remove the original once that has worked
+	newName := JobsAdmin.NewJobPartPlanFileName(jobID, partNum)
+	tempPath := newName.GetJobPartPlanPath() + ".converting"
+	if err = ioutil.WriteFile(tempPath, converted, common.DEFAULT_FILE_PERM); err != nil {
+		return jpfn, err
+	}
+	if err = os.Rename(tempPath, newName.GetJobPartPlanPath()); err != nil {
+		return jpfn, err
+	}
+	if newName != jpfn {
+		_ = os.Remove(jpfn.GetJobPartPlanPath())
+	}
+	return newName, nil
+}
+
+// convertPlanFile converts the contents of a plan file (excluding its layout description) from the given layout to ours
+func convertPlanFile(data []byte, layout planFileLayout) ([]byte, error) {
+	order := layout.byteOrder()
+	if layout.HeaderSize > uint64(len(data)) || layout.TransferSize == 0 {
+		return nil, errors.New("plan file is too short")
+	}
+
+	header := JobPartPlanHeader{}
+	if err := convertPlanStruct(data[:layout.HeaderSize], layout.Header, order, structBytes(&header), nativePlanLayout.Header); err != nil {
+		return nil, err
+	}
+	header.Version = DataSchemaVersion
+
+	oldTransfersStart := layout.HeaderSize + uint64(header.CommandStringLength)
+	oldStringsStart := oldTransfersStart + uint64(header.NumTransfers)*layout.TransferSize
+	if oldStringsStart > uint64(len(data)) {
+		return nil, errors.New("plan file is too short for its transfers")
+	}
+	newStringsStart := nativePlanLayout.HeaderSize + uint64(header.CommandStringLength) + uint64(header.NumTransfers)*nativePlanLayout.TransferSize
+	stringsShift := int64(newStringsStart) - int64(oldStringsStart) // since strings are located by their offsets in the file
+	if header.DstBlobData.HeaderOverflowOffset != 0 {
+		header.DstBlobData.HeaderOverflowOffset += stringsShift
+	}
+
+	converted := &bytesWriter{}
+	writePlanStruct(converted, &header)
+	converted.Write(data[layout.HeaderSize:oldTransfersStart]) // the command string
+
+	for t := uint64(0); t < uint64(header.NumTransfers); t++ {
+		start := oldTransfersStart + t*layout.TransferSize
+		transfer := JobPartPlanTransfer{}
+		if err := convertPlanStruct(data[start:start+layout.TransferSize], layout.Transfer, order, structBytes(&transfer), nativePlanLayout.Transfer); err != nil {
+			return nil, err
+		}
+		transfer.SrcOffset += stringsShift
+		writePlanStruct(converted, &transfer)
+	}
+
+	converted.Write(data[oldStringsStart:])
+	writePlanLayout(converted, int64(len(converted.buf)))
+	return converted.buf, nil
+}
+
+type bytesWriter struct {
+	buf []byte
+}
+
+func (w *bytesWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// writePlanStruct serializes the plan struct that v points to, in our layout, to an io.Writer & returns the number of bytes written
+func writePlanStruct(writer io.Writer, v interface{}) int64 {
+	value := reflect.ValueOf(v).Elem()
+	byteSlice := make([]byte, value.Type().Size()) // so that padding is always zero
+	encodePlanStruct(value, 0, nativeByteOrder, byteSlice)
+	_, err := writer.Write(byteSlice)
+	common.PanicIfErr(err)
+	return int64(len(byteSlice))
+}
+
+// encodePlanStruct serializes each field of the struct value into buf, at the field's offset plus base, in the given byte order
+func encodePlanStruct(value reflect.Value, base uint64, order binary.ByteOrder, buf []byte) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := value.Field(i)
+		offset := base + uint64(t.Field(i).Offset)
+		switch field.Kind() {
+		case reflect.Struct:
+			encodePlanStruct(field, offset, order, buf)
+		case reflect.Array:
+			elemSize := uint64(field.Type().Elem().Size())
+			for j := 0; j < field.Len(); j++ {
+				at := offset + uint64(j)*elemSize
+				putPlanScalar(field.Index(j), order, buf[at:at+elemSize])
+			}
+		default:
+			putPlanScalar(field, order, buf[offset:offset+uint64(field.Type().Size())])
+		}
+	}
+}
+
+// putPlanScalar serializes a bool or integer into dst, which is its size
+func putPlanScalar(v reflect.Value, order binary.ByteOrder, dst []byte) {
+	var x uint64
+	switch {
+	case v.Kind() == reflect.Bool:
+		if v.Bool() {
+			x = 1
+		}
+	case isSignedKind(v.Kind()):
+		x = uint64(v.Int())
+	default:
+		x = v.Uint()
+	}
+	putPlanUint(dst, x, order)
+}
+
+// putPlanUint stores the low bytes of v in dst, in the given byte order
+func putPlanUint(dst []byte, v uint64, order binary.ByteOrder) {
+	switch len(dst) {
+	case 1:
+		dst[0] = byte(v)
+	case 2:
+		order.PutUint16(dst, uint16(v))
+	case 4:
+		order.PutUint32(dst, uint32(v))
+	case 8:
+		order.PutUint64(dst, v)
+	}
+}
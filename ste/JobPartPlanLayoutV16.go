@@ -0,0 +1,139 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/binary"
+	"reflect"
+	"unsafe"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Plan files of data schema version 16 were written before plan files described their own layout. They were always read
+// by the build (and so on the architecture) that wrote them, so their layout is that of these structs, which are copies
+// of the plan structs as they were in version 16, with the same field names, and with primitive types in place of the
+// named types so that they stay as they were. They must not be changed.
+
+type planUUIDV16 struct {
+	D1 uint32
+	D2 uint16
+	D3 uint16
+	D4 [8]uint8
+}
+
+type planHeaderV16 struct {
+	Version                        uint32
+	StartTime                      int64
+	JobID                          planUUIDV16
+	PartNum                        uint32
+	SourceRootLength               uint16
+	SourceRoot                     [1000]byte
+	SourceExtraQueryLength         uint16
+	SourceExtraQuery               [1000]byte
+	DestinationRootLength          uint16
+	DestinationRoot                [1000]byte
+	DestExtraQueryLength           uint16
+	DestExtraQuery                 [1000]byte
+	IsFinalPart                    bool
+	ForceWrite                     uint8
+	ForceIfReadOnly                bool
+	AutoDecompress                 bool
+	Priority                       uint8
+	TTLAfterCompletion             uint32
+	FromTo                         uint16
+	Fpo                            uint8
+	CommandStringLength            uint32
+	NumTransfers                   uint32
+	LogLevel                       uint8
+	DstBlobData                    planDstBlobV16
+	DstLocalData                   planDstLocalV16
+	PreserveSMBPermissions         uint8
+	PreserveSMBInfo                bool
+	S2SGetPropertiesInBackend      bool
+	S2SSourceChangeValidation      bool
+	DestLengthValidation           bool
+	S2SInvalidMetadataHandleOption uint8
+	atomicJobStatus                uint32
+	DeleteSnapshotsOption          uint8
+}
+
+type planDstBlobV16 struct {
+	BlobType                 uint8
+	NoGuessMimeType          bool
+	ContentTypeLength        uint16
+	ContentType              [256]byte
+	ContentEncodingLength    uint16
+	ContentEncoding          [256]byte
+	ContentLanguageLength    uint16
+	ContentLanguage          [256]byte
+	ContentDispositionLength uint16
+	ContentDisposition       [256]byte
+	CacheControlLength       uint16
+	CacheControl             [256]byte
+	BlockBlobTier            uint8
+	PageBlobTier             uint8
+	PutMd5                   bool
+	MetadataLength           uint16
+	Metadata                 [1000]byte
+	BlobTagsLength           uint16
+	BlobTags                 [4000]byte
+	BlockSize                int64
+}
+
+type planDstLocalV16 struct {
+	PreserveLastModifiedTime bool
+	MD5VerificationOption    uint8
+}
+
+type planTransferV16 struct {
+	SrcOffset                   int64
+	SrcLength                   int16
+	DstLength                   int16
+	EntityType                  uint8
+	ModifiedTime                int64
+	SourceSize                  int64
+	CompletionTime              uint64
+	SrcContentTypeLength        int16
+	SrcContentEncodingLength    int16
+	SrcContentLanguageLength    int16
+	SrcContentDispositionLength int16
+	SrcCacheControlLength       int16
+	SrcContentMD5Length         int16
+	SrcMetadataLength           int16
+	SrcBlobTypeLength           int16
+	SrcBlobTierLength           int16
+	SrcBlobVersionIDLength      int16
+	SrcBlobTagsLength           int16
+	atomicTransferStatus        int32
+	atomicErrorCode             int32
+}
+
+// legacyPlanLayouts are the layouts of the plan files, from before plan files described their layout, that can be converted
+var legacyPlanLayouts = map[common.Version]planFileLayout{
+	16: {
+		BigEndian:    nativeByteOrder == binary.BigEndian,
+		HeaderSize:   uint64(unsafe.Sizeof(planHeaderV16{})),
+		TransferSize: uint64(unsafe.Sizeof(planTransferV16{})),
+		Header:       describePlanStruct(reflect.TypeOf(planHeaderV16{}), "", 0, nil),
+		Transfer:     describePlanStruct(reflect.TypeOf(planTransferV16{}), "", 0, nil),
+	},
+}
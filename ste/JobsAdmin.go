@@ -76,9 +76,18 @@ var JobsAdmin interface {
 	// AddJobPartMgr associates the specified JobPartMgr with the Jobs Administrator
 	//AddJobPartMgr(appContext context.Context, planFile JobPartPlanFileName) IJobPartMgr
 	/*ScheduleTransfer(jptm IJobPartTransferMgr)*/
-	ScheduleChunk(priority common.JobPriority, chunkFunc chunkFunc)
+	ScheduleChunk(priority common.JobPriority, part IJobPartMgr, chunkFunc chunkFunc)
 
-	ResurrectJob(jobId common.JobID, sourceSAS string, destinationSAS string) bool
+	// ScheduleSmallFileChunk schedules a chunk of a small file on the small-file lane, which has its own pool of workers
+	ScheduleSmallFileChunk(part IJobPartMgr, chunkFunc chunkFunc)
+
+	// ScheduleBoostedChunk schedules a chunk of a nearly-complete transfer, which the main pool picks up ahead of other chunks
+	ScheduleBoostedChunk(part IJobPartMgr, chunkFunc chunkFunc)
+
+	// IsSmallFile says whether a file of the given size counts as small, i.e. is smaller than the small-file threshold
+	IsSmallFile(size int64) bool
+
+	ResurrectJob(jobId common.JobID, sourceSAS string, destinationSAS string, alsoToSAS map[string]string) bool
 
 	ResurrectJobParts()
 
@@ -88,14 +97,9 @@ var JobsAdmin interface {
 	// JobPartPlanFile will be created inside this folder.
 	AppPathFolder() string
 
-	// returns the current value of bytesOverWire.
+	// returns the current value of bytesOverWire, for all jobs.
 	BytesOverWire() int64
 
-	AddSuccessfulBytesInActiveFiles(n int64)
-
-	// returns number of bytes successfully transferred in transfers that are currently in progress
-	SuccessfulBytesInActiveFiles() uint64
-
 	MessagesForJobLog() <-chan struct {
 		string
 		pipeline.LogLevel
@@ -137,9 +141,27 @@ func initJobsAdmin(appCtx context.Context, concurrency ConcurrencySettings, targ
 	// from which each part is picked up one by one
 	// and transfers of that JobPart are scheduled
 	partsCh := make(chan IJobPartMgr, PartsChannelSize)
+
+	// When the parts take turns, the work waits in round robin queues instead, which feed these channels one item at a time
+	workChannelSize := channelSize
+	var roundRobin *roundRobinQueues
+	if concurrency.SchedulingFairness == common.ESchedulingFairness.RoundRobin() {
+		workChannelSize = 0
+		roundRobin = &roundRobinQueues{
+			normalTransfers: newRoundRobinQueue(channelSize),
+			lowTransfers:    newRoundRobinQueue(channelSize),
+			normalChunks:    newRoundRobinQueue(channelSize),
+			boostedChunks:   newRoundRobinQueue(channelSize),
+			lowChunks:       newRoundRobinQueue(channelSize),
+			smallFileChunks: newRoundRobinQueue(channelSize),
+		}
+	}
+
 	// Create normal & low transfer/chunk channels
-	normalTransferCh, normalChunkCh := make(chan IJobPartTransferMgr, channelSize), make(chan chunkFunc, channelSize)
-	lowTransferCh, lowChunkCh := make(chan IJobPartTransferMgr, channelSize), make(chan chunkFunc, channelSize)
+	normalTransferCh, normalChunkCh := make(chan IJobPartTransferMgr, workChannelSize), make(chan chunkFunc, workChannelSize)
+	lowTransferCh, lowChunkCh := make(chan IJobPartTransferMgr, workChannelSize), make(chan chunkFunc, workChannelSize)
+	boostedChunkCh := make(chan chunkFunc, workChannelSize)
+	smallFileChunkCh := make(chan chunkFunc, workChannelSize)
 
 	maxRamBytesToUse := getMaxRamForChunks()
 
@@ -179,8 +201,11 @@ func initJobsAdmin(appCtx context.Context, concurrency ConcurrencySettings, targ
 			normalTransferCh: normalTransferCh,
 			lowTransferCh:    lowTransferCh,
 			normalChunckCh:   normalChunkCh,
+			boostedChunkCh:   boostedChunkCh,
 			lowChunkCh:       lowChunkCh,
+			smallFileChunkCh: smallFileChunkCh,
 		},
+		roundRobin: roundRobin,
 		poolSizingChannels: poolSizingChannels{ // all deliberately unbuffered, because pool sizer routine works in lock-step with these - processing them as they happen, never catching up on populated buffer later
 			entryNotificationCh: make(chan struct{}),
 			exitNotificationCh:  make(chan struct{}),
@@ -207,6 +232,15 @@ func initJobsAdmin(appCtx context.Context, concurrency ConcurrencySettings, targ
 	// Spin up slice pool pruner
 	go ja.slicePoolPruneLoop()
 
+	if roundRobin != nil {
+		go roundRobin.normalTransfers.feedTransfers(normalTransferCh)
+		go roundRobin.lowTransfers.feedTransfers(lowTransferCh)
+		go roundRobin.normalChunks.feedChunks(normalChunkCh)
+		go roundRobin.boostedChunks.feedChunks(boostedChunkCh)
+		go roundRobin.lowChunks.feedChunks(lowChunkCh)
+		go roundRobin.smallFileChunks.feedChunks(smallFileChunkCh)
+	}
+
 	// One routine constantly monitors the partsChannel.  It takes the JobPartManager from
 	// the Channel and schedules the transfers of that JobPart.
 	go ja.scheduleJobParts()
@@ -218,6 +252,12 @@ func initJobsAdmin(appCtx context.Context, concurrency ConcurrencySettings, targ
 	for cc := 0; cc < concurrency.TransferInitiationPoolSize.Value; cc++ {
 		go ja.transferProcessor(cc)
 	}
+
+	// Small files get their own pool too. Each needs only one short request, so lots of them can be in flight at once,
+	// without being queued up behind the chunks of big files (which the main pool is sized for).
+	for cc := 0; cc < concurrency.SmallFilePoolSize.Value; cc++ {
+		go ja.smallFileChunkProcessor(cc)
+	}
 }
 
 // Decide on a max amount of RAM we are willing to use. This functions as a cap, and prevents excessive usage.
@@ -423,30 +463,43 @@ func (ja *jobsAdmin) chunkProcessor(workerID int) {
 
 	for {
 		// We check for scalebacks first to shrink goroutine pool
-		// Then, we check chunks: normal & low priority
+		// Then, we check chunks: boosted (i.e. of nearly-complete transfers), normal & low priority
 		select {
 		case <-ja.poolSizingChannels.scalebackRequestCh:
 			return
 		default:
 			select {
-			case chunkFunc := <-ja.xferChannels.normalChunckCh:
+			case chunkFunc := <-ja.xferChannels.boostedChunkCh:
 				chunkFunc(workerID)
 			default:
 				select {
-				case chunkFunc := <-ja.xferChannels.lowChunkCh:
+				case chunkFunc := <-ja.xferChannels.normalChunckCh:
 					chunkFunc(workerID)
 				default:
-					time.Sleep(100 * time.Millisecond) // Sleep before looping around
-					// TODO: Question: In order to safely support high goroutine counts,
-					// do we need to review sleep duration, or find an approach that does not require waking every x milliseconds
-					// For now, duration has been increased substantially from the previous 1 ms, to reduce cost of
-					// the wake-ups.
+					select {
+					case chunkFunc := <-ja.xferChannels.lowChunkCh:
+						chunkFunc(workerID)
+					default:
+						time.Sleep(100 * time.Millisecond) // Sleep before looping around
+						// TODO: Question: In order to safely support high goroutine counts,
+						// do we need to review sleep duration, or find an approach that does not require waking every x milliseconds
+						// For now, duration has been increased substantially from the previous 1 ms, to reduce cost of
+						// the wake-ups.
+					}
 				}
 			}
 		}
 	}
 }
 
+// dedicated worker for the small-file lane. Unlike chunkProcessor, it's not part of the pool that gets sized
+// dynamically, so it can simply block until there's something to do
+func (ja *jobsAdmin) smallFileChunkProcessor(workerID int) {
+	for chunkFunc := range ja.xferChannels.smallFileChunkCh {
+		chunkFunc(workerID)
+	}
+}
+
 // separate from the chunkProcessor, this dedicated worker that reads in and executes transfer initiation jobs
 // (which in turn schedule chunks that get picked up by chunkProcessor)
 func (ja *jobsAdmin) transferProcessor(workerID int) {
@@ -487,18 +540,18 @@ func (ja *jobsAdmin) transferProcessor(workerID int) {
 // There will be only 1 instance of the jobsAdmin type.
 // The coordinator uses this to manage all the running jobs and their job parts.
 type jobsAdmin struct {
-	atomicSuccessfulBytesInActiveFiles int64
-	atomicBytesTransferredWhileTuning  int64
-	atomicTuningEndSeconds             int64
-	atomicCurrentMainPoolSize          int32 // align 64 bit integers for 32 bit arch
-	concurrency                        ConcurrencySettings
-	logger                             common.ILoggerCloser
-	jobIDToJobMgr                      jobIDToJobMgr // Thread-safe map from each JobID to its JobInfo
+	atomicBytesTransferredWhileTuning int64
+	atomicTuningEndSeconds            int64
+	atomicCurrentMainPoolSize         int32 // align 64 bit integers for 32 bit arch
+	concurrency                       ConcurrencySettings
+	logger                            common.ILoggerCloser
+	jobIDToJobMgr                     jobIDToJobMgr // Thread-safe map from each JobID to its JobInfo
 	// Other global state can be stored in more fields here...
 	logDir                      string // Where log files are stored
 	planDir                     string // Initialize to directory where Job Part Plans are stored
 	coordinatorChannels         CoordinatorChannels
 	xferChannels                XferChannels
+	roundRobin                  *roundRobinQueues // nil unless the parts take turns
 	poolSizingChannels          poolSizingChannels
 	appCtx                      context.Context
 	pacer                       pacerAdmin
@@ -526,7 +579,19 @@ type XferChannels struct {
 	normalTransferCh <-chan IJobPartTransferMgr // Read-only
 	lowTransferCh    <-chan IJobPartTransferMgr // Read-only
 	normalChunckCh   chan chunkFunc             // Read-write
+	boostedChunkCh   chan chunkFunc             // Read-write
 	lowChunkCh       chan chunkFunc             // Read-write
+	smallFileChunkCh chan chunkFunc             // Read-write
+}
+
+// roundRobinQueues hold the work waiting for each pool when the job parts take turns (see common.SchedulingFairness)
+type roundRobinQueues struct {
+	normalTransfers *roundRobinQueue
+	lowTransfers    *roundRobinQueue
+	normalChunks    *roundRobinQueue
+	boostedChunks   *roundRobinQueue
+	lowChunks       *roundRobinQueue
+	smallFileChunks *roundRobinQueue
 }
 
 type poolSizingChannels struct {
@@ -578,60 +643,77 @@ func (ja *jobsAdmin) JobMgrEnsureExists(jobID common.JobID,
 		})
 }
 
-func (ja *jobsAdmin) ScheduleTransfer(priority common.JobPriority, jptm IJobPartTransferMgr) {
+func (ja *jobsAdmin) ScheduleTransfer(priority common.JobPriority, part IJobPartMgr, jptm IJobPartTransferMgr) {
 	switch priority { // priority determines which channel handles the job part's transfers
 	case common.EJobPriority.Normal():
 		//jptm.SetChunkChannel(ja.xferChannels.normalChunckCh)
-		ja.coordinatorChannels.normalTransferCh <- jptm
+		if ja.roundRobin != nil {
+			ja.roundRobin.normalTransfers.push(part, jptm)
+		} else {
+			ja.coordinatorChannels.normalTransferCh <- jptm
+		}
 	case common.EJobPriority.Low():
 		//jptm.SetChunkChannel(ja.xferChannels.lowChunkCh)
-		ja.coordinatorChannels.lowTransferCh <- jptm
+		if ja.roundRobin != nil {
+			ja.roundRobin.lowTransfers.push(part, jptm)
+		} else {
+			ja.coordinatorChannels.lowTransferCh <- jptm
+		}
 	default:
 		ja.Panic(fmt.Errorf("invalid priority: %q", priority))
 	}
 }
 
-func (ja *jobsAdmin) ScheduleChunk(priority common.JobPriority, chunkFunc chunkFunc) {
+func (ja *jobsAdmin) ScheduleChunk(priority common.JobPriority, part IJobPartMgr, chunkFunc chunkFunc) {
 	switch priority { // priority determines which channel handles the job part's transfers
 	case common.EJobPriority.Normal():
-		ja.xferChannels.normalChunckCh <- chunkFunc
+		if ja.roundRobin != nil {
+			ja.roundRobin.normalChunks.push(part, chunkFunc)
+		} else {
+			ja.xferChannels.normalChunckCh <- chunkFunc
+		}
 	case common.EJobPriority.Low():
-		ja.xferChannels.lowChunkCh <- chunkFunc
+		if ja.roundRobin != nil {
+			ja.roundRobin.lowChunks.push(part, chunkFunc)
+		} else {
+			ja.xferChannels.lowChunkCh <- chunkFunc
+		}
 	default:
 		ja.Panic(fmt.Errorf("invalid priority: %q", priority))
 	}
 }
 
-func (ja *jobsAdmin) BytesOverWire() int64 {
-	return ja.pacer.GetTotalTraffic()
+func (ja *jobsAdmin) ScheduleSmallFileChunk(part IJobPartMgr, chunkFunc chunkFunc) {
+	if ja.roundRobin != nil {
+		ja.roundRobin.smallFileChunks.push(part, chunkFunc)
+	} else {
+		ja.xferChannels.smallFileChunkCh <- chunkFunc
+	}
 }
 
-func (ja *jobsAdmin) AddSuccessfulBytesInActiveFiles(n int64) {
-	atomic.AddInt64(&ja.atomicSuccessfulBytesInActiveFiles, n)
+func (ja *jobsAdmin) ScheduleBoostedChunk(part IJobPartMgr, chunkFunc chunkFunc) {
+	if ja.roundRobin != nil {
+		ja.roundRobin.boostedChunks.push(part, chunkFunc)
+	} else {
+		ja.xferChannels.boostedChunkCh <- chunkFunc
+	}
 }
 
-func (ja *jobsAdmin) SuccessfulBytesInActiveFiles() uint64 {
-	n := atomic.LoadInt64(&ja.atomicSuccessfulBytesInActiveFiles)
-	if n < 0 {
-		n = 0 // should never happen, but would result in nasty over/underflow if it did
-	}
-	return uint64(n)
+func (ja *jobsAdmin) IsSmallFile(size int64) bool {
+	// no workers, or a zero threshold, means no lane (not even for empty files)
+	threshold := int64(ja.concurrency.SmallFileThreshold.Value)
+	return ja.concurrency.SmallFilePoolSize.Value > 0 && threshold > 0 && size < threshold
+}
+
+func (ja *jobsAdmin) BytesOverWire() int64 {
+	return ja.pacer.GetTotalTraffic()
 }
 
-func (ja *jobsAdmin) ResurrectJob(jobId common.JobID, sourceSAS string, destinationSAS string) bool {
+func (ja *jobsAdmin) ResurrectJob(jobId common.JobID, sourceSAS string, destinationSAS string, alsoToSAS map[string]string) bool {
 	// Search the existing plan files for the PartPlans for the given jobId
-	// only the files which have JobId has prefix and DataSchemaVersion as Suffix
+	// only the files which have JobId has prefix and a readable DataSchemaVersion as Suffix
 	// are include in the result
-	files := func(prefix, ext string) []os.FileInfo {
-		var files []os.FileInfo
-		filepath.Walk(ja.planDir, func(path string, fileInfo os.FileInfo, _ error) error {
-			if !fileInfo.IsDir() && strings.HasPrefix(fileInfo.Name(), prefix) && strings.HasSuffix(fileInfo.Name(), ext) {
-				files = append(files, fileInfo)
-			}
-			return nil
-		})
-		return files
-	}(jobId.String(), fmt.Sprintf(".steV%d", DataSchemaVersion))
+	files := ja.readablePlanFiles(jobId.String())
 	// If no files with JobId exists then return false
 	if len(files) == 0 {
 		return false
@@ -645,25 +727,57 @@ func (ja *jobsAdmin) ResurrectJob(jobId common.JobID, sourceSAS string, destinat
 			continue
 		}
 		mmf := planFile.Map()
-		jm := ja.JobMgrEnsureExists(jobID, mmf.Plan().LogLevel, "")
-		jm.AddJobPart(partNum, planFile, mmf, sourceSAS, destinationSAS, false)
+		plan := mmf.Plan()
+		jm := ja.JobMgrEnsureExists(jobID, plan.LogLevel, "")
+		partDestinationSAS := destinationSAS
+		if sas, ok := alsoToSAS[string(plan.DestinationRoot[:plan.DestinationRootLength])]; ok {
+			// the part is for one of the --also-to destinations of an upload, which has a SAS of its own
+			partDestinationSAS = sas
+		}
+		jm.AddJobPart(partNum, planFile, mmf, sourceSAS, partDestinationSAS, false)
 	}
 	return true
 }
 
+// readablePlanFiles returns the plan files, whose names start with the given prefix, that can be read by this app.
+// Plan files written by an older version of the app, or on a different architecture, are converted to the current layout
+func (ja *jobsAdmin) readablePlanFiles(prefix string) []os.FileInfo {
+	var files []os.FileInfo
+	filepath.Walk(ja.planDir, func(path string, fileInfo os.FileInfo, _ error) error {
+		if fileInfo == nil || fileInfo.IsDir() || !strings.HasPrefix(fileInfo.Name(), prefix) || !isReadablePlanFileName(fileInfo.Name()) {
+			return nil
+		}
+
+		planFile, err := JobPartPlanFileName(fileInfo.Name()).ConvertToCurrentLayout()
+		if err != nil {
+			ja.Log(pipeline.LogWarning, fmt.Sprintf("Skipping job part plan file %s: %s", fileInfo.Name(), err.Error()))
+			return nil
+		}
+		if string(planFile) != fileInfo.Name() {
+			if fileInfo, err = os.Stat(planFile.GetJobPartPlanPath()); err != nil {
+				return nil
+			}
+		}
+		files = append(files, fileInfo)
+		return nil
+	})
+	return files
+}
+
+// isReadablePlanFileName reports whether the name is that of a plan file with a data schema version we can read
+func isReadablePlanFileName(name string) bool {
+	for v := OldestReadableDataSchemaVersion; v <= DataSchemaVersion; v++ {
+		if strings.HasSuffix(name, fmt.Sprintf(".steV%d", v)) {
+			return true
+		}
+	}
+	return false
+}
+
 // reconstructTheExistingJobParts reconstructs the in memory JobPartPlanInfo for existing memory map JobFile
 func (ja *jobsAdmin) ResurrectJobParts() {
 	// Get all the Job part plan files in the plan directory
-	files := func(ext string) []os.FileInfo {
-		var files []os.FileInfo
-		filepath.Walk(ja.planDir, func(path string, fileInfo os.FileInfo, _ error) error {
-			if !fileInfo.IsDir() && strings.HasSuffix(fileInfo.Name(), ext) {
-				files = append(files, fileInfo)
-			}
-			return nil
-		})
-		return files
-	}(fmt.Sprintf(".steV%d", DataSchemaVersion))
+	files := ja.readablePlanFiles("")
 
 	// TODO : sort the file.
 	for f := 0; f < len(files); f++ {
@@ -0,0 +1,302 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// POSIX ACLs are stored by Linux in these extended attributes, in the format parsed by posixACLEntriesFromXattr
+const (
+	posixACLAccessXattr  = "system.posix_acl_access"
+	posixACLDefaultXattr = "system.posix_acl_default"
+	posixACLXattrVersion = 2
+)
+
+// tags of the entries in a POSIX ACL extended attribute
+const (
+	posixACLTagUserObj  = 0x01
+	posixACLTagUser     = 0x02
+	posixACLTagGroupObj = 0x04
+	posixACLTagGroup    = 0x08
+	posixACLTagMask     = 0x10
+	posixACLTagOther    = 0x20
+)
+
+// posixACLEntriesFromXattr converts a POSIX ACL extended attribute to ADLS Gen2 ACL entries, e.g. "user::rwx".
+// The entries for named users and groups are only included if asked for, since they hold local IDs.
+func posixACLEntriesFromXattr(data []byte, isDefault bool, includeNamed bool) ([]string, error) {
+	const headerSize, entrySize = 4, 8
+	if len(data) < headerSize || (len(data)-headerSize)%entrySize != 0 {
+		return nil, errors.New("malformed POSIX ACL extended attribute")
+	}
+	if version := binary.LittleEndian.Uint32(data); version != posixACLXattrVersion {
+		return nil, fmt.Errorf("unsupported POSIX ACL extended attribute version %d", version)
+	}
+
+	prefix := ""
+	if isDefault {
+		prefix = "default:"
+	}
+
+	entries := make([]string, 0, (len(data)-headerSize)/entrySize)
+	for offset := headerSize; offset < len(data); offset += entrySize {
+		tag := binary.LittleEndian.Uint16(data[offset:])
+		perm := posixPermissionString(binary.LittleEndian.Uint16(data[offset+2:]))
+		id := strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data[offset+4:])), 10)
+
+		switch tag {
+		case posixACLTagUserObj:
+			entries = append(entries, prefix+"user::"+perm)
+		case posixACLTagUser:
+			if includeNamed {
+				entries = append(entries, prefix+"user:"+id+":"+perm)
+			}
+		case posixACLTagGroupObj:
+			entries = append(entries, prefix+"group::"+perm)
+		case posixACLTagGroup:
+			if includeNamed {
+				entries = append(entries, prefix+"group:"+id+":"+perm)
+			}
+		case posixACLTagMask:
+			entries = append(entries, prefix+"mask::"+perm)
+		case posixACLTagOther:
+			entries = append(entries, prefix+"other::"+perm)
+		default:
+			return nil, fmt.Errorf("unknown POSIX ACL entry tag %#x", tag)
+		}
+	}
+	return entries, nil
+}
+
+// posixACLEntriesFromMode returns the ACL entries that are equivalent to the given permission bits
+func posixACLEntriesFromMode(mode os.FileMode) []string {
+	perm := uint16(mode.Perm())
+	return []string{
+		"user::" + posixPermissionString(perm>>6),
+		"group::" + posixPermissionString(perm>>3),
+		"other::" + posixPermissionString(perm),
+	}
+}
+
+// posixPermissionString formats the lowest three bits of perm, e.g. as "r-x"
+func posixPermissionString(perm uint16) string {
+	result := []byte("---")
+	if perm&4 != 0 {
+		result[0] = 'r'
+	}
+	if perm&2 != 0 {
+		result[1] = 'w'
+	}
+	if perm&1 != 0 {
+		result[2] = 'x'
+	}
+	return string(result)
+}
+
+// dirAccessControlTracker remembers which destination directories have had their access control set, so that
+// a directory created implicitly above many files only has it set once per job
+type dirAccessControlTracker struct {
+	applied sync.Map
+}
+
+func newDirAccessControlTracker() *dirAccessControlTracker {
+	return &dirAccessControlTracker{}
+}
+
+// claim returns true the first time it's called for a given directory
+func (t *dirAccessControlTracker) claim(dir string) bool {
+	_, alreadyClaimed := t.applied.LoadOrStore(dir, struct{}{})
+	return !alreadyClaimed
+}
+
+// mapPOSIXPrincipals replaces the local numeric IDs, or Windows SIDs, in the access control with the Azure AD object IDs
+// they map to. The service would take an unmapped ID as an object ID that doesn't exist, so owners, groups and named
+// entries without a mapping are left out, and returned so that they can be logged.
+func mapPOSIXPrincipals(ac azbfs.BlobFSAccessControl, idMap common.POSIXIDMap) (result azbfs.BlobFSAccessControl, unmapped []string) {
+	mapID := func(id string, lookup func(uint32) (string, bool), lookupSID func(string) (string, bool)) (string, bool) {
+		if common.IsSID(id) {
+			return lookupSID(id)
+		}
+		n, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return "", false
+		}
+		return lookup(uint32(n))
+	}
+
+	if ac.Owner != "" {
+		if objectID, ok := mapID(ac.Owner, idMap.User, idMap.UserSID); ok {
+			result.Owner = objectID
+		} else {
+			unmapped = append(unmapped, "owner "+ac.Owner)
+		}
+	}
+	if ac.Group != "" {
+		if objectID, ok := mapID(ac.Group, idMap.Group, idMap.GroupSID); ok {
+			result.Group = objectID
+		} else {
+			unmapped = append(unmapped, "group "+ac.Group)
+		}
+	}
+
+	var entries []string
+	for _, entry := range strings.Split(ac.ACL, ",") {
+		prefix := ""
+		if strings.HasPrefix(entry, "default:") {
+			prefix, entry = "default:", strings.TrimPrefix(entry, "default:")
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[1] == "" {
+			entries = append(entries, prefix+entry) // not a named entry
+			continue
+		}
+
+		lookup, lookupSID := idMap.User, idMap.UserSID
+		if parts[0] == "group" {
+			lookup, lookupSID = idMap.Group, idMap.GroupSID
+		}
+		if objectID, ok := mapID(parts[1], lookup, lookupSID); ok {
+			entries = append(entries, prefix+parts[0]+":"+objectID+":"+parts[2])
+		} else {
+			unmapped = append(unmapped, "ACL entry "+prefix+entry)
+		}
+	}
+	result.ACL = strings.Join(entries, ",")
+	return result, unmapped
+}
+
+// posixSIP returns the source info provider if the source's POSIX permissions are to be preserved
+func (u *blobFSSenderBase) posixSIP() (IPOSIXPermissionBearingSourceInfoProvider, bool) {
+	if !u.jptm.Info().PreserveSMBPermissions.IsTruthy() {
+		return nil, false
+	}
+	posixSIP, ok := u.sip.(IPOSIXPermissionBearingSourceInfoProvider)
+	return posixSIP, ok
+}
+
+// getPOSIXAccessControl reads the access control of a source path, with its owners mapped to ADLS Gen2 principals
+func (u *blobFSSenderBase) getPOSIXAccessControl(posixSIP IPOSIXPermissionBearingSourceInfoProvider, srcPath string) (azbfs.BlobFSAccessControl, error) {
+	includeOwnership := u.jptm.Info().PreserveSMBPermissions == common.EPreservePermissionsOption.OwnershipAndACLs()
+	ac, err := posixSIP.GetPOSIXAccessControl(srcPath, includeOwnership)
+	if err != nil || !posixSIP.IsLocal() {
+		return ac, err // a remote source's owners are already ADLS Gen2 principals
+	}
+
+	ac, unmapped := mapPOSIXPrincipals(ac, u.jptm.POSIXIDMap())
+	if len(unmapped) > 0 {
+		u.jptm.MarkPropertiesNotPreserved(fmt.Sprintf("Not preserving the %s of %s, as they have no mapping in the POSIX ID map", strings.Join(unmapped, ", "), srcPath))
+	}
+	return ac, nil
+}
+
+// setPathAccessControl gives the destination file, or folder, the access control of its source
+func (u *blobFSSenderBase) setPathAccessControl() error {
+	posixSIP, ok := u.posixSIP()
+	if !ok {
+		return nil
+	}
+	ac, err := u.getPOSIXAccessControl(posixSIP, u.jptm.Info().Source)
+	if err != nil {
+		return err
+	}
+
+	if u.SendableEntityType() == common.EEntityType.Folder() {
+		_, err = u.dirURL().SetAccessControl(u.jptm.Context(), ac)
+	} else {
+		_, err = u.fileURL().SetAccessControl(u.jptm.Context(), ac)
+	}
+	return err
+}
+
+// setImplicitDirsAccessControl gives the directories above the file, which creating it may have created implicitly,
+// the access control of their sources. It stops at the destination root, which the user chose and which we didn't create.
+// That's only needed for local sources, since the directories of a remote one are transferred as folders of their own.
+// The directories are matched up with their sources by their path relative to the destination root, since the
+// destination can have more or fewer levels than the source, e.g. when the source root's name is added to it.
+func (u *blobFSSenderBase) setImplicitDirsAccessControl(parentDir azbfs.DirectoryURL) error {
+	posixSIP, ok := u.posixSIP()
+	if !ok || !posixSIP.IsLocal() {
+		return nil
+	}
+
+	root, err := url.Parse(u.jptm.GetDestinationRoot())
+	if err != nil {
+		return err
+	}
+	rootPath := strings.TrimSuffix(root.Path, "/")
+	fileURL := u.fileURL().URL()
+	srcBase := implicitDirsSourceBase(u.jptm.GetSourceRoot(), u.jptm.Info().Source, strings.TrimPrefix(fileURL.Path, rootPath+"/"))
+
+	for dir := parentDir; !dir.IsFileSystemRoot(); {
+		dirURL := dir.URL()
+		dirPath := strings.TrimSuffix(dirURL.Path, "/")
+		if len(dirPath) <= len(rootPath) || !strings.HasPrefix(dirPath, rootPath+"/") {
+			break // reached the destination root
+		}
+
+		if u.jptm.DirAccessControlTracker().claim(dirPath) {
+			srcDir := filepath.Join(srcBase, filepath.FromSlash(strings.TrimPrefix(dirPath, rootPath+"/")))
+			if _, err := os.Stat(srcDir); os.IsNotExist(err) {
+				// e.g. a directory that only exists at the destination, because the path was rewritten
+				u.jptm.Log(pipeline.LogDebug, fmt.Sprintf("Not setting the access control of directory %s, as %s doesn't exist", dirPath, srcDir))
+			} else {
+				ac, err := u.getPOSIXAccessControl(posixSIP, srcDir)
+				if err != nil {
+					return err
+				}
+				if _, err = dir.SetAccessControl(u.jptm.Context(), ac); err != nil {
+					return err
+				}
+				u.jptm.Log(pipeline.LogDebug, fmt.Sprintf("Set the access control of directory %s from %s", dirPath, srcDir))
+			}
+		}
+
+		if dir, err = dir.GetParentDir(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// implicitDirsSourceBase returns the local directory which corresponds to the destination root. That's the source root,
+// unless the destination paths start with the source root's name, in which case it's the source root's parent.
+func implicitDirsSourceBase(srcRoot, srcPath, dstRelativePath string) string {
+	srcRelativePath := strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(srcPath, srcRoot)), "/")
+	if dstRelativePath != srcRelativePath && dstRelativePath == path.Join(filepath.Base(srcRoot), srcRelativePath) {
+		return filepath.Dir(srcRoot)
+	}
+	return srcRoot
+}
@@ -103,6 +103,21 @@ type ConcurrencySettings struct {
 	// (i.e. creates chunkfuncs)
 	TransferInitiationPoolSize *ConfiguredInt
 
+	// SmallFilePoolSize is the size of the goroutine pool that sends the chunks of small uploads (i.e. those smaller than SmallFileThreshold bytes).
+	// It's separate from the main pool, and much bigger, since each small file needs only one short request
+	SmallFilePoolSize *ConfiguredInt
+
+	// SmallFileThreshold is the size, in bytes, below which files are small. 0 means no files use the small-file pool
+	SmallFileThreshold *ConfiguredInt
+
+	// SchedulingFairness says whether the transfers and chunks of different job parts are handed to the pools in the order
+	// they were scheduled, or by taking turns between the parts
+	SchedulingFairness common.SchedulingFairness
+
+	// BoostNearlyComplete says whether the main pool should pick up the chunks of transfers that are nearly complete ahead
+	// of other chunks, so that fewer partly-transferred files are open at once. It's set from the command line.
+	BoostNearlyComplete bool
+
 	// EnumerationPoolSize is size of auxiliary goroutine pool used in enumerators (only some of which are in fact parallelized)
 	EnumerationPoolSize *ConfiguredInt
 
@@ -132,6 +147,8 @@ func (c ConcurrencySettings) AutoTuneMainPool() bool {
 }
 
 const defaultTransferInitiationPoolSize = 64
+const defaultSmallFilePoolSize = 256
+const defaultSmallFileThreshold = 1024 * 1024
 const defaultEnumerationPoolSize = 16
 const concurrentFilesFloor = 32
 
@@ -146,13 +163,16 @@ func NewConcurrencySettings(maxFileAndSocketHandles int, requestAutoTuneGRs bool
 		InitialMainPoolSize:        initialMainPoolSize,
 		MaxMainPoolSize:            maxMainPoolSize,
 		TransferInitiationPoolSize: getTransferInitiationPoolSize(),
+		SmallFilePoolSize:          getSmallFilePoolSize(),
+		SmallFileThreshold:         getSmallFileThreshold(),
+		SchedulingFairness:         getSchedulingFairness(),
 		EnumerationPoolSize:        getEnumerationPoolSize(),
 		ParallelStatFiles:          getParallelStatFiles(),
 		CheckCpuWhenTuning:         getCheckCpuUsageWhenTuning(),
 	}
 
 	s.MaxOpenDownloadFiles = getMaxOpenPayloadFiles(maxFileAndSocketHandles,
-		maxMainPoolSize.Value+s.TransferInitiationPoolSize.Value+s.EnumerationPoolSize.Value)
+		maxMainPoolSize.Value+s.TransferInitiationPoolSize.Value+s.SmallFilePoolSize.Value+s.EnumerationPoolSize.Value)
 
 	// Set the max idle connections that we allow. If there are any more idle connections
 	// than this, they will be closed, and then will result in creation of new connections
@@ -168,7 +188,7 @@ func NewConcurrencySettings(maxFileAndSocketHandles int, requestAutoTuneGRs bool
 	// on Windows when this value was set to 500 but there were 1000 to 2000 goroutines in the
 	// main pool size.  Using DialContext appears to mitigate that issue, so the value
 	// we compute here is really just to reduce unneeded make and break of connections)
-	s.MaxIdleConnections = maxMainPoolSize.Value
+	s.MaxIdleConnections = maxMainPoolSize.Value + s.SmallFilePoolSize.Value
 
 	return s
 }
@@ -230,6 +250,39 @@ func getTransferInitiationPoolSize() *ConfiguredInt {
 	return &ConfiguredInt{defaultTransferInitiationPoolSize, false, envVar.Name, "hard-coded default"}
 }
 
+func getSmallFilePoolSize() *ConfiguredInt {
+	envVar := common.EEnvironmentVariable.SmallFilePoolSize()
+
+	if c := tryNewConfiguredInt(envVar); c != nil {
+		return c
+	}
+
+	return &ConfiguredInt{defaultSmallFilePoolSize, false, envVar.Name, "hard-coded default"}
+}
+
+func getSmallFileThreshold() *ConfiguredInt {
+	envVar := common.EEnvironmentVariable.SmallFileThreshold()
+
+	if c := tryNewConfiguredInt(envVar); c != nil {
+		return c
+	}
+
+	return &ConfiguredInt{defaultSmallFileThreshold, false, envVar.Name, "hard-coded default"}
+}
+
+func getSchedulingFairness() common.SchedulingFairness {
+	envVar := common.EEnvironmentVariable.SchedulingFairness()
+	fairness := common.ESchedulingFairness.FIFO()
+
+	if override := common.GetLifecycleMgr().GetEnvironmentVariable(envVar); override != "" {
+		if err := fairness.Parse(override); err != nil {
+			log.Fatalf("error parsing the env %s %q failed with error %v",
+				envVar.Name, override, err)
+		}
+	}
+	return fairness
+}
+
 func getEnumerationPoolSize() *ConfiguredInt {
 	envVar := common.EEnvironmentVariable.EnumerationPoolSize()
 
@@ -0,0 +1,147 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// warmUpTimeout bounds how long the start of a job may be held back while its endpoints are warmed up.
+// Warming up is only an optimization, so anything not done by then is just left to the transfers themselves
+const warmUpTimeout = 10 * time.Second
+
+var warmConnectionsOnce sync.Once
+var warmConnections int
+
+// warmConnectionCount is how many connections, per endpoint, AZCOPY_WARM_CONNECTIONS asks us to open before the
+// first transfer of a job is scheduled
+func warmConnectionCount() int {
+	warmConnectionsOnce.Do(func() {
+		raw := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.WarmConnections())
+		if n, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && n > 0 {
+			warmConnections = n
+		}
+	})
+	return warmConnections
+}
+
+// endpointsToWarmUp returns the distinct endpoints (as scheme://host/) of the job's source and destination that are
+// reached through our own HTTP client. S3 and GCP sources are left out, because their SDKs bring their own
+func endpointsToWarmUp(fromTo common.FromTo, sourceRoot, destinationRoot string) []string {
+	endpoints := make([]string, 0, 2)
+	for _, e := range []struct {
+		location common.Location
+		root     string
+	}{{fromTo.From(), sourceRoot}, {fromTo.To(), destinationRoot}} {
+		switch e.location {
+		case common.ELocation.Blob(), common.ELocation.BlobFS(), common.ELocation.File(), common.ELocation.ManagedDisk():
+		default:
+			continue
+		}
+		u, err := url.Parse(e.root)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			continue
+		}
+		endpoint := u.Scheme + "://" + u.Host + "/"
+		if len(endpoints) == 0 || endpoints[0] != endpoint {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// warmUpEndpoints resolves the name of each endpoint, and then opens up to count connections to it, so that the
+// first chunks don't each pay for a DNS lookup and a TLS handshake. Go doesn't cache DNS answers itself, so the
+// lookup only helps where the OS (or a local resolver) does; but that's the usual case, and the lookup is cheap.
+// The connections are opened with HEAD requests to the root of the endpoint. The service's answer doesn't matter
+// (it's typically an authorization failure): once the response is read the connection is back in the client's
+// pool, ready for the transfers
+func warmUpEndpoints(ctx context.Context, client *http.Client, endpoints []string, count int, log func(string)) {
+	ctx, cancel := context.WithTimeout(ctx, warmUpTimeout)
+	defer cancel()
+
+	// connections beyond what the client keeps idle would just be closed again
+	if t, ok := client.Transport.(*http.Transport); ok && t.MaxIdleConnsPerHost > 0 && count > t.MaxIdleConnsPerHost {
+		count = t.MaxIdleConnsPerHost
+	}
+
+	for _, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			continue
+		}
+		start := time.Now()
+		addrs, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+		if err != nil {
+			log(fmt.Sprintf("Could not resolve %s ahead of the transfers: %v", u.Hostname(), err))
+			continue // the transfers will fail with a clearer error, if the name really can't be resolved
+		}
+		log(fmt.Sprintf("Resolved %s to %s in %v", u.Hostname(), strings.Join(addrs, ", "), time.Since(start)))
+
+		if count > 0 {
+			start = time.Now()
+			opened := openConnections(ctx, client, endpoint, count)
+			log(fmt.Sprintf("Opened %d of %d warm connections to %s in %v", opened, count, u.Host, time.Since(start)))
+		}
+	}
+}
+
+// openConnections sends count concurrent requests to the endpoint, and returns how many of them got a response.
+// No response is closed until they have all come back (or failed). Otherwise the connection of the first one
+// could be re-used by a later one, and we'd end up with fewer connections than asked for
+func openConnections(ctx context.Context, client *http.Client, endpoint string, count int) int {
+	var opened int32
+	allBack := &sync.WaitGroup{}
+	allBack.Add(count)
+	allClosed := &sync.WaitGroup{}
+	allClosed.Add(count)
+	for i := 0; i < count; i++ {
+		go func() {
+			defer allClosed.Done()
+			req, err := http.NewRequest(http.MethodHead, endpoint, nil)
+			if err != nil {
+				allBack.Done()
+				return
+			}
+			resp, err := client.Do(req.WithContext(ctx))
+			allBack.Done()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&opened, 1)
+			allBack.Wait()
+			resp.Body.Close()
+		}()
+	}
+	allClosed.Wait()
+	return int(opened)
+}
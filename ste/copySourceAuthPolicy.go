@@ -0,0 +1,59 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+const xMsCopySourceAuthorizationHeader = "x-ms-copy-source-authorization"
+
+// copySourceAuthorizationServiceVersion is the first service version accepting x-ms-copy-source-authorization.
+const copySourceAuthorizationServiceVersion = "2020-10-02"
+
+// NewCopySourceAuthPolicyFactory creates a factory which authorizes the source of service side copies (PutBlockFromURL, PutBlobFromURL etc.)
+// with the source's own bearer token, so that the source and destination can be in different tenants.
+// The token credential refreshes itself, so every request picks up the current token.
+// The requests must ask for a service version that accepts the token, which withCopyFromURLServiceVersion sees to
+func NewCopySourceAuthPolicyFactory(sourceCredential azblob.TokenCredential) pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			if exist, _ := doesHeaderExistCaseInsensitive(request.Header, xMsCopySourceHeader); exist {
+				request.Header.Set(xMsCopySourceAuthorizationHeader, "Bearer "+sourceCredential.Token())
+			}
+			return next.Do(ctx, request)
+		}
+	})
+}
+
+// withCopyFromURLServiceVersion returns the context for a request that copies from a URL, with the latest service version from the sdk
+// as its service version. When the source is authorized with its own token, the version is raised, if need be, to one that accepts it
+func withCopyFromURLServiceVersion(jptm IJobPartTransferMgr) context.Context {
+	version := azblob.ServiceVersion
+	// service versions are dates, so they compare lexically
+	if jptm.IsCopySourceAuthorized() && version < copySourceAuthorizationServiceVersion {
+		version = copySourceAuthorizationServiceVersion
+	}
+	return context.WithValue(jptm.Context(), ServiceAPIVersionOverride, version)
+}
@@ -21,8 +21,12 @@
 package ste
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/url"
+	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-file-go/azfile"
@@ -124,7 +128,10 @@ func (bd *azureFilesDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, s
 		// wait until we get the headers back... but we have not yet read its whole body.
 		// The Download method encapsulates any retries that may be necessary to get to the point of receiving response headers.
 		jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
-		get, err := srcFileURL.Download(jptm.Context(), id.OffsetInFile(), length, false)
+		primaryContext, abandonPrimary := context.WithCancel(jptm.Context())
+		defer abandonPrimary()
+		requestStart := time.Now()
+		get, err := srcFileURL.Download(primaryContext, id.OffsetInFile(), length, false)
 		if err != nil {
 			jptm.FailActiveDownload("Downloading response body", err) // cancel entire transfer because this chunk has failed
 			return
@@ -145,7 +152,22 @@ func (bd *azureFilesDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, s
 			NotifyFailedRead: common.NewReadLogFunc(jptm, u),
 		})
 		defer retryReader.Close()
-		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), retryReader, pacer), true)
+		body := hedgeChunkBody(jptm.Context(), retryReader, abandonPrimary, requestStart, length,
+			func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+				hedge, err := srcFileURL.Download(ctx, id.OffsetInFile()+offset, length-offset, false)
+				if err != nil {
+					return nil, err
+				}
+				if !hedge.LastModified().Equal(jptm.LastModifiedTime().In(hedge.LastModified().Location())) {
+					hedge.Response().Body.Close()
+					return nil, errors.New("Azure File modified during transfer")
+				}
+				return hedge.Body(azfile.RetryReaderOptions{MaxRetryRequests: MaxRetryPerDownloadBody}), nil
+			},
+			func() {
+				jptm.Log(pipeline.LogInfo, fmt.Sprintf("Chunk at offset %d is slower than usual, so requesting the rest of it again", id.OffsetInFile()))
+			})
+		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), body, pacer), true)
 		if err != nil {
 			jptm.FailActiveDownload("Enqueuing chunk", err)
 			return
@@ -22,7 +22,12 @@ package ste
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
@@ -38,6 +43,17 @@ type blobDownloader struct {
 
 	// used to avoid downloading zero ranges of page blobs
 	pageRangeOptimizer *pageRangeOptimizer
+
+	// kept to preserve the blob's creation time once the download is done
+	jptm        IJobPartTransferMgr
+	srcPipeline pipeline.Pipeline
+
+	// the blob's creation and last access times, taken from the first download response (or, if no range
+	// was downloaded, from the blob's properties) when they are to be preserved
+	sourceTimesOnce sync.Once
+	sourceTimesErr  error
+	creationTime    time.Time
+	lastAccessTime  time.Time
 }
 
 func newBlobDownloader() downloader {
@@ -48,6 +64,9 @@ func newBlobDownloader() downloader {
 }
 
 func (bd *blobDownloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline) {
+	bd.jptm = jptm
+	bd.srcPipeline = srcPipeline
+
 	if jptm.Info().SrcBlobType == azblob.BlobPageBlob {
 		// page blobs need a file-specific pacer
 		// See comments in uploader-pageBlob for the reasons, since the same reasons apply are are explained there
@@ -62,6 +81,92 @@ func (bd *blobDownloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipelin
 
 func (bd *blobDownloader) Epilogue() {
 	_ = bd.filePacer.Close()
+
+	if bd.jptm == nil || !bd.jptm.IsLive() || !bd.needsSourceTimes() {
+		return
+	}
+
+	// normally already recorded from a download response; this only makes a request for e.g. empty blobs
+	bd.sourceTimesOnce.Do(func() { bd.sourceTimesErr = bd.fetchSourceTimes() })
+	if bd.sourceTimesErr != nil {
+		bd.jptm.FailActiveDownload("Getting source creation and last access times", bd.sourceTimesErr)
+		return
+	}
+
+	info := bd.jptm.Info()
+	if info.PreserveSMBInfo {
+		if err := bd.preserveCreationTime(); err != nil {
+			bd.jptm.FailActiveDownload("Setting destination file creation time", err)
+		}
+	}
+	if info.PreserveLastAccessTime && bd.lastAccessTime.IsZero() {
+		bd.jptm.MarkPropertiesNotPreserved("The last access time is not preserved, as last access time tracking isn't enabled on the source account")
+	}
+}
+
+// the last access time is only returned from this service version on
+const lastAccessTimeServiceVersion = "2020-02-10"
+
+func (bd *blobDownloader) needsSourceTimes() bool {
+	info := bd.jptm.Info()
+	return info.PreserveSMBInfo || info.PreserveLastAccessTime
+}
+
+// sourceTimesContext asks for a service version which returns the last access time, if it's wanted
+func (bd *blobDownloader) sourceTimesContext(ctx context.Context) context.Context {
+	if bd.jptm.Info().PreserveLastAccessTime {
+		return context.WithValue(ctx, ServiceAPIVersionOverride, lastAccessTimeServiceVersion)
+	}
+	return ctx
+}
+
+// recordSourceTimes takes the blob's creation and last access times from the headers of a response about the blob.
+// The last access time is only known when last access time tracking is enabled on the account.
+func (bd *blobDownloader) recordSourceTimes(header http.Header) error {
+	if value := header.Get("x-ms-creation-time"); value != "" {
+		t, err := time.Parse(time.RFC1123, value)
+		if err != nil {
+			return fmt.Errorf("cannot parse the creation time %q: %w", value, err)
+		}
+		bd.creationTime = t
+	}
+	if value := header.Get("x-ms-last-access-time"); value != "" {
+		t, err := time.Parse(time.RFC1123, value)
+		if err != nil {
+			return fmt.Errorf("cannot parse the last access time %q: %w", value, err)
+		}
+		bd.lastAccessTime = t
+	}
+	return nil
+}
+
+// fetchSourceTimes gets the blob's properties, for when no range of the blob was downloaded
+func (bd *blobDownloader) fetchSourceTimes() error {
+	u, err := url.Parse(bd.jptm.Info().Source)
+	if err != nil {
+		return err
+	}
+
+	props, err := azblob.NewBlobURL(*u, bd.srcPipeline).GetProperties(bd.sourceTimesContext(bd.jptm.Context()), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return err
+	}
+	return bd.recordSourceTimes(props.Response().Header)
+}
+
+func (bd *blobDownloader) LastAccessTime() (time.Time, bool) {
+	return bd.lastAccessTime, !bd.lastAccessTime.IsZero()
+}
+
+// preserveCreationTime applies the blob's creation time to the downloaded file.
+// This is the only SMB property a blob has. It's a no-op where the OS doesn't allow setting the creation time.
+func (bd *blobDownloader) preserveCreationTime() error {
+	ctad, ok := interface{}(bd).(creationTimeAwareDownloader)
+	if !ok {
+		return nil
+	}
+
+	return ctad.PutCreationTime(bd.creationTime, bd.jptm.Info())
 }
 
 // Returns a chunk-func for blob downloads
@@ -114,11 +219,26 @@ func (bd *blobDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipe
 		// The Download method encapsulates any retries that may be necessary to get to the point of receiving response headers.
 		jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
 		enrichedContext := withRetryNotification(jptm.Context(), bd.filePacer)
-		get, err := srcBlobURL.Download(enrichedContext, id.OffsetInFile(), length, accessConditions, false, azblob.ClientProvidedKeyOptions{})
+		if bd.needsSourceTimes() {
+			enrichedContext = bd.sourceTimesContext(enrichedContext)
+		}
+		primaryContext, abandonPrimary := context.WithCancel(enrichedContext)
+		defer abandonPrimary()
+		requestStart := time.Now()
+		get, err := srcBlobURL.Download(primaryContext, id.OffsetInFile(), length, accessConditions, false, azblob.ClientProvidedKeyOptions{})
 		if err != nil {
 			jptm.FailActiveDownload("Downloading response body", err) // cancel entire transfer because this chunk has failed
 			return
 		}
+		if err = checkSecondaryReadIsCurrent(get.Response(), jptm.LastModifiedTime()); err != nil {
+			get.Response().Body.Close()
+			jptm.FailActiveDownload("Downloading from the secondary endpoint", err)
+			return
+		}
+		if bd.needsSourceTimes() {
+			// every range's response carries the blob's properties, so the first one saves a GetProperties call
+			bd.sourceTimesOnce.Do(func() { bd.sourceTimesErr = bd.recordSourceTimes(get.Response().Header) })
+		}
 
 		// Enqueue the response body to be written out to disk
 		// The retryReader encapsulates any retries that may be necessary while downloading the body
@@ -128,7 +248,22 @@ func (bd *blobDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipe
 			NotifyFailedRead: common.NewReadLogFunc(jptm, u),
 		})
 		defer retryReader.Close()
-		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), retryReader, pacer), true)
+		body := hedgeChunkBody(jptm.Context(), retryReader, abandonPrimary, requestStart, length,
+			func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+				hedge, err := srcBlobURL.Download(ctx, id.OffsetInFile()+offset, length-offset, accessConditions, false, azblob.ClientProvidedKeyOptions{})
+				if err != nil {
+					return nil, err
+				}
+				if err = checkSecondaryReadIsCurrent(hedge.Response(), jptm.LastModifiedTime()); err != nil {
+					hedge.Response().Body.Close()
+					return nil, err
+				}
+				return hedge.Body(azblob.RetryReaderOptions{MaxRetryRequests: destWriter.MaxRetryPerDownloadBody()}), nil
+			},
+			func() {
+				jptm.Log(pipeline.LogInfo, fmt.Sprintf("Chunk at offset %d is slower than usual, so requesting the rest of it again", id.OffsetInFile()))
+			})
+		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), body, pacer), true)
 		if err != nil {
 			jptm.FailActiveDownload("Enqueuing chunk", err)
 			return
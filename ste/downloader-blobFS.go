@@ -21,7 +21,10 @@
 package ste
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/url"
 	"time"
 
@@ -57,7 +60,10 @@ func (bd *blobFSDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPi
 		// wait until we get the headers back... but we have not yet read its whole body.
 		// The Download method encapsulates any retries that may be necessary to get to the point of receiving response headers.
 		jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
-		get, err := srcFileURL.Download(jptm.Context(), id.OffsetInFile(), length)
+		primaryContext, abandonPrimary := context.WithCancel(jptm.Context())
+		defer abandonPrimary()
+		requestStart := time.Now()
+		get, err := srcFileURL.Download(primaryContext, id.OffsetInFile(), length)
 		if err != nil {
 			jptm.FailActiveDownload("Downloading response body", err) // cancel entire transfer because this chunk has failed
 			return
@@ -82,7 +88,22 @@ func (bd *blobFSDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPi
 			NotifyFailedRead: common.NewReadLogFunc(jptm, u),
 		})
 		defer retryReader.Close()
-		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), retryReader, pacer), true)
+		body := hedgeChunkBody(jptm.Context(), retryReader, abandonPrimary, requestStart, length,
+			func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+				hedge, err := srcFileURL.Download(ctx, id.OffsetInFile()+offset, length-offset)
+				if err != nil {
+					return nil, err
+				}
+				if hedge.LastModified() != get.LastModified() {
+					hedge.Response().Body.Close()
+					return nil, errors.New("BFS File modified during transfer")
+				}
+				return hedge.Body(azbfs.RetryReaderOptions{MaxRetryRequests: MaxRetryPerDownloadBody}), nil
+			},
+			func() {
+				jptm.Log(pipeline.LogInfo, fmt.Sprintf("Chunk at offset %d is slower than usual, so requesting the rest of it again", id.OffsetInFile()))
+			})
+		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), body, pacer), true)
 		if err != nil {
 			jptm.FailActiveDownload("Enqueuing chunk", err)
 			return
@@ -0,0 +1,65 @@
+// +build windows
+
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// This file implements the windows-triggered creationTimeAwareDownloader interface.
+
+func (*blobDownloader) PutCreationTime(creationTime time.Time, txInfo TransferInfo) error {
+	if creationTime.IsZero() {
+		return nil // e.g. old blobs, for which the service doesn't return a creation time
+	}
+
+	destPtr, err := syscall.UTF16PtrFromString(txInfo.Destination)
+	if err != nil {
+		return fmt.Errorf("failed convert destination string to UTF16 pointer: %w", err)
+	}
+
+	var sa windows.SecurityAttributes
+	sa.Length = uint32(unsafe.Sizeof(sa))
+	sa.InheritHandle = 1
+
+	// need custom CreateFile call because need FILE_WRITE_ATTRIBUTES
+	fd, err := windows.CreateFile(destPtr,
+		windows.FILE_WRITE_ATTRIBUTES, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, &sa,
+		windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return fmt.Errorf("attempted file open: %w", err)
+	}
+	defer windows.Close(fd)
+
+	// only the creation time is set, the last write time is preserved separately, like for any download
+	creationFileTime := windows.NsecToFiletime(creationTime.UnixNano())
+	if err = windows.SetFileTime(fd, &creationFileTime, nil, nil); err != nil {
+		return fmt.Errorf("attempted update file creation time: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// localDownloader reads the source of a copy between local paths. The rest of the work, i.e. writing the destination
+// file, is the same as for any download. The source is opened once per file, and each chunk reads its own range of it
+type localDownloader struct {
+	jptm   IJobPartTransferMgr
+	sip    ILocalSourceInfoProvider
+	source common.CloseableReaderAt
+}
+
+func newLocalDownloader() downloader {
+	return &localDownloader{}
+}
+
+func (ld *localDownloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline) {
+	ld.jptm = jptm
+	sip, err := newLocalSourceInfoProvider(jptm)
+	if err != nil {
+		jptm.FailActiveDownload("Getting source info", err)
+		return
+	}
+	ld.sip = sip.(ILocalSourceInfoProvider)
+
+	if err = ld.checkSourceUnchanged(); err != nil {
+		jptm.FailActiveDownload("Checking source", err)
+		return
+	}
+	if jptm.Info().SourceSize == 0 {
+		return // nothing to read
+	}
+	if ld.source, err = ld.sip.OpenSourceFile(); err != nil {
+		jptm.FailActiveDownload("Opening source", err)
+	}
+}
+
+func (ld *localDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline, destWriter common.ChunkedFileWriter, id common.ChunkID, length int64, pacer pacer) chunkFunc {
+	return createDownloadChunkFunc(jptm, id, func() {
+		if ld.source == nil {
+			return // the prologue failed, so the transfer already has
+		}
+
+		// a local read can't be usefully retried, so it's not retryable
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		section := ioutil.NopCloser(io.NewSectionReader(ld.source, id.OffsetInFile(), length))
+		err := destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), section, pacer), false)
+		if err != nil {
+			jptm.FailActiveDownload("Reading source", err)
+			return
+		}
+	})
+}
+
+// Epilogue closes the source, and fails the transfer if the source changed while it was being copied
+func (ld *localDownloader) Epilogue() {
+	if ld.source != nil {
+		_ = ld.source.Close()
+	}
+	if ld.jptm != nil && ld.jptm.IsLive() && ld.sip != nil {
+		if err := ld.checkSourceUnchanged(); err != nil {
+			ld.jptm.FailActiveDownload("Checking source", err)
+		}
+	}
+}
+
+// checkSourceUnchanged makes sure the source is as it was when it was enumerated
+func (ld *localDownloader) checkSourceUnchanged() error {
+	lmt, err := ld.sip.GetFreshFileLastModifiedTime()
+	if err != nil {
+		return err
+	}
+	if !lmt.Equal(ld.jptm.LastModifiedTime()) {
+		return errors.New("file modified since transfer scheduled")
+	}
+	return nil
+}
+
+func (ld *localDownloader) SetFolderProperties(jptm IJobPartTransferMgr) error {
+	// no-op (the folder is created, but as for other downloads, its properties aren't preserved)
+	return nil
+}
+
+// isSameLocalFile says whether both paths lead to one existing file
+func isSameLocalFile(a, b string) bool {
+	infoA, err := common.OSStat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := common.OSStat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
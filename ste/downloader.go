@@ -21,6 +21,8 @@
 package ste
 
 import (
+	"time"
+
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
 )
@@ -54,6 +56,17 @@ type smbPropertyAwareDownloader interface {
 	PutSMBProperties(sip ISMBPropertyBearingSourceInfoProvider, txInfo TransferInfo) error
 }
 
+// creationTimeAwareDownloader is a windows-triggered interface, like smbPropertyAwareDownloader.
+// It's for sources which have a creation time but no other SMB properties, e.g. blobs.
+type creationTimeAwareDownloader interface {
+	PutCreationTime(creationTime time.Time, txInfo TransferInfo) error
+}
+
+// accessTimeAwareDownloader is a downloader which knows the last access time of its source.
+type accessTimeAwareDownloader interface {
+	LastAccessTime() (time.Time, bool)
+}
+
 type downloaderFactory func() downloader
 
 func createDownloadChunkFunc(jptm IJobPartTransferMgr, id common.ChunkID, body func()) chunkFunc {
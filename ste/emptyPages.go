@@ -0,0 +1,101 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// minEmptyPageRunSkipped is the shortest run of empty pages that's left out of a chunk that also holds data.
+// Shorter runs are sent anyway, since splitting the chunk into more requests would cost more than sending their zeros
+const minEmptyPageRunSkipped = 256 * 1024
+
+// pagesIn counts the pages that length bytes occupy
+func pagesIn(length int64) int64 {
+	return (length + azblob.PageBlobPageBytes - 1) / azblob.PageBlobPageBytes
+}
+
+// MarkEmptyPagesSkipped notes pages of the source that weren't uploaded because they were empty, so that they're
+// counted in the job's summary if the transfer succeeds
+func (jptm *jobPartTransferMgr) MarkEmptyPagesSkipped(count int64) {
+	atomic.AddInt64(&jptm.atomicEmptyPagesSkipped, count)
+}
+
+func (jm *jobMgr) RecordEmptyPagesSkipped(count uint64) {
+	atomic.AddUint64(&jm.atomicEmptyPagesSkipped, count)
+}
+
+// EmptyPagesSkippedCount returns how many empty pages the job's successful page blob uploads didn't need to send
+func (jm *jobMgr) EmptyPagesSkippedCount() uint64 {
+	return atomic.LoadUint64(&jm.atomicEmptyPagesSkipped)
+}
+
+// chunkSection reads part of a chunk, so that the data either side of a run of empty pages can be sent on its own.
+// Its positions are relative to the section, since that's what a retried request expects of its body
+type chunkSection struct {
+	chunk    io.ReadSeeker
+	offset   int64
+	length   int64
+	position int64
+}
+
+func newChunkSection(chunk io.ReadSeeker, offset, length int64) *chunkSection {
+	return &chunkSection{chunk: chunk, offset: offset, length: length}
+}
+
+func (s *chunkSection) Read(p []byte) (int, error) {
+	if s.position >= s.length {
+		return 0, io.EOF
+	}
+	if _, err := s.chunk.Seek(s.offset+s.position, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if remaining := s.length - s.position; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := s.chunk.Read(p)
+	s.position += int64(n)
+	if err == io.EOF && s.position < s.length {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (s *chunkSection) Seek(offset int64, whence int) (int64, error) {
+	newPosition := s.position
+	switch whence {
+	case io.SeekStart:
+		newPosition = offset
+	case io.SeekCurrent:
+		newPosition += offset
+	case io.SeekEnd:
+		newPosition = s.length + offset
+	}
+	if newPosition < 0 {
+		return 0, errors.New("cannot seek to before beginning")
+	}
+	s.position = newPosition
+	return s.position, nil
+}
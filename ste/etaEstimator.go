@@ -0,0 +1,176 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// how many progress samples are kept. Progress is normally sampled every 2 seconds, so this is the last minute or so
+	etaWindowSize = 30
+
+	// how many intervals (i.e. pairs of samples) are needed before we offer an estimate at all
+	etaMinIntervals = 3
+
+	// samples closer together than this are dropped. The job summary is fetched by more than just the progress loop
+	// (e.g. for the final summary, and by 'jobs show'), and those extra calls would otherwise fill the window with tiny intervals
+	etaMinSampleSpacing = time.Second
+
+	// the throughput that a job achieves when it is moving the bytes of big files, rather than paying the per-file cost of small ones.
+	// Used, with the per-file overhead, to model how long the remaining work will take
+	etaPeakThroughputPercentile = 0.9
+
+	// the typical throughput, used when no files completed recently, so the per-file overhead can't be measured
+	etaTypicalThroughputPercentile = 0.5
+)
+
+type etaSample struct {
+	at        time.Time
+	bytesDone uint64
+	filesDone uint32
+}
+
+// etaEstimator predicts how long the rest of a job will take, from its progress over a sliding window of recent samples.
+// Rather than dividing the remaining bytes by the average speed since the start of the job (which is slow to react and,
+// for jobs of many small files, badly wrong) it models the time left as
+//
+//	remaining bytes / peak throughput  +  remaining files * per-file overhead
+//
+// where the peak throughput is a high percentile of the recent per-interval throughputs, and the per-file overhead is
+// however much time each interval spent beyond what moving its bytes at that peak would take, shared among the files it completed
+type etaEstimator struct {
+	lock    sync.Mutex
+	samples []etaSample // used as a ring buffer
+	next    int
+}
+
+func newEtaEstimator(size int) *etaEstimator {
+	return &etaEstimator{samples: make([]etaSample, 0, size)}
+}
+
+// record adds a sample of the job's progress, as at the given time, unless the last one was taken too recently
+func (e *etaEstimator) record(at time.Time, bytesDone uint64, filesDone uint32) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if len(e.samples) > 0 {
+		latest := e.samples[(e.next+len(e.samples)-1)%len(e.samples)]
+		if at.Sub(latest.at) < etaMinSampleSpacing {
+			return
+		}
+	}
+
+	s := etaSample{at: at, bytesDone: bytesDone, filesDone: filesDone}
+	if len(e.samples) < cap(e.samples) {
+		e.samples = append(e.samples, s)
+		return
+	}
+	e.samples[e.next] = s
+	e.next = (e.next + 1) % len(e.samples)
+}
+
+// ordered returns the samples from oldest to newest
+func (e *etaEstimator) ordered() []etaSample {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	result := make([]etaSample, 0, len(e.samples))
+	result = append(result, e.samples[e.next:]...)
+	result = append(result, e.samples[:e.next]...)
+	return result
+}
+
+// estimate returns how long the given remaining bytes and files are likely to take.
+// It returns false if there is not yet enough recent progress for that to be meaningful
+func (e *etaEstimator) estimate(remainingBytes uint64, remainingFiles uint32) (time.Duration, bool) {
+	if remainingBytes == 0 && remainingFiles == 0 {
+		return 0, true
+	}
+
+	samples := e.ordered()
+	type interval struct {
+		seconds float64
+		bytes   float64
+		files   float64
+	}
+	intervals := make([]interval, 0, len(samples))
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		secs := cur.at.Sub(prev.at).Seconds()
+		if secs <= 0 || cur.bytesDone < prev.bytesDone || cur.filesDone < prev.filesDone {
+			continue // e.g. counts that went backwards because a transfer failed and its in-flight bytes no longer count
+		}
+		intervals = append(intervals, interval{
+			seconds: secs,
+			bytes:   float64(cur.bytesDone - prev.bytesDone),
+			files:   float64(cur.filesDone - prev.filesDone),
+		})
+	}
+	if len(intervals) < etaMinIntervals {
+		return 0, false
+	}
+
+	rates := make([]float64, len(intervals))
+	for i, in := range intervals {
+		rates[i] = in.bytes / in.seconds
+	}
+	sort.Float64s(rates)
+	percentile := func(p float64) float64 { return rates[int(p*float64(len(rates)-1))] }
+	peakRate := percentile(etaPeakThroughputPercentile)
+	typicalRate := percentile(etaTypicalThroughputPercentile)
+
+	overheads := make([]float64, 0, len(intervals))
+	for _, in := range intervals {
+		if in.files == 0 {
+			continue
+		}
+		spare := in.seconds
+		if peakRate > 0 {
+			spare -= in.bytes / peakRate
+		}
+		if spare < 0 {
+			spare = 0
+		}
+		overheads = append(overheads, spare/in.files)
+	}
+
+	var seconds float64
+	switch {
+	case remainingBytes > 0 && peakRate <= 0:
+		return 0, false // no bytes are moving, so any estimate would be a guess
+	case len(overheads) > 0:
+		sort.Float64s(overheads)
+		perFileOverhead := overheads[len(overheads)/2]
+		if remainingBytes > 0 {
+			seconds = float64(remainingBytes) / peakRate
+		}
+		seconds += float64(remainingFiles) * perFileOverhead
+	case typicalRate > 0:
+		seconds = float64(remainingBytes) / typicalRate
+	default:
+		return 0, false
+	}
+
+	return time.Duration(seconds * float64(time.Second)), true
+}
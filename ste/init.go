@@ -27,6 +27,7 @@ import (
 	"io/ioutil"
 	"math"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
@@ -140,6 +141,15 @@ func MainSTE(concurrency ConcurrencySettings, targetRateInMegaBitsPerSec float64
 func ExecuteNewCopyJobPartOrder(order common.CopyJobPartOrderRequest) common.CopyJobPartOrderResponse {
 	// Get the file name for this Job Part's Plan
 	jppfn := JobsAdmin.NewJobPartPlanFileName(order.JobID, order.PartNum)
+	if err := checkPlanFolderSpace(JobsAdmin.AppPathFolder(), order); err != nil {
+		return common.CopyJobPartOrderResponse{ErrorMsg: common.CopyJobPartOrderErrorType(err.Error())}
+	}
+	if order.PartNum == 0 {
+		if err := claimJob(JobsAdmin.AppPathFolder(), order.JobID, true); err != nil {
+			return common.CopyJobPartOrderResponse{ErrorMsg: common.CopyJobPartOrderErrorType(err.Error())}
+		}
+		go watchForCancellation(JobsAdmin.AppPathFolder(), order.JobID)
+	}
 	jppfn.Create(order)                                                                   // Convert the order to a plan file
 	jpm := JobsAdmin.JobMgrEnsureExists(order.JobID, order.LogLevel, order.CommandString) // Get a this job part's job manager (create it if it doesn't exist)
 
@@ -154,7 +164,9 @@ func ExecuteNewCopyJobPartOrder(order common.CopyJobPartOrderRequest) common.Cop
 	// Get credential info from RPC request order, and set in InMemoryTransitJobState.
 	jpm.setInMemoryTransitJobState(
 		InMemoryTransitJobState{
-			credentialInfo: order.CredentialInfo,
+			credentialInfo:          order.CredentialInfo,
+			s2sSourceCredentialInfo: order.S2SSourceCredentialInfo,
+			posixIDMap:              order.POSIXIDMap,
 		})
 	// Supply no plan MMF because we don't have one, and AddJobPart will create one on its own.
 	jpm.AddJobPart(order.PartNum, jppfn, nil, order.SourceRoot.SAS, order.DestinationRoot.SAS, true) // Add this part to the Job and schedule its transfers
@@ -173,7 +185,7 @@ func CancelPauseJobOrder(jobID common.JobID, desiredJobStatus common.JobStatus)
 	if !found {
 		// If the Job is not found, search for Job Plan files in the existing plan file
 		// and resurrect the job
-		if !JobsAdmin.ResurrectJob(jobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+		if !JobsAdmin.ResurrectJob(jobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING, nil) {
 			return common.CancelPauseResumeResponse{
 				CancelledPauseResumed: false,
 				ErrorMsg:              fmt.Sprintf("no active job with JobId %s exists", jobID.String()),
@@ -237,6 +249,96 @@ func CancelPauseJobOrder(jobID common.JobID, desiredJobStatus common.JobStatus)
 	return jr
 }
 
+// CancelTransfer cancels one transfer of a job, leaving the rest of the job to carry on. The transfer is marked Cancelled
+// in its plan, which is where the job notices it (see jobPartTransferMgr.WasCanceled), so the job may be running in this
+// process, or, when plan files are memory mapped, in another. If the job is resumed, the transfer is tried again, as are
+// the transfers of a cancelled job.
+func CancelTransfer(req common.CancelTransferRequest) common.CancelPauseResumeResponse {
+	jm, found := JobsAdmin.JobMgr(req.JobID)
+	if !found {
+		if !JobsAdmin.ResurrectJob(req.JobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING, nil) {
+			return common.CancelPauseResumeResponse{
+				CancelledPauseResumed: false,
+				ErrorMsg:              fmt.Sprintf("no job with JobId %s exists", req.JobID.String()),
+			}
+		}
+		jm, _ = JobsAdmin.JobMgr(req.JobID)
+	}
+
+	// transfers are numbered across the parts of the job, in the order that jobs show lists them
+	index := uint32(0)
+	for partNum := PartNumber(0); true; partNum++ {
+		jpm, found := jm.JobPartMgr(partNum)
+		if !found {
+			break
+		}
+		jpp := jpm.Plan()
+		for t := uint32(0); t < jpp.NumTransfers; t, index = t+1, index+1 {
+			if !transferMatches(jpp, t, index, req) {
+				continue
+			}
+
+			src, _, _ := jpp.TransferSrcDstStrings(t)
+			status, cancelled := jpp.Transfer(t).CancelUnlessFinished()
+			if !cancelled {
+				return common.CancelPauseResumeResponse{
+					CancelledPauseResumed: false,
+					ErrorMsg:              fmt.Sprintf("cannot cancel the transfer of %s since it has already finished with status %s", src, status),
+				}
+			}
+			msg := fmt.Sprintf("JobID=%v transfer of %s canceled", req.JobID, src)
+			if jm.ShouldLog(pipeline.LogInfo) {
+				jm.Log(pipeline.LogInfo, msg)
+			}
+			return common.CancelPauseResumeResponse{
+				CancelledPauseResumed: true,
+				ErrorMsg:              msg,
+			}
+		}
+	}
+
+	return common.CancelPauseResumeResponse{
+		CancelledPauseResumed: false,
+		ErrorMsg:              fmt.Sprintf("job %s has no such transfer", req.JobID),
+	}
+}
+
+// transferMatches says whether the transfer is the one the request identifies, either by its index, or by its source or
+// destination, whether in full (as jobs show lists them) or relative to the job's source or destination
+func transferMatches(jpp *JobPartPlanHeader, transfer uint32, index uint32, req common.CancelTransferRequest) bool {
+	if req.Path == "" {
+		return index == req.Index
+	}
+
+	src, dst, _ := jpp.TransferSrcDstStrings(transfer)
+	if req.Path == src || req.Path == dst {
+		return true
+	}
+	relativePath := strings.Trim(req.Path, "/\\")
+	srcRelative, dstRelative := jpp.TransferSrcDstRelatives(transfer)
+	return relativePath == strings.Trim(srcRelative, "/\\") || relativePath == strings.Trim(dstRelative, "/\\")
+}
+
+// overrideSourceRoot points every part of a job with a local source at the source root's new path
+func overrideSourceRoot(jm IJobMgr, root string) error {
+	var err error
+	jm.(*jobMgr).jobPartMgrs.Iterate(true, func(partNum common.PartNumber, jpm IJobPartMgr) {
+		if err != nil {
+			return
+		}
+		plan := jpm.Plan()
+		if !plan.FromTo.From().IsLocal() {
+			err = fmt.Errorf("the source root can only be overridden for jobs with a local source, not for %s", plan.FromTo)
+			return
+		}
+		err = plan.setSourceRoot(root)
+	})
+	if err == nil && jm.ShouldLog(pipeline.LogInfo) {
+		jm.Log(pipeline.LogInfo, "Source root overridden to "+root)
+	}
+	return err
+}
+
 func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeResponse {
 	// Strip '?' if present as first character of the source sas / destination sas
 	if len(req.SourceSAS) > 0 && req.SourceSAS[0] == '?' {
@@ -245,9 +347,27 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 	if len(req.DestinationSAS) > 0 && req.DestinationSAS[0] == '?' {
 		req.DestinationSAS = req.DestinationSAS[1:]
 	}
+	for root, sas := range req.AlsoToSAS {
+		req.AlsoToSAS[root] = strings.TrimPrefix(sas, "?")
+	}
+	// A job cancelled with 'jobs cancel' is done with for good
+	if isJobCancelled(JobsAdmin.AppPathFolder(), req.JobID) {
+		return common.CancelPauseResumeResponse{
+			CancelledPauseResumed: false,
+			ErrorMsg:              fmt.Sprintf("cannot resume job with JobId %s, since it was cancelled with 'azcopy jobs cancel'", req.JobID),
+		}
+	}
+	// Another azcopy process may be running the job already
+	if err := claimJob(JobsAdmin.AppPathFolder(), req.JobID, false); err != nil {
+		return common.CancelPauseResumeResponse{
+			CancelledPauseResumed: false,
+			ErrorMsg:              err.Error(),
+		}
+	}
 	// Always search the plan files in Azcopy folder,
 	// and resurrect the Job with provided credentials, to ensure SAS and etc get updated.
-	if !JobsAdmin.ResurrectJob(req.JobID, req.SourceSAS, req.DestinationSAS) {
+	if !JobsAdmin.ResurrectJob(req.JobID, req.SourceSAS, req.DestinationSAS, req.AlsoToSAS) {
+		releaseJob(JobsAdmin.AppPathFolder(), req.JobID)
 		return common.CancelPauseResumeResponse{
 			CancelledPauseResumed: false,
 			ErrorMsg:              fmt.Sprintf("no job with JobId %v exists", req.JobID),
@@ -292,6 +412,7 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		var errorMsg = ""
 		switch jpm.Plan().FromTo {
 		case common.EFromTo.LocalBlob(),
+			common.EFromTo.LocalManagedDisk(),
 			common.EFromTo.LocalFile(),
 			common.EFromTo.S3Blob(),
 			common.EFromTo.GCPBlob():
@@ -301,6 +422,7 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		case common.EFromTo.BlobLocal(),
 			common.EFromTo.FileLocal(),
 			common.EFromTo.BlobTrash(),
+			common.EFromTo.BlobNone(),
 			common.EFromTo.FileTrash():
 			if len(req.SourceSAS) == 0 {
 				errorMsg = "The source-sas switch must be provided to resume the job"
@@ -320,6 +442,16 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		}
 	}
 
+	// A local source that has been remounted elsewhere is read from its new path, by this and any later resumes
+	if req.SourceRootOverride != "" {
+		if err := overrideSourceRoot(jm, req.SourceRootOverride); err != nil {
+			return common.CancelPauseResumeResponse{
+				CancelledPauseResumed: false,
+				ErrorMsg:              fmt.Sprintf("cannot resume job with JobId %s. %s", req.JobID, err.Error()),
+			}
+		}
+	}
+
 	// After creating the Job mgr, set the include / exclude list of transfer.
 	jm.SetIncludeExclude(req.IncludeTransfer, req.ExcludeTransfer)
 	jpp0 := jpm.Plan()
@@ -345,7 +477,9 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		// Get credential info from RPC request, and set in InMemoryTransitJobState.
 		jm.setInMemoryTransitJobState(
 			InMemoryTransitJobState{
-				credentialInfo: req.CredentialInfo,
+				credentialInfo:          req.CredentialInfo,
+				s2sSourceCredentialInfo: req.S2SSourceCredentialInfo,
+				posixIDMap:              req.POSIXIDMap,
 			})
 
 		jpp0.SetJobStatus(common.EJobStatus.InProgress())
@@ -353,6 +487,7 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		if jm.ShouldLog(pipeline.LogInfo) {
 			jm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v resumed", req.JobID))
 		}
+		go watchForCancellation(JobsAdmin.AppPathFolder(), req.JobID)
 
 		// Iterate through all transfer of the Job Parts and reset the transfer status
 		jm.(*jobMgr).jobPartMgrs.Iterate(true, func(partNum common.PartNumber, jpm IJobPartMgr) {
@@ -361,9 +496,10 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 			for t := uint32(0); t < jpp.NumTransfers; t++ {
 				// transferHeader represents the memory map transfer header of transfer at index position for given job and part number
 				jppt := jpp.Transfer(t)
-				// If the transfer status is less than -1, it means the transfer failed because of some reason.
-				// Transfer Status needs to reset.
-				if jppt.TransferStatus() <= common.ETransferStatus.Failed() {
+				// Transfers which failed, were cancelled, or were skipped because of what was at the destination
+				// are tried again. Those skipped for their size would only be skipped again, so they're left as they are.
+				if ts := jppt.TransferStatus(); ts.IsFailed() || ts == common.ETransferStatus.Cancelled() ||
+					(ts.IsSkipped() && ts != common.ETransferStatus.SkippedSizeLimit()) {
 					jppt.SetTransferStatus(common.ETransferStatus.Started(), true)
 					jppt.SetErrorCode(0, true)
 				}
@@ -395,10 +531,16 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 	// getJobPartMapFromJobPartInfoMap gives the map of partNo to JobPartPlanInfo Pointer for a given JobId
 	jm, found := JobsAdmin.JobMgr(jobID)
 	if !found {
+		// A job that isn't running here is shown from the summary it last saved, if there is one,
+		// rather than by reading every transfer in its plan
+		if summary, ok := loadJobSummarySnapshot(JobsAdmin.AppPathFolder(), jobID); ok {
+			return summary
+		}
+
 		// Job with JobId does not exists
 		// Search the plan files in Azcopy folder
 		// and resurrect the Job
-		if !JobsAdmin.ResurrectJob(jobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+		if !JobsAdmin.ResurrectJob(jobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING, nil) {
 			return common.ListJobSummaryResponse{
 				ErrorMsg: fmt.Sprintf("no job with JobId %v exists", jobID),
 			}
@@ -416,6 +558,7 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 		CompleteJobOrdered: false,                          // default to false; returns true if ALL job parts have been ordered
 		FailedTransfers:    []common.TransferDetail{},
 	}
+	defer func() { jm.(*jobMgr).snapshotSummary(js) }()
 
 	// To avoid race condition: get overall status BEFORE we get counts of completed files)
 	// (if we get it afterwards, we can get a cases where the counts haven't reached 100% done, but by the time we
@@ -427,6 +570,12 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 	}
 	part0PlanStatus := part0.Plan().JobStatus()
 
+	// the spread of the transfers' durations is only worth working out once, when the job is done
+	var durations *transferDurations
+	if part0PlanStatus.IsJobDone() {
+		durations = &transferDurations{}
+	}
+
 	// Now iterate and count things up
 	jm.(*jobMgr).jobPartMgrs.Iterate(true, func(partNum common.PartNumber, jpm IJobPartMgr) {
 		jpp := jpm.Plan()
@@ -446,46 +595,48 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 			}
 
 			// check for all completed transfer to calculate the progress percentage at the end
-			switch jppt.TransferStatus() {
-			case common.ETransferStatus.NotStarted(),
-				common.ETransferStatus.Started():
+			switch ts := jppt.TransferStatus(); {
+			case ts == common.ETransferStatus.NotStarted(),
+				ts == common.ETransferStatus.Started():
 				js.TotalBytesExpected += uint64(jppt.SourceSize)
-			case common.ETransferStatus.Success():
+			case ts == common.ETransferStatus.Success():
 				js.TransfersCompleted++
+				if d, ok := jppt.Duration(); ok && durations != nil && jppt.EntityType == common.EEntityType.File() {
+					durations.add(d, func() common.TransferDuration {
+						src, dst, _ := jpp.TransferSrcDstStrings(t)
+						return common.TransferDuration{Src: src, Dst: dst, Bytes: jppt.SourceSize}
+					})
+				}
 				js.TotalBytesTransferred += uint64(jppt.SourceSize)
 				js.TotalBytesExpected += uint64(jppt.SourceSize)
-			case common.ETransferStatus.Failed(),
-				common.ETransferStatus.TierAvailabilityCheckFailure(),
-				common.ETransferStatus.BlobTierFailure():
+			case ts.IsFailed():
 				js.TransfersFailed++
-				// getting the source and destination for failed transfer at position - index
-				src, dst, isFolder := jpp.TransferSrcDstStrings(t)
-				// appending to list of failed transfer
-				js.FailedTransfers = append(js.FailedTransfers,
-					common.TransferDetail{
-						Src:                src,
-						Dst:                dst,
-						IsFolderProperties: isFolder,
-						TransferStatus:     common.ETransferStatus.Failed(),
-						ErrorCode:          jppt.ErrorCode()}) // TODO: Optimize
-			case common.ETransferStatus.SkippedEntityAlreadyExists(),
-				common.ETransferStatus.SkippedBlobHasSnapshots():
+				// appending to list of failed transfer, until it's as long as a summary's list can be
+				if len(js.FailedTransfers) < maxSummaryTransferDetails {
+					detail, _ := failedOrSkippedTransferDetail(jpp, t, ts)
+					js.FailedTransfers = append(js.FailedTransfers, detail)
+				}
+			case ts.IsSkipped():
 				js.TransfersSkipped++
-				// getting the source and destination for skipped transfer at position - index
-				src, dst, isFolder := jpp.TransferSrcDstStrings(t)
-				js.SkippedTransfers = append(js.SkippedTransfers,
-					common.TransferDetail{
-						Src:                src,
-						Dst:                dst,
-						IsFolderProperties: isFolder,
-						TransferStatus:     jppt.TransferStatus(),
-					})
+				if ts == common.ETransferStatus.SkippedSizeLimit() {
+					js.TransfersSkippedByFilter++
+				}
+				js.BytesSkipped += uint64(jppt.SourceSize)
+				if len(js.SkippedTransfers) < maxSummaryTransferDetails {
+					detail, _ := failedOrSkippedTransferDetail(jpp, t, ts)
+					js.SkippedTransfers = append(js.SkippedTransfers, detail)
+				}
 			}
 		}
 	})
 
+	// the rest are listed in the job's transfer details file, which is written with the job's summary
+	if uint32(len(js.FailedTransfers)) < js.TransfersFailed || uint32(len(js.SkippedTransfers)) < js.TransfersSkipped {
+		js.TransferDetailsFile = jobTransferDetailsPath(JobsAdmin.AppPathFolder(), jobID)
+	}
+
 	// Add on byte count from files in flight, to get a more accurate running total
-	js.TotalBytesTransferred += JobsAdmin.SuccessfulBytesInActiveFiles()
+	js.TotalBytesTransferred += jm.SuccessfulBytesInActiveFiles()
 	if js.TotalBytesExpected == 0 {
 		// if no bytes expected, and we should avoid dividing by 0 (which results in NaN)
 		js.PercentComplete = 100
@@ -493,13 +644,25 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 		js.PercentComplete = 100 * float32(js.TotalBytesTransferred) / float32(js.TotalBytesExpected)
 	}
 
+	js.EstimatedSecondsRemaining = -1
+	transfersDone := js.TransfersCompleted + js.TransfersFailed + js.TransfersSkipped
+	if eta, ok := jm.EstimateTimeRemaining(js.TotalBytesTransferred, js.TotalBytesExpected, transfersDone, js.TotalTransfers); ok {
+		js.EstimatedSecondsRemaining = int64(eta.Seconds())
+	}
+
 	// This is added to let FE to continue fetching the Job Progress Summary
 	// in case of resume. In case of resume, the Job is already completely
 	// ordered so the progress summary should be fetched until all job parts
 	// are iterated and have been scheduled
 	js.CompleteJobOrdered = js.CompleteJobOrdered || jm.AllTransfersScheduled()
 
-	js.BytesOverWire = uint64(JobsAdmin.BytesOverWire())
+	js.BytesOverWire = uint64(jm.BytesOverWire())
+	js.Overwrites, js.VersionedOverwrites = jm.OverwriteCounts()
+	js.TransfersWithPropertiesNotPreserved = jm.PropertiesNotPreservedCount()
+	js.EmptyPagesSkipped = jm.EmptyPagesSkippedCount()
+	if durations != nil {
+		durations.fill(&js)
+	}
 
 	// Get the number of active go routines performing the transfer or executing the chunk Func
 	// TODO: added for debugging purpose. remove later (is covered by GetPerfInfo now anyway)
@@ -513,6 +676,15 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 		js.AverageE2EMilliseconds = pipeStats.AverageE2EMilliseconds()
 		js.NetworkErrorPercentage = pipeStats.NetworkErrorPercentage()
 		js.ServerBusyPercentage = pipeStats.TotalServerBusyPercentage()
+		js.BytesRetried = uint64(pipeStats.GetRetriedBytes())
+		retries, serverBusy := pipeStats.GetRetryCounts()
+		js.RetryCount, js.ServerBusyCount = uint64(retries), uint64(serverBusy)
+		newConns, reusedConns, tlsHandshakes, resumedTLSSessions := pipeStats.GetConnectionCounts()
+		js.NewConnectionCount, js.ReusedConnectionCount = uint64(newConns), uint64(reusedConns)
+		js.TLSHandshakeCount, js.ResumedTLSSessionCount = uint64(tlsHandshakes), uint64(resumedTLSSessions)
+		js.MedianE2EMilliseconds = pipeStats.E2EMillisecondsPercentile(0.5)
+		js.P95E2EMilliseconds = pipeStats.E2EMillisecondsPercentile(0.95)
+		js.P99E2EMilliseconds = pipeStats.E2EMillisecondsPercentile(0.99)
 	}
 
 	// If the status is cancelled, then no need to check for completerJobOrdered
@@ -544,7 +716,7 @@ func ListJobTransfers(r common.ListJobTransfersRequest) common.ListJobTransfersR
 		// Job with JobId does not exists
 		// Search the plan files in Azcopy folder
 		// and resurrect the Job
-		if !JobsAdmin.ResurrectJob(r.JobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+		if !JobsAdmin.ResurrectJob(r.JobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING, nil) {
 			return common.ListJobTransfersResponse{
 				ErrorMsg: fmt.Sprintf("no job with JobId %v exists", r.JobID),
 			}
@@ -573,14 +745,11 @@ func ListJobTransfers(r common.ListJobTransfersRequest) common.ListJobTransfersR
 			// If the expected status is not to list all transfer and
 			// if the transfer status is not equal to the given status
 			// skip the transfer.
-			// If the given status is failed and the current transfer status is <= -1,
-			// it means transfer failed and could have failed because of some other reason.
-			// In this case we don't skip the transfer.
-			// For Example: In case with-status is Failed, transfers with status "BlobAlreadyExistsFailure"
-			// will also be included.
+			// If the given status is failed, transfers which failed for more specific reasons are included too,
+			// e.g. TimedOut, but skipped and cancelled ones are not.
 			if r.OfStatus != common.ETransferStatus.All() &&
 				((transferEntry.TransferStatus() != r.OfStatus) &&
-					!(r.OfStatus == common.ETransferStatus.Failed() && transferEntry.TransferStatus() <= common.ETransferStatus.Failed())) {
+					!(r.OfStatus == common.ETransferStatus.Failed() && transferEntry.TransferStatus().IsFailed())) {
 				continue
 			}
 			// getting source and destination of a transfer at index index for given jobId and part number.
@@ -640,13 +809,27 @@ func ListJobs(givenStatus common.JobStatus) common.ListJobsResponse {
 	return listJobResponse
 }
 
+// ReleaseJob unmaps the plan files, and closes the log, of a job that was resurrected only so that it could be read,
+// as ListJobs does, so that reading many jobs doesn't keep them all in memory
+func ReleaseJob(jobID common.JobID) {
+	jm, found := JobsAdmin.JobMgr(jobID)
+	if !found {
+		return
+	}
+	jm.(*jobMgr).jobPartMgrs.Iterate(false, func(k common.PartNumber, v IJobPartMgr) {
+		v.Close()
+	})
+	jm.CloseLog()
+	JobsAdmin.(*jobsAdmin).DeleteJob(jobID)
+}
+
 // GetJobFromTo api returns the job FromTo info.
 func GetJobFromTo(r common.GetJobFromToRequest) common.GetJobFromToResponse {
 	jm, found := JobsAdmin.JobMgr(r.JobID)
 	if !found {
 		// Job with JobId does not exists.
 		// Search the plan files in Azcopy folder and resurrect the Job.
-		if !JobsAdmin.ResurrectJob(r.JobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+		if !JobsAdmin.ResurrectJob(r.JobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING, nil) {
 			return common.GetJobFromToResponse{
 				ErrorMsg: fmt.Sprintf("no job with JobID %v exists", r.JobID),
 			}
@@ -670,10 +853,12 @@ func GetJobFromTo(r common.GetJobFromToRequest) common.GetJobFromToResponse {
 		}
 	}
 
+	plan := jp0.Plan()
 	return common.GetJobFromToResponse{
-		ErrorMsg:    "",
-		FromTo:      jp0.Plan().FromTo,
-		Source:      source,
-		Destination: destination,
+		ErrorMsg:          "",
+		FromTo:            plan.FromTo,
+		Source:            source,
+		Destination:       destination,
+		S2SSourceTenantID: string(plan.S2SSourceTenantID[:plan.S2SSourceTenantIDLength]),
 	}
 }
@@ -0,0 +1,138 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// JobCancelledSuffix ends the name of the file, next to a job's plan files, that records that the job was cancelled with
+// 'jobs cancel'. A process running the job watches for it, and a job that has it can't be resumed
+const JobCancelledSuffix = ".cancelled"
+
+// how often a process running a job looks for its cancelled file
+const jobCancelledPollInterval = time.Second
+
+func jobCancelledPath(planDir string, jobID common.JobID) string {
+	return filepath.Join(planDir, jobID.String()+JobCancelledSuffix)
+}
+
+func isJobCancelled(planDir string, jobID common.JobID) bool {
+	_, err := os.Stat(jobCancelledPath(planDir, jobID))
+	return err == nil
+}
+
+func markJobCancelled(planDir string, jobID common.JobID) error {
+	return ioutil.WriteFile(jobCancelledPath(planDir, jobID), []byte(time.Now().Format(time.RFC3339)), common.DEFAULT_FILE_PERM)
+}
+
+// CancelJobAnywhere cancels the job for good, wherever it is. If it's running in this process, it's cancelled just as when
+// the user presses Ctrl-C. If it's running in another process, that process notices the cancelled file and does the same.
+// If it isn't running at all, it's marked Cancelled in its plan. In every case, the job can't be resumed afterwards
+func CancelJobAnywhere(jobID common.JobID) common.CancelPauseResumeResponse {
+	planDir := JobsAdmin.AppPathFolder()
+	failed := func(format string, a ...interface{}) common.CancelPauseResumeResponse {
+		return common.CancelPauseResumeResponse{CancelledPauseResumed: false, ErrorMsg: fmt.Sprintf(format, a...)}
+	}
+
+	// make sure no other azcopy process starts running the job while we cancel it
+	unlock, err := LockPlanFolder(planDir)
+	if err != nil {
+		return failed("couldn't lock the plan folder %s: %s", planDir, err)
+	}
+	defer unlock()
+
+	if planFiles, _ := filepath.Glob(filepath.Join(planDir, jobID.String()+"--*")); len(planFiles) == 0 {
+		return failed("no job with JobId %s exists", jobID)
+	}
+	owner, running := GetJobOwner(planDir, jobID)
+
+	if running && owner.isThisProcess() {
+		if err = markJobCancelled(planDir, jobID); err != nil {
+			return failed("couldn't mark job %s cancelled: %s", jobID, err)
+		}
+		return CancelPauseJobOrder(jobID, common.EJobStatus.Cancelling())
+	}
+
+	if running {
+		if err = markJobCancelled(planDir, jobID); err != nil {
+			return failed("couldn't mark job %s cancelled: %s", jobID, err)
+		}
+		return common.CancelPauseResumeResponse{
+			CancelledPauseResumed: true,
+			ErrorMsg:              fmt.Sprintf("Job %s is being cancelled by %s, which will stop it within a few seconds", jobID, owner),
+		}
+	}
+
+	// no one is running the job, so its plan is ours to change
+	if !JobsAdmin.ResurrectJob(jobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING, nil) {
+		return failed("no job with JobId %s exists", jobID)
+	}
+	jm, _ := JobsAdmin.JobMgr(jobID)
+	jpm, found := jm.JobPartMgr(0)
+	if !found {
+		return failed("job with JobId %s has a missing 0th part", jobID)
+	}
+	jpp0 := jpm.Plan()
+	if jpp0.JobStatus() == common.EJobStatus.Completed() {
+		return failed("Can't cancel JobID=%v because it has already completed", jobID)
+	}
+	if err = markJobCancelled(planDir, jobID); err != nil {
+		return failed("couldn't mark job %s cancelled: %s", jobID, err)
+	}
+	jpp0.SetJobStatus(common.EJobStatus.Cancelled())
+	if err = setJobSummarySnapshotStatus(planDir, jobID, common.EJobStatus.Cancelled()); err != nil {
+		return failed("job %s was cancelled, but its saved summary couldn't be updated, so jobs show may not say so: %s", jobID, err)
+	}
+	return common.CancelPauseResumeResponse{
+		CancelledPauseResumed: true,
+		ErrorMsg:              fmt.Sprintf("JobID=%v canceled. It isn't running, and can no longer be resumed", jobID),
+	}
+}
+
+// watchForCancellation cancels the job once another process has marked it cancelled with CancelJobAnywhere.
+// It's started when this process claims the job, and stops once the job is released
+func watchForCancellation(planDir string, jobID common.JobID) {
+	ticker := time.NewTicker(jobCancelledPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if owner, running := GetJobOwner(planDir, jobID); !running || !owner.isThisProcess() {
+			return
+		}
+		if isJobCancelled(planDir, jobID) {
+			if jm, found := JobsAdmin.JobMgr(jobID); found {
+				jm.Log(pipeline.LogWarning, "The job was cancelled from another azcopy process, with 'jobs cancel'")
+			}
+			common.GetLifecycleMgr().Info(fmt.Sprintf("Job %s was cancelled from another azcopy process", jobID))
+			CancelPauseJobOrder(jobID, common.EJobStatus.Cancelling())
+			return
+		}
+	}
+}
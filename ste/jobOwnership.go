@@ -0,0 +1,131 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// JobOwnerSuffix ends the name of the file, next to a job's plan files, that says which azcopy process is running the job
+const JobOwnerSuffix = ".owner"
+
+// the file in the plan folder that azcopy processes lock while they claim jobs, or remove their files
+const planFolderLockName = ".azcopy.lock"
+
+// JobOwner is what a job's owner file holds
+type JobOwner struct {
+	PID     int
+	Host    string
+	Started time.Time
+}
+
+func (o JobOwner) String() string {
+	return fmt.Sprintf("azcopy process %d on %s, running it since %s", o.PID, o.Host, o.Started.Format(time.RFC3339))
+}
+
+// isLive says whether the owner is still running. An owner on another machine, which can happen when the plan folder
+// is on a network share, is assumed to be, since we've no way to check
+func (o JobOwner) isLive() bool {
+	if o.Host != thisHost() {
+		return true
+	}
+	return o.PID == os.Getpid() || common.IsProcessRunning(o.PID)
+}
+
+func (o JobOwner) isThisProcess() bool {
+	return o.PID == os.Getpid() && o.Host == thisHost()
+}
+
+func thisHost() string {
+	host, _ := os.Hostname()
+	return host
+}
+
+func jobOwnerPath(planDir string, jobID common.JobID) string {
+	return filepath.Join(planDir, jobID.String()+JobOwnerSuffix)
+}
+
+// LockPlanFolder stops other azcopy processes from claiming jobs, or removing job files, until the returned unlock is called
+func LockPlanFolder(planDir string) (unlock func(), err error) {
+	return common.LockFile(filepath.Join(planDir, planFolderLockName))
+}
+
+// GetJobOwner returns the process running the job, unless no live process is
+func GetJobOwner(planDir string, jobID common.JobID) (owner JobOwner, running bool) {
+	b, err := ioutil.ReadFile(jobOwnerPath(planDir, jobID))
+	if err != nil || json.Unmarshal(b, &owner) != nil {
+		return JobOwner{}, false
+	}
+	return owner, owner.isLive()
+}
+
+// claimJob records this process as the one running the job, so that no other azcopy process sharing the plan folder
+// runs or removes it at the same time. A new job must not have any plan files yet, since that means its ID is already taken
+func claimJob(planDir string, jobID common.JobID, isNewJob bool) error {
+	unlock, err := LockPlanFolder(planDir)
+	if err != nil {
+		return fmt.Errorf("couldn't lock the plan folder %s: %w", planDir, err)
+	}
+	defer unlock()
+
+	if owner, running := GetJobOwner(planDir, jobID); running {
+		if owner.isThisProcess() {
+			return nil
+		}
+		return fmt.Errorf("job %s is already in use by %s. If that process is no longer running it, remove %s and try again",
+			jobID, owner, jobOwnerPath(planDir, jobID))
+	}
+	if isNewJob {
+		if planFiles, _ := filepath.Glob(filepath.Join(planDir, jobID.String()+"--*")); len(planFiles) > 0 {
+			return fmt.Errorf("job ID %s is already used by a job in the plan folder %s", jobID, planDir)
+		}
+	}
+
+	b, err := json.Marshal(JobOwner{PID: os.Getpid(), Host: thisHost(), Started: time.Now()})
+	if err != nil {
+		return err
+	}
+	path := jobOwnerPath(planDir, jobID)
+	if err = ioutil.WriteFile(path+".tmp", b, common.DEFAULT_FILE_PERM); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// releaseJob removes the job's owner file, if this process owns the job, once the job is done with
+func releaseJob(planDir string, jobID common.JobID) {
+	unlock, err := LockPlanFolder(planDir)
+	if err != nil {
+		return // the file is left behind, but the next process to claim the job will see that we're gone
+	}
+	defer unlock()
+
+	if owner, running := GetJobOwner(planDir, jobID); running && owner.isThisProcess() {
+		_ = os.Remove(jobOwnerPath(planDir, jobID))
+	}
+}
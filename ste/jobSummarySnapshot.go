@@ -0,0 +1,122 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// JobSummarySnapshotSuffix ends the name of the file, next to a job's plan files, that holds the job's last saved summary
+const JobSummarySnapshotSuffix = ".summary"
+
+// IsJobSidecarFileName says whether the file, in the plan folder, is one that's kept alongside a job's plan files
+func IsJobSidecarFileName(name string) bool {
+	return strings.HasSuffix(name, JobSummarySnapshotSuffix) || strings.HasSuffix(name, JobTransferDetailsSuffix) ||
+		strings.HasSuffix(name, JobOwnerSuffix) || strings.HasSuffix(name, JobCancelledSuffix)
+}
+
+// how often, at most, a running job's summary is saved. It's also saved once the job is done
+const jobSummarySnapshotInterval = 30 * time.Second
+
+func jobSummarySnapshotPath(planDir string, jobID common.JobID) string {
+	return filepath.Join(planDir, jobID.String()+JobSummarySnapshotSuffix)
+}
+
+// saveJobSummarySnapshot writes the summary next to the job's plan files. It replaces the previous one in a single
+// rename, so that a crash part way through leaves that one intact
+func saveJobSummarySnapshot(planDir string, summary common.ListJobSummaryResponse) error {
+	b, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	path := jobSummarySnapshotPath(planDir, summary.JobID)
+	if err = ioutil.WriteFile(path+".tmp", b, common.DEFAULT_FILE_PERM); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// loadJobSummarySnapshot reads the job's last saved summary, if it has one. The job isn't running in this process,
+// so what was in flight when the summary was saved, such as the open connections and time remaining, is left out
+func loadJobSummarySnapshot(planDir string, jobID common.JobID) (summary common.ListJobSummaryResponse, ok bool) {
+	b, err := ioutil.ReadFile(jobSummarySnapshotPath(planDir, jobID))
+	if err != nil || json.Unmarshal(b, &summary) != nil || summary.JobID != jobID {
+		return common.ListJobSummaryResponse{}, false
+	}
+
+	summary.ActiveConnections = 0
+	summary.EstimatedSecondsRemaining = -1
+	summary.PerfStrings = nil
+	summary.PerfConstraint = common.EPerfConstraint.Unknown()
+	summary.BytesOverWire = 0
+	summary.BytesRetried = 0
+	return summary, true
+}
+
+// setJobSummarySnapshotStatus changes the status in the job's saved summary, if it has one. It's for a job that isn't
+// running, whose plan has just been changed, e.g. by cancelling it, since the summary is shown in preference to the plan.
+// If the summary can't be saved again, it's removed, so that the job is shown from its plan instead
+func setJobSummarySnapshotStatus(planDir string, jobID common.JobID, status common.JobStatus) error {
+	summary, ok := loadJobSummarySnapshot(planDir, jobID)
+	if !ok {
+		return nil
+	}
+	summary.JobStatus = status
+	if err := saveJobSummarySnapshot(planDir, summary); err != nil {
+		if err = os.Remove(jobSummarySnapshotPath(planDir, jobID)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotSummary saves the job's summary, if it's done or the last one was saved long enough ago, so that the job
+// can be shown later, even after a crash, without reading every transfer in its plan
+func (jm *jobMgr) snapshotSummary(summary common.ListJobSummaryResponse) {
+	now := time.Now()
+	last := atomic.LoadInt64(&jm.atomicSummarySnapshotTime)
+	if !summary.JobStatus.IsJobDone() && now.Sub(time.Unix(0, last)) < jobSummarySnapshotInterval {
+		return
+	}
+	if !atomic.CompareAndSwapInt64(&jm.atomicSummarySnapshotTime, last, now.UnixNano()) {
+		return // another call is saving it
+	}
+
+	// the details first, so that a saved summary never names a details file that hasn't been written
+	if summary.TransferDetailsFile != "" {
+		if err := jm.saveJobTransferDetails(summary.TransferDetailsFile); err != nil {
+			jm.Log(pipeline.LogWarning, "Couldn't save the details of the job's failed and skipped transfers: "+err.Error())
+		}
+	}
+	if err := saveJobSummarySnapshot(JobsAdmin.AppPathFolder(), summary); err != nil {
+		jm.Log(pipeline.LogWarning, "Couldn't save the job's summary: "+err.Error())
+	}
+}
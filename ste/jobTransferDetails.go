@@ -0,0 +1,113 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// JobTransferDetailsSuffix ends the name of the file, next to a job's plan files, that has the details of all the
+// job's failed and skipped transfers, when there are too many of them to list in its summary
+const JobTransferDetailsSuffix = ".details"
+
+// how many failed, and how many skipped, transfers a job's summary lists. The rest are only counted, and their details
+// left to the job's transfer details file, so that a job with millions of failures doesn't hold them all in memory
+const maxSummaryTransferDetails = 1000
+
+func jobTransferDetailsPath(planDir string, jobID common.JobID) string {
+	return filepath.Join(planDir, jobID.String()+JobTransferDetailsSuffix)
+}
+
+// failedOrSkippedTransferDetail describes the transfer, as a job's summary lists it, if it failed or was skipped
+func failedOrSkippedTransferDetail(jpp *JobPartPlanHeader, t uint32, ts common.TransferStatus) (common.TransferDetail, bool) {
+	if !ts.IsFailed() && !ts.IsSkipped() {
+		return common.TransferDetail{}, false
+	}
+
+	// getting the source and destination for the transfer at position - index
+	src, dst, isFolder := jpp.TransferSrcDstStrings(t)
+	detail := common.TransferDetail{Src: src, Dst: dst, IsFolderProperties: isFolder, TransferStatus: ts}
+	if ts.IsFailed() {
+		detail.TransferStatus = common.ETransferStatus.Failed()
+		detail.ErrorCode = jpp.Transfer(t).ErrorCode()
+	}
+	return detail, true
+}
+
+// saveJobTransferDetails writes the details of all the job's failed and skipped transfers to the given file, one at a
+// time, rather than gathering them first. Like the job's summary, it replaces the previous file in a single rename
+func (jm *jobMgr) saveJobTransferDetails(path string) error {
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	jm.jobPartMgrs.Iterate(true, func(_ common.PartNumber, jpm IJobPartMgr) {
+		jpp := jpm.Plan()
+		for t := uint32(0); t < jpp.NumTransfers && err == nil; t++ {
+			if detail, ok := failedOrSkippedTransferDetail(jpp, t, jpp.Transfer(t).TransferStatus()); ok {
+				err = encoder.Encode(detail)
+			}
+		}
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path + ".tmp")
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// ReadJobTransferDetails reads a job's transfer details file, as named by its summary, into the transfers that
+// failed and those that were skipped
+func ReadJobTransferDetails(path string) (failed, skipped []common.TransferDetail, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for decoder.More() {
+		var detail common.TransferDetail
+		if err = decoder.Decode(&detail); err != nil {
+			return nil, nil, err
+		}
+		if detail.TransferStatus.IsFailed() {
+			failed = append(failed, detail)
+		} else {
+			skipped = append(skipped, detail)
+		}
+	}
+	return failed, skipped, nil
+}
@@ -0,0 +1,103 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// planColdAfter is how long a plan must go without being asked for, with none of its transfers running, before it
+// can be evicted. It's long enough for anything that's reading the plan, such as a progress summary, to have finished
+const planColdAfter = 5 * time.Second
+
+// mappedPlanTracker keeps track of which plans are mapped, and once there are more than its limit, evicts the coldest
+// of them: those that have gone longest without being asked for. Plans that aren't cold are left alone, so while
+// many parts are busy there can be more plans mapped than the limit
+type mappedPlanTracker struct {
+	limitOnce sync.Once
+	limit     int // 0 for no limit
+
+	lock   sync.Mutex
+	mapped map[*JobPartPlanMMF]struct{}
+}
+
+var mappedPlans = newMappedPlanTracker()
+
+func newMappedPlanTracker() *mappedPlanTracker {
+	return &mappedPlanTracker{mapped: make(map[*JobPartPlanMMF]struct{})}
+}
+
+func (t *mappedPlanTracker) getLimit() int {
+	t.limitOnce.Do(func() {
+		envVar := common.EEnvironmentVariable.MaxMappedPlanFiles()
+		limit, err := strconv.Atoi(common.GetLifecycleMgr().GetEnvironmentVariable(envVar))
+		if err != nil || limit < 0 {
+			limit, _ = strconv.Atoi(envVar.DefaultValue)
+		}
+		t.limit = limit
+	})
+	return t.limit
+}
+
+// add records that the plan has been mapped, and says whether that takes the number mapped over the limit
+func (t *mappedPlanTracker) add(mmf *JobPartPlanMMF) bool {
+	limit := t.getLimit()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.mapped[mmf] = struct{}{}
+	return limit > 0 && len(t.mapped) > limit
+}
+
+func (t *mappedPlanTracker) remove(mmf *JobPartPlanMMF) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.mapped, mmf)
+}
+
+// evictColdest evicts cold plans, coldest first, until the number mapped is back within the limit, or there are no more cold ones
+func (t *mappedPlanTracker) evictColdest(now time.Time) {
+	t.lock.Lock()
+	excess := len(t.mapped) - t.getLimit()
+	candidates := make([]*JobPartPlanMMF, 0, len(t.mapped))
+	for mmf := range t.mapped {
+		candidates = append(candidates, mmf)
+	}
+	t.lock.Unlock() // since evicting a plan locks it, and plans call add and remove while they're locked
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return atomic.LoadInt64(&candidates[i].atomicLastUsed) < atomic.LoadInt64(&candidates[j].atomicLastUsed)
+	})
+	for _, mmf := range candidates {
+		if excess <= 0 {
+			return
+		}
+		if mmf.evictIfCold(now) {
+			excess--
+		}
+	}
+}
@@ -45,7 +45,9 @@ type PartNumber = common.PartNumber
 // i.e. different jobs could have different OAuth tokens requested from FE, and these jobs can run at same time in STE.
 // This can be optimized if FE would no more be another module vs STE module.
 type InMemoryTransitJobState struct {
-	credentialInfo common.CredentialInfo
+	credentialInfo          common.CredentialInfo
+	s2sSourceCredentialInfo common.CredentialInfo
+	posixIDMap              common.POSIXIDMap
 }
 
 type IJobMgr interface {
@@ -79,6 +81,18 @@ type IJobMgr interface {
 	ChunkStatusLogger() common.ChunkStatusLogger
 	HttpClient() *http.Client
 	PipelineNetworkStats() *pipelineNetworkStats
+	EstimateTimeRemaining(bytesDone, bytesExpected uint64, transfersDone, transfersTotal uint32) (time.Duration, bool)
+	// returns the number of bytes this job has sent or received over the wire
+	BytesOverWire() int64
+	AddSuccessfulBytesInActiveFiles(n int64)
+	// returns number of bytes successfully transferred in this job's transfers that are currently in progress
+	SuccessfulBytesInActiveFiles() uint64
+	RecordOverwrite(keptVersion bool)
+	OverwriteCounts() (overwrites, versioned uint32)
+	RecordPropertiesNotPreserved()
+	PropertiesNotPreservedCount() uint32
+	RecordEmptyPagesSkipped(count uint64)
+	EmptyPagesSkippedCount() uint64
 	getOverwritePrompter() *overwritePrompter
 	common.ILoggerCloser
 }
@@ -99,6 +113,8 @@ func newJobMgr(concurrency ConcurrencySettings, jobID common.JobID, appCtx conte
 		exclusiveDestinationMapHolder: &atomic.Value{},
 		initMu:                        &sync.Mutex{},
 		jobPartProgress:               jobPartProgressCh,
+		eta:                           newEtaEstimator(etaWindowSize),
+		pacer:                         newJobTrafficCounter(JobsAdmin.(*jobsAdmin).pacer),
 		/*Other fields remain zero-value until this job is scheduled */}
 	jm.reset(appCtx, commandString)
 	jm.logJobsAdminMessages()
@@ -151,6 +167,19 @@ func (jm *jobMgr) logConcurrencyParameters() {
 		jm.concurrency.TransferInitiationPoolSize.Value,
 		jm.concurrency.TransferInitiationPoolSize.GetDescription()))
 
+	jm.logger.Log(level, fmt.Sprintf("Max concurrent small file routines: %d, for uploads smaller than %d bytes (%s; %s)",
+		jm.concurrency.SmallFilePoolSize.Value,
+		jm.concurrency.SmallFileThreshold.Value,
+		jm.concurrency.SmallFilePoolSize.GetDescription(),
+		jm.concurrency.SmallFileThreshold.GetDescription()))
+
+	jm.logger.Log(level, fmt.Sprintf("Scheduling across job parts: %s (set %s environment variable to FIFO or RoundRobin to override)",
+		jm.concurrency.SchedulingFairness,
+		common.EEnvironmentVariable.SchedulingFairness().Name))
+
+	jm.logger.Log(level, fmt.Sprintf("Boost chunks of nearly-complete transfers: %t (set with --boost-nearly-complete)",
+		jm.concurrency.BoostNearlyComplete))
+
 	jm.logger.Log(level, fmt.Sprintf("Max enumeration routines: %d (%s)",
 		jm.concurrency.EnumerationPoolSize.Value,
 		jm.concurrency.EnumerationPoolSize.GetDescription()))
@@ -168,6 +197,7 @@ type jobMgrInitState struct {
 	securityInfoPersistenceManager *securityInfoPersistenceManager
 	folderCreationTracker          common.FolderCreationTracker
 	folderDeletionManager          common.FolderDeletionManager
+	dirAccessControlTracker        *dirAccessControlTracker
 }
 
 // jobMgr represents the runtime information for a Job
@@ -177,9 +207,19 @@ type jobMgr struct {
 	// refer to: https://golang.org/pkg/sync/atomic/#pkg-note-BUG
 	atomicNumberOfBytesCovered uint64
 	atomicTotalBytesToXfer     uint64
+	// pages that the job's successful page blob uploads left out because they were empty
+	atomicEmptyPagesSkipped uint64
 	// atomicCurrentConcurrentConnections defines the number of active goroutines performing the transfer / executing the chunk func
 	// TODO: added for debugging purpose. remove later
 	atomicCurrentConcurrentConnections int64
+	atomicSuccessfulBytesInActiveFiles int64
+	// when the job's summary was last saved, in Unix nanoseconds
+	atomicSummarySnapshotTime int64
+	// destinations known to have existed that the job's successful transfers replaced, and how many of those kept a previous version
+	atomicOverwrites          uint32
+	atomicVersionedOverwrites uint32
+	// successful transfers that left some property of their source behind
+	atomicPropertiesNotPreserved uint32
 	// atomicAllTransfersScheduled defines whether all job parts have been iterated and resumed or not
 	atomicAllTransfersScheduled     int32
 	atomicFinalPartOrderedIndicator int32
@@ -220,6 +260,12 @@ type jobMgr struct {
 	initState *jobMgrInitState
 
 	jobPartProgress chan jobPartProgressInfo
+
+	// recent progress of the job, from which we predict how long the rest of it will take
+	eta *etaEstimator
+
+	// the pacer the job's transfers use, which counts the job's own traffic
+	pacer *jobTrafficCounter
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -231,6 +277,38 @@ func (jm *jobMgr) Progress() (uint64, uint64) {
 
 //func (jm *jobMgr) Throughput() XferThroughput { return jm.throughput }
 
+func (jm *jobMgr) BytesOverWire() int64 {
+	return jm.pacer.GetTotalTraffic()
+}
+
+func (jm *jobMgr) AddSuccessfulBytesInActiveFiles(n int64) {
+	atomic.AddInt64(&jm.atomicSuccessfulBytesInActiveFiles, n)
+}
+
+func (jm *jobMgr) SuccessfulBytesInActiveFiles() uint64 {
+	n := atomic.LoadInt64(&jm.atomicSuccessfulBytesInActiveFiles)
+	if n < 0 {
+		n = 0 // should never happen, but would result in nasty over/underflow if it did
+	}
+	return uint64(n)
+}
+
+// EstimateTimeRemaining records the job's current progress, and predicts from its recent progress how long the rest will take.
+// It returns false while there is too little recent progress to go on
+func (jm *jobMgr) EstimateTimeRemaining(bytesDone, bytesExpected uint64, transfersDone, transfersTotal uint32) (time.Duration, bool) {
+	jm.eta.record(time.Now(), bytesDone, transfersDone)
+
+	var remainingBytes uint64
+	if bytesExpected > bytesDone {
+		remainingBytes = bytesExpected - bytesDone
+	}
+	var remainingTransfers uint32
+	if transfersTotal > transfersDone {
+		remainingTransfers = transfersTotal - transfersDone
+	}
+	return jm.eta.estimate(remainingBytes, remainingTransfers)
+}
+
 // JobID returns the JobID that this jobMgr managers
 func (jm *jobMgr) JobID() common.JobID { return jm.jobID }
 
@@ -337,7 +415,7 @@ func (jm *jobMgr) TryGetPerformanceAdvice(bytesInJob uint64, filesInJob uint32,
 func (jm *jobMgr) AddJobPart(partNum PartNumber, planFile JobPartPlanFileName, existingPlanMMF *JobPartPlanMMF, sourceSAS string,
 	destinationSAS string, scheduleTransfers bool) IJobPartMgr {
 	jpm := &jobPartMgr{jobMgr: jm, filename: planFile, sourceSAS: sourceSAS,
-		destinationSAS: destinationSAS, pacer: JobsAdmin.(*jobsAdmin).pacer,
+		destinationSAS: destinationSAS, pacer: jm.pacer,
 		slicePool:        JobsAdmin.(*jobsAdmin).slicePool,
 		cacheLimiter:     JobsAdmin.(*jobsAdmin).cacheLimiter,
 		fileCountLimiter: JobsAdmin.(*jobsAdmin).fileCountLimiter}
@@ -361,6 +439,7 @@ func (jm *jobMgr) AddJobPart(partNum PartNumber, planFile JobPartPlanFileName, e
 			securityInfoPersistenceManager: newSecurityInfoPersistenceManager(jm.ctx),
 			folderCreationTracker:          common.NewFolderCreationTracker(jpm.Plan().Fpo),
 			folderDeletionManager:          common.NewFolderDeletionManager(jm.ctx, jpm.Plan().Fpo, logger),
+			dirAccessControlTracker:        newDirAccessControlTracker(),
 		}
 	}
 	jpm.jobMgrInitState = jm.initState // so jpm can use it as much as desired without locking (since the only mutation is the init in jobManager. As far as jobPartManager is concerned, the init state is read-only
@@ -405,7 +484,7 @@ func (jm *jobMgr) setDirection(fromTo common.FromTo) {
 	if fromTo.IsUpload() {
 		jm.atomicTransferDirection.AtomicStore(common.ETransferDirection.Upload())
 	}
-	if fromTo.IsDownload() {
+	if fromTo.IsDownload() || fromTo == common.EFromTo.LocalLocal() { // local copies write files the way downloads do
 		jm.atomicTransferDirection.AtomicStore(common.ETransferDirection.Download())
 		JobsAdmin.RequestTuneSlowly()
 	}
@@ -533,6 +612,9 @@ func (jm *jobMgr) reportJobPartDoneHandler() {
 	}
 
 	jm.chunkStatusLogger.FlushLog() // TODO: remove once we sort out what will be calling CloseLog (currently nothing)
+
+	// other azcopy processes may now resume or remove the job
+	releaseJob(JobsAdmin.AppPathFolder(), jm.jobID)
 }
 
 func (jm *jobMgr) getInMemoryTransitJobState() InMemoryTransitJobState {
@@ -2,6 +2,7 @@ package ste
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"mime"
 	"net"
@@ -32,6 +33,8 @@ type IJobPartMgr interface {
 	GetForceIfReadOnly() bool
 	AutoDecompress() bool
 	ScheduleChunks(chunkFunc chunkFunc)
+	ScheduleSmallFileChunk(chunkFunc chunkFunc)
+	ScheduleBoostedChunk(chunkFunc chunkFunc)
 	RescheduleTransfer(jptm IJobPartTransferMgr)
 	BlobTypeOverride() common.BlobType
 	BlobTiers() (blockBlobTier common.BlockBlobTier, pageBlobTier common.PageBlobTier)
@@ -51,9 +54,16 @@ type IJobPartMgr interface {
 	common.ILogger
 	SourceProviderPipeline() pipeline.Pipeline
 	getOverwritePrompter() *overwritePrompter
+	recordOverwrite(keptVersion bool)
+	recordPropertiesNotPreserved()
+	recordEmptyPagesSkipped(count uint64)
+	sourcePipeline() pipeline.Pipeline
 	getFolderCreationTracker() common.FolderCreationTracker
 	SecurityInfoPersistenceManager() *securityInfoPersistenceManager
 	FolderDeletionManager() common.FolderDeletionManager
+	DirAccessControlTracker() *dirAccessControlTracker
+	POSIXIDMap() common.POSIXIDMap
+	isCopySourceAuthorized() bool
 }
 
 type serviceAPIVersionOverride struct{}
@@ -99,6 +109,7 @@ func NewAzcopyHTTPClient(maxIdleConns int) *http.Client {
 			MaxIdleConnsPerHost:    maxIdleConns,
 			IdleConnTimeout:        180 * time.Second,
 			TLSHandshakeTimeout:    10 * time.Second,
+			TLSClientConfig:        &tls.Config{ClientSessionCache: tls.NewLRUClientSessionCache(0)}, // so that replacement connections can resume a TLS session instead of doing a full handshake
 			ExpectContinueTimeout:  1 * time.Second,
 			DisableKeepAlives:      false,
 			DisableCompression:     true, // must disable the auto-decompression of gzipped files, and just download the gzipped version. See https://github.com/Azure/azure-storage-azcopy/issues/374
@@ -150,7 +161,8 @@ func newAzcopyHTTPClientFactory(pipelineHTTPClient *http.Client) pipeline.Factor
 }
 
 // NewBlobPipeline creates a Pipeline using the specified credentials and options.
-func NewBlobPipeline(c azblob.Credential, o azblob.PipelineOptions, r XferRetryOptions, p pacer, client *http.Client, statsAcc *pipelineNetworkStats) pipeline.Pipeline {
+// Extra policies are placed right after the version policy, so that they can see (and adjust) the final request headers.
+func NewBlobPipeline(c azblob.Credential, o azblob.PipelineOptions, r XferRetryOptions, p pacer, client *http.Client, statsAcc *pipelineNetworkStats, extraPolicies ...pipeline.Factory) pipeline.Pipeline {
 	if c == nil {
 		panic("c can't be nil")
 	}
@@ -164,9 +176,13 @@ func NewBlobPipeline(c azblob.Credential, o azblob.PipelineOptions, r XferRetryO
 		pipeline.MethodFactoryMarker(), // indicates at what stage in the pipeline the method factory is invoked
 		//NewPacerPolicyFactory(p),
 		NewVersionPolicyFactory(),
-		NewRequestLogPolicyFactory(RequestLogOptions{LogWarningIfTryOverThreshold: o.RequestLog.LogWarningIfTryOverThreshold}),
-		newXferStatsPolicyFactory(statsAcc),
 	}
+	f = append(f, extraPolicies...)
+	f = append(f,
+		newHedgedRequestPolicyFactory(),
+		newMinThroughputPolicyFactory(),
+		NewRequestLogPolicyFactory(RequestLogOptions{LogWarningIfTryOverThreshold: o.RequestLog.LogWarningIfTryOverThreshold}),
+		newXferStatsPolicyFactory(statsAcc))
 	return pipeline.NewPipeline(f, pipeline.Options{HTTPSender: newAzcopyHTTPClientFactory(client), Log: o.Log})
 }
 
@@ -188,6 +204,8 @@ func NewBlobFSPipeline(c azbfs.Credential, o azbfs.PipelineOptions, r XferRetryO
 
 	f = append(f,
 		pipeline.MethodFactoryMarker(), // indicates at what stage in the pipeline the method factory is invoked
+		newHedgedRequestPolicyFactory(),
+		newMinThroughputPolicyFactory(),
 		NewRequestLogPolicyFactory(RequestLogOptions{LogWarningIfTryOverThreshold: o.RequestLog.LogWarningIfTryOverThreshold}),
 		newXferStatsPolicyFactory(statsAcc))
 
@@ -208,6 +226,8 @@ func NewFilePipeline(c azfile.Credential, o azfile.PipelineOptions, r azfile.Ret
 		c,
 		pipeline.MethodFactoryMarker(), // indicates at what stage in the pipeline the method factory is invoked
 		NewVersionPolicyFactory(),
+		newHedgedRequestPolicyFactory(),
+		newMinThroughputPolicyFactory(),
 		NewRequestLogPolicyFactory(RequestLogOptions{LogWarningIfTryOverThreshold: o.RequestLog.LogWarningIfTryOverThreshold}),
 		newXferStatsPolicyFactory(statsAcc),
 	}
@@ -292,6 +312,18 @@ func (jpm *jobPartMgr) getOverwritePrompter() *overwritePrompter {
 	return jpm.jobMgr.getOverwritePrompter()
 }
 
+func (jpm *jobPartMgr) recordOverwrite(keptVersion bool) {
+	jpm.jobMgr.RecordOverwrite(keptVersion)
+}
+
+func (jpm *jobPartMgr) recordPropertiesNotPreserved() {
+	jpm.jobMgr.RecordPropertiesNotPreserved()
+}
+
+func (jpm *jobPartMgr) recordEmptyPagesSkipped(count uint64) {
+	jpm.jobMgr.RecordEmptyPagesSkipped(count)
+}
+
 func (jpm *jobPartMgr) getFolderCreationTracker() common.FolderCreationTracker {
 	if jpm.jobMgrInitState == nil || jpm.jobMgrInitState.folderCreationTracker == nil {
 		panic("folderCreationTracker should have been initialized already")
@@ -313,6 +345,18 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 		plan.SetJobStatus(common.EJobStatus.Completed())
 		return
 	}
+	if plan.NumTransfers == 0 {
+		// a later part can be empty when it only seals the job, e.g. once --follow-list-of-files has sent every transfer
+		// in earlier parts. No transfer will report it done, so it's done straight away
+		if plan.IsFinalPart {
+			jpm.jobMgr.ConfirmAllTransfersScheduled()
+			jpm.Log(pipeline.LogInfo, "Final job part has been scheduled")
+		}
+		jpm.jobMgr.ReportJobPartDone(jobPartProgressInfo{})
+		return
+	}
+	// the transfers hold pointers into the plan, so it mustn't be evicted until they're all done
+	jpm.planMMF.pin()
 
 	// get the list of include / exclude transfers
 	includeTransfer, excludeTransfer := jpm.jobMgr.IncludeExclude()
@@ -323,13 +367,7 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 	// *** Open the job part: process any job part plan-setting used by all transfers ***
 	dstData := plan.DstBlobData
 
-	jpm.httpHeaders = common.ResourceHTTPHeaders{
-		ContentType:        string(dstData.ContentType[:dstData.ContentTypeLength]),
-		ContentEncoding:    string(dstData.ContentEncoding[:dstData.ContentEncodingLength]),
-		ContentDisposition: string(dstData.ContentDisposition[:dstData.ContentDispositionLength]),
-		ContentLanguage:    string(dstData.ContentLanguage[:dstData.ContentLanguageLength]),
-		CacheControl:       string(dstData.CacheControl[:dstData.CacheControlLength]),
-	}
+	jpm.httpHeaders = plan.DstHTTPHeaders()
 
 	jpm.putMd5 = dstData.PutMd5
 	jpm.blockBlobTier = dstData.BlockBlobTier
@@ -358,17 +396,32 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 	jpm.preserveLastModifiedTime = plan.DstLocalData.PreserveLastModifiedTime
 
 	jpm.blobTypeOverride = plan.DstBlobData.BlobType
-	jpm.newJobXfer = computeJobXfer(plan.FromTo, plan.DstBlobData.BlobType)
+	jpm.newJobXfer = computeJobXfer(plan.FromTo, plan.DstBlobData.BlobType, plan.PermissionsOnly)
+	if plan.movesByRename() {
+		jpm.newJobXfer = RenameBlobFS
+	}
 
 	jpm.priority = plan.Priority
 
 	jpm.createPipelines(jobCtx) // pipeline is created per job part manager
 
+	// Get the endpoints ready before the first transfer of the job needs them. Later parts share the same client, and so its connections
+	if plan.PartNum == 0 {
+		endpoints := endpointsToWarmUp(plan.FromTo, string(plan.SourceRoot[:plan.SourceRootLength]), string(plan.DestinationRoot[:plan.DestinationRootLength]))
+		warmUpEndpoints(jobCtx, jpm.jobMgr.HttpClient(), endpoints, warmConnectionCount(), func(s string) { jpm.Log(pipeline.LogInfo, s) })
+	}
+
 	// *** Schedule this job part's transfers ***
 	for t := uint32(0); t < plan.NumTransfers; t++ {
 		jppt := plan.Transfer(t)
 		ts := jppt.TransferStatus()
 		if ts == common.ETransferStatus.Success() {
+			if jppt.SourceDeletionPending() {
+				// the transfer isn't run again, so its source is left alone, but it's logged, since the user asked for it to be deleted
+				src, _, _ := plan.TransferSrcDstStrings(t)
+				jpm.Log(pipeline.LogWarning, fmt.Sprintf("%s was transferred, but the job ended while it was being deleted, so it may still be there",
+					common.URLStringExtension(src).RedactSecretQueryParamForLogging()))
+			}
 			jpm.ReportTransferDone(ts) // Don't schedule an already-completed/failed transfer
 			continue
 		}
@@ -390,11 +443,12 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 			//TODO: insert the factory func interface in jptm.
 			// numChunks will be set by the transfer's prologue method
 		}
+		jptm.ctx = withSecondaryReadNotification(jptm.ctx, jptm)
 		if jpm.ShouldLog(pipeline.LogInfo) {
 			jpm.Log(pipeline.LogInfo, fmt.Sprintf("scheduling JobID=%v, Part#=%d, Transfer#=%d, priority=%v", plan.JobID, plan.PartNum, t, plan.Priority))
 		}
 
-		JobsAdmin.(*jobsAdmin).ScheduleTransfer(jpm.priority, jptm)
+		JobsAdmin.(*jobsAdmin).ScheduleTransfer(jpm.priority, jpm, jptm)
 
 		// This sets the atomic variable atomicAllTransfersScheduled to 1
 		// atomicAllTransfersScheduled variables is used in case of resume job
@@ -411,11 +465,25 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 }
 
 func (jpm *jobPartMgr) ScheduleChunks(chunkFunc chunkFunc) {
-	JobsAdmin.ScheduleChunk(jpm.priority, chunkFunc)
+	JobsAdmin.ScheduleChunk(jpm.priority, jpm, chunkFunc)
+}
+
+func (jpm *jobPartMgr) ScheduleSmallFileChunk(chunkFunc chunkFunc) {
+	JobsAdmin.ScheduleSmallFileChunk(jpm, chunkFunc)
+}
+
+// ScheduleBoostedChunk boosts the chunk ahead of the main pool's other chunks, unless the part has low priority, in which
+// case it still waits behind the chunks of normal priority parts
+func (jpm *jobPartMgr) ScheduleBoostedChunk(chunkFunc chunkFunc) {
+	if jpm.priority != common.EJobPriority.Normal() {
+		jpm.ScheduleChunks(chunkFunc)
+		return
+	}
+	JobsAdmin.ScheduleBoostedChunk(jpm, chunkFunc)
 }
 
 func (jpm *jobPartMgr) RescheduleTransfer(jptm IJobPartTransferMgr) {
-	JobsAdmin.(*jobsAdmin).ScheduleTransfer(jpm.priority, jptm)
+	JobsAdmin.(*jobsAdmin).ScheduleTransfer(jpm.priority, jpm, jptm)
 }
 
 func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
@@ -450,12 +518,32 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 		RetryDelay:    UploadRetryDelay,
 		MaxRetryDelay: UploadMaxRetryDelay}
 
+	// If asked to, downloads from an RA-GRS account fall back to its secondary endpoint when reads from the primary keep failing
+	if (fromTo == common.EFromTo.BlobLocal() || fromTo == common.EFromTo.BlobFSLocal()) && readFromSecondaryEnabled() {
+		plan := jpm.Plan()
+		if secondaryHost := secondaryHostFor(string(plan.SourceRoot[:plan.SourceRootLength])); secondaryHost != "" {
+			xferRetryOption.RetryReadsFromSecondaryHost = secondaryHost
+			jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, reads that fail against the primary endpoint will be retried against %s", plan.JobID, secondaryHost))
+		}
+	}
+
 	var statsAccForSip *pipelineNetworkStats = nil // we don't accumulate stats on the source info provider
 
+	// A blob source authorized with OAuth (possibly in another tenant than the destination) has its own token,
+	// which is used to read the source, and is handed to the service for the copy itself.
+	var sourceCredential azblob.Credential = azblob.NewAnonymousCredential()
+	var blobPipelineExtraPolicies []pipeline.Factory
+	if jpm.isCopySourceAuthorized() {
+		s2sSrcCredInfo := jpm.jobMgr.getInMemoryTransitJobState().s2sSourceCredentialInfo
+		sourceCredential = common.CreateBlobCredential(ctx, s2sSrcCredInfo, credOption)
+		blobPipelineExtraPolicies = append(blobPipelineExtraPolicies, NewCopySourceAuthPolicyFactory(sourceCredential.(azblob.TokenCredential)))
+		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, source credential type: %v, tenant: %s", jpm.Plan().JobID, s2sSrcCredInfo.CredentialType, s2sSrcCredInfo.OAuthTokenInfo.Tenant))
+	}
+
 	// Create source info provider's pipeline for S2S copy.
 	if fromTo == common.EFromTo.BlobBlob() || fromTo == common.EFromTo.BlobFile() {
 		jpm.sourceProviderPipeline = NewBlobPipeline(
-			azblob.NewAnonymousCredential(),
+			sourceCredential,
 			azblob.PipelineOptions{
 				Log: jpm.jobMgr.PipelineLogInfo(),
 				Telemetry: azblob.TelemetryOptions{
@@ -467,6 +555,21 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 			jpm.jobMgr.HttpClient(),
 			statsAccForSip)
 	}
+	// An ADLS Gen2 source is read, as well as described, through the DFS endpoint, authorized by its SAS
+	if fromTo == common.EFromTo.BlobFSBlobFS() {
+		jpm.sourceProviderPipeline = NewBlobFSPipeline(
+			azbfs.NewAnonymousCredential(),
+			azbfs.PipelineOptions{
+				Log: jpm.jobMgr.PipelineLogInfo(),
+				Telemetry: azbfs.TelemetryOptions{
+					Value: userAgent,
+				},
+			},
+			xferRetryOption,
+			jpm.pacer,
+			jpm.jobMgr.HttpClient(),
+			statsAccForSip)
+	}
 	// Consider the file-local SDDL transfer case.
 	if fromTo == common.EFromTo.FileBlob() || fromTo == common.EFromTo.FileFile() || fromTo == common.EFromTo.FileLocal() {
 		jpm.sourceProviderPipeline = NewFilePipeline(
@@ -491,8 +594,8 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 
 	// Create pipeline for data transfer.
 	switch fromTo {
-	case common.EFromTo.BlobTrash(), common.EFromTo.BlobLocal(), common.EFromTo.LocalBlob(), common.EFromTo.BenchmarkBlob(),
-		common.EFromTo.BlobBlob(), common.EFromTo.FileBlob(), common.EFromTo.S3Blob(), common.EFromTo.GCPBlob():
+	case common.EFromTo.BlobTrash(), common.EFromTo.BlobNone(), common.EFromTo.BlobLocal(), common.EFromTo.LocalBlob(), common.EFromTo.BenchmarkBlob(),
+		common.EFromTo.BlobBlob(), common.EFromTo.FileBlob(), common.EFromTo.S3Blob(), common.EFromTo.GCPBlob(), common.EFromTo.LocalManagedDisk():
 		credential := common.CreateBlobCredential(ctx, credInfo, credOption)
 		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, credential type: %v", jpm.Plan().JobID, credInfo.CredentialType))
 		jpm.pipeline = NewBlobPipeline(
@@ -506,9 +609,10 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 			xferRetryOption,
 			jpm.pacer,
 			jpm.jobMgr.HttpClient(),
-			jpm.jobMgr.PipelineNetworkStats())
+			jpm.jobMgr.PipelineNetworkStats(),
+			blobPipelineExtraPolicies...)
 	// Create pipeline for Azure BlobFS.
-	case common.EFromTo.BlobFSLocal(), common.EFromTo.LocalBlobFS(), common.EFromTo.BenchmarkBlobFS():
+	case common.EFromTo.BlobFSLocal(), common.EFromTo.LocalBlobFS(), common.EFromTo.BenchmarkBlobFS(), common.EFromTo.BlobFSBlobFS():
 		credential := common.CreateBlobFSCredential(ctx, credInfo, credOption)
 		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, credential type: %v", jpm.Plan().JobID, credInfo.CredentialType))
 
@@ -527,8 +631,10 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 	// Create pipeline for Azure File.
 	case common.EFromTo.FileTrash(), common.EFromTo.FileLocal(), common.EFromTo.LocalFile(), common.EFromTo.BenchmarkFile(),
 		common.EFromTo.FileFile(), common.EFromTo.BlobFile():
+		credential := common.CreateFileCredential(ctx, credInfo, credOption)
+		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, credential type: %v", jpm.Plan().JobID, credInfo.CredentialType))
 		jpm.pipeline = NewFilePipeline(
-			azfile.NewAnonymousCredential(),
+			credential,
 			azfile.PipelineOptions{
 				Log: jpm.jobMgr.PipelineLogInfo(),
 				Telemetry: azfile.TelemetryOptions{
@@ -545,6 +651,8 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 			jpm.pacer,
 			jpm.jobMgr.HttpClient(),
 			jpm.jobMgr.PipelineNetworkStats())
+	case common.EFromTo.LocalLocal():
+		// nothing remote to reach, so there's no pipeline
 	default:
 		panic(fmt.Errorf("Unrecognized from-to: %q", fromTo.String()))
 	}
@@ -567,9 +675,31 @@ func (jpm *jobPartMgr) ExclusiveDestinationMap() *common.ExclusiveStringMap {
 }
 
 func (jpm *jobPartMgr) StartJobXfer(jptm IJobPartTransferMgr) {
+	if reason := jpm.sizeLimitSkipReason(jptm.Info()); reason != "" {
+		// logging as Warning so that it turns up even in compact logs, like other skipped transfers
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, reason)
+		jptm.SetStatus(common.ETransferStatus.SkippedSizeLimit())
+		jptm.ReportTransferDone()
+		return
+	}
 	jpm.newJobXfer(jptm, jpm.pipeline, jpm.pacer)
 }
 
+// sizeLimitSkipReason says why the file is to be skipped, if it's outside the size bounds of the job. Folders are never skipped
+func (jpm *jobPartMgr) sizeLimitSkipReason(info TransferInfo) string {
+	if info.IsFolderPropertiesTransfer() {
+		return ""
+	}
+	plan := jpm.Plan()
+	if plan.SkipLargerThan > 0 && info.SourceSize > plan.SkipLargerThan {
+		return fmt.Sprintf("File is %d bytes, which is larger than the limit of %d bytes, so will be skipped", info.SourceSize, plan.SkipLargerThan)
+	}
+	if plan.SkipSmallerThan > 0 && info.SourceSize < plan.SkipSmallerThan {
+		return fmt.Sprintf("File is %d bytes, which is smaller than the limit of %d bytes, so will be skipped", info.SourceSize, plan.SkipSmallerThan)
+	}
+	return ""
+}
+
 func (jpm *jobPartMgr) GetOverwriteOption() common.OverwriteOption {
 	return jpm.Plan().ForceWrite
 }
@@ -666,6 +796,25 @@ func (jpm *jobPartMgr) FolderDeletionManager() common.FolderDeletionManager {
 	return jpm.jobMgrInitState.folderDeletionManager
 }
 
+func (jpm *jobPartMgr) DirAccessControlTracker() *dirAccessControlTracker {
+	if jpm.jobMgrInitState == nil || jpm.jobMgrInitState.dirAccessControlTracker == nil {
+		panic("directory access control tracker should have been initialized already")
+	}
+
+	return jpm.jobMgrInitState.dirAccessControlTracker
+}
+
+func (jpm *jobPartMgr) POSIXIDMap() common.POSIXIDMap {
+	return jpm.jobMgr.getInMemoryTransitJobState().posixIDMap
+}
+
+// isCopySourceAuthorized says whether the job's source is a blob authorized with its own OAuth token, which is handed to the service
+// for copies from its URL
+func (jpm *jobPartMgr) isCopySourceAuthorized() bool {
+	return jpm.Plan().FromTo == common.EFromTo.BlobBlob() &&
+		jpm.jobMgr.getInMemoryTransitJobState().s2sSourceCredentialInfo.CredentialType == common.ECredentialType.OAuthToken()
+}
+
 func (jpm *jobPartMgr) localDstData() *JobPartPlanDstLocal {
 	return &jpm.Plan().DstLocalData
 }
@@ -675,14 +824,14 @@ func (jpm *jobPartMgr) deleteSnapshotsOption() common.DeleteSnapshotsOption {
 }
 
 func (jpm *jobPartMgr) updateJobPartProgress(status common.TransferStatus) {
-	switch status {
-	case common.ETransferStatus.Success():
+	switch {
+	case status == common.ETransferStatus.Success():
 		atomic.AddUint32(&jpm.atomicTransfersCompleted, 1)
-	case common.ETransferStatus.Failed(), common.ETransferStatus.BlobTierFailure():
+	case status.IsFailed():
 		atomic.AddUint32(&jpm.atomicTransfersFailed, 1)
-	case common.ETransferStatus.SkippedEntityAlreadyExists(), common.ETransferStatus.SkippedBlobHasSnapshots():
+	case status.IsSkipped():
 		atomic.AddUint32(&jpm.atomicTransfersSkipped, 1)
-	case common.ETransferStatus.Cancelled():
+	case status == common.ETransferStatus.Cancelled():
 	default:
 		jpm.Log(pipeline.LogError, fmt.Sprintf("Unexpected status: %v", status.String()))
 	}
@@ -695,16 +844,17 @@ func (jpm *jobPartMgr) ReportTransferDone(status common.TransferStatus) (transfe
 
 	//Add a safety count-check
 
+	plan := jpm.Plan()
 	if jpm.ShouldLog(pipeline.LogInfo) {
-		plan := jpm.Plan()
 		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, Part#=%d, TransfersDone=%d of %d", plan.JobID, plan.PartNum, transfersDone, plan.NumTransfers))
 	}
-	if transfersDone == jpm.planMMF.Plan().NumTransfers {
+	if transfersDone == plan.NumTransfers {
 		jppi := jobPartProgressInfo{
 			transfersCompleted: int(atomic.LoadUint32(&jpm.atomicTransfersCompleted)),
 			transfersSkipped:   int(atomic.LoadUint32(&jpm.atomicTransfersSkipped)),
 			transfersFailed:    int(atomic.LoadUint32(&jpm.atomicTransfersFailed)),
 		}
+		jpm.planMMF.unpin()
 		jpm.jobMgr.ReportJobPartDone(jppi)
 	}
 	return transfersDone
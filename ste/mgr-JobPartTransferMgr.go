@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -25,8 +26,17 @@ type IJobPartTransferMgr interface {
 	PreserveLastModifiedTime() (time.Time, bool)
 	ShouldPutMd5() bool
 	MD5ValidationOption() common.HashValidationOption
+	FlushPolicy() common.FlushPolicy
+	FreeSpaceCheck() common.FreeSpaceCheckOption
 	BlobTypeOverride() common.BlobType
 	BlobTiers() (blockBlobTier common.BlockBlobTier, pageBlobTier common.PageBlobTier)
+	BlockIDPrefix() string
+	CommitPending() bool
+	SetCommitPending(pending bool)
+	SourceDeletionPending() bool
+	SetSourceDeletionPending(pending bool)
+	ReadFromSecondary() bool
+	IsCopySourceAuthorized() bool
 	JobHasLowFileCount() bool
 	//ScheduleChunk(chunkFunc chunkFunc)
 	Context() context.Context
@@ -36,6 +46,7 @@ type IJobPartTransferMgr interface {
 	EnsureDestinationUnlocked()
 	HoldsDestinationLock() bool
 	StartJobXfer()
+	StartTransferTimeout()
 	GetOverwriteOption() common.OverwriteOption
 	GetForceIfReadOnly() bool
 	ShouldDecompress() bool
@@ -80,11 +91,17 @@ type IJobPartTransferMgr interface {
 	ChunkStatusLogger() common.ChunkStatusLogger
 	LogAtLevelForCurrentTransfer(level pipeline.LogLevel, msg string)
 	GetOverwritePrompter() *overwritePrompter
+	MarkOverwrite(keptVersion bool)
+	MarkPropertiesNotPreserved(reason string)
+	MarkEmptyPagesSkipped(count int64)
 	GetFolderCreationTracker() common.FolderCreationTracker
 	common.ILogger
 	DeleteSnapshotsOption() common.DeleteSnapshotsOption
 	SecurityInfoPersistenceManager() *securityInfoPersistenceManager
 	FolderDeletionManager() common.FolderDeletionManager
+	DirAccessControlTracker() *dirAccessControlTracker
+	POSIXIDMap() common.POSIXIDMap
+	GetSourceRoot() string
 	GetDestinationRoot() string
 	ShouldInferContentType() bool
 }
@@ -97,9 +114,11 @@ type TransferInfo struct {
 	EntityType             common.EntityType
 	PreserveSMBPermissions common.PreservePermissionsOption
 	PreserveSMBInfo        bool
+	PreserveLastAccessTime bool
 
 	// Transfer info for S2S copy
 	SrcProperties
+	SrcMetadataOverride            bool // SrcMetadata was computed for this file and replaces the job's metadata
 	S2SGetPropertiesInBackend      bool
 	S2SSourceChangeValidation      bool
 	DestLengthValidation           bool
@@ -159,6 +178,9 @@ type jobPartTransferMgr struct {
 	// (hard to infer from atomicChunksDone because that counts both successes and failures)
 	atomicSuccessfulBytes int64
 
+	// pages of a page blob upload that weren't sent because they were empty
+	atomicEmptyPagesSkipped int64
+
 	// NumberOfChunksDone represents the number of chunks of a transfer
 	// which are either completed or failed.
 	// NumberOfChunksDone determines the final cancellation or completion of a transfer
@@ -173,6 +195,15 @@ type jobPartTransferMgr struct {
 	// used to show whether THIS jptm holds the destination lock
 	atomicDestLockHeldIndicator uint32
 
+	// used to show whether any of this transfer's reads were served by the secondary endpoint of an RA-GRS account
+	atomicSecondaryReadIndicator uint32
+
+	// used to show whether this transfer is replacing a destination known to exist, and if so, whether a previous version of it is kept
+	atomicOverwriteIndicator uint32
+
+	// used to show whether some property of the source, such as part of its ACL, couldn't be given to the destination
+	atomicPropertiesNotPreservedIndicator uint32
+
 	jobPartMgr          IJobPartMgr // Refers to the "owning" Job Part
 	jobPartPlanTransfer *JobPartPlanTransfer
 	transferIndex       uint32
@@ -189,6 +220,11 @@ type jobPartTransferMgr struct {
 
 	actionAfterLastChunk func()
 
+	// fails the transfer if it runs for longer than the job's per-transfer time limit.
+	// It's started by the first chunk to run, and stopped when the transfer is done
+	timeoutLock  sync.Mutex
+	timeoutTimer *time.Timer
+
 	/*
 		@Parteek removed 3/23 morning, as jeff ad equivalent
 		// transfer chunks are put into this channel and execution engine takes chunk out of this channel.
@@ -208,9 +244,39 @@ func (jptm *jobPartTransferMgr) FromTo() common.FromTo {
 }
 
 func (jptm *jobPartTransferMgr) StartJobXfer() {
+	jptm.jobPartPlanTransfer.SetStartTime(time.Now())
 	jptm.jobPartMgr.StartJobXfer(jptm)
 }
 
+// StartTransferTimeout starts the clock on the job's per-transfer time limit, if it has one.
+// It's called as each chunk starts running, and only the first call counts, so time spent queued is never held against the transfer
+func (jptm *jobPartTransferMgr) StartTransferTimeout() {
+	limit := jptm.jobPartMgr.Plan().TransferTimeout
+	if limit <= 0 {
+		return
+	}
+
+	jptm.timeoutLock.Lock()
+	defer jptm.timeoutLock.Unlock()
+	if jptm.timeoutTimer == nil && atomic.LoadUint32(&jptm.atomicCompletionIndicator) == 0 {
+		jptm.timeoutTimer = time.AfterFunc(limit, func() { jptm.failOnTimeout(limit) })
+	}
+}
+
+// failOnTimeout fails the transfer, if it is still running, because it has run for longer than limit.
+// Unlike failActiveTransfer, the status is set before cancelling, so that the epilogue doesn't report the transfer as cancelled instead
+func (jptm *jobPartTransferMgr) failOnTimeout(limit time.Duration) {
+	if atomic.LoadUint32(&jptm.atomicCompletionIndicator) != 0 || !jptm.IsLive() {
+		return
+	}
+	jptm.SetStatus(common.ETransferStatus.TimedOut())
+	jptm.Cancel()
+
+	info := jptm.Info()
+	jptm.logTransferError(transferErrorCodeTimedOut, info.Source, info.Destination,
+		fmt.Sprintf("Transfer was still running after the time limit of %v, so it has been failed", limit), 0)
+}
+
 func (jptm *jobPartTransferMgr) GetOverwriteOption() common.OverwriteOption {
 	return jptm.jobPartMgr.GetOverwriteOption()
 }
@@ -333,6 +399,7 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 		EntityType:                     entityType,
 		PreserveSMBPermissions:         plan.PreserveSMBPermissions,
 		PreserveSMBInfo:                plan.PreserveSMBInfo,
+		PreserveLastAccessTime:         plan.DstLocalData.PreserveLastAccessTime,
 		S2SGetPropertiesInBackend:      s2sGetPropertiesInBackend,
 		S2SSourceChangeValidation:      s2sSourceChangeValidation,
 		S2SInvalidMetadataHandleOption: s2sInvalidMetadataHandleOption,
@@ -342,8 +409,9 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 			SrcMetadata:    srcMetadata,
 			SrcBlobTags:    srcBlobTags,
 		},
-		SrcBlobType:    srcBlobType,
-		S2SSrcBlobTier: srcBlobTier,
+		SrcMetadataOverride: plan.Transfer(jptm.transferIndex).SrcMetadataOverride,
+		SrcBlobType:         srcBlobType,
+		S2SSrcBlobTier:      srcBlobTier,
 	}
 
 	return *jptm.transferInfo
@@ -429,16 +497,34 @@ func (jptm *jobPartTransferMgr) HoldsDestinationLock() bool {
 }
 
 func (jptm *jobPartTransferMgr) useFileCountLimiter() bool {
-	ft := jptm.FromTo()    // TODO: consider changing isDownload (and co) to have struct receiver instead of pointer receiver, so don't need variable like this
-	return ft.IsDownload() // count-based limits are only applied for download a present
+	ft := jptm.FromTo()                                         // TODO: consider changing isDownload (and co) to have struct receiver instead of pointer receiver, so don't need variable like this
+	return ft.IsDownload() || ft == common.EFromTo.LocalLocal() // count-based limits are only applied when writing local files at present
 }
 
 func (jptm *jobPartTransferMgr) RescheduleTransfer() {
 	jptm.jobPartMgr.RescheduleTransfer(jptm)
 }
 
+// ScheduleChunks sends the chunks of small uploads to the small-file lane, so that they aren't queued up behind the chunks of big files.
+// Downloads and service-to-service copies always use the main pool, whose size is tuned for them.
+// With --boost-nearly-complete, the remaining chunks of a transfer that's nearly complete jump the main pool's queue,
+// so that the file is finished, and closed, sooner.
 func (jptm *jobPartTransferMgr) ScheduleChunks(chunkFunc chunkFunc) {
-	jptm.jobPartMgr.ScheduleChunks(chunkFunc)
+	ft := jptm.FromTo()
+	if ft.IsUpload() && JobsAdmin.IsSmallFile(jptm.Info().SourceSize) {
+		jptm.jobPartMgr.ScheduleSmallFileChunk(chunkFunc)
+	} else if JobsAdmin.(*jobsAdmin).concurrency.BoostNearlyComplete && isNearlyComplete(atomic.LoadUint32(&jptm.atomicChunksDone), jptm.numChunks) {
+		jptm.jobPartMgr.ScheduleBoostedChunk(chunkFunc)
+	} else {
+		jptm.jobPartMgr.ScheduleChunks(chunkFunc)
+	}
+}
+
+// nearlyCompleteFraction is the fraction of its chunks that a transfer must have done for the rest of them to be boosted
+const nearlyCompleteFraction = 0.9
+
+func isNearlyComplete(chunksDone uint32, numChunks uint32) bool {
+	return numChunks > 0 && float64(chunksDone) > nearlyCompleteFraction*float64(numChunks)
 }
 
 func (jptm *jobPartTransferMgr) ResourceDstData(dataFileToXfer []byte) (headers common.ResourceHTTPHeaders, metadata common.Metadata, blobTags common.BlobTags) {
@@ -468,6 +554,14 @@ func (jptm *jobPartTransferMgr) MD5ValidationOption() common.HashValidationOptio
 	return jptm.jobPartMgr.(*jobPartMgr).localDstData().MD5VerificationOption
 }
 
+func (jptm *jobPartTransferMgr) FlushPolicy() common.FlushPolicy {
+	return jptm.jobPartMgr.(*jobPartMgr).localDstData().FlushPolicy
+}
+
+func (jptm *jobPartTransferMgr) FreeSpaceCheck() common.FreeSpaceCheckOption {
+	return jptm.jobPartMgr.(*jobPartMgr).localDstData().FreeSpaceCheck
+}
+
 func (jptm *jobPartTransferMgr) DeleteSnapshotsOption() common.DeleteSnapshotsOption {
 	return jptm.jobPartMgr.(*jobPartMgr).deleteSnapshotsOption()
 }
@@ -480,6 +574,38 @@ func (jptm *jobPartTransferMgr) BlobTiers() (blockBlobTier common.BlockBlobTier,
 	return jptm.jobPartMgr.BlobTiers()
 }
 
+// BlockIDPrefix identifies the transfer in the same way in every run of the job, so that the blocks that one run stages
+// can be recognized by the next. It's 55 bytes long, leaving room for an 8 digit block index within the service's limit of 64
+func (jptm *jobPartTransferMgr) BlockIDPrefix() string {
+	plan := jptm.jobPartMgr.Plan()
+	return fmt.Sprintf("%s-%08x-%08x-", plan.JobID, uint32(plan.PartNum), jptm.transferIndex)
+}
+
+// CommitPending says whether all of the transfer's blocks were staged, but have not yet been committed
+func (jptm *jobPartTransferMgr) CommitPending() bool {
+	return jptm.jobPartPlanTransfer.CommitPending()
+}
+
+// SetCommitPending records, in the plan file, whether all of the transfer's blocks have been staged but not yet committed
+func (jptm *jobPartTransferMgr) SetCommitPending(pending bool) {
+	jptm.jobPartPlanTransfer.SetCommitPending(pending)
+}
+
+// SourceDeletionPending says whether the transfer's source was being deleted or renamed away, but isn't known to have been
+func (jptm *jobPartTransferMgr) SourceDeletionPending() bool {
+	return jptm.jobPartPlanTransfer.SourceDeletionPending()
+}
+
+// SetSourceDeletionPending records, in the plan file, whether the transfer's source is being deleted or renamed away
+func (jptm *jobPartTransferMgr) SetSourceDeletionPending(pending bool) {
+	jptm.jobPartPlanTransfer.setSourceDeletion(common.Iffint32(pending, sourceDeletionPending, sourceDeletionNone))
+}
+
+// IsCopySourceAuthorized says whether the service is handed the source's own token, to authorize it for copies from its URL
+func (jptm *jobPartTransferMgr) IsCopySourceAuthorized() bool {
+	return jptm.jobPartMgr.isCopySourceAuthorized()
+}
+
 // JobHasLowFileCount returns an estimate of whether we only have a very small number of files in the overall job
 // (An "estimate" because it actually only looks at the current job part)
 func (jptm *jobPartTransferMgr) JobHasLowFileCount() bool {
@@ -511,7 +637,7 @@ func (jptm *jobPartTransferMgr) ReportChunkDone(id common.ChunkID) (lastChunk bo
 	// track progress
 	if jptm.IsLive() {
 		atomic.AddInt64(&jptm.atomicSuccessfulBytes, id.Length())
-		JobsAdmin.AddSuccessfulBytesInActiveFiles(id.Length())
+		jptm.jobPartMgr.(*jobPartMgr).jobMgr.AddSuccessfulBytesInActiveFiles(id.Length())
 	}
 
 	// Do our actual processing
@@ -519,7 +645,7 @@ func (jptm *jobPartTransferMgr) ReportChunkDone(id common.ChunkID) (lastChunk bo
 	lastChunk = chunksDone == jptm.numChunks
 	if lastChunk {
 		jptm.runActionAfterLastChunk()
-		JobsAdmin.AddSuccessfulBytesInActiveFiles(-atomic.LoadInt64(&jptm.atomicSuccessfulBytes)) // subtract our bytes from the active files bytes, because we are done now
+		jptm.jobPartMgr.(*jobPartMgr).jobMgr.AddSuccessfulBytesInActiveFiles(-atomic.LoadInt64(&jptm.atomicSuccessfulBytes)) // subtract our bytes from the active files bytes, because we are done now
 	}
 	return lastChunk, chunksDone
 }
@@ -568,8 +694,16 @@ func (jptm *jobPartTransferMgr) SetErrorCode(errorCode int32) {
 	return atomic.LoadUint32(&jptm.atomicChunksDone)
 }*/
 
-func (jptm *jobPartTransferMgr) Cancel()           { jptm.cancel() }
-func (jptm *jobPartTransferMgr) WasCanceled() bool { return jptm.ctx.Err() != nil }
+func (jptm *jobPartTransferMgr) Cancel() { jptm.cancel() }
+
+// WasCanceled says whether the transfer was cancelled, either with its job, or on its own, in its plan (see CancelTransfer).
+// The latter may have been done by another process, so it's only seen here, and is then passed on to the transfer's context.
+func (jptm *jobPartTransferMgr) WasCanceled() bool {
+	if jptm.ctx.Err() == nil && jptm.jobPartPlanTransfer.TransferStatus() == common.ETransferStatus.Cancelled() {
+		jptm.cancel()
+	}
+	return jptm.ctx.Err() != nil
+}
 
 // SetDestinationIsModified tells the jptm that it should consider the destination to have been modified
 func (jptm *jobPartTransferMgr) SetDestinationIsModified() {
@@ -583,6 +717,19 @@ func (jptm *jobPartTransferMgr) SetDestinationIsModified() {
 	}
 }
 
+// SecondaryReadCallback is called by the retry policy when one of this transfer's reads succeeded against the
+// secondary endpoint, because the primary was failing. It's logged once per transfer, so that the log records which endpoint served it
+func (jptm *jobPartTransferMgr) SecondaryReadCallback(host string) {
+	if atomic.SwapUint32(&jptm.atomicSecondaryReadIndicator, 1) == 0 {
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "reads from the primary endpoint failed, so some or all of the data was read from the secondary endpoint "+host)
+	}
+}
+
+// ReadFromSecondary says whether any of this transfer's data was read from the secondary endpoint
+func (jptm *jobPartTransferMgr) ReadFromSecondary() bool {
+	return atomic.LoadUint32(&jptm.atomicSecondaryReadIndicator) == 1
+}
+
 func (jptm *jobPartTransferMgr) hasStartedWork() bool {
 	return atomic.LoadUint32(&jptm.atomicDestModifiedIndicator) == 1
 }
@@ -776,6 +923,7 @@ const (
 	transferErrorCodeUploadFailed   transferErrorCode = "UPLOADFAILED"
 	transferErrorCodeDownloadFailed transferErrorCode = "DOWNLOADFAILED"
 	transferErrorCodeCopyFailed     transferErrorCode = "COPYFAILED"
+	transferErrorCodeTimedOut       transferErrorCode = "TIMEDOUT"
 )
 
 func (jptm *jobPartTransferMgr) LogAtLevelForCurrentTransfer(level pipeline.LogLevel, msg string) {
@@ -849,8 +997,16 @@ func (jptm *jobPartTransferMgr) Panic(err error) { jptm.jobPartMgr.Panic(err) }
 // Call ReportTransferDone to report when a Transfer for this Job Part has completed
 // TODO: I feel like this should take the status & we kill SetStatus
 func (jptm *jobPartTransferMgr) ReportTransferDone() uint32 {
+	// before the transfer's context is cancelled, since the deletion uses it
+	jptm.deleteSourceIfMoved()
+
 	// In case of context leak in job part transfer manager.
 	jptm.Cancel()
+	jptm.timeoutLock.Lock()
+	if jptm.timeoutTimer != nil {
+		jptm.timeoutTimer.Stop()
+	}
+	jptm.timeoutLock.Unlock()
 
 	// defensive programming check, to make sure this method is not called twice for the same transfer
 	// (since if it was, job would count us as TWO completions, and maybe miss another transfer that
@@ -862,6 +1018,20 @@ func (jptm *jobPartTransferMgr) ReportTransferDone() uint32 {
 	if atomic.SwapUint32(&jptm.atomicCompletionIndicator, 1) != 0 {
 		panic("cannot report the same transfer done twice")
 	}
+	jptm.jobPartPlanTransfer.SetCompletionTime(time.Now())
+
+	if overwrite := atomic.LoadUint32(&jptm.atomicOverwriteIndicator); overwrite != overwriteNone &&
+		jptm.jobPartPlanTransfer.TransferStatus() == common.ETransferStatus.Success() {
+		jptm.jobPartMgr.recordOverwrite(overwrite == overwriteVersioned)
+	}
+	if atomic.LoadUint32(&jptm.atomicPropertiesNotPreservedIndicator) != 0 &&
+		jptm.jobPartPlanTransfer.TransferStatus() == common.ETransferStatus.Success() {
+		jptm.jobPartMgr.recordPropertiesNotPreserved()
+	}
+	if skipped := atomic.LoadInt64(&jptm.atomicEmptyPagesSkipped); skipped > 0 &&
+		jptm.jobPartPlanTransfer.TransferStatus() == common.ETransferStatus.Success() {
+		jptm.jobPartMgr.recordEmptyPagesSkipped(uint64(skipped))
+	}
 
 	return jptm.jobPartMgr.ReportTransferDone(jptm.jobPartPlanTransfer.TransferStatus())
 }
@@ -878,6 +1048,19 @@ func (jptm *jobPartTransferMgr) FolderDeletionManager() common.FolderDeletionMan
 	return jptm.jobPartMgr.FolderDeletionManager()
 }
 
+func (jptm *jobPartTransferMgr) DirAccessControlTracker() *dirAccessControlTracker {
+	return jptm.jobPartMgr.DirAccessControlTracker()
+}
+
+func (jptm *jobPartTransferMgr) POSIXIDMap() common.POSIXIDMap {
+	return jptm.jobPartMgr.POSIXIDMap()
+}
+
+func (jptm *jobPartTransferMgr) GetSourceRoot() string {
+	p := jptm.jobPartMgr.Plan()
+	return string(p.SourceRoot[:p.SourceRootLength])
+}
+
 func (jptm *jobPartTransferMgr) GetDestinationRoot() string {
 	p := jptm.jobPartMgr.Plan()
 	return string(p.DestinationRoot[:p.DestinationRootLength])
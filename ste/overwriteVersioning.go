@@ -0,0 +1,70 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"sync/atomic"
+)
+
+// values of jobPartTransferMgr.atomicOverwriteIndicator
+const (
+	overwriteNone        uint32 = 0
+	overwriteUnversioned uint32 = 1
+	overwriteVersioned   uint32 = 2
+)
+
+// versionKeepingSender is implemented by the senders whose destination can keep the previous version of what they
+// overwrite. It's only meaningful once RemoteFileExists has found the destination
+type versionKeepingSender interface {
+	// DestinationKeepsVersions says whether the destination's storage account has blob versioning enabled,
+	// so that overwriting the destination keeps its current content as a previous version
+	DestinationKeepsVersions() bool
+}
+
+// keepsVersions tells, from the response to getting the properties of an existing blob, whether blob versioning is
+// enabled on its account. Only then does the service give the blob's version ID
+func keepsVersions(resp responseError) bool {
+	return resp.Response() != nil && resp.Response().Header.Get("x-ms-version-id") != ""
+}
+
+// MarkOverwrite notes that the transfer is replacing a destination that's known to exist, so that the overwrite is
+// counted in the job's summary if the transfer succeeds
+func (jptm *jobPartTransferMgr) MarkOverwrite(keptVersion bool) {
+	overwrite := overwriteUnversioned
+	if keptVersion {
+		overwrite = overwriteVersioned
+	}
+	atomic.StoreUint32(&jptm.atomicOverwriteIndicator, overwrite)
+}
+
+func (jm *jobMgr) RecordOverwrite(keptVersion bool) {
+	atomic.AddUint32(&jm.atomicOverwrites, 1)
+	if keptVersion {
+		atomic.AddUint32(&jm.atomicVersionedOverwrites, 1)
+	}
+}
+
+// OverwriteCounts returns how many destinations, known to have existed, the job's successful transfers have replaced,
+// and how many of those were blobs that kept their previous content as a version. Destinations are only known to exist
+// when the overwrite option isn't true, since that's when they're checked before the transfer
+func (jm *jobMgr) OverwriteCounts() (overwrites, versioned uint32) {
+	return atomic.LoadUint32(&jm.atomicOverwrites), atomic.LoadUint32(&jm.atomicVersionedOverwrites)
+}
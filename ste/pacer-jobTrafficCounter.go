@@ -0,0 +1,60 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// jobTrafficCounter is the pacer that a job's transfers use. It passes their requests on to the pacer shared by all
+// the jobs in the process, which does the pacing, and counts the job's own traffic, so that the job's summary
+// reports the bytes that it moved, rather than those of every job that's running
+type jobTrafficCounter struct {
+	atomicTraffic int64
+	shared        pacer
+}
+
+func newJobTrafficCounter(shared pacer) *jobTrafficCounter {
+	return &jobTrafficCounter{shared: shared}
+}
+
+func (c *jobTrafficCounter) RequestTrafficAllocation(ctx context.Context, byteCount int64) error {
+	if err := c.shared.RequestTrafficAllocation(ctx, byteCount); err != nil {
+		return err
+	}
+	atomic.AddInt64(&c.atomicTraffic, byteCount)
+	return nil
+}
+
+func (c *jobTrafficCounter) UndoRequest(byteCount int64) {
+	c.shared.UndoRequest(byteCount)
+	atomic.AddInt64(&c.atomicTraffic, -byteCount)
+}
+
+// Close leaves the shared pacer running, since it outlives any one job
+func (c *jobTrafficCounter) Close() error {
+	return nil
+}
+
+func (c *jobTrafficCounter) GetTotalTraffic() int64 {
+	return atomic.LoadInt64(&c.atomicTraffic)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"hash"
+)
+
+// how many prefetched chunks may wait to be hashed before the reading of the file waits for the hashing
+const hashPipelineDepth = 4
+
+// bufferHasher is the part of a prefetched chunk reader that the pipelined hasher needs
+type bufferHasher interface {
+	WriteBufferTo(h hash.Hash)
+}
+
+type chunkToHash struct {
+	chunk  bufferHasher
+	hashed chan struct{}
+}
+
+// pipelinedHasher hashes the chunks of a file on its own goroutine, in the order in which they're read, so that
+// reading the next chunk from disk overlaps with hashing this one, and hashing this one overlaps with sending
+// the chunks before it. A chunk mustn't be sent until it's hashed, since sending it releases its buffer, and any
+// re-read for a retry isn't guaranteed to match what was hashed (see DocumentationForDependencyOnChangeDetection).
+type pipelinedHasher struct {
+	h      hash.Hash
+	chunks chan chunkToHash
+	done   chan struct{}
+}
+
+func newPipelinedHasher(h hash.Hash) *pipelinedHasher {
+	p := &pipelinedHasher{h: h, chunks: make(chan chunkToHash, hashPipelineDepth), done: make(chan struct{})}
+	go p.run()
+	return p
+}
+
+func (p *pipelinedHasher) run() {
+	defer close(p.done)
+	for c := range p.chunks {
+		c.chunk.WriteBufferTo(p.h)
+		close(c.hashed)
+	}
+}
+
+// add queues a prefetched chunk for hashing, and returns a channel that's closed once it has been hashed
+func (p *pipelinedHasher) add(chunk bufferHasher) <-chan struct{} {
+	hashed := make(chan struct{})
+	p.chunks <- chunkToHash{chunk: chunk, hashed: hashed}
+	return hashed
+}
+
+// sum waits for all the chunks that were added to be hashed, and returns the hash of the whole file.
+// No chunks may be added afterwards.
+func (p *pipelinedHasher) sum() []byte {
+	close(p.chunks)
+	<-p.done
+	return p.h.Sum(nil)
+}
+
+// afterHashing makes a chunk func wait until its chunk has been hashed
+func afterHashing(cf chunkFunc, hashed <-chan struct{}) chunkFunc {
+	return func(workerId int) {
+		<-hashed
+		cf(workerId)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// how much room is left for plan files. A variable so that tests can pretend the volume is full
+var getPlanFolderFreeSpace = common.GetFreeSpace
+
+// minPlanFileSize is a lower bound on the size of the plan file for the given part. It leaves out the metadata and tags,
+// which would have to be marshalled to be measured, and are absent for most transfers
+func minPlanFileSize(order common.CopyJobPartOrderRequest) int64 {
+	size := int64(unsafe.Sizeof(JobPartPlanHeader{})) + int64(len(order.CommandString))
+	for _, t := range order.Transfers {
+		size += int64(unsafe.Sizeof(JobPartPlanTransfer{})) +
+			int64(len(t.Source)+len(t.Destination)) +
+			int64(len(t.ContentType)+len(t.ContentEncoding)+len(t.ContentLanguage)+len(t.ContentDisposition)+len(t.CacheControl)) +
+			int64(len(t.ContentMD5)+len(t.BlobType)+len(t.BlobTier)+len(t.BlobVersionID))
+	}
+	return size
+}
+
+// checkPlanFolderSpace returns an error if the volume holding the plan files clearly hasn't room for the given part's plan.
+// Running out of room part way through writing the plan, or later while it's updated through its memory map,
+// would fail far less clearly. If the free space can't be found, we don't stand in the way
+func checkPlanFolderSpace(planDir string, order common.CopyJobPartOrderRequest) error {
+	free, err := getPlanFolderFreeSpace(planDir)
+	if err != nil {
+		return nil
+	}
+	if need := minPlanFileSize(order); uint64(need) > free {
+		const mib = 1024 * 1024
+		return fmt.Errorf("the volume holding the job plan files (%s) has %.1f MiB free, which isn't enough for part %d of the job, which needs at least %.1f MiB. "+
+			"Use --plan-file-location, or the environment variable %s, to put the plan files on a larger volume",
+			planDir, float64(free)/mib, order.PartNum, float64(need)/mib, common.EEnvironmentVariable.JobPlanLocation().Name)
+	}
+	return nil
+}
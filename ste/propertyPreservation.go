@@ -0,0 +1,43 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"sync/atomic"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+)
+
+// MarkPropertiesNotPreserved logs why some property of the source couldn't be given to the destination, and notes it,
+// so that the transfer is counted in the job's summary if it succeeds. Strict jobs fail when any transfer is
+func (jptm *jobPartTransferMgr) MarkPropertiesNotPreserved(reason string) {
+	jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, reason)
+	atomic.StoreUint32(&jptm.atomicPropertiesNotPreservedIndicator, 1)
+}
+
+func (jm *jobMgr) RecordPropertiesNotPreserved() {
+	atomic.AddUint32(&jm.atomicPropertiesNotPreserved, 1)
+}
+
+// PropertiesNotPreservedCount returns how many of the job's successful transfers left some property of their source behind
+func (jm *jobMgr) PropertiesNotPreservedCount() uint32 {
+	return atomic.LoadUint32(&jm.atomicPropertiesNotPreserved)
+}
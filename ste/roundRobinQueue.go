@@ -0,0 +1,111 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"sync"
+)
+
+// roundRobinQueue holds the work waiting for one of the pools, with a queue for each job part, and hands it out by
+// taking turns between the parts that have work waiting. When everything goes through one FIFO channel, a big part that
+// was scheduled early is worked through before any of the parts after it, even if those hold the files that are needed first.
+type roundRobinQueue struct {
+	lock     sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	capacity int
+	count    int
+
+	queues map[IJobPartMgr][]interface{}
+	turns  []IJobPartMgr // the parts with work waiting, in the order they take their turns
+	next   int           // the index in turns of the part whose turn is next
+}
+
+func newRoundRobinQueue(capacity int) *roundRobinQueue {
+	q := &roundRobinQueue{
+		capacity: capacity,
+		queues:   make(map[IJobPartMgr][]interface{}),
+	}
+	q.notEmpty = sync.NewCond(&q.lock)
+	q.notFull = sync.NewCond(&q.lock)
+	return q
+}
+
+// push adds the item to the end of its part's queue, waiting, as a full channel would, while the queue is at capacity
+func (q *roundRobinQueue) push(part IJobPartMgr, item interface{}) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for q.count >= q.capacity {
+		q.notFull.Wait()
+	}
+	if _, waiting := q.queues[part]; !waiting {
+		q.turns = append(q.turns, part) // a part that's new to the queue takes its turn after those already waiting
+	}
+	q.queues[part] = append(q.queues[part], item)
+	q.count++
+	q.notEmpty.Signal()
+}
+
+// pop takes the next item from the part whose turn it is, waiting until there is one
+func (q *roundRobinQueue) pop() interface{} {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for q.count == 0 {
+		q.notEmpty.Wait()
+	}
+	part := q.turns[q.next]
+	items := q.queues[part]
+	item := items[0]
+	items[0] = nil // so the queue doesn't keep the item alive
+
+	if len(items) == 1 {
+		// the part has nothing else waiting, so it drops out of the turns (and the next part moves up to this index)
+		delete(q.queues, part)
+		q.turns = append(q.turns[:q.next], q.turns[q.next+1:]...)
+	} else {
+		q.queues[part] = items[1:]
+		q.next++
+	}
+	if q.next >= len(q.turns) {
+		q.next = 0
+	}
+
+	q.count--
+	q.notFull.Signal()
+	return item
+}
+
+// feedTransfers hands the queue's transfers, in turn, to the channel that the transfer initiation pool reads. The channel should
+// be unbuffered, so that the order is decided here, when a worker is ready, and not when the transfer is scheduled.
+func (q *roundRobinQueue) feedTransfers(ch chan<- IJobPartTransferMgr) {
+	for {
+		ch <- q.pop().(IJobPartTransferMgr)
+	}
+}
+
+// feedChunks hands the queue's chunks, in turn, to the channel that a chunk pool reads. Like feedTransfers, it should be unbuffered.
+func (q *roundRobinQueue) feedChunks(ch chan<- chunkFunc) {
+	for {
+		ch <- q.pop().(chunkFunc)
+	}
+}
@@ -51,7 +51,7 @@ func newURLToBlobCopier(jptm IJobPartTransferMgr, destination string, p pipeline
 	} else {
 		if blobSrcInfoProvider, ok := srcInfoProvider.(IBlobSourceInfoProvider); ok { // If source is a blob, detect the source blob type.
 			targetBlobType = blobSrcInfoProvider.BlobType()
-		} else { // If source is not a blob, infer the blob type from the extension.
+		} else { // If source is not a blob, infer the blob type from the extension of the destination or the source.
 			srcURL, err := url.Parse(jptm.Info().Source)
 
 			// I don't think it would ever reach here if the source URL failed to parse, but this is a sanity check.
@@ -61,7 +61,7 @@ func newURLToBlobCopier(jptm IJobPartTransferMgr, destination string, p pipeline
 
 			fileName := srcURL.Path
 
-			targetBlobType = inferBlobType(fileName, azblob.BlobBlockBlob)
+			targetBlobType = inferDestinationBlobType(fileName, destination, azblob.BlobBlockBlob)
 		}
 
 		if targetBlobType != azblob.BlobBlockBlob {
@@ -0,0 +1,298 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/sddl"
+)
+
+// Windows access rights that have a POSIX counterpart, or that only the owner has in POSIX
+const (
+	winFileReadData   = 0x1
+	winFileWriteData  = 0x2
+	winFileAppendData = 0x4
+	winFileExecute    = 0x20
+	winWriteDAC       = 0x40000
+	winWriteOwner     = 0x80000
+	winGenericAll     = 0x10000000
+	winGenericExecute = 0x20000000
+	winGenericWrite   = 0x40000000
+	winGenericRead    = 0x80000000
+)
+
+// sddlAccessRights are the two-letter rights which an ACE string may list instead of a hexadecimal access mask
+var sddlAccessRights = map[string]uint32{
+	"GA": winGenericAll, "GR": winGenericRead, "GW": winGenericWrite, "GX": winGenericExecute,
+	"FA": 0x1F01FF, "FR": 0x120089, "FW": 0x120116, "FX": 0x1200A0,
+	"RC": 0x20000, "SD": 0x10000, "WD": winWriteDAC, "WO": winWriteOwner,
+	"CC": 0x1, "DC": 0x2, "LC": 0x4, "SW": 0x8, "RP": 0x10, "WP": 0x20, "DT": 0x40, "LO": 0x80, "CR": 0x100,
+}
+
+// well-known SIDs, which don't name a principal that ADLS Gen2 could know
+const (
+	sidEveryone     = "S-1-1-0"
+	sidCreatorOwner = "S-1-3-0"
+	sidCreatorGroup = "S-1-3-1"
+)
+
+// the fields of an ACE string, e.g. A;OICI;FA;;;S-1-5-32-544
+const (
+	aceFieldType   = 0
+	aceFieldFlags  = 1
+	aceFieldRights = 2
+	aceFieldSID    = 5
+)
+
+// parseSDDLAccessMask reads the rights of an ACE string, which are either a hexadecimal mask or two-letter rights
+func parseSDDLAccessMask(rights string) (uint32, error) {
+	if strings.HasPrefix(strings.ToLower(rights), "0x") {
+		mask, err := strconv.ParseUint(rights[2:], 16, 32)
+		return uint32(mask), err
+	}
+	if len(rights)%2 != 0 {
+		return 0, fmt.Errorf("invalid access rights %q", rights)
+	}
+	var mask uint32
+	for i := 0; i < len(rights); i += 2 {
+		right, ok := sddlAccessRights[strings.ToUpper(rights[i:i+2])]
+		if !ok {
+			return 0, fmt.Errorf("unknown access right %q", rights[i:i+2])
+		}
+		mask |= right
+	}
+	return mask, nil
+}
+
+// posixPermsFromAccessMask returns the rwx bits, in the form taken by posixPermissionString, which an access mask grants.
+// Write needs only one of write and append data, so that a principal that may change the file can still do so.
+func posixPermsFromAccessMask(mask uint32) uint16 {
+	var perms uint16
+	if mask&(winFileReadData|winGenericRead|winGenericAll) != 0 {
+		perms |= 4
+	}
+	if mask&(winFileWriteData|winFileAppendData|winGenericWrite|winGenericAll) != 0 {
+		perms |= 2
+	}
+	if mask&(winFileExecute|winGenericExecute|winGenericAll) != 0 {
+		perms |= 1
+	}
+	return perms
+}
+
+// canonicalSID turns the abbreviations of the well-known SIDs which matter to the translation into SIDs
+func canonicalSID(sid string) string {
+	switch sid = strings.ToUpper(strings.TrimSpace(sid)); sid {
+	case "WD":
+		return sidEveryone
+	case "CO":
+		return sidCreatorOwner
+	case "CG":
+		return sidCreatorGroup
+	}
+	return sid
+}
+
+// isWellKnownGroupSID tells whether a SID is one of the builtin groups, such as Administrators or Authenticated Users,
+// which Windows has on every machine
+func isWellKnownGroupSID(sid string) bool {
+	return strings.HasPrefix(sid, "S-1-5-32-") || sid == "S-1-5-11" || sid == "S-1-5-4"
+}
+
+// posixACLBuilder collects the permissions of the entries of one POSIX ACL, in the way Windows evaluates ACEs:
+// the first ACE of a principal that mentions a right decides whether the principal has it.
+type posixACLBuilder struct {
+	allowed, denied map[string]uint16 // keyed by the entry without its permissions, e.g. "user::" or "group:<SID>:"
+	named           []string          // the keys of named entries, in the order they were first seen
+}
+
+func newPOSIXACLBuilder() *posixACLBuilder {
+	return &posixACLBuilder{allowed: map[string]uint16{}, denied: map[string]uint16{}}
+}
+
+func (b *posixACLBuilder) apply(key string, perms uint16, allow bool) {
+	allowed, seen := b.allowed[key]
+	if !seen && !strings.HasSuffix(key, "::") {
+		b.named = append(b.named, key)
+	}
+
+	undecided := perms &^ (allowed | b.denied[key])
+	if allow {
+		allowed |= undecided
+	} else {
+		b.denied[key] |= undecided
+	}
+	b.allowed[key] = allowed // recorded even when empty, so that the entry is still listed
+}
+
+func (b *posixACLBuilder) isEmpty() bool {
+	return len(b.allowed) == 0
+}
+
+// entries returns the ACL in the order ADLS Gen2 lists it, with a mask when there are named entries.
+// Everyone is the POSIX other class, and since Windows also grants its rights to everybody else, they're added to
+// every other entry, unless that entry's own principal was denied them.
+func (b *posixACLBuilder) entries(prefix string) []string {
+	everyone := b.allowed["other::"]
+	perms := func(key string) uint16 {
+		return (b.allowed[key] | everyone) &^ b.denied[key]
+	}
+
+	result := []string{prefix + "user::" + posixPermissionString(perms("user::"))}
+	mask := perms("group::")
+	for _, key := range b.named {
+		if strings.HasPrefix(key, "user:") {
+			result = append(result, prefix+key+posixPermissionString(perms(key)))
+			mask |= perms(key)
+		}
+	}
+	result = append(result, prefix+"group::"+posixPermissionString(perms("group::")))
+	for _, key := range b.named {
+		if strings.HasPrefix(key, "group:") {
+			result = append(result, prefix+key+posixPermissionString(perms(key)))
+			mask |= perms(key)
+		}
+	}
+	if len(b.named) > 0 {
+		result = append(result, prefix+"mask::"+posixPermissionString(mask))
+	}
+	return append(result, prefix+"other::"+posixPermissionString(everyone))
+}
+
+// posixAccessControlFromSDDL translates a Windows security descriptor to the closest ADLS Gen2 access control.
+// The owner's ACEs become the user:: entry, the owning group's the group:: entry, and Everyone's the other:: entry.
+// Other principals become named entries, which like their owner and group are only included if asked for, and are
+// left as SIDs for mapPOSIXPrincipals. ACEs inherited by both files and subfolders become the default ACL of a folder.
+// Whatever POSIX can't represent is returned, one line per ACE, so that it can be logged:
+// deny ACEs only take rights away from their own principal, and ACEs of other types, or with inheritance that
+// default ACLs can't express, are left out.
+func posixAccessControlFromSDDL(sd sddl.SDDLString, isDir, includeOwnership bool, isGroupSID func(string) bool) (result azbfs.BlobFSAccessControl, unrepresentable []string) {
+	owner, group := canonicalSID(sd.OwnerSID), canonicalSID(sd.GroupSID)
+	if includeOwnership {
+		result.Owner, result.Group = owner, group
+	}
+
+	if strings.Contains(strings.ToUpper(sd.DACL.Flags), "NO_ACCESS_CONTROL") {
+		// a null DACL gives everybody full access
+		result.ACL = "user::rwx,group::rwx,other::rwx"
+		if isDir {
+			result.ACL += ",default:user::rwx,default:group::rwx,default:other::rwx"
+		}
+		return result, nil
+	}
+
+	access, defaults := newPOSIXACLBuilder(), newPOSIXACLBuilder()
+	for _, ace := range sd.DACL.ACLEntries {
+		aceString := "(" + strings.Join(ace.Sections, ";") + ")"
+		report := func(format string, a ...interface{}) {
+			unrepresentable = append(unrepresentable, aceString+" "+fmt.Sprintf(format, a...))
+		}
+
+		if len(ace.Sections) <= aceFieldSID {
+			report("is malformed")
+			continue
+		}
+		aceType := strings.ToUpper(strings.TrimSpace(ace.Sections[aceFieldType]))
+		if aceType != "A" && aceType != "D" {
+			report("is of a type that POSIX ACLs don't have")
+			continue
+		}
+		allow := aceType == "A"
+		mask, err := parseSDDLAccessMask(strings.TrimSpace(ace.Sections[aceFieldRights]))
+		if err != nil {
+			report("has %s", err)
+			continue
+		}
+
+		flags := map[string]bool{}
+		aceFlags := strings.ToUpper(strings.TrimSpace(ace.Sections[aceFieldFlags]))
+		for i := 0; i+2 <= len(aceFlags); i += 2 {
+			flags[aceFlags[i:i+2]] = true
+		}
+
+		sid := canonicalSID(ace.Sections[aceFieldSID])
+		var keys, defaultKeys []string
+		switch sid {
+		case sidCreatorOwner:
+			defaultKeys = []string{"user::"} // only stands for the owner of what inherits it
+		case sidCreatorGroup:
+			defaultKeys = []string{"group::"}
+		case sidEveryone:
+			keys = []string{"other::"}
+		default:
+			named := "user:" + sid + ":"
+			if isGroupSID(sid) {
+				named = "group:" + sid + ":"
+			}
+			if sid == owner {
+				keys = append(keys, "user::")
+			}
+			if sid == group {
+				keys = append(keys, "group::")
+			}
+			// named entries hold principals, so they're only included if asked for. What's inherited stays with the
+			// principal, rather than going to the owner of whatever inherits it, so it's always a named entry.
+			if includeOwnership {
+				if keys == nil {
+					keys = []string{named}
+				}
+				defaultKeys = []string{named}
+			}
+		}
+
+		if !allow {
+			report("denies rights, which POSIX ACLs can't, so they were only taken away from the principal's own entry")
+		} else if mask&(winWriteDAC|winWriteOwner|winGenericAll) != 0 && (len(keys) == 0 || keys[0] != "user::") && sid != sidCreatorOwner {
+			report("lets a principal other than the owner change permissions or ownership, which POSIX only lets the owner do")
+		}
+
+		perms := posixPermsFromAccessMask(mask)
+		if !flags["IO"] {
+			for _, key := range keys {
+				access.apply(key, perms, allow)
+			}
+		}
+		if !isDir || !(flags["OI"] || flags["CI"]) {
+			continue // files don't pass anything on
+		}
+		switch {
+		case !flags["OI"] || !flags["CI"]:
+			report("is only inherited by files or only by subfolders, but a default ACL is inherited by both, so it was left out of the default ACL")
+		case flags["NP"]:
+			report("is only inherited by immediate children, but a default ACL is passed on further, so it was left out of the default ACL")
+		default:
+			for _, key := range defaultKeys {
+				defaults.apply(key, perms, allow)
+			}
+		}
+	}
+
+	entries := access.entries("")
+	if !defaults.isEmpty() {
+		entries = append(entries, defaults.entries("default:")...)
+	}
+	result.ACL = strings.Join(entries, ",")
+	return result, unrepresentable
+}
@@ -47,6 +47,9 @@ type appendBlobSenderBase struct {
 	blobTagsToApply azblob.BlobTagsMap
 
 	soleChunkFuncSemaphore *semaphore.Weighted
+
+	// set by RemoteFileExists, when the destination exists and its account keeps blob versions
+	destKeepsVersions bool
 }
 
 type appendBlockFunc = func()
@@ -103,7 +106,13 @@ func (s *appendBlobSenderBase) NumChunks() uint32 {
 }
 
 func (s *appendBlobSenderBase) RemoteFileExists() (bool, time.Time, error) {
-	return remoteObjectExists(s.destAppendBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{}))
+	props, err := s.destAppendBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	s.destKeepsVersions = err == nil && keepsVersions(props)
+	return remoteObjectExists(props, err)
+}
+
+func (s *appendBlobSenderBase) DestinationKeepsVersions() bool {
+	return s.destKeepsVersions
 }
 
 // Returns a chunk-func for sending append blob to remote
@@ -161,6 +170,10 @@ func (s *appendBlobSenderBase) Prologue(ps common.PrologueState) (destinationMod
 	return
 }
 
+func (s *appendBlobSenderBase) SetPropertiesOfExistingFile() error {
+	return setPropertiesOfExistingBlob(s.jptm.Context(), s.destAppendBlobURL.BlobURL, s.metadataToApply, s.blobTagsToApply)
+}
+
 func (s *appendBlobSenderBase) Epilogue() {
 	// Empty function because you don't have to commit on an append blob
 }
@@ -359,6 +359,16 @@ func (u *azureFileSenderBase) EnsureFolderExists() error {
 }
 
 func (u *azureFileSenderBase) SetFolderProperties() error {
+	return u.setFolderProperties(false)
+}
+
+// SetPropertiesOfExistingFolder is SetFolderProperties for permissions-only transfers, which merge the source's metadata
+// into what the directory already has rather than replace it
+func (u *azureFileSenderBase) SetPropertiesOfExistingFolder() error {
+	return u.setFolderProperties(true)
+}
+
+func (u *azureFileSenderBase) setFolderProperties(mergeMetadata bool) error {
 	info := u.jptm.Info()
 
 	_, err := u.addPermissionsToHeaders(info, u.dirURL().URL())
@@ -371,7 +381,15 @@ func (u *azureFileSenderBase) SetFolderProperties() error {
 		return err
 	}
 
-	_, err = u.dirURL().SetMetadata(u.ctx, u.metadataToApply)
+	metadata := u.metadataToApply
+	if mergeMetadata {
+		props, err := u.dirURL().GetProperties(u.ctx)
+		if err != nil {
+			return err
+		}
+		metadata = mergeFileMetadata(props.NewMetadata(), metadata)
+	}
+	_, err = u.dirURL().SetMetadata(u.ctx, metadata)
 	if err != nil {
 		return err
 	}
@@ -383,6 +401,40 @@ func (u *azureFileSenderBase) SetFolderProperties() error {
 	return err
 }
 
+func (u *azureFileSenderBase) SetPropertiesOfExistingFile() error {
+	info := u.jptm.Info()
+
+	_, err := u.addPermissionsToHeaders(info, u.fileURL().URL())
+	if err != nil {
+		return err
+	}
+
+	_, err = u.addSMBPropertiesToHeaders(info, u.fileURL().URL())
+	if err != nil {
+		return err
+	}
+
+	// setting metadata replaces all of it, and setting the SMB properties sets the content headers too,
+	// so keep what the file already has
+	props, err := u.fileURL().GetProperties(u.ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.fileURL().SetMetadata(u.ctx, mergeFileMetadata(props.NewMetadata(), u.metadataToApply))
+	if err != nil {
+		return err
+	}
+
+	headers := props.NewHTTPHeaders()
+	headers.SMBProperties = u.headersToApply.SMBProperties // includes the permission key
+
+	return u.DoWithOverrideReadOnly(u.ctx,
+		func() (interface{}, error) { return u.fileURL().SetHTTPHeaders(u.ctx, headers) },
+		u.fileOrDirURL,
+		u.jptm.GetForceIfReadOnly())
+}
+
 func (u *azureFileSenderBase) DirUrlToString() string {
 	dirUrl := azfile.NewFileURLParts(u.dirURL().URL()).URL()
 	return dirUrl.String()
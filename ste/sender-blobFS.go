@@ -23,6 +23,7 @@ package ste
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/url"
 	"time"
 
@@ -41,6 +42,7 @@ type blobFSSenderBase struct {
 	pacer               pacer
 	creationTimeHeaders *azbfs.BlobFSHTTPHeaders
 	flushThreshold      int64
+	sip                 ISourceInfoProvider
 }
 
 func newBlobFSSenderBase(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (*blobFSSenderBase, error) {
@@ -78,6 +80,7 @@ func newBlobFSSenderBase(jptm IJobPartTransferMgr, destination string, p pipelin
 		pacer:               pacer,
 		creationTimeHeaders: &headers,
 		flushThreshold:      chunkSize * int64(ADLSFlushThreshold),
+		sip:                 sip,
 	}, nil
 }
 
@@ -153,6 +156,11 @@ func (u *blobFSSenderBase) Prologue(state common.PrologueState) (destinationModi
 		u.jptm.FailActiveUpload("Ensuring parent directory exists", err)
 		return
 	}
+	err = u.setImplicitDirsAccessControl(parentDir)
+	if err != nil {
+		u.jptm.FailActiveUpload("Setting access control of parent directories", err)
+		return
+	}
 
 	// Create file with the source size
 	_, err = u.fileURL().Create(u.jptm.Context(), *u.creationTimeHeaders) // "create" actually calls "create path", so if we didn't need to track folder creation, we could just let this call create the folder as needed
@@ -180,6 +188,22 @@ func (u *blobFSSenderBase) Cleanup() {
 	}
 }
 
+// flush commits the data appended to the file. It's done incrementally, to avoid timeouts on a full flush.
+func (u *blobFSSenderBase) flush(md5Hash []byte) error {
+	ss := u.jptm.Info().SourceSize
+	for i := int64(math.Min(float64(ss), float64(u.flushThreshold))); ; i = int64(math.Min(float64(ss), float64(i+u.flushThreshold))) {
+		// Close only at the end of the file, keep all uncommitted data before then.
+		_, err := u.fileURL().FlushData(u.jptm.Context(), i, md5Hash, *u.creationTimeHeaders, i != ss, i == ss)
+		if err != nil {
+			return err
+		}
+
+		if i == ss {
+			return nil
+		}
+	}
+}
+
 func (u *blobFSSenderBase) GetDestinationLength() (int64, error) {
 	prop, err := u.fileURL().GetProperties(u.jptm.Context())
 
@@ -214,8 +238,13 @@ func (u *blobFSSenderBase) doEnsureDirExists(d azbfs.DirectoryURL) error {
 }
 
 func (u *blobFSSenderBase) SetFolderProperties() error {
-	// we don't currently preserve any properties for BlobFS folders
-	return nil
+	// the only properties we preserve for BlobFS folders are their POSIX permissions
+	return u.setPathAccessControl()
+}
+
+func (u *blobFSSenderBase) SetPropertiesOfExistingFile() error {
+	// the only properties we preserve for BlobFS files, apart from those set when they're created, are their POSIX permissions
+	return u.setPathAccessControl()
 }
 
 func (u *blobFSSenderBase) DirUrlToString() string {
@@ -23,7 +23,6 @@ package ste
 import (
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
-	"math"
 )
 
 type blobFSUploader struct {
@@ -71,24 +70,19 @@ func (u *blobFSUploader) Epilogue() {
 
 	// flush
 	if jptm.IsLive() {
-		ss := jptm.Info().SourceSize
 		md5Hash, ok := <-u.md5Channel
 		if ok {
-			// Flush incrementally to avoid timeouts on a full flush
-			for i := int64(math.Min(float64(ss), float64(u.flushThreshold))); ; i = int64(math.Min(float64(ss), float64(i+u.flushThreshold))) {
-				// Close only at the end of the file, keep all uncommitted data before then.
-				_, err := u.fileURL().FlushData(jptm.Context(), i, md5Hash, *u.creationTimeHeaders, i != ss, i == ss)
-				if err != nil {
-					jptm.FailActiveUpload("Flushing data", err)
-					break // don't return, since need cleanup below
-				}
-
-				if i == ss {
-					break
-				}
+			if err := u.flush(md5Hash); err != nil {
+				jptm.FailActiveUpload("Flushing data", err) // don't return, since need cleanup below
 			}
 		} else {
 			jptm.FailActiveUpload("Getting hash", errNoHash) // don't return, since need cleanup below
 		}
 	}
+
+	if jptm.IsLive() {
+		if err := u.setPathAccessControl(); err != nil {
+			jptm.FailActiveUpload("Setting access control", err)
+		}
+	}
 }
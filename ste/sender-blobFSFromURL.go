@@ -0,0 +1,130 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// urlToBlobFSCopier copies between ADLS Gen2 accounts. The service has no way to append data from a URL, so each chunk
+// is read from the source path and appended to the destination one, both through the DFS endpoint, without being staged locally.
+type urlToBlobFSCopier struct {
+	blobFSSenderBase
+	srcURL url.URL
+}
+
+func newURLToBlobFSCopier(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
+	srcInfoProvider := sip.(IRemoteSourceInfoProvider) // "downcast" to the type we know it really has
+
+	senderBase, err := newBlobFSSenderBase(jptm, destination, p, pacer, sip)
+	if err != nil {
+		return nil, err
+	}
+
+	srcURL, err := srcInfoProvider.PreSignedSourceURL()
+	if err != nil {
+		return nil, err
+	}
+
+	return &urlToBlobFSCopier{blobFSSenderBase: *senderBase, srcURL: *srcURL}, nil
+}
+
+func (u *urlToBlobFSCopier) GenerateCopyFunc(id common.ChunkID, blockIndex int32, adjustedChunkSize int64, chunkIsWholeFile bool) chunkFunc {
+	return createSendToRemoteChunkFunc(u.jptm, id, func() {
+		if u.jptm.Info().SourceSize == 0 {
+			// nothing to do, since this is a dummy chunk in a zero-size file, and the prologue will have done all the real work
+			return
+		}
+
+		u.jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
+		if err := u.pacer.RequestTrafficAllocation(u.jptm.Context(), adjustedChunkSize); err != nil {
+			u.jptm.FailActiveUpload("Pacing block (global level)", err)
+		}
+		if err := u.copyRange(id, adjustedChunkSize); err != nil {
+			u.jptm.FailActiveS2SCopy("Copying range", err)
+			return
+		}
+	})
+}
+
+// copyRange reads a range of the source and appends it to the destination. The range is held in RAM, so that appending
+// it can be retried, and counts against the RAM budget like other chunks.
+func (u *urlToBlobFSCopier) copyRange(id common.ChunkID, length int64) error {
+	jptm := u.jptm
+
+	// relaxed, as for retries, since we're on a worker goroutine that chunks holding RAM may be waiting for
+	if err := jptm.CacheLimiter().WaitUntilAdd(jptm.Context(), length, func() bool { return true }); err != nil {
+		return err
+	}
+	defer jptm.CacheLimiter().Remove(length)
+	buffer := jptm.SlicePool().RentSlice(length)
+	defer jptm.SlicePool().ReturnSlice(buffer)
+
+	srcFileURL := azbfs.NewFileURL(u.srcURL, jptm.SourceProviderPipeline())
+	get, err := srcFileURL.Download(jptm.Context(), id.OffsetInFile(), length)
+	if err != nil {
+		return err
+	}
+
+	// as with downloads, make sure the source doesn't change while it's being read
+	if lmt, err := time.Parse(time.RFC1123, get.LastModified()); err == nil && !lmt.Equal(jptm.LastModifiedTime().In(lmt.Location())) {
+		get.Response().Body.Close()
+		return errors.New("the source file was modified during the transfer")
+	}
+
+	body := get.Body(azbfs.RetryReaderOptions{
+		MaxRetryRequests: MaxRetryPerDownloadBody,
+		NotifyFailedRead: common.NewReadLogFunc(jptm, &u.srcURL),
+	})
+	defer body.Close()
+	if _, err = io.ReadFull(body, buffer); err != nil {
+		return err
+	}
+
+	_, err = u.fileURL().AppendData(jptm.Context(), id.OffsetInFile(), bytes.NewReader(buffer)) // note: AppendData is really UpdatePath with "append" action
+	return err
+}
+
+func (u *urlToBlobFSCopier) Epilogue() {
+	jptm := u.jptm
+
+	if jptm.IsLive() {
+		// the data doesn't pass through an MD5 hasher of ours, so the source's hash, if it has one, is carried over
+		if err := u.flush(jptm.Info().SrcHTTPHeaders.ContentMD5); err != nil {
+			jptm.FailActiveS2SCopy("Flushing data", err)
+		}
+	}
+
+	if jptm.IsLive() {
+		if err := u.setPathAccessControl(); err != nil {
+			jptm.FailActiveS2SCopy("Setting access control", err)
+		}
+	}
+}
@@ -56,6 +56,12 @@ type blockBlobSenderBase struct {
 
 	atomicPutListIndicator int32
 	muBlockIDs             *sync.Mutex
+
+	// commitOnly is set when an earlier run of the job staged all of the blocks but didn't commit them
+	commitOnly bool
+
+	// set by RemoteFileExists, when the destination exists and its account keeps blob versions
+	destKeepsVersions bool
 }
 
 func getVerifiedChunkParams(transferInfo TransferInfo, memLimit int64) (chunkSize int64, numChunks uint32, err error) {
@@ -153,16 +159,74 @@ func (s *blockBlobSenderBase) NumChunks() uint32 {
 }
 
 func (s *blockBlobSenderBase) RemoteFileExists() (bool, time.Time, error) {
-	return remoteObjectExists(s.destBlockBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{}))
+	props, err := s.destBlockBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	s.destKeepsVersions = err == nil && keepsVersions(props)
+	return remoteObjectExists(props, err)
+}
+
+func (s *blockBlobSenderBase) DestinationKeepsVersions() bool {
+	return s.destKeepsVersions
 }
 
 func (s *blockBlobSenderBase) Prologue(ps common.PrologueState) (destinationModified bool) {
 	if s.jptm.ShouldInferContentType() {
 		s.headersToApply.ContentType = ps.GetInferredContentType(s.jptm)
 	}
+	s.commitOnly = s.canCommitStagedBlocks()
+	return false
+}
+
+// canCommitStagedBlocks says whether an earlier run of the job staged all of the blocks and then failed or crashed before
+// committing them. If so, and they're all still at the destination, they are just committed again, along with the headers
+// and metadata that are worked out afresh in this run
+func (s *blockBlobSenderBase) canCommitStagedBlocks() bool {
+	jptm := s.jptm
+	if !jptm.CommitPending() {
+		return false
+	}
+
+	blockList, err := s.destBlockBlobURL.GetBlockList(jptm.Context(), azblob.BlockListUncommitted, azblob.LeaseAccessConditions{})
+	if err == nil {
+		staged := make(map[string]int64, len(blockList.UncommittedBlocks))
+		for _, b := range blockList.UncommittedBlocks {
+			staged[b.Name] = int64(b.Size)
+		}
+		if s.allBlocksStaged(staged, jptm.Info().SourceSize) {
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "All blocks were staged by an earlier run of the job, so they will only be committed")
+			return true
+		}
+	}
+
+	// they'll have to be staged again
+	jptm.SetCommitPending(false)
 	return false
 }
 
+// allBlocksStaged says whether every one of our blocks is among those staged at the destination, with the right size
+func (s *blockBlobSenderBase) allBlocksStaged(staged map[string]int64, srcSize int64) bool {
+	for i := int32(0); i < int32(s.numChunks); i++ {
+		size := s.chunkSize
+		if remaining := srcSize - int64(i)*s.chunkSize; remaining < size {
+			size = remaining
+		}
+		if stagedSize, ok := staged[s.generateEncodedBlockID(i)]; !ok || stagedSize != size {
+			return false
+		}
+	}
+	return true
+}
+
+// generateAlreadyStaged returns a chunk-func for a block that an earlier run of the job staged, which only has to be
+// listed in the commit. The reader, if there is one, was only needed to hash the block
+func (s *blockBlobSenderBase) generateAlreadyStaged(id common.ChunkID, blockIndex int32, reader common.SingleChunkReader) chunkFunc {
+	return createSendToRemoteChunkFunc(s.jptm, id, func() {
+		if reader != nil {
+			defer reader.Close()
+		}
+		s.setBlockID(blockIndex, s.generateEncodedBlockID(blockIndex))
+	})
+}
+
 func (s *blockBlobSenderBase) Epilogue() {
 	jptm := s.jptm
 
@@ -191,10 +255,13 @@ func (s *blockBlobSenderBase) Epilogue() {
 			blobTags = nil
 		}
 
+		// from here until the commit succeeds, a resumed job can just commit the blocks rather than stage them again
+		jptm.SetCommitPending(true)
 		if _, err := s.destBlockBlobURL.CommitBlockList(jptm.Context(), blockIDs, s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{}, s.destBlobTier, blobTags, azblob.ClientProvidedKeyOptions{}); err != nil {
 			jptm.FailActiveSend("Committing block list", err)
 			return
 		}
+		jptm.SetCommitPending(false)
 
 		if separateSetTagsRequired {
 			if _, err := s.destBlockBlobURL.SetTags(jptm.Context(), nil, nil, nil, s.blobTagsToApply); err != nil {
@@ -204,11 +271,18 @@ func (s *blockBlobSenderBase) Epilogue() {
 	}
 }
 
+func (s *blockBlobSenderBase) SetPropertiesOfExistingFile() error {
+	return setPropertiesOfExistingBlob(s.jptm.Context(), s.destBlockBlobURL.BlobURL, s.metadataToApply, s.blobTagsToApply)
+}
+
 func (s *blockBlobSenderBase) Cleanup() {
 	jptm := s.jptm
 
 	// Cleanup
-	if jptm.IsDeadInflight() {
+	if jptm.IsDeadInflight() && jptm.CommitPending() {
+		// only the commit failed, so leave the staged blocks for the job to commit when it's resumed
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, "Leaving the staged blocks at the destination for the job to commit when resumed")
+	} else if jptm.IsDeadInflight() {
 		// there is a possibility that some uncommitted blocks will be there
 		// Delete the uncommitted blobs
 		deletionContext, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
@@ -242,7 +316,9 @@ func (s *blockBlobSenderBase) setBlockID(index int32, value string) {
 	s.blockIDs[index] = value
 }
 
-func (s *blockBlobSenderBase) generateEncodedBlockID() string {
-	blockID := common.NewUUID().String()
+// generateEncodedBlockID returns the ID of the block at the given index. It's the same in every run of the job, so that
+// a run can tell which of the blocks it needs were staged by an earlier one
+func (s *blockBlobSenderBase) generateEncodedBlockID(index int32) string {
+	blockID := fmt.Sprintf("%s%08x", s.jptm.BlockIDPrefix(), uint32(index))
 	return base64.StdEncoding.EncodeToString([]byte(blockID))
 }
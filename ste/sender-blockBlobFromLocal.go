@@ -22,6 +22,8 @@ package ste
 
 import (
 	"bytes"
+	"crypto/md5"
+	"io"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -58,6 +60,9 @@ func (u *blockBlobUploader) GenerateUploadFunc(id common.ChunkID, blockIndex int
 		return u.generatePutWholeBlob(id, blockIndex, reader)
 	} else {
 		setPutListNeed(&u.atomicPutListIndicator, putListNeeded)
+		if u.commitOnly {
+			return u.generateAlreadyStaged(id, blockIndex, reader)
+		}
 		return u.generatePutBlock(id, blockIndex, reader)
 	}
 }
@@ -66,7 +71,7 @@ func (u *blockBlobUploader) GenerateUploadFunc(id common.ChunkID, blockIndex int
 func (u *blockBlobUploader) generatePutBlock(id common.ChunkID, blockIndex int32, reader common.SingleChunkReader) chunkFunc {
 	return createSendToRemoteChunkFunc(u.jptm, id, func() {
 		// step 1: generate block ID
-		encodedBlockID := u.generateEncodedBlockID()
+		encodedBlockID := u.generateEncodedBlockID(blockIndex)
 
 		// step 2: save the block ID into the list of block IDs
 		u.setBlockID(blockIndex, encodedBlockID)
@@ -90,19 +95,8 @@ func (u *blockBlobUploader) generatePutWholeBlob(id common.ChunkID, blockIndex i
 
 		// Upload the blob
 		jptm.LogChunkStatus(id, common.EWaitReason.Body())
-		var err error
-		if !ValidateTier(jptm, u.destBlobTier, u.destBlockBlobURL.BlobURL, u.jptm.Context()) {
-			u.destBlobTier = azblob.DefaultAccessTier
-		}
-
-		blobTags := u.blobTagsToApply
-		separateSetTagsRequired := separateSetTagsRequired(blobTags)
-		if separateSetTagsRequired || len(blobTags) == 0 {
-			blobTags = nil
-		}
-
 		if jptm.Info().SourceSize == 0 {
-			_, err = u.destBlockBlobURL.Upload(jptm.Context(), bytes.NewReader(nil), u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{}, u.destBlobTier, blobTags, azblob.ClientProvidedKeyOptions{})
+			u.putWholeBlob(bytes.NewReader(nil))
 		} else {
 			// File with content
 
@@ -115,24 +109,57 @@ func (u *blockBlobUploader) generatePutWholeBlob(id common.ChunkID, blockIndex i
 			u.headersToApply.ContentMD5 = md5Hash
 
 			// Upload the file
-			body := newPacedRequestBody(jptm.Context(), reader, u.pacer)
-			_, err = u.destBlockBlobURL.Upload(jptm.Context(), body, u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{}, u.destBlobTier, blobTags, azblob.ClientProvidedKeyOptions{})
+			u.putWholeBlob(newPacedRequestBody(jptm.Context(), reader, u.pacer))
 		}
+	})
+}
 
-		// if the put blob is a failure, update the transfer status to failed
-		if err != nil {
-			jptm.FailActiveUpload("Uploading blob", err)
-			return
-		}
+// GenerateSmallFileUploadFunc generates PUT Blob for a small file that has already been read into memory.
+// Since we have all the data, the MD5 is computed here rather than being sent on the md5Channel.
+func (u *blockBlobUploader) GenerateSmallFileUploadFunc(id common.ChunkID, data []byte) chunkFunc {
+	setPutListNeed(&u.atomicPutListIndicator, putListNotNeeded)
 
-		if separateSetTagsRequired {
-			if _, err := u.destBlockBlobURL.SetTags(jptm.Context(), nil, nil, nil, u.blobTagsToApply); err != nil {
-				u.jptm.Log(pipeline.LogWarning, err.Error())
-			}
+	return createSendToRemoteChunkFunc(u.jptm, id, func() {
+		jptm := u.jptm
+
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		if jptm.ShouldPutMd5() && len(data) > 0 {
+			md5Hash := md5.Sum(data)
+			u.headersToApply.ContentMD5 = md5Hash[:]
 		}
+		u.putWholeBlob(newPacedRequestBody(jptm.Context(), bytes.NewReader(data), u.pacer))
 	})
 }
 
+// putWholeBlob sends the blob, with its properties, in a single request
+func (u *blockBlobUploader) putWholeBlob(body io.ReadSeeker) {
+	jptm := u.jptm
+
+	if !ValidateTier(jptm, u.destBlobTier, u.destBlockBlobURL.BlobURL, u.jptm.Context()) {
+		u.destBlobTier = azblob.DefaultAccessTier
+	}
+
+	blobTags := u.blobTagsToApply
+	separateSetTagsRequired := separateSetTagsRequired(blobTags)
+	if separateSetTagsRequired || len(blobTags) == 0 {
+		blobTags = nil
+	}
+
+	_, err := u.destBlockBlobURL.Upload(jptm.Context(), body, u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{}, u.destBlobTier, blobTags, azblob.ClientProvidedKeyOptions{})
+
+	// if the put blob is a failure, update the transfer status to failed
+	if err != nil {
+		jptm.FailActiveUpload("Uploading blob", err)
+		return
+	}
+
+	if separateSetTagsRequired {
+		if _, err := u.destBlockBlobURL.SetTags(jptm.Context(), nil, nil, nil, u.blobTagsToApply); err != nil {
+			u.jptm.Log(pipeline.LogWarning, err.Error())
+		}
+	}
+}
+
 func (u *blockBlobUploader) Epilogue() {
 	jptm := u.jptm
 
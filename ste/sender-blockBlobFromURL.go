@@ -23,7 +23,10 @@ package ste
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"net/url"
+	"sync/atomic"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -35,6 +38,9 @@ type urlToBlockBlobCopier struct {
 	blockBlobSenderBase
 
 	srcURL url.URL
+
+	// set once the destination has refused to read the source itself, after which blocks are copied through this machine
+	atomicStreamThroughClient int32
 }
 
 func newURLToBlockBlobCopier(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, srcInfoProvider IRemoteSourceInfoProvider) (s2sCopier, error) {
@@ -85,6 +91,9 @@ func (c *urlToBlockBlobCopier) GenerateCopyFunc(id common.ChunkID, blockIndex in
 
 	}
 	setPutListNeed(&c.atomicPutListIndicator, putListNeeded)
+	if c.commitOnly {
+		return c.generateAlreadyStaged(id, blockIndex, nil)
+	}
 	return c.generatePutBlockFromURL(id, blockIndex, adjustedChunkSize)
 }
 
@@ -155,7 +164,7 @@ func (c *urlToBlockBlobCopier) generateCreateEmptyBlob(id common.ChunkID) chunkF
 func (c *urlToBlockBlobCopier) generatePutBlockFromURL(id common.ChunkID, blockIndex int32, adjustedChunkSize int64) chunkFunc {
 	return createSendToRemoteChunkFunc(c.jptm, id, func() {
 		// step 1: generate block ID
-		encodedBlockID := c.generateEncodedBlockID()
+		encodedBlockID := c.generateEncodedBlockID(blockIndex)
 
 		// step 2: save the block ID into the list of block IDs
 		c.setBlockID(blockIndex, encodedBlockID)
@@ -164,26 +173,80 @@ func (c *urlToBlockBlobCopier) generatePutBlockFromURL(id common.ChunkID, blockI
 		c.jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
 
 		// Set the latest service version from sdk as service version in the context, to use StageBlockFromURL API
-		ctxWithLatestServiceVersion := context.WithValue(c.jptm.Context(), ServiceAPIVersionOverride, azblob.ServiceVersion)
+		ctxWithLatestServiceVersion := withCopyFromURLServiceVersion(c.jptm)
 
 		if err := c.pacer.RequestTrafficAllocation(c.jptm.Context(), adjustedChunkSize); err != nil {
 			c.jptm.FailActiveUpload("Pacing block", err)
 		}
-		_, err := c.destBlockBlobURL.StageBlockFromURL(ctxWithLatestServiceVersion, encodedBlockID, c.srcURL,
-			id.OffsetInFile(), adjustedChunkSize, azblob.LeaseAccessConditions{}, azblob.ModifiedAccessConditions{}, azblob.ClientProvidedKeyOptions{})
-		if err != nil {
-			c.jptm.FailActiveSend("Staging block from URL", err)
+		if atomic.LoadInt32(&c.atomicStreamThroughClient) == 0 {
+			_, err := c.destBlockBlobURL.StageBlockFromURL(ctxWithLatestServiceVersion, encodedBlockID, c.srcURL,
+				id.OffsetInFile(), adjustedChunkSize, azblob.LeaseAccessConditions{}, azblob.ModifiedAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+			if err == nil {
+				return
+			}
+			if c.jptm.FromTo() != common.EFromTo.BlobBlob() || !urlCopyRefused(err) {
+				c.jptm.FailActiveSend("Staging block from URL", err)
+				return
+			}
+			if atomic.CompareAndSwapInt32(&c.atomicStreamThroughClient, 0, 1) {
+				c.jptm.Log(pipeline.LogWarning, fmt.Sprintf("The destination couldn't read the source itself, so the blocks of %s will be copied through this machine instead: %s",
+					c.jptm.Info().Source, err))
+			}
+		}
+
+		if err := c.stageBlockThroughClient(id, encodedBlockID, adjustedChunkSize); err != nil {
+			c.jptm.FailActiveSend("Staging block through this machine", err)
 			return
 		}
 	})
 }
 
+// urlCopyRefused tells whether the destination failed a copy from URL because it couldn't read the source,
+// e.g. since the source's credentials only work for this machine, or the source is behind a firewall
+func urlCopyRefused(err error) bool {
+	stgErr, ok := err.(azblob.StorageError)
+	return ok && stgErr.ServiceCode() == azblob.ServiceCodeCannotVerifyCopySource
+}
+
+// stageBlockThroughClient downloads the block from the source and stages it, for when the destination can't read the
+// source. The block is held in RAM, so that staging it can be retried, and counts against the RAM budget like other chunks.
+func (c *urlToBlockBlobCopier) stageBlockThroughClient(id common.ChunkID, encodedBlockID string, length int64) error {
+	jptm := c.jptm
+
+	// relaxed, as for retries, since we're on a worker goroutine that chunks holding RAM may be waiting for
+	if err := jptm.CacheLimiter().WaitUntilAdd(jptm.Context(), length, func() bool { return true }); err != nil {
+		return err
+	}
+	defer jptm.CacheLimiter().Remove(length)
+	buffer := jptm.SlicePool().RentSlice(length)
+	defer jptm.SlicePool().ReturnSlice(buffer)
+
+	// as with downloads, make sure the source doesn't change while it's being read
+	accessConditions := azblob.BlobAccessConditions{ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfUnmodifiedSince: jptm.LastModifiedTime()}}
+	srcBlobURL := azblob.NewBlobURL(c.srcURL, jptm.SourceProviderPipeline())
+	get, err := srcBlobURL.Download(jptm.Context(), id.OffsetInFile(), length, accessConditions, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return err
+	}
+	body := get.Body(azblob.RetryReaderOptions{
+		MaxRetryRequests: MaxRetryPerDownloadBody,
+		NotifyFailedRead: common.NewReadLogFunc(jptm, &c.srcURL),
+	})
+	defer body.Close()
+	if _, err = io.ReadFull(body, buffer); err != nil {
+		return err
+	}
+
+	_, err = c.destBlockBlobURL.StageBlock(jptm.Context(), encodedBlockID, bytes.NewReader(buffer), azblob.LeaseAccessConditions{}, nil, azblob.ClientProvidedKeyOptions{})
+	return err
+}
+
 func (c *urlToBlockBlobCopier) generateStartCopyBlobFromURL(id common.ChunkID, blockIndex int32, adjustedChunkSize int64) chunkFunc {
 	return createSendToRemoteChunkFunc(c.jptm, id, func() {
 
 		c.jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
 
-		ctxWithLatestServiceVersion := context.WithValue(c.jptm.Context(), ServiceAPIVersionOverride, azblob.ServiceVersion)
+		ctxWithLatestServiceVersion := withCopyFromURLServiceVersion(c.jptm)
 
 		if err := c.pacer.RequestTrafficAllocation(c.jptm.Context(), adjustedChunkSize); err != nil {
 			c.jptm.FailActiveUpload("Pacing block", err)
@@ -63,6 +63,9 @@ type pageBlobSenderBase struct {
 	// there was a potential for us to not zero out 512b segments that we'd prefetched all zeroes for.
 	// This only posed danger when there was already data in one of these segments.
 	destPageRangeOptimizer *pageRangeOptimizer
+
+	// set by RemoteFileExists, when the destination exists and its account keeps blob versions
+	destKeepsVersions bool
 }
 
 const (
@@ -102,7 +105,7 @@ func newPageBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pipel
 	// This is only necessary if our destination is a managed disk impexp account.
 	// Read the in struct explanation if necessary.
 	var destRangeOptimizer *pageRangeOptimizer
-	if isInManagedDiskImportExportAccount(*destURL) {
+	if isManagedDiskDestination(jptm.FromTo(), *destURL) {
 		destRangeOptimizer = newPageRangeOptimizer(destPageBlobURL,
 			context.WithValue(jptm.Context(), ServiceAPIVersionOverride, azblob.ServiceVersion))
 	}
@@ -156,8 +159,14 @@ func isInLegacyDiskExportAccount(u url.URL) bool {
 	return strings.HasPrefix(u.Host, legacyDiskExportPrefix) // md-....
 }
 
+// isManagedDiskDestination says whether the destination is a managed disk, either because the job says so, or because
+// it's in an import/export account (which is how managed disks were recognized before they were a location of their own)
+func isManagedDiskDestination(fromTo common.FromTo, u url.URL) bool {
+	return fromTo.To() == common.ELocation.ManagedDisk() || isInManagedDiskImportExportAccount(u)
+}
+
 func (s *pageBlobSenderBase) isInManagedDiskImportExportAccount() bool {
-	return isInManagedDiskImportExportAccount(s.destPageBlobURL.URL())
+	return isManagedDiskDestination(s.jptm.FromTo(), s.destPageBlobURL.URL())
 }
 
 func (s *pageBlobSenderBase) SendableEntityType() common.EntityType {
@@ -173,7 +182,13 @@ func (s *pageBlobSenderBase) NumChunks() uint32 {
 }
 
 func (s *pageBlobSenderBase) RemoteFileExists() (bool, time.Time, error) {
-	return remoteObjectExists(s.destPageBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{}))
+	props, err := s.destPageBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	s.destKeepsVersions = err == nil && keepsVersions(props)
+	return remoteObjectExists(props, err)
+}
+
+func (s *pageBlobSenderBase) DestinationKeepsVersions() bool {
+	return s.destKeepsVersions
 }
 
 var premiumPageBlobTierRegex = regexp.MustCompile(`P\d+`)
@@ -265,6 +280,10 @@ func (s *pageBlobSenderBase) Prologue(ps common.PrologueState) (destinationModif
 	return
 }
 
+func (s *pageBlobSenderBase) SetPropertiesOfExistingFile() error {
+	return setPropertiesOfExistingBlob(s.jptm.Context(), s.destPageBlobURL.BlobURL, s.metadataToApply, s.blobTagsToApply)
+}
+
 func (s *pageBlobSenderBase) Epilogue() {
 	_ = s.filePacer.Close() // release resources
 }
@@ -22,6 +22,7 @@ package ste
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
@@ -77,30 +78,72 @@ func (u *pageBlobUploader) GenerateUploadFunc(id common.ChunkID, blockIndex int3
 				jptm.Log(pipeline.LogDebug,
 					fmt.Sprintf("Not uploading range from %d to %d,  all bytes are zero",
 						id.OffsetInFile(), id.OffsetInFile()+reader.Length()))
+				jptm.MarkEmptyPagesSkipped(pagesIn(reader.Length()))
 				return
 			}
 		}
 
+		// the chunk may still have long runs of empty pages (e.g. the unused parts of a sparse VHD), which we leave out too
+		dataRanges, emptyPagesSkipped := u.pagesToUpload(id, reader)
+		dataLength := int64(0)
+		for _, r := range dataRanges {
+			dataLength += r.End - r.Start + 1
+		}
+
 		// control rate of sending (since page blobs can effectively have per-blob throughput limits)
 		// Note that this level of control here is specific to the individual page blob, and is additional
 		// to the application-wide pacing that we (optionally) do below when writing the response body.
 		jptm.LogChunkStatus(id, common.EWaitReason.FilePacer())
-		if err := u.filePacer.RequestTrafficAllocation(jptm.Context(), reader.Length()); err != nil {
+		if err := u.filePacer.RequestTrafficAllocation(jptm.Context(), dataLength); err != nil {
 			jptm.FailActiveUpload("Pacing block", err)
 		}
 
 		// send it
 		jptm.LogChunkStatus(id, common.EWaitReason.Body())
-		body := newPacedRequestBody(jptm.Context(), reader, u.pacer)
 		enrichedContext := withRetryNotification(jptm.Context(), u.filePacer)
-		_, err := u.destPageBlobURL.UploadPages(enrichedContext, id.OffsetInFile(), body, azblob.PageBlobAccessConditions{}, nil, azblob.ClientProvidedKeyOptions{})
-		if err != nil {
-			jptm.FailActiveUpload("Uploading page", err)
-			return
+		for _, r := range dataRanges {
+			var body io.ReadSeeker = reader
+			if len(dataRanges) > 1 || r.End-r.Start+1 != reader.Length() {
+				body = newChunkSection(reader, r.Start-id.OffsetInFile(), r.End-r.Start+1)
+			}
+			_, err := u.destPageBlobURL.UploadPages(enrichedContext, r.Start, newPacedRequestBody(jptm.Context(), body, u.pacer),
+				azblob.PageBlobAccessConditions{}, nil, azblob.ClientProvidedKeyOptions{})
+			if err != nil {
+				jptm.FailActiveUpload("Uploading page", err)
+				return
+			}
+		}
+		if emptyPagesSkipped > 0 {
+			jptm.Log(pipeline.LogDebug, fmt.Sprintf("Uploaded range from %d to %d in %d parts, leaving out %d empty pages",
+				id.OffsetInFile(), id.OffsetInFile()+reader.Length(), len(dataRanges), emptyPagesSkipped))
+			jptm.MarkEmptyPagesSkipped(emptyPagesSkipped)
 		}
 	})
 }
 
+// pagesToUpload works out which parts of a chunk that isn't entirely empty need sending, as ranges of the file.
+// Runs of empty pages are left out if they're long enough to be worth an extra request, unless the destination
+// (a managed disk that may already hold data) has data there that they need to overwrite
+func (u *pageBlobUploader) pagesToUpload(id common.ChunkID, reader common.SingleChunkReader) (dataRanges []azblob.PageRange, emptyPagesSkipped int64) {
+	chunkStart := id.OffsetInFile()
+	next := chunkStart
+	for _, run := range reader.PrefetchedZeroRuns(azblob.PageBlobPageBytes, minEmptyPageRunSkipped) {
+		runRange := azblob.PageRange{Start: chunkStart + run.Offset, End: chunkStart + run.Offset + run.Length - 1}
+		if u.destPageRangeOptimizer != nil && u.destPageRangeOptimizer.doesRangeContainData(runRange) {
+			continue
+		}
+		if runRange.Start > next {
+			dataRanges = append(dataRanges, azblob.PageRange{Start: next, End: runRange.Start - 1})
+		}
+		next = runRange.End + 1
+		emptyPagesSkipped += pagesIn(run.Length)
+	}
+	if chunkEnd := chunkStart + reader.Length(); chunkEnd > next {
+		dataRanges = append(dataRanges, azblob.PageRange{Start: next, End: chunkEnd - 1})
+	}
+	return
+}
+
 func (u *pageBlobUploader) Epilogue() {
 	jptm := u.jptm
 
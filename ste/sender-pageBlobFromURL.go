@@ -111,9 +111,7 @@ func (c *urlToPageBlobCopier) GenerateCopyFunc(id common.ChunkID, blockIndex int
 
 		// set the latest service version from sdk as service version in the context, to use UploadPagesFromURL API.
 		// AND enrich the context for 503 (ServerBusy) detection
-		enrichedContext := withRetryNotification(
-			context.WithValue(c.jptm.Context(), ServiceAPIVersionOverride, azblob.ServiceVersion),
-			c.filePacer)
+		enrichedContext := withRetryNotification(withCopyFromURLServiceVersion(c.jptm), c.filePacer)
 
 		// upload the page (including application of global pacing. We don't have a separate wait reason for global pacing
 		// so just do it inside the S2SCopyOnWire state)
@@ -75,6 +75,20 @@ type folderSender interface {
 	DirUrlToString() string
 }
 
+/////////////////////////////////////////////////////////////////////////////////////////////////
+// permissionsOnlySender is a sender that can apply the source's permissions and properties
+// to a file that already exists at the destination, without sending any data
+/////////////////////////////////////////////////////////////////////////////////////////////////
+type permissionsOnlySender interface {
+	SetPropertiesOfExistingFile() error
+}
+
+// permissionsOnlyFolderSender is a folderSender whose folders have properties, such as metadata, that SetFolderProperties
+// would replace, where a permissions-only transfer should only update them
+type permissionsOnlyFolderSender interface {
+	SetPropertiesOfExistingFolder() error
+}
+
 type senderFactory func(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error)
 
 /////////////////////////////////////////////////////////////////////////////////////////////////
@@ -108,6 +122,15 @@ type uploader interface {
 	Md5Channel() chan<- []byte
 }
 
+// smallFileUploader is implemented by uploaders that can send a whole small file, already read into memory, in one request.
+// That bypasses block staging and the chunk buffers, which cost more than they save at such small sizes.
+type smallFileUploader interface {
+	uploader
+
+	// GenerateSmallFileUploadFunc returns a func() that will upload the whole file, whose contents are data
+	GenerateSmallFileUploadFunc(chunkID common.ChunkID, data []byte) chunkFunc
+}
+
 func newMd5Channel() chan []byte {
 	return make(chan []byte, 1) // must be buffered, so as not to hold up the goroutine running anyToRemote (which needs to start on the NEXT file after finishing its current one)
 }
@@ -170,6 +193,9 @@ func createChunkFunc(setDoneStatusOnExit bool, jptm IJobPartTransferMgr, id comm
 			}
 		}
 
+		// time spent waiting for a worker doesn't count towards the transfer's time limit, so it starts with the first chunk
+		jptm.StartTransferTimeout()
+
 		// tell the jptm that the destination should be assumed to have been modified
 		// (this is necessary for those cases where the prologue does not modify the dest, so the flag will not have been set at prologue time)
 		// It's idempotent, so we call it every time rather than, say, test OffsetInFile and assume that id.OffsetInFile == 0 will always run first.
@@ -187,7 +213,7 @@ func newBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pi
 	intendedType := override.ToAzBlobType()
 
 	if override == common.EBlobType.Detect() {
-		intendedType = inferBlobType(jptm.Info().Source, azblob.BlobBlockBlob)
+		intendedType = inferDestinationBlobType(jptm.Info().Source, destination, azblob.BlobBlockBlob)
 		// jptm.LogTransferInfo(fmt.Sprintf("Autodetected %s blob type as %s.", jptm.Info().Source , intendedType))
 		// TODO: Log these? @JohnRusk and @zezha-msft this creates quite a bit of spam in the logs but is important info.
 		// TODO: Perhaps we should log it only if it isn't a block blob?
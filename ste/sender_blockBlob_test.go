@@ -23,6 +23,8 @@ package ste
 import (
 	"fmt"
 
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
 	chk "gopkg.in/check.v1"
 )
 
@@ -58,3 +60,23 @@ func (s *blockBlobSuite) TestGetVerifiedChunkParams(c *chk.C) {
 	c.Assert(err.Error(), chk.Equals, expectedErr)
 
 }
+
+func (s *blockBlobSuite) TestMergeBlobMetadata(c *chk.C) {
+	existing := azblob.Metadata{"owner": "alice", "project": "old", "keep": "me"}
+	merged := mergeBlobMetadata(existing, azblob.Metadata{"Project": "new", "added": "yes"})
+
+	// the blob's other metadata survives, and keys differing only in case are replaced
+	c.Assert(merged, chk.DeepEquals, azblob.Metadata{"owner": "alice", "Project": "new", "keep": "me", "added": "yes"})
+	c.Assert(existing["project"], chk.Equals, "old") // the existing metadata isn't modified
+}
+
+func (s *blockBlobSuite) TestMergeFileMetadata(c *chk.C) {
+	existing := azfile.Metadata{"owner": "alice", "project": "old"}
+
+	// permissions-only transfers of Azure Files keep the file's or directory's metadata in the same way
+	merged := mergeFileMetadata(existing, azfile.Metadata{"Project": "new"})
+	c.Assert(merged, chk.DeepEquals, azfile.Metadata{"owner": "alice", "Project": "new"})
+
+	// and a source without metadata leaves it all in place
+	c.Assert(mergeFileMetadata(existing, azfile.Metadata{}), chk.DeepEquals, existing)
+}
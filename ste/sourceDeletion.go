@@ -0,0 +1,161 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// values of JobPartPlanTransfer.atomicSourceDeletion
+const (
+	sourceDeletionNone    int32 = 0
+	sourceDeletionPending int32 = 1 // the transfer succeeded, and its source is being deleted
+	sourceDeletionDone    int32 = 2
+)
+
+// errSourceChangedSinceTransfer is returned instead of deleting a source that was modified after it was read,
+// since the newer content was never transferred
+var errSourceChangedSinceTransfer = errors.New("the source was modified after it was transferred, so it was kept")
+
+// SourceDeletionPending says whether the transfer's source was to be deleted, but isn't known to have been
+func (jppt *JobPartPlanTransfer) SourceDeletionPending() bool {
+	return atomic.LoadInt32(&jppt.atomicSourceDeletion) == sourceDeletionPending
+}
+
+func (jppt *JobPartPlanTransfer) setSourceDeletion(state int32) {
+	atomic.StoreInt32(&jppt.atomicSourceDeletion, state)
+}
+
+// sourcePipeline returns the pipeline that reaches the job's source: the main one for downloads, and the source info
+// provider's for service to service copies. Uploads don't need one
+func (jpm *jobPartMgr) sourcePipeline() pipeline.Pipeline {
+	if jpm.Plan().FromTo.IsDownload() {
+		return jpm.pipeline
+	}
+	return jpm.sourceProviderPipeline
+}
+
+// deleteSourceIfMoved deletes the source of a file that has been transferred successfully, when the job is a move.
+// It's called as the transfer is reported done, which is after its destination has been committed and, if the job
+// asked for it, verified. If the source can't be deleted, the transfer fails, so that resuming the job tries again
+func (jptm *jobPartTransferMgr) deleteSourceIfMoved() {
+	jppt := jptm.jobPartPlanTransfer
+	if !jptm.jobPartMgr.Plan().DeleteSourceAfterTransfer || jppt.EntityType != common.EEntityType.File() ||
+		jppt.TransferStatus() != common.ETransferStatus.Success() {
+		return
+	}
+	if jptm.jobPartMgr.Plan().movesByRename() {
+		jppt.setSourceDeletion(sourceDeletionDone) // the rename took the file away from its source
+		return
+	}
+
+	// recorded first, so that if we crash part way, resuming knows the source may or may not still be there
+	jppt.setSourceDeletion(sourceDeletionPending)
+	info := jptm.Info()
+	fromTo := jptm.FromTo()
+	var lastModified time.Time
+	if lmt := jptm.LastModifiedTime(); lmt.After(time.Unix(0, 0)) { // otherwise the enumeration didn't find it
+		lastModified = lmt
+	}
+	if err := deleteTransferSource(jptm.Context(), fromTo.From(), info.Source, lastModified, jptm.jobPartMgr.sourcePipeline()); err != nil {
+		jptm.LogError(info.Source, "DELETE SOURCE ERROR (the file was transferred, but its source could not be deleted) ", err)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jppt.setSourceDeletion(sourceDeletionNone)
+		return
+	}
+	jppt.setSourceDeletion(sourceDeletionDone)
+	jptm.Log(pipeline.LogInfo, fmt.Sprintf("DELETE SOURCE SUCCESSFUL: %s", common.URLStringExtension(info.Source).RedactSecretQueryParamForLogging()))
+}
+
+// deleteTransferSource deletes one file at the source. A source that's already gone counts as deleted.
+// Unless lastModified is zero, a source modified since then is kept, and errSourceChangedSinceTransfer is returned
+func deleteTransferSource(ctx context.Context, from common.Location, source string, lastModified time.Time, p pipeline.Pipeline) error {
+	if from == common.ELocation.Local() {
+		if !lastModified.IsZero() {
+			info, err := os.Stat(source)
+			if os.IsNotExist(err) {
+				return nil
+			} else if err != nil {
+				return err
+			} else if !info.ModTime().Equal(lastModified) {
+				return errSourceChangedSinceTransfer
+			}
+		}
+		if err := os.Remove(source); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return err
+	}
+	switch from {
+	case common.ELocation.Blob():
+		// a blob with snapshots isn't deleted, since they'd be lost with it
+		_, err = azblob.NewBlobURL(*u, p).Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{
+			ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfUnmodifiedSince: lastModified}})
+	case common.ELocation.File():
+		// Azure Files has no conditional delete, so the source is looked at just before it's deleted
+		fileURL := azfile.NewFileURL(*u, p)
+		if !lastModified.IsZero() {
+			var props *azfile.FileGetPropertiesResponse
+			if props, err = fileURL.GetProperties(ctx); err == nil && !props.LastModified().Equal(lastModified) {
+				return errSourceChangedSinceTransfer
+			}
+		}
+		if err == nil {
+			_, err = fileURL.Delete(ctx)
+		}
+	case common.ELocation.BlobFS():
+		if lastModified.IsZero() {
+			_, err = azbfs.NewFileURL(*u, p).Delete(ctx)
+		} else {
+			_, err = azbfs.NewFileURL(*u, p).DeleteIfUnmodifiedSince(ctx, lastModified)
+		}
+	default:
+		return errors.New("sources in " + from.String() + " can't be deleted")
+	}
+	if respErr, ok := err.(responseError); ok && respErr.Response() != nil {
+		switch respErr.Response().StatusCode {
+		case http.StatusNotFound:
+			return nil
+		case http.StatusPreconditionFailed:
+			return errSourceChangedSinceTransfer
+		}
+	}
+	return err
+}
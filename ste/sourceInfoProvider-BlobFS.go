@@ -0,0 +1,82 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Source info provider for ADLS Gen2
+type blobFSSourceInfoProvider struct {
+	defaultRemoteSourceInfoProvider
+}
+
+func newBlobFSSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, error) {
+	base, err := newDefaultRemoteSourceInfoProvider(jptm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blobFSSourceInfoProvider{defaultRemoteSourceInfoProvider: *base}, nil
+}
+
+func (p *blobFSSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
+	presignedURL, err := p.PreSignedSourceURL()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	fileURL := azbfs.NewFileURL(*presignedURL, p.jptm.SourceProviderPipeline())
+	props, err := fileURL.GetProperties(p.jptm.Context())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC1123, props.LastModified())
+}
+
+// GetPOSIXAccessControl reads the access control of the source path. Since the source is itself in ADLS Gen2, its owners
+// and named entries are already Azure AD principals.
+func (p *blobFSSourceInfoProvider) GetPOSIXAccessControl(path string, includeOwnership bool) (azbfs.BlobFSAccessControl, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return azbfs.BlobFSAccessControl{}, err
+	}
+
+	var ac azbfs.BlobFSAccessControl
+	if p.EntityType() == common.EEntityType.Folder() {
+		ac, err = azbfs.NewDirectoryURL(*u, p.jptm.SourceProviderPipeline()).GetAccessControl(p.jptm.Context())
+	} else {
+		ac, err = azbfs.NewFileURL(*u, p.jptm.SourceProviderPipeline()).GetAccessControl(p.jptm.Context())
+	}
+	if err != nil {
+		return azbfs.BlobFSAccessControl{}, err
+	}
+
+	if !includeOwnership {
+		ac.Owner, ac.Group = "", ""
+	}
+	return ac, nil
+}
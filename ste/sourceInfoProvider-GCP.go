@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/minio/minio-go"
 	"golang.org/x/oauth2/google"
 	"io/ioutil"
 	"net/url"
@@ -18,6 +19,7 @@ type gcpSourceInfoProvider struct {
 	rawSourceURL *url.URL
 
 	gcpClient   *gcpUtils.Client
+	hmacClient  *minio.Client // set instead of gcpClient, when reading with an HMAC key through the XML API
 	gcpURLParts common.GCPURLParts
 }
 
@@ -37,6 +39,14 @@ func newGCPSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, er
 		return nil, err
 	}
 
+	if common.UseGCPHMACKey() {
+		p.hmacClient, err = gcpClientFactory.GetGCPHMACClient()
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	}
+
 	p.gcpClient, err = gcpClientFactory.GetGCPClient(
 		p.jptm.Context(),
 		common.CredentialInfo{
@@ -60,6 +70,9 @@ func newGCPSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, er
 }
 
 func (p *gcpSourceInfoProvider) PreSignedSourceURL() (*url.URL, error) {
+	if p.hmacClient != nil {
+		return p.hmacClient.PresignedGetObject(p.gcpURLParts.BucketName, p.gcpURLParts.ObjectKey, defaultPresignExpires, url.Values{})
+	}
 
 	conf, err := google.JWTConfigFromJSON(jsonKey)
 	if err != nil {
@@ -92,7 +105,7 @@ func (p *gcpSourceInfoProvider) Properties() (*SrcProperties, error) {
 		SrcMetadata:    p.transferInfo.SrcMetadata,
 	}
 	if p.transferInfo.S2SGetPropertiesInBackend {
-		objectInfo, err := p.gcpClient.Bucket(p.gcpURLParts.BucketName).Object(p.gcpURLParts.ObjectKey).Attrs(p.jptm.Context())
+		objectInfo, err := p.objectAttrs()
 		if err != nil {
 			return nil, err
 		}
@@ -125,9 +138,8 @@ func (p *gcpSourceInfoProvider) handleInvalidMetadataKeys(m common.Metadata) (co
 	switch p.transferInfo.S2SInvalidMetadataHandleOption {
 	case common.EInvalidMetadataHandleOption.ExcludeIfInvalid():
 		retainedMetadata, excludedMetadata, invalidKeyExists := m.ExcludeInvalidKey()
-		if invalidKeyExists && p.jptm.ShouldLog(pipeline.LogWarning) {
-			p.jptm.Log(pipeline.LogWarning,
-				fmt.Sprintf("METADATAWARNING: For source %q, invalid metadata with keys %s are excluded", p.transferInfo.Source, excludedMetadata.ConcatenatedKeys()))
+		if invalidKeyExists {
+			p.jptm.MarkPropertiesNotPreserved(fmt.Sprintf("METADATAWARNING: invalid metadata with keys %s are excluded", excludedMetadata.ConcatenatedKeys()))
 		}
 		return retainedMetadata, nil
 	case common.EInvalidMetadataHandleOption.FailIfInvalid():
@@ -155,13 +167,26 @@ func (p *gcpSourceInfoProvider) IsLocal() bool {
 }
 
 func (p *gcpSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
-	objectInfo, err := p.gcpClient.Bucket(p.gcpURLParts.BucketName).Object(p.gcpURLParts.ObjectKey).Attrs(p.jptm.Context())
+	objectInfo, err := p.objectAttrs()
 	if err != nil {
 		return time.Time{}, err
 	}
 	return objectInfo.Updated, nil
 }
 
+// objectAttrs reads the source object's attributes, through whichever API we have access to
+func (p *gcpSourceInfoProvider) objectAttrs() (*gcpUtils.ObjectAttrs, error) {
+	if p.hmacClient != nil {
+		oi, err := p.hmacClient.StatObject(p.gcpURLParts.BucketName, p.gcpURLParts.ObjectKey, minio.StatObjectOptions{})
+		if err != nil {
+			return nil, err
+		}
+		attrs := common.NewGCPObjectAttrsFromXMLAPI(p.gcpURLParts.BucketName, oi)
+		return &attrs, nil
+	}
+	return p.gcpClient.Bucket(p.gcpURLParts.BucketName).Object(p.gcpURLParts.ObjectKey).Attrs(p.jptm.Context())
+}
+
 func (p *gcpSourceInfoProvider) EntityType() common.EntityType {
 	return common.EEntityType.File() // All folders are virtual in GCP and only files exist.
 }
@@ -43,6 +43,14 @@ func (f localFileSourceInfoProvider) Properties() (*SrcProperties, error) {
 
 	headers, metadata, blobTags := f.jptm.ResourceDstData(nil) // we don't have a known MIME type yet, so pass nil for the sniffed content of thefile
 
+	// metadata computed per file by the front end (e.g. with --metadata-rules) takes precedence over the job's metadata
+	if f.transferInfo.SrcMetadataOverride {
+		metadata = f.transferInfo.SrcMetadata
+		if metadata == nil {
+			metadata = common.Metadata{}
+		}
+	}
+
 	return &SrcProperties{
 		SrcHTTPHeaders: common.ResourceHTTPHeaders{
 			ContentType:        headers.ContentType,
@@ -63,6 +71,13 @@ func (f localFileSourceInfoProvider) IsLocal() bool {
 func (f localFileSourceInfoProvider) OpenSourceFile() (common.CloseableReaderAt, error) {
 	path := f.jptm.Info().Source
 
+	if useDirectIO() {
+		// if the file can't be opened for direct IO (e.g. on a file system that doesn't support it) we just use normal IO
+		if file, err := common.OpenFileForDirectIO(path); err == nil {
+			return common.NewDirectIOReaderAt(file), nil
+		}
+	}
+
 	if custom, ok := interface{}(f).(ICustomLocalOpener); ok {
 		return custom.Open(path)
 	}
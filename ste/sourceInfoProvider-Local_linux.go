@@ -0,0 +1,81 @@
+// +build linux
+
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+)
+
+// This file os-triggers the IPOSIXPermissionBearingSourceInfoProvider interface on a local SIP.
+
+func (f localFileSourceInfoProvider) GetPOSIXAccessControl(path string, includeOwnership bool) (azbfs.BlobFSAccessControl, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return azbfs.BlobFSAccessControl{}, err
+	}
+
+	entries, err := readPOSIXACLXattr(path, posixACLAccessXattr, false, includeOwnership)
+	if err != nil {
+		return azbfs.BlobFSAccessControl{}, err
+	}
+	if entries == nil {
+		// no extended ACL, so the permission bits are the whole story
+		entries = posixACLEntriesFromMode(os.FileMode(stat.Mode))
+	}
+
+	if stat.Mode&syscall.S_IFMT == syscall.S_IFDIR {
+		defaultEntries, err := readPOSIXACLXattr(path, posixACLDefaultXattr, true, includeOwnership)
+		if err != nil {
+			return azbfs.BlobFSAccessControl{}, err
+		}
+		entries = append(entries, defaultEntries...)
+	}
+
+	result := azbfs.BlobFSAccessControl{ACL: strings.Join(entries, ",")}
+	if includeOwnership {
+		result.Owner = strconv.FormatUint(uint64(stat.Uid), 10)
+		result.Group = strconv.FormatUint(uint64(stat.Gid), 10)
+	}
+	return result, nil
+}
+
+// readPOSIXACLXattr returns the ACL entries in the given extended attribute, or nil if the path doesn't have it
+func readPOSIXACLXattr(path string, name string, isDefault bool, includeNamed bool) ([]string, error) {
+	size, err := syscall.Getxattr(path, name, nil)
+	if err == syscall.ENODATA || err == syscall.ENOTSUP {
+		return nil, nil
+	} else if err != nil {
+		return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+	}
+
+	data := make([]byte, size)
+	if size, err = syscall.Getxattr(path, name, data); err != nil {
+		return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+	}
+	return posixACLEntriesFromXattr(data[:size], isDefault, includeNamed)
+}
@@ -3,6 +3,7 @@
 package ste
 
 import (
+	"fmt"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"os"
 	"strings"
@@ -12,10 +13,12 @@ import (
 	"github.com/Azure/azure-storage-file-go/azfile"
 	"golang.org/x/sys/windows"
 
+	"github.com/Azure/azure-storage-azcopy/azbfs"
 	"github.com/Azure/azure-storage-azcopy/sddl"
 )
 
-// This file os-triggers the ISMBPropertyBearingSourceInfoProvider and CustomLocalOpener interfaces on a local SIP.
+// This file os-triggers the ISMBPropertyBearingSourceInfoProvider, IPOSIXPermissionBearingSourceInfoProvider and
+// CustomLocalOpener interfaces on a local SIP.
 
 func (f localFileSourceInfoProvider) Open(path string) (*os.File, error) {
 	srcPtr, err := syscall.UTF16PtrFromString(path)
@@ -59,6 +62,40 @@ func (f localFileSourceInfoProvider) GetSDDL() (string, error) {
 	return fSDDL.PortableString(), nil
 }
 
+// GetPOSIXAccessControl translates the Windows ACL of the path to POSIX, for ADLS Gen2.
+// Principals are left as SIDs, which the POSIX ID map turns into Azure AD object IDs.
+func (f localFileSourceInfoProvider) GetPOSIXAccessControl(path string, includeOwnership bool) (azbfs.BlobFSAccessControl, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return azbfs.BlobFSAccessControl{}, err
+	}
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION|windows.GROUP_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return azbfs.BlobFSAccessControl{}, err
+	}
+
+	// the portable form spells out well-known SIDs, such as BA, so that they can be mapped like any other
+	fSDDL, err := sddl.ParseSDDL(sd.String())
+	if err == nil {
+		fSDDL, err = sddl.ParseSDDL(fSDDL.PortableString())
+	}
+	if err != nil {
+		return azbfs.BlobFSAccessControl{}, err
+	}
+
+	idMap := f.jptm.POSIXIDMap()
+	isGroupSID := func(sid string) bool {
+		_, mapped := idMap.GroupSID(sid)
+		return mapped || isWellKnownGroupSID(sid)
+	}
+	ac, unrepresentable := posixAccessControlFromSDDL(fSDDL, info.IsDir(), includeOwnership, isGroupSID)
+	if len(unrepresentable) > 0 {
+		f.jptm.MarkPropertiesNotPreserved(fmt.Sprintf("The POSIX ACL of %s only approximates its Windows ACL, since these entries can't be represented exactly: %s",
+			path, strings.Join(unrepresentable, " ")))
+	}
+	return ac, nil
+}
+
 func (f localFileSourceInfoProvider) GetSMBProperties() (TypedSMBPropertyHolder, error) {
 	info, err := common.GetFileInformation(f.jptm.Info().Source)
 
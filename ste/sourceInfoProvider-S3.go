@@ -134,9 +134,8 @@ func (p *s3SourceInfoProvider) handleInvalidMetadataKeys(m common.Metadata) (com
 	switch p.transferInfo.S2SInvalidMetadataHandleOption {
 	case common.EInvalidMetadataHandleOption.ExcludeIfInvalid():
 		retainedMetadata, excludedMetadata, invalidKeyExists := m.ExcludeInvalidKey()
-		if invalidKeyExists && p.jptm.ShouldLog(pipeline.LogWarning) {
-			p.jptm.Log(pipeline.LogWarning,
-				fmt.Sprintf("METADATAWARNING: For source %q, invalid metadata with keys %s are excluded", p.transferInfo.Source, excludedMetadata.ConcatenatedKeys()))
+		if invalidKeyExists {
+			p.jptm.MarkPropertiesNotPreserved(fmt.Sprintf("METADATAWARNING: invalid metadata with keys %s are excluded", excludedMetadata.ConcatenatedKeys()))
 		}
 		return retainedMetadata, nil
 
@@ -27,6 +27,7 @@ import (
 
 	"github.com/Azure/azure-storage-file-go/azfile"
 
+	"github.com/Azure/azure-storage-azcopy/azbfs"
 	"github.com/Azure/azure-storage-azcopy/common"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -91,6 +92,14 @@ type ISMBPropertyBearingSourceInfoProvider interface {
 	GetSMBProperties() (TypedSMBPropertyHolder, error)
 }
 
+// IPOSIXPermissionBearingSourceInfoProvider can read the POSIX owner, group and ACLs of its source.
+// It takes a path so that it can also read those of the directories above the source.
+type IPOSIXPermissionBearingSourceInfoProvider interface {
+	ISourceInfoProvider
+
+	GetPOSIXAccessControl(path string, includeOwnership bool) (azbfs.BlobFSAccessControl, error)
+}
+
 type ICustomLocalOpener interface {
 	ISourceInfoProvider
 	Open(path string) (*os.File, error)
@@ -0,0 +1,79 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// slowestTransfersListed is how many of its slowest transfers a job's summary lists
+const slowestTransfersListed = 10
+
+// transferDurations gathers how long a job's successful file transfers took, for the job's summary
+type transferDurations struct {
+	durations []time.Duration
+	slowest   []common.TransferDuration // slowest first
+}
+
+// add counts the duration of one transfer. Describing the transfer means reading its source and destination from the
+// plan, so that's only done for those that are among the slowest so far
+func (d *transferDurations) add(duration time.Duration, describe func() common.TransferDuration) {
+	d.durations = append(d.durations, duration)
+
+	ms := duration.Milliseconds()
+	if len(d.slowest) == slowestTransfersListed && ms <= d.slowest[len(d.slowest)-1].Milliseconds {
+		return
+	}
+	i := sort.Search(len(d.slowest), func(i int) bool { return d.slowest[i].Milliseconds < ms })
+	d.slowest = append(d.slowest, common.TransferDuration{})
+	copy(d.slowest[i+1:], d.slowest[i:])
+	d.slowest[i] = describe()
+	d.slowest[i].Milliseconds = ms
+	if len(d.slowest) > slowestTransfersListed {
+		d.slowest = d.slowest[:slowestTransfersListed]
+	}
+}
+
+// fill puts the spread of the durations, and the slowest transfers, into the job's summary
+func (d *transferDurations) fill(js *common.ListJobSummaryResponse) {
+	if len(d.durations) == 0 {
+		return
+	}
+	sort.Slice(d.durations, func(i, j int) bool { return d.durations[i] < d.durations[j] })
+
+	// nearest-rank percentiles, so that each is one of the durations
+	percentile := func(p float64) int64 {
+		rank := int(math.Ceil(p*float64(len(d.durations)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return d.durations[rank].Milliseconds()
+	}
+	js.MinTransferMilliseconds = percentile(0)
+	js.MedianTransferMilliseconds = percentile(0.5)
+	js.P95TransferMilliseconds = percentile(0.95)
+	js.MaxTransferMilliseconds = percentile(1)
+	js.SlowestTransfers = d.slowest
+}
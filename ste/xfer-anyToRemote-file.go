@@ -26,7 +26,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Azure/azure-storage-blob-go/azblob"
-	"hash"
+	"io"
 	"net/http"
 	"net/url"
 	"runtime"
@@ -87,20 +87,26 @@ func BlobTierAllowed(destTier azblob.AccessTierType) bool {
 		return true
 	}
 
+	return BlobTierAllowedOn(destAccountSKU, destAccountKind, destTier)
+}
+
+// BlobTierAllowedOn says whether an account with the given SKU and kind can take the tier. The front end uses it too,
+// to turn down an explicit --page-blob-tier before the job starts, rather than have every transfer fall back to the default
+func BlobTierAllowedOn(accountSKU string, accountKind string, destTier azblob.AccessTierType) bool {
 	// If the account is premium, Storage/StorageV2 only supports page blobs (Tiers P1-80). Block blob does not support tiering whatsoever.
-	if strings.Contains(destAccountSKU, "Premium") {
+	if strings.Contains(accountSKU, "Premium") {
 		// storage V1/V2
-		if destAccountKind == "StorageV2" {
+		if accountKind == "StorageV2" {
 			// P1-80 possible.
 			return premiumPageBlobTierRegex.MatchString(string(destTier))
 		}
 
-		if destAccountKind == "Storage" {
+		if accountKind == "Storage" {
 			// No tier setting is allowed.
 			return false
 		}
 
-		if strings.Contains(destAccountKind, "Block") {
+		if strings.Contains(accountKind, "Block") {
 			// No tier setting is allowed.
 			return false
 		}
@@ -258,6 +264,19 @@ func anyToRemote_file(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pi
 				}
 			}
 
+			// whatever was decided above, find out whether the overwrite would keep the current content as a version
+			keptVersion := false
+			if v, ok := s.(versionKeepingSender); ok {
+				keptVersion = v.DestinationKeepsVersions()
+			}
+			if jptm.GetOverwriteOption() == common.EOverwriteOption.IfVersioned() {
+				// only overwrite if what's overwritten isn't lost
+				shouldOverwrite = keptVersion
+				if !keptVersion {
+					jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "Blob versioning isn't enabled on the destination's account, so overwriting it would lose its content")
+				}
+			}
+
 			if !shouldOverwrite {
 				// logging as Warning so that it turns up even in compact logs, and because previously we use Error here
 				jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "File already exists, so will be skipped")
@@ -265,6 +284,7 @@ func anyToRemote_file(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pi
 				jptm.ReportTransferDone()
 				return
 			}
+			jptm.MarkOverwrite(keptVersion)
 		}
 	}
 
@@ -343,7 +363,67 @@ func anyToRemote_file(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pi
 	jptm.LogChunkStatus(pseudoId, common.EWaitReason.ChunkDone())
 
 	// Step 6: Go through the file and schedule chunk messages to send each chunk
-	scheduleSendChunks(jptm, info.Source, srcFile, srcSize, s, sourceFileFactory, srcInfoProvider)
+	if sfu, ok := s.(smallFileUploader); ok && srcInfoProvider.IsLocal() && numChunks == 1 && JobsAdmin.IsSmallFile(srcSize) {
+		scheduleSmallFileSend(jptm, info.Source, srcFile, srcSize, sfu)
+	} else {
+		scheduleSendChunks(jptm, info.Source, srcFile, srcSize, s, sourceFileFactory, srcInfoProvider)
+	}
+}
+
+// Schedule the upload of a small local file, which we read all at once and send with a single request.
+// Unlike scheduleSendChunks, there's no chunk reader here, but the buffer is still counted by the cache limiter
+// and comes from the slice pool, so that a long queue of small files can't use unbounded RAM.
+func scheduleSmallFileSend(jptm IJobPartTransferMgr, srcPath string, srcFile common.CloseableReaderAt, srcSize int64, s smallFileUploader) {
+	id := common.NewChunkID(srcPath, 0, srcSize)
+
+	data := []byte{}
+	var readErr error
+	if jptm.WasCanceled() {
+		readErr = jobCancelledLocalPrefetchErr
+	} else if srcSize > 0 {
+		jptm.LogChunkStatus(id, common.EWaitReason.RAMToSchedule())
+		readErr = jptm.CacheLimiter().WaitUntilAdd(jptm.Context(), srcSize, func() bool { return false })
+		if readErr == nil {
+			data = jptm.SlicePool().RentSlice(srcSize)
+			jptm.LogChunkStatus(id, common.EWaitReason.DiskIO())
+			_, readErr = io.ReadFull(io.NewSectionReader(srcFile, 0, srcSize), data)
+			if readErr != nil {
+				releaseSmallFileBuffer(jptm, data)
+			}
+		}
+	}
+
+	// the whole file is the leading bytes
+	ps := common.PrologueState{}
+	if readErr == nil {
+		ps.LeadingBytes = data
+	}
+	if s.Prologue(ps) {
+		jptm.SetDestinationIsModified()
+	}
+
+	jptm.LogChunkStatus(id, common.EWaitReason.WorkerGR())
+	var cf chunkFunc
+	if readErr == nil {
+		upload := s.GenerateSmallFileUploadFunc(id, data)
+		cf = func(workerId int) {
+			defer releaseSmallFileBuffer(jptm, data)
+			upload(workerId)
+		}
+	} else {
+		// as with other uploads, the chunk must be scheduled even though we know it will fail
+		cf = createSendToRemoteChunkFunc(jptm, id, func() { jptm.FailActiveSend("small file read", readErr) })
+	}
+	jptm.ScheduleChunks(cf)
+}
+
+// releaseSmallFileBuffer gives back the buffer, and its share of the cache limit, taken by scheduleSmallFileSend
+func releaseSmallFileBuffer(jptm IJobPartTransferMgr, data []byte) {
+	if len(data) == 0 {
+		return // empty files don't take a buffer
+	}
+	jptm.SlicePool().ReturnSlice(data)
+	jptm.CacheLimiter().Remove(int64(len(data)))
 }
 
 var jobCancelledLocalPrefetchErr = errors.New("job was cancelled; Pre-fetching stopped")
@@ -356,6 +436,7 @@ var jobCancelledLocalPrefetchErr = errors.New("job was cancelled; Pre-fetching s
 // is harmless (and a good thing, to avoid excessive RAM usage).
 // To take advantage of the good sequential read performance provided by many file systems,
 // and to be able to compute an MD5 hash for the file, we work sequentially through the file here.
+// The hashing itself is pipelined, so that it doesn't hold up the reading of the next chunk.
 func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common.CloseableReaderAt, srcSize int64, s sender, sourceFileFactory common.ChunkReaderSourceFactory, srcInfoProvider ISourceInfoProvider) {
 	// For generic send
 	chunkSize := s.ChunkSize()
@@ -367,17 +448,17 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 	var chunkReader common.SingleChunkReader
 	ps := common.PrologueState{}
 
-	var md5Hasher hash.Hash
-	if jptm.ShouldPutMd5() {
-		md5Hasher = md5.New()
-	} else {
-		md5Hasher = common.NewNullHasher()
-	}
+	var md5Hasher *pipelinedHasher
 	safeToUseHash := true
+	readOnce := false
 
 	if srcInfoProvider.IsLocal() {
 		md5Channel = s.(uploader).Md5Channel()
 		defer close(md5Channel)
+		if jptm.ShouldPutMd5() {
+			md5Hasher = newPipelinedHasher(md5.New())
+		}
+		readOnce = shouldReadSourceOnce(jptm)
 	}
 
 	chunkIDCount := int32(0)
@@ -391,6 +472,7 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 		}
 
 		id := common.NewChunkID(srcPath, startIndex, adjustedChunkSize) // TODO: stop using adjustedChunkSize, below, and use the size that's in the ID
+		var hashed <-chan struct{}
 
 		if srcInfoProvider.IsLocal() {
 			if jptm.WasCanceled() {
@@ -402,18 +484,21 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 				// Furthermore, this prevents prefetchErr changing from under us.
 				if prefetchErr == nil {
 					// create reader and prefetch the data into it
-					chunkReader = createPopulatedChunkReader(jptm, sourceFileFactory, id, adjustedChunkSize, srcFile)
+					chunkReader = createPopulatedChunkReader(jptm, sourceFileFactory, id, adjustedChunkSize, srcFile, readOnce)
 
 					// Wait until we have enough RAM, and when we do, prefetch the data for this chunk.
 					prefetchErr = chunkReader.BlockingPrefetch(srcFile, false)
 					if prefetchErr == nil {
-						// *** NOTE: the hasher hashes the buffer as it is right now.  IF the chunk upload fails, then
+						ps = chunkReader.GetPrologueState()
+
+						// *** NOTE: the hasher hashes the buffer as it is before the chunk is sent.  IF the chunk upload fails, then
 						//     the chunkReader will repeat the read from disk. So there is an essential dependency
 						//     between the hashing and our change detection logic.
 						common.DocumentationForDependencyOnChangeDetection() // <-- read the documentation here ***
 
-						chunkReader.WriteBufferTo(md5Hasher)
-						ps = chunkReader.GetPrologueState()
+						if md5Hasher != nil {
+							hashed = md5Hasher.add(chunkReader)
+						}
 					} else {
 						safeToUseHash = false // because we've missed a chunk
 					}
@@ -439,6 +524,12 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 		if srcInfoProvider.IsLocal() {
 			if prefetchErr == nil {
 				cf = s.(uploader).GenerateUploadFunc(id, chunkIDCount, chunkReader, isWholeFile)
+				if readOnce {
+					cf = closeReaderAfter(cf, chunkReader)
+				}
+				if hashed != nil {
+					cf = afterHashing(cf, hashed)
+				}
 			} else {
 				if chunkReader != nil {
 					_ = chunkReader.Close()
@@ -461,17 +552,23 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 		panic(fmt.Errorf("difference in the number of chunk calculated %v and actual chunks scheduled %v for src %s of size %v", numChunks, chunkIDCount, srcPath, srcSize))
 	}
 
-	if srcInfoProvider.IsLocal() && safeToUseHash {
-		md5Channel <- md5Hasher.Sum(nil)
+	if srcInfoProvider.IsLocal() {
+		md5Hash := common.NewNullHasher().Sum(nil)
+		if md5Hasher != nil {
+			md5Hash = md5Hasher.sum() // always waited for, so that the hasher's goroutine ends
+		}
+		if safeToUseHash {
+			md5Channel <- md5Hash
+		}
 	}
 }
 
 // Make reader for this chunk.
 // Each chunk reader also gets a factory to make a reader for the file, in case it needs to repeat its part
-// of the file read later (when doing a retry)
+// of the file read later (when doing a retry), unless it's to keep its data until it's closed (see shouldReadSourceOnce)
 // BTW, the reader we create here just works with a single chuck. (That's in contrast with downloads, where we have
 // to use an object that encompasses the whole file, so that it can put the chunks back into order. We don't have that requirement here.)
-func createPopulatedChunkReader(jptm IJobPartTransferMgr, sourceFileFactory common.ChunkReaderSourceFactory, id common.ChunkID, adjustedChunkSize int64, srcFile common.CloseableReaderAt) common.SingleChunkReader {
+func createPopulatedChunkReader(jptm IJobPartTransferMgr, sourceFileFactory common.ChunkReaderSourceFactory, id common.ChunkID, adjustedChunkSize int64, srcFile common.CloseableReaderAt, readOnce bool) common.SingleChunkReader {
 	chunkReader := common.NewSingleChunkReader(jptm.Context(),
 		sourceFileFactory,
 		id,
@@ -479,11 +576,59 @@ func createPopulatedChunkReader(jptm IJobPartTransferMgr, sourceFileFactory comm
 		jptm.ChunkStatusLogger(),
 		jptm,
 		jptm.SlicePool(),
-		jptm.CacheLimiter())
+		jptm.CacheLimiter(),
+		readOnce)
 
 	return chunkReader
 }
 
+var (
+	readSourceOnceSetting string
+	readSourceOnceOncer   sync.Once
+
+	// whether each source root is on a network file system, so we only look that up once
+	networkSourceRoots     = make(map[string]bool)
+	networkSourceRootsLock sync.Mutex
+)
+
+// shouldReadSourceOnce says whether each chunk of a local file should be read only once, and kept until it has been
+// sent, rather than being re-read for retries. Re-reading is slow on network file systems, so by default that's done
+// when the source is on one
+func shouldReadSourceOnce(jptm IJobPartTransferMgr) bool {
+	readSourceOnceOncer.Do(func() {
+		readSourceOnceSetting = strings.ToLower(common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.ReadSourceOnce()))
+	})
+
+	switch readSourceOnceSetting {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	root := jptm.GetSourceRoot()
+	networkSourceRootsLock.Lock()
+	defer networkSourceRootsLock.Unlock()
+	isNetwork, known := networkSourceRoots[root]
+	if !known {
+		isNetwork = common.IsNetworkFileSystem(root)
+		networkSourceRoots[root] = isNetwork
+		if isNetwork {
+			jptm.Log(pipeline.LogInfo, fmt.Sprintf("Source %s is on a network file system, so each chunk will be read from it only once", root))
+		}
+	}
+	return isNetwork
+}
+
+// closeReaderAfter closes a chunk's reader once its chunk func is done, to free the data that it kept for retries.
+// Closing it again is harmless, and so is closing it when the chunk was never sent.
+func closeReaderAfter(cf chunkFunc, reader io.Closer) chunkFunc {
+	return func(workerId int) {
+		defer reader.Close()
+		cf(workerId)
+	}
+}
+
 func isDummyChunkInEmptyFile(startIndex int64, fileSize int64) bool {
 	return startIndex == 0 && fileSize == 0
 }
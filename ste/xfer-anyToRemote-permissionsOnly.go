@@ -0,0 +1,132 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
+)
+
+// anyToRemote_permissionsOnly applies the permissions and properties of the sources to destinations that already exist,
+// e.g. to fix up a migration that was done without preserving them. No data is sent.
+func anyToRemote_permissionsOnly(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer, senderFactory senderFactory, sipf sourceInfoProviderFactory) {
+	info := jptm.Info()
+
+	// step 1. perform initial checks
+	if jptm.WasCanceled() {
+		/* This is earliest we detect that jptm has been cancelled before we reach destination */
+		jptm.SetStatus(common.ETransferStatus.Cancelled())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// step 2. Create sender
+	srcInfoProvider, err := sipf(jptm)
+	if err != nil {
+		jptm.LogSendError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	baseSender, err := senderFactory(jptm, info.Destination, p, pacer, srcInfoProvider)
+	if err != nil {
+		jptm.LogSendError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// step 3. Set the properties. Nothing is created, so a destination that doesn't exist fails the transfer.
+	if info.IsFolderPropertiesTransfer() {
+		if s, ok := baseSender.(permissionsOnlyFolderSender); ok {
+			err = s.SetPropertiesOfExistingFolder()
+		} else if s, ok := baseSender.(folderSender); ok {
+			err = s.SetFolderProperties()
+		} else {
+			jptm.LogSendError(info.Source, info.Destination, "sender implementation does not support folders", 0)
+			jptm.SetStatus(common.ETransferStatus.Failed())
+			jptm.ReportTransferDone()
+			return
+		}
+	} else {
+		s, ok := baseSender.(permissionsOnlySender)
+		if !ok {
+			jptm.LogSendError(info.Source, info.Destination, "sender implementation does not support permissions-only transfers", 0)
+			jptm.SetStatus(common.ETransferStatus.Failed())
+			jptm.ReportTransferDone()
+			return
+		}
+		err = s.SetPropertiesOfExistingFile()
+	}
+	if err != nil {
+		jptm.FailActiveSend("setting permissions and properties", err)
+	}
+
+	commonSenderCompletion(jptm, baseSender, info) // for consistency, always run the standard epilogue
+}
+
+// setPropertiesOfExistingBlob is the permissionsOnlySender implementation shared by all blob types. Blobs don't have
+// permissions of their own, so the properties that can be fixed up are the metadata and tags. Setting metadata replaces
+// all of it, so the source's metadata is merged into what the blob already has rather than dropping the rest.
+func setPropertiesOfExistingBlob(ctx context.Context, blobURL azblob.BlobURL, metadata azblob.Metadata, blobTags azblob.BlobTagsMap) error {
+	props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return err
+	}
+
+	if _, err = blobURL.SetMetadata(ctx, mergeBlobMetadata(props.NewMetadata(), metadata), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{}); err != nil {
+		return err
+	}
+	if len(blobTags) > 0 {
+		if _, err = blobURL.SetTags(ctx, nil, nil, nil, blobTags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFileMetadata is mergeBlobMetadata for Azure Files, whose metadata is replaced as a whole in just the same way
+func mergeFileMetadata(existing, toApply azfile.Metadata) azfile.Metadata {
+	return azfile.Metadata(mergeBlobMetadata(azblob.Metadata(existing), azblob.Metadata(toApply)))
+}
+
+// mergeBlobMetadata returns the existing metadata with the given metadata applied over it. Keys are case-insensitive.
+func mergeBlobMetadata(existing, toApply azblob.Metadata) azblob.Metadata {
+	result := azblob.Metadata{}
+	for k, v := range existing {
+		result[k] = v
+	}
+	for k, v := range toApply {
+		for existingKey := range result {
+			if strings.EqualFold(existingKey, k) {
+				delete(result, existingKey)
+			}
+		}
+		result[k] = v
+	}
+	return result
+}
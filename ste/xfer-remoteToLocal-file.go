@@ -26,6 +26,8 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
@@ -64,6 +66,13 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 		jptm.ReportTransferDone()
 		return
 	}
+	// a copy between local paths mustn't truncate its own source, e.g. where the destination is reached through a link
+	if jptm.FromTo() == common.EFromTo.LocalLocal() && isSameLocalFile(info.Source, info.Destination) {
+		jptm.LogDownloadError(info.Source, info.Destination, "Transfer source and destination are the same file, which would cause data loss. Aborting transfer.", 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
 	// if the force Write flags is set to false or prompt
 	// then check the file exists at the remote location
 	// if it does, react accordingly
@@ -155,17 +164,28 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 		return
 	}
 
+	writeSequentially := shouldWriteSequentially(jptm)
+
 	var dstFile io.WriteCloser
 	if strings.EqualFold(info.Destination, common.Dev_Null) {
 		// the user wants to discard the downloaded data
 		dstFile = devNullWriter{}
 	} else {
 		// Normal scenario, create the destination file as expected
+		if err = checkFreeSpaceForFile(jptm, info.Destination, fileSize); err != nil {
+			failFileCreation(err)
+			return
+		}
+
 		// Use pseudo chunk id to allow our usual state tracking mechanism to keep count of how many
 		// file creations are running at any given instant, for perf diagnostics
 		pseudoId := common.NewPseudoChunkIDForWholeFile(info.Source)
 		jptm.LogChunkStatus(pseudoId, common.EWaitReason.CreateLocalFile())
-		dstFile, err = createDestinationFile(jptm, info.Destination, fileSize, writeThrough)
+		sizeToAllocate := fileSize
+		if writeSequentially {
+			sizeToAllocate = 0 // the file just grows as it is written, since pre-sizing it can be slow on network file systems
+		}
+		dstFile, err = createDestinationFile(jptm, info.Destination, sizeToAllocate, writeThrough)
 		jptm.LogChunkStatus(pseudoId, common.EWaitReason.ChunkDone()) // normal setting to done doesn't apply to these pseudo ids
 		if err != nil {
 			failFileCreation(err)
@@ -201,6 +221,10 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 	// step 5b: create destination writer
 	chunkLogger := jptm.ChunkStatusLogger()
 	sourceMd5Exists := len(info.SrcHTTPHeaders.ContentMD5) > 0
+	maxChunksAhead := uint32(0)
+	if writeSequentially {
+		maxChunksAhead = sequentialWriteMaxChunksAhead(jptm, downloadChunkSize)
+	}
 	dstWriter := common.NewChunkedFileWriter(
 		jptm.Context(),
 		jptm.SlicePool(),
@@ -210,7 +234,9 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 		numChunks,
 		MaxRetryPerDownloadBody,
 		jptm.MD5ValidationOption(),
-		sourceMd5Exists)
+		sourceMd5Exists,
+		maxChunksAhead,
+		jptm.FlushPolicy())
 
 	// step 5c: run prologue in downloader (here it can, for example, create things that will require cleanup in the epilogue)
 	common.GetLifecycleMgr().E2EAwaitAllowOpenFiles()
@@ -263,6 +289,145 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 
 }
 
+// in sequential write mode, the most chunks of a file that may be downloaded ahead of the point up to which the file
+// has been written. The chunked file writer sizes the window from the throughput it sees, within this.
+// It lets one file's reorder buffer take up to a quarter of the RAM budget, so that several files can still progress.
+func sequentialWriteMaxChunksAhead(jptm IJobPartTransferMgr, chunkSize int64) uint32 {
+	const minChunksAhead, maxChunksAhead = 2, 1024
+	n := jptm.CacheLimiter().Limit() / 4 / chunkSize
+	if n < minChunksAhead {
+		return minChunksAhead
+	}
+	if n > maxChunksAhead {
+		return maxChunksAhead
+	}
+	return uint32(n)
+}
+
+var (
+	sequentialWriteSetting string
+	sequentialWriteOncer   sync.Once
+
+	// whether each destination root is on a network file system, so we only look that up once
+	networkDestinationRoots     = make(map[string]bool)
+	networkDestinationRootsLock sync.Mutex
+
+	// the free space on each destination root's volume, so that checking each file doesn't have to look at the volume
+	destinationFreeSpace = newFreeSpaceCache(common.GetFreeSpace, freeSpaceRefreshInterval)
+)
+
+// how long a cached free space figure is trusted for, before the volume is looked at again
+const freeSpaceRefreshInterval = 5 * time.Second
+
+// freeSpaceCache remembers the free space on each destination volume, keyed by the destination root since all of a
+// job's files are below that. Space for each file that's checked is taken off the cached figure, since the file is
+// about to be written, and the figure is refreshed from the volume once it's older than the refresh interval
+type freeSpaceCache struct {
+	lock            sync.Mutex
+	volumes         map[string]*cachedFreeSpace
+	lookup          func(path string) (uint64, error)
+	refreshInterval time.Duration
+}
+
+type cachedFreeSpace struct {
+	available uint64
+	lookedUp  time.Time
+}
+
+func newFreeSpaceCache(lookup func(path string) (uint64, error), refreshInterval time.Duration) *freeSpaceCache {
+	return &freeSpaceCache{
+		volumes:         make(map[string]*cachedFreeSpace),
+		lookup:          lookup,
+		refreshInterval: refreshInterval,
+	}
+}
+
+// reserve takes the needed space off the root's free space, if it's there, and returns how much was available.
+// known is false if the free space can't be looked up
+func (c *freeSpaceCache) reserve(root string, needed uint64) (available uint64, fits bool, known bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cached, ok := c.volumes[root]
+	if !ok || time.Since(cached.lookedUp) > c.refreshInterval {
+		free, err := c.lookup(root)
+		if err != nil {
+			return 0, false, false
+		}
+		cached = &cachedFreeSpace{available: free, lookedUp: time.Now()}
+		c.volumes[root] = cached
+	}
+
+	available = cached.available
+	if needed > available {
+		return available, false, true
+	}
+	cached.available -= needed
+	return available, true, true
+}
+
+// shouldWriteSequentially says whether downloaded files should be written strictly in order, without pre-sizing them.
+// Network file systems such as SMB and NFS perform poorly otherwise, so by default that's done when the destination is on one
+func shouldWriteSequentially(jptm IJobPartTransferMgr) bool {
+	sequentialWriteOncer.Do(func() {
+		sequentialWriteSetting = strings.ToLower(common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.SequentialWrites()))
+	})
+
+	switch sequentialWriteSetting {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	root := jptm.GetDestinationRoot()
+	networkDestinationRootsLock.Lock()
+	defer networkDestinationRootsLock.Unlock()
+	isNetwork, known := networkDestinationRoots[root]
+	if !known {
+		isNetwork = common.IsNetworkFileSystem(root)
+		networkDestinationRoots[root] = isNetwork
+		if isNetwork {
+			jptm.Log(pipeline.LogInfo, fmt.Sprintf("Destination %s is on a network file system, so files will be written sequentially", root))
+		}
+	}
+	return isNetwork
+}
+
+// checkFreeSpaceForFile makes sure there's room on the destination volume for the file, so that it fails up front
+// rather than part way through. Any existing file at the destination counts as free space, since it's truncated
+// when the new one is created
+func checkFreeSpaceForFile(jptm IJobPartTransferMgr, destination string, fileSize int64) error {
+	option := jptm.FreeSpaceCheck()
+	if option == common.EFreeSpaceCheckOption.None() {
+		return nil
+	}
+
+	needed := uint64(fileSize)
+	var existingSize uint64
+	if existing, err := common.OSStat(destination); err == nil && existing.Mode().IsRegular() {
+		existingSize = uint64(existing.Size())
+	}
+	if needed > existingSize {
+		needed -= existingSize
+	} else {
+		needed = 0
+	}
+
+	available, fits, known := destinationFreeSpace.reserve(jptm.GetDestinationRoot(), needed)
+	if !known || fits {
+		return nil // if we can't tell, the write will just have to find out
+	}
+	available += existingSize
+
+	msg := fmt.Sprintf("not enough free space on the destination volume, since the file is %d bytes but only %d are available", fileSize, available)
+	if option == common.EFreeSpaceCheckOption.Fail() {
+		return errors.New(msg)
+	}
+	jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, msg)
+	return nil
+}
+
 func createDestinationFile(jptm IJobPartTransferMgr, destination string, size int64, writeThrough bool) (file io.WriteCloser, err error) {
 	ct := common.ECompressionType.None()
 	if jptm.ShouldDecompress() {
@@ -276,11 +441,26 @@ func createDestinationFile(jptm IJobPartTransferMgr, destination string, size in
 		// and we still need to set size to zero here, so relying on enumeration more wouldn't simply this code much, if at all.
 	}
 
-	var dstFile io.WriteCloser
-	dstFile, err = common.CreateFileOfSizeWithWriteThroughOption(destination, size, writeThrough, jptm.GetFolderCreationTracker(), jptm.GetForceIfReadOnly())
+	directIO := useDirectIO()
+	var osFile *os.File
+	osFile, err = common.CreateFileOfSizeWithWriteThroughOption(destination, size, writeThrough, directIO, jptm.GetFolderCreationTracker(), jptm.GetForceIfReadOnly())
 	if err != nil {
 		return nil, err
 	}
+	var destinationFile common.DirectIOFile = osFile
+	if jptm.FlushPolicy() != common.EFlushPolicy.Never() {
+		// whatever the policy, the file is committed to durable storage once it is complete. But not if the transfer failed,
+		// since the file is about to be deleted
+		destinationFile = common.SyncOnCloseFile{File: osFile, ShouldSync: jptm.IsLive}
+	}
+	var dstFile io.WriteCloser = destinationFile
+	if directIO {
+		// the OS only accepts aligned writes to the file, so stage what's written in an aligned buffer
+		if dstFile, err = common.NewDirectIOWriter(jptm.Context(), destinationFile, size, jptm.CacheLimiter()); err != nil {
+			_ = osFile.Close()
+			return nil, err
+		}
+	}
 	if jptm.ShouldDecompress() {
 		jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "will be decompressed from "+ct.String())
 
@@ -313,10 +493,10 @@ func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, active
 
 		// wait until all received chunks are flushed out
 		md5OfFileAsWritten, flushError := cw.Flush(jptm.Context())
-		closeErr := activeDstFile.Close() // always try to close if, even if flush failed
 		if flushError != nil {
-			jptm.FailActiveDownload("Flushing file", flushError)
+			jptm.FailActiveDownload("Flushing file", flushError) // before closing, so that the file is not synced for nothing
 		}
+		closeErr := activeDstFile.Close() // always try to close if, even if flush failed
 		if closeErr != nil {
 			jptm.FailActiveDownload("Closing file", closeErr)
 			jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "Error closing file: "+closeErr.Error()) // log this way so that this line will be logged even if transfer is already failed
@@ -359,13 +539,41 @@ func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, active
 		// TODO: ...So I have preserved that behavior here.
 		// TODO: question: But is that correct?
 		lastModifiedTime, preserveLastModifiedTime := jptm.PreserveLastModifiedTime()
-		if preserveLastModifiedTime && !info.PreserveSMBInfo {
-			err := os.Chtimes(jptm.Info().Destination, lastModifiedTime, lastModifiedTime)
+		if srcLMT, ok := info.SrcMetadata.SourceLastModifiedTime(); ok {
+			lastModifiedTime = srcLMT // prefer the time recorded when the file was uploaded, so that round trips are stable
+		}
+		// Azure Files' SMB properties include the last write time, blobs only have a creation time.
+		fromTo := jptm.FromTo()
+		preserveLastModifiedTime = preserveLastModifiedTime && !(info.PreserveSMBInfo && fromTo.From() == common.ELocation.File())
+
+		var lastAccessTime time.Time
+		preserveLastAccessTime := false
+		if atad, ok := dl.(accessTimeAwareDownloader); ok && info.PreserveLastAccessTime {
+			lastAccessTime, preserveLastAccessTime = atad.LastAccessTime()
+		}
+
+		if preserveLastModifiedTime || preserveLastAccessTime {
+			err := error(nil)
+			if !preserveLastAccessTime {
+				lastAccessTime = lastModifiedTime
+			}
+			if !preserveLastModifiedTime {
+				// os.Chtimes always sets both, so keep the current modified time
+				var fi os.FileInfo
+				if fi, err = common.OSStat(info.Destination); err == nil {
+					lastModifiedTime = fi.ModTime()
+				}
+			}
+
+			if err == nil {
+				err = os.Chtimes(info.Destination, lastAccessTime, lastModifiedTime)
+			}
 			if err != nil {
 				jptm.LogError(info.Destination, "Changing Modified Time ", err)
 				// do NOT return, since final status and cleanup logging still to come
 			} else {
-				jptm.Log(pipeline.LogInfo, fmt.Sprintf(" Preserved Modified Time for %s", info.Destination))
+				jptm.Log(pipeline.LogInfo, fmt.Sprintf(" Preserved %s for %s",
+					common.IffString(preserveLastAccessTime, common.IffString(preserveLastModifiedTime, "Modified and Access Time", "Access Time"), "Modified Time"), info.Destination))
 			}
 		}
 	}
@@ -404,7 +612,8 @@ func commonDownloaderCompletion(jptm IJobPartTransferMgr, info TransferInfo, ent
 
 		// Final logging
 		if jptm.ShouldLog(pipeline.LogInfo) { // TODO: question: can we remove these ShouldLogs?  Aren't they inside Log?
-			jptm.Log(pipeline.LogInfo, fmt.Sprintf("DOWNLOADSUCCESSFUL: %s%s", info.entityTypeLogIndicator(), info.Destination))
+			jptm.Log(pipeline.LogInfo, fmt.Sprintf("DOWNLOADSUCCESSFUL: %s%s%s", info.entityTypeLogIndicator(), info.Destination,
+				common.IffString(jptm.ReadFromSecondary(), " (served by the secondary endpoint)", "")))
 		}
 		if jptm.ShouldLog(pipeline.LogDebug) {
 			jptm.Log(pipeline.LogDebug, "Finalizing Transfer")
@@ -0,0 +1,169 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// movesByRename says whether the job part's files are moved by renaming them, rather than by copying them and then
+// deleting their sources. That's possible when they stay within one ADLS Gen2 filesystem, and is near-instant
+// regardless of size. Since a rename can only replace the destination or refuse to, the overwrite options that
+// need the destination's properties still use copy and delete
+func (jpph *JobPartPlanHeader) movesByRename() bool {
+	if jpph.FromTo != common.EFromTo.BlobFSBlobFS() || !jpph.DeleteSourceAfterTransfer ||
+		(jpph.ForceWrite != common.EOverwriteOption.True() && jpph.ForceWrite != common.EOverwriteOption.False()) {
+		return false
+	}
+	return isSameFileSystem(string(jpph.SourceRoot[:jpph.SourceRootLength]), string(jpph.DestinationRoot[:jpph.DestinationRootLength]))
+}
+
+// isSameFileSystem says whether two ADLS Gen2 URLs are in the same filesystem of the same account
+func isSameFileSystem(a, b string) bool {
+	fileSystemOf := func(s string) (string, bool) {
+		u, err := url.Parse(s)
+		if err != nil {
+			return "", false
+		}
+		parts := azbfs.NewBfsURLParts(*u)
+		if parts.FileSystemName == "" {
+			return "", false
+		}
+		parts.DirectoryOrFilePath = ""
+		parts.UnparsedParams = ""
+		parts.SAS = azbfs.SASQueryParameters{}
+		fsURL := parts.URL()
+		return fsURL.String(), true
+	}
+	fsA, okA := fileSystemOf(a)
+	fsB, okB := fileSystemOf(b)
+	return okA && okB && fsA == fsB
+}
+
+// RenameBlobFS moves one file within an ADLS Gen2 filesystem by renaming it. The file keeps its properties and
+// access control. Folders are copied as usual, since moves leave source folders in place
+func RenameBlobFS(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer) {
+	if jptm.Info().IsFolderPropertiesTransfer() {
+		parameterizeSend(anyToRemote, newURLToBlobFSCopier, newBlobFSSourceInfoProvider)(jptm, p, pacer)
+		return
+	}
+
+	if jptm.WasCanceled() {
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// schedule the work as a chunk, so it runs on the main goroutine pool rather than the transfer initiation pool
+	info := jptm.Info()
+	id := common.NewChunkID(info.Destination, 0, 0)
+	cf := createChunkFunc(true, jptm, id, func() { doRenameBlobFS(jptm, p) })
+	jptm.ScheduleChunks(cf)
+}
+
+func doRenameBlobFS(jptm IJobPartTransferMgr, p pipeline.Pipeline) {
+	info := jptm.Info()
+	transferDone := func(status common.TransferStatus) {
+		jptm.SetStatus(status)
+		jptm.ReportTransferDone()
+	}
+	fail := func(msg string, err error) {
+		jptm.LogSendError(info.Source, info.Destination, msg+err.Error(), 0)
+		transferDone(common.ETransferStatus.Failed())
+	}
+
+	srcURL, err := url.Parse(info.Source)
+	if err != nil {
+		fail("Parsing source URL. ", err)
+		return
+	}
+	dstURL, err := url.Parse(info.Destination)
+	if err != nil {
+		fail("Parsing destination URL. ", err)
+		return
+	}
+	srcFileURL := azbfs.NewFileURL(*srcURL, p)
+	dstFileURL := azbfs.NewFileURL(*dstURL, p)
+
+	// a rename doesn't create the destination's parent directories
+	parentDir, err := dstFileURL.GetParentDir()
+	if err != nil {
+		fail("Getting parent directory URL. ", err)
+		return
+	}
+	if !parentDir.IsFileSystemRoot() {
+		_, err = parentDir.Create(jptm.Context(), false)
+		if err == nil {
+			dirURL := parentDir.URL()
+			jptm.GetFolderCreationTracker().RecordCreation(dirURL.String())
+		} else if stgErr, ok := err.(azbfs.StorageError); !ok || stgErr.ServiceCode() != azbfs.ServiceCodePathAlreadyExists {
+			fail("Ensuring parent directory exists. ", err)
+			return
+		}
+	}
+
+	var ifNoneMatch *string
+	if jptm.GetOverwriteOption() == common.EOverwriteOption.False() {
+		anyETag := "*"
+		ifNoneMatch = &anyETag
+	}
+
+	// recorded before renaming, so that if the job ends before we hear back, resuming it knows the file may already have been moved
+	resumingRename := jptm.SourceDeletionPending()
+	jptm.SetSourceDeletionPending(true)
+
+	_, err = dstFileURL.RenameFrom(jptm.Context(), srcFileURL, ifNoneMatch)
+	if stgErr, ok := err.(azbfs.StorageError); ok {
+		switch {
+		case stgErr.ServiceCode() == azbfs.ServiceCodePathAlreadyExists:
+			jptm.SetSourceDeletionPending(false)
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "File already exists, so will be skipped")
+			transferDone(common.ETransferStatus.SkippedEntityAlreadyExists())
+			return
+		case stgErr.Response().StatusCode == http.StatusNotFound && resumingRename:
+			// the source is gone. Since an earlier attempt at this rename had started, e.g. before the job was
+			// resumed, that's most likely because it succeeded, in which case the file is already where it should be
+			if _, propErr := dstFileURL.GetProperties(jptm.Context()); propErr == nil {
+				jptm.Log(pipeline.LogInfo, "Source not found, but an earlier attempt at moving it had started and the destination exists, so the file was already moved")
+				err = nil
+			}
+		}
+		if err != nil {
+			// the service refused the rename, so it didn't happen
+			jptm.SetSourceDeletionPending(false)
+		}
+	}
+	if err != nil {
+		fail("Renaming file. ", err)
+		return
+	}
+
+	if jptm.ShouldLog(pipeline.LogInfo) {
+		jptm.Log(pipeline.LogInfo, fmt.Sprintf("RENAME SUCCESSFUL: %s", common.URLStringExtension(info.Source).RedactSecretQueryParamForLogging()))
+	}
+	transferDone(common.ETransferStatus.Success())
+}
@@ -0,0 +1,119 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// SetBlobProperties changes the content headers, metadata and tags of an existing blob, leaving everything else about it alone
+func SetBlobProperties(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer) {
+
+	// If the transfer was cancelled, then report the transfer as done.
+	if jptm.WasCanceled() {
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// schedule the work as a chunk, so it will run on the main goroutine pool, instead of the
+	// smaller "transfer initiation pool", where this code runs.
+	id := common.NewChunkID(jptm.Info().Source, 0, 0)
+	cf := createChunkFunc(true, jptm, id, func() { doSetBlobProperties(jptm, p) })
+	jptm.ScheduleChunks(cf)
+}
+
+func doSetBlobProperties(jptm IJobPartTransferMgr, p pipeline.Pipeline) {
+
+	info := jptm.Info()
+	// Get the url of the blob whose properties are set
+	u, _ := url.Parse(info.Source)
+
+	blobURL := azblob.NewBlobURL(*u, p)
+	ctx := jptm.Context()
+
+	transferDone := func(err error) {
+		if err != nil {
+			jptm.LogError(info.Source, "SET PROPERTIES ERROR ", err)
+			jptm.SetStatus(common.ETransferStatus.Failed())
+		} else {
+			jptm.Log(pipeline.LogInfo, fmt.Sprintf("SET PROPERTIES SUCCESSFUL: %s", strings.Split(info.Source, "?")[0]))
+			jptm.SetStatus(common.ETransferStatus.Success())
+		}
+		jptm.ReportTransferDone()
+	}
+
+	// only the values that were given are changed, so empty ones are left as they are
+	headers, metadata, blobTags := jptm.ResourceDstData(nil)
+
+	if headers.ContentType != "" || headers.ContentEncoding != "" || headers.ContentLanguage != "" ||
+		headers.ContentDisposition != "" || headers.CacheControl != "" {
+		// Set Blob Properties replaces all the content headers, so start from the existing ones
+		props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+		if err != nil {
+			transferDone(err)
+			return
+		}
+
+		newHeaders := props.NewHTTPHeaders()
+		if headers.ContentType != "" {
+			newHeaders.ContentType = headers.ContentType
+		}
+		if headers.ContentEncoding != "" {
+			newHeaders.ContentEncoding = headers.ContentEncoding
+		}
+		if headers.ContentLanguage != "" {
+			newHeaders.ContentLanguage = headers.ContentLanguage
+		}
+		if headers.ContentDisposition != "" {
+			newHeaders.ContentDisposition = headers.ContentDisposition
+		}
+		if headers.CacheControl != "" {
+			newHeaders.CacheControl = headers.CacheControl
+		}
+
+		if _, err = blobURL.SetHTTPHeaders(ctx, newHeaders, azblob.BlobAccessConditions{}); err != nil {
+			transferDone(err)
+			return
+		}
+	}
+
+	if len(metadata) > 0 {
+		if _, err := blobURL.SetMetadata(ctx, metadata.ToAzBlobMetadata(), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{}); err != nil {
+			transferDone(err)
+			return
+		}
+	}
+
+	if len(blobTags) > 0 {
+		if _, err := blobURL.SetTags(ctx, nil, nil, nil, blobTags.ToAzBlobTagsMap()); err != nil {
+			transferDone(err)
+			return
+		}
+	}
+
+	transferDone(nil)
+}
@@ -21,6 +21,7 @@
 package ste
 
 import (
+	"net/url"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -55,6 +56,18 @@ const PacerTimeToWaitInMs = 50
 // Sync.Once is used so we only log a CPK error once and prevent gumming up stdout
 var cpkAccessFailureLogGLCM sync.Once
 
+// Direct (unbuffered) IO to local files, read once from the environment
+var directIOOnce sync.Once
+var directIOEnabled bool
+
+// useDirectIO says whether local files should be read and written without going through the OS's cache
+func useDirectIO() bool {
+	directIOOnce.Do(func() {
+		directIOEnabled = strings.EqualFold(common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.DirectIO()), "true")
+	})
+	return directIOEnabled
+}
+
 //////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 // These types are define the STE Coordinator
@@ -79,9 +92,7 @@ func parameterizeSend(targetFunction newJobXferWithSenderFactory, sf senderFacto
 }
 
 // the xfer factory is generated based on the type of source and destination
-func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
-
-	const blobFSNotS2S = "blobFS not supported as S2S source"
+func computeJobXfer(fromTo common.FromTo, blobType common.BlobType, permissionsOnly bool) newJobXfer {
 
 	//local helper functions
 
@@ -109,7 +120,7 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 			case common.ELocation.File():
 				return newURLToAzureFileCopier
 			case common.ELocation.BlobFS():
-				panic(blobFSNotS2S)
+				return newURLToBlobFSCopier
 			default:
 				panic("unexpected target location type")
 			}
@@ -122,6 +133,8 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 				return newAzureFilesUploader
 			case common.ELocation.BlobFS():
 				return newBlobFSUploader
+			case common.ELocation.ManagedDisk():
+				return newPageBlobUploader
 			default:
 				panic("unexpected target location type")
 			}
@@ -139,7 +152,7 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 		case common.ELocation.File():
 			return newFileSourceInfoProvider
 		case common.ELocation.BlobFS():
-			panic(blobFSNotS2S)
+			return newBlobFSSourceInfoProvider
 		case common.ELocation.S3():
 			return newS3SourceInfoProvider
 		case common.ELocation.GCP():
@@ -155,9 +168,16 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 		return DeleteBlob
 	case fromTo == common.EFromTo.FileTrash():
 		return DeleteFile
+	case fromTo == common.EFromTo.BlobNone():
+		return SetBlobProperties
+	case fromTo == common.EFromTo.LocalLocal():
+		// local copies are downloads whose source happens to be local, so they write files the same way
+		return parameterizeDownload(remoteToLocal, newLocalDownloader)
 	default:
 		if fromTo.IsDownload() {
 			return parameterizeDownload(remoteToLocal, getDownloader(fromTo.From()))
+		} else if permissionsOnly {
+			return parameterizeSend(anyToRemote_permissionsOnly, getSenderFactory(fromTo), getSipFactory(fromTo.From()))
 		} else {
 			return parameterizeSend(anyToRemote, getSenderFactory(fromTo), getSipFactory(fromTo.From()))
 		}
@@ -177,3 +197,14 @@ func inferBlobType(filename string, defaultBlobType azblob.BlobType) azblob.Blob
 
 	return defaultBlobType
 }
+
+// infers a blob type from the extension of the destination if it's one we recognize, and otherwise from that of the source,
+// so that a file which is renamed to a .vhd on its way to the service still becomes a page blob
+func inferDestinationBlobType(sourcePath string, destinationURL string, defaultBlobType azblob.BlobType) azblob.BlobType {
+	destinationPath := destinationURL
+	if u, err := url.Parse(destinationURL); err == nil {
+		destinationPath = u.Path // leave out the query string, since the SAS would otherwise be taken as the extension
+	}
+
+	return inferBlobType(destinationPath, inferBlobType(sourcePath, defaultBlobType))
+}
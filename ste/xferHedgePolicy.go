@@ -0,0 +1,468 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"io"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+const (
+	// a chunk request that takes longer than this percentile of recent ones gets a duplicate (i.e. hedged) request
+	hedgeLatencyPercentile = 0.95
+
+	// how many recent latencies are kept, and how many are needed before any request is hedged
+	hedgeLatencyWindow     = 500
+	hedgeMinLatencySamples = 50
+)
+
+var (
+	shouldHedgeChunkRequests bool
+	shouldHedgeOncer         sync.Once
+
+	// shared by all pipelines, since all chunk requests of a job go to the same service
+	hedgeLatencies = newChunkLatencyTrackers()
+)
+
+// chunkLatencyKind groups requests whose latencies are comparable: the same operation, on chunks of about the same size
+type chunkLatencyKind struct {
+	operation  string
+	sizeBucket int // sizes within a factor of two of each other share a bucket
+}
+
+// chunkLatencyTrackers keeps a chunkLatencyTracker for each kind of chunk request, so that e.g. the small final block of each file
+// doesn't make the full-sized ones look slow
+type chunkLatencyTrackers struct {
+	lock   sync.Mutex
+	byKind map[chunkLatencyKind]*chunkLatencyTracker
+}
+
+func newChunkLatencyTrackers() *chunkLatencyTrackers {
+	return &chunkLatencyTrackers{byKind: make(map[chunkLatencyKind]*chunkLatencyTracker)}
+}
+
+func (t *chunkLatencyTrackers) trackerFor(kind chunkLatencyKind) *chunkLatencyTracker {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tracker, ok := t.byKind[kind]
+	if !ok {
+		tracker = newChunkLatencyTracker(hedgeLatencyWindow)
+		t.byKind[kind] = tracker
+	}
+	return tracker
+}
+
+// chunkLatencyKindOf works out the kind of a hedgeable chunk request, from its comp parameter and the length of its source range
+func chunkLatencyKindOf(request pipeline.Request) chunkLatencyKind {
+	kind := chunkLatencyKind{operation: strings.ToLower(request.URL.Query().Get("comp"))}
+	if length, ok := rangeLength(request.Header.Get("x-ms-source-range")); ok {
+		kind.sizeBucket = bits.Len64(uint64(length))
+	}
+	return kind
+}
+
+// rangeLength parses a range header value of the form bytes=start-end
+func rangeLength(value string) (int64, bool) {
+	bounds := strings.SplitN(strings.TrimPrefix(value, "bytes="), "-", 2)
+	if len(bounds) != 2 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	end, err := strconv.ParseInt(bounds[1], 10, 64)
+	if err != nil || end < start {
+		return 0, false
+	}
+	return end - start + 1, true
+}
+
+// chunkLatencyTracker keeps the latencies of the most recent chunk requests, so that we can tell when a request is slower than usual
+type chunkLatencyTracker struct {
+	lock    sync.Mutex
+	samples []time.Duration // used as a ring buffer
+	next    int
+}
+
+func newChunkLatencyTracker(size int) *chunkLatencyTracker {
+	return &chunkLatencyTracker{samples: make([]time.Duration, 0, size)}
+}
+
+func (t *chunkLatencyTracker) record(latency time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.samples) < cap(t.samples) {
+		t.samples = append(t.samples, latency)
+		return
+	}
+	t.samples[t.next] = latency
+	t.next = (t.next + 1) % len(t.samples)
+}
+
+// percentile returns the latency below which the given fraction of recent requests completed.
+// It returns false if there have not yet been enough requests for that to be meaningful
+func (t *chunkLatencyTracker) percentile(p float64) (time.Duration, bool) {
+	t.lock.Lock()
+	if len(t.samples) < hedgeMinLatencySamples {
+		t.lock.Unlock()
+		return 0, false
+	}
+	sorted := make([]time.Duration, len(t.samples))
+	copy(sorted, t.samples)
+	t.lock.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(p*float64(len(sorted)-1))], true
+}
+
+// isHedgeableChunkRequest says whether a request copies one chunk, and can safely be sent twice.
+// That covers blocks, pages and ranges that are put from a URL (S2S), which are complete once their response arrives.
+// Appending a block from a URL is left out, since it is not idempotent, as are requests that carry a body.
+// Downloads are not hedged here, since the response to a ranged GET arrives as soon as its body starts. The downloaders
+// hedge them instead, with newHedgedChunkBody, which measures each chunk to the end of its body
+func isHedgeableChunkRequest(request pipeline.Request) bool {
+	if request.Method != http.MethodPut {
+		return false
+	}
+	if isCopy, _ := doesHeaderExistCaseInsensitive(request.Header, xMsCopySourceHeader); !isCopy {
+		return false
+	}
+	comp := strings.ToLower(request.URL.Query().Get("comp"))
+	return comp == "block" || comp == "page" || comp == "range"
+}
+
+// isRangedGet says whether a request downloads a range, i.e. a chunk, of a blob or file
+func isRangedGet(request pipeline.Request) bool {
+	if request.Method != http.MethodGet {
+		return false
+	}
+	hasRange, _ := doesHeaderExistCaseInsensitive(request.Header, "x-ms-range")
+	return hasRange || request.Header.Get("Range") != ""
+}
+
+// isHedgingEnabled says whether AZCOPY_HEDGE_CHUNK_REQUESTS is true
+func isHedgingEnabled() bool {
+	shouldHedgeOncer.Do(func() {
+		raw := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.HedgeChunkRequests())
+		shouldHedgeChunkRequests = strings.ToLower(raw) == "true"
+	})
+	return shouldHedgeChunkRequests
+}
+
+// newHedgedRequestPolicyFactory creates a factory for a policy which, when AZCOPY_HEDGE_CHUNK_REQUESTS is true, sends a second copy of
+// any chunk request that is slower than most recent ones, and takes whichever response comes back first.
+// That trims the long tail of chunks that happen to be served by a slow server node
+func newHedgedRequestPolicyFactory() pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			if !isHedgingEnabled() || !isHedgeableChunkRequest(request) {
+				return next.Do(ctx, request)
+			}
+			return doHedged(ctx, request, next, hedgeLatencies.trackerFor(chunkLatencyKindOf(request)), func() {
+				if po.ShouldLog(pipeline.LogInfo) {
+					po.Log(pipeline.LogInfo, "Chunk request is slower than usual, so sending another copy of it: "+request.URL.Path)
+				}
+			})
+		}
+	})
+}
+
+type hedgeResult struct {
+	response pipeline.Response
+	err      error
+	cancel   context.CancelFunc
+}
+
+// doHedged sends the request, and if it takes longer than most recent ones, sends a copy of it too, calling onHedge when it does so
+func doHedged(ctx context.Context, request pipeline.Request, next pipeline.Policy, latencies *chunkLatencyTracker, onHedge func()) (pipeline.Response, error) {
+	start := time.Now()
+	threshold, ok := latencies.percentile(hedgeLatencyPercentile)
+	if !ok {
+		response, err := next.Do(ctx, request)
+		if err == nil {
+			latencies.record(time.Since(start))
+		}
+		return response, err
+	}
+
+	results := make(chan hedgeResult, 2)
+	send := func(r pipeline.Request) {
+		// each copy gets its own context, so that the slower one can be cancelled without affecting the other
+		sendCtx, cancel := context.WithCancel(ctx)
+		response, err := next.Do(sendCtx, r)
+		results <- hedgeResult{response: response, err: err, cancel: cancel}
+	}
+
+	go send(request)
+	inFlight := 1
+
+	timer := time.NewTimer(threshold)
+	defer timer.Stop()
+
+	var winner hedgeResult
+	select {
+	case winner = <-results:
+		inFlight--
+	case <-timer.C:
+		onHedge()
+		go send(request.Copy())
+		inFlight++
+		winner = <-results
+		inFlight--
+	}
+
+	// if the first to come back failed outright, the other may yet succeed
+	if winner.err != nil && inFlight > 0 {
+		winner.cancel()
+		winner = <-results
+		inFlight--
+	}
+
+	if inFlight > 0 {
+		go func() {
+			loser := <-results
+			loser.cancel()
+			if loser.err == nil && loser.response != nil && loser.response.Response() != nil {
+				loser.response.Response().Body.Close()
+			}
+		}()
+	}
+
+	if winner.err != nil {
+		winner.cancel()
+		return winner.response, winner.err
+	}
+	latencies.record(time.Since(start))
+
+	// the body is read after we return, so the winner's context must stay alive until then
+	if httpResponse := winner.response.Response(); httpResponse != nil && httpResponse.Body != nil {
+		httpResponse.Body = &cancelOnCloseBody{ReadCloser: httpResponse.Body, cancel: winner.cancel}
+	} else {
+		winner.cancel()
+	}
+	return winner.response, nil
+}
+
+// cancelOnCloseBody releases the context of a response once its body has been read
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// hedgeFetchFunc requests the part of a chunk that starts at offset (relative to the start of the chunk) and runs to its end
+type hedgeFetchFunc func(ctx context.Context, offset int64) (io.ReadCloser, error)
+
+// hedgeChunkBody wraps the body of a downloaded chunk so that, when AZCOPY_HEDGE_CHUNK_REQUESTS is true, a chunk that is slower
+// than most recent ones of the same size has the rest of its range requested again, and is completed by whichever copy gets there first.
+// The primary's request must have been sent with a context that abandonPrimary cancels, since closing a retrying body only makes it retry.
+// start is when the primary's request was sent
+func hedgeChunkBody(ctx context.Context, primary io.ReadCloser, abandonPrimary context.CancelFunc, start time.Time, length int64, fetch hedgeFetchFunc, onHedge func()) io.ReadCloser {
+	if !isHedgingEnabled() {
+		return primary
+	}
+	latencies := hedgeLatencies.trackerFor(chunkLatencyKind{operation: "get", sizeBucket: bits.Len64(uint64(length))})
+	return newHedgedChunkBody(ctx, primary, abandonPrimary, start, length, latencies, fetch, onHedge)
+}
+
+type primaryChunkRead struct {
+	n   int
+	err error
+}
+
+type hedgedChunkFetch struct {
+	offset int64
+	data   []byte
+	err    error
+}
+
+// hedgedChunkBody reads a chunk from its primary response, until that is overtaken by a hedged request for the rest of the chunk.
+// Reads of the primary are done into a buffer of our own, by a goroutine, so that an abandoned read can never write into the caller's buffer
+type hedgedChunkBody struct {
+	ctx            context.Context
+	hedgeCtx       context.Context
+	cancelHedge    context.CancelFunc
+	primary        io.ReadCloser
+	abandonPrimary context.CancelFunc
+	start          time.Time
+	length         int64
+	latencies      *chunkLatencyTracker
+	fetch          hedgeFetchFunc
+	onHedge        func()
+
+	pos         int64
+	buf         []byte
+	pending     chan primaryChunkRead // non-nil while a read of the primary is outstanding
+	hedgeTimer  *time.Timer
+	hedgeDue    <-chan time.Time
+	hedgeDone   chan hedgedChunkFetch
+	hedged      []byte // once non-nil, the rest of the chunk is read from here
+	hedgeOffset int64
+	recorded    bool
+}
+
+func newHedgedChunkBody(ctx context.Context, primary io.ReadCloser, abandonPrimary context.CancelFunc, start time.Time, length int64,
+	latencies *chunkLatencyTracker, fetch hedgeFetchFunc, onHedge func()) *hedgedChunkBody {
+	hedgeCtx, cancelHedge := context.WithCancel(ctx)
+	b := &hedgedChunkBody{
+		ctx:            ctx,
+		hedgeCtx:       hedgeCtx,
+		cancelHedge:    cancelHedge,
+		primary:        primary,
+		abandonPrimary: abandonPrimary,
+		start:          start,
+		length:         length,
+		latencies:      latencies,
+		fetch:          fetch,
+		onHedge:        onHedge,
+	}
+	if threshold, ok := latencies.percentile(hedgeLatencyPercentile); ok {
+		b.hedgeTimer = time.NewTimer(threshold - time.Since(start))
+		b.hedgeDue = b.hedgeTimer.C
+	}
+	return b
+}
+
+func (b *hedgedChunkBody) Read(p []byte) (int, error) {
+	if b.hedged != nil {
+		return b.readHedged(p)
+	}
+	if b.pos >= b.length {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if b.pending == nil {
+		if len(b.buf) < len(p) {
+			b.buf = make([]byte, len(p))
+		}
+		b.pending = make(chan primaryChunkRead, 1)
+		go func(buf []byte, done chan<- primaryChunkRead) {
+			n, err := b.primary.Read(buf)
+			done <- primaryChunkRead{n: n, err: err}
+		}(b.buf[:len(p)], b.pending)
+	}
+
+	for {
+		select {
+		case r := <-b.pending:
+			b.pending = nil
+			copy(p, b.buf[:r.n])
+			b.pos += int64(r.n)
+			if r.err != nil || b.pos >= b.length {
+				// the primary has finished, one way or the other, so any hedge is no longer needed
+				if r.err == nil || r.err == io.EOF {
+					b.recordLatency()
+				}
+				b.stopHedging()
+			}
+			return r.n, r.err
+		case <-b.hedgeDue:
+			b.hedgeDue = nil
+			b.startHedge()
+		case h := <-b.hedgeDone:
+			b.hedgeDone = nil
+			if h.err != nil {
+				continue // the primary may yet succeed
+			}
+
+			// switch to the hedge. The outstanding read keeps its buffer, and whatever it returns is ignored
+			b.hedged = h.data
+			b.hedgeOffset = h.offset
+			b.pending = nil
+			b.buf = nil
+			b.abandonPrimary()
+			_ = b.primary.Close()
+			b.recordLatency()
+			b.stopHedging()
+			return b.readHedged(p)
+		case <-b.ctx.Done():
+			return 0, b.ctx.Err()
+		}
+	}
+}
+
+// startHedge requests the part of the chunk that hasn't been read yet, and reads it in full, since only then do we know that it has overtaken the primary
+func (b *hedgedChunkBody) startHedge() {
+	b.onHedge()
+	b.hedgeDone = make(chan hedgedChunkFetch, 1)
+	go func(offset int64, done chan<- hedgedChunkFetch) {
+		body, err := b.fetch(b.hedgeCtx, offset)
+		if err != nil {
+			done <- hedgedChunkFetch{err: err}
+			return
+		}
+		defer body.Close()
+		data := make([]byte, b.length-offset)
+		_, err = io.ReadFull(body, data)
+		done <- hedgedChunkFetch{offset: offset, data: data, err: err}
+	}(b.pos, b.hedgeDone)
+}
+
+func (b *hedgedChunkBody) readHedged(p []byte) (int, error) {
+	n := copy(p, b.hedged[b.pos-b.hedgeOffset:])
+	b.pos += int64(n)
+	if b.pos >= b.length {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (b *hedgedChunkBody) recordLatency() {
+	if !b.recorded {
+		b.recorded = true
+		b.latencies.record(time.Since(b.start))
+	}
+}
+
+func (b *hedgedChunkBody) stopHedging() {
+	if b.hedgeTimer != nil {
+		b.hedgeTimer.Stop()
+	}
+	b.hedgeDue = nil
+	b.cancelHedge()
+}
+
+// Close closes the primary, which is how a stalled chunk is made to retry.
+// Once the hedge has taken over, the primary is already closed and abandoned, so closing it again does no harm
+func (b *hedgedChunkBody) Close() error {
+	return b.primary.Close()
+}
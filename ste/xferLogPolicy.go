@@ -262,6 +262,14 @@ func prepareRequestForServiceLogging(request pipeline.Request) *http.Request {
 			}
 		}
 	}
+
+	// The source's bearer token must never be logged.
+	if exist, key := doesHeaderExistCaseInsensitive(req.Header, xMsCopySourceAuthorizationHeader); exist {
+		if req == request {
+			req = request.Copy()
+		}
+		req.Header.Set(key, "REDACTED")
+	}
 	return req.Request
 }
 
@@ -0,0 +1,276 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+const (
+	// a chunk is only abandoned after its throughput has been below the floor for the whole of the period set by
+	// AZCOPY_MIN_CHUNK_THROUGHPUT_PERIOD, measured as this many windows in a row. That way a brief stall is tolerated,
+	// but a degraded connection is not waited out
+	defaultMinThroughputPeriod = 30 * time.Second
+	minThroughputSlowWindows   = 3
+
+	// how often, per window, the monitors are checked. So a window may end up to this fraction of its length late
+	minThroughputChecksPerWindow = 5
+)
+
+var (
+	minChunkBytesPerSecond int64
+	minThroughputOncer     sync.Once
+
+	// all the monitors share one ticker, rather than each chunk request having a goroutine of its own
+	throughputMonitors *throughputMonitorSet
+)
+
+// errChunkTooSlow is returned when a chunk was abandoned for being too slow. It is a net.Error, so that both
+// our retry policy and the SDK's retry reader try again, which they do on a fresh connection
+type errChunkTooSlow struct{}
+
+func (errChunkTooSlow) Error() string {
+	return "chunk was abandoned, since its throughput stayed below the minimum set by " + common.EEnvironmentVariable.MinChunkThroughput().Name
+}
+func (errChunkTooSlow) Timeout() bool   { return true }
+func (errChunkTooSlow) Temporary() bool { return true }
+
+// throughputMonitor cancels a chunk request whose data keeps moving more slowly than the floor
+type throughputMonitor struct {
+	atomicBytes          int64
+	atomicFirstByteNanos int64 // when the first byte moved, as UnixNano. 0 until then
+	atomicDone           int32
+	atomicTripped        int32
+	floor                int64 // bytes per second
+	cancel               context.CancelFunc
+
+	// only used by the checks of the throughputMonitorSet
+	windowStart      time.Time
+	windowStartBytes int64
+	slowWindows      int
+}
+
+func newThroughputMonitor(floor int64, cancel context.CancelFunc) *throughputMonitor {
+	m := &throughputMonitor{floor: floor, cancel: cancel}
+	throughputMonitors.add(m)
+	return m
+}
+
+func isBelowFloor(bytes int64, window time.Duration, floor int64) bool {
+	return float64(bytes)/window.Seconds() < float64(floor)
+}
+
+func (m *throughputMonitor) add(n int) {
+	if n > 0 && atomic.LoadInt64(&m.atomicFirstByteNanos) == 0 {
+		atomic.CompareAndSwapInt64(&m.atomicFirstByteNanos, 0, time.Now().UnixNano())
+	}
+	atomic.AddInt64(&m.atomicBytes, int64(n))
+}
+func (m *throughputMonitor) stop() { atomic.StoreInt32(&m.atomicDone, 1) }
+func (m *throughputMonitor) tripped() bool {
+	return atomic.LoadInt32(&m.atomicTripped) == 1
+}
+
+// check looks at the window that's ending, if there is one, and says whether the monitor has finished watching its chunk.
+// Windows only start once the first byte has moved, so that time spent waiting for the service to respond isn't counted
+func (m *throughputMonitor) check(now time.Time, window time.Duration) (finished bool) {
+	if atomic.LoadInt32(&m.atomicDone) == 1 {
+		return true
+	}
+
+	firstByte := atomic.LoadInt64(&m.atomicFirstByteNanos)
+	if firstByte == 0 {
+		return false
+	}
+	if m.windowStart.IsZero() {
+		m.windowStart = time.Unix(0, firstByte)
+	}
+
+	elapsed := now.Sub(m.windowStart)
+	if elapsed < window {
+		return false
+	}
+
+	bytes := atomic.LoadInt64(&m.atomicBytes)
+	if isBelowFloor(bytes-m.windowStartBytes, elapsed, m.floor) {
+		m.slowWindows++
+	} else {
+		m.slowWindows = 0
+	}
+	m.windowStart = now
+	m.windowStartBytes = bytes
+
+	if m.slowWindows >= minThroughputSlowWindows {
+		atomic.StoreInt32(&m.atomicTripped, 1)
+		m.cancel()
+		return true
+	}
+	return false
+}
+
+// throughputMonitorSet checks all the active monitors on one shared ticker
+type throughputMonitorSet struct {
+	window    time.Duration
+	startOnce sync.Once
+
+	lock     sync.Mutex
+	monitors map[*throughputMonitor]struct{}
+}
+
+func newThroughputMonitorSet(window time.Duration) *throughputMonitorSet {
+	return &throughputMonitorSet{window: window, monitors: make(map[*throughputMonitor]struct{})}
+}
+
+func (s *throughputMonitorSet) add(m *throughputMonitor) {
+	s.startOnce.Do(func() { go s.run() })
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.monitors[m] = struct{}{}
+}
+
+func (s *throughputMonitorSet) run() {
+	ticker := time.NewTicker(s.window / minThroughputChecksPerWindow)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.checkAll(now)
+	}
+}
+
+// checkAll must not be called concurrently with itself, since the monitors' windows are not locked
+func (s *throughputMonitorSet) checkAll(now time.Time) {
+	s.lock.Lock()
+	active := make([]*throughputMonitor, 0, len(s.monitors))
+	for m := range s.monitors {
+		active = append(active, m)
+	}
+	s.lock.Unlock()
+
+	for _, m := range active {
+		if m.check(now, s.window) {
+			s.lock.Lock()
+			delete(s.monitors, m)
+			s.lock.Unlock()
+		}
+	}
+}
+
+// throughputMonitoredBody counts the bytes read through it, and reports errChunkTooSlow if the monitor abandoned the chunk
+type throughputMonitoredBody struct {
+	io.ReadCloser
+	monitor *throughputMonitor
+}
+
+func (b *throughputMonitoredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.monitor.add(n)
+	if err == io.EOF {
+		b.monitor.stop()
+	} else if err != nil && b.monitor.tripped() {
+		err = errChunkTooSlow{}
+	}
+	return n, err
+}
+
+func (b *throughputMonitoredBody) Close() error {
+	b.monitor.stop()
+	return b.ReadCloser.Close()
+}
+
+// Seek lets the retry policy rewind the body of an upload, just as it could before the monitor was wrapped around it
+func (b *throughputMonitoredBody) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := b.ReadCloser.(io.Seeker)
+	if !ok {
+		return 0, errors.New("the body of this request cannot be rewound")
+	}
+	return seeker.Seek(offset, whence)
+}
+
+// newMinThroughputPolicyFactory creates a factory for a policy which, when AZCOPY_MIN_CHUNK_THROUGHPUT is set, abandons any chunk
+// upload or download whose data moves more slowly than that for a sustained period. Without it, a chunk on a degraded connection
+// would only be retried once its try timed out, many minutes later
+func newMinThroughputPolicyFactory() pipeline.Factory {
+	minThroughputOncer.Do(func() {
+		raw := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.MinChunkThroughput())
+		if kbps, err := strconv.ParseInt(raw, 10, 64); err == nil && kbps > 0 {
+			minChunkBytesPerSecond = kbps * 1024
+		}
+
+		period := defaultMinThroughputPeriod
+		raw = common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.MinChunkThroughputPeriod())
+		if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil && seconds > 0 {
+			period = time.Duration(seconds) * time.Second
+		}
+		throughputMonitors = newThroughputMonitorSet(period / minThroughputSlowWindows)
+	})
+
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			isUpload := request.Method == http.MethodPut && request.ContentLength > 0 && request.Body != nil
+			if minChunkBytesPerSecond == 0 || !(isUpload || isRangedGet(request)) {
+				return next.Do(ctx, request)
+			}
+
+			monitoredCtx, cancel := context.WithCancel(ctx)
+			monitor := newThroughputMonitor(minChunkBytesPerSecond, cancel)
+			if isUpload {
+				request.Body = &throughputMonitoredBody{ReadCloser: request.Body, monitor: monitor}
+			}
+
+			response, err := next.Do(monitoredCtx, request)
+			if isUpload {
+				monitor.stop() // once the response is back, all the data has been sent
+			}
+			if err != nil {
+				monitor.stop()
+				cancel()
+				if monitor.tripped() {
+					return response, errChunkTooSlow{}
+				}
+				return response, err
+			}
+
+			// the body is read after we return, so the context must stay alive until then
+			if httpResponse := response.Response(); httpResponse != nil && httpResponse.Body != nil {
+				var body io.ReadCloser = httpResponse.Body
+				if !isUpload {
+					body = &throughputMonitoredBody{ReadCloser: body, monitor: monitor}
+				}
+				httpResponse.Body = &cancelOnCloseBody{ReadCloser: body, cancel: cancel}
+			} else {
+				monitor.stop()
+				cancel()
+			}
+			return response, nil
+		}
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// secondaryReadReceiver should be implemented by code that wishes to know when one of its reads was served by the
+// secondary endpoint of an RA-GRS account. It registers itself into the context with withSecondaryReadNotification
+type secondaryReadReceiver interface {
+	SecondaryReadCallback(host string)
+}
+
+var secondaryReadNotifyContextKey = contextKey{"secondaryReadNotify"}
+
+// withSecondaryReadNotification returns a context that contains a receiver, which the retry policies will call
+// whenever a request succeeds against the secondary host
+func withSecondaryReadNotification(ctx context.Context, r secondaryReadReceiver) context.Context {
+	return context.WithValue(ctx, secondaryReadNotifyContextKey, r)
+}
+
+func notifySecondaryRead(ctx context.Context, host string) {
+	if r, ok := ctx.Value(secondaryReadNotifyContextKey).(secondaryReadReceiver); ok {
+		r.SecondaryReadCallback(host)
+	}
+}
+
+var readFromSecondaryOnce sync.Once
+var readFromSecondary bool
+
+// readFromSecondaryEnabled says whether the user has opted in, with AZCOPY_READ_FROM_SECONDARY, to downloads falling
+// back to the secondary endpoint when the primary one keeps failing
+func readFromSecondaryEnabled() bool {
+	readFromSecondaryOnce.Do(func() {
+		raw := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.ReadFromSecondary())
+		readFromSecondary = strings.EqualFold(raw, "true")
+	})
+	return readFromSecondary
+}
+
+// secondaryHostFor returns the secondary endpoint's host for the account that serves sourceRoot,
+// e.g. myaccount-secondary.blob.core.windows.net for myaccount.blob.core.windows.net.
+// It returns "" when the source isn't on a standard storage endpoint (e.g. the emulator, or a custom domain), since
+// there's no way to know where, or whether, such an account has a secondary
+func secondaryHostFor(sourceRoot string) string {
+	u, err := url.Parse(sourceRoot)
+	if err != nil || u.Port() != "" {
+		return ""
+	}
+	host := u.Hostname()
+	if !strings.Contains(host, ".core.") {
+		return ""
+	}
+	dot := strings.Index(host, ".")
+	account := host[:dot]
+	if account == "" || strings.HasSuffix(account, "-secondary") {
+		return ""
+	}
+	return account + "-secondary" + host[dot:]
+}
+
+// checkSecondaryReadIsCurrent fails a response that the secondary endpoint served from a different version of the blob
+// than the one that was enumerated. While the secondary lags behind the primary, it can still have an older version,
+// which the If-Unmodified-Since condition of the download lets through, so that one file could mix the two versions
+func checkSecondaryReadIsCurrent(resp *http.Response, lastModified time.Time) error {
+	if resp == nil || resp.Request == nil || !isSecondaryHost(resp.Request.URL.Hostname()) {
+		return nil
+	}
+	served, err := time.Parse(http.TimeFormat, resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return fmt.Errorf("the secondary endpoint %s didn't say when the blob was last modified", resp.Request.URL.Host)
+	}
+	if !served.Equal(lastModified) {
+		return fmt.Errorf("the secondary endpoint %s has the version of the blob last modified at %v, not the one last modified at %v, "+
+			"since it hasn't caught up with the primary yet", resp.Request.URL.Host, served.UTC(), lastModified.UTC())
+	}
+	return nil
+}
+
+// isSecondaryHost says whether the host is the secondary endpoint of an account, as named by secondaryHostFor
+func isSecondaryHost(host string) bool {
+	dot := strings.Index(host, ".")
+	return dot > 0 && strings.HasSuffix(host[:dot], "-secondary")
+}
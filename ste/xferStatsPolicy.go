@@ -23,10 +23,12 @@ package ste
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -39,13 +41,26 @@ type pipelineNetworkStats struct {
 	atomic503CountIOPS         int64
 	atomic503CountUnknown      int64 // counts 503's when we don't know the reason
 	atomicE2ETotalMilliseconds int64 // should this be nanoseconds?  Not really needed, given typical minimum operation lengths that we observe
-	atomicStartSeconds         int64
-	nocopy                     common.NoCopy
-	tunerInterface             ConcurrencyTuner
+	atomicFailedRequestBytes   int64 // request body bytes of tries that failed, which the retry policy will (usually) send again
+	atomicRetryableTryCount    int64 // tries that failed in a way that's retried, counted from the start, for the summary
+	atomicServerBusyCount      int64 // 503s, counted from the start, for the summary
+	// how requests got their connections, counted from the start, for the summary. Lots of new connections (or few
+	// resumed TLS sessions) point at something, like a proxy or firewall, that doesn't let connections be kept alive
+	atomicNewConnectionCount     int64
+	atomicReusedConnectionCount  int64
+	atomicTLSHandshakeCount      int64
+	atomicResumedTLSSessionCount int64
+	atomicStartSeconds           int64
+	nocopy                       common.NoCopy
+	tunerInterface               ConcurrencyTuner
+	latencies                    *chunkLatencyTracker // of recent requests, for the summary's percentiles
 }
 
+// how many recent request latencies the summary's percentiles are worked out from
+const requestLatencyWindow = 10000
+
 func newPipelineNetworkStats(tunerInterface ConcurrencyTuner) *pipelineNetworkStats {
-	s := &pipelineNetworkStats{tunerInterface: tunerInterface}
+	s := &pipelineNetworkStats{tunerInterface: tunerInterface, latencies: newChunkLatencyTracker(requestLatencyWindow)}
 	tunerWillCallUs := tunerInterface.RequestCallbackWhenStable(s.start) // we want to start gather stats after the tuner has reached a stable value. No point in gathering them earlier
 	if !tunerWillCallUs {
 		// assume tuner is inactive, and start ourselves now
@@ -119,6 +134,59 @@ func (s *pipelineNetworkStats) GetTotalRetries() int64 {
 		atomic.LoadInt64(&s.atomic503CountUnknown)
 }
 
+// GetRetriedBytes returns how many request body bytes were sent in tries that failed, whether or not the stats have started,
+// since they're for reconciling the job's traffic rather than for tuning
+func (s *pipelineNetworkStats) GetRetriedBytes() int64 {
+	s.nocopy.Check()
+	return atomic.LoadInt64(&s.atomicFailedRequestBytes)
+}
+
+// GetRetryCounts returns how many tries failed in a way that's retried, and how many of those were 503s, since the job started
+func (s *pipelineNetworkStats) GetRetryCounts() (retries, serverBusy int64) {
+	s.nocopy.Check()
+	return atomic.LoadInt64(&s.atomicRetryableTryCount), atomic.LoadInt64(&s.atomicServerBusyCount)
+}
+
+// GetConnectionCounts returns how many requests opened a new connection and how many re-used a kept-alive one, and how
+// many TLS handshakes were done and how many of those resumed an earlier session, since the job started
+func (s *pipelineNetworkStats) GetConnectionCounts() (newConns, reusedConns, tlsHandshakes, resumedTLSSessions int64) {
+	s.nocopy.Check()
+	return atomic.LoadInt64(&s.atomicNewConnectionCount), atomic.LoadInt64(&s.atomicReusedConnectionCount),
+		atomic.LoadInt64(&s.atomicTLSHandshakeCount), atomic.LoadInt64(&s.atomicResumedTLSSessionCount)
+}
+
+// connectionTrace returns a trace that counts, into these stats, how the HTTP client connects for a request
+func (s *pipelineNetworkStats) connectionTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				atomic.AddInt64(&s.atomicReusedConnectionCount, 1)
+			} else {
+				atomic.AddInt64(&s.atomicNewConnectionCount, 1)
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				return
+			}
+			atomic.AddInt64(&s.atomicTLSHandshakeCount, 1)
+			if state.DidResume {
+				atomic.AddInt64(&s.atomicResumedTLSSessionCount, 1)
+			}
+		},
+	}
+}
+
+// E2EMillisecondsPercentile returns the end-to-end time within which the given fraction of recent requests completed,
+// or zero if there haven't been enough requests yet
+func (s *pipelineNetworkStats) E2EMillisecondsPercentile(p float64) int {
+	s.nocopy.Check()
+	if d, ok := s.latencies.percentile(p); ok {
+		return int(d.Milliseconds())
+	}
+	return 0
+}
+
 func (s *pipelineNetworkStats) IOPSServerBusyPercentage() float32 {
 	s.nocopy.Check()
 	ops := float32(atomic.LoadInt64(&s.atomicOperationCount))
@@ -168,12 +236,27 @@ type xferStatsPolicy struct {
 func (p *xferStatsPolicy) Do(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
 	start := time.Now()
 
+	if p.stats != nil {
+		ctx = httptrace.WithClientTrace(ctx, p.stats.connectionTrace())
+	}
 	resp, err := p.next.Do(ctx, request)
 
 	if p.stats != nil {
+		if isRetryableTry(resp, err) {
+			atomic.AddInt64(&p.stats.atomicRetryableTryCount, 1)
+			if err == nil && resp.Response().StatusCode == http.StatusServiceUnavailable {
+				atomic.AddInt64(&p.stats.atomicServerBusyCount, 1)
+			}
+			if request.ContentLength > 0 {
+				atomic.AddInt64(&p.stats.atomicFailedRequestBytes, request.ContentLength)
+			}
+		}
+
 		if p.stats.IsStarted() {
+			e2e := time.Since(start)
 			atomic.AddInt64(&p.stats.atomicOperationCount, 1)
-			atomic.AddInt64(&p.stats.atomicE2ETotalMilliseconds, int64(time.Since(start).Seconds()*1000))
+			atomic.AddInt64(&p.stats.atomicE2ETotalMilliseconds, int64(e2e.Seconds()*1000))
+			p.stats.latencies.record(e2e)
 
 			if err != nil && !isContextCancelledError(err) {
 				// no response from server
@@ -198,6 +281,19 @@ func (p *xferStatsPolicy) Do(ctx context.Context, request pipeline.Request) (pip
 	return resp, err
 }
 
+// isRetryableTry says whether a try failed in a way that the retry policies retry. We're below the method factory, so
+// error statuses haven't been turned into errors yet, and have to be looked at here
+func isRetryableTry(resp pipeline.Response, err error) bool {
+	if err != nil {
+		return !isContextCancelledError(err)
+	}
+	if resp == nil || resp.Response() == nil {
+		return false
+	}
+	status := resp.Response().StatusCode
+	return status >= http.StatusInternalServerError || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
 // transparentlyReadBody reads the response body, and then (because body is read-once-only) replaces it with
 // a new body that will return the same content to anyone else who reads it.
 // This looks like a fairly common approach in Go, e.g. https://stackoverflow.com/a/23077519
@@ -0,0 +1,45 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type blobTierAllowedSuite struct{}
+
+var _ = chk.Suite(&blobTierAllowedSuite{})
+
+func (s *blobTierAllowedSuite) TestPageBlobTiersNeedPremiumStorageV2(c *chk.C) {
+	var tier common.PageBlobTier
+	c.Assert(tier.Parse("P80"), chk.IsNil)
+	c.Assert(tier, chk.Equals, common.EPageBlobTier.P80())
+
+	c.Assert(BlobTierAllowedOn("Premium_LRS", "StorageV2", tier.ToAccessTierType()), chk.Equals, true)
+	c.Assert(BlobTierAllowedOn("Premium_LRS", "BlockBlobStorage", tier.ToAccessTierType()), chk.Equals, false)
+	c.Assert(BlobTierAllowedOn("Standard_LRS", "StorageV2", tier.ToAccessTierType()), chk.Equals, false)
+
+	// standard accounts still take the block blob tiers
+	c.Assert(BlobTierAllowedOn("Standard_LRS", "StorageV2", azblob.AccessTierCool), chk.Equals, true)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/base64"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type blockBlobCommitOnlySuite struct{}
+
+var _ = chk.Suite(&blockBlobCommitOnlySuite{})
+
+// prefixOnlyJptm is just enough of a transfer for a sender to name its blocks
+type prefixOnlyJptm struct {
+	IJobPartTransferMgr
+	prefix string
+}
+
+func (j prefixOnlyJptm) BlockIDPrefix() string { return j.prefix }
+
+func (s *blockBlobCommitOnlySuite) TestCommitPendingIsKeptInThePlan(c *chk.C) {
+	jppt := &JobPartPlanTransfer{}
+	c.Assert(jppt.CommitPending(), chk.Equals, false)
+
+	jppt.SetCommitPending(true)
+	c.Assert(jppt.CommitPending(), chk.Equals, true)
+	c.Assert(jppt.atomicCommitPending, chk.Equals, int32(1))
+
+	jppt.SetCommitPending(false)
+	c.Assert(jppt.CommitPending(), chk.Equals, false)
+}
+
+func (s *blockBlobCommitOnlySuite) TestBlockIDsAreStableAndWithinTheLimit(c *chk.C) {
+	prefix := common.NewJobID().String() + "-00000001-00000002-"
+	sender := &blockBlobSenderBase{jptm: prefixOnlyJptm{prefix: prefix}}
+
+	id := sender.generateEncodedBlockID(3)
+	c.Assert(sender.generateEncodedBlockID(3), chk.Equals, id)
+	c.Assert(sender.generateEncodedBlockID(4), chk.Not(chk.Equals), id)
+
+	decoded, err := base64.StdEncoding.DecodeString(id)
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(decoded) <= 64, chk.Equals, true)
+}
+
+func (s *blockBlobCommitOnlySuite) TestAllBlocksStagedChecksEveryBlockAndItsSize(c *chk.C) {
+	sender := &blockBlobSenderBase{jptm: prefixOnlyJptm{prefix: "p-"}, chunkSize: 10, numChunks: 3}
+	staged := map[string]int64{
+		sender.generateEncodedBlockID(0): 10,
+		sender.generateEncodedBlockID(1): 10,
+		sender.generateEncodedBlockID(2): 5,
+		"someone else's":                 10,
+	}
+	c.Assert(sender.allBlocksStaged(staged, 25), chk.Equals, true)
+
+	// the last block is short
+	staged[sender.generateEncodedBlockID(2)] = 10
+	c.Assert(sender.allBlocksStaged(staged, 25), chk.Equals, false)
+
+	// a block is missing
+	delete(staged, sender.generateEncodedBlockID(1))
+	c.Assert(sender.allBlocksStaged(staged, 30), chk.Equals, false)
+}
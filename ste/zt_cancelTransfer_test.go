@@ -0,0 +1,64 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type cancelTransferSuite struct{}
+
+var _ = chk.Suite(&cancelTransferSuite{})
+
+func (s *cancelTransferSuite) TestCancelUnlessFinished(c *chk.C) {
+	jppt := &JobPartPlanTransfer{}
+	jppt.SetTransferStatus(common.ETransferStatus.Started(), true)
+	status, cancelled := jppt.CancelUnlessFinished()
+	c.Assert(cancelled, chk.Equals, true)
+	c.Assert(status, chk.Equals, common.ETransferStatus.Started())
+	c.Assert(jppt.TransferStatus(), chk.Equals, common.ETransferStatus.Cancelled())
+
+	for _, finished := range []common.TransferStatus{common.ETransferStatus.Success(), common.ETransferStatus.Failed(),
+		common.ETransferStatus.SkippedEntityAlreadyExists(), common.ETransferStatus.Cancelled()} {
+		jppt.SetTransferStatus(finished, true)
+		status, cancelled = jppt.CancelUnlessFinished()
+		c.Assert(cancelled, chk.Equals, false)
+		c.Assert(status, chk.Equals, finished)
+		c.Assert(jppt.TransferStatus(), chk.Equals, finished)
+	}
+}
+
+func (s *cancelTransferSuite) TestTransferSeesCancellationInItsPlan(c *chk.C) {
+	jobCtx, cancelJob := context.WithCancel(context.Background())
+	defer cancelJob()
+	ctx, cancel := context.WithCancel(jobCtx)
+	jppt := &JobPartPlanTransfer{}
+	jptm := &jobPartTransferMgr{jobPartPlanTransfer: jppt, ctx: ctx, cancel: cancel}
+	c.Assert(jptm.WasCanceled(), chk.Equals, false)
+
+	jppt.CancelUnlessFinished()
+	c.Assert(jptm.WasCanceled(), chk.Equals, true)
+	c.Assert(ctx.Err(), chk.NotNil) // so that its requests in flight are stopped too
+	c.Assert(jobCtx.Err(), chk.IsNil)
+}
@@ -0,0 +1,59 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type computeJobXferSuite struct{}
+
+var _ = chk.Suite(&computeJobXferSuite{})
+
+func (s *computeJobXferSuite) TestEveryDownloadHasAnXfer(c *chk.C) {
+	for _, fromTo := range []common.FromTo{common.EFromTo.BlobLocal(), common.EFromTo.FileLocal(), common.EFromTo.BlobFSLocal()} {
+		c.Assert(computeJobXfer(fromTo, common.EBlobType.Detect(), false), chk.NotNil, chk.Commentf("%v", fromTo))
+	}
+}
+
+func (s *computeJobXferSuite) TestLocalCopyHasAnXfer(c *chk.C) {
+	c.Assert(computeJobXfer(common.EFromTo.LocalLocal(), common.EBlobType.Detect(), false), chk.NotNil)
+}
+
+func (s *computeJobXferSuite) TestEveryUploadHasAnXfer(c *chk.C) {
+	for _, fromTo := range []common.FromTo{common.EFromTo.LocalBlob(), common.EFromTo.LocalFile(), common.EFromTo.LocalBlobFS()} {
+		c.Assert(computeJobXfer(fromTo, common.EBlobType.Detect(), false), chk.NotNil, chk.Commentf("%v", fromTo))
+		c.Assert(computeJobXfer(fromTo, common.EBlobType.Detect(), true), chk.NotNil, chk.Commentf("%v, permissions only", fromTo))
+	}
+}
+
+func (s *computeJobXferSuite) TestSetPropertiesHasAnXfer(c *chk.C) {
+	c.Assert(computeJobXfer(common.EFromTo.BlobNone(), common.EBlobType.Detect(), false), chk.NotNil)
+}
+
+func (s *computeJobXferSuite) TestEveryServiceToServiceCopyHasAnXfer(c *chk.C) {
+	for _, fromTo := range []common.FromTo{common.EFromTo.BlobBlob(), common.EFromTo.FileBlob(), common.EFromTo.BlobFile(), common.EFromTo.FileFile(), common.EFromTo.BlobFSBlobFS(), common.EFromTo.S3Blob(), common.EFromTo.GCPBlob()} {
+		c.Assert(computeJobXfer(fromTo, common.EBlobType.Detect(), false), chk.NotNil, chk.Commentf("%v", fromTo))
+		c.Assert(computeJobXfer(fromTo, common.EBlobType.BlockBlob(), false), chk.NotNil, chk.Commentf("%v, to block blobs", fromTo))
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+)
+
+type connectionStatsSuite struct{}
+
+var _ = chk.Suite(&connectionStatsSuite{})
+
+func (s *connectionStatsSuite) TestStatsPolicyCountsConnections(c *chk.C) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data"))
+	}))
+	defer server.Close()
+	client := server.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+
+	stats := &pipelineNetworkStats{tunerInterface: &nullConcurrencyTuner{}, latencies: newChunkLatencyTracker(requestLatencyWindow)}
+	policy := &xferStatsPolicy{next: newAzcopyHTTPClientFactory(client).New(nil, nil), stats: stats}
+	serverURL, err := url.Parse(server.URL)
+	c.Assert(err, chk.IsNil)
+	get := func() {
+		request, err := pipeline.NewRequest(http.MethodGet, *serverURL, nil)
+		c.Assert(err, chk.IsNil)
+		resp, err := policy.Do(context.Background(), request)
+		c.Assert(err, chk.IsNil)
+		_, _ = ioutil.ReadAll(resp.Response().Body)
+		_ = resp.Response().Body.Close()
+	}
+
+	// one connection, kept alive
+	for i := 0; i < 3; i++ {
+		get()
+	}
+	newConns, reusedConns, tlsHandshakes, resumedTLSSessions := stats.GetConnectionCounts()
+	c.Assert(newConns, chk.Equals, int64(1))
+	c.Assert(reusedConns, chk.Equals, int64(2))
+	c.Assert(tlsHandshakes, chk.Equals, int64(1))
+	c.Assert(resumedTLSSessions, chk.Equals, int64(0))
+
+	// and when it's dropped, its replacement resumes the session
+	client.CloseIdleConnections()
+	get()
+	newConns, reusedConns, tlsHandshakes, resumedTLSSessions = stats.GetConnectionCounts()
+	c.Assert(newConns, chk.Equals, int64(2))
+	c.Assert(reusedConns, chk.Equals, int64(2))
+	c.Assert(tlsHandshakes, chk.Equals, int64(2))
+	c.Assert(resumedTLSSessions, chk.Equals, int64(1))
+}
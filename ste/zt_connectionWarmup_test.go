@@ -0,0 +1,80 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type connectionWarmupSuite struct{}
+
+var _ = chk.Suite(&connectionWarmupSuite{})
+
+func (s *connectionWarmupSuite) TestEndpointsToWarmUp(c *chk.C) {
+	c.Assert(endpointsToWarmUp(common.EFromTo.LocalBlob(), "/data", "https://acct.blob.core.windows.net/ctr/dir"),
+		chk.DeepEquals, []string{"https://acct.blob.core.windows.net/"})
+	c.Assert(endpointsToWarmUp(common.EFromTo.BlobBlob(), "https://src.blob.core.windows.net/a", "https://dst.blob.core.windows.net/b"),
+		chk.DeepEquals, []string{"https://src.blob.core.windows.net/", "https://dst.blob.core.windows.net/"})
+	c.Assert(endpointsToWarmUp(common.EFromTo.BlobBlob(), "https://acct.blob.core.windows.net/a", "https://acct.blob.core.windows.net/b"),
+		chk.DeepEquals, []string{"https://acct.blob.core.windows.net/"})
+	c.Assert(endpointsToWarmUp(common.EFromTo.S3Blob(), "https://s3.amazonaws.com/bucket", "http://127.0.0.1:10000/devstoreaccount1/ctr"),
+		chk.DeepEquals, []string{"http://127.0.0.1:10000/"})
+	c.Assert(endpointsToWarmUp(common.EFromTo.BlobLocal(), "not a url", "/data"), chk.HasLen, 0)
+}
+
+func (s *connectionWarmupSuite) TestWarmUpOpensDistinctConnections(c *chk.C) {
+	var newConns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden) // like the service does, for a request it can't authorize
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&newConns, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client := server.Client()
+	client.Transport.(*http.Transport).MaxIdleConnsPerHost = 6
+
+	var logged []string
+	warmUpEndpoints(context.Background(), client, []string{server.URL + "/"}, 10, func(s string) { logged = append(logged, s) })
+
+	// asking for more than the client keeps idle just gets as many as it keeps
+	c.Assert(atomic.LoadInt32(&newConns), chk.Equals, int32(6))
+	c.Assert(logged, chk.HasLen, 2)
+
+	// and the transfers find them ready
+	for i := 0; i < 6; i++ {
+		resp, err := client.Get(server.URL + "/ctr/blob")
+		c.Assert(err, chk.IsNil)
+		resp.Body.Close()
+	}
+	c.Assert(atomic.LoadInt32(&newConns), chk.Equals, int32(6))
+}
@@ -0,0 +1,70 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type copySourceAuthSuite struct{}
+
+var _ = chk.Suite(&copySourceAuthSuite{})
+
+type copySourceAuthTestJptm struct {
+	IJobPartTransferMgr
+	authorized bool
+}
+
+func (j copySourceAuthTestJptm) Context() context.Context     { return context.Background() }
+func (j copySourceAuthTestJptm) IsCopySourceAuthorized() bool { return j.authorized }
+
+func (s *copySourceAuthSuite) TestCopyFromURLServiceVersion(c *chk.C) {
+	version := func(authorized bool) string {
+		return withCopyFromURLServiceVersion(copySourceAuthTestJptm{authorized: authorized}).Value(ServiceAPIVersionOverride).(string)
+	}
+	c.Assert(version(false), chk.Equals, azblob.ServiceVersion)
+	c.Assert(version(true) >= copySourceAuthorizationServiceVersion, chk.Equals, true)
+	c.Assert(version(true) >= azblob.ServiceVersion, chk.Equals, true)
+}
+
+func (s *copySourceAuthSuite) TestPolicyLeavesServiceVersionAlone(c *chk.C) {
+	var sent http.Header
+	next := pipeline.PolicyFunc(func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+		sent = request.Header
+		return nil, nil
+	})
+	policy := NewCopySourceAuthPolicyFactory(azblob.NewTokenCredential("token", nil)).New(next, nil)
+
+	u, _ := url.Parse("https://acct.blob.core.windows.net/c/b?comp=block")
+	request, _ := pipeline.NewRequest(http.MethodPut, *u, nil)
+	request.Header.Set("x-ms-version", "2019-12-12")
+	request.Header.Set(xMsCopySourceHeader, "https://src.blob.core.windows.net/c/b")
+	_, _ = policy.Do(context.Background(), request)
+
+	c.Assert(sent.Get(xMsCopySourceAuthorizationHeader), chk.Equals, "Bearer token")
+	c.Assert(sent.Get("x-ms-version"), chk.Equals, "2019-12-12")
+}
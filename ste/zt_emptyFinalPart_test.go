@@ -0,0 +1,72 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"unsafe"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+)
+
+type emptyFinalPartSuite struct{}
+
+var _ = chk.Suite(&emptyFinalPartSuite{})
+
+// partsDoneJobMgr records what the parts of a job report to it
+type partsDoneJobMgr struct {
+	IJobMgr
+	allTransfersScheduled bool
+	partsDone             int
+}
+
+func (m *partsDoneJobMgr) ConfirmAllTransfersScheduled()          { m.allTransfersScheduled = true }
+func (m *partsDoneJobMgr) ReportJobPartDone(jobPartProgressInfo)  { m.partsDone++ }
+func (m *partsDoneJobMgr) Log(pipeline.LogLevel, string)          {}
+func (m *partsDoneJobMgr) ShouldLog(level pipeline.LogLevel) bool { return false }
+
+func (s *emptyFinalPartSuite) TestEmptyFinalPartCompletes(c *chk.C) {
+	jobMgr := &partsDoneJobMgr{}
+	header := &JobPartPlanHeader{PartNum: 3, NumTransfers: 0, IsFinalPart: true}
+	jpm := &jobPartMgr{
+		jobMgr:  jobMgr,
+		planMMF: &JobPartPlanMMF{tracker: newMappedPlanTracker(), mapping: &fakeMappedPlan{}, atomicPlan: unsafe.Pointer(header)},
+	}
+
+	// the transfers were all sent in earlier parts, so the part that seals the job is done as soon as it's scheduled
+	jpm.ScheduleTransfers(context.Background())
+	c.Assert(jobMgr.allTransfersScheduled, chk.Equals, true)
+	c.Assert(jobMgr.partsDone, chk.Equals, 1)
+}
+
+func (s *emptyFinalPartSuite) TestEmptyPartDoesNotSealJob(c *chk.C) {
+	jobMgr := &partsDoneJobMgr{}
+	header := &JobPartPlanHeader{PartNum: 3, NumTransfers: 0}
+	jpm := &jobPartMgr{
+		jobMgr:  jobMgr,
+		planMMF: &JobPartPlanMMF{tracker: newMappedPlanTracker(), mapping: &fakeMappedPlan{}, atomicPlan: unsafe.Pointer(header)},
+	}
+
+	jpm.ScheduleTransfers(context.Background())
+	c.Assert(jobMgr.allTransfersScheduled, chk.Equals, false)
+	c.Assert(jobMgr.partsDone, chk.Equals, 1)
+}
@@ -0,0 +1,96 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type emptyPagesSuite struct{}
+
+var _ = chk.Suite(&emptyPagesSuite{})
+
+type bytesReaderAt struct {
+	*bytes.Reader
+}
+
+func (bytesReaderAt) Close() error { return nil }
+
+type nopChunkLogger struct{}
+
+func (nopChunkLogger) ShouldLog(level pipeline.LogLevel) bool  { return false }
+func (nopChunkLogger) Log(level pipeline.LogLevel, msg string) {}
+func (nopChunkLogger) Panic(err error)                         { panic(err) }
+
+func (s *emptyPagesSuite) TestChunkSectionReadsOnlyItsPart(c *chk.C) {
+	section := newChunkSection(strings.NewReader("0123456789"), 3, 4)
+	data, err := ioutil.ReadAll(section)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "3456")
+
+	// as a retry would
+	length, err := section.Seek(0, io.SeekEnd)
+	c.Assert(err, chk.IsNil)
+	c.Assert(length, chk.Equals, int64(4))
+	_, err = section.Seek(0, io.SeekStart)
+	c.Assert(err, chk.IsNil)
+	data, err = ioutil.ReadAll(section)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "3456")
+}
+
+func (s *emptyPagesSuite) TestOnlyLongRunsOfEmptyPagesAreLeftOut(c *chk.C) {
+	const chunkOffset = 4 * 1024 * 1024
+	data := make([]byte, 1024*1024)
+	data[0] = 1                        // the first page has data, then comes a long run of empty ones
+	data[minEmptyPageRunSkipped*2] = 1 // then a short run after this page, that's sent anyway
+	data[minEmptyPageRunSkipped*2+2*azblob.PageBlobPageBytes] = 1
+
+	source := bytesReaderAt{bytes.NewReader(data)}
+	reader := common.NewSingleChunkReader(context.Background(), func() (common.CloseableReaderAt, error) { return source, nil },
+		common.NewChunkID("f", chunkOffset, int64(len(data))), int64(len(data)),
+		common.NewChunkStatusLogger(common.NewJobID(), common.NewNullCpuMonitor(), "", false), nopChunkLogger{},
+		common.NewMultiSizeSlicePool(int64(len(data))), common.NewCacheLimiter(int64(len(data))), false)
+	c.Assert(reader.BlockingPrefetch(source, false), chk.IsNil)
+	defer reader.Close()
+
+	u := &pageBlobUploader{}
+	dataRanges, skipped := u.pagesToUpload(common.NewChunkID("f", chunkOffset, int64(len(data))), reader)
+
+	lastDataPage := int64(minEmptyPageRunSkipped*2 + 2*azblob.PageBlobPageBytes)
+	c.Assert(dataRanges, chk.DeepEquals, []azblob.PageRange{
+		{Start: chunkOffset, End: chunkOffset + azblob.PageBlobPageBytes - 1},
+		{Start: chunkOffset + minEmptyPageRunSkipped*2, End: chunkOffset + lastDataPage + azblob.PageBlobPageBytes - 1},
+	})
+	firstRun := int64(minEmptyPageRunSkipped*2 - azblob.PageBlobPageBytes)
+	lastRun := int64(len(data)) - lastDataPage - azblob.PageBlobPageBytes
+	c.Assert(skipped, chk.Equals, (firstRun+lastRun)/azblob.PageBlobPageBytes)
+}
@@ -0,0 +1,109 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type etaEstimatorSuite struct{}
+
+var _ = chk.Suite(&etaEstimatorSuite{})
+
+func (s *etaEstimatorSuite) TestNoEstimateUntilEnoughSamples(c *chk.C) {
+	e := newEtaEstimator(etaWindowSize)
+	start := time.Now()
+	for i := 0; i < etaMinIntervals; i++ {
+		e.record(start.Add(time.Duration(i)*time.Second), uint64(i*1000), 0)
+	}
+	_, ok := e.estimate(1000, 1)
+	c.Assert(ok, chk.Equals, false)
+
+	e.record(start.Add(etaMinIntervals*time.Second), etaMinIntervals*1000, 0)
+	eta, ok := e.estimate(10000, 0)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(eta, chk.Equals, 10*time.Second)
+}
+
+func (s *etaEstimatorSuite) TestOnlyRecentSamplesCount(c *chk.C) {
+	e := newEtaEstimator(5)
+	start := time.Now()
+	bytes := uint64(0)
+	for i := 0; i < 20; i++ {
+		// slow to begin with, then ten times faster
+		if i < 15 {
+			bytes += 100
+		} else {
+			bytes += 1000
+		}
+		e.record(start.Add(time.Duration(i)*time.Second), bytes, 0)
+	}
+
+	eta, ok := e.estimate(10000, 0)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(eta, chk.Equals, 10*time.Second)
+}
+
+func (s *etaEstimatorSuite) TestPerFileOverhead(c *chk.C) {
+	e := newEtaEstimator(etaWindowSize)
+	start := time.Now()
+	bytes, files := uint64(0), uint32(0)
+	for i := 0; i < 10; i++ {
+		if i%2 == 0 {
+			// one big file's worth of bytes, at full speed
+			bytes += 1000
+		} else {
+			// ten small files, which take a second between them but hardly move any bytes
+			bytes += 10
+			files += 10
+		}
+		e.record(start.Add(time.Duration(i)*time.Second), bytes, files)
+	}
+
+	// the bytes go at 1000/sec, and each file adds about 0.1 sec
+	eta, ok := e.estimate(5000, 100)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(eta > 14*time.Second && eta < 16*time.Second, chk.Equals, true, chk.Commentf("eta was %v", eta))
+}
+
+func (s *etaEstimatorSuite) TestSamplesTooCloseTogetherAreDropped(c *chk.C) {
+	e := newEtaEstimator(etaWindowSize)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		e.record(start.Add(time.Duration(i)*time.Second), uint64(i*1000), 0)
+		// e.g. an extra call for 'jobs show', just after the progress loop's call
+		e.record(start.Add(time.Duration(i)*time.Second+10*time.Millisecond), uint64(i*1000+5), 0)
+	}
+	c.Assert(len(e.ordered()), chk.Equals, 10)
+
+	eta, ok := e.estimate(10000, 0)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(eta, chk.Equals, 10*time.Second)
+}
+
+func (s *etaEstimatorSuite) TestNothingRemaining(c *chk.C) {
+	e := newEtaEstimator(etaWindowSize)
+	eta, ok := e.estimate(0, 0)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(eta, chk.Equals, time.Duration(0))
+}
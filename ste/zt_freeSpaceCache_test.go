@@ -0,0 +1,86 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type freeSpaceCacheSuite struct{}
+
+var _ = chk.Suite(&freeSpaceCacheSuite{})
+
+func (s *freeSpaceCacheSuite) TestReservesFromCachedFreeSpace(c *chk.C) {
+	lookups := 0
+	cache := newFreeSpaceCache(func(string) (uint64, error) {
+		lookups++
+		return 100, nil
+	}, time.Hour)
+
+	available, fits, known := cache.reserve("/data", 60)
+	c.Assert(known, chk.Equals, true)
+	c.Assert(fits, chk.Equals, true)
+	c.Assert(available, chk.Equals, uint64(100))
+
+	available, fits, _ = cache.reserve("/data", 50) // only 40 left, since the first file is being written
+	c.Assert(fits, chk.Equals, false)
+	c.Assert(available, chk.Equals, uint64(40))
+
+	_, fits, _ = cache.reserve("/data", 40)
+	c.Assert(fits, chk.Equals, true)
+	c.Assert(lookups, chk.Equals, 1)
+
+	_, fits, _ = cache.reserve("/other", 40) // each root is looked up separately
+	c.Assert(fits, chk.Equals, true)
+	c.Assert(lookups, chk.Equals, 2)
+}
+
+func (s *freeSpaceCacheSuite) TestRefreshesStaleFreeSpace(c *chk.C) {
+	lookups := 0
+	cache := newFreeSpaceCache(func(string) (uint64, error) {
+		lookups++
+		return 100, nil
+	}, 0)
+
+	_, fits, _ := cache.reserve("/data", 100)
+	c.Assert(fits, chk.Equals, true)
+	time.Sleep(time.Millisecond)
+	_, fits, _ = cache.reserve("/data", 100) // looked up again, e.g. after files were deleted to make room
+	c.Assert(fits, chk.Equals, true)
+	c.Assert(lookups, chk.Equals, 2)
+}
+
+func (s *freeSpaceCacheSuite) TestUnknownFreeSpaceIsNotCached(c *chk.C) {
+	lookups := 0
+	cache := newFreeSpaceCache(func(string) (uint64, error) {
+		lookups++
+		return 0, errors.New("can't tell")
+	}, time.Hour)
+
+	_, _, known := cache.reserve("/data", 1)
+	c.Assert(known, chk.Equals, false)
+	_, _, known = cache.reserve("/data", 1)
+	c.Assert(known, chk.Equals, false)
+	c.Assert(lookups, chk.Equals, 2)
+}
@@ -0,0 +1,181 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+)
+
+type hedgePolicySuite struct{}
+
+var _ = chk.Suite(&hedgePolicySuite{})
+
+func (s *hedgePolicySuite) TestLatencyPercentile(c *chk.C) {
+	tracker := newChunkLatencyTracker(100)
+	_, ok := tracker.percentile(hedgeLatencyPercentile)
+	c.Assert(ok, chk.Equals, false)
+
+	for i := 1; i <= 200; i++ {
+		tracker.record(time.Duration(i) * time.Millisecond)
+	}
+
+	// only the latest 100 (i.e. 101ms to 200ms) are kept
+	p, ok := tracker.percentile(0.95)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(p, chk.Equals, 195*time.Millisecond)
+}
+
+func (s *hedgePolicySuite) TestHedgeableChunkRequests(c *chk.C) {
+	newRequest := func(method string, rawURL string, header map[string]string) pipeline.Request {
+		u, _ := url.Parse(rawURL)
+		request, err := pipeline.NewRequest(method, *u, nil)
+		c.Assert(err, chk.IsNil)
+		for k, v := range header {
+			request.Header.Set(k, v)
+		}
+		return request
+	}
+
+	c.Assert(isHedgeableChunkRequest(newRequest(http.MethodGet, "https://a.blob.core.windows.net/c/b", map[string]string{"x-ms-range": "bytes=0-99"})), chk.Equals, false)
+	c.Assert(isHedgeableChunkRequest(newRequest(http.MethodGet, "https://a.blob.core.windows.net/c?comp=list", nil)), chk.Equals, false)
+	c.Assert(isHedgeableChunkRequest(newRequest(http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=block&blockid=x", map[string]string{xMsCopySourceHeader: "https://src"})), chk.Equals, true)
+	c.Assert(isHedgeableChunkRequest(newRequest(http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=appendblock", map[string]string{xMsCopySourceHeader: "https://src"})), chk.Equals, false)
+	c.Assert(isHedgeableChunkRequest(newRequest(http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=block&blockid=x", nil)), chk.Equals, false)
+}
+
+func (s *hedgePolicySuite) TestLatencyKinds(c *chk.C) {
+	newRequest := func(rawURL string, sourceRange string) pipeline.Request {
+		u, _ := url.Parse(rawURL)
+		request, err := pipeline.NewRequest(http.MethodPut, *u, nil)
+		c.Assert(err, chk.IsNil)
+		request.Header.Set(xMsCopySourceHeader, "https://src")
+		request.Header.Set("x-ms-source-range", sourceRange)
+		return request
+	}
+
+	fullBlock := chunkLatencyKindOf(newRequest("https://a.blob.core.windows.net/c/b?comp=block&blockid=x", "bytes=0-8388607"))
+	otherFullBlock := chunkLatencyKindOf(newRequest("https://a.blob.core.windows.net/c/b?comp=block&blockid=y", "bytes=8388608-16777215"))
+	lastBlock := chunkLatencyKindOf(newRequest("https://a.blob.core.windows.net/c/b?comp=block&blockid=z", "bytes=16777216-16778239"))
+	page := chunkLatencyKindOf(newRequest("https://a.blob.core.windows.net/c/b?comp=page", "bytes=0-8388607"))
+
+	c.Assert(fullBlock, chk.Equals, otherFullBlock)
+	c.Assert(fullBlock, chk.Not(chk.Equals), lastBlock)
+	c.Assert(fullBlock, chk.Not(chk.Equals), page)
+
+	trackers := newChunkLatencyTrackers()
+	c.Assert(trackers.trackerFor(fullBlock), chk.Equals, trackers.trackerFor(otherFullBlock))
+	c.Assert(trackers.trackerFor(fullBlock), chk.Not(chk.Equals), trackers.trackerFor(lastBlock))
+}
+
+func (s *hedgePolicySuite) TestSlowRequestIsHedged(c *chk.C) {
+	tracker := newChunkLatencyTracker(hedgeLatencyWindow)
+	for i := 0; i < hedgeMinLatencySamples; i++ {
+		tracker.record(10 * time.Millisecond)
+	}
+
+	// the first copy of the request hangs until cancelled, and the second is answered straight away
+	var calls int32
+	next := pipeline.PolicyFunc(func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return pipeline.NewHTTPResponse(&http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader([]byte("data")))}), nil
+	})
+
+	u, _ := url.Parse("https://a.blob.core.windows.net/c/b")
+	request, _ := pipeline.NewRequest(http.MethodGet, *u, nil)
+	hedged := false
+	response, err := doHedged(context.Background(), request, next, tracker, func() { hedged = true })
+
+	c.Assert(err, chk.IsNil)
+	c.Assert(hedged, chk.Equals, true)
+	body, err := ioutil.ReadAll(response.Response().Body)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(body), chk.Equals, "data")
+	c.Assert(response.Response().Body.Close(), chk.IsNil)
+}
+
+func (s *hedgePolicySuite) TestSlowChunkBodyIsHedged(c *chk.C) {
+	tracker := newChunkLatencyTracker(hedgeLatencyWindow)
+	for i := 0; i < hedgeMinLatencySamples; i++ {
+		tracker.record(10 * time.Millisecond)
+	}
+	content := []byte("0123456789abcdef")
+
+	// the primary sends the first half of the chunk quickly, and then stalls until it is abandoned
+	primaryReader, primaryWriter := io.Pipe()
+	go func() { _, _ = primaryWriter.Write(content[:8]) }()
+	abandoned := false
+	abandonPrimary := func() {
+		abandoned = true
+		_ = primaryWriter.CloseWithError(context.Canceled)
+	}
+
+	var fetchedFrom int64 = -1
+	fetch := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		fetchedFrom = offset
+		return ioutil.NopCloser(bytes.NewReader(content[offset:])), nil
+	}
+	hedged := false
+	body := newHedgedChunkBody(context.Background(), primaryReader, abandonPrimary, time.Now(), int64(len(content)), tracker, fetch, func() { hedged = true })
+
+	// read in small pieces, the way a partly-read chunk would be
+	result := make([]byte, len(content))
+	_, err := io.ReadFull(body, result[:8])
+	c.Assert(err, chk.IsNil)
+	_, err = io.ReadFull(body, result[8:])
+	c.Assert(err, chk.IsNil)
+
+	c.Assert(string(result), chk.Equals, string(content))
+	c.Assert(hedged, chk.Equals, true)
+	c.Assert(abandoned, chk.Equals, true)
+	c.Assert(fetchedFrom, chk.Equals, int64(8))
+}
+
+func (s *hedgePolicySuite) TestFastChunkBodyIsNotHedged(c *chk.C) {
+	tracker := newChunkLatencyTracker(hedgeLatencyWindow)
+	for i := 0; i < hedgeMinLatencySamples; i++ {
+		tracker.record(time.Minute)
+	}
+	content := []byte("0123456789abcdef")
+
+	fetch := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		c.Error("a fast chunk should not be hedged")
+		return nil, context.Canceled
+	}
+	body := newHedgedChunkBody(context.Background(), ioutil.NopCloser(bytes.NewReader(content)), func() {}, time.Now(), int64(len(content)), tracker, fetch, func() {})
+
+	result, err := ioutil.ReadAll(body)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(result), chk.Equals, string(content))
+	c.Assert(body.Close(), chk.IsNil)
+}
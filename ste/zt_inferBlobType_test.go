@@ -0,0 +1,43 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type inferBlobTypeSuite struct{}
+
+var _ = chk.Suite(&inferBlobTypeSuite{})
+
+func (s *inferBlobTypeSuite) TestInferDestinationBlobType(c *chk.C) {
+	const container = "https://acct.blob.core.windows.net/container/"
+
+	// neither name is a disk image
+	c.Assert(inferDestinationBlobType("/data/a.txt", container+"a.txt?sv=2019-12-12&sig=abc", azblob.BlobBlockBlob), chk.Equals, azblob.BlobBlockBlob)
+
+	// the source's extension is used when the destination's isn't recognized
+	c.Assert(inferDestinationBlobType("/data/disk.VHD", container+"disk.VHD", azblob.BlobBlockBlob), chk.Equals, azblob.BlobPageBlob)
+
+	// a file renamed to a disk image on the way up is still a page blob, and the SAS isn't mistaken for the extension
+	c.Assert(inferDestinationBlobType("/data/disk.img", container+"disk.vhdx?sv=2019-12-12&sig=abc", azblob.BlobBlockBlob), chk.Equals, azblob.BlobPageBlob)
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobCancelSuite struct{}
+
+var _ = chk.Suite(&jobCancelSuite{})
+
+func (s *jobCancelSuite) TestMarkJobCancelled(c *chk.C) {
+	dir, err := ioutil.TempDir("", "plans")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	jobID := common.NewJobID()
+
+	c.Assert(isJobCancelled(dir, jobID), chk.Equals, false)
+	c.Assert(markJobCancelled(dir, jobID), chk.IsNil)
+	c.Assert(isJobCancelled(dir, jobID), chk.Equals, true)
+	c.Assert(isJobCancelled(dir, common.NewJobID()), chk.Equals, false)
+
+	// it goes with the job's other files when the job is removed
+	c.Assert(IsJobSidecarFileName(filepath.Base(jobCancelledPath(dir, jobID))), chk.Equals, true)
+}
+
+func (s *jobCancelSuite) TestWatchStopsWhenJobNotOwned(c *chk.C) {
+	dir, err := ioutil.TempDir("", "plans")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	jobID := common.NewJobID()
+
+	// this process doesn't own the job, so there's nothing to watch for
+	done := make(chan struct{})
+	go func() {
+		watchForCancellation(dir, jobID)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * jobCancelledPollInterval):
+		c.Fatal("the watch didn't stop")
+	}
+}
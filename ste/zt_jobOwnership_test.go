@@ -0,0 +1,94 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobOwnershipSuite struct{}
+
+var _ = chk.Suite(&jobOwnershipSuite{})
+
+func writeJobOwner(c *chk.C, planDir string, jobID common.JobID, owner JobOwner) {
+	b, err := json.Marshal(owner)
+	c.Assert(err, chk.IsNil)
+	c.Assert(ioutil.WriteFile(jobOwnerPath(planDir, jobID), b, common.DEFAULT_FILE_PERM), chk.IsNil)
+}
+
+func (s *jobOwnershipSuite) TestClaimAndRelease(c *chk.C) {
+	dir, err := ioutil.TempDir("", "plans")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	jobID := common.NewJobID()
+
+	c.Assert(claimJob(dir, jobID, true), chk.IsNil)
+	owner, running := GetJobOwner(dir, jobID)
+	c.Assert(running, chk.Equals, true)
+	c.Assert(owner.PID, chk.Equals, os.Getpid())
+	c.Assert(IsJobSidecarFileName(filepath.Base(jobOwnerPath(dir, jobID))), chk.Equals, true)
+
+	// claiming it again, e.g. to resume it, is fine from the same process
+	c.Assert(claimJob(dir, jobID, false), chk.IsNil)
+
+	releaseJob(dir, jobID)
+	_, running = GetJobOwner(dir, jobID)
+	c.Assert(running, chk.Equals, false)
+	_, err = os.Stat(jobOwnerPath(dir, jobID))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
+
+func (s *jobOwnershipSuite) TestJobInUseByAnotherProcess(c *chk.C) {
+	dir, err := ioutil.TempDir("", "plans")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	jobID := common.NewJobID()
+
+	// an owner on another machine is assumed to be running
+	writeJobOwner(c, dir, jobID, JobOwner{PID: os.Getpid(), Host: thisHost() + "-other", Started: time.Now()})
+	c.Assert(claimJob(dir, jobID, false), chk.ErrorMatches, "job .* is already in use by azcopy process .*")
+
+	// and isn't released by this process
+	releaseJob(dir, jobID)
+	_, running := GetJobOwner(dir, jobID)
+	c.Assert(running, chk.Equals, true)
+}
+
+func (s *jobOwnershipSuite) TestNewJobIDAlreadyInPlanFolder(c *chk.C) {
+	dir, err := ioutil.TempDir("", "plans")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	jobID := common.NewJobID()
+
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf(jobPartPlanFileNameFormat, jobID.String(), 0, DataSchemaVersion)), nil, common.DEFAULT_FILE_PERM), chk.IsNil)
+	c.Assert(claimJob(dir, jobID, true), chk.ErrorMatches, "job ID .* is already used by a job in the plan folder .*")
+
+	// but resuming it is fine
+	c.Assert(claimJob(dir, jobID, false), chk.IsNil)
+}
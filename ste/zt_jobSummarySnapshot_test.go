@@ -0,0 +1,90 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobSummarySnapshotSuite struct{}
+
+var _ = chk.Suite(&jobSummarySnapshotSuite{})
+
+func (s *jobSummarySnapshotSuite) TestSaveAndLoadJobSummarySnapshot(c *chk.C) {
+	planDir, err := ioutil.TempDir("", "plans")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(planDir)
+
+	jobID := common.NewJobID()
+	_, ok := loadJobSummarySnapshot(planDir, jobID)
+	c.Assert(ok, chk.Equals, false)
+
+	saved := common.ListJobSummaryResponse{
+		JobID:                     jobID,
+		JobStatus:                 common.EJobStatus.CompletedWithErrors(),
+		TotalTransfers:            3,
+		TransfersCompleted:        2,
+		TransfersFailed:           1,
+		FailedTransfers:           []common.TransferDetail{{Src: "a", Dst: "b", TransferStatus: common.ETransferStatus.Failed()}},
+		ActiveConnections:         4,
+		EstimatedSecondsRemaining: 10,
+	}
+	c.Assert(saveJobSummarySnapshot(planDir, saved), chk.IsNil)
+
+	// the counts come back as they were, but not what was in flight at the time
+	loaded, ok := loadJobSummarySnapshot(planDir, jobID)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(loaded.JobStatus, chk.Equals, saved.JobStatus)
+	c.Assert(loaded.TransfersCompleted, chk.Equals, uint32(2))
+	c.Assert(loaded.TransfersFailed, chk.Equals, uint32(1))
+	c.Assert(loaded.FailedTransfers, chk.DeepEquals, saved.FailedTransfers)
+	c.Assert(loaded.ActiveConnections, chk.Equals, int64(0))
+	c.Assert(loaded.EstimatedSecondsRemaining, chk.Equals, int64(-1))
+
+	// another job's summary isn't mistaken for this one's
+	_, ok = loadJobSummarySnapshot(planDir, common.NewJobID())
+	c.Assert(ok, chk.Equals, false)
+}
+
+func (s *jobSummarySnapshotSuite) TestCancelledJobSummarySnapshot(c *chk.C) {
+	planDir, err := ioutil.TempDir("", "plans")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(planDir)
+
+	// a job without a saved summary is shown from its plan, which has the new status already
+	jobID := common.NewJobID()
+	c.Assert(setJobSummarySnapshotStatus(planDir, jobID, common.EJobStatus.Cancelled()), chk.IsNil)
+	_, ok := loadJobSummarySnapshot(planDir, jobID)
+	c.Assert(ok, chk.Equals, false)
+
+	// one that was saved while the job was running says it's cancelled once it's cancelled
+	saved := common.ListJobSummaryResponse{JobID: jobID, JobStatus: common.EJobStatus.InProgress(), TotalTransfers: 3, TransfersCompleted: 1}
+	c.Assert(saveJobSummarySnapshot(planDir, saved), chk.IsNil)
+	c.Assert(setJobSummarySnapshotStatus(planDir, jobID, common.EJobStatus.Cancelled()), chk.IsNil)
+	loaded, ok := loadJobSummarySnapshot(planDir, jobID)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(loaded.JobStatus, chk.Equals, common.EJobStatus.Cancelled())
+	c.Assert(loaded.TransfersCompleted, chk.Equals, uint32(1))
+}
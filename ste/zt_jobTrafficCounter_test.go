@@ -0,0 +1,71 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+
+	chk "gopkg.in/check.v1"
+)
+
+type jobTrafficCounterSuite struct{}
+
+var _ = chk.Suite(&jobTrafficCounterSuite{})
+
+// countingPacer stands in for the pacer that all jobs share
+type countingPacer struct {
+	total int64
+}
+
+func (p *countingPacer) RequestTrafficAllocation(ctx context.Context, byteCount int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.total += byteCount
+	return nil
+}
+func (p *countingPacer) UndoRequest(byteCount int64) { p.total -= byteCount }
+func (p *countingPacer) Close() error                { return nil }
+
+func (s *jobTrafficCounterSuite) TestJobsCountTheirOwnTraffic(c *chk.C) {
+	shared := &countingPacer{}
+	job1, job2 := newJobTrafficCounter(shared), newJobTrafficCounter(shared)
+
+	c.Assert(job1.RequestTrafficAllocation(context.Background(), 100), chk.IsNil)
+	c.Assert(job2.RequestTrafficAllocation(context.Background(), 30), chk.IsNil)
+	job1.UndoRequest(20)
+
+	// each job sees only its own bytes, while all of them are still paced together
+	c.Assert(job1.GetTotalTraffic(), chk.Equals, int64(80))
+	c.Assert(job2.GetTotalTraffic(), chk.Equals, int64(30))
+	c.Assert(shared.total, chk.Equals, int64(110))
+
+	// a request that the shared pacer refuses isn't counted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Assert(job2.RequestTrafficAllocation(ctx, 50), chk.NotNil)
+	c.Assert(job2.GetTotalTraffic(), chk.Equals, int64(30))
+
+	// and closing a job leaves the shared pacer to the others
+	c.Assert(job1.Close(), chk.IsNil)
+	c.Assert(job2.RequestTrafficAllocation(context.Background(), 5), chk.IsNil)
+	c.Assert(shared.total, chk.Equals, int64(115))
+}
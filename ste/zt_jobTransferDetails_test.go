@@ -0,0 +1,68 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobTransferDetailsSuite struct{}
+
+var _ = chk.Suite(&jobTransferDetailsSuite{})
+
+func (s *jobTransferDetailsSuite) TestReadJobTransferDetails(c *chk.C) {
+	dir, err := ioutil.TempDir("", "plans")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	details := []common.TransferDetail{
+		{Src: "a", Dst: "b", TransferStatus: common.ETransferStatus.Failed(), ErrorCode: 404},
+		{Src: "c", Dst: "d", TransferStatus: common.ETransferStatus.SkippedEntityAlreadyExists()},
+		{Src: "e", Dst: "f", IsFolderProperties: true, TransferStatus: common.ETransferStatus.Failed()},
+	}
+	path := jobTransferDetailsPath(dir, common.NewJobID())
+	file, err := os.Create(path)
+	c.Assert(err, chk.IsNil)
+	encoder := json.NewEncoder(file)
+	for _, detail := range details {
+		c.Assert(encoder.Encode(detail), chk.IsNil)
+	}
+	c.Assert(file.Close(), chk.IsNil)
+
+	failed, skipped, err := ReadJobTransferDetails(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(failed, chk.DeepEquals, []common.TransferDetail{details[0], details[2]})
+	c.Assert(skipped, chk.DeepEquals, []common.TransferDetail{details[1]})
+
+	// the file sits with the job's plan files, and is cleaned up with them
+	c.Assert(IsJobSidecarFileName(filepath.Base(path)), chk.Equals, true)
+	c.Assert(IsJobSidecarFileName(filepath.Base(jobSummarySnapshotPath(dir, common.NewJobID()))), chk.Equals, true)
+	c.Assert(IsJobSidecarFileName("job--00001.steV18"), chk.Equals, false)
+
+	_, _, err = ReadJobTransferDetails(filepath.Join(dir, "missing"))
+	c.Assert(err, chk.NotNil)
+}
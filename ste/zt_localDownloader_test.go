@@ -0,0 +1,54 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+)
+
+type localDownloaderSuite struct{}
+
+var _ = chk.Suite(&localDownloaderSuite{})
+
+func (s *localDownloaderSuite) TestIsSameLocalFile(c *chk.C) {
+	dir, err := ioutil.TempDir("", "localcopy")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	c.Assert(ioutil.WriteFile(a, []byte("a"), 0644), chk.IsNil)
+	c.Assert(ioutil.WriteFile(b, []byte("a"), 0644), chk.IsNil)
+
+	c.Assert(isSameLocalFile(a, a), chk.Equals, true)
+	c.Assert(isSameLocalFile(a, filepath.Join(dir, ".", "a")), chk.Equals, true)
+	c.Assert(isSameLocalFile(a, b), chk.Equals, false) // same content isn't the same file
+	c.Assert(isSameLocalFile(a, filepath.Join(dir, "missing")), chk.Equals, false)
+
+	link := filepath.Join(dir, "link")
+	if os.Link(a, link) == nil {
+		c.Assert(isSameLocalFile(a, link), chk.Equals, true)
+	}
+}
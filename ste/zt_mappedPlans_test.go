@@ -0,0 +1,98 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"time"
+	"unsafe"
+
+	chk "gopkg.in/check.v1"
+)
+
+type mappedPlansSuite struct{}
+
+var _ = chk.Suite(&mappedPlansSuite{})
+
+type fakeMappedPlan struct {
+	unmapped bool
+}
+
+func (f *fakeMappedPlan) Slice() []byte { return make([]byte, 8) }
+func (f *fakeMappedPlan) UseMMF() bool  { return !f.unmapped }
+func (f *fakeMappedPlan) UnuseMMF()     {}
+func (f *fakeMappedPlan) Unmap()        { f.unmapped = true }
+
+func (s *mappedPlansSuite) TestEvictsColdestUnpinnedPlans(c *chk.C) {
+	tracker := newMappedPlanTracker()
+	tracker.limit = 2
+	tracker.limitOnce.Do(func() {})
+
+	now := time.Now()
+	newPlan := func(lastUsed time.Time) (*JobPartPlanMMF, *fakeMappedPlan) {
+		mapping := &fakeMappedPlan{}
+		mmf := &JobPartPlanMMF{tracker: tracker, mapping: mapping, atomicLastUsed: lastUsed.UnixNano()}
+		tracker.add(mmf)
+		return mmf, mapping
+	}
+
+	cold, coldMapping := newPlan(now.Add(-time.Minute))
+	running, runningMapping := newPlan(now.Add(-2 * time.Minute))
+	running.pin()
+	c.Assert(tracker.add(running), chk.Equals, false) // adding a plan again doesn't count it twice
+
+	// over the limit, the cold plan is evicted, but the older one is kept, since its transfers are running
+	_, recentMapping := newPlan(now)
+	tracker.evictColdest(now)
+	c.Assert(coldMapping.unmapped, chk.Equals, true)
+	c.Assert(cold.mapping, chk.IsNil)
+	c.Assert(runningMapping.unmapped, chk.Equals, false)
+	c.Assert(recentMapping.unmapped, chk.Equals, false)
+	c.Assert(len(tracker.mapped), chk.Equals, 2)
+
+	// with nothing else cold, the limit is exceeded rather than evict a plan that's in use
+	newPlan(now)
+	tracker.evictColdest(now)
+	c.Assert(len(tracker.mapped), chk.Equals, 3)
+
+	// until its transfers are done
+	running.unpin()
+	tracker.evictColdest(now)
+	c.Assert(runningMapping.unmapped, chk.Equals, true)
+	c.Assert(len(tracker.mapped), chk.Equals, 2)
+}
+
+func (s *mappedPlansSuite) TestMappedPlanIsReadWithoutTheLock(c *chk.C) {
+	header := &JobPartPlanHeader{NumTransfers: 7}
+	mmf := &JobPartPlanMMF{tracker: newMappedPlanTracker(), mapping: &fakeMappedPlan{}, atomicPlan: unsafe.Pointer(header)}
+
+	// while the plan is being mapped or evicted, say, its lock is held, but that doesn't hold up transfers reading it
+	mmf.lock.Lock()
+	defer mmf.lock.Unlock()
+	read := make(chan *JobPartPlanHeader)
+	go func() { read <- mmf.Plan() }()
+	select {
+	case plan := <-read:
+		c.Assert(plan.NumTransfers, chk.Equals, uint32(7))
+	case <-time.After(time.Second):
+		c.Fatal("reading a mapped plan waited on its lock")
+	}
+	c.Assert(time.Since(time.Unix(0, mmf.atomicLastUsed)) < time.Second, chk.Equals, true)
+}
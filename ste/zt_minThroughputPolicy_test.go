@@ -0,0 +1,127 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type minThroughputSuite struct{}
+
+var _ = chk.Suite(&minThroughputSuite{})
+
+func (s *minThroughputSuite) TestBelowFloor(c *chk.C) {
+	c.Assert(isBelowFloor(500*1024, 10*time.Second, 100*1024), chk.Equals, true)
+	c.Assert(isBelowFloor(1000*1024, 10*time.Second, 100*1024), chk.Equals, false)
+}
+
+func (s *minThroughputSuite) TestSlowChunkIsAbandoned(c *chk.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	monitor := &throughputMonitor{floor: 1024, cancel: cancel}
+	window := 10 * time.Second
+
+	// nothing is held against the chunk until its first byte moves
+	start := time.Now()
+	for i := 1; i <= 10; i++ {
+		c.Assert(monitor.check(start.Add(time.Duration(i)*window), window), chk.Equals, false)
+	}
+	c.Assert(monitor.tripped(), chk.Equals, false)
+
+	// then it only trickles, so after the given number of windows it's abandoned
+	monitor.add(1)
+	firstByte := time.Unix(0, monitor.atomicFirstByteNanos)
+	finished := false
+	for i := 1; i <= minThroughputSlowWindows; i++ {
+		c.Assert(finished, chk.Equals, false)
+		finished = monitor.check(firstByte.Add(time.Duration(i)*window), window)
+	}
+	c.Assert(finished, chk.Equals, true)
+	c.Assert(monitor.tripped(), chk.Equals, true)
+	c.Assert(ctx.Err(), chk.NotNil)
+
+	// the error from the cancelled read is replaced by one that the retry policies will retry
+	body := &throughputMonitoredBody{ReadCloser: ioutil.NopCloser(&failingReader{err: ctx.Err()}), monitor: monitor}
+	_, err := body.Read(make([]byte, 10))
+	_, isNetError := err.(net.Error)
+	c.Assert(isNetError, chk.Equals, true)
+}
+
+func (s *minThroughputSuite) TestFastChunkIsNotAbandoned(c *chk.C) {
+	_, cancel := context.WithCancel(context.Background())
+	monitor := &throughputMonitor{floor: 1, cancel: cancel}
+	body := &throughputMonitoredBody{ReadCloser: ioutil.NopCloser(strings.NewReader("some data")), monitor: monitor}
+
+	data, err := ioutil.ReadAll(body)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "some data")
+	c.Assert(monitor.atomicBytes, chk.Equals, int64(len("some data")))
+	c.Assert(monitor.atomicDone, chk.Equals, int32(1))
+	c.Assert(monitor.tripped(), chk.Equals, false)
+}
+
+func (s *minThroughputSuite) TestSharedTickerDropsFinishedMonitors(c *chk.C) {
+	set := newThroughputMonitorSet(10 * time.Second)
+	_, cancel := context.WithCancel(context.Background())
+	monitor := &throughputMonitor{floor: 1, cancel: cancel}
+	set.monitors[monitor] = struct{}{}
+
+	set.checkAll(time.Now())
+	c.Assert(len(set.monitors), chk.Equals, 1)
+
+	monitor.stop()
+	set.checkAll(time.Now())
+	c.Assert(len(set.monitors), chk.Equals, 0)
+}
+
+func (s *minThroughputSuite) TestUploadBodyStaysSeekable(c *chk.C) {
+	_, cancel := context.WithCancel(context.Background())
+	monitor := &throughputMonitor{floor: 1, cancel: cancel}
+	body := &throughputMonitoredBody{ReadCloser: &seekableNopCloser{strings.NewReader("some data")}, monitor: monitor}
+
+	_, err := ioutil.ReadAll(body)
+	c.Assert(err, chk.IsNil)
+	_, err = body.Seek(0, io.SeekStart)
+	c.Assert(err, chk.IsNil)
+	data, err := ioutil.ReadAll(body)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "some data")
+}
+
+type seekableNopCloser struct {
+	io.ReadSeeker
+}
+
+func (seekableNopCloser) Close() error { return nil }
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
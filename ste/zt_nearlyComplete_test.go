@@ -0,0 +1,37 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	chk "gopkg.in/check.v1"
+)
+
+type nearlyCompleteSuite struct{}
+
+var _ = chk.Suite(&nearlyCompleteSuite{})
+
+func (s *nearlyCompleteSuite) TestNearlyCompleteAfterNinetyPercentOfChunks(c *chk.C) {
+	c.Assert(isNearlyComplete(90, 100), chk.Equals, false)
+	c.Assert(isNearlyComplete(91, 100), chk.Equals, true)
+	c.Assert(isNearlyComplete(9, 10), chk.Equals, false)
+	c.Assert(isNearlyComplete(0, 1), chk.Equals, false) // a single chunk is never boosted, since there's nothing done before it
+	c.Assert(isNearlyComplete(0, 0), chk.Equals, false) // the number of chunks isn't known yet
+}
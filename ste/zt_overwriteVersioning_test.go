@@ -0,0 +1,69 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"net/http"
+
+	chk "gopkg.in/check.v1"
+)
+
+type overwriteVersioningSuite struct{}
+
+var _ = chk.Suite(&overwriteVersioningSuite{})
+
+type propertiesResponse struct {
+	resp *http.Response
+}
+
+func (r propertiesResponse) Response() *http.Response {
+	return r.resp
+}
+
+func (s *overwriteVersioningSuite) TestKeepsVersions(c *chk.C) {
+	versioned := &http.Response{Header: http.Header{}}
+	versioned.Header.Set("x-ms-version-id", "2021-02-05T09:18:29.1234567Z")
+	c.Assert(keepsVersions(propertiesResponse{versioned}), chk.Equals, true)
+
+	c.Assert(keepsVersions(propertiesResponse{&http.Response{Header: http.Header{}}}), chk.Equals, false)
+	c.Assert(keepsVersions(propertiesResponse{}), chk.Equals, false)
+}
+
+func (s *overwriteVersioningSuite) TestOverwriteCounts(c *chk.C) {
+	jm := &jobMgr{}
+	jm.RecordOverwrite(true)
+	jm.RecordOverwrite(false)
+	jm.RecordOverwrite(true)
+
+	overwrites, versioned := jm.OverwriteCounts()
+	c.Assert(overwrites, chk.Equals, uint32(3))
+	c.Assert(versioned, chk.Equals, uint32(2))
+}
+
+func (s *overwriteVersioningSuite) TestMarkOverwrite(c *chk.C) {
+	jptm := &jobPartTransferMgr{}
+	c.Assert(jptm.atomicOverwriteIndicator, chk.Equals, overwriteNone)
+
+	jptm.MarkOverwrite(false)
+	c.Assert(jptm.atomicOverwriteIndicator, chk.Equals, overwriteUnversioned)
+	jptm.MarkOverwrite(true)
+	c.Assert(jptm.atomicOverwriteIndicator, chk.Equals, overwriteVersioned)
+}
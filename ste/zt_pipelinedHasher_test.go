@@ -0,0 +1,77 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"crypto/md5"
+	"hash"
+	"sync/atomic"
+
+	chk "gopkg.in/check.v1"
+)
+
+type pipelinedHasherSuite struct{}
+
+var _ = chk.Suite(&pipelinedHasherSuite{})
+
+type testChunkToHash struct {
+	data    []byte
+	release chan struct{} // hashing waits for this, if set
+}
+
+func (c testChunkToHash) WriteBufferTo(h hash.Hash) {
+	if c.release != nil {
+		<-c.release
+	}
+	_, _ = h.Write(c.data)
+}
+
+func (s *pipelinedHasherSuite) TestHashesChunksInOrder(c *chk.C) {
+	hasher := newPipelinedHasher(md5.New())
+	for _, data := range []string{"the ", "quick ", "brown ", "fox ", "jumps ", "over"} {
+		hasher.add(testChunkToHash{data: []byte(data)})
+	}
+
+	expected := md5.Sum([]byte("the quick brown fox jumps over"))
+	c.Assert(hasher.sum(), chk.DeepEquals, expected[:])
+}
+
+func (s *pipelinedHasherSuite) TestChunkIsOnlySentOnceHashed(c *chk.C) {
+	hasher := newPipelinedHasher(md5.New())
+	release := make(chan struct{})
+	hashed := hasher.add(testChunkToHash{data: []byte("chunk"), release: release})
+
+	var sent int32
+	done := make(chan struct{})
+	cf := afterHashing(func(int) { atomic.StoreInt32(&sent, 1) }, hashed)
+	go func() {
+		cf(0)
+		close(done)
+	}()
+
+	c.Assert(atomic.LoadInt32(&sent), chk.Equals, int32(0)) // still being hashed
+	close(release)
+	<-done
+	c.Assert(atomic.LoadInt32(&sent), chk.Equals, int32(1))
+
+	expected := md5.Sum([]byte("chunk"))
+	c.Assert(hasher.sum(), chk.DeepEquals, expected[:])
+}
@@ -0,0 +1,54 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type planFolderSpaceSuite struct{}
+
+var _ = chk.Suite(&planFolderSpaceSuite{})
+
+func (s *planFolderSpaceSuite) TestCheckPlanFolderSpace(c *chk.C) {
+	defer func(f func(string) (uint64, error)) { getPlanFolderFreeSpace = f }(getPlanFolderFreeSpace)
+
+	order := common.CopyJobPartOrderRequest{PartNum: 3, Transfers: make([]common.CopyTransfer, 1000)}
+	for i := range order.Transfers {
+		order.Transfers[i] = common.CopyTransfer{Source: "/some/fairly/long/source/path.dat", Destination: "/some/fairly/long/source/path.dat"}
+	}
+	need := minPlanFileSize(order)
+
+	getPlanFolderFreeSpace = func(string) (uint64, error) { return uint64(need), nil }
+	c.Assert(checkPlanFolderSpace("/plans", order), chk.IsNil)
+
+	getPlanFolderFreeSpace = func(string) (uint64, error) { return uint64(need) - 1, nil }
+	err := checkPlanFolderSpace("/plans", order)
+	c.Assert(err, chk.NotNil)
+	c.Assert(err.Error(), chk.Matches, ".*/plans.*part 3.*--plan-file-location.*")
+
+	// not knowing the free space doesn't stop the job
+	getPlanFolderFreeSpace = func(string) (uint64, error) { return 0, errors.New("unsupported") }
+	c.Assert(checkPlanFolderSpace("/plans", order), chk.IsNil)
+}
@@ -0,0 +1,265 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type planLayoutSuite struct{}
+
+var _ = chk.Suite(&planLayoutSuite{})
+
+// an "older" version of a plan struct, with fields in a different order and of different sizes
+type testOldPlanStruct struct {
+	Count   int16
+	Flag    bool
+	Removed uint32
+	Name    [4]byte
+	JobID   common.JobID
+}
+
+type testNewPlanStruct struct {
+	JobID common.JobID
+	Name  [8]byte
+	Flag  bool
+	Count int64
+	Added uint16
+}
+
+func (s *planLayoutSuite) TestConvertBetweenLayouts(c *chk.C) {
+	jobID := common.NewJobID()
+	old := testOldPlanStruct{Count: -3, Flag: true, Removed: 7, Name: [4]byte{'a', 'b', 'c', 'd'}, JobID: jobID}
+
+	// re-encode the old struct as big endian, as if it had been written on a different architecture
+	oldLayout := describePlanStruct(reflect.TypeOf(old), "", 0, nil)
+	src := make([]byte, reflect.TypeOf(old).Size())
+	native := structBytes(&old)
+	for _, f := range oldLayout {
+		elemSize, count := f.Size, uint64(1)
+		if f.IsArray {
+			elemSize, count = f.ElemSize, f.Size/f.ElemSize
+		}
+		for i := uint64(0); i < count; i++ {
+			at := f.Offset + i*elemSize
+			convertPlanScalar(native[at:at+elemSize], f.Kind, nativeByteOrder, src[at:at+elemSize])
+			if elemSize > 1 && nativeByteOrder == binary.LittleEndian {
+				reverseBytes(src[at : at+elemSize])
+			}
+		}
+	}
+
+	converted := testNewPlanStruct{Added: 9}
+	err := convertPlanStruct(src, oldLayout, binary.BigEndian, structBytes(&converted), describePlanStruct(reflect.TypeOf(converted), "", 0, nil))
+	c.Assert(err, chk.IsNil)
+
+	c.Assert(converted.JobID, chk.Equals, jobID)
+	c.Assert(converted.Name, chk.Equals, [8]byte{'a', 'b', 'c', 'd'})
+	c.Assert(converted.Flag, chk.Equals, true)
+	c.Assert(converted.Count, chk.Equals, int64(-3)) // sign extended
+	c.Assert(converted.Added, chk.Equals, uint16(9)) // not in the old struct, so left alone
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+func (s *planLayoutSuite) TestPlanLayoutRoundTrip(c *chk.C) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("plan contents")
+	writePlanLayout(buf, int64(buf.Len()))
+
+	layout, offset, err := readPlanLayout(buf.Bytes())
+	c.Assert(err, chk.IsNil)
+	c.Assert(offset, chk.Equals, uint64(len("plan contents")))
+	c.Assert(reflect.DeepEqual(layout, nativePlanLayout), chk.Equals, true)
+
+	_, _, err = readPlanLayout([]byte("plan contents without a layout"))
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *planLayoutSuite) TestConvertPlanFileRelocatesStrings(c *chk.C) {
+	// a plan whose header has grown by 8 bytes since it was written
+	oldLayout := nativePlanLayout
+	oldLayout.HeaderSize -= 8
+	oldLayout.Header = nil
+	for _, f := range nativePlanLayout.Header {
+		if f.Offset+f.Size <= oldLayout.HeaderSize {
+			oldLayout.Header = append(oldLayout.Header, f)
+		}
+	}
+
+	header := JobPartPlanHeader{NumTransfers: 1, CommandStringLength: 3}
+	transfer := JobPartPlanTransfer{SrcOffset: int64(oldLayout.HeaderSize) + 3 + int64(oldLayout.TransferSize), SrcLength: 3, DstLength: 3}
+	old := &bytesWriter{}
+	old.Write(structBytes(&header)[:oldLayout.HeaderSize])
+	old.Write([]byte("cmd"))
+	writePlanStruct(old, &transfer)
+	old.Write([]byte("srcdst"))
+
+	converted, err := convertPlanFile(old.buf, oldLayout)
+	c.Assert(err, chk.IsNil)
+
+	newHeader := JobPartPlanHeader{}
+	copy(structBytes(&newHeader), converted)
+	c.Assert(newHeader.Version, chk.Equals, DataSchemaVersion)
+	c.Assert(newHeader.NumTransfers, chk.Equals, uint32(1))
+
+	newTransfer := JobPartPlanTransfer{}
+	copy(structBytes(&newTransfer), converted[nativePlanLayout.HeaderSize+3:])
+	c.Assert(string(converted[newTransfer.SrcOffset:newTransfer.SrcOffset+6]), chk.Equals, "srcdst")
+
+	layout, _, err := readPlanLayout(converted)
+	c.Assert(err, chk.IsNil)
+	c.Assert(reflect.DeepEqual(layout, nativePlanLayout), chk.Equals, true)
+}
+
+func (s *planLayoutSuite) TestWritePlanStructSerializesEachField(c *chk.C) {
+	transfer := JobPartPlanTransfer{SrcOffset: -5, SrcLength: 3, SourceSize: 1 << 40, atomicTransferStatus: common.ETransferStatus.Failed()}
+	written := &bytesWriter{}
+	c.Assert(writePlanStruct(written, &transfer), chk.Equals, int64(nativePlanLayout.TransferSize))
+
+	// written in our layout, so it can be mapped and used as is
+	read := JobPartPlanTransfer{}
+	copy(structBytes(&read), written.buf)
+	c.Assert(read, chk.DeepEquals, transfer)
+}
+
+func (s *planLayoutSuite) TestConvertVersion16Plan(c *chk.C) {
+	jobID := common.NewJobID()
+	layout, ok := legacyPlanLayouts[16]
+	c.Assert(ok, chk.Equals, true)
+
+	header := planHeaderV16{Version: 16, NumTransfers: 1, CommandStringLength: 3, IsFinalPart: true}
+	header.JobID = planUUIDV16{D1: jobID.D1, D2: jobID.D2, D3: jobID.D3, D4: jobID.D4}
+	header.DstBlobData.BlockSize = 8 * 1024 * 1024
+	header.DstLocalData.PreserveLastModifiedTime = true
+	transfer := planTransferV16{SrcOffset: int64(layout.HeaderSize) + 3 + int64(layout.TransferSize), SrcLength: 3, DstLength: 3, SourceSize: 42,
+		atomicTransferStatus: int32(common.ETransferStatus.Success())}
+	old := &bytesWriter{}
+	writePlanStruct(old, &header)
+	old.Write([]byte("cmd"))
+	writePlanStruct(old, &transfer)
+	old.Write([]byte("srcdst"))
+
+	converted, err := convertPlanFile(old.buf, layout)
+	c.Assert(err, chk.IsNil)
+
+	newHeader := JobPartPlanHeader{}
+	copy(structBytes(&newHeader), converted)
+	c.Assert(newHeader.Version, chk.Equals, DataSchemaVersion)
+	c.Assert(newHeader.JobID, chk.Equals, jobID)
+	c.Assert(newHeader.IsFinalPart, chk.Equals, true)
+	c.Assert(newHeader.DstBlobData.BlockSize, chk.Equals, int64(8*1024*1024))
+	c.Assert(newHeader.DstLocalData.PreserveLastModifiedTime, chk.Equals, true)
+
+	newTransfer := JobPartPlanTransfer{}
+	copy(structBytes(&newTransfer), converted[nativePlanLayout.HeaderSize+3:])
+	c.Assert(newTransfer.SourceSize, chk.Equals, int64(42))
+	c.Assert(newTransfer.atomicTransferStatus, chk.Equals, common.ETransferStatus.Success())
+	c.Assert(string(converted[newTransfer.SrcOffset:newTransfer.SrcOffset+6]), chk.Equals, "srcdst")
+}
+
+// planWithOverflowingHeaders returns the bytes of a plan, with no transfers, whose content type and cache control are
+// stored after it, as they are when too long for their fields
+func planWithOverflowingHeaders(headerSize uint64, contentType, contentEncoding, cacheControl string) []byte {
+	header := JobPartPlanHeader{CommandStringLength: 3}
+	header.DstBlobData.ContentTypeLength = uint16(len(contentType))
+	header.DstBlobData.ContentEncodingLength = uint16(len(contentEncoding))
+	copy(header.DstBlobData.ContentEncoding[:], contentEncoding)
+	header.DstBlobData.CacheControlLength = uint16(len(cacheControl))
+	header.DstBlobData.HeaderOverflowOffset = int64(headerSize) + 3
+
+	plan := &bytesWriter{}
+	plan.Write(structBytes(&header)[:headerSize])
+	plan.Write([]byte("cmd"))
+	plan.Write([]byte(contentType + cacheControl))
+	return plan.buf
+}
+
+func (s *planLayoutSuite) TestDstHTTPHeadersReadsOverflow(c *chk.C) {
+	contentType := "application/vnd.example+json; profile=" + strings.Repeat("x", CustomHeaderMaxBytes)
+	cacheControl := strings.Repeat("private, ", 100)
+	plan := planWithOverflowingHeaders(nativePlanLayout.HeaderSize, contentType, "gzip", cacheControl)
+
+	// a plan is used where it's mapped, so use a copy that's aligned as its header needs to be
+	aligned := make([]uint64, len(plan)/8+1)
+	alignedPlan := (*[1 << 20]byte)(unsafe.Pointer(&aligned[0]))[:len(plan)]
+	copy(alignedPlan, plan)
+
+	headers := (*JobPartPlanHeader)(unsafe.Pointer(&alignedPlan[0])).DstHTTPHeaders()
+	c.Assert(headers.ContentType, chk.Equals, contentType)
+	c.Assert(headers.ContentEncoding, chk.Equals, "gzip")
+	c.Assert(headers.ContentLanguage, chk.Equals, "")
+	c.Assert(headers.CacheControl, chk.Equals, cacheControl)
+}
+
+func (s *planLayoutSuite) TestConvertPlanFileRelocatesHeaderOverflow(c *chk.C) {
+	// a plan whose header has grown by 8 bytes since it was written
+	oldLayout := nativePlanLayout
+	oldLayout.HeaderSize -= 8
+	oldLayout.Header = nil
+	for _, f := range nativePlanLayout.Header {
+		if f.Offset+f.Size <= oldLayout.HeaderSize {
+			oldLayout.Header = append(oldLayout.Header, f)
+		}
+	}
+	contentType := strings.Repeat("t", CustomHeaderMaxBytes+1)
+	old := planWithOverflowingHeaders(oldLayout.HeaderSize, contentType, "", "")
+
+	converted, err := convertPlanFile(old, oldLayout)
+	c.Assert(err, chk.IsNil)
+
+	newHeader := JobPartPlanHeader{}
+	copy(structBytes(&newHeader), converted)
+	offset := newHeader.DstBlobData.HeaderOverflowOffset
+	c.Assert(offset, chk.Equals, int64(nativePlanLayout.HeaderSize)+3)
+	c.Assert(string(converted[offset:offset+int64(len(contentType))]), chk.Equals, contentType)
+}
+
+func (s *planLayoutSuite) TestConvertPlanKeepsMetadataFromSmallerField(c *chk.C) {
+	// version 16 plans could only hold 1000 bytes of metadata
+	layout := legacyPlanLayouts[16]
+	metadata := "key=" + strings.Repeat("v", 990)
+	header := planHeaderV16{Version: 16, CommandStringLength: 3}
+	header.DstBlobData.MetadataLength = uint16(len(metadata))
+	copy(header.DstBlobData.Metadata[:], metadata)
+	old := &bytesWriter{}
+	writePlanStruct(old, &header)
+	old.Write([]byte("cmd"))
+
+	converted, err := convertPlanFile(old.buf, layout)
+	c.Assert(err, chk.IsNil)
+
+	newHeader := JobPartPlanHeader{}
+	copy(structBytes(&newHeader), converted)
+	c.Assert(len(newHeader.DstBlobData.Metadata), chk.Equals, MetadataMaxBytes)
+	c.Assert(string(newHeader.DstBlobData.Metadata[:newHeader.DstBlobData.MetadataLength]), chk.Equals, metadata)
+}
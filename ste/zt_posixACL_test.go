@@ -0,0 +1,137 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/binary"
+	"os"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type posixACLSuite struct{}
+
+var _ = chk.Suite(&posixACLSuite{})
+
+func buildPOSIXACLXattr(entries ...[3]uint32) []byte {
+	data := make([]byte, 4, 4+8*len(entries))
+	binary.LittleEndian.PutUint32(data, posixACLXattrVersion)
+	for _, e := range entries {
+		entry := make([]byte, 8)
+		binary.LittleEndian.PutUint16(entry, uint16(e[0]))
+		binary.LittleEndian.PutUint16(entry[2:], uint16(e[1]))
+		binary.LittleEndian.PutUint32(entry[4:], e[2])
+		data = append(data, entry...)
+	}
+	return data
+}
+
+func (s *posixACLSuite) TestPOSIXACLEntriesFromXattr(c *chk.C) {
+	const undefinedID = 0xFFFFFFFF
+	data := buildPOSIXACLXattr(
+		[3]uint32{posixACLTagUserObj, 7, undefinedID},
+		[3]uint32{posixACLTagUser, 6, 1001},
+		[3]uint32{posixACLTagGroupObj, 5, undefinedID},
+		[3]uint32{posixACLTagMask, 7, undefinedID},
+		[3]uint32{posixACLTagOther, 0, undefinedID},
+	)
+
+	entries, err := posixACLEntriesFromXattr(data, false, true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.DeepEquals, []string{"user::rwx", "user:1001:rw-", "group::r-x", "mask::rwx", "other::---"})
+
+	// named entries are dropped unless asked for, and default entries are prefixed
+	entries, err = posixACLEntriesFromXattr(data, true, false)
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.DeepEquals, []string{"default:user::rwx", "default:group::r-x", "default:mask::rwx", "default:other::---"})
+
+	_, err = posixACLEntriesFromXattr(data[:7], false, true)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *posixACLSuite) TestPOSIXACLEntriesFromMode(c *chk.C) {
+	c.Assert(posixACLEntriesFromMode(os.FileMode(0754)), chk.DeepEquals, []string{"user::rwx", "group::r-x", "other::r--"})
+}
+
+func (s *posixACLSuite) TestMapPOSIXPrincipals(c *chk.C) {
+	idMap := common.POSIXIDMap{
+		Users:  map[uint32]string{1000: "user-object-id"},
+		Groups: map[uint32]string{100: "group-object-id"},
+	}
+	ac := azbfs.BlobFSAccessControl{Owner: "1000", Group: "200", ACL: "user::rwx,user:1000:rw-,user:1001:r--,group::r-x,default:group:100:r-x,other::---"}
+
+	mapped, unmapped := mapPOSIXPrincipals(ac, idMap)
+	c.Assert(mapped.Owner, chk.Equals, "user-object-id")
+	c.Assert(mapped.Group, chk.Equals, "") // unmapped owning groups aren't sent at all
+	c.Assert(mapped.ACL, chk.Equals, "user::rwx,user:user-object-id:rw-,group::r-x,default:group:group-object-id:r-x,other::---")
+	c.Assert(unmapped, chk.DeepEquals, []string{"group 200", "ACL entry user:1001:r--"})
+}
+
+func (s *posixACLSuite) TestImplicitDirsSourceBase(c *chk.C) {
+	// the destination paths are relative to the source root
+	c.Assert(implicitDirsSourceBase("/data/photos", "/data/photos/2020/a.jpg", "2020/a.jpg"), chk.Equals, "/data/photos")
+	// the destination paths start with the source root's name
+	c.Assert(implicitDirsSourceBase("/data/photos", "/data/photos/2020/a.jpg", "photos/2020/a.jpg"), chk.Equals, "/data")
+	// rewritten paths fall back to the source root
+	c.Assert(implicitDirsSourceBase("/data/photos", "/data/photos/2020/a.jpg", "archive/2020/a.jpg"), chk.Equals, "/data/photos")
+}
+
+// posixTestJptm is just enough of a transfer to read a source's access control
+type posixTestJptm struct {
+	IJobPartTransferMgr
+	info TransferInfo
+}
+
+func (j posixTestJptm) Info() TransferInfo                      { return j.info }
+func (j posixTestJptm) POSIXIDMap() common.POSIXIDMap           { return common.POSIXIDMap{} }
+func (j posixTestJptm) Log(level pipeline.LogLevel, msg string) {}
+
+type fixedAccessControlSIP struct {
+	ISourceInfoProvider
+	isLocal bool
+	ac      azbfs.BlobFSAccessControl
+}
+
+func (p fixedAccessControlSIP) IsLocal() bool { return p.isLocal }
+func (p fixedAccessControlSIP) GetPOSIXAccessControl(path string, includeOwnership bool) (azbfs.BlobFSAccessControl, error) {
+	return p.ac, nil
+}
+
+func (s *posixACLSuite) TestRemoteSourcePrincipalsAreNotMapped(c *chk.C) {
+	info := TransferInfo{PreserveSMBPermissions: common.EPreservePermissionsOption.OwnershipAndACLs()}
+	sender := &blobFSSenderBase{jptm: posixTestJptm{info: info}}
+	ac := azbfs.BlobFSAccessControl{Owner: "5f3c7a9e-0000-0000-0000-000000000001", ACL: "user::rwx,user:5f3c7a9e-0000-0000-0000-000000000002:r--,other::---"}
+
+	// an ADLS Gen2 source's principals are already object IDs
+	got, err := sender.getPOSIXAccessControl(fixedAccessControlSIP{isLocal: false, ac: ac}, "https://acct.dfs.core.windows.net/fs/file")
+	c.Assert(err, chk.IsNil)
+	c.Assert(got, chk.DeepEquals, ac)
+
+	// whereas a local source's IDs have to be in the map
+	got, err = sender.getPOSIXAccessControl(fixedAccessControlSIP{isLocal: true, ac: ac}, "/data/file")
+	c.Assert(err, chk.IsNil)
+	c.Assert(got.Owner, chk.Equals, "")
+	c.Assert(got.ACL, chk.Equals, "user::rwx,other::---")
+}
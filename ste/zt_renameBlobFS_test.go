@@ -0,0 +1,128 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type renameBlobFSSuite struct{}
+
+var _ = chk.Suite(&renameBlobFSSuite{})
+
+func (s *renameBlobFSSuite) TestIsSameFileSystem(c *chk.C) {
+	cases := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://acct.dfs.core.windows.net/fs/dir", "https://acct.dfs.core.windows.net/fs/other/dir", true},
+		{"https://acct.dfs.core.windows.net/fs/dir?sv=1&sig=a", "https://acct.dfs.core.windows.net/fs?sv=1&sig=b", true},
+		{"https://acct.dfs.core.windows.net/fs/dir", "https://acct.dfs.core.windows.net/fs2/dir", false},
+		{"https://acct.dfs.core.windows.net/fs/dir", "https://other.dfs.core.windows.net/fs/dir", false},
+		{"https://127.0.0.1/acct/fs/dir", "https://127.0.0.1/acct/fs/x", true},
+		{"https://127.0.0.1/acct/fs/dir", "https://127.0.0.1/other/fs/dir", false},
+		{"https://acct.dfs.core.windows.net/", "https://acct.dfs.core.windows.net/", false}, // no filesystem
+	}
+	for _, x := range cases {
+		c.Check(isSameFileSystem(x.a, x.b), chk.Equals, x.same, chk.Commentf("%s and %s", x.a, x.b))
+	}
+}
+
+func (s *renameBlobFSSuite) TestMovesByRename(c *chk.C) {
+	header := func(fromTo common.FromTo, deleteSource bool, overwrite common.OverwriteOption, src, dst string) *JobPartPlanHeader {
+		h := &JobPartPlanHeader{FromTo: fromTo, DeleteSourceAfterTransfer: deleteSource, ForceWrite: overwrite}
+		h.SourceRootLength = uint16(copy(h.SourceRoot[:], src))
+		h.DestinationRootLength = uint16(copy(h.DestinationRoot[:], dst))
+		return h
+	}
+	src := "https://acct.dfs.core.windows.net/fs/a"
+	dst := "https://acct.dfs.core.windows.net/fs/b"
+	bfs := common.EFromTo.BlobFSBlobFS()
+
+	c.Check(header(bfs, true, common.EOverwriteOption.True(), src, dst).movesByRename(), chk.Equals, true)
+	c.Check(header(bfs, true, common.EOverwriteOption.False(), src, dst).movesByRename(), chk.Equals, true)
+
+	// a copy, or a move that needs to compare with the destination, or one between filesystems, copies and deletes
+	c.Check(header(bfs, false, common.EOverwriteOption.True(), src, dst).movesByRename(), chk.Equals, false)
+	c.Check(header(bfs, true, common.EOverwriteOption.IfSourceNewer(), src, dst).movesByRename(), chk.Equals, false)
+	c.Check(header(bfs, true, common.EOverwriteOption.True(), src, "https://acct.dfs.core.windows.net/fs2/b").movesByRename(), chk.Equals, false)
+	c.Check(header(common.EFromTo.BlobBlob(), true, common.EOverwriteOption.True(), src, dst).movesByRename(), chk.Equals, false)
+}
+
+// renameTestJptm is just enough of a transfer to be moved by renaming it
+type renameTestJptm struct {
+	IJobPartTransferMgr
+	info            TransferInfo
+	deletionPending bool
+	status          common.TransferStatus
+}
+
+func (j *renameTestJptm) Info() TransferInfo       { return j.info }
+func (j *renameTestJptm) Context() context.Context { return context.Background() }
+func (j *renameTestJptm) GetOverwriteOption() common.OverwriteOption {
+	return common.EOverwriteOption.True()
+}
+func (j *renameTestJptm) SourceDeletionPending() bool                                   { return j.deletionPending }
+func (j *renameTestJptm) SetSourceDeletionPending(pending bool)                         { j.deletionPending = pending }
+func (j *renameTestJptm) SetStatus(status common.TransferStatus)                        { j.status = status }
+func (j *renameTestJptm) ReportTransferDone() uint32                                    { return 0 }
+func (j *renameTestJptm) Log(level pipeline.LogLevel, msg string)                       {}
+func (j *renameTestJptm) ShouldLog(level pipeline.LogLevel) bool                        { return false }
+func (j *renameTestJptm) LogSendError(source, destination, errorMsg string, status int) {}
+
+// sourceGonePipeline answers every rename with 404, since the source isn't there, and says that the destination exists
+func sourceGonePipeline() pipeline.Pipeline {
+	return pipeline.NewPipeline([]pipeline.Factory{pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			response := &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: http.Header{}, Request: request.Request,
+				Body: ioutil.NopCloser(strings.NewReader(""))}
+			if request.Method == http.MethodPut {
+				response.StatusCode = http.StatusNotFound
+				response.Status = "404 Not Found"
+				response.Header.Set("X-Ms-Error-Code", "SourcePathNotFound")
+			}
+			return pipeline.NewHTTPResponse(response), nil
+		}
+	})}, pipeline.Options{})
+}
+
+func (s *renameBlobFSSuite) TestMissingSourceIsOnlyMovedWhenResuming(c *chk.C) {
+	info := TransferInfo{Source: "https://acct.dfs.core.windows.net/fs/a", Destination: "https://acct.dfs.core.windows.net/fs/b"}
+
+	// a source that was never there isn't reported as moved, just because the destination already exists
+	jptm := &renameTestJptm{info: info}
+	doRenameBlobFS(jptm, sourceGonePipeline())
+	c.Assert(jptm.status, chk.Equals, common.ETransferStatus.Failed())
+	c.Assert(jptm.deletionPending, chk.Equals, false)
+
+	// whereas if an earlier attempt had started the rename, it most likely succeeded
+	jptm = &renameTestJptm{info: info, deletionPending: true}
+	doRenameBlobFS(jptm, sourceGonePipeline())
+	c.Assert(jptm.status, chk.Equals, common.ETransferStatus.Success())
+}
@@ -0,0 +1,73 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+)
+
+type retriedBytesSuite struct{}
+
+var _ = chk.Suite(&retriedBytesSuite{})
+
+func responseWithStatus(status int) pipeline.Response {
+	return pipeline.NewHTTPResponse(&http.Response{StatusCode: status})
+}
+
+func (s *retriedBytesSuite) TestIsRetryableTry(c *chk.C) {
+	c.Assert(isRetryableTry(responseWithStatus(http.StatusCreated), nil), chk.Equals, false)
+	c.Assert(isRetryableTry(responseWithStatus(http.StatusNotFound), nil), chk.Equals, false)
+	c.Assert(isRetryableTry(nil, nil), chk.Equals, false)
+
+	c.Assert(isRetryableTry(responseWithStatus(http.StatusServiceUnavailable), nil), chk.Equals, true)
+	c.Assert(isRetryableTry(responseWithStatus(http.StatusInternalServerError), nil), chk.Equals, true)
+	c.Assert(isRetryableTry(responseWithStatus(http.StatusTooManyRequests), nil), chk.Equals, true)
+	c.Assert(isRetryableTry(nil, errors.New("connection reset by peer")), chk.Equals, true)
+}
+
+func (s *retriedBytesSuite) TestStatsPolicyCountsRetryableTries(c *chk.C) {
+	stats := &pipelineNetworkStats{tunerInterface: &nullConcurrencyTuner{}, latencies: newChunkLatencyTracker(requestLatencyWindow)}
+	statuses := []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusCreated}
+	next := pipeline.PolicyFunc(func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+		status := statuses[0]
+		statuses = statuses[1:]
+		return responseWithStatus(status), nil
+	})
+	policy := &xferStatsPolicy{next: next, stats: stats}
+
+	request, err := pipeline.NewRequest(http.MethodPut, url.URL{Scheme: "https", Host: "acct.blob.core.windows.net", Path: "/c/b"}, nil)
+	c.Assert(err, chk.IsNil)
+	request.ContentLength = 100
+	for i := 0; i < 3; i++ {
+		_, _ = policy.Do(context.Background(), request)
+	}
+
+	retries, serverBusy := stats.GetRetryCounts()
+	c.Assert(retries, chk.Equals, int64(2))
+	c.Assert(serverBusy, chk.Equals, int64(1))
+	c.Assert(stats.GetRetriedBytes(), chk.Equals, int64(200))
+}
@@ -0,0 +1,84 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type roundRobinQueueSuite struct{}
+
+var _ = chk.Suite(&roundRobinQueueSuite{})
+
+func (s *roundRobinQueueSuite) TestPartsTakeTurns(c *chk.C) {
+	q := newRoundRobinQueue(100)
+	bigPart, smallPart, laterPart := &jobPartMgr{}, &jobPartMgr{}, &jobPartMgr{}
+
+	for i := 0; i < 4; i++ {
+		q.push(bigPart, "big")
+	}
+	q.push(smallPart, "small")
+	q.push(laterPart, "later 1")
+	q.push(laterPart, "later 2")
+
+	order := make([]interface{}, 0)
+	for i := 0; i < 7; i++ {
+		order = append(order, q.pop())
+	}
+	c.Assert(order, chk.DeepEquals, []interface{}{"big", "small", "later 1", "big", "later 2", "big", "big"})
+}
+
+func (s *roundRobinQueueSuite) TestPartsKeepTheirOwnOrder(c *chk.C) {
+	q := newRoundRobinQueue(100)
+	part := &jobPartMgr{}
+	for i := 0; i < 3; i++ {
+		q.push(part, i)
+	}
+	c.Assert(q.pop(), chk.Equals, 0)
+
+	q.push(&jobPartMgr{}, "other") // joins the turns after the part already waiting
+	c.Assert(q.pop(), chk.Equals, 1)
+	c.Assert(q.pop(), chk.Equals, "other")
+	c.Assert(q.pop(), chk.Equals, 2)
+}
+
+func (s *roundRobinQueueSuite) TestPushWaitsWhileFull(c *chk.C) {
+	q := newRoundRobinQueue(1)
+	part := &jobPartMgr{}
+	q.push(part, "first")
+
+	pushed := make(chan struct{})
+	go func() {
+		q.push(part, "second")
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		c.Fatal("pushed to a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.Assert(q.pop(), chk.Equals, "first")
+	<-pushed
+	c.Assert(q.pop(), chk.Equals, "second")
+}
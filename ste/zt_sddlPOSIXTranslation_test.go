@@ -0,0 +1,113 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"strings"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/sddl"
+)
+
+type sddlPOSIXTranslationSuite struct{}
+
+var _ = chk.Suite(&sddlPOSIXTranslationSuite{})
+
+const (
+	testOwnerSID = "S-1-5-21-1-2-3-1001"
+	testGroupSID = "S-1-5-21-1-2-3-513"
+)
+
+func translateTestSDDL(c *chk.C, sd string, isDir, includeOwnership bool) (azbfs.BlobFSAccessControl, []string) {
+	parsed, err := sddl.ParseSDDL(sd)
+	c.Assert(err, chk.IsNil)
+	return posixAccessControlFromSDDL(parsed, isDir, includeOwnership, isWellKnownGroupSID)
+}
+
+func (s *sddlPOSIXTranslationSuite) TestParseSDDLAccessMask(c *chk.C) {
+	mask, err := parseSDDLAccessMask("0x1200a9")
+	c.Assert(err, chk.IsNil)
+	c.Assert(posixPermissionString(posixPermsFromAccessMask(mask)), chk.Equals, "r-x")
+
+	mask, err = parseSDDLAccessMask("FRFW")
+	c.Assert(err, chk.IsNil)
+	c.Assert(posixPermissionString(posixPermsFromAccessMask(mask)), chk.Equals, "rw-")
+
+	_, err = parseSDDLAccessMask("FRZZ")
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *sddlPOSIXTranslationSuite) TestTranslateDirectoryACL(c *chk.C) {
+	ac, unrepresentable := translateTestSDDL(c, "O:"+testOwnerSID+"G:"+testGroupSID+"D:P"+
+		"(A;OICI;FA;;;"+testOwnerSID+")(A;OICI;0x1200a9;;;S-1-5-32-545)(A;;FR;;;WD)(A;OICIIO;GA;;;CO)", true, true)
+
+	c.Assert(unrepresentable, chk.HasLen, 0)
+	c.Assert(ac.Owner, chk.Equals, testOwnerSID)
+	c.Assert(ac.Group, chk.Equals, testGroupSID)
+	// Everyone's read goes to every entry, and the owner's inheritable ACE stays with the owner in the default ACL,
+	// while CREATOR OWNER's becomes the default owner entry
+	c.Assert(ac.ACL, chk.Equals, "user::rwx,group::r--,group:S-1-5-32-545:r-x,mask::r-x,other::r--,"+
+		"default:user::rwx,default:user:"+testOwnerSID+":rwx,default:group::---,default:group:S-1-5-32-545:r-x,default:mask::rwx,default:other::---")
+}
+
+func (s *sddlPOSIXTranslationSuite) TestTranslateReportsWhatPOSIXCantRepresent(c *chk.C) {
+	ac, unrepresentable := translateTestSDDL(c, "O:"+testOwnerSID+"G:"+testGroupSID+"D:"+
+		"(D;;FW;;;"+testOwnerSID+")(A;;FA;;;"+testOwnerSID+")(A;;FA;;;BA)(OA;;CR;ab721a53-1e2f-11d0-9819-00aa0040529b;;WD)(A;OICIIO;FA;;;CO)", false, false)
+
+	// the deny ACE comes first, so the owner can't write, and nothing is inherited by a file
+	c.Assert(ac.ACL, chk.Equals, "user::r-x,group::---,other::---")
+	c.Assert(ac.Owner, chk.Equals, "")
+	c.Assert(unrepresentable, chk.HasLen, 3)
+	c.Assert(strings.HasPrefix(unrepresentable[0], "(D;;FW;;;"+testOwnerSID+") denies rights"), chk.Equals, true)
+	c.Assert(strings.HasPrefix(unrepresentable[1], "(A;;FA;;;BA) lets a principal other than the owner"), chk.Equals, true)
+	c.Assert(strings.HasPrefix(unrepresentable[2], "(OA;"), chk.Equals, true)
+}
+
+func (s *sddlPOSIXTranslationSuite) TestTranslatePartialInheritance(c *chk.C) {
+	ac, unrepresentable := translateTestSDDL(c, "O:"+testOwnerSID+"G:"+testGroupSID+"D:(A;OI;FR;;;WD)(A;OICINP;FR;;;"+testGroupSID+")", true, true)
+
+	c.Assert(ac.ACL, chk.Equals, "user::r--,group::r--,other::r--") // no default ACL at all
+	c.Assert(unrepresentable, chk.HasLen, 2)
+}
+
+func (s *sddlPOSIXTranslationSuite) TestTranslateNullDACL(c *chk.C) {
+	ac, unrepresentable := translateTestSDDL(c, "O:"+testOwnerSID+"G:"+testGroupSID+"D:NO_ACCESS_CONTROL", false, true)
+
+	c.Assert(ac.ACL, chk.Equals, "user::rwx,group::rwx,other::rwx")
+	c.Assert(unrepresentable, chk.HasLen, 0)
+}
+
+func (s *sddlPOSIXTranslationSuite) TestMapSIDPrincipals(c *chk.C) {
+	idMap := common.POSIXIDMap{
+		UserSIDs:  map[string]string{testOwnerSID: "user-object-id"},
+		GroupSIDs: map[string]string{"S-1-5-32-545": "group-object-id"},
+	}
+	ac := azbfs.BlobFSAccessControl{Owner: testOwnerSID, Group: testGroupSID, ACL: "user::rwx,group::r--,group:S-1-5-32-545:r-x,mask::r-x,other::r--"}
+
+	mapped, unmapped := mapPOSIXPrincipals(ac, idMap)
+	c.Assert(mapped.Owner, chk.Equals, "user-object-id")
+	c.Assert(mapped.Group, chk.Equals, "")
+	c.Assert(mapped.ACL, chk.Equals, "user::rwx,group::r--,group:group-object-id:r-x,mask::r-x,other::r--")
+	c.Assert(unmapped, chk.DeepEquals, []string{"group " + testGroupSID})
+}
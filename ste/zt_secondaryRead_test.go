@@ -0,0 +1,80 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type secondaryReadSuite struct{}
+
+var _ = chk.Suite(&secondaryReadSuite{})
+
+func (s *secondaryReadSuite) TestSecondaryHostFor(c *chk.C) {
+	c.Assert(secondaryHostFor("https://acct.blob.core.windows.net/container/dir"), chk.Equals, "acct-secondary.blob.core.windows.net")
+	c.Assert(secondaryHostFor("https://acct.dfs.core.chinacloudapi.cn/fs"), chk.Equals, "acct-secondary.dfs.core.chinacloudapi.cn")
+
+	// already the secondary, the emulator, and custom domains have no secondary we can work out
+	c.Assert(secondaryHostFor("https://acct-secondary.blob.core.windows.net/container"), chk.Equals, "")
+	c.Assert(secondaryHostFor("http://127.0.0.1:10000/devstoreaccount1/container"), chk.Equals, "")
+	c.Assert(secondaryHostFor("https://files.contoso.com/container"), chk.Equals, "")
+}
+
+type secondaryReadRecorder struct {
+	hosts []string
+}
+
+func (r *secondaryReadRecorder) SecondaryReadCallback(host string) {
+	r.hosts = append(r.hosts, host)
+}
+
+func (s *secondaryReadSuite) TestNotifySecondaryRead(c *chk.C) {
+	// nothing registered, nothing to do
+	notifySecondaryRead(context.Background(), "acct-secondary.blob.core.windows.net")
+
+	r := &secondaryReadRecorder{}
+	ctx := withSecondaryReadNotification(context.Background(), r)
+	notifySecondaryRead(ctx, "acct-secondary.blob.core.windows.net")
+	c.Assert(r.hosts, chk.DeepEquals, []string{"acct-secondary.blob.core.windows.net"})
+}
+
+func (s *secondaryReadSuite) TestSecondaryReadMustBeCurrent(c *chk.C) {
+	enumerated := time.Date(2021, 3, 1, 2, 0, 0, 0, time.UTC)
+	response := func(host string, lastModified time.Time) *http.Response {
+		u, err := url.Parse("https://" + host + "/container/blob")
+		c.Assert(err, chk.IsNil)
+		header := http.Header{}
+		header.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		return &http.Response{Request: &http.Request{URL: u}, Header: header}
+	}
+
+	c.Assert(checkSecondaryReadIsCurrent(response("acct-secondary.blob.core.windows.net", enumerated), enumerated), chk.IsNil)
+	// a secondary that lags behind still has the older version, which mustn't be mixed with the primary's
+	lagging := response("acct-secondary.blob.core.windows.net", enumerated.Add(-time.Hour))
+	c.Assert(checkSecondaryReadIsCurrent(lagging, enumerated), chk.NotNil)
+	// the primary is already held to the enumerated version by the download's access conditions
+	c.Assert(checkSecondaryReadIsCurrent(response("acct.blob.core.windows.net", enumerated.Add(-time.Hour)), enumerated), chk.IsNil)
+}
@@ -0,0 +1,132 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type setBlobPropertiesSuite struct{}
+
+var _ = chk.Suite(&setBlobPropertiesSuite{})
+
+// setPropertiesTestJptm is just enough of a transfer to set the properties of a blob
+type setPropertiesTestJptm struct {
+	IJobPartTransferMgr
+	headers  common.ResourceHTTPHeaders
+	metadata common.Metadata
+	blobTags common.BlobTags
+	status   common.TransferStatus
+}
+
+func (j *setPropertiesTestJptm) Info() TransferInfo {
+	return TransferInfo{Source: "https://acct.blob.core.windows.net/container/blob"}
+}
+func (j *setPropertiesTestJptm) Context() context.Context { return context.Background() }
+func (j *setPropertiesTestJptm) ResourceDstData(dataFileToXfer []byte) (common.ResourceHTTPHeaders, common.Metadata, common.BlobTags) {
+	return j.headers, j.metadata, j.blobTags
+}
+func (j *setPropertiesTestJptm) SetStatus(status common.TransferStatus)       { j.status = status }
+func (j *setPropertiesTestJptm) ReportTransferDone() uint32                   { return 0 }
+func (j *setPropertiesTestJptm) Log(level pipeline.LogLevel, msg string)      {}
+func (j *setPropertiesTestJptm) LogError(resource, context string, err error) {}
+
+// propertiesRecorder answers like a blob that already has content headers, and remembers what was asked of it
+type propertiesRecorder struct {
+	requests         []*http.Request
+	getPropertiesErr bool
+}
+
+func (r *propertiesRecorder) pipeline() pipeline.Pipeline {
+	return pipeline.NewPipeline([]pipeline.Factory{pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			r.requests = append(r.requests, request.Request)
+			response := &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: http.Header{}, Request: request.Request,
+				Body: ioutil.NopCloser(strings.NewReader(""))}
+			switch {
+			case request.Method == http.MethodHead && r.getPropertiesErr:
+				response.StatusCode = http.StatusNotFound
+				response.Status = "404 Not Found"
+				response.Header.Set("X-Ms-Error-Code", "BlobNotFound")
+			case request.Method == http.MethodHead:
+				response.Header.Set("Content-Type", "application/pdf")
+				response.Header.Set("Cache-Control", "no-cache")
+			case request.URL.Query().Get("comp") == "tags":
+				response.StatusCode = http.StatusNoContent
+				response.Status = "204 No Content"
+			}
+			return pipeline.NewHTTPResponse(response), nil
+		}
+	})}, pipeline.Options{})
+}
+
+// comps lists what was asked of the blob, by method and comp parameter
+func (r *propertiesRecorder) comps() []string {
+	comps := make([]string, 0, len(r.requests))
+	for _, request := range r.requests {
+		comps = append(comps, request.Method+" "+request.URL.Query().Get("comp"))
+	}
+	return comps
+}
+
+func (s *setBlobPropertiesSuite) TestGivenHeadersAreMergedWithExistingOnes(c *chk.C) {
+	jptm := &setPropertiesTestJptm{headers: common.ResourceHTTPHeaders{ContentLanguage: "en-US"}}
+	recorder := &propertiesRecorder{}
+	doSetBlobProperties(jptm, recorder.pipeline())
+
+	c.Assert(jptm.status, chk.Equals, common.ETransferStatus.Success())
+	c.Assert(recorder.comps(), chk.DeepEquals, []string{"HEAD ", "PUT properties"})
+
+	// the headers that weren't given keep the values the blob already had
+	set := recorder.requests[1].Header
+	c.Assert(set.Get("X-Ms-Blob-Content-Language"), chk.Equals, "en-US")
+	c.Assert(set.Get("X-Ms-Blob-Content-Type"), chk.Equals, "application/pdf")
+	c.Assert(set.Get("X-Ms-Blob-Cache-Control"), chk.Equals, "no-cache")
+}
+
+func (s *setBlobPropertiesSuite) TestOnlyGivenPropertiesAreSet(c *chk.C) {
+	jptm := &setPropertiesTestJptm{metadata: common.Metadata{"author": "jiac"}, blobTags: common.BlobTags{"team": "storage"}}
+	recorder := &propertiesRecorder{}
+	doSetBlobProperties(jptm, recorder.pipeline())
+
+	// without headers to set, the existing ones don't need to be read
+	c.Assert(jptm.status, chk.Equals, common.ETransferStatus.Success())
+	c.Assert(recorder.comps(), chk.DeepEquals, []string{"PUT metadata", "PUT tags"})
+	c.Assert(recorder.requests[0].Header.Get("X-Ms-Meta-Author"), chk.Equals, "jiac")
+}
+
+func (s *setBlobPropertiesSuite) TestFailureToReadHeadersFailsTheTransfer(c *chk.C) {
+	jptm := &setPropertiesTestJptm{headers: common.ResourceHTTPHeaders{ContentType: "text/plain"}, metadata: common.Metadata{"author": "jiac"}}
+	recorder := &propertiesRecorder{getPropertiesErr: true}
+	doSetBlobProperties(jptm, recorder.pipeline())
+
+	// nothing is changed once a step has failed
+	c.Assert(jptm.status, chk.Equals, common.ETransferStatus.Failed())
+	c.Assert(recorder.comps(), chk.DeepEquals, []string{"HEAD "})
+}
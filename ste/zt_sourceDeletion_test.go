@@ -0,0 +1,94 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type sourceDeletionSuite struct{}
+
+var _ = chk.Suite(&sourceDeletionSuite{})
+
+func (s *sourceDeletionSuite) TestLocalSourceIsDeleted(c *chk.C) {
+	dir, err := ioutil.TempDir("", "move")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "moved")
+	c.Assert(ioutil.WriteFile(source, []byte("data"), 0600), chk.IsNil)
+
+	c.Assert(deleteTransferSource(context.Background(), common.ELocation.Local(), source, time.Time{}, nil), chk.IsNil)
+	_, err = os.Stat(source)
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+
+	// one that's already gone counts as deleted, so that deleting it again, after a crash, doesn't fail the transfer
+	c.Assert(deleteTransferSource(context.Background(), common.ELocation.Local(), source, time.Time{}, nil), chk.IsNil)
+}
+
+func (s *sourceDeletionSuite) TestChangedLocalSourceIsKept(c *chk.C) {
+	dir, err := ioutil.TempDir("", "move")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "moved")
+	c.Assert(ioutil.WriteFile(source, []byte("data"), 0600), chk.IsNil)
+	info, err := os.Stat(source)
+	c.Assert(err, chk.IsNil)
+	transferred := info.ModTime()
+
+	// written again after it was read, so the newer content was never transferred
+	c.Assert(ioutil.WriteFile(source, []byte("newer data"), 0600), chk.IsNil)
+	c.Assert(os.Chtimes(source, transferred, transferred.Add(time.Minute)), chk.IsNil)
+	err = deleteTransferSource(context.Background(), common.ELocation.Local(), source, transferred, nil)
+	c.Assert(err, chk.Equals, errSourceChangedSinceTransfer)
+	_, err = os.Stat(source)
+	c.Assert(err, chk.IsNil)
+
+	// and once it's as it was when transferred, it's deleted
+	c.Assert(os.Chtimes(source, transferred, transferred), chk.IsNil)
+	c.Assert(deleteTransferSource(context.Background(), common.ELocation.Local(), source, transferred, nil), chk.IsNil)
+	_, err = os.Stat(source)
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
+
+func (s *sourceDeletionSuite) TestOnlyDeletableSourcesAreDeleted(c *chk.C) {
+	err := deleteTransferSource(context.Background(), common.ELocation.S3(), "https://bucket.s3.amazonaws.com/object", time.Time{}, nil)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *sourceDeletionSuite) TestDeletionIsRecordedPerTransfer(c *chk.C) {
+	jppt := &JobPartPlanTransfer{}
+	c.Assert(jppt.SourceDeletionPending(), chk.Equals, false)
+
+	jppt.setSourceDeletion(sourceDeletionPending)
+	c.Assert(jppt.SourceDeletionPending(), chk.Equals, true)
+
+	jppt.setSourceDeletion(sourceDeletionDone)
+	c.Assert(jppt.SourceDeletionPending(), chk.Equals, false)
+}
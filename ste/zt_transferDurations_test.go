@@ -0,0 +1,88 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type transferDurationsSuite struct{}
+
+var _ = chk.Suite(&transferDurationsSuite{})
+
+func (s *transferDurationsSuite) TestDurationIsOnlyKnownOnceDone(c *chk.C) {
+	jppt := &JobPartPlanTransfer{}
+	_, ok := jppt.Duration()
+	c.Assert(ok, chk.Equals, false)
+
+	start := time.Now()
+	jppt.SetStartTime(start)
+	_, ok = jppt.Duration()
+	c.Assert(ok, chk.Equals, false)
+
+	jppt.SetCompletionTime(start.Add(3 * time.Second))
+	d, ok := jppt.Duration()
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(d, chk.Equals, 3*time.Second)
+
+	// a resumed transfer is timed from its new start
+	jppt.SetStartTime(start.Add(time.Minute))
+	_, ok = jppt.Duration()
+	c.Assert(ok, chk.Equals, false)
+}
+
+func (s *transferDurationsSuite) TestSpreadAndSlowest(c *chk.C) {
+	d := &transferDurations{}
+	described := 0
+	for i := 1; i <= 100; i++ {
+		name := fmt.Sprintf("file%d", i)
+		// out of order, so that the slowest aren't simply the last ones added
+		d.add(time.Duration((i*37)%100+1)*time.Millisecond, func() common.TransferDuration {
+			described++
+			return common.TransferDuration{Src: name, Bytes: 1024}
+		})
+	}
+
+	js := common.ListJobSummaryResponse{}
+	d.fill(&js)
+	c.Assert(js.MinTransferMilliseconds, chk.Equals, int64(1))
+	c.Assert(js.MedianTransferMilliseconds, chk.Equals, int64(50))
+	c.Assert(js.P95TransferMilliseconds, chk.Equals, int64(95))
+	c.Assert(js.MaxTransferMilliseconds, chk.Equals, int64(100))
+
+	c.Assert(js.SlowestTransfers, chk.HasLen, slowestTransfersListed)
+	for i, t := range js.SlowestTransfers {
+		c.Assert(t.Milliseconds, chk.Equals, int64(100-i))
+		c.Assert(t.Bytes, chk.Equals, int64(1024))
+	}
+	c.Assert(described < 100, chk.Equals, true)
+}
+
+func (s *transferDurationsSuite) TestNoTransfersLeavesSummaryAlone(c *chk.C) {
+	js := common.ListJobSummaryResponse{}
+	(&transferDurations{}).fill(&js)
+	c.Assert(js.SlowestTransfers, chk.IsNil)
+	c.Assert(js.MaxTransferMilliseconds, chk.Equals, int64(0))
+}